@@ -0,0 +1,118 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// ReportSource provides the processing report summaries checked by the Monitor. It is satisfied by storage.Database.
+type ReportSource interface {
+	CountGaps(ctx context.Context) (int, error)
+	IndexedHeight(ctx context.Context, reporter string) (int64, error)
+}
+
+// MonitorConfig configures the conditions checked by a Monitor.
+type MonitorConfig struct {
+	Reporter     string        // name of the indexer whose lag is checked
+	GapThreshold int           // alert when more than this many gaps are recorded, zero to disable
+	LagThreshold int64         // alert when the indexer is more than this many epochs behind the chain head, zero to disable
+	Interval     time.Duration // time between checks
+}
+
+// NewMonitor creates a Monitor that reads processing reports from reports and the chain head from opener.
+func NewMonitor(n *Notifier, reports ReportSource, opener lens.APIOpener, cfg MonitorConfig) *Monitor {
+	return &Monitor{
+		notifier: n,
+		reports:  reports,
+		opener:   opener,
+		cfg:      cfg,
+	}
+}
+
+// A Monitor is a job that periodically checks the number of gaps in the processing reports and how far the indexer
+// has fallen behind the chain head, sending an alert when either exceeds its threshold and another when it recovers.
+type Monitor struct {
+	notifier *Notifier
+	reports  ReportSource
+	opener   lens.APIOpener
+	cfg      MonitorConfig
+}
+
+func (m *Monitor) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["reporter"] = m.cfg.Reporter
+	out["gapThreshold"] = m.cfg.GapThreshold
+	out["lagThreshold"] = m.cfg.LagThreshold
+	out["interval"] = m.cfg.Interval.String()
+	return out
+}
+
+// Run checks the alert conditions until the context is done.
+func (m *Monitor) Run(ctx context.Context) error {
+	for {
+		if m.cfg.GapThreshold > 0 {
+			if err := m.checkGaps(ctx); err != nil {
+				return xerrors.Errorf("check gaps: %w", err)
+			}
+		}
+		if m.cfg.LagThreshold > 0 {
+			if err := m.checkLag(ctx); err != nil {
+				return xerrors.Errorf("check lag: %w", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.cfg.Interval):
+		}
+	}
+}
+
+func (m *Monitor) checkGaps(ctx context.Context) error {
+	gaps, err := m.reports.CountGaps(ctx)
+	if err != nil {
+		return err
+	}
+	log.Debugw("checked gaps", "count", gaps, "threshold", m.cfg.GapThreshold)
+
+	if gaps > m.cfg.GapThreshold {
+		return m.notifier.Alert(ctx, "gaps", fmt.Sprintf("%d gaps recorded in processing reports, exceeding the threshold of %d", gaps, m.cfg.GapThreshold))
+	}
+	return m.notifier.Resolve(ctx, "gaps", fmt.Sprintf("gaps recorded in processing reports are back within the threshold: %d", gaps))
+}
+
+func (m *Monitor) checkLag(ctx context.Context) error {
+	indexed, err := m.reports.IndexedHeight(ctx, m.cfg.Reporter)
+	if err != nil {
+		return err
+	}
+	if indexed == 0 {
+		// nothing indexed yet so lag is meaningless
+		return nil
+	}
+
+	node, closer, err := m.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("get chain head: %w", err)
+	}
+
+	lag := int64(head.Height()) - indexed
+	log.Debugw("checked lag", "lag", lag, "head", head.Height(), "indexed", indexed, "threshold", m.cfg.LagThreshold)
+
+	if lag > m.cfg.LagThreshold {
+		return m.notifier.Alert(ctx, "lag", fmt.Sprintf("%s is %d epochs behind the chain head (head %d, indexed %d), exceeding the threshold of %d", m.cfg.Reporter, lag, head.Height(), indexed, m.cfg.LagThreshold))
+	}
+	return m.notifier.Resolve(ctx, "lag", fmt.Sprintf("%s has caught up and is %d epochs behind the chain head", m.cfg.Reporter, lag))
+}
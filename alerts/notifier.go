@@ -0,0 +1,150 @@
+// Package alerts posts messages to a chat webhook when jobs fail or indexing falls behind.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/version"
+)
+
+var log = logging.Logger("visor/alerts")
+
+// Webhook formats supported by the Notifier.
+const (
+	FormatSlack   = "slack"   // posts {"text": ...}, also accepted by Mattermost and Rocket.Chat
+	FormatDiscord = "discord" // posts {"content": ...}
+)
+
+// A Notifier posts alert messages to a Slack or Discord compatible webhook.
+type Notifier struct {
+	url      string
+	format   string
+	source   string        // prefixed to every message to identify the visor instance
+	cooldown time.Duration // minimum time between alerts with the same key
+	client   *http.Client
+
+	mu   sync.Mutex
+	sent map[string]time.Time // time each alert key was last sent
+}
+
+// NewNotifier returns a Notifier that posts to url using the given format. Alerts with the same key are sent at most
+// once per cooldown so a job that is restarted after every failure does not flood the channel. source is included in
+// each message to identify the instance raising the alert.
+func NewNotifier(url string, format string, source string, cooldown time.Duration, timeout time.Duration) (*Notifier, error) {
+	switch format {
+	case FormatSlack, FormatDiscord:
+	default:
+		return nil, xerrors.Errorf("unsupported webhook format %q, expected %q or %q", format, FormatSlack, FormatDiscord)
+	}
+	return &Notifier{
+		url:      url,
+		format:   format,
+		source:   source,
+		cooldown: cooldown,
+		client:   &http.Client{Timeout: timeout},
+		sent:     make(map[string]time.Time),
+	}, nil
+}
+
+// Alert sends msg unless an alert with the same key was sent within the cooldown period. The cooldown only starts
+// once the alert has been delivered, so an alert that fails to send is retried on the next call.
+func (n *Notifier) Alert(ctx context.Context, key string, msg string) error {
+	n.mu.Lock()
+	last, ok := n.sent[key]
+	n.mu.Unlock()
+	if ok && time.Since(last) < n.cooldown {
+		log.Debugw("suppressing alert", "key", key)
+		return nil
+	}
+
+	if err := n.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	n.sent[key] = time.Now()
+	n.mu.Unlock()
+	return nil
+}
+
+// Resolve sends msg and clears the cooldown for key if an alert with that key had been sent, so recovery is only
+// reported for conditions that were alerted on. The alert stays active if the message fails to send.
+func (n *Notifier) Resolve(ctx context.Context, key string, msg string) error {
+	n.mu.Lock()
+	_, ok := n.sent[key]
+	n.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := n.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	delete(n.sent, key)
+	n.mu.Unlock()
+	return nil
+}
+
+// Send posts msg to the webhook without applying any cooldown.
+func (n *Notifier) Send(ctx context.Context, msg string) error {
+	if n.source != "" {
+		msg = fmt.Sprintf("[%s] %s", n.source, msg)
+	}
+
+	var payload interface{}
+	switch n.format {
+	case FormatDiscord:
+		payload = struct {
+			Content string `json:"content"`
+		}{Content: msg}
+	default:
+		payload = struct {
+			Text string `json:"text"`
+		}{Text: msg}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return xerrors.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return xerrors.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "visor/"+version.String())
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return xerrors.Errorf("webhook failed with status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// JobFailed sends an alert reporting that the named job stopped with an error. It satisfies
+// schedule.JobFailureHandler.
+func (n *Notifier) JobFailed(ctx context.Context, name string, err error) {
+	if aerr := n.Alert(ctx, "job:"+name, fmt.Sprintf("job %s failed: %v", name, err)); aerr != nil {
+		log.Errorw("failed to send job failure alert", "job", name, "error", aerr)
+	}
+}
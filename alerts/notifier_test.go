@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhook struct {
+	mu       sync.Mutex
+	payloads []map[string]string
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var p map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	w.payloads = append(w.payloads, p)
+	w.mu.Unlock()
+}
+
+func TestNotifierFormats(t *testing.T) {
+	wh := &webhook{}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	slack, err := NewNotifier(srv.URL, FormatSlack, "visor1", time.Hour, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, slack.Send(context.Background(), "hello"))
+
+	discord, err := NewNotifier(srv.URL, FormatDiscord, "", time.Hour, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, discord.Send(context.Background(), "hello"))
+
+	require.Len(t, wh.payloads, 2)
+	assert.Equal(t, map[string]string{"text": "[visor1] hello"}, wh.payloads[0])
+	assert.Equal(t, map[string]string{"content": "hello"}, wh.payloads[1])
+
+	_, err = NewNotifier(srv.URL, "teams", "", time.Hour, time.Second)
+	assert.Error(t, err)
+}
+
+func TestNotifierCooldown(t *testing.T) {
+	wh := &webhook{}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	n, err := NewNotifier(srv.URL, FormatSlack, "", time.Hour, time.Second)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, n.Resolve(ctx, "lag", "recovered")) // nothing alerted so nothing to resolve
+	require.NoError(t, n.Alert(ctx, "lag", "behind"))
+	require.NoError(t, n.Alert(ctx, "lag", "still behind")) // suppressed by cooldown
+	require.NoError(t, n.Alert(ctx, "gaps", "gaps"))
+	require.NoError(t, n.Resolve(ctx, "lag", "recovered"))
+	require.NoError(t, n.Alert(ctx, "lag", "behind again"))
+
+	var texts []string
+	for _, p := range wh.payloads {
+		texts = append(texts, p["text"])
+	}
+	assert.Equal(t, []string{"behind", "gaps", "recovered", "behind again"}, texts)
+}
+
+func TestNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.Error(rw, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(srv.URL, FormatSlack, "", time.Hour, time.Second)
+	require.NoError(t, err)
+	err = n.Send(context.Background(), "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_token")
+}
+
+func TestNotifierRetriesFailedAlert(t *testing.T) {
+	wh := &webhook{}
+	failing := int32(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		wh.ServeHTTP(rw, r)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(srv.URL, FormatSlack, "", time.Hour, time.Second)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.Error(t, n.Alert(ctx, "lag", "behind"))
+	require.NoError(t, n.Resolve(ctx, "lag", "recovered")) // the failed alert was never delivered
+
+	atomic.StoreInt32(&failing, 0)
+	require.NoError(t, n.Alert(ctx, "lag", "behind"))
+
+	atomic.StoreInt32(&failing, 1)
+	require.Error(t, n.Resolve(ctx, "lag", "recovered"))
+	require.NoError(t, n.Alert(ctx, "lag", "still behind")) // still alerted so suppressed by cooldown
+
+	atomic.StoreInt32(&failing, 0)
+	require.NoError(t, n.Resolve(ctx, "lag", "recovered"))
+
+	var texts []string
+	for _, p := range wh.payloads {
+		texts = append(texts, p["text"])
+	}
+	assert.Equal(t, []string{"behind", "recovered"}, texts)
+}
@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/adt"
+	"github.com/filecoin-project/sentinel-visor/metrics"
+)
+
+// Strategies used to diff two versions of an actor state structure.
+const (
+	// StrategyLegacy iterates every element of both versions, see CompareMap and CompareArray.
+	StrategyLegacy = "legacy"
+
+	// StrategyFast walks the nodes of both versions, skipping subtrees they share, see Hamt and Amt.
+	StrategyFast = "fast"
+)
+
+// AdaptiveArrayThreshold enables the adaptive strategy for arrays when non-zero. Arrays that could be diffed with
+// StrategyFast are instead diffed with StrategyLegacy when both versions hold fewer elements than the threshold, where
+// reading every element costs less than comparing the nodes of both versions.
+var AdaptiveArrayThreshold uint64
+
+// ArrayStrategy returns the strategy to use to diff two versions of the array named by structure. legacy reports
+// whether the versions can only be diffed by iteration, for example because they were written by incompatible
+// versions of the AMT. The choice is recorded in the actor diff metric.
+func ArrayStrategy(ctx context.Context, structure string, legacy bool, pre, cur adt.Array) string {
+	strategy := StrategyFast
+	if legacy || useIteration(AdaptiveArrayThreshold, pre.Length(), cur.Length()) {
+		strategy = StrategyLegacy
+	}
+	recordStrategy(ctx, structure, strategy)
+	return strategy
+}
+
+// MapStrategy returns the strategy to use to diff two versions of the map named by structure. legacy reports whether
+// the versions can only be diffed by iteration. Maps do not record their size so there is no adaptive strategy for
+// them. The choice is recorded in the actor diff metric.
+func MapStrategy(ctx context.Context, structure string, legacy bool) string {
+	strategy := StrategyFast
+	if legacy {
+		strategy = StrategyLegacy
+	}
+	recordStrategy(ctx, structure, strategy)
+	return strategy
+}
+
+// useIteration reports whether structures of the given sizes are small enough to be diffed by iteration under
+// threshold.
+func useIteration(threshold, preLen, curLen uint64) bool {
+	return threshold > 0 && preLen < threshold && curLen < threshold
+}
+
+func recordStrategy(ctx context.Context, structure, strategy string) {
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Structure, structure), tag.Upsert(metrics.Strategy, strategy))
+	stats.Record(ctx, metrics.ActorDiff.M(1))
+}
@@ -0,0 +1,35 @@
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapStrategy(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, StrategyLegacy, MapStrategy(ctx, "test", true))
+	assert.Equal(t, StrategyFast, MapStrategy(ctx, "test", false))
+}
+
+func TestUseIteration(t *testing.T) {
+	testCases := []struct {
+		name      string
+		threshold uint64
+		preLen    uint64
+		curLen    uint64
+		want      bool
+	}{
+		{name: "disabled", threshold: 0, preLen: 1, curLen: 1, want: false},
+		{name: "both small", threshold: 100, preLen: 10, curLen: 99, want: true},
+		{name: "pre large", threshold: 100, preLen: 100, curLen: 10, want: false},
+		{name: "cur large", threshold: 100, preLen: 10, curLen: 1000, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, useIteration(tc.threshold, tc.preLen, tc.curLen))
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package builtin
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/filecoin-project/go-address"
@@ -151,6 +152,20 @@ func ActorFamily(name string) string {
 	return name[idx+1:]
 }
 
+// ActorVersion returns the actor version encoded in an actor name such as fil/3/storageminer, or -1 if the name is
+// not the name of a builtin actor.
+func ActorVersion(name string) int {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != "fil" {
+		return -1
+	}
+	v, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
 func IsBuiltinActor(c cid.Cid) bool {
 
 	if builtin0.IsBuiltinActor(c) {
@@ -176,6 +191,83 @@ func IsBuiltinActor(c cid.Cid) bool {
 	return false
 }
 
+// AllActorCodes returns the code CIDs of every builtin actor in every supported actors version.
+func AllActorCodes() []cid.Cid {
+	var out []cid.Cid
+
+	out = append(out,
+		builtin0.AccountActorCodeID,
+		builtin0.CronActorCodeID,
+		builtin0.InitActorCodeID,
+		builtin0.MultisigActorCodeID,
+		builtin0.PaymentChannelActorCodeID,
+		builtin0.RewardActorCodeID,
+		builtin0.StorageMarketActorCodeID,
+		builtin0.StorageMinerActorCodeID,
+		builtin0.StoragePowerActorCodeID,
+		builtin0.SystemActorCodeID,
+		builtin0.VerifiedRegistryActorCodeID,
+	)
+
+	out = append(out,
+		builtin2.AccountActorCodeID,
+		builtin2.CronActorCodeID,
+		builtin2.InitActorCodeID,
+		builtin2.MultisigActorCodeID,
+		builtin2.PaymentChannelActorCodeID,
+		builtin2.RewardActorCodeID,
+		builtin2.StorageMarketActorCodeID,
+		builtin2.StorageMinerActorCodeID,
+		builtin2.StoragePowerActorCodeID,
+		builtin2.SystemActorCodeID,
+		builtin2.VerifiedRegistryActorCodeID,
+	)
+
+	out = append(out,
+		builtin3.AccountActorCodeID,
+		builtin3.CronActorCodeID,
+		builtin3.InitActorCodeID,
+		builtin3.MultisigActorCodeID,
+		builtin3.PaymentChannelActorCodeID,
+		builtin3.RewardActorCodeID,
+		builtin3.StorageMarketActorCodeID,
+		builtin3.StorageMinerActorCodeID,
+		builtin3.StoragePowerActorCodeID,
+		builtin3.SystemActorCodeID,
+		builtin3.VerifiedRegistryActorCodeID,
+	)
+
+	out = append(out,
+		builtin4.AccountActorCodeID,
+		builtin4.CronActorCodeID,
+		builtin4.InitActorCodeID,
+		builtin4.MultisigActorCodeID,
+		builtin4.PaymentChannelActorCodeID,
+		builtin4.RewardActorCodeID,
+		builtin4.StorageMarketActorCodeID,
+		builtin4.StorageMinerActorCodeID,
+		builtin4.StoragePowerActorCodeID,
+		builtin4.SystemActorCodeID,
+		builtin4.VerifiedRegistryActorCodeID,
+	)
+
+	out = append(out,
+		builtin5.AccountActorCodeID,
+		builtin5.CronActorCodeID,
+		builtin5.InitActorCodeID,
+		builtin5.MultisigActorCodeID,
+		builtin5.PaymentChannelActorCodeID,
+		builtin5.RewardActorCodeID,
+		builtin5.StorageMarketActorCodeID,
+		builtin5.StorageMinerActorCodeID,
+		builtin5.StoragePowerActorCodeID,
+		builtin5.SystemActorCodeID,
+		builtin5.VerifiedRegistryActorCodeID,
+	)
+
+	return out
+}
+
 func IsAccountActor(c cid.Cid) bool {
 
 	if c == builtin0.AccountActorCodeID {
@@ -45,7 +45,7 @@ func DiffAddressMap(ctx context.Context, store adt.Store, pre, cur State) (*Addr
 	}
 
 	mapDiffer := NewAddressMapDiffer(pre, cur)
-	if requiresLegacyDiffing(pre, cur, preOpts, curOpts) {
+	if diff.MapStrategy(ctx, "init_addresses", requiresLegacyDiffing(pre, cur, preOpts, curOpts)) == diff.StrategyLegacy {
 		log.Warnw("actor HAMT opts differ, running slower generic map diff", "preCID", pre.Code(), "curCID", cur.Code())
 		if err := diff.CompareMap(prem, curm, mapDiffer); err != nil {
 			return nil, err
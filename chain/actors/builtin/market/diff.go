@@ -32,8 +32,11 @@ func DiffDealProposals(ctx context.Context, store adt.Store, pre, cur State) (*D
 	}
 
 	diffContainer := NewMarketProposalsDiffContainer(preP, curP)
-	if requiresLegacyDiffing(pre, cur, preOpts, curOpts) {
-		log.Warn("actor AMT opts differ, running slower generic array diff", "preCID", pre.Code(), "curCID", cur.Code())
+	legacy := requiresLegacyDiffing(pre, cur, preOpts, curOpts)
+	if diff.ArrayStrategy(ctx, "market_proposals", legacy, preP.array(), curP.array()) == diff.StrategyLegacy {
+		if legacy {
+			log.Warn("actor AMT opts differ, running slower generic array diff", "preCID", pre.Code(), "curCID", cur.Code())
+		}
 		if err := diff.CompareArray(preP.array(), curP.array(), diffContainer); err != nil {
 			return nil, fmt.Errorf("diffing deal states: %w", err)
 		}
@@ -114,8 +117,11 @@ func DiffDealStates(ctx context.Context, store adt.Store, pre, cur State) (*Deal
 	}
 
 	diffContainer := NewMarketStatesDiffContainer(preS, curS)
-	if requiresLegacyDiffing(pre, cur, preOpts, curOpts) {
-		log.Warn("actor AMT opts differ, running slower generic array diff", "preCID", pre.Code(), "curCID", cur.Code())
+	legacy := requiresLegacyDiffing(pre, cur, preOpts, curOpts)
+	if diff.ArrayStrategy(ctx, "market_states", legacy, preS.array(), curS.array()) == diff.StrategyLegacy {
+		if legacy {
+			log.Warn("actor AMT opts differ, running slower generic array diff", "preCID", pre.Code(), "curCID", cur.Code())
+		}
 		if err := diff.CompareArray(preS.array(), curS.array(), diffContainer); err != nil {
 			return nil, fmt.Errorf("diffing deal states: %w", err)
 		}
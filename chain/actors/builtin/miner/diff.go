@@ -2,6 +2,9 @@ package miner
 
 import (
 	"context"
+	"runtime"
+	"sort"
+	"sync"
 
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"go.opentelemetry.io/otel/api/global"
@@ -41,19 +44,17 @@ func DiffPreCommits(ctx context.Context, store adt.Store, pre, cur State) (*PreC
 	}
 
 	diffContainer := NewPreCommitDiffContainer(pre, cur)
-	if mapRequiresLegacyDiffing(pre, cur, preOpts, curOpts) {
-		if span.IsRecording() {
-			span.SetAttribute("diff", "legacy")
-		}
+	strategy := diff.MapStrategy(ctx, "miner_precommits", mapRequiresLegacyDiffing(pre, cur, preOpts, curOpts))
+	if span.IsRecording() {
+		span.SetAttribute("diff", strategy)
+	}
+	if strategy == diff.StrategyLegacy {
 		err = diff.CompareMap(prep, curp, diffContainer)
 		if err != nil {
 			return nil, xerrors.Errorf("diff miner precommit: %w", err)
 		}
 		return diffContainer.Results, nil
 	}
-	if span.IsRecording() {
-		span.SetAttribute("diff", "fast")
-	}
 
 	changes, err := diff.Hamt(ctx, prep, curp, store, store, hamt.UseHashFunction(hamt.HashFunc(preOpts.HashFunc)), hamt.UseTreeBitWidth(preOpts.Bitwidth))
 	if err != nil {
@@ -138,44 +139,33 @@ func DiffSectors(ctx context.Context, store adt.Store, pre, cur State) (*SectorC
 	preBw := pre.SectorsAmtBitwidth()
 	curBw := cur.SectorsAmtBitwidth()
 	diffContainer := NewSectorDiffContainer(pre, cur)
-	if arrayRequiresLegacyDiffing(pre, cur, preBw, curBw) {
-		if span.IsRecording() {
-			span.SetAttribute("diff", "legacy")
-		}
+	strategy := diff.ArrayStrategy(ctx, "miner_sectors", arrayRequiresLegacyDiffing(pre, cur, preBw, curBw), pres, curs)
+	if span.IsRecording() {
+		span.SetAttribute("diff", strategy)
+	}
+	if strategy == diff.StrategyLegacy {
 		err = diff.CompareArray(pres, curs, diffContainer)
 		if err != nil {
 			return nil, xerrors.Errorf("diff miner sectors: %w", err)
 		}
-		return diffContainer.Results, nil
-	}
-	changes, err := diff.Amt(ctx, pres, curs, store, store, amt.UseTreeBitWidth(uint(preBw)))
-	if err != nil {
-		return nil, err
-	}
-	if span.IsRecording() {
-		span.SetAttribute("diff", "fast")
+	} else {
+		changes, err := diff.Amt(ctx, pres, curs, store, store, amt.UseTreeBitWidth(uint(preBw)))
+		if err != nil {
+			return nil, err
+		}
+		diffContainer.changes = changes
 	}
 
-	for _, change := range changes {
-		switch change.Type {
-		case amt.Add:
-			if err := diffContainer.Add(change.Key, change.After); err != nil {
-				return nil, err
-			}
-		case amt.Remove:
-			if err := diffContainer.Remove(change.Key, change.Before); err != nil {
-				return nil, err
-			}
-		case amt.Modify:
-			if err := diffContainer.Modify(change.Key, change.Before, change.After); err != nil {
-				return nil, err
-			}
-		}
+	if err := diffContainer.decodeChanges(ctx); err != nil {
+		return nil, err
 	}
 
 	return diffContainer.Results, nil
 }
 
+// SectorDiffWorkers is the maximum number of goroutines used to decode the sectors that changed between two miner states.
+var SectorDiffWorkers = runtime.NumCPU()
+
 func NewSectorDiffContainer(pre, cur State) *sectorDiffContainer {
 	return &sectorDiffContainer{
 		Results: new(SectorChanges),
@@ -184,47 +174,154 @@ func NewSectorDiffContainer(pre, cur State) *sectorDiffContainer {
 	}
 }
 
+// sectorDiffContainer gathers the raw sector changes between two states. Decoding is deferred until all changes have
+// been collected so it can be spread over multiple goroutines.
 type sectorDiffContainer struct {
 	Results    *SectorChanges
 	pre, after State
+	changes    []*amt.Change
 }
 
 func (m *sectorDiffContainer) Add(key uint64, val *cbg.Deferred) error {
-	si, err := m.after.decodeSectorOnChainInfo(val)
-	if err != nil {
-		return xerrors.Errorf("sector diff container add: %w", err)
-	}
-	m.Results.Added = append(m.Results.Added, si)
+	m.changes = append(m.changes, &amt.Change{
+		Type:  amt.Add,
+		Key:   key,
+		After: copyDeferred(val),
+	})
 	return nil
 }
 
 func (m *sectorDiffContainer) Modify(key uint64, from, to *cbg.Deferred) error {
-	siFrom, err := m.pre.decodeSectorOnChainInfo(from)
-	if err != nil {
-		return xerrors.Errorf("sector diff container modify from: %w", err)
+	m.changes = append(m.changes, &amt.Change{
+		Type:   amt.Modify,
+		Key:    key,
+		Before: copyDeferred(from),
+		After:  copyDeferred(to),
+	})
+	return nil
+}
+
+func (m *sectorDiffContainer) Remove(key uint64, val *cbg.Deferred) error {
+	m.changes = append(m.changes, &amt.Change{
+		Type:   amt.Remove,
+		Key:    key,
+		Before: copyDeferred(val),
+	})
+	return nil
+}
+
+type decodedSectorChange struct {
+	from, to SectorOnChainInfo
+	err      error
+}
+
+// decodeChanges decodes the collected changes concurrently and appends them to the results ordered by sector number
+// so that output is deterministic regardless of the diffing strategy or scheduling of workers.
+func (m *sectorDiffContainer) decodeChanges(ctx context.Context) error {
+	if len(m.changes) == 0 {
+		return nil
 	}
 
-	siTo, err := m.after.decodeSectorOnChainInfo(to)
-	if err != nil {
-		return xerrors.Errorf("sector diff container modify to: %w", err)
+	sort.Slice(m.changes, func(i, j int) bool {
+		return m.changes[i].Key < m.changes[j].Key
+	})
+
+	workers := SectorDiffWorkers
+	if workers > len(m.changes) {
+		workers = len(m.changes)
 	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	decoded := make([]decodedSectorChange, len(m.changes))
+	work := make(chan int)
 
-	if siFrom.Expiration != siTo.Expiration {
-		m.Results.Extended = append(m.Results.Extended, SectorExtensions{
-			From: siFrom,
-			To:   siTo,
-		})
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				decoded[i] = m.decodeChange(m.changes[i])
+			}
+		}()
 	}
+
+feed:
+	for i := range m.changes {
+		select {
+		case <-ctx.Done():
+			break feed
+		case work <- i:
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i, change := range m.changes {
+		d := decoded[i]
+		if d.err != nil {
+			return d.err
+		}
+		switch change.Type {
+		case amt.Add:
+			m.Results.Added = append(m.Results.Added, d.to)
+		case amt.Remove:
+			m.Results.Removed = append(m.Results.Removed, d.from)
+		case amt.Modify:
+			if d.from.Expiration != d.to.Expiration {
+				m.Results.Extended = append(m.Results.Extended, SectorExtensions{
+					From: d.from,
+					To:   d.to,
+				})
+			}
+		}
+	}
+
 	return nil
 }
 
-func (m *sectorDiffContainer) Remove(key uint64, val *cbg.Deferred) error {
-	si, err := m.pre.decodeSectorOnChainInfo(val)
-	if err != nil {
-		return xerrors.Errorf("sector diff container remove: %w", err)
+func (m *sectorDiffContainer) decodeChange(change *amt.Change) decodedSectorChange {
+	var d decodedSectorChange
+	switch change.Type {
+	case amt.Add:
+		d.to, d.err = m.after.decodeSectorOnChainInfo(change.After)
+		if d.err != nil {
+			d.err = xerrors.Errorf("sector diff container add: %w", d.err)
+		}
+	case amt.Remove:
+		d.from, d.err = m.pre.decodeSectorOnChainInfo(change.Before)
+		if d.err != nil {
+			d.err = xerrors.Errorf("sector diff container remove: %w", d.err)
+		}
+	case amt.Modify:
+		d.from, d.err = m.pre.decodeSectorOnChainInfo(change.Before)
+		if d.err != nil {
+			d.err = xerrors.Errorf("sector diff container modify from: %w", d.err)
+			return d
+		}
+		d.to, d.err = m.after.decodeSectorOnChainInfo(change.After)
+		if d.err != nil {
+			d.err = xerrors.Errorf("sector diff container modify to: %w", d.err)
+		}
 	}
-	m.Results.Removed = append(m.Results.Removed, si)
-	return nil
+	return d
+}
+
+// copyDeferred returns a copy of val. Legacy array diffing reuses the same value between callbacks so its raw bytes
+// must be copied before they can be retained.
+func copyDeferred(val *cbg.Deferred) *cbg.Deferred {
+	if val == nil {
+		return nil
+	}
+	raw := make([]byte, len(val.Raw))
+	copy(raw, val.Raw)
+	return &cbg.Deferred{Raw: raw}
 }
 
 func arrayRequiresLegacyDiffing(pre, cur State, pOpts, cOpts int) bool {
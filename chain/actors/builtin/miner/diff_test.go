@@ -0,0 +1,101 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// fakeSectorState decodes sectors encoded as two bytes: sector number followed by expiration.
+type fakeSectorState struct {
+	State
+}
+
+var errBadSector = errors.New("bad sector")
+
+func (fakeSectorState) decodeSectorOnChainInfo(val *cbg.Deferred) (SectorOnChainInfo, error) {
+	if len(val.Raw) != 2 {
+		return SectorOnChainInfo{}, errBadSector
+	}
+	return SectorOnChainInfo{
+		SectorNumber: abi.SectorNumber(val.Raw[0]),
+		Expiration:   abi.ChainEpoch(val.Raw[1]),
+	}, nil
+}
+
+func sector(num, expiration byte) *cbg.Deferred {
+	return &cbg.Deferred{Raw: []byte{num, expiration}}
+}
+
+func sectorNumbers(infos []SectorOnChainInfo) []abi.SectorNumber {
+	var nums []abi.SectorNumber
+	for _, info := range infos {
+		nums = append(nums, info.SectorNumber)
+	}
+	return nums
+}
+
+func TestSectorDiffContainerOrdering(t *testing.T) {
+	defer func(w int) { SectorDiffWorkers = w }(SectorDiffWorkers)
+
+	for _, workers := range []int{0, 1, 3, 64} {
+		SectorDiffWorkers = workers
+
+		m := NewSectorDiffContainer(fakeSectorState{}, fakeSectorState{})
+		require.NoError(t, m.Add(9, sector(9, 1)))
+		require.NoError(t, m.Remove(4, sector(4, 1)))
+		require.NoError(t, m.Modify(7, sector(7, 1), sector(7, 5)))
+		require.NoError(t, m.Add(2, sector(2, 1)))
+		require.NoError(t, m.Modify(3, sector(3, 1), sector(3, 1))) // expiration unchanged
+		require.NoError(t, m.Remove(1, sector(1, 1)))
+		require.NoError(t, m.Modify(5, sector(5, 2), sector(5, 8)))
+
+		require.NoError(t, m.decodeChanges(context.Background()), "workers=%d", workers)
+
+		assert.Equal(t, []abi.SectorNumber{2, 9}, sectorNumbers(m.Results.Added), "workers=%d", workers)
+		assert.Equal(t, []abi.SectorNumber{1, 4}, sectorNumbers(m.Results.Removed), "workers=%d", workers)
+		require.Len(t, m.Results.Extended, 2, "workers=%d", workers)
+		assert.Equal(t, abi.SectorNumber(5), m.Results.Extended[0].To.SectorNumber)
+		assert.Equal(t, abi.ChainEpoch(8), m.Results.Extended[0].To.Expiration)
+		assert.Equal(t, abi.SectorNumber(7), m.Results.Extended[1].From.SectorNumber)
+		assert.Equal(t, abi.ChainEpoch(1), m.Results.Extended[1].From.Expiration)
+	}
+}
+
+func TestSectorDiffContainerCopiesValues(t *testing.T) {
+	m := NewSectorDiffContainer(fakeSectorState{}, fakeSectorState{})
+
+	// legacy diffing reuses the deferred value between callbacks
+	val := sector(1, 1)
+	require.NoError(t, m.Add(1, val))
+	val.Raw[0], val.Raw[1] = 2, 2
+	require.NoError(t, m.Add(2, val))
+
+	require.NoError(t, m.decodeChanges(context.Background()))
+	assert.Equal(t, []abi.SectorNumber{1, 2}, sectorNumbers(m.Results.Added))
+}
+
+func TestSectorDiffContainerDecodeError(t *testing.T) {
+	m := NewSectorDiffContainer(fakeSectorState{}, fakeSectorState{})
+	require.NoError(t, m.Add(1, sector(1, 1)))
+	require.NoError(t, m.Add(2, &cbg.Deferred{Raw: []byte{2}}))
+
+	err := m.decodeChanges(context.Background())
+	assert.True(t, errors.Is(err, errBadSector))
+}
+
+func TestSectorDiffContainerCancelled(t *testing.T) {
+	m := NewSectorDiffContainer(fakeSectorState{}, fakeSectorState{})
+	require.NoError(t, m.Add(1, sector(1, 1)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.decodeChanges(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -1,6 +1,7 @@
 package miner
 
 import (
+	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-bitfield"
@@ -29,6 +30,28 @@ func AllPartSectors(mas State, sget func(Partition) (bitfield.BitField, error))
 	return bitfield.MultiMerge(parts...)
 }
 
+// LoadPreCommits returns every sector precommitted by the miner that has not yet been proven or expired.
+func LoadPreCommits(mas State) ([]SectorPreCommitOnChainInfo, error) {
+	precommits, err := mas.precommits()
+	if err != nil {
+		return nil, xerrors.Errorf("loading precommits: %w", err)
+	}
+
+	var out []SectorPreCommitOnChainInfo
+	var val cbg.Deferred
+	if err := precommits.ForEach(&val, func(key string) error {
+		info, err := mas.decodeSectorPreCommitOnChainInfo(&val)
+		if err != nil {
+			return xerrors.Errorf("decoding precommit %x: %w", key, err)
+		}
+		out = append(out, info)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SealProofTypeFromSectorSize returns preferred seal proof type for creating
 // new miner actors and new sectors
 func SealProofTypeFromSectorSize(ssize abi.SectorSize, nv network.Version) (abi.RegisteredSealProof, error) {
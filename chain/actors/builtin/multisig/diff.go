@@ -50,7 +50,7 @@ func DiffPendingTransactions(ctx context.Context, store adt.Store, pre, cur Stat
 		return nil, err
 	}
 	diffContainer := NewTransactionDiffContainer(pre, cur)
-	if requiresLegacyDiffing(pre, cur, preOpts, curOpts) {
+	if diff.MapStrategy(ctx, "multisig_transactions", requiresLegacyDiffing(pre, cur, preOpts, curOpts)) == diff.StrategyLegacy {
 		if err := diff.CompareMap(pret, curt, diffContainer); err != nil {
 			return nil, err
 		}
@@ -53,7 +53,7 @@ func DiffClaims(ctx context.Context, store adt.Store, pre, cur State) (*ClaimCha
 
 	diffContainer := NewClaimDiffContainer(pre, cur)
 
-	if requiresLegacyDiffing(pre, cur, preOpts, curOpts) {
+	if diff.MapStrategy(ctx, "power_claims", requiresLegacyDiffing(pre, cur, preOpts, curOpts)) == diff.StrategyLegacy {
 		if err := diff.CompareMap(prec, curc, diffContainer); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,266 @@
+package chain
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// A CatchUpStorage reports how far a job has indexed and records the ranges of tipsets walked to catch up with the
+// chain head as walk jobs, so a catch up that is interrupted can be resumed when the job restarts.
+type CatchUpStorage interface {
+	WalkJobStorage
+
+	// LatestIndexedHeight returns the greatest height for which reporter has completed any of tasks, or zero if it has
+	// completed none.
+	LatestIndexedHeight(ctx context.Context, reporter string, tasks []string) (int64, error)
+
+	// IncompleteWalkJobs returns the walk jobs with the given name that have not reached the end of their range, in
+	// the order they were created.
+	IncompleteWalkJobs(ctx context.Context, name string) ([]*visormodel.WalkJob, error)
+}
+
+// A WalkObserverFunc returns a new observer for a catch up walk that reports each committed tipset to cursor.
+type WalkObserverFunc func(cursor *WalkCursor) (TipSetObserver, error)
+
+// catchUpSuffix is appended to the name of a job to name the walk jobs that record its catch up ranges.
+const catchUpSuffix = "_catchup"
+
+// NewCatchUpWatcher creates a CatchUpWatcher for the job called name that follows the chain head using watcher while
+// walking the tipsets missed since the job last indexed any of tasks. Each range is walked with a new observer returned
+// by newWalkObs, which must not be the observer used by watcher.
+func NewCatchUpWatcher(watcher *Watcher, newWalkObs WalkObserverFunc, opener lens.APIOpener, store CatchUpStorage, name string, tasks []string) *CatchUpWatcher {
+	return &CatchUpWatcher{
+		watcher:    watcher,
+		newWalkObs: newWalkObs,
+		opener:     opener,
+		store:      store,
+		name:       name,
+		tasks:      tasks,
+		droppedCh:  make(chan struct{}, 1),
+	}
+}
+
+// CatchUpWatcher is a task that indexes any tipsets missed since indexing last stopped before following the chain
+// head. The chain head is followed from the start so no head events are missed while the missing tipsets are walked.
+// Tipsets dropped by the watcher's head event queue, reported with Dropped, are walked in the same way.
+type CatchUpWatcher struct {
+	watcher    *Watcher
+	newWalkObs WalkObserverFunc
+	opener     lens.APIOpener
+	store      CatchUpStorage
+	name       string        // name of the job, recorded as the reporter of its processing reports
+	tasks      []string      // tasks performed by the watcher, used to find where indexing stopped
+	caughtUp   bool          // true once the missing tipsets have been walked so a restart does not look for them again
+	droppedCh  chan struct{} // signaled when a tipset is dropped
+
+	mu          sync.Mutex // protects following fields
+	dropped     bool       // true if tipsets have been dropped since the last walk of dropped tipsets
+	droppedFrom int64      // lowest height of a dropped tipset
+	droppedTo   int64      // highest height of a dropped tipset
+}
+
+func (c *CatchUpWatcher) Params() map[string]interface{} {
+	out := c.watcher.Params()
+	out["catchUp"] = true
+	return out
+}
+
+// Run walks any missing tipsets and follows the chain head, blocking until the context is done or an error occurs.
+func (c *CatchUpWatcher) Run(ctx context.Context) error {
+	var jobs []*visormodel.WalkJob
+	if !c.caughtUp {
+		// The ranges to walk are found before the watcher starts so the tipsets it indexes are not taken as progress
+		var err error
+		jobs, err = c.catchUpJobs(ctx)
+		if err != nil {
+			return xerrors.Errorf("catch up: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- c.watcher.Run(ctx)
+	}()
+
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- c.walkMissed(ctx, jobs)
+	}()
+
+	select {
+	case err := <-watchErr:
+		// Stop the walk before returning so the observer is closed
+		cancel()
+		<-walkErr
+		return err
+	case err := <-walkErr:
+		// The walk only stops before the watcher if it fails
+		cancel()
+		<-watchErr
+		return xerrors.Errorf("catch up: %w", err)
+	}
+}
+
+// Dropped records that the watcher's head event queue dropped he, so the tipset it applied is walked instead. Only
+// applied tipsets are walked. Dropped does not block so it may be passed to HeadEventQueue.OnDrop.
+func (c *CatchUpWatcher) Dropped(he *HeadEvent) {
+	if he.Type != HeadEventApply || he.TipSet == nil {
+		return
+	}
+	height := int64(he.TipSet.Height())
+
+	c.mu.Lock()
+	if !c.dropped || height < c.droppedFrom {
+		c.droppedFrom = height
+	}
+	if !c.dropped || height > c.droppedTo {
+		c.droppedTo = height
+	}
+	c.dropped = true
+	c.mu.Unlock()
+
+	select {
+	case c.droppedCh <- struct{}{}:
+	default:
+	}
+}
+
+// walkMissed walks the catch up ranges in jobs and then the tipsets dropped by the watcher's head event queue as they
+// are reported, until the context is done or a walk fails.
+func (c *CatchUpWatcher) walkMissed(ctx context.Context, jobs []*visormodel.WalkJob) error {
+	if err := c.walk(ctx, jobs); err != nil {
+		return err
+	}
+	c.caughtUp = true
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.droppedCh:
+		}
+
+		job, err := c.droppedJob(ctx)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			continue
+		}
+		if err := c.walk(ctx, []*visormodel.WalkJob{job}); err != nil {
+			return err
+		}
+	}
+}
+
+// droppedJob records the range of heights of the tipsets dropped since it was last called as a catch up walk job so
+// the range is resumed if the walk is interrupted. The range may include tipsets that were not dropped, which are
+// indexed again. It returns nil if no tipsets have been dropped.
+func (c *CatchUpWatcher) droppedJob(ctx context.Context) (*visormodel.WalkJob, error) {
+	c.mu.Lock()
+	dropped, from, to := c.dropped, c.droppedFrom, c.droppedTo
+	c.dropped = false
+	c.mu.Unlock()
+	if !dropped {
+		return nil, nil
+	}
+
+	log.Warnw("walking tipsets dropped by the head event queue", "from", from, "to", to)
+	job := &visormodel.WalkJob{
+		Name:      c.name + catchUpSuffix,
+		Tasks:     c.tasks,
+		MinHeight: from,
+		MaxHeight: to,
+		Direction: WalkAscending,
+	}
+	if err := c.store.CreateWalkJob(ctx, job); err != nil {
+		return nil, xerrors.Errorf("record dropped tipsets: %w", err)
+	}
+	return job, nil
+}
+
+// catchUpJobs returns the catch up ranges left incomplete by earlier runs of the job followed by a new range from the
+// latest height indexed to the chain head. The new range is recorded so it is resumed if this run is interrupted.
+func (c *CatchUpWatcher) catchUpJobs(ctx context.Context) ([]*visormodel.WalkJob, error) {
+	name := c.name + catchUpSuffix
+	jobs, err := c.store.IncompleteWalkJobs(ctx, name)
+	if err != nil {
+		return nil, xerrors.Errorf("incomplete catch ups: %w", err)
+	}
+	for _, job := range jobs {
+		log.Infow("resuming interrupted catch up", "walk_id", job.ID, "from", job.MinHeight, "to", job.MaxHeight)
+	}
+
+	indexed, err := c.store.LatestIndexedHeight(ctx, c.name, c.tasks)
+	if err != nil {
+		return nil, xerrors.Errorf("latest indexed height: %w", err)
+	}
+	if indexed == 0 {
+		log.Warnw("no indexed tipsets found, not catching up", "reporter", c.name)
+		return jobs, nil
+	}
+
+	// An interrupted range may end beyond the heights indexed so far
+	from := indexed + 1
+	for _, job := range jobs {
+		if job.MaxHeight >= from {
+			from = job.MaxHeight + 1
+		}
+	}
+
+	node, closer, err := c.opener.Open(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	// The range ends at the current chain head, which is also the first tipset the watcher will index, so the walk may
+	// index it a second time.
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get chain head: %w", err)
+	}
+	to := int64(head.Height())
+	if from > to {
+		return jobs, nil
+	}
+
+	job := &visormodel.WalkJob{
+		Name:      name,
+		Tasks:     c.tasks,
+		MinHeight: from,
+		MaxHeight: to,
+		Direction: WalkDescending,
+	}
+	if err := c.store.CreateWalkJob(ctx, job); err != nil {
+		return nil, xerrors.Errorf("record catch up: %w", err)
+	}
+	return append(jobs, job), nil
+}
+
+// walk walks each of the catch up ranges in turn, recording its progress so it can be resumed.
+func (c *CatchUpWatcher) walk(ctx context.Context, jobs []*visormodel.WalkJob) error {
+	for _, job := range jobs {
+		cursor := NewWalkCursor(c.store, job)
+		obs, err := c.newWalkObs(cursor)
+		if err != nil {
+			return xerrors.Errorf("create walk observer: %w", err)
+		}
+
+		log.Infow("catching up with chain head", "walk_id", job.ID, "from", job.MinHeight, "to", job.MaxHeight)
+		walker := NewWalker(obs, c.opener, job.MinHeight, job.MaxHeight, WalkDirectionOpt(job.Direction), WalkCursorOpt(cursor))
+		if err := walker.Run(ctx); err != nil {
+			return err
+		}
+		log.Infow("caught up with chain head", "walk_id", job.ID, "from", job.MinHeight, "to", job.MaxHeight)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package chain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// A CompletionSource reports which tasks have completed successfully.
+type CompletionSource interface {
+	// CompletedTasks returns the named tasks that have completed at heights between from and to inclusive.
+	CompletedTasks(ctx context.Context, from, to int64, tasks []string) ([]visormodel.TaskCompletion, error)
+}
+
+// tipSetSource is the part of the lens used to find the tipsets in a range of heights.
+type tipSetSource interface {
+	ChainHead(context.Context) (*types.TipSet, error)
+	ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error)
+	ChainGetTipSetByHeight(context.Context, abi.ChainEpoch, types.TipSetKey) (*types.TipSet, error)
+}
+
+// A CompletenessReport describes whether a set of tasks has completed for every tipset in a range of heights.
+type CompletenessReport struct {
+	From     int64
+	To       int64
+	Tasks    []string
+	TipSets  int            // number of tipsets in the range, null rounds have no tipset and are not counted
+	Complete bool           // true if every task has completed for every tipset in the range
+	Missing  []MissingTasks // tipsets for which some task has not completed, in order of increasing height
+}
+
+// MissingTasks lists the tasks that have not completed for a tipset.
+type MissingTasks struct {
+	Height    int64
+	StateRoot string
+	Tasks     []string
+}
+
+// CheckCompleteness reports whether each of the tasks has completed for every tipset of the canonical chain between
+// from and to inclusive. A task has completed for a tipset if it has recorded a successful report with the tipset's
+// height and parent state root, so reports for tipsets that have since been reverted are not counted.
+func CheckCompleteness(ctx context.Context, node tipSetSource, src CompletionSource, from, to int64, tasks []string) (*CompletenessReport, error) {
+	if from < 0 || to < from {
+		return nil, xerrors.Errorf("invalid height range %d-%d", from, to)
+	}
+	if len(tasks) == 0 {
+		return nil, xerrors.Errorf("no tasks given")
+	}
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get chain head: %w", err)
+	}
+	if to > int64(head.Height()) {
+		return nil, xerrors.Errorf("height %d is after the chain head at %d", to, head.Height())
+	}
+
+	completions, err := src.CompletedTasks(ctx, from, to, tasks)
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[int64]map[string]map[string]bool) // height -> state root -> task
+	for _, c := range completions {
+		if completed[c.Height] == nil {
+			completed[c.Height] = make(map[string]map[string]bool)
+		}
+		if completed[c.Height][c.StateRoot] == nil {
+			completed[c.Height][c.StateRoot] = make(map[string]bool)
+		}
+		completed[c.Height][c.StateRoot][c.Task] = true
+	}
+
+	report := &CompletenessReport{
+		From:  from,
+		To:    to,
+		Tasks: tasks,
+	}
+
+	// Returns the tipset at the end of the range or, if that is a null round, the tipset before it
+	ts, err := node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(to), head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("get tipset by height: %w", err)
+	}
+	for int64(ts.Height()) >= from {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		report.TipSets++
+		stateRoot := ts.ParentState().String()
+		done := completed[int64(ts.Height())][stateRoot]
+		var missing []string
+		for _, task := range tasks {
+			if !done[task] {
+				missing = append(missing, task)
+			}
+		}
+		if len(missing) > 0 {
+			report.Missing = append(report.Missing, MissingTasks{
+				Height:    int64(ts.Height()),
+				StateRoot: stateRoot,
+				Tasks:     missing,
+			})
+		}
+
+		if ts.Height() == 0 {
+			break
+		}
+		ts, err = node.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("get tipset: %w", err)
+		}
+	}
+
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].Height < report.Missing[j].Height })
+	report.Complete = len(report.Missing) == 0
+	return report, nil
+}
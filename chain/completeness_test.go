@@ -0,0 +1,111 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+// fakeChain is a chain of tipsets, one for each height that is not a null round.
+type fakeChain struct {
+	tipsets []*types.TipSet // in order of increasing height
+}
+
+func newFakeChain(t *testing.T, height int64, nullRounds ...int64) *fakeChain {
+	null := make(map[int64]bool)
+	for _, h := range nullRounds {
+		null[h] = true
+	}
+	c := &fakeChain{}
+	for h := int64(0); h <= height; h++ {
+		if null[h] {
+			continue
+		}
+		bh := testutil.FakeBlockHeader(t, h, testutil.RandomCid())
+		if len(c.tipsets) > 0 {
+			bh.Parents = c.tipsets[len(c.tipsets)-1].Cids()
+		}
+		ts, err := types.NewTipSet([]*types.BlockHeader{bh})
+		require.NoError(t, err)
+		c.tipsets = append(c.tipsets, ts)
+	}
+	return c
+}
+
+func (c *fakeChain) ChainHead(context.Context) (*types.TipSet, error) {
+	return c.tipsets[len(c.tipsets)-1], nil
+}
+
+func (c *fakeChain) ChainGetTipSet(_ context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	for _, ts := range c.tipsets {
+		if ts.Key() == tsk {
+			return ts, nil
+		}
+	}
+	return nil, xerrors.Errorf("tipset not found")
+}
+
+func (c *fakeChain) ChainGetTipSetByHeight(_ context.Context, h abi.ChainEpoch, _ types.TipSetKey) (*types.TipSet, error) {
+	for i := len(c.tipsets) - 1; i >= 0; i-- {
+		if c.tipsets[i].Height() <= h {
+			return c.tipsets[i], nil
+		}
+	}
+	return nil, xerrors.Errorf("tipset not found")
+}
+
+type fakeCompletions []visormodel.TaskCompletion
+
+func (f fakeCompletions) CompletedTasks(ctx context.Context, from, to int64, tasks []string) ([]visormodel.TaskCompletion, error) {
+	return f, nil
+}
+
+func TestCheckCompleteness(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeChain(t, 6, 3)
+
+	var done fakeCompletions
+	for _, ts := range c.tipsets {
+		for _, task := range []string{BlocksTask, MessagesTask} {
+			done = append(done, visormodel.TaskCompletion{Height: int64(ts.Height()), StateRoot: ts.ParentState().String(), Task: task})
+		}
+	}
+
+	report, err := CheckCompleteness(ctx, c, done, 1, 5, []string{BlocksTask, MessagesTask})
+	require.NoError(t, err)
+	assert.True(t, report.Complete)
+	assert.Equal(t, 4, report.TipSets, "null round should not be counted")
+	assert.Empty(t, report.Missing)
+
+	// Remove the messages task at height 2 and record it against a reverted tipset at height 4
+	var partial fakeCompletions
+	for _, d := range done {
+		if d.Task == MessagesTask && (d.Height == 2 || d.Height == 4) {
+			continue
+		}
+		partial = append(partial, d)
+	}
+	partial = append(partial, visormodel.TaskCompletion{Height: 4, StateRoot: testutil.RandomCid().String(), Task: MessagesTask})
+
+	report, err = CheckCompleteness(ctx, c, partial, 0, 6, []string{BlocksTask, MessagesTask})
+	require.NoError(t, err)
+	assert.False(t, report.Complete)
+	require.Len(t, report.Missing, 2)
+	assert.Equal(t, int64(2), report.Missing[0].Height)
+	assert.Equal(t, []string{MessagesTask}, report.Missing[0].Tasks)
+	assert.Equal(t, int64(4), report.Missing[1].Height)
+
+	_, err = CheckCompleteness(ctx, c, done, 0, 7, []string{BlocksTask})
+	assert.Error(t, err, "range after the head")
+
+	_, err = CheckCompleteness(ctx, c, done, 5, 1, []string{BlocksTask})
+	assert.Error(t, err, "inverted range")
+}
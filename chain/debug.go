@@ -0,0 +1,96 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// ExtractTask runs one of the indexer's tasks against a tipset and returns the data it extracted without persisting
+// anything, for debugging extraction. Message and actor state tasks are run as they would be when the indexer sees ts
+// and its parent: message tasks extract the messages of the parent executed by ts and actor state tasks extract the
+// actors that changed between the parent's state and the state of ts. If the indexer has an address filter, actor
+// state tasks only extract the filtered actor, which may be given by any of its addresses.
+func (t *TipSetIndexer) ExtractTask(ctx context.Context, task string, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	if p, ok := t.processors[task]; ok {
+		return p.ProcessTipSet(ctx, ts)
+	}
+
+	_, isMessageTask := t.messageProcessors[task]
+	_, isActorTask := t.actorProcessors[task]
+	if !isMessageTask && !isActorTask {
+		return nil, nil, xerrors.Errorf("indexer does not run task %s", task)
+	}
+
+	if err := t.openNode(ctx); err != nil {
+		return nil, nil, err
+	}
+	pts, err := t.node.ChainGetTipSet(ctx, ts.Parents())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("get parent tipset: %w", err)
+	}
+
+	if p, ok := t.messageProcessors[task]; ok {
+		msgs, err := t.node.GetExecutedAndBlockMessagesForTipset(ctx, ts, pts)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("get messages: %w", err)
+		}
+		return p.ProcessMessages(ctx, ts, pts, msgs.Executed, msgs.Block)
+	}
+
+	if p, ok := t.actorProcessors[task]; ok {
+		var changes map[string]types.Actor
+		if pts.Height() == 0 {
+			changes, err = t.getGenesisActors(ctx)
+		} else {
+			changes, err = t.stateChangedActors(ctx, pts.ParentState(), ts.ParentState())
+		}
+		if err != nil {
+			return nil, nil, xerrors.Errorf("get actor changes: %w", err)
+		}
+
+		if t.addressFilter != nil {
+			id, err := t.lookupID(ts, t.addressFilter.address)
+			if err != nil {
+				return nil, nil, err
+			}
+			act, ok := changes[id]
+			changes = map[string]types.Actor{}
+			if ok {
+				changes[id] = act
+			} else {
+				log.Infow("actor state did not change in tipset", "address", id, "height", ts.Height())
+			}
+		}
+		return p.ProcessActors(ctx, ts, pts, changes)
+	}
+
+	return nil, nil, xerrors.Errorf("indexer does not run task %s", task)
+}
+
+// lookupID returns the ID address of an actor in the state of ts.
+func (t *TipSetIndexer) lookupID(ts *types.TipSet, addrStr string) (string, error) {
+	addr, err := address.NewFromString(addrStr)
+	if err != nil {
+		return "", xerrors.Errorf("parse address: %w", err)
+	}
+	if addr.Protocol() == address.ID {
+		return addr.String(), nil
+	}
+
+	tree, err := state.LoadStateTree(t.node.Store(), ts.ParentState())
+	if err != nil {
+		return "", xerrors.Errorf("load state tree: %w", err)
+	}
+	id, err := tree.LookupID(addr)
+	if err != nil {
+		return "", xerrors.Errorf("lookup id of %s: %w", addr, err)
+	}
+	return id.String(), nil
+}
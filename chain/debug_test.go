@@ -0,0 +1,45 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+type fixedProcessor struct {
+	seen *types.TipSet
+}
+
+func (p *fixedProcessor) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	p.seen = ts
+	return model.PersistableList{}, &visormodel.ProcessingReport{Height: int64(ts.Height())}, nil
+}
+
+func (p *fixedProcessor) Close() error {
+	return nil
+}
+
+func TestExtractTask(t *testing.T) {
+	ctx := context.Background()
+	p := &fixedProcessor{}
+	idx, err := NewTipSetIndexer(nil, nil, 0, "debug", nil, TipSetProcessorOpt("fixed", p))
+	require.NoError(t, err)
+
+	ts := testutil.FakeTipset(t)
+	data, report, err := idx.ExtractTask(ctx, "fixed", ts)
+	require.NoError(t, err)
+	assert.NotNil(t, data)
+	assert.Equal(t, int64(ts.Height()), report.Height)
+	assert.Equal(t, ts, p.seen)
+
+	// tasks the indexer was not configured with are refused without opening the lens
+	_, _, err = idx.ExtractTask(ctx, MessagesTask, ts)
+	require.Error(t, err)
+}
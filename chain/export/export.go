@@ -0,0 +1,184 @@
+// Package export writes ranges of the chain to CAR files that can be read by the CAR lens.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+)
+
+var log = logging.Logger("visor/export")
+
+// ChainReader is the subset of the lens API needed to export the chain.
+type ChainReader interface {
+	ChainHead(context.Context) (*types.TipSet, error)
+	ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error)
+	ChainGetTipSetByHeight(context.Context, abi.ChainEpoch, types.TipSetKey) (*types.TipSet, error)
+	ChainReadObj(ctx context.Context, obj cid.Cid) ([]byte, error)
+}
+
+// Config controls what is exported.
+type Config struct {
+	From int64 // lowest height to export
+	To   int64 // highest height to export
+
+	// IncludeState exports the state tree of every exported tipset. Without state the export can only be used by
+	// tasks that read block headers, messages and receipts.
+	IncludeState bool
+}
+
+// Result summarises an export.
+type Result struct {
+	Head    types.TipSetKey // key of the highest exported tipset, which is the root of the CAR
+	TipSets int
+	Blocks  int // number of IPLD blocks written
+	Bytes   int64
+}
+
+// Export writes the tipsets between cfg.From and cfg.To as a CAR file to w. Every tipset in the range is exported
+// with its block headers, messages and the receipts of its parent's messages. The parent of the lowest tipset is
+// exported too, since tasks read the parent of the tipset they process. The root of the CAR is the highest tipset
+// so the CAR lens starts its chain from there.
+func Export(ctx context.Context, node ChainReader, w io.Writer, cfg Config) (*Result, error) {
+	if cfg.From > cfg.To {
+		return nil, xerrors.Errorf("from height %d is above to height %d", cfg.From, cfg.To)
+	}
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get chain head: %w", err)
+	}
+	if cfg.To > int64(head.Height()) {
+		return nil, xerrors.Errorf("to height %d is above the chain head at %d", cfg.To, head.Height())
+	}
+
+	ts, err := node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(cfg.To), head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("get tipset at height %d: %w", cfg.To, err)
+	}
+
+	bw := bufio.NewWriter(w)
+	e := &exporter{
+		node: node,
+		w:    bw,
+		seen: cid.NewSet(),
+		res:  &Result{Head: ts.Key()},
+	}
+
+	if err := car.WriteHeader(&car.CarHeader{Roots: ts.Cids(), Version: 1}, bw); err != nil {
+		return nil, xerrors.Errorf("write header: %w", err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := e.exportTipSet(ctx, ts, cfg.IncludeState); err != nil {
+			return nil, xerrors.Errorf("export tipset at height %d: %w", ts.Height(), err)
+		}
+		e.res.TipSets++
+
+		if int64(ts.Height()) < cfg.From || ts.Height() == 0 {
+			break
+		}
+		ts, err = node.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("get parent tipset: %w", err)
+		}
+		if int64(ts.Height())%1000 == 0 {
+			log.Infow("exporting", "height", ts.Height(), "tipsets", e.res.TipSets, "blocks", e.res.Blocks)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, xerrors.Errorf("flush: %w", err)
+	}
+	return e.res, nil
+}
+
+type exporter struct {
+	node ChainReader
+	w    io.Writer
+	seen *cid.Set
+	res  *Result
+}
+
+func (e *exporter) exportTipSet(ctx context.Context, ts *types.TipSet, includeState bool) error {
+	for _, bh := range ts.Blocks() {
+		if !e.seen.Visit(bh.Cid()) {
+			continue
+		}
+		data, err := bh.Serialize()
+		if err != nil {
+			return xerrors.Errorf("serialize block header: %w", err)
+		}
+		if err := e.write(bh.Cid(), data); err != nil {
+			return err
+		}
+
+		roots := []cid.Cid{bh.Messages, bh.ParentMessageReceipts}
+		if includeState {
+			roots = append(roots, bh.ParentStateRoot)
+		}
+		for _, root := range roots {
+			if err := e.exportDAG(ctx, root); err != nil {
+				return xerrors.Errorf("export %s: %w", root, err)
+			}
+		}
+	}
+	return nil
+}
+
+// exportDAG writes every dag-cbor block reachable from root that has not already been written.
+func (e *exporter) exportDAG(ctx context.Context, root cid.Cid) error {
+	stack := []cid.Cid{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Only dag-cbor blocks are held in the chain store, other links such as piece commitments are not followed.
+		// Identity hashed blocks carry their data in the cid.
+		if c.Prefix().Codec != cid.DagCBOR || c.Prefix().MhType == mh.IDENTITY {
+			continue
+		}
+		if !e.seen.Visit(c) {
+			continue
+		}
+
+		data, err := e.node.ChainReadObj(ctx, c)
+		if err != nil {
+			return xerrors.Errorf("read %s: %w", c, err)
+		}
+		if err := e.write(c, data); err != nil {
+			return err
+		}
+
+		if err := cbg.ScanForLinks(bytes.NewReader(data), func(link cid.Cid) {
+			stack = append(stack, link)
+		}); err != nil {
+			return xerrors.Errorf("scan %s for links: %w", c, err)
+		}
+	}
+	return nil
+}
+
+func (e *exporter) write(c cid.Cid, data []byte) error {
+	if err := carutil.LdWrite(e.w, c.Bytes(), data); err != nil {
+		return xerrors.Errorf("write %s: %w", c, err)
+	}
+	e.res.Blocks++
+	e.res.Bytes += int64(len(data))
+	return nil
+}
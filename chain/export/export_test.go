@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/ipld/go-car"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+type fakeChain struct {
+	objs    map[cid.Cid][]byte
+	tipsets []*types.TipSet // indexed by height
+}
+
+func (f *fakeChain) ChainHead(context.Context) (*types.TipSet, error) {
+	return f.tipsets[len(f.tipsets)-1], nil
+}
+
+func (f *fakeChain) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	for _, ts := range f.tipsets {
+		if ts.Key() == tsk {
+			return ts, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+func (f *fakeChain) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return f.tipsets[h], nil
+}
+
+func (f *fakeChain) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	data, ok := f.objs[c]
+	if !ok {
+		return nil, io.EOF
+	}
+	return data, nil
+}
+
+func (f *fakeChain) put(t *testing.T, v interface{}) cid.Cid {
+	nd, err := cbor.WrapObject(v, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	f.objs[nd.Cid()] = nd.RawData()
+	return nd.Cid()
+}
+
+// newFakeChain returns a chain of tipsets from genesis to height. Every tipset shares a state node.
+func newFakeChain(t *testing.T, height int) *fakeChain {
+	f := &fakeChain{objs: map[cid.Cid][]byte{}}
+	shared := f.put(t, map[string]interface{}{"shared": true})
+
+	var parents []cid.Cid
+	for h := 0; h <= height; h++ {
+		bh := testutil.FakeBlockHeader(t, int64(h), f.put(t, map[string]interface{}{"state": h, "link": shared}))
+		bh.Parents = parents
+		bh.Messages = f.put(t, map[string]interface{}{"messages": f.put(t, map[string]interface{}{"message": h})})
+		bh.ParentMessageReceipts = f.put(t, map[string]interface{}{"receipts": h})
+
+		ts, err := types.NewTipSet([]*types.BlockHeader{bh})
+		require.NoError(t, err)
+		f.tipsets = append(f.tipsets, ts)
+		parents = ts.Cids()
+	}
+	return f
+}
+
+func readCAR(t *testing.T, buf *bytes.Buffer) (*car.CarHeader, map[cid.Cid]bool) {
+	cr, err := car.NewCarReader(buf)
+	require.NoError(t, err)
+	cids := map[cid.Cid]bool{}
+	for {
+		blk, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.False(t, cids[blk.Cid()], "block written twice")
+		cids[blk.Cid()] = true
+	}
+	return cr.Header, cids
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeChain(t, 5)
+
+	var buf bytes.Buffer
+	res, err := Export(ctx, f, &buf, Config{From: 2, To: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 4, res.TipSets) // heights 4 to 2 and the parent at 1
+	assert.Equal(t, f.tipsets[4].Key(), res.Head)
+
+	header, cids := readCAR(t, &buf)
+	assert.Equal(t, f.tipsets[4].Cids(), header.Roots)
+	assert.Equal(t, res.Blocks, len(cids))
+
+	for h, ts := range f.tipsets {
+		bh := ts.Blocks()[0]
+		exported := h >= 1 && h <= 4
+		assert.Equal(t, exported, cids[bh.Cid()], "header at height %d", h)
+		assert.Equal(t, exported, cids[bh.Messages], "messages at height %d", h)
+		assert.Equal(t, exported, cids[bh.ParentMessageReceipts], "receipts at height %d", h)
+		assert.False(t, cids[bh.ParentStateRoot], "state at height %d", h)
+	}
+
+	// each tipset has a header, two message nodes and a receipt node
+	assert.Equal(t, 4*4, len(cids))
+}
+
+func TestExportState(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeChain(t, 3)
+
+	var buf bytes.Buffer
+	res, err := Export(ctx, f, &buf, Config{From: 3, To: 3, IncludeState: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.TipSets)
+
+	_, cids := readCAR(t, &buf)
+	for _, h := range []int{2, 3} {
+		assert.True(t, cids[f.tipsets[h].Blocks()[0].ParentStateRoot], "state at height %d", h)
+	}
+	// two tipsets each with four nodes and a state root, plus the state node they share
+	assert.Equal(t, 2*5+1, len(cids))
+
+	_, err = Export(ctx, f, &buf, Config{From: 3, To: 4})
+	require.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package chain
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
+)
+
+// A FeeMarketObserver is a job that records, each time the head of the node the lens is connected to advances, the gas
+// premiums of the messages included in the head compared with those of the messages still pending in the node's message
+// pool. Epochs that become the head and are replaced between two polls are not observed since the message pool can only
+// be observed as it is now.
+type FeeMarketObserver struct {
+	opener   lens.APIOpener
+	storage  model.Storage
+	name     string        // recorded as the observer of each observation
+	interval time.Duration // time between polls of the chain head
+}
+
+func NewFeeMarketObserver(opener lens.APIOpener, storage model.Storage, name string, interval time.Duration) *FeeMarketObserver {
+	return &FeeMarketObserver{
+		opener:   opener,
+		storage:  storage,
+		name:     name,
+		interval: interval,
+	}
+}
+
+func (o *FeeMarketObserver) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["interval"] = o.interval.String()
+	return out
+}
+
+// Run records the fee market of each new head until the context is done.
+func (o *FeeMarketObserver) Run(ctx context.Context) error {
+	node, closer, err := o.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	mpool, ok := node.(lens.MempoolAPI)
+	if !ok {
+		return xerrors.Errorf("lens does not report pending messages")
+	}
+
+	var lastHeight int64 = -1
+	for {
+		start := time.Now()
+		head, err := node.ChainHead(ctx)
+		if err != nil {
+			return xerrors.Errorf("get chain head: %w", err)
+		}
+
+		if int64(head.Height()) > lastHeight {
+			obs, err := o.observe(ctx, node, mpool, head)
+			if err != nil {
+				return err
+			}
+			if err := o.storage.PersistBatch(ctx, obs); err != nil {
+				return xerrors.Errorf("persist fee market observation: %w", err)
+			}
+			log.Debugw("observed fee market", "height", obs.Height, "included", obs.IncludedMessages, "pending", obs.PendingMessages)
+			lastHeight = obs.Height
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.interval - time.Since(start)):
+		}
+	}
+}
+
+func (o *FeeMarketObserver) observe(ctx context.Context, node lens.API, mpool lens.MempoolAPI, head *types.TipSet) (*chainmodel.FeeMarketObservation, error) {
+	// The same message may be included in several blocks of the tipset
+	seen := make(map[cid.Cid]struct{})
+	var included []*types.Message
+	for _, bh := range head.Blocks() {
+		msgs, err := node.ChainGetBlockMessages(ctx, bh.Cid())
+		if err != nil {
+			return nil, xerrors.Errorf("get block messages: %w", err)
+		}
+		for i, c := range msgs.Cids {
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+			if i < len(msgs.BlsMessages) {
+				included = append(included, msgs.BlsMessages[i])
+			} else {
+				included = append(included, &msgs.SecpkMessages[i-len(msgs.BlsMessages)].Message)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	pending, err := mpool.MpoolPending(ctx, head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("get pending messages: %w", err)
+	}
+
+	return NewFeeMarketObservation(o.name, now, head, included, pending), nil
+}
+
+// NewFeeMarketObservation returns the observation made at now of the messages included in head and of those pending in
+// the message pool.
+func NewFeeMarketObservation(observer string, now time.Time, head *types.TipSet, included []*types.Message, pending []*types.SignedMessage) *chainmodel.FeeMarketObservation {
+	obs := &chainmodel.FeeMarketObservation{
+		Height:           int64(head.Height()),
+		Observer:         observer,
+		ObservedAt:       now,
+		StateRoot:        head.ParentState().String(),
+		BaseFee:          head.Blocks()[0].ParentBaseFee.String(),
+		IncludedMessages: int64(len(included)),
+		PendingMessages:  int64(len(pending)),
+	}
+
+	premiums := make([]big.Int, 0, len(included))
+	for _, m := range included {
+		premiums = append(premiums, m.GasPremium)
+	}
+	obs.IncludedMinPremium, obs.IncludedMedianPremium = premiumStats(premiums)
+
+	premiums = make([]big.Int, 0, len(pending))
+	for _, m := range pending {
+		premiums = append(premiums, m.Message.GasPremium)
+	}
+	obs.PendingMinPremium, obs.PendingMedianPremium = premiumStats(premiums)
+
+	return obs
+}
+
+// premiumStats returns the lowest and median of the premiums, or empty strings if there are none. The median of an
+// even number of premiums is the mean of the two middle premiums, rounded down.
+func premiumStats(premiums []big.Int) (string, string) {
+	if len(premiums) == 0 {
+		return "", ""
+	}
+	sort.Slice(premiums, func(i, j int) bool { return premiums[i].LessThan(premiums[j]) })
+
+	mid := len(premiums) / 2
+	median := premiums[mid]
+	if len(premiums)%2 == 0 {
+		median = big.Div(big.Add(premiums[mid-1], premiums[mid]), big.NewInt(2))
+	}
+	return premiums[0].String(), median.String()
+}
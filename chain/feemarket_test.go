@@ -0,0 +1,56 @@
+package chain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFeeMarketObservation(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	head := mustMakeTs(nil, 100, dummyCid)
+	head.Blocks()[0].ParentBaseFee = abi.NewTokenAmount(150)
+
+	msg := func(premium int64) *types.Message {
+		return &types.Message{GasPremium: big.NewInt(premium)}
+	}
+	pendingMsg := func(premium int64) *types.SignedMessage {
+		return &types.SignedMessage{Message: *msg(premium)}
+	}
+
+	t.Run("odd and even counts", func(t *testing.T) {
+		obs := NewFeeMarketObservation("observer", now, head,
+			[]*types.Message{msg(300), msg(100), msg(200)},
+			[]*types.SignedMessage{pendingMsg(90), pendingMsg(40), pendingMsg(10), pendingMsg(51)},
+		)
+
+		assert.EqualValues(t, 100, obs.Height)
+		assert.Equal(t, "observer", obs.Observer)
+		assert.Equal(t, now, obs.ObservedAt)
+		assert.Equal(t, dummyCid.String(), obs.StateRoot)
+		assert.Equal(t, "150", obs.BaseFee)
+
+		assert.EqualValues(t, 3, obs.IncludedMessages)
+		assert.Equal(t, "100", obs.IncludedMinPremium)
+		assert.Equal(t, "200", obs.IncludedMedianPremium)
+
+		assert.EqualValues(t, 4, obs.PendingMessages)
+		assert.Equal(t, "10", obs.PendingMinPremium)
+		assert.Equal(t, "45", obs.PendingMedianPremium)
+	})
+
+	t.Run("no messages", func(t *testing.T) {
+		obs := NewFeeMarketObservation("observer", now, head, nil, nil)
+
+		assert.EqualValues(t, 0, obs.IncludedMessages)
+		assert.Empty(t, obs.IncludedMinPremium)
+		assert.Empty(t, obs.IncludedMedianPremium)
+		assert.EqualValues(t, 0, obs.PendingMessages)
+		assert.Empty(t, obs.PendingMinPremium)
+		assert.Empty(t, obs.PendingMedianPremium)
+	})
+}
@@ -1,5 +1,24 @@
 package chain
 
+import (
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/account"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/cron"
+	init_ "github.com/filecoin-project/sentinel-visor/chain/actors/builtin/init"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/multisig"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/paych"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/reward"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
+)
+
 func NewAddressFilter(addr string) *AddressFilter {
 	return &AddressFilter{address: addr}
 }
@@ -11,3 +30,82 @@ type AddressFilter struct {
 func (f *AddressFilter) Allow(addr string) bool {
 	return f.address == addr
 }
+
+// ActorFamilies maps the name of each family of actors that can be selected by an ActorTypeFilter to the codes of
+// every version of its actor.
+var ActorFamilies = map[string]func() []cid.Cid{
+	"account":  account.AllCodes,
+	"cron":     cron.AllCodes,
+	"init":     init_.AllCodes,
+	"market":   market.AllCodes,
+	"miner":    miner.AllCodes,
+	"multisig": multisig.AllCodes,
+	"paych":    paych.AllCodes,
+	"power":    power.AllCodes,
+	"reward":   reward.AllCodes,
+	"verifreg": verifreg.AllCodes,
+}
+
+// NewActorTypeFilter returns a filter that allows the actors of the named families, see ActorFamilies.
+func NewActorTypeFilter(families []string) (*ActorTypeFilter, error) {
+	f := &ActorTypeFilter{codes: cid.NewSet()}
+	seen := map[string]bool{}
+	for _, family := range families {
+		family = strings.TrimSpace(family)
+		if family == "" || seen[family] {
+			continue
+		}
+		codes, ok := ActorFamilies[family]
+		if !ok {
+			return nil, xerrors.Errorf("unknown actor type %q, must be one of %s", family, strings.Join(actorFamilyNames(), ", "))
+		}
+		for _, c := range codes() {
+			f.codes.Add(c)
+		}
+		seen[family] = true
+		f.families = append(f.families, family)
+	}
+	if f.codes.Len() == 0 {
+		return nil, xerrors.Errorf("no actor types given")
+	}
+	sort.Strings(f.families)
+	return f, nil
+}
+
+// An ActorTypeFilter limits the actors whose state is extracted to those with particular codes.
+type ActorTypeFilter struct {
+	codes    *cid.Set
+	families []string // names of the allowed families, sorted
+}
+
+func (f *ActorTypeFilter) Allow(code cid.Cid) bool {
+	return f.codes.Has(code)
+}
+
+// Families returns the names of the families of actors allowed by the filter in alphabetical order.
+func (f *ActorTypeFilter) Families() []string {
+	return f.families
+}
+
+// Limits reports whether the filter excludes any of the actors allowed by allow, so that a task extracting those actors
+// would see only some of them. The system actor belongs to none of ActorFamilies and is not considered since its state
+// does not change after genesis.
+func (f *ActorTypeFilter) Limits(allow func(code cid.Cid) bool) bool {
+	for _, codes := range ActorFamilies {
+		for _, c := range codes() {
+			if allow(c) && !f.codes.Has(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func actorFamilyNames() []string {
+	names := make([]string, 0, len(ActorFamilies))
+	for name := range ActorFamilies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,55 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
+)
+
+func TestActorTypeFilter(t *testing.T) {
+	f, err := NewActorTypeFilter([]string{"miner", " market", ""})
+	require.NoError(t, err)
+
+	// every version of an actor is allowed
+	for _, c := range miner.AllCodes() {
+		assert.True(t, f.Allow(c))
+	}
+	assert.True(t, f.Allow(market.AllCodes()[0]))
+	assert.False(t, f.Allow(power.AllCodes()[0]))
+
+	assert.Equal(t, []string{"market", "miner"}, f.Families())
+
+	_, err = NewActorTypeFilter([]string{"miners"})
+	assert.Error(t, err)
+
+	_, err = NewActorTypeFilter(nil)
+	assert.Error(t, err)
+}
+
+func TestActorTypeFilterLimits(t *testing.T) {
+	f, err := NewActorTypeFilter([]string{"miner", "market", "miner"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"market", "miner"}, f.Families())
+
+	allowCodes := func(codes []cid.Cid) func(cid.Cid) bool {
+		set := cid.NewSet()
+		for _, c := range codes {
+			set.Add(c)
+		}
+		return set.Has
+	}
+
+	// a task that extracts only allowed actors sees all of them
+	assert.False(t, f.Limits(allowCodes(miner.AllCodes())))
+	assert.False(t, f.Limits(allowCodes(append(miner.AllCodes(), market.AllCodes()...))))
+
+	// a task that extracts other actors does not
+	assert.True(t, f.Limits(allowCodes(power.AllCodes())))
+	assert.True(t, f.Limits(func(cid.Cid) bool { return true }))
+}
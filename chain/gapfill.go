@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// A HeightRange is a range of heights, inclusive of both ends.
+type HeightRange struct {
+	From int64
+	To   int64
+}
+
+func (r HeightRange) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// NewGapFiller creates a GapFiller that walks each of the ranges in order of height. newObs is called to create the
+// observer for each walk since an observer tracks the previous tipset it was given and the ranges need not be
+// contiguous.
+func NewGapFiller(newObs func() (TipSetObserver, error), opener lens.APIOpener, ranges []HeightRange) (*GapFiller, error) {
+	sorted := make([]HeightRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].From < sorted[j].From
+	})
+	for _, r := range sorted {
+		if r.From < 0 || r.From > r.To {
+			return nil, xerrors.Errorf("invalid height range %s", r)
+		}
+	}
+
+	return &GapFiller{
+		newObs: newObs,
+		opener: opener,
+		ranges: sorted,
+	}, nil
+}
+
+// GapFiller is a task that indexes the tipsets in a set of height ranges, such as ranges known to be missing or to
+// contain bad data.
+type GapFiller struct {
+	newObs func() (TipSetObserver, error)
+	opener lens.APIOpener
+	ranges []HeightRange
+	next   int // index of the next range to walk, so a restarted job continues with the range that failed
+}
+
+func (g *GapFiller) Params() map[string]interface{} {
+	ranges := make([]string, len(g.ranges))
+	for i, r := range g.ranges {
+		ranges[i] = r.String()
+	}
+	out := make(map[string]interface{})
+	out["ranges"] = ranges
+	out["completed"] = g.next
+	return out
+}
+
+// Run walks each remaining range and returns once they are all complete, the context is done or an error occurs.
+func (g *GapFiller) Run(ctx context.Context) error {
+	for g.next < len(g.ranges) {
+		r := g.ranges[g.next]
+
+		obs, err := g.newObs()
+		if err != nil {
+			return xerrors.Errorf("create observer: %w", err)
+		}
+
+		log.Infow("filling height range", "from", r.From, "to", r.To)
+		if err := NewWalker(obs, g.opener, r.From, r.To).Run(ctx); err != nil {
+			return xerrors.Errorf("fill range %s: %w", r, err)
+		}
+		g.next++
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGapFillerRanges(t *testing.T) {
+	g, err := NewGapFiller(nil, nil, []HeightRange{{From: 500, To: 600}, {From: 100, To: 100}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100-100", "500-600"}, g.Params()["ranges"])
+
+	_, err = NewGapFiller(nil, nil, []HeightRange{{From: 10, To: 5}})
+	assert.Error(t, err)
+
+	_, err = NewGapFiller(nil, nil, []HeightRange{{From: -1, To: 5}})
+	assert.Error(t, err)
+}
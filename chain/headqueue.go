@@ -0,0 +1,204 @@
+package chain
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+)
+
+// maxApplyGap is the greatest number of tipsets that ApplyPath will load to connect an applied tipset to the head.
+const maxApplyGap = 900
+
+// maxPendingHeadEvents is the greatest number of events a HeadEventQueue holds while they wait for space in its buffer,
+// enough for the watcher to fall a little over a day behind the chain head.
+const maxPendingHeadEvents = 3000
+
+// A HeadEventQueue is a HeadNotifier that delivers events in the order they were pushed without ever blocking the
+// sender. Events that cannot be buffered are queued until the watcher reads them, so a slow watcher does not delay the
+// source of the events. The queue is bounded: once it is full the oldest queued events are dropped and passed to the
+// function set with OnDrop so the tipsets they applied can be indexed some other way.
+type HeadEventQueue struct {
+	mu       sync.Mutex       // protects following fields
+	events   chan *HeadEvent  // created lazily, closed by first cancel call or by the drain once canceled
+	done     chan struct{}    // created lazily, closed by first cancel call while draining
+	err      error            // set to non-nil by the first cancel call
+	pending  []*HeadEvent     // events waiting for space in the buffer, in order of arrival
+	draining bool             // true while a goroutine is moving pending events into the buffer
+	onDrop   func(*HeadEvent) // called with each event dropped from pending, may be nil
+
+	// maxPending is the greatest number of events held in pending, at least two
+	maxPending int
+
+	// size of the buffer to maintain for events. Using a buffer reduces the
+	// number of events that must be queued when the watcher falls behind.
+	bufferSize int
+}
+
+func NewHeadEventQueue(bufferSize int) *HeadEventQueue {
+	return &HeadEventQueue{
+		bufferSize: bufferSize,
+		maxPending: maxPendingHeadEvents,
+	}
+}
+
+// OnDrop sets a function to be called with each event dropped because the queue is full. It is called while the queue
+// is locked so it must not block or use the queue.
+func (q *HeadEventQueue) OnDrop(f func(he *HeadEvent)) {
+	q.mu.Lock()
+	q.onDrop = f
+	q.mu.Unlock()
+}
+
+func (q *HeadEventQueue) eventsCh() chan *HeadEvent {
+	// caller must hold mu
+	if q.events == nil {
+		q.events = make(chan *HeadEvent, q.bufferSize)
+		q.done = make(chan struct{})
+	}
+	return q.events
+}
+
+func (q *HeadEventQueue) HeadEvents() <-chan *HeadEvent {
+	q.mu.Lock()
+	ev := q.eventsCh()
+	q.mu.Unlock()
+	return ev
+}
+
+func (q *HeadEventQueue) Err() error {
+	q.mu.Lock()
+	err := q.err
+	q.mu.Unlock()
+	return err
+}
+
+// Cancel closes the events channel, discarding any queued events. Err will return err, or a generic error if err is
+// nil.
+func (q *HeadEventQueue) Cancel(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return
+	}
+	if err == nil {
+		err = xerrors.Errorf("canceled")
+	}
+	q.err = err
+	q.eventsCh()
+	if q.draining {
+		// The drain owns the events channel until it stops and closes it
+		close(q.done)
+		return
+	}
+	close(q.events)
+}
+
+// Push delivers an event to the buffer without blocking, queueing it behind any earlier events that are still waiting
+// for space and dropping the oldest of those if too many are waiting. It returns the error passed to Cancel if the queue
+// has been canceled.
+func (q *HeadEventQueue) Push(he *HeadEvent) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return q.err
+	}
+	ev := q.eventsCh()
+
+	if len(q.pending) == 0 {
+		select {
+		case ev <- he:
+			return nil
+		default:
+		}
+	}
+
+	if len(q.pending) >= q.maxPending {
+		// The first pending event may be being sent by the drain so the one after it is dropped
+		dropped := q.pending[1]
+		q.pending = append(q.pending[:1], q.pending[2:]...)
+		if dropped.TipSet != nil {
+			log.Errorw("head event queue full, dropping event", "type", dropped.Type, "height", dropped.TipSet.Height())
+		}
+		if q.onDrop != nil {
+			q.onDrop(dropped)
+		}
+	}
+
+	q.pending = append(q.pending, he)
+	log.Warnw("head event buffer at capacity, queueing event", "queued", len(q.pending))
+	if !q.draining {
+		q.draining = true
+		go q.drain(ev, q.done)
+	}
+	return nil
+}
+
+// drain moves pending events into the buffer as the watcher reads them, stopping once none are pending or the queue
+// is canceled.
+func (q *HeadEventQueue) drain(ev chan *HeadEvent, done chan struct{}) {
+	for {
+		q.mu.Lock()
+		if q.err != nil {
+			q.draining = false
+			q.pending = nil
+			close(ev)
+			q.mu.Unlock()
+			return
+		}
+		if len(q.pending) == 0 {
+			q.draining = false
+			q.mu.Unlock()
+			return
+		}
+		he := q.pending[0]
+		q.mu.Unlock()
+
+		select {
+		case ev <- he:
+			q.mu.Lock()
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+		case <-done:
+			// the next iteration closes the events channel
+		}
+	}
+}
+
+// tipSetLoader is the part of the lens used to load the ancestors of an applied tipset.
+type tipSetLoader interface {
+	ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error)
+}
+
+// ApplyPath returns the tipsets that must be applied to move the chain head from the tipset with key head to ts, in
+// order of increasing height and ending with ts. ChainNotify may report a single apply for several tipsets when the
+// node catches up after falling behind, so the ancestors of ts are loaded until one whose parent is head is found.
+// Only ts is returned if head is empty or is not among the nearest ancestors of ts.
+func ApplyPath(ctx context.Context, node tipSetLoader, head types.TipSetKey, ts *types.TipSet) ([]*types.TipSet, error) {
+	if head == types.EmptyTSK || ts.Key() == head {
+		return []*types.TipSet{ts}, nil
+	}
+
+	path := []*types.TipSet{ts}
+	cur := ts
+	for cur.Parents() != head {
+		if len(path) > maxApplyGap || cur.Height() == 0 {
+			log.Warnw("applied tipset does not descend from head", "height", ts.Height(), "head", head.String())
+			return []*types.TipSet{ts}, nil
+		}
+
+		parent, err := node.ChainGetTipSet(ctx, cur.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("load parent of tipset at height %d: %w", cur.Height(), err)
+		}
+		path = append(path, parent)
+		cur = parent
+	}
+
+	// reverse so tipsets are applied in order of increasing height
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
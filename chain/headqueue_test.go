@@ -0,0 +1,117 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func TestHeadEventQueueKeepsOrderBeyondBuffer(t *testing.T) {
+	c := newFakeChain(t, 20)
+	q := NewHeadEventQueue(2)
+
+	// Nothing reads the events while they are pushed so most of them must be queued
+	for _, ts := range c.tipsets {
+		require.NoError(t, q.Push(&HeadEvent{Type: HeadEventApply, TipSet: ts}))
+	}
+
+	for _, want := range c.tipsets {
+		he := <-q.HeadEvents()
+		assert.Equal(t, want.Height(), he.TipSet.Height())
+	}
+}
+
+func TestHeadEventQueueDropsOldestWhenFull(t *testing.T) {
+	c := newFakeChain(t, 10)
+	q := NewHeadEventQueue(2)
+	q.maxPending = 3
+
+	var dropped []abi.ChainEpoch
+	q.OnDrop(func(he *HeadEvent) {
+		dropped = append(dropped, he.TipSet.Height())
+	})
+
+	for _, ts := range c.tipsets {
+		require.NoError(t, q.Push(&HeadEvent{Type: HeadEventApply, TipSet: ts}))
+	}
+
+	// Two events fill the buffer and the first queued event is kept since it may be in flight, leaving the two most
+	// recent events queued behind it
+	n := len(c.tipsets)
+	var want []abi.ChainEpoch
+	for _, ts := range c.tipsets[:3] {
+		want = append(want, ts.Height())
+	}
+	want = append(want, c.tipsets[n-2].Height(), c.tipsets[n-1].Height())
+	for _, h := range want {
+		he := <-q.HeadEvents()
+		assert.Equal(t, h, he.TipSet.Height())
+	}
+
+	var wantDropped []abi.ChainEpoch
+	for _, ts := range c.tipsets[3 : n-2] {
+		wantDropped = append(wantDropped, ts.Height())
+	}
+	assert.Equal(t, wantDropped, dropped)
+}
+
+func TestHeadEventQueueCancel(t *testing.T) {
+	c := newFakeChain(t, 10)
+	q := NewHeadEventQueue(1)
+	for _, ts := range c.tipsets {
+		require.NoError(t, q.Push(&HeadEvent{Type: HeadEventApply, TipSet: ts}))
+	}
+
+	stop := xerrors.Errorf("stop")
+	q.Cancel(stop)
+	assert.ErrorIs(t, q.Push(&HeadEvent{Type: HeadEventApply, TipSet: c.tipsets[0]}), stop)
+	assert.ErrorIs(t, q.Err(), stop)
+
+	// The channel is closed once the buffered events have been read
+	for range q.HeadEvents() {
+	}
+}
+
+func TestApplyPath(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeChain(t, 10, 4)
+
+	heights := func(tss []*types.TipSet) []int64 {
+		var out []int64
+		for _, ts := range tss {
+			out = append(out, int64(ts.Height()))
+		}
+		return out
+	}
+
+	t.Run("next tipset", func(t *testing.T) {
+		path, err := ApplyPath(ctx, c, c.tipsets[5].Key(), c.tipsets[6])
+		require.NoError(t, err)
+		assert.Equal(t, []int64{7}, heights(path))
+	})
+
+	t.Run("several tipsets", func(t *testing.T) {
+		// height 4 is a null round
+		path, err := ApplyPath(ctx, c, c.tipsets[1].Key(), c.tipsets[len(c.tipsets)-1])
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 3, 5, 6, 7, 8, 9, 10}, heights(path))
+	})
+
+	t.Run("no head", func(t *testing.T) {
+		path, err := ApplyPath(ctx, c, types.EmptyTSK, c.tipsets[6])
+		require.NoError(t, err)
+		assert.Equal(t, []int64{7}, heights(path))
+	})
+
+	t.Run("head not an ancestor", func(t *testing.T) {
+		other := newFakeChain(t, 3)
+		path, err := ApplyPath(ctx, c, other.tipsets[3].Key(), c.tipsets[6])
+		require.NoError(t, err)
+		assert.Equal(t, []int64{7}, heights(path))
+	})
+}
@@ -0,0 +1,34 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/filecoin-project/lotus/chain/types"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// An ObservedHeadStorage records the chain head most recently observed by a job for each of its tasks.
+type ObservedHeadStorage interface {
+	RecordObservedHead(ctx context.Context, reporter string, tasks []string, height int64, tipset string) error
+}
+
+// HeadTrackingOpt configures the watcher to record each chain head it observes for the tasks of the reporter, so the
+// lag of each task can be read from storage.
+func HeadTrackingOpt(store ObservedHeadStorage, reporter string, tasks []string) WatcherOpt {
+	return func(w *Watcher) {
+		w.headStore = store
+		w.headReporter = reporter
+		w.headTasks = tasks
+	}
+}
+
+// recordHead records ts as the observed chain head. Failures are logged since the head is only used for monitoring.
+func (c *Watcher) recordHead(ctx context.Context, ts *types.TipSet) {
+	if c.headStore == nil || ts == nil {
+		return
+	}
+	if err := c.headStore.RecordObservedHead(ctx, c.headReporter, c.headTasks, int64(ts.Height()), visormodel.EncodeTipSetKey(ts.Key())); err != nil {
+		log.Warnw("failed to record observed head", "height", ts.Height(), "error", err)
+	}
+}
@@ -0,0 +1,38 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHeadStorage records the heights of the observed heads it was given.
+type fakeHeadStorage struct {
+	reporter string
+	tasks    []string
+	heights  []int64
+}
+
+func (f *fakeHeadStorage) RecordObservedHead(ctx context.Context, reporter string, tasks []string, height int64, tipset string) error {
+	f.reporter, f.tasks = reporter, tasks
+	f.heights = append(f.heights, height)
+	return nil
+}
+
+func TestWatcherRecordsObservedHeads(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeChain(t, 5)
+	store := &fakeHeadStorage{}
+	w := NewWatcher(&fakeShedder{}, NullHeadNotifier{}, 2, HeadTrackingOpt(store, "watch", []string{BlocksTask}))
+
+	require.NoError(t, w.index(ctx, &HeadEvent{Type: HeadEventCurrent, TipSet: c.tipsets[3]}))
+	require.NoError(t, w.index(ctx, &HeadEvent{Type: HeadEventApply, TipSet: c.tipsets[4]}))
+	require.NoError(t, w.index(ctx, &HeadEvent{Type: HeadEventRevert, TipSet: c.tipsets[4]}))
+
+	// Reverts are followed by the apply of the new head, which is recorded then
+	assert.Equal(t, []int64{3, 4}, store.heights)
+	assert.Equal(t, "watch", store.reporter)
+	assert.Equal(t, []string{BlocksTask}, store.tasks)
+}
@@ -3,8 +3,13 @@ package chain
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/filecoin-project/go-address"
@@ -20,21 +25,32 @@ import (
 	"go.opentelemetry.io/otel/label"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
 	init_ "github.com/filecoin-project/sentinel-visor/chain/actors/builtin/init"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/multisig"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/reward"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
 	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
+	commonmodel "github.com/filecoin-project/sentinel-visor/model/actors/common"
 	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
 	"github.com/filecoin-project/sentinel-visor/tasks/actorstate"
 	"github.com/filecoin-project/sentinel-visor/tasks/blocks"
 	"github.com/filecoin-project/sentinel-visor/tasks/chaineconomics"
+	"github.com/filecoin-project/sentinel-visor/tasks/chainthroughput"
+	"github.com/filecoin-project/sentinel-visor/tasks/gasbymethod"
+	"github.com/filecoin-project/sentinel-visor/tasks/gastraces"
+	"github.com/filecoin-project/sentinel-visor/tasks/marketsnapshots"
 	"github.com/filecoin-project/sentinel-visor/tasks/messages"
+	"github.com/filecoin-project/sentinel-visor/tasks/minersnapshots"
 	"github.com/filecoin-project/sentinel-visor/tasks/msapprovals"
+	"github.com/filecoin-project/sentinel-visor/tasks/msigvesting"
+	"github.com/filecoin-project/sentinel-visor/tasks/systembalances"
+	"github.com/filecoin-project/sentinel-visor/version"
 )
 
 const (
@@ -45,18 +61,42 @@ const (
 	ActorStatesInitTask     = "actorstatesinit"     // task that only extracts init actor states (but not the raw state)
 	ActorStatesMarketTask   = "actorstatesmarket"   // task that only extracts market actor states (but not the raw state)
 	ActorStatesMultisigTask = "actorstatesmultisig" // task that only extracts multisig actor states (but not the raw state)
+	ActorStatesVerifregTask = "actorstatesverifreg" // task that only extracts verified registry governance changes (but not the raw state)
+	ActorStatesUntypedTask  = "actorstatesuntyped"  // task that extracts the raw state of actors that have no dedicated task
 	BlocksTask              = "blocks"              // task that extracts block data
 	MessagesTask            = "messages"            // task that extracts message data
 	ChainEconomicsTask      = "chaineconomics"      // task that extracts chain economics data
 	MultisigApprovalsTask   = "msapprovals"         // task that extracts multisig actor approvals
+	GasByMethodTask         = "gasbymethod"         // task that aggregates message gas by actor family and method
+	MultisigVestingTask     = "msigvesting"         // task that extracts the vesting balances of genesis multisigs
+	ChainThroughputTask     = "chainthroughput"     // task that summarises message throughput and block space utilization
+	SystemBalancesTask      = "systembalances"      // task that extracts the balances of system actors holding network funds
+	MinerSnapshotsTask      = "minersnapshots"      // task that periodically records every sector and precommit of every miner
+	MarketSnapshotsTask     = "marketsnapshots"     // task that periodically records every deal held by the storage market
+	GasTracesTask           = "gastraces"           // task that replays messages to record the gas charges in their execution traces
 )
 
+// StateUnavailableReason is recorded in the processing reports of tasks that were skipped because the lens did not hold
+// the state they needed, see StateProbeOpt.
+const StateUnavailableReason = "state not available from lens, it may have been pruned by the node"
+
+// stateFreeTasks are the tasks that read only block headers and can run when the state of a tipset is not available.
+var stateFreeTasks = map[string]bool{
+	BlocksTask: true,
+}
+
 var log = logging.Logger("visor/chain")
 
-var _ TipSetObserver = (*TipSetIndexer)(nil)
+var (
+	_ TipSetObserver       = (*TipSetIndexer)(nil)
+	_ TipSetRevertObserver = (*TipSetIndexer)(nil)
+	_ TaskShedder          = (*TipSetIndexer)(nil)
+)
 
 // A TipSetWatcher waits for tipsets and persists their block data into a database.
 type TipSetIndexer struct {
+	pending           int64 // number of tipsets not yet fully processed, accessed atomically
+	persisting        int64 // number of batches currently being persisted, accessed atomically
 	window            time.Duration
 	storage           model.Storage
 	processors        map[string]TipSetProcessor
@@ -64,11 +104,27 @@ type TipSetIndexer struct {
 	actorProcessors   map[string]ActorProcessor
 	name              string
 	persistSlot       chan struct{} // filled with a token when a goroutine is persisting data
+	persistLimit      Semaphore     // bounds the batches being persisted by all indexers of a job, may be nil
 	lastTipSet        *types.TipSet
 	node              lens.API
 	opener            lens.APIOpener
 	closer            lens.APICloser
 	addressFilter     *AddressFilter
+	actorTypeFilter   *ActorTypeFilter  // limits actor state extraction to some types of actor, may be nil
+	probeState        bool              // true if tasks needing state are skipped when the lens does not hold it
+	probed            *probedRange      // heights whose state has been probed up front, may be nil
+	strict            bool              // abort indexing on the first extraction or persistence error
+	codesPersisted    bool              // true once the builtin actor codes have been written to storage
+	networkVerified   bool              // true once storage has been checked to hold data for the lens's network
+	runID             string            // identifies this indexer's run in the processing reports it writes
+	runRecorded       bool              // true once the run has been recorded in storage
+	notifier          *IndexNotifier    // receives an event when the outputs of a tipset have been persisted
+	persistObservers  []PersistObserver // given the data persisted for each tipset
+	commitObservers   []CommitObserver  // told of each tipset whose outputs were all committed
+	persistErrMu      sync.Mutex        // protects persistErr
+	persistErr        error             // first persistence error seen in strict mode
+	shedMu            sync.Mutex        // protects shed
+	shed              map[string]bool   // tasks that are skipped until restored, see ShedTasks
 }
 
 type TipSetIndexerOpt func(t *TipSetIndexer)
@@ -79,10 +135,82 @@ func AddressFilterOpt(f *AddressFilter) TipSetIndexerOpt {
 	}
 }
 
+// ActorTypeFilterOpt configures the indexer to extract the state of only the actors allowed by the filter, so jobs that
+// need the data of a few types of actor do not pay for extracting the rest. Tasks that would have extracted actors
+// excluded by the filter report a partial status, which does not count as completion.
+func ActorTypeFilterOpt(f *ActorTypeFilter) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.actorTypeFilter = f
+	}
+}
+
+// StateProbeOpt configures the indexer to check that the lens holds the parent state of each tipset, and of its parent,
+// before running the tasks that need it. When the state is missing, typically because the node has pruned it, those tasks are reported as
+// skipped with StateUnavailableReason instead of failing with errors from deep within the extraction. Tasks that read
+// only block headers still run.
+func StateProbeOpt() TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.probeState = true
+	}
+}
+
+// StrictOpt configures the indexer to fail on the first extraction or persistence error instead of reporting the
+// error and continuing with the next tipset. This is intended for producing datasets that must not contain gaps.
+func StrictOpt() TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.strict = true
+	}
+}
+
+// IndexNotifierOpt configures the indexer to send an event to the notifier each time the outputs of a tipset's tasks
+// have been persisted.
+func IndexNotifierOpt(n *IndexNotifier) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.notifier = n
+	}
+}
+
+// A PersistObserver is given the models extracted from a tipset once they have been persisted, with lists flattened
+// into individual models. Data that was not persisted, because of an error or because the tipset had already been
+// indexed, is not included.
+type PersistObserver interface {
+	TipSetPersisted(ctx context.Context, ts *types.TipSet, models []interface{})
+}
+
+// PersistObserverOpt configures the indexer to pass the data persisted for each tipset to the observer.
+func PersistObserverOpt(o PersistObserver) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.persistObservers = append(t.persistObservers, o)
+	}
+}
+
+// A CommitObserver is told of each tipset whose task outputs have all been committed to storage, including outputs
+// that were already present. Tipsets are committed in the order they were given to the indexer. A tipset with an
+// output that could not be persisted is not committed.
+type CommitObserver interface {
+	TipSetCommitted(ctx context.Context, ts *types.TipSet)
+}
+
+// CommitObserverOpt configures the indexer to tell the observer of each tipset whose outputs have been committed.
+func CommitObserverOpt(o CommitObserver) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.commitObservers = append(t.commitObservers, o)
+	}
+}
+
+// TipSetProcessorOpt adds a processor that is run for each tipset alongside the indexer's tasks. The name is used as
+// the task name in processing reports and must not be the name of one of the indexer's tasks.
+func TipSetProcessorOpt(name string, p TipSetProcessor) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		t.processors[name] = p
+	}
+}
+
 // A TipSetIndexer extracts block, message and actor state data from a tipset and persists it to storage. Extraction
 // and persistence are concurrent. Extraction of the a tipset can proceed while data from the previous extraction is
 // being persisted. The indexer may be given a time window in which to complete data extraction. The name of the
-// indexer is used as the reporter in the visor_processing_reports table.
+// indexer is used as the reporter in the visor_processing_reports table. tasks may include task groups and wildcard
+// patterns, see ExpandTasks.
 func NewTipSetIndexer(o lens.APIOpener, d model.Storage, window time.Duration, name string, tasks []string, options ...TipSetIndexerOpt) (*TipSetIndexer, error) {
 	tsi := &TipSetIndexer{
 		storage:           d,
@@ -95,6 +223,17 @@ func NewTipSetIndexer(o lens.APIOpener, d model.Storage, window time.Duration, n
 		opener:            o,
 	}
 
+	runID, err := newRunID()
+	if err != nil {
+		return nil, err
+	}
+	tsi.runID = runID
+
+	tasks, err := ExpandTasks(tasks)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, task := range tasks {
 		switch task {
 		case BlocksTask:
@@ -117,13 +256,41 @@ func NewTipSetIndexer(o lens.APIOpener, d model.Storage, window time.Duration, n
 			tsi.actorProcessors[ActorStatesMarketTask] = actorstate.NewTask(o, actorstate.NewTypedActorExtractorMap(market.AllCodes()))
 		case ActorStatesMultisigTask:
 			tsi.actorProcessors[ActorStatesMultisigTask] = actorstate.NewTask(o, actorstate.NewTypedActorExtractorMap(multisig.AllCodes()))
+		case ActorStatesVerifregTask:
+			tsi.actorProcessors[ActorStatesVerifregTask] = actorstate.NewTask(o, actorstate.NewTypedActorExtractorMap(verifreg.AllCodes()))
+		case ActorStatesUntypedTask:
+			tsi.actorProcessors[ActorStatesUntypedTask] = actorstate.NewTask(o, &actorstate.UntypedActorExtractorMap{})
 		case MultisigApprovalsTask:
 			tsi.messageProcessors[MultisigApprovalsTask] = msapprovals.NewTask(o)
+		case GasByMethodTask:
+			tsi.messageProcessors[GasByMethodTask] = gasbymethod.NewTask()
+		case MultisigVestingTask:
+			tsi.processors[MultisigVestingTask] = msigvesting.NewTask(o)
+		case ChainThroughputTask:
+			tsi.messageProcessors[ChainThroughputTask] = chainthroughput.NewTask()
+		case SystemBalancesTask:
+			tsi.processors[SystemBalancesTask] = systembalances.NewTask(o)
+		case MinerSnapshotsTask:
+			tsi.processors[MinerSnapshotsTask] = minersnapshots.NewTask(o)
+		case MarketSnapshotsTask:
+			tsi.processors[MarketSnapshotsTask] = marketsnapshots.NewTask(o)
+		case GasTracesTask:
+			tsi.processors[GasTracesTask] = gastraces.NewTask(o)
 		default:
 			return nil, xerrors.Errorf("unknown task: %s", task)
 		}
 	}
 
+	// The raw actor states task sees every actor so it alone records deleted actors. The typed tasks allow disjoint
+	// sets of actors so they can each record their own when it is not running.
+	if _, ok := tsi.actorProcessors[ActorStatesRawTask]; ok {
+		for name, p := range tsi.actorProcessors {
+			if ap, ok := p.(*actorstate.Task); ok && name != ActorStatesRawTask {
+				ap.SkipDeletions()
+			}
+		}
+	}
+
 	for _, opt := range options {
 		opt(tsi)
 	}
@@ -131,6 +298,21 @@ func NewTipSetIndexer(o lens.APIOpener, d model.Storage, window time.Duration, n
 	return tsi, nil
 }
 
+// limitedByActorType reports whether the actor type filter excludes any of the actors extracted by the named task.
+func (t *TipSetIndexer) limitedByActorType(task string) bool {
+	if t.actorTypeFilter == nil {
+		return false
+	}
+	p, ok := t.actorProcessors[task]
+	if !ok {
+		return false
+	}
+	if ap, ok := p.(*actorstate.Task); ok {
+		return t.actorTypeFilter.Limits(ap.Allow)
+	}
+	return true
+}
+
 // TipSet is called when a new tipset has been discovered
 func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 	ctx, span := global.Tracer("").Start(ctx, "Indexer.TipSet")
@@ -141,6 +323,37 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 
 	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Name, t.name))
 
+	// In strict mode a failure to persist a previous tipset must stop any further indexing
+	if err := t.persistError(); err != nil {
+		return err
+	}
+
+	// Refuse to mix data from different networks in the same storage
+	if !t.networkVerified {
+		if err := t.verifyNetwork(ctx); err != nil {
+			return xerrors.Errorf("verify network: %w", err)
+		}
+		t.networkVerified = true
+	}
+
+	// Record the build and task revisions that produced the reports linked to this run
+	if !t.runRecorded {
+		if err := t.recordRun(ctx); err != nil {
+			return xerrors.Errorf("record run: %w", err)
+		}
+		t.runRecorded = true
+	}
+
+	// Track the tipset as pending until it has been persisted. Responsibility for marking the tipset as done passes
+	// to the persistence goroutine if one is started.
+	t.addPending(ctx, 1)
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			t.addPending(ctx, -1)
+		}
+	}()
+
 	var cancel func()
 	var tctx context.Context // cancellable context for the task
 	if t.window > 0 {
@@ -155,16 +368,36 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 
 	ll := log.With("height", int64(ts.Height()))
 
+	if !t.codesPersisted && len(t.actorProcessors) > 0 {
+		// Record the actor codes once so consumers can resolve the code CIDs found in extracted actor data. Failure
+		// is not fatal since the codes will be written with the next tipset.
+		if err := t.storage.PersistBatch(ctx, builtinActorCodes()); err != nil {
+			ll.Warnw("failed to persist builtin actor codes", "error", err)
+		} else {
+			t.codesPersisted = true
+		}
+	}
+
 	start := time.Now()
 
 	inFlight := 0
 	results := make(chan *TaskResult, len(t.processors)+len(t.actorProcessors))
 
-	// A map to gather the persistable outputs from each task
-	taskOutputs := make(map[string]model.PersistableList, len(t.processors)+len(t.actorProcessors))
+	// A map to gather the processing report and persistable outputs from each task
+	taskOutputs := make(map[string]*taskOutput, len(t.processors)+len(t.actorProcessors))
+
+	// Tasks that have been shed, or that need state the lens no longer holds, are reported as skipped without running
+	skipped, err := t.skippedTasks(ctx, ts)
+	if err != nil {
+		return err
+	}
 
 	// Run each tipset processing task concurrently
 	for name, p := range t.processors {
+		if reason, ok := skipped[name]; ok {
+			taskOutputs[name] = &taskOutput{report: t.buildSkippedTipsetReport(ts, name, start, reason)}
+			continue
+		}
 		inFlight++
 		go t.runProcessor(tctx, p, name, ts, results)
 	}
@@ -192,48 +425,50 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 		// in a batch. No report is generated because a different run of the indexer could cover the parent and child
 		// for this tipset.
 		if parent != nil {
-			if t.node == nil {
-				node, closer, err := t.opener.Open(ctx)
-				if err != nil {
-					return xerrors.Errorf("unable to open lens: %w", err)
-				}
-				t.node = node
-				t.closer = closer
+			if err := t.openNode(ctx); err != nil {
+				return err
 			}
 
 			if types.CidArrsEqual(child.Parents().Cids(), parent.Cids()) {
 				// If we have message processors then extract the messages and receipts
-				if len(t.messageProcessors) > 0 {
+				messageProcessors := make(map[string]MessageProcessor, len(t.messageProcessors))
+				for name, p := range t.messageProcessors {
+					if reason, ok := skipped[name]; ok {
+						taskOutputs[name] = &taskOutput{report: t.buildSkippedTipsetReport(parent, name, start, reason)}
+						continue
+					}
+					messageProcessors[name] = p
+				}
+				if len(messageProcessors) > 0 {
 					tsMsgs, err := t.node.GetExecutedAndBlockMessagesForTipset(ctx, child, parent)
 					if err == nil {
 						// Start all the message processors
-						for name, p := range t.messageProcessors {
+						for name, p := range messageProcessors {
 							inFlight++
 							go t.runMessageProcessor(tctx, p, name, child, parent, tsMsgs.Executed, tsMsgs.Block, results)
 						}
 					} else {
 						ll.Errorw("failed to extract messages", "error", err)
 						terr := xerrors.Errorf("failed to extract messages: %w", err)
-						// We need to report that all message tasks failed
-						for name := range t.messageProcessors {
-							report := &visormodel.ProcessingReport{
-								Height:         int64(ts.Height()),
-								StateRoot:      ts.ParentState().String(),
-								Reporter:       t.name,
-								Task:           name,
-								StartedAt:      start,
-								CompletedAt:    time.Now(),
-								Status:         visormodel.ProcessingStatusError,
-								ErrorsDetected: terr,
-							}
-							taskOutputs[name] = model.PersistableList{report}
+						// We need to report that all message tasks failed. Message tasks report against the tipset
+						// containing the messages, which is the parent.
+						for name := range messageProcessors {
+							taskOutputs[name] = &taskOutput{report: t.buildErrorReport(parent, name, start, terr)}
 						}
 
 					}
 				}
 
 				// If we have actor processors then find actors that have changed state
-				if len(t.actorProcessors) > 0 {
+				actorProcessors := make(map[string]ActorProcessor, len(t.actorProcessors))
+				for name, p := range t.actorProcessors {
+					if reason, ok := skipped[name]; ok {
+						taskOutputs[name] = &taskOutput{report: t.buildSkippedTipsetReport(child, name, start, reason)}
+						continue
+					}
+					actorProcessors[name] = p
+				}
+				if len(actorProcessors) > 0 {
 					changesStart := time.Now()
 					var err error
 					var changes map[string]types.Actor
@@ -252,26 +487,24 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 								}
 							}
 						}
-						for name, p := range t.actorProcessors {
+						if t.actorTypeFilter != nil {
+							for addr, act := range changes {
+								if !t.actorTypeFilter.Allow(act.Code) {
+									delete(changes, addr)
+								}
+							}
+						}
+						for name, p := range actorProcessors {
 							inFlight++
 							go t.runActorProcessor(tctx, p, name, child, parent, changes, results)
 						}
 					} else {
 						ll.Errorw("failed to extract actor changes", "error", err)
 						terr := xerrors.Errorf("failed to extract actor changes: %w", err)
-						// We need to report that all actor tasks failed
-						for name := range t.actorProcessors {
-							report := &visormodel.ProcessingReport{
-								Height:         int64(ts.Height()),
-								StateRoot:      ts.ParentState().String(),
-								Reporter:       t.name,
-								Task:           name,
-								StartedAt:      start,
-								CompletedAt:    time.Now(),
-								Status:         visormodel.ProcessingStatusError,
-								ErrorsDetected: terr,
-							}
-							taskOutputs[name] = model.PersistableList{report}
+						// We need to report that all actor tasks failed. Actor tasks report against the tipset
+						// containing the state changes, which is the child.
+						for name := range actorProcessors {
+							taskOutputs[name] = &taskOutput{report: t.buildErrorReport(child, name, start, terr)}
 						}
 					}
 				}
@@ -281,14 +514,15 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 				// tipset to be skipped completely.
 				log.Errorw("mismatching child and parent tipsets", "height", ts.Height(), "child", child.Key(), "parent", parent.Key())
 
-				// We need to report that all message and actor tasks were skipped
+				// We need to report that all message and actor tasks were skipped, against the same tipsets as their
+				// failures would be reported.
 				reason := "tipset did not have expected parent or child"
 				for name := range t.messageProcessors {
-					taskOutputs[name] = model.PersistableList{t.buildSkippedTipsetReport(ts, name, start, reason)}
+					taskOutputs[name] = &taskOutput{report: t.buildSkippedTipsetReport(parent, name, start, reason)}
 					ll.Infow("task skipped", "task", name, "reason", reason)
 				}
 				for name := range t.actorProcessors {
-					taskOutputs[name] = model.PersistableList{t.buildSkippedTipsetReport(ts, name, start, reason)}
+					taskOutputs[name] = &taskOutput{report: t.buildSkippedTipsetReport(child, name, start, reason)}
 					ll.Infow("task skipped", "task", name, "reason", reason)
 				}
 			}
@@ -325,27 +559,47 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 
 		// Fill in some report metadata
 		res.Report.Reporter = t.name
+		res.Report.RunID = t.runID
 		res.Report.Task = res.Task
 		res.Report.StartedAt = res.StartedAt
 		res.Report.CompletedAt = res.CompletedAt
+		res.Report.TipSet = res.TipSet
 
 		if res.Report.ErrorsDetected != nil {
 			res.Report.Status = visormodel.ProcessingStatusError
+			res.Report.ErrorClass = visormodel.ClassifyErrorsDetected(res.Report.ErrorsDetected)
 		} else if res.Report.StatusInformation != "" {
 			res.Report.Status = visormodel.ProcessingStatusInfo
 		} else {
 			res.Report.Status = visormodel.ProcessingStatusOK
 		}
+		if res.Report.Status != visormodel.ProcessingStatusError && t.limitedByActorType(res.Task) {
+			// The task did not see every actor it extracts so its output must not be taken as complete
+			limit := "actor states limited to types: " + strings.Join(t.actorTypeFilter.Families(), ", ")
+			if res.Report.StatusInformation != "" {
+				limit = res.Report.StatusInformation + "; " + limit
+			}
+			res.Report.Status = visormodel.ProcessingStatusPartial
+			res.Report.StatusInformation = limit
+		}
 
 		llt.Infow("task report", "status", res.Report.Status, "time", res.Report.CompletedAt.Sub(res.Report.StartedAt))
 
 		// Persist the processing report and the data in a single transaction
-		taskOutputs[res.Task] = model.PersistableList{res.Report, res.Data}
+		taskOutputs[res.Task] = &taskOutput{report: res.Report, data: res.Data}
 	}
 
 	// remember the last tipset we observed
 	t.lastTipSet = ts
 
+	if t.strict {
+		for task, out := range taskOutputs {
+			if out.report.Status == visormodel.ProcessingStatusError {
+				return xerrors.Errorf("task %s failed at height %d: %v", task, out.report.Height, out.report.ErrorsDetected)
+			}
+		}
+	}
+
 	if len(taskOutputs) == 0 {
 		// Nothing to persist
 		ll.Debugw("tipset complete, nothing to persist", "total_time", time.Since(start))
@@ -362,38 +616,180 @@ func (t *TipSetIndexer) TipSet(ctx context.Context, ts *types.TipSet) error {
 	}
 
 	// Persist all results
+	handedOff = true
 	go func() {
 		// free up the slot when done
 		defer func() {
 			<-t.persistSlot
+			t.addPending(ctx, -1)
 		}()
 
 		ll.Debugw("persisting data", "time", time.Since(start))
 		var wg sync.WaitGroup
 		wg.Add(len(taskOutputs))
 
+		var indexedMu sync.Mutex // protects indexed, persistedModels and committed
+		var indexed []IndexedTask
+		var persistedModels []interface{}
+		committed := true
+
 		// Persist each processor's data concurrently since they don't overlap
-		for task, p := range taskOutputs {
-			go func(task string, p model.Persistable) {
+		for task, out := range taskOutputs {
+			go func(task string, out *taskOutput) {
 				defer wg.Done()
 				start := time.Now()
-				ctx, _ = tag.New(ctx, tag.Upsert(metrics.TaskType, task))
+				ctx, _ := tag.New(ctx, tag.Upsert(metrics.TaskType, task))
+
+				t.addPersisting(ctx, 1)
+				defer t.addPersisting(ctx, -1)
 
-				if err := t.storage.PersistBatch(ctx, p); err != nil {
+				var persisted bool
+				err := t.persistLimit.Acquire(ctx)
+				if err == nil {
+					persisted, err = t.persistTaskOutput(ctx, out)
+					t.persistLimit.Release()
+				}
+				if t.notifier != nil {
+					it := IndexedTask{
+						Task:      task,
+						Status:    out.report.Status,
+						Persisted: err == nil && persisted,
+					}
+					if err != nil {
+						it.Status = visormodel.ProcessingStatusError
+					}
+					if out.collector != nil {
+						it.Rows = out.collector.Rows()
+					}
+					indexedMu.Lock()
+					indexed = append(indexed, it)
+					indexedMu.Unlock()
+				}
+				if err != nil {
 					stats.Record(ctx, metrics.PersistFailure.M(1))
 					ll.Errorw("persistence failed", "task", task, "error", err)
+					t.setPersistError(xerrors.Errorf("persist task %s at height %d: %w", task, ts.Height(), err))
+					t.persistFailureReport(ctx, out.report, err)
+					indexedMu.Lock()
+					committed = false
+					indexedMu.Unlock()
+					return
+				}
+				if !persisted {
+					ll.Infow("task already completed for tipset, data not persisted", "task", task)
 					return
 				}
+				if len(t.persistObservers) > 0 && out.collector != nil {
+					indexedMu.Lock()
+					out.collector.Each(func(m interface{}) {
+						persistedModels = append(persistedModels, m)
+					})
+					indexedMu.Unlock()
+				}
 				ll.Debugw("task data persisted", "task", task, "time", time.Since(start))
-			}(task, p)
+			}(task, out)
 		}
 		wg.Wait()
 		ll.Debugw("tipset complete", "total_time", time.Since(start))
+
+		if len(persistedModels) > 0 {
+			for _, o := range t.persistObservers {
+				o.TipSetPersisted(ctx, ts, persistedModels)
+			}
+		}
+
+		if committed {
+			for _, o := range t.commitObservers {
+				o.TipSetCommitted(ctx, ts)
+			}
+		}
+
+		if t.notifier != nil {
+			sort.Slice(indexed, func(i, j int) bool { return indexed[i].Task < indexed[j].Task })
+			t.notifier.Notify(&IndexedTipSet{
+				Height:      int64(ts.Height()),
+				TipSet:      visormodel.EncodeTipSetKey(ts.Key()),
+				StateRoot:   ts.ParentState().String(),
+				Reporter:    t.name,
+				Tasks:       indexed,
+				CompletedAt: time.Now(),
+			})
+		}
 	}()
 
 	return nil
 }
 
+// persistTaskOutput persists the output of a task. When the storage supports it the report and data are only
+// persisted if the task has not already been completed for the tipset, which avoids duplicating data when a tipset is
+// indexed more than once. It returns false if the output was not persisted for that reason.
+func (t *TipSetIndexer) persistTaskOutput(ctx context.Context, out *taskOutput) (bool, error) {
+	var data model.Persistable
+	if out.data != nil {
+		data = out
+	}
+
+	if cs, ok := t.storage.(visormodel.CompletionStorage); ok {
+		return cs.PersistCompletion(ctx, out.report, data)
+	}
+
+	// The data is persisted first so its checksum is recorded in the report
+	if err := t.persistTaskBatch(ctx, out.report.Task, model.PersistableList{data, out.report}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// persistFailureReport attempts to record that the data produced by a task could not be persisted. The report
+// replaces the one that would have been persisted with the data so the tipset can be found by gap filling.
+func (t *TipSetIndexer) persistFailureReport(ctx context.Context, report *visormodel.ProcessingReport, err error) {
+	failure := *report
+	failure.Status = visormodel.ProcessingStatusError
+	failure.StatusInformation = ""
+	failure.ErrorsDetected = xerrors.Errorf("persist task data: %w", err)
+	failure.ErrorClass = visormodel.ErrorClassDBError
+	if err := t.persistTaskBatch(ctx, report.Task, &failure); err != nil {
+		log.Errorw("failed to persist report of persistence failure", "height", report.Height, "task", report.Task, "error", err)
+	}
+}
+
+// persistTaskBatch persists models extracted by task, passing the task to storage that organises data by task.
+func (t *TipSetIndexer) persistTaskBatch(ctx context.Context, task string, ps ...model.Persistable) error {
+	if ts, ok := t.storage.(model.TaskStorage); ok {
+		return ts.PersistTaskBatch(ctx, task, ps...)
+	}
+	return t.storage.PersistBatch(ctx, ps...)
+}
+
+// setPersistError records the first persistence error seen when the indexer is in strict mode.
+func (t *TipSetIndexer) setPersistError(err error) {
+	if !t.strict {
+		return
+	}
+	t.persistErrMu.Lock()
+	defer t.persistErrMu.Unlock()
+	if t.persistErr == nil {
+		t.persistErr = err
+	}
+}
+
+// persistError returns the first persistence error seen when the indexer is in strict mode.
+func (t *TipSetIndexer) persistError() error {
+	t.persistErrMu.Lock()
+	defer t.persistErrMu.Unlock()
+	return t.persistErr
+}
+
+// addPending adjusts the count of tipsets that are pending completion and records it as a metric.
+func (t *TipSetIndexer) addPending(ctx context.Context, delta int64) {
+	stats.Record(ctx, metrics.TipSetsPending.M(atomic.AddInt64(&t.pending, delta)))
+}
+
+// addPersisting adjusts the count of batches that are being persisted and records it as a metric.
+func (t *TipSetIndexer) addPersisting(ctx context.Context, delta int64) {
+	stats.Record(ctx, metrics.PersistBatchInFlight.M(atomic.AddInt64(&t.persisting, delta)))
+}
+
 func (t *TipSetIndexer) runProcessor(ctx context.Context, p TipSetProcessor, name string, ts *types.TipSet, results chan *TaskResult) {
 	ctx, _ = tag.New(ctx, tag.Upsert(metrics.TaskType, name))
 	stats.Record(ctx, metrics.TipsetHeight.M(int64(ts.Height())))
@@ -416,11 +812,28 @@ func (t *TipSetIndexer) runProcessor(ctx context.Context, p TipSetProcessor, nam
 		Task:        name,
 		Report:      report,
 		Data:        data,
+		TipSet:      visormodel.EncodeTipSetKey(ts.Key()),
 		StartedAt:   start,
 		CompletedAt: time.Now(),
 	}
 }
 
+// builtinActorCodes returns a description of every builtin actor code.
+func builtinActorCodes() commonmodel.BuiltinActorCodeList {
+	codes := builtin.AllActorCodes()
+	out := make(commonmodel.BuiltinActorCodeList, 0, len(codes))
+	for _, c := range codes {
+		name := builtin.ActorNameByCode(c)
+		out = append(out, &commonmodel.BuiltinActorCode{
+			Code:    c.String(),
+			Name:    name,
+			Family:  builtin.ActorFamily(name),
+			Version: builtin.ActorVersion(name),
+		})
+	}
+	return out
+}
+
 // getGenesisActors returns a map of all actors contained in the genesis block.
 func (t *TipSetIndexer) getGenesisActors(ctx context.Context) (map[string]types.Actor, error) {
 	out := map[string]types.Actor{}
@@ -547,6 +960,7 @@ func (t *TipSetIndexer) runMessageProcessor(ctx context.Context, p MessageProces
 		Task:        name,
 		Report:      report,
 		Data:        data,
+		TipSet:      visormodel.EncodeTipSetKey(pts.Key()),
 		StartedAt:   start,
 		CompletedAt: time.Now(),
 	}
@@ -574,11 +988,89 @@ func (t *TipSetIndexer) runActorProcessor(ctx context.Context, p ActorProcessor,
 		Task:        name,
 		Report:      report,
 		Data:        data,
+		TipSet:      visormodel.EncodeTipSetKey(ts.Key()),
 		StartedAt:   start,
 		CompletedAt: time.Now(),
 	}
 }
 
+// openNode opens the lens used by the indexer if it is not already open.
+func (t *TipSetIndexer) openNode(ctx context.Context) error {
+	if t.node != nil {
+		return nil
+	}
+	node, closer, err := t.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("unable to open lens: %w", err)
+	}
+	t.node = node
+	t.closer = closer
+	return nil
+}
+
+// verifyNetwork checks that the storage holds data for the network the lens is connected to, if the storage is able
+// to record the network.
+func (t *TipSetIndexer) verifyNetwork(ctx context.Context) error {
+	ns, ok := t.storage.(visormodel.NetworkStorage)
+	if !ok {
+		return nil
+	}
+
+	if err := t.openNode(ctx); err != nil {
+		return err
+	}
+
+	name, err := t.node.StateNetworkName(ctx)
+	if err != nil {
+		return xerrors.Errorf("get network name: %w", err)
+	}
+
+	genesis, err := t.node.ChainGetGenesis(ctx)
+	if err != nil {
+		return xerrors.Errorf("get genesis: %w", err)
+	}
+
+	return ns.VerifyNetwork(ctx, string(name), visormodel.EncodeTipSetKey(genesis.Key()))
+}
+
+// recordRun records the version of visor and the revision of each task run by the indexer, if the storage is able to
+// record runs.
+func (t *TipSetIndexer) recordRun(ctx context.Context) error {
+	rs, ok := t.storage.(visormodel.RunStorage)
+	if !ok {
+		return nil
+	}
+
+	revisions := map[string]int{}
+	for name := range t.processors {
+		revisions[name] = TaskRevision(name)
+	}
+	for name := range t.messageProcessors {
+		revisions[name] = TaskRevision(name)
+	}
+	for name := range t.actorProcessors {
+		revisions[name] = TaskRevision(name)
+	}
+
+	return rs.RecordRun(ctx, &visormodel.Run{
+		ID:            t.runID,
+		Reporter:      t.name,
+		Version:       version.String(),
+		Commit:        version.Commit(),
+		TaskRevisions: revisions,
+		StartedAt:     time.Now(),
+	})
+}
+
+// newRunID returns a random identifier for a run of an indexer.
+func newRunID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", xerrors.Errorf("generate run id: %w", err)
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
+
 func (t *TipSetIndexer) closeProcessors() error {
 	if t.closer != nil {
 		t.closer()
@@ -626,11 +1118,101 @@ func (t *TipSetIndexer) Close() error {
 	// the channel is empty for reuse.
 	<-t.persistSlot
 
-	return t.closeProcessors()
+	if err := t.closeProcessors(); err != nil {
+		return err
+	}
+
+	// Surface any persistence failure that occurred after the last tipset was indexed
+	return t.persistError()
+}
+
+// ShedTasks stops the named tasks from running until RestoreTasks is called. Tipsets indexed in the meantime are
+// reported as skipped for those tasks so they are found as gaps and can be filled later. Names that are not tasks of
+// the indexer are ignored.
+func (t *TipSetIndexer) ShedTasks(tasks []string) {
+	t.shedMu.Lock()
+	defer t.shedMu.Unlock()
+	t.shed = make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		t.shed[task] = true
+	}
+}
+
+// RestoreTasks runs all of the indexer's tasks again after they were shed.
+func (t *TipSetIndexer) RestoreTasks() {
+	t.shedMu.Lock()
+	defer t.shedMu.Unlock()
+	t.shed = nil
+}
+
+// shedTasks returns the set of tasks currently shed.
+func (t *TipSetIndexer) shedTasks() map[string]bool {
+	t.shedMu.Lock()
+	defer t.shedMu.Unlock()
+	return t.shed
+}
+
+// skippedTasks returns the tasks that must not be run for ts together with the reason each is skipped.
+func (t *TipSetIndexer) skippedTasks(ctx context.Context, ts *types.TipSet) (map[string]string, error) {
+	skipped := make(map[string]string)
+	for task := range t.shedTasks() {
+		skipped[task] = TaskShedReason
+	}
+	if !t.probeState {
+		return skipped, nil
+	}
+
+	if err := t.openNode(ctx); err != nil {
+		return nil, err
+	}
+	available, err := t.stateAvailable(ctx, ts)
+	if err != nil {
+		// Leave the tasks to run and report whatever error they encounter
+		log.Warnw("failed to probe state availability", "height", ts.Height(), "error", err)
+		return skipped, nil
+	}
+	if available {
+		return skipped, nil
+	}
+
+	log.Warnw("state not available, skipping tasks that need it", "height", ts.Height(), "state_root", ts.ParentState().String())
+	for name := range t.processors {
+		if !stateFreeTasks[name] {
+			skipped[name] = StateUnavailableReason
+		}
+	}
+	for name := range t.messageProcessors {
+		skipped[name] = StateUnavailableReason
+	}
+	for name := range t.actorProcessors {
+		skipped[name] = StateUnavailableReason
+	}
+	return skipped, nil
+}
+
+// stateAvailable reports whether the lens holds the state needed to index ts, using the result of probing its range up
+// front when there is one.
+func (t *TipSetIndexer) stateAvailable(ctx context.Context, ts *types.TipSet) (bool, error) {
+	if r := t.probed; r != nil && int64(ts.Height()) >= r.from && int64(ts.Height()) <= r.to {
+		return int64(ts.Height()) >= r.availableFrom, nil
+	}
+	return StateAvailable(ctx, t.node, ts)
+}
+
+// ProbesState reports whether the indexer skips tasks for tipsets whose state the lens does not hold, see StateProbeOpt.
+func (t *TipSetIndexer) ProbesState() bool {
+	return t.probeState
+}
+
+// StateProbed records which heights between from and to have state so the indexer does not probe each tipset in the
+// range. It must be called before the tipsets in the range are indexed.
+func (t *TipSetIndexer) StateProbed(from, to, availableFrom int64) {
+	t.probed = &probedRange{from: from, to: to, availableFrom: availableFrom}
 }
 
 // SkipTipSet writes a processing report to storage for each indexer task to indicate that the entire tipset
-// was not processed.
+// was not processed. Message tasks report against the parent of the tipset, which contains the messages it would
+// have executed, so their reports are omitted if the parent cannot be loaded.
 func (t *TipSetIndexer) SkipTipSet(ctx context.Context, ts *types.TipSet, reason string) error {
 	var reports model.PersistableList
 
@@ -639,30 +1221,96 @@ func (t *TipSetIndexer) SkipTipSet(ctx context.Context, ts *types.TipSet, reason
 		reports = append(reports, t.buildSkippedTipsetReport(ts, name, timestamp, reason))
 	}
 
-	for name := range t.messageProcessors {
-		reports = append(reports, t.buildSkippedTipsetReport(ts, name, timestamp, reason))
+	if len(t.messageProcessors) > 0 && ts.Height() > 0 {
+		parent, err := t.loadParent(ctx, ts)
+		if err != nil {
+			log.Warnw("failed to load parent of skipped tipset, message tasks will not be reported", "height", ts.Height(), "error", err)
+		} else {
+			for name := range t.messageProcessors {
+				reports = append(reports, t.buildSkippedTipsetReport(parent, name, timestamp, reason))
+			}
+		}
 	}
 
 	for name := range t.actorProcessors {
 		reports = append(reports, t.buildSkippedTipsetReport(ts, name, timestamp, reason))
 	}
 
+	// Storage that organises data by task is given each task's report separately
+	if _, ok := t.storage.(model.TaskStorage); ok {
+		for _, report := range reports {
+			if err := t.persistTaskBatch(ctx, report.(*visormodel.ProcessingReport).Task, report); err != nil {
+				return xerrors.Errorf("persist reports: %w", err)
+			}
+		}
+		return nil
+	}
+
 	if err := t.storage.PersistBatch(ctx, reports...); err != nil {
 		return xerrors.Errorf("persist reports: %w", err)
 	}
 	return nil
 }
 
+// RevertTipSet flags any data that was persisted for a tipset as non-canonical following a reorg. It is a no-op if
+// the storage does not support tracking canonical data.
+func (t *TipSetIndexer) RevertTipSet(ctx context.Context, ts *types.TipSet) error {
+	rs, ok := t.storage.(model.ReorgStorage)
+	if !ok {
+		return nil
+	}
+
+	blocks := make([]string, 0, len(ts.Cids()))
+	for _, c := range ts.Cids() {
+		blocks = append(blocks, c.String())
+	}
+
+	log.Infow("marking reverted tipset as non-canonical", "height", ts.Height(), "tipset", ts.Key())
+	if err := rs.MarkNonCanonical(ctx, int64(ts.Height()), ts.ParentState().String(), blocks); err != nil {
+		return xerrors.Errorf("mark non-canonical: %w", err)
+	}
+	return nil
+}
+
+func (t *TipSetIndexer) buildErrorReport(ts *types.TipSet, taskName string, start time.Time, err error) *visormodel.ProcessingReport {
+	return &visormodel.ProcessingReport{
+		Height:         int64(ts.Height()),
+		StateRoot:      ts.ParentState().String(),
+		Reporter:       t.name,
+		RunID:          t.runID,
+		Task:           taskName,
+		StartedAt:      start,
+		CompletedAt:    time.Now(),
+		Status:         visormodel.ProcessingStatusError,
+		ErrorsDetected: err,
+		ErrorClass:     visormodel.ClassifyError(err),
+		TipSet:         visormodel.EncodeTipSetKey(ts.Key()),
+	}
+}
+
+// loadParent loads the parent of a tipset with a lens of its own since it may be called while the indexer's lens is
+// in use by another tipset.
+func (t *TipSetIndexer) loadParent(ctx context.Context, ts *types.TipSet) (*types.TipSet, error) {
+	node, closer, err := t.opener.Open(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open lens: %w", err)
+	}
+	defer closer()
+	return node.ChainGetTipSet(ctx, ts.Parents())
+}
+
 func (t *TipSetIndexer) buildSkippedTipsetReport(ts *types.TipSet, taskName string, timestamp time.Time, reason string) *visormodel.ProcessingReport {
 	return &visormodel.ProcessingReport{
 		Height:            int64(ts.Height()),
 		StateRoot:         ts.ParentState().String(),
 		Reporter:          t.name,
+		RunID:             t.runID,
 		Task:              taskName,
 		StartedAt:         timestamp,
 		CompletedAt:       timestamp,
 		Status:            visormodel.ProcessingStatusSkip,
 		StatusInformation: reason,
+		TipSet:            visormodel.EncodeTipSetKey(ts.Key()),
 	}
 }
 
@@ -673,10 +1321,36 @@ type TaskResult struct {
 	Error       error
 	Report      *visormodel.ProcessingReport
 	Data        model.Persistable
+	TipSet      string // key of the tipset the report is written for, encoded using visor.EncodeTipSetKey
 	StartedAt   time.Time
 	CompletedAt time.Time
 }
 
+// A taskOutput is the processing report produced by a task for a tipset and any data that accompanies it.
+type taskOutput struct {
+	report    *visormodel.ProcessingReport
+	data      model.Persistable
+	collector *model.Collector // models persisted from data by the latest attempt to persist it
+}
+
+// Persist persists the data produced by the task through a collector so the rows written can be counted and
+// inspected without persisting them a second time. A checksum of the data is recorded in the report so the output of
+// independent instances can be compared, which requires the report to be persisted after the data.
+func (o *taskOutput) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	o.collector = model.NewCollector(s)
+	if err := o.data.Persist(ctx, o.collector, version); err != nil {
+		return err
+	}
+
+	checksum, err := model.Checksum(o.collector.Models())
+	if err != nil {
+		log.Warnw("failed to compute checksum of task data", "task", o.report.Task, "error", err)
+		return nil
+	}
+	o.report.Checksum = checksum
+	return nil
+}
+
 type TipSetProcessor interface {
 	// ProcessTipSet processes a tipset. If error is non-nil then the processor encountered a fatal error.
 	// Any data returned must be accompanied by a processing report.
@@ -0,0 +1,134 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+// fakeStateLens serves a chain with a tipset at every height and holds the state of the tipsets at or above prunedTo,
+// all other lens methods are unimplemented.
+type fakeStateLens struct {
+	lens.API
+	tipsets  []*types.TipSet // indexed by height
+	prunedTo int64
+}
+
+func newFakeStateLens(t *testing.T, height int64) *fakeStateLens {
+	f := &fakeStateLens{}
+	var parents []cid.Cid
+	for h := int64(0); h <= height; h++ {
+		bh := testutil.FakeBlockHeader(t, h, testutil.RandomCid())
+		bh.Parents = parents
+		ts, err := types.NewTipSet([]*types.BlockHeader{bh})
+		require.NoError(t, err)
+		f.tipsets = append(f.tipsets, ts)
+		parents = ts.Cids()
+	}
+	return f
+}
+
+func (f *fakeStateLens) ChainHasObj(ctx context.Context, obj cid.Cid) (bool, error) {
+	for h, ts := range f.tipsets {
+		if ts.ParentState() == obj {
+			return int64(h) >= f.prunedTo, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStateLens) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	for _, ts := range f.tipsets {
+		if ts.Key() == tsk {
+			return ts, nil
+		}
+	}
+	return nil, xerrors.Errorf("tipset %s not found", tsk)
+}
+
+func (f *fakeStateLens) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	if int(h) >= len(f.tipsets) {
+		return nil, xerrors.Errorf("height %d beyond head", h)
+	}
+	return f.tipsets[h], nil
+}
+
+func TestSkippedTasksWhenStateUnavailable(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeStateLens(t, 10)
+	node.prunedTo = 5
+
+	idx := &TipSetIndexer{
+		node:              node,
+		probeState:        true,
+		processors:        map[string]TipSetProcessor{BlocksTask: nil, ChainEconomicsTask: nil},
+		messageProcessors: map[string]MessageProcessor{MessagesTask: nil},
+		actorProcessors:   map[string]ActorProcessor{ActorStatesRawTask: nil},
+	}
+	idx.ShedTasks([]string{ChainEconomicsTask})
+
+	unavailable := map[string]string{
+		ChainEconomicsTask: StateUnavailableReason,
+		MessagesTask:       StateUnavailableReason,
+		ActorStatesRawTask: StateUnavailableReason,
+	}
+	available := map[string]string{ChainEconomicsTask: TaskShedReason}
+
+	skipped, err := idx.skippedTasks(ctx, node.tipsets[4])
+	require.NoError(t, err)
+	assert.Equal(t, unavailable, skipped)
+
+	// the tipset's own state is held but not that of its parent
+	skipped, err = idx.skippedTasks(ctx, node.tipsets[5])
+	require.NoError(t, err)
+	assert.Equal(t, unavailable, skipped)
+
+	skipped, err = idx.skippedTasks(ctx, node.tipsets[6])
+	require.NoError(t, err)
+	assert.Equal(t, available, skipped)
+
+	// a range probed up front is not probed again
+	idx.StateProbed(0, 8, 8)
+	skipped, err = idx.skippedTasks(ctx, node.tipsets[7])
+	require.NoError(t, err)
+	assert.Equal(t, unavailable, skipped)
+
+	skipped, err = idx.skippedTasks(ctx, node.tipsets[9])
+	require.NoError(t, err)
+	assert.Equal(t, available, skipped)
+}
+
+func TestStateAvailableFrom(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeStateLens(t, 20)
+
+	for _, prunedTo := range []int64{0, 1, 7, 20, 21} {
+		node.prunedTo = prunedTo
+		// a tipset also needs the state of its parent
+		want := prunedTo + 1
+		if prunedTo == 0 {
+			want = 0
+		}
+		if want > 20 {
+			want = 21
+		}
+
+		from, err := StateAvailableFrom(ctx, node, 0, 20)
+		require.NoError(t, err)
+		assert.Equal(t, want, from, "pruned to %d", prunedTo)
+	}
+
+	node.prunedTo = 7
+	from, err := StateAvailableFrom(ctx, node, 10, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, from)
+}
@@ -0,0 +1,328 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// A Semaphore limits the number of operations that may be in progress at once. A nil Semaphore imposes no limit.
+type Semaphore chan struct{}
+
+// NewSemaphore returns a semaphore allowing n operations at once, or nil if n is not positive.
+func NewSemaphore(n int) Semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(Semaphore, n)
+}
+
+// Acquire waits until an operation may start or the context is done.
+func (s Semaphore) Acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release marks an operation started by Acquire as finished.
+func (s Semaphore) Release() {
+	if s == nil {
+		return
+	}
+	<-s
+}
+
+// JobLimits bounds the load a job places on the lens and storage it shares with the other jobs of a daemon, so a
+// backfill cannot starve a watch of the node or the database. The limits are shared by every indexer of the job.
+type JobLimits struct {
+	lensCalls      int
+	persistBatches int
+	lens           Semaphore
+	persist        Semaphore
+}
+
+// NewJobLimits returns limits allowing at most lensCalls lens calls and persistBatches batches being persisted at once.
+// Zero means no limit. Nil is returned if neither is limited.
+func NewJobLimits(lensCalls, persistBatches int) *JobLimits {
+	if lensCalls <= 0 && persistBatches <= 0 {
+		return nil
+	}
+	return &JobLimits{
+		lensCalls:      lensCalls,
+		persistBatches: persistBatches,
+		lens:           NewSemaphore(lensCalls),
+		persist:        NewSemaphore(persistBatches),
+	}
+}
+
+func (l *JobLimits) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	if l == nil {
+		return out
+	}
+	if l.lensCalls > 0 {
+		out["maxLensCalls"] = l.lensCalls
+		out["lensCalls"] = len(l.lens)
+	}
+	if l.persistBatches > 0 {
+		out["maxPersistBatches"] = l.persistBatches
+		out["persistBatches"] = len(l.persist)
+	}
+	return out
+}
+
+// Opener returns an opener for lenses whose calls are limited, or o itself if lens calls are not limited.
+func (l *JobLimits) Opener(o lens.APIOpener) lens.APIOpener {
+	if l == nil || l.lens == nil {
+		return o
+	}
+	return &limitedOpener{opener: o, sem: l.lens}
+}
+
+// PersistLimitOpt configures the indexer to wait for the job's limits to allow it before persisting each batch of task
+// output.
+func PersistLimitOpt(l *JobLimits) TipSetIndexerOpt {
+	return func(t *TipSetIndexer) {
+		if l != nil {
+			t.persistLimit = l.persist
+		}
+	}
+}
+
+type limitedOpener struct {
+	opener lens.APIOpener
+	sem    Semaphore
+}
+
+func (o *limitedOpener) Open(ctx context.Context) (lens.API, lens.APICloser, error) {
+	node, closer, err := o.opener.Open(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &limitedAPI{api: node, sem: o.sem}, closer, nil
+}
+
+// limitedAPI holds a slot of its semaphore for the duration of each call to the lens it wraps. Subscriptions only hold
+// a slot while they are being made.
+type limitedAPI struct {
+	api lens.API
+	sem Semaphore
+}
+
+var (
+	_ lens.API               = (*limitedAPI)(nil)
+	_ lens.ExecutionTraceAPI = (*limitedAPI)(nil)
+)
+
+func (a *limitedAPI) Store() adt.Store {
+	return &limitedStore{Store: a.api.Store(), sem: a.sem}
+}
+
+func (a *limitedAPI) GetExecutedAndBlockMessagesForTipset(ctx context.Context, ts, pts *types.TipSet) (*lens.TipSetMessages, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.GetExecutedAndBlockMessagesForTipset(ctx, ts, pts)
+}
+
+func (a *limitedAPI) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainNotify(ctx)
+}
+
+func (a *limitedAPI) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainHead(ctx)
+}
+
+func (a *limitedAPI) ChainHasObj(ctx context.Context, obj cid.Cid) (bool, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return false, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainHasObj(ctx, obj)
+}
+
+func (a *limitedAPI) ChainReadObj(ctx context.Context, obj cid.Cid) ([]byte, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainReadObj(ctx, obj)
+}
+
+func (a *limitedAPI) ChainGetGenesis(ctx context.Context) (*types.TipSet, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetGenesis(ctx)
+}
+
+func (a *limitedAPI) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetTipSet(ctx, tsk)
+}
+
+func (a *limitedAPI) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetTipSetByHeight(ctx, h, tsk)
+}
+
+func (a *limitedAPI) ChainGetBlockMessages(ctx context.Context, msg cid.Cid) (*api.BlockMessages, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetBlockMessages(ctx, msg)
+}
+
+func (a *limitedAPI) ChainGetParentMessages(ctx context.Context, blockCid cid.Cid) ([]api.Message, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetParentMessages(ctx, blockCid)
+}
+
+func (a *limitedAPI) ChainGetParentReceipts(ctx context.Context, blockCid cid.Cid) ([]*types.MessageReceipt, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.ChainGetParentReceipts(ctx, blockCid)
+}
+
+func (a *limitedAPI) StateGetActor(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateGetActor(ctx, addr, tsk)
+}
+
+func (a *limitedAPI) StateListActors(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateListActors(ctx, tsk)
+}
+
+func (a *limitedAPI) StateChangedActors(ctx context.Context, old, new cid.Cid) (map[string]types.Actor, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateChangedActors(ctx, old, new)
+}
+
+func (a *limitedAPI) StateMinerPower(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*api.MinerPower, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateMinerPower(ctx, addr, tsk)
+}
+
+func (a *limitedAPI) StateMarketDeals(ctx context.Context, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateMarketDeals(ctx, tsk)
+}
+
+func (a *limitedAPI) StateReadState(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateReadState(ctx, addr, tsk)
+}
+
+func (a *limitedAPI) StateGetReceipt(ctx context.Context, msg cid.Cid, tsk types.TipSetKey) (*types.MessageReceipt, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return a.api.StateGetReceipt(ctx, msg, tsk)
+}
+
+func (a *limitedAPI) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return api.CirculatingSupply{}, err
+	}
+	defer a.sem.Release()
+	return a.api.StateVMCirculatingSupplyInternal(ctx, tsk)
+}
+
+func (a *limitedAPI) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
+	if err := a.sem.Acquire(ctx); err != nil {
+		return "", err
+	}
+	defer a.sem.Release()
+	return a.api.StateNetworkName(ctx)
+}
+
+func (a *limitedAPI) StateCompute(ctx context.Context, height abi.ChainEpoch, msgs []*types.Message, tsk types.TipSetKey) (*api.ComputeStateOutput, error) {
+	tracer, ok := a.api.(lens.ExecutionTraceAPI)
+	if !ok {
+		return nil, xerrors.Errorf("lens does not support execution traces")
+	}
+	if err := a.sem.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.sem.Release()
+	return tracer.StateCompute(ctx, height, msgs, tsk)
+}
+
+// limitedStore holds a slot of its semaphore while reading or writing each object.
+type limitedStore struct {
+	adt.Store
+	sem Semaphore
+}
+
+func (s *limitedStore) Get(ctx context.Context, c cid.Cid, out interface{}) error {
+	if err := s.sem.Acquire(ctx); err != nil {
+		return err
+	}
+	defer s.sem.Release()
+	return s.Store.Get(ctx, c, out)
+}
+
+func (s *limitedStore) Put(ctx context.Context, v interface{}) (cid.Cid, error) {
+	if err := s.sem.Acquire(ctx); err != nil {
+		return cid.Undef, err
+	}
+	defer s.sem.Release()
+	return s.Store.Put(ctx, v)
+}
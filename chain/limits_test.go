@@ -0,0 +1,55 @@
+package chain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		s := NewSemaphore(0)
+		require.Nil(t, s)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, s.Acquire(context.Background()))
+		}
+		s.Release()
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		s := NewSemaphore(2)
+		require.NoError(t, s.Acquire(context.Background()))
+		require.NoError(t, s.Acquire(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, s.Acquire(ctx), context.DeadlineExceeded)
+
+		s.Release()
+		require.NoError(t, s.Acquire(context.Background()))
+	})
+}
+
+func TestJobLimits(t *testing.T) {
+	assert.Nil(t, NewJobLimits(0, 0))
+
+	var unlimited *JobLimits
+	assert.Empty(t, unlimited.Params())
+	assert.Nil(t, unlimited.Opener(nil))
+
+	l := NewJobLimits(0, 3)
+	require.NotNil(t, l)
+	assert.Nil(t, l.lens)
+	require.NoError(t, l.persist.Acquire(context.Background()))
+	assert.Equal(t, map[string]interface{}{
+		"maxPersistBatches": 3,
+		"persistBatches":    1,
+	}, l.Params())
+
+	indexer := &TipSetIndexer{}
+	PersistLimitOpt(l)(indexer)
+	assert.Equal(t, l.persist, indexer.persistLimit)
+}
@@ -0,0 +1,138 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
+)
+
+// A NodeSyncObserver is a job that periodically records the sync state of the node the lens is connected to, so gaps
+// in the data can later be correlated with periods when the node was not in sync.
+type NodeSyncObserver struct {
+	opener   lens.APIOpener
+	storage  model.Storage
+	name     string        // recorded as the observer of each sync state
+	interval time.Duration // time between observations
+}
+
+func NewNodeSyncObserver(opener lens.APIOpener, storage model.Storage, name string, interval time.Duration) *NodeSyncObserver {
+	return &NodeSyncObserver{
+		opener:   opener,
+		storage:  storage,
+		name:     name,
+		interval: interval,
+	}
+}
+
+func (o *NodeSyncObserver) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["interval"] = o.interval.String()
+	return out
+}
+
+// Run records the sync state of the node until the context is done.
+func (o *NodeSyncObserver) Run(ctx context.Context) error {
+	node, closer, err := o.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	syncer, ok := node.(lens.SyncStateAPI)
+	if !ok {
+		return xerrors.Errorf("lens does not report sync state")
+	}
+
+	genesis, err := node.ChainGetGenesis(ctx)
+	if err != nil {
+		return xerrors.Errorf("get genesis: %w", err)
+	}
+	genesisTime := time.Unix(int64(genesis.MinTimestamp()), 0)
+
+	for {
+		start := time.Now()
+		obs, err := o.observe(ctx, node, syncer, genesisTime)
+		if err != nil {
+			return err
+		}
+		if err := o.storage.PersistBatch(ctx, obs); err != nil {
+			return xerrors.Errorf("persist observed node sync: %w", err)
+		}
+		log.Debugw("observed node sync", "head_height", obs.HeadHeight, "behind", obs.Behind, "workers", len(obs.Workers))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.interval - time.Since(start)):
+		}
+	}
+}
+
+func (o *NodeSyncObserver) observe(ctx context.Context, node lens.API, syncer lens.SyncStateAPI, genesisTime time.Time) (*chainmodel.ObservedNodeSync, error) {
+	now := time.Now().UTC()
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get chain head: %w", err)
+	}
+
+	state, err := syncer.SyncState(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get sync state: %w", err)
+	}
+
+	return NewObservedNodeSync(o.name, now, genesisTime, int64(head.Height()), time.Unix(int64(head.MinTimestamp()), 0).UTC(), state), nil
+}
+
+// NewObservedNodeSync returns the record of a sync state observed at now by a node whose chain head is at headHeight.
+func NewObservedNodeSync(observer string, now, genesisTime time.Time, headHeight int64, headTime time.Time, state *api.SyncState) *chainmodel.ObservedNodeSync {
+	obs := &chainmodel.ObservedNodeSync{
+		ObservedAt:    now,
+		Observer:      observer,
+		HeadHeight:    headHeight,
+		HeadTimestamp: headTime,
+		Workers:       []chainmodel.NodeSyncWorker{},
+	}
+
+	expected := int64(now.Sub(genesisTime) / (builtin.EpochDurationSeconds * time.Second))
+	if expected > headHeight {
+		obs.Behind = expected - headHeight
+	}
+
+	if state == nil {
+		return obs
+	}
+	for _, ss := range state.ActiveSyncs {
+		w := chainmodel.NodeSyncWorker{
+			WorkerID: ss.WorkerID,
+			Stage:    ss.Stage.String(),
+			Height:   int64(ss.Height),
+		}
+		if ss.Base != nil {
+			w.BaseHeight = int64(ss.Base.Height())
+		}
+		if ss.Target != nil {
+			w.TargetHeight = int64(ss.Target.Height())
+		}
+		if ss.Stage == api.StageSyncErrored {
+			w.Error = ss.Message
+		}
+		if !ss.Start.IsZero() {
+			started := ss.Start.UTC()
+			w.StartedAt = &started
+		}
+		if !ss.End.IsZero() {
+			ended := ss.End.UTC()
+			w.EndedAt = &ended
+		}
+		obs.Workers = append(obs.Workers, w)
+	}
+	return obs
+}
@@ -0,0 +1,49 @@
+package chain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewObservedNodeSync(t *testing.T) {
+	genesis := time.Date(2020, 8, 24, 22, 0, 0, 0, time.UTC)
+	now := genesis.Add(1000 * 30 * time.Second)
+	headTime := genesis.Add(990 * 30 * time.Second)
+	started := now.Add(-time.Minute)
+
+	state := &api.SyncState{
+		ActiveSyncs: []api.ActiveSync{
+			{WorkerID: 1, Stage: api.StageMessages, Height: 995, Start: started},
+			{WorkerID: 2, Stage: api.StageSyncErrored, Height: 980, Message: "validating block: bad parent weight", Start: started, End: now},
+			{WorkerID: 3, Stage: api.StageIdle},
+		},
+	}
+
+	obs := NewObservedNodeSync("observer", now, genesis, 990, headTime, state)
+	assert.Equal(t, "observer", obs.Observer)
+	assert.EqualValues(t, 990, obs.HeadHeight)
+	assert.EqualValues(t, 10, obs.Behind)
+	require.Len(t, obs.Workers, 3)
+
+	assert.EqualValues(t, 1, obs.Workers[0].WorkerID)
+	assert.Equal(t, api.StageMessages.String(), obs.Workers[0].Stage)
+	assert.EqualValues(t, 995, obs.Workers[0].Height)
+	assert.Empty(t, obs.Workers[0].Error)
+	require.NotNil(t, obs.Workers[0].StartedAt)
+	assert.True(t, started.Equal(*obs.Workers[0].StartedAt))
+	assert.Nil(t, obs.Workers[0].EndedAt)
+
+	assert.Equal(t, "validating block: bad parent weight", obs.Workers[1].Error)
+	require.NotNil(t, obs.Workers[1].EndedAt)
+
+	assert.Nil(t, obs.Workers[2].StartedAt)
+
+	// A node ahead of the expected epoch, for example due to clock skew, is not behind
+	obs = NewObservedNodeSync("observer", now, genesis, 1001, now, nil)
+	assert.EqualValues(t, 0, obs.Behind)
+	assert.Empty(t, obs.Workers)
+}
@@ -0,0 +1,74 @@
+package chain
+
+import (
+	"sync"
+	"time"
+)
+
+// An IndexedTipSet describes a tipset whose task outputs have been persisted by a TipSetIndexer.
+type IndexedTipSet struct {
+	Height      int64         `json:"height"`
+	TipSet      string        `json:"tipset"`     // key of the tipset encoded using visor.EncodeTipSetKey
+	StateRoot   string        `json:"state_root"` // parent state root of the tipset
+	Reporter    string        `json:"reporter"`   // name of the indexer
+	Tasks       []IndexedTask `json:"tasks"`
+	CompletedAt time.Time     `json:"completed_at"`
+}
+
+// An IndexedTask describes the outcome of a single task for an indexed tipset.
+type IndexedTask struct {
+	Task      string `json:"task"`
+	Status    string `json:"status"`    // status of the task's processing report
+	Persisted bool   `json:"persisted"` // false if the data was already present or could not be persisted
+	Rows      int    `json:"rows"`      // number of rows produced by the task, excluding its report
+}
+
+// subscriberBuffer is the number of events that may be queued for a subscriber before further events are dropped.
+const subscriberBuffer = 256
+
+// An IndexNotifier broadcasts IndexedTipSet events to any number of subscribers. Subscribers that do not keep up
+// miss events rather than slowing indexing.
+type IndexNotifier struct {
+	mu   sync.Mutex
+	subs map[chan *IndexedTipSet]struct{}
+}
+
+func NewIndexNotifier() *IndexNotifier {
+	return &IndexNotifier{
+		subs: map[chan *IndexedTipSet]struct{}{},
+	}
+}
+
+// Subscribe returns a channel that receives events and a function that cancels the subscription and closes the
+// channel.
+func (n *IndexNotifier) Subscribe() (<-chan *IndexedTipSet, func()) {
+	ch := make(chan *IndexedTipSet, subscriberBuffer)
+
+	n.mu.Lock()
+	n.subs[ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			delete(n.subs, ch)
+			n.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Notify sends an event to all current subscribers without blocking.
+func (n *IndexNotifier) Notify(ev *IndexedTipSet) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.Warnw("dropping indexed tipset event for slow subscriber", "height", ev.Height)
+		}
+	}
+}
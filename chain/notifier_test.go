@@ -0,0 +1,40 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexNotifier(t *testing.T) {
+	n := NewIndexNotifier()
+
+	ch1, cancel1 := n.Subscribe()
+	ch2, cancel2 := n.Subscribe()
+	defer cancel2()
+
+	n.Notify(&IndexedTipSet{Height: 1})
+	require.Equal(t, int64(1), (<-ch1).Height)
+	require.Equal(t, int64(1), (<-ch2).Height)
+
+	cancel1()
+	cancel1() // cancelling twice is harmless
+	_, ok := <-ch1
+	assert.False(t, ok, "channel should be closed after cancel")
+
+	n.Notify(&IndexedTipSet{Height: 2})
+	assert.Equal(t, int64(2), (<-ch2).Height)
+}
+
+func TestIndexNotifierDropsForSlowSubscriber(t *testing.T) {
+	n := NewIndexNotifier()
+	ch, cancel := n.Subscribe()
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		n.Notify(&IndexedTipSet{Height: int64(i)})
+	}
+	assert.Len(t, ch, subscriberBuffer)
+	assert.Equal(t, int64(0), (<-ch).Height)
+}
@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+)
+
+// A BlockPropagationObserver is a job that records the time each block is first received from the network, and the
+// peer it was received from, so that block propagation delays can be measured.
+type BlockPropagationObserver struct {
+	api     lens.BlockGossipAPI
+	storage model.Storage
+	name    string // recorded as the observer of each block
+}
+
+func NewBlockPropagationObserver(api lens.BlockGossipAPI, storage model.Storage, name string) *BlockPropagationObserver {
+	return &BlockPropagationObserver{
+		api:     api,
+		storage: storage,
+		name:    name,
+	}
+}
+
+// Run records blocks until the context is done or the subscription fails.
+func (o *BlockPropagationObserver) Run(ctx context.Context) error {
+	blks, err := o.api.SubscribeBlockGossip(ctx)
+	if err != nil {
+		return xerrors.Errorf("subscribe to block gossip: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case blk, ok := <-blks:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return xerrors.Errorf("block gossip subscription closed")
+			}
+
+			obs := &blocks.ObservedBlockPropagation{
+				Height:     int64(blk.Header.Height),
+				Cid:        blk.Header.Cid().String(),
+				Observer:   o.name,
+				Miner:      blk.Header.Miner.String(),
+				PeerID:     blk.Peer.String(),
+				BlockTime:  time.Unix(int64(blk.Header.Timestamp), 0).UTC(),
+				ReceivedAt: blk.ReceivedAt.UTC(),
+			}
+			log.Debugw("observed block", "height", obs.Height, "cid", obs.Cid, "peer", obs.PeerID, "delay", obs.ReceivedAt.Sub(obs.BlockTime))
+
+			// Unless the storage upserts, rows already present are left unchanged so the first receipt is kept if a
+			// block is seen again after a restart
+			if err := o.storage.PersistBatch(ctx, obs); err != nil {
+				return xerrors.Errorf("persist observed block: %w", err)
+			}
+		}
+	}
+}
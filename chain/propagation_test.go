@@ -0,0 +1,54 @@
+package chain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+type fakeGossip struct {
+	blks chan *lens.GossipBlock
+}
+
+func (g *fakeGossip) SubscribeBlockGossip(ctx context.Context) (<-chan *lens.GossipBlock, error) {
+	return g.blks, nil
+}
+
+type recordingStorage struct {
+	persisted []model.Persistable
+}
+
+func (s *recordingStorage) PersistBatch(ctx context.Context, ps ...model.Persistable) error {
+	s.persisted = append(s.persisted, ps...)
+	return nil
+}
+
+func TestBlockPropagationObserver(t *testing.T) {
+	bh := testutil.FakeBlockHeader(t, 10, testutil.RandomCid())
+	received := time.Unix(int64(bh.Timestamp)+3, 0)
+
+	g := &fakeGossip{blks: make(chan *lens.GossipBlock, 1)}
+	g.blks <- &lens.GossipBlock{Header: bh, Peer: peer.ID("peer1"), ReceivedAt: received}
+	close(g.blks)
+
+	strg := &recordingStorage{}
+	err := NewBlockPropagationObserver(g, strg, "observer1").Run(context.Background())
+	require.Error(t, err, "closed subscription should be reported")
+
+	require.Len(t, strg.persisted, 1)
+	obs := strg.persisted[0].(*blocks.ObservedBlockPropagation)
+	assert.Equal(t, int64(10), obs.Height)
+	assert.Equal(t, bh.Cid().String(), obs.Cid)
+	assert.Equal(t, "observer1", obs.Observer)
+	assert.Equal(t, peer.ID("peer1").String(), obs.PeerID)
+	assert.Equal(t, 3*time.Second, obs.ReceivedAt.Sub(obs.BlockTime))
+}
@@ -0,0 +1,172 @@
+package chain
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/retrieval"
+)
+
+// A RetrievalTargetSource chooses the deals whose payloads are queried for retrieval.
+type RetrievalTargetSource interface {
+	// RetrievalTargets returns up to limit deals that are active at height.
+	RetrievalTargets(ctx context.Context, height int64, limit int) ([]retrieval.RetrievalTarget, error)
+}
+
+// A RetrievalObserver is a job that periodically asks a sample of miners whether they will serve a retrieval of the
+// payload of one of their active deals, and records each response.
+type RetrievalObserver struct {
+	opener   lens.APIOpener
+	targets  RetrievalTargetSource
+	storage  model.Storage
+	name     string        // recorded as the observer of each event
+	sample   int           // number of miners to query in each round
+	interval time.Duration // time between the start of each round
+	timeout  time.Duration // time allowed for each query
+}
+
+func NewRetrievalObserver(opener lens.APIOpener, targets RetrievalTargetSource, storage model.Storage, name string, sample int, interval, timeout time.Duration) *RetrievalObserver {
+	return &RetrievalObserver{
+		opener:   opener,
+		targets:  targets,
+		storage:  storage,
+		name:     name,
+		sample:   sample,
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+func (o *RetrievalObserver) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["sample"] = o.sample
+	out["interval"] = o.interval.String()
+	out["timeout"] = o.timeout.String()
+	return out
+}
+
+// Run queries miners until the context is done.
+func (o *RetrievalObserver) Run(ctx context.Context) error {
+	node, closer, err := o.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	markets, ok := node.(lens.RetrievalMarketAPI)
+	if !ok {
+		return xerrors.Errorf("lens does not support retrieval queries")
+	}
+
+	for {
+		start := time.Now()
+		if err := o.observe(ctx, node, markets); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.interval - time.Since(start)):
+		}
+	}
+}
+
+func (o *RetrievalObserver) observe(ctx context.Context, node lens.API, markets lens.RetrievalMarketAPI) error {
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("get chain head: %w", err)
+	}
+
+	targets, err := o.targets.RetrievalTargets(ctx, int64(head.Height()), o.sample)
+	if err != nil {
+		return xerrors.Errorf("get retrieval targets: %w", err)
+	}
+
+	events := make(retrieval.ObservedRetrievalEventList, 0, len(targets))
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ev, err := o.query(ctx, markets, t)
+		if err != nil {
+			log.Warnw("skipping retrieval target", "deal", t.DealID, "miner", t.ProviderID, "error", err)
+			continue
+		}
+		log.Debugw("observed retrieval query", "miner", ev.MinerID, "payload", ev.PayloadCid, "event", ev.Event, "response_time_ms", ev.ResponseTimeMs)
+		events = append(events, ev)
+	}
+
+	if err := o.storage.PersistBatch(ctx, events); err != nil {
+		return xerrors.Errorf("persist observed retrieval events: %w", err)
+	}
+	log.Infow("observed retrieval queries", "count", len(events), "height", head.Height())
+	return nil
+}
+
+// query asks the provider of the target for a retrieval offer. An error is only returned if the target is invalid;
+// failures to query the miner are recorded in the event.
+func (o *RetrievalObserver) query(ctx context.Context, markets lens.RetrievalMarketAPI, t retrieval.RetrievalTarget) (*retrieval.ObservedRetrievalEvent, error) {
+	miner, err := address.NewFromString(t.ProviderID)
+	if err != nil {
+		return nil, xerrors.Errorf("parse provider address: %w", err)
+	}
+	root, err := cid.Decode(t.PayloadCid)
+	if err != nil {
+		return nil, xerrors.Errorf("parse payload cid: %w", err)
+	}
+	var piece *cid.Cid
+	if t.PieceCid != "" {
+		c, err := cid.Decode(t.PieceCid)
+		if err != nil {
+			return nil, xerrors.Errorf("parse piece cid: %w", err)
+		}
+		piece = &c
+	}
+
+	ev := &retrieval.ObservedRetrievalEvent{
+		ObservedAt: time.Now().UTC(),
+		Observer:   o.name,
+		MinerID:    t.ProviderID,
+		PayloadCid: t.PayloadCid,
+		PieceCid:   t.PieceCid,
+		DealID:     t.DealID,
+	}
+
+	qctx, cancel := context.WithTimeout(ctx, o.timeout)
+	offer, err := markets.ClientMinerQueryOffer(qctx, miner, root, piece)
+	cancel()
+	ev.ResponseTimeMs = time.Since(ev.ObservedAt).Milliseconds()
+
+	switch {
+	case err != nil:
+		ev.Event = retrieval.EventError
+		ev.Error = err.Error()
+	case offer.Err != "":
+		// The markets client reports both refusals and failures to reach the miner in the offer
+		ev.Event = retrieval.EventError
+		if strings.Contains(offer.Err, "unavailable") {
+			ev.Event = retrieval.EventUnavailable
+		}
+		ev.Error = offer.Err
+	default:
+		ev.Event = retrieval.EventOffer
+		ev.Size = offer.Size
+		ev.MinPrice = offer.MinPrice.String()
+		ev.UnsealPrice = offer.UnsealPrice.String()
+		ev.PaymentInterval = offer.PaymentInterval
+		ev.PaymentIntervalIncrease = offer.PaymentIntervalIncrease
+	}
+	if offer.MinerPeer.ID != "" {
+		ev.PeerID = offer.MinerPeer.ID.String()
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,71 @@
+package chain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/retrieval"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+// fakeMarkets answers retrieval queries with the offer or error configured for each miner.
+type fakeMarkets struct {
+	offers map[string]api.QueryOffer
+	errs   map[string]error
+}
+
+func (m *fakeMarkets) ClientMinerQueryOffer(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (api.QueryOffer, error) {
+	if err := m.errs[miner.String()]; err != nil {
+		return api.QueryOffer{}, err
+	}
+	return m.offers[miner.String()], nil
+}
+
+func TestRetrievalObserverQuery(t *testing.T) {
+	payload := testutil.RandomCid()
+	markets := &fakeMarkets{
+		offers: map[string]api.QueryOffer{
+			"f01000": {Root: payload, Size: 2048, MinPrice: types.NewInt(100), UnsealPrice: types.NewInt(10), PaymentInterval: 1 << 20},
+			"f01001": {Err: "retrieval query offer was unavailable: not found"},
+			"f01002": {Err: "failed to dial peer"},
+		},
+		errs: map[string]error{
+			"f01003": xerrors.Errorf("rpc failed"),
+		},
+	}
+
+	o := NewRetrievalObserver(nil, nil, nil, "observer1", 10, time.Minute, time.Second)
+	query := func(miner string) *retrieval.ObservedRetrievalEvent {
+		ev, err := o.query(context.Background(), markets, retrieval.RetrievalTarget{DealID: 7, ProviderID: miner, PayloadCid: payload.String()})
+		require.NoError(t, err)
+		assert.Equal(t, "observer1", ev.Observer)
+		assert.Equal(t, int64(7), ev.DealID)
+		return ev
+	}
+
+	ev := query("f01000")
+	assert.Equal(t, retrieval.EventOffer, ev.Event)
+	assert.Equal(t, uint64(2048), ev.Size)
+	assert.Equal(t, "100", ev.MinPrice)
+	assert.Equal(t, "10", ev.UnsealPrice)
+	assert.Empty(t, ev.Error)
+
+	ev = query("f01001")
+	assert.Equal(t, retrieval.EventUnavailable, ev.Event)
+	assert.Empty(t, ev.MinPrice)
+
+	assert.Equal(t, retrieval.EventError, query("f01002").Event)
+	assert.Equal(t, "rpc failed", query("f01003").Error)
+
+	_, err := o.query(context.Background(), markets, retrieval.RetrievalTarget{ProviderID: "f01000", PayloadCid: "not a cid"})
+	assert.Error(t, err)
+}
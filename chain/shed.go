@@ -0,0 +1,98 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/filecoin-project/lotus/chain/types"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+)
+
+// TaskShedReason is recorded in the processing reports of tasks that were skipped because they had been shed.
+const TaskShedReason = "task shed while indexer is behind the chain head"
+
+// A TaskShedder can stop running some of its tasks to reduce the work done for each tipset.
+type TaskShedder interface {
+	// ShedTasks stops the named tasks from running until RestoreTasks is called.
+	ShedTasks(tasks []string)
+
+	// RestoreTasks runs all tasks again.
+	RestoreTasks()
+}
+
+// An Alerter is told when a watcher starts and stops shedding tasks. It is satisfied by alerts.Notifier.
+type Alerter interface {
+	Alert(ctx context.Context, key string, msg string) error
+	Resolve(ctx context.Context, key string, msg string) error
+}
+
+// A WatcherOpt configures optional behaviour of a Watcher.
+type WatcherOpt func(w *Watcher)
+
+// LagSheddingOpt configures the watcher to shed tasks when the tipsets it indexes fall more than threshold epochs
+// behind the chain head, so the remaining tasks are kept up to date under load. The tasks are restored once the lag
+// is back to half the threshold. The observer given to the watcher must be a TaskShedder. alerter is told when
+// shedding starts and stops and may be nil.
+func LagSheddingOpt(threshold int64, tasks []string, alerter Alerter) WatcherOpt {
+	return func(w *Watcher) {
+		w.shedThreshold = threshold
+		w.shedTasks = tasks
+		w.alerter = alerter
+	}
+}
+
+// shedAlertKey identifies shedding alerts so that repeated alerts are subject to the alerter's cooldown.
+const shedAlertKey = "shed"
+
+// checkLag sheds or restores tasks according to how far the last tipset indexed is behind head.
+func (c *Watcher) checkLag(ctx context.Context, head *types.TipSet) {
+	if c.shedThreshold <= 0 || len(c.shedTasks) == 0 {
+		return
+	}
+	shedder, ok := c.obs.(TaskShedder)
+	if !ok {
+		return
+	}
+
+	indexed := atomic.LoadInt64(&c.lastIndexed)
+	if indexed == 0 {
+		// nothing indexed yet so lag is meaningless
+		return
+	}
+	lag := int64(head.Height()) - indexed
+
+	switch {
+	case !c.shedding && lag > c.shedThreshold:
+		c.shedding = true
+		shedder.ShedTasks(c.shedTasks)
+		metrics.RecordCount(ctx, metrics.WatchShedTasks, len(c.shedTasks))
+		log.Warnw("shedding tasks", "lag", lag, "threshold", c.shedThreshold, "tasks", c.shedTasks)
+		c.alert(ctx, false, fmt.Sprintf("indexing is %d epochs behind the chain head (head %d, indexed %d), exceeding the threshold of %d: shedding tasks %s", lag, head.Height(), indexed, c.shedThreshold, strings.Join(c.shedTasks, ",")))
+
+	case c.shedding && lag <= c.shedThreshold/2:
+		c.shedding = false
+		shedder.RestoreTasks()
+		metrics.RecordCount(ctx, metrics.WatchShedTasks, 0)
+		log.Infow("restoring shed tasks", "lag", lag, "threshold", c.shedThreshold, "tasks", c.shedTasks)
+		c.alert(ctx, true, fmt.Sprintf("indexing has caught up and is %d epochs behind the chain head: restored tasks %s", lag, strings.Join(c.shedTasks, ",")))
+	}
+}
+
+// alert sends a shedding alert, or a recovery if resolved is true. Failure to send is logged but is not fatal.
+func (c *Watcher) alert(ctx context.Context, resolved bool, msg string) {
+	if c.alerter == nil {
+		return
+	}
+	var err error
+	if resolved {
+		err = c.alerter.Resolve(ctx, shedAlertKey, msg)
+	} else {
+		err = c.alerter.Alert(ctx, shedAlertKey, msg)
+	}
+	if err != nil {
+		log.Errorw("failed to send shedding alert", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package chain
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeShedder is a tipset observer that records the tasks it was asked to shed.
+type fakeShedder struct {
+	shed []string
+}
+
+func (f *fakeShedder) TipSet(ctx context.Context, ts *types.TipSet) error { return nil }
+func (f *fakeShedder) SkipTipSet(ctx context.Context, ts *types.TipSet, reason string) error {
+	return nil
+}
+func (f *fakeShedder) Close() error             { return nil }
+func (f *fakeShedder) ShedTasks(tasks []string) { f.shed = tasks }
+func (f *fakeShedder) RestoreTasks()            { f.shed = nil }
+
+// fakeAlerter records the keys of alerts and resolutions sent.
+type fakeAlerter struct {
+	alerts   []string
+	resolves []string
+}
+
+func (f *fakeAlerter) Alert(ctx context.Context, key string, msg string) error {
+	f.alerts = append(f.alerts, key)
+	return nil
+}
+
+func (f *fakeAlerter) Resolve(ctx context.Context, key string, msg string) error {
+	f.resolves = append(f.resolves, key)
+	return nil
+}
+
+func TestWatcherShedsTasksWhenLagging(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeChain(t, 40)
+	obs := &fakeShedder{}
+	alerter := &fakeAlerter{}
+	w := NewWatcher(obs, NullHeadNotifier{}, 2, LagSheddingOpt(10, []string{ActorStatesRawTask, GasByMethodTask}, alerter))
+
+	// Nothing shed before any tipset has been indexed
+	w.checkLag(ctx, c.tipsets[30])
+	assert.Empty(t, obs.shed)
+
+	atomic.StoreInt64(&w.lastIndexed, 15)
+	w.checkLag(ctx, c.tipsets[25])
+	assert.Empty(t, obs.shed, "lag at threshold")
+
+	w.checkLag(ctx, c.tipsets[26])
+	assert.Equal(t, []string{ActorStatesRawTask, GasByMethodTask}, obs.shed)
+	assert.Equal(t, []string{shedAlertKey}, alerter.alerts)
+
+	// Tasks stay shed until the lag is back to half the threshold
+	atomic.StoreInt64(&w.lastIndexed, 20)
+	w.checkLag(ctx, c.tipsets[26])
+	assert.NotEmpty(t, obs.shed)
+	assert.Equal(t, []string{shedAlertKey}, alerter.alerts, "no repeated alert while shedding")
+
+	atomic.StoreInt64(&w.lastIndexed, 24)
+	w.checkLag(ctx, c.tipsets[29])
+	assert.Empty(t, obs.shed)
+	assert.Equal(t, []string{shedAlertKey}, alerter.resolves)
+}
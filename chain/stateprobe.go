@@ -0,0 +1,69 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// A StateProber is an observer that skips the tasks needing state for tipsets whose state the lens does not hold. A
+// Walker probes its whole range before walking it and passes the result to StateProbed, so the observer need not probe
+// each tipset it is given.
+type StateProber interface {
+	// ProbesState reports whether the observer skips tasks for tipsets without state.
+	ProbesState() bool
+
+	// StateProbed records that, of the heights between from and to inclusive, the lens holds the state for those at or
+	// above availableFrom only.
+	StateProbed(from, to, availableFrom int64)
+}
+
+// StateAvailable reports whether node holds the state needed to index ts: the state ts was executed against and that of
+// its parent, which actor state tasks compare it with.
+func StateAvailable(ctx context.Context, node lens.API, ts *types.TipSet) (bool, error) {
+	available, err := node.ChainHasObj(ctx, ts.ParentState())
+	if err != nil || !available || ts.Height() == 0 {
+		return available, err
+	}
+
+	parent, err := node.ChainGetTipSet(ctx, ts.Parents())
+	if err != nil {
+		return false, xerrors.Errorf("get parent tipset: %w", err)
+	}
+	return node.ChainHasObj(ctx, parent.ParentState())
+}
+
+// StateAvailableFrom returns the lowest height between from and to inclusive for which node holds the state needed to
+// index the tipset, or to+1 if it holds none. Nodes discard the state of tipsets older than some height, so the range is
+// searched by bisection rather than probing every tipset. A null round is judged by the tipset before it.
+func StateAvailableFrom(ctx context.Context, node lens.API, from, to int64) (int64, error) {
+	lo, hi := from, to+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ts, err := node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(mid), types.EmptyTSK)
+		if err != nil {
+			return 0, xerrors.Errorf("get tipset by height: %w", err)
+		}
+		available, err := StateAvailable(ctx, node, ts)
+		if err != nil {
+			return 0, xerrors.Errorf("probe state at height %d: %w", mid, err)
+		}
+		if available {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
+// probedRange records the result of probing the state available for a range of heights.
+type probedRange struct {
+	from          int64
+	to            int64
+	availableFrom int64
+}
@@ -0,0 +1,151 @@
+package chain
+
+import (
+	"path"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// AllTasks lists the name of every task the indexer can run.
+var AllTasks = []string{
+	BlocksTask,
+	MessagesTask,
+	ChainEconomicsTask,
+	ActorStatesRawTask,
+	ActorStatesPowerTask,
+	ActorStatesRewardTask,
+	ActorStatesMinerTask,
+	ActorStatesInitTask,
+	ActorStatesMarketTask,
+	ActorStatesMultisigTask,
+	ActorStatesVerifregTask,
+	ActorStatesUntypedTask,
+	MultisigApprovalsTask,
+	GasByMethodTask,
+	MultisigVestingTask,
+	ChainThroughputTask,
+	SystemBalancesTask,
+	MinerSnapshotsTask,
+	MarketSnapshotsTask,
+	GasTracesTask,
+}
+
+// TaskGroups maps the name of a group of tasks to the tasks it contains. A group may be used anywhere a list of tasks
+// is accepted.
+var TaskGroups = map[string][]string{
+	"all":             AllTasks,
+	"default":         {BlocksTask, MessagesTask, ChainEconomicsTask, ActorStatesRawTask},
+	"actorstates-all": {ActorStatesRawTask, ActorStatesPowerTask, ActorStatesRewardTask, ActorStatesMinerTask, ActorStatesInitTask, ActorStatesMarketTask, ActorStatesMultisigTask, ActorStatesVerifregTask, ActorStatesUntypedTask},
+}
+
+// TaskRevisions holds the revision of each task whose extracted data has changed since it was first released. The
+// revision of a task must be incremented whenever a change alters the rows it persists for the same tipset, so data
+// written by the earlier revision can be found through the runs recorded with each processing report. Tasks that are
+// not listed are at revision 1.
+var TaskRevisions = map[string]int{
+	ActorStatesUntypedTask: 2, // the verified registry has its own task
+	MessagesTask:           2, // receipts record the height and state root of the tipset that included their message
+	ActorStatesMinerTask:   2, // miner infos record the sector size, window post proof type and consensus fault elapsed
+	ActorStatesMarketTask:  2, // deal piece and payload cids are recorded in market_deal_pieces
+}
+
+// taskTables lists the tables written by each task, other than the processing reports. Actor state tasks record the
+// actors deleted from the state tree unless the raw actor states task records them.
+var taskTables = map[string][]string{
+	BlocksTask:              {"block_headers", "block_parents", "drand_block_entries", "epoch_timestamps"},
+	MessagesTask:            {"messages", "block_messages", "receipts", "message_gas_economy", "message_heights", "parsed_messages", "derived_gas_outputs"},
+	ChainEconomicsTask:      {"chain_economics"},
+	ActorStatesRawTask:      {"actors", "actor_states", "actor_deletions"},
+	ActorStatesPowerTask:    {"chain_powers", "power_actor_claims", "actor_deletions"},
+	ActorStatesRewardTask:   {"chain_rewards", "actor_deletions"},
+	ActorStatesMinerTask:    {"miner_current_deadline_infos", "miner_deadline_schedules", "miner_fee_debts", "miner_infos", "miner_locked_funds", "miner_pre_commit_infos", "miner_sector_deals", "miner_sector_events", "miner_sector_infos", "miner_sector_posts", "actor_deletions"},
+	ActorStatesInitTask:     {"id_addresses", "actor_deletions"},
+	ActorStatesMarketTask:   {"market_deal_proposals", "market_deal_states", "market_deal_pieces", "actor_deletions"},
+	ActorStatesMultisigTask: {"multisig_transactions", "actor_deletions"},
+	ActorStatesVerifregTask: {"verifreg_governance", "actor_deletions"},
+	ActorStatesUntypedTask:  {"actor_states", "actor_deletions"},
+	MultisigApprovalsTask:   {"multisig_approvals"},
+	GasByMethodTask:         {"message_gas_by_method"},
+	MultisigVestingTask:     {"multisig_vesting"},
+	ChainThroughputTask:     {"chain_throughput"},
+	SystemBalancesTask:      {"chain_system_balances"},
+	MinerSnapshotsTask:      {"miner_sector_snapshots", "miner_pre_commit_snapshots"},
+	MarketSnapshotsTask:     {"market_deal_snapshots"},
+	GasTracesTask:           {"message_gas_traces"},
+}
+
+// TaskTables returns the tables written by the named tasks, each once, in the order they are first written. Unknown
+// tasks write no tables.
+func TaskTables(tasks []string) []string {
+	var tables []string
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		for _, table := range taskTables[task] {
+			if !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+// TaskRevision returns the revision of the named task.
+func TaskRevision(task string) int {
+	if rev, ok := TaskRevisions[task]; ok {
+		return rev
+	}
+	return 1
+}
+
+// ExpandTasks expands a list of task names, group names and wildcard patterns into the concrete tasks they name.
+// Patterns use the syntax of path.Match, for example actorstates* matches every actor state task. Each task appears
+// once in the result, in the order it was first named. Other names are returned unchanged. It is an error for a
+// pattern to match no task.
+func ExpandTasks(names []string) ([]string, error) {
+	var tasks []string
+	seen := make(map[string]bool)
+	add := func(task string) {
+		if !seen[task] {
+			seen[task] = true
+			tasks = append(tasks, task)
+		}
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if group, ok := TaskGroups[name]; ok {
+			for _, task := range group {
+				add(task)
+			}
+			continue
+		}
+
+		if !strings.ContainsAny(name, "*?[") {
+			add(name)
+			continue
+		}
+
+		matched := false
+		for _, task := range AllTasks {
+			ok, err := path.Match(name, task)
+			if err != nil {
+				return nil, xerrors.Errorf("invalid task pattern %q: %w", name, err)
+			}
+			if ok {
+				matched = true
+				add(task)
+			}
+		}
+		if !matched {
+			return nil, xerrors.Errorf("task pattern %q does not match any task", name)
+		}
+	}
+
+	return tasks, nil
+}
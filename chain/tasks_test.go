@@ -0,0 +1,57 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTasks(t *testing.T) {
+	testCases := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{
+			name:  "concrete",
+			names: []string{BlocksTask, MessagesTask},
+			want:  []string{BlocksTask, MessagesTask},
+		},
+		{
+			name:  "group",
+			names: []string{"actorstates-all"},
+			want:  []string{ActorStatesRawTask, ActorStatesPowerTask, ActorStatesRewardTask, ActorStatesMinerTask, ActorStatesInitTask, ActorStatesMarketTask, ActorStatesMultisigTask, ActorStatesVerifregTask, ActorStatesUntypedTask},
+		},
+		{
+			name:  "wildcard",
+			names: []string{"ms*"},
+			want:  []string{MultisigApprovalsTask, MultisigVestingTask},
+		},
+		{
+			name:  "duplicates removed",
+			names: []string{BlocksTask, "default", " blocks "},
+			want:  []string{BlocksTask, MessagesTask, ChainEconomicsTask, ActorStatesRawTask},
+		},
+		{
+			name:  "unknown names passed through",
+			names: []string{"nosuchtask", ""},
+			want:  []string{"nosuchtask"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExpandTasks(tc.names)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := ExpandTasks([]string{"nosuchtask*"})
+	assert.Error(t, err)
+
+	all, err := ExpandTasks([]string{"all"})
+	require.NoError(t, err)
+	assert.Equal(t, AllTasks, all)
+}
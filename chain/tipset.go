@@ -15,6 +15,12 @@ type TipSetObserver interface {
 	Close() error
 }
 
+// A TipSetRevertObserver is notified when a tipset that has already been passed to a TipSetObserver is reverted
+// from the chain.
+type TipSetRevertObserver interface {
+	RevertTipSet(ctx context.Context, ts *types.TipSet) error
+}
+
 var (
 	ErrCacheEmpty       = errors.New("cache empty")
 	ErrAddOutOfOrder    = errors.New("added tipset height lower than current head")
@@ -13,23 +13,89 @@ import (
 	"github.com/filecoin-project/sentinel-visor/lens"
 )
 
-func NewWalker(obs TipSetObserver, opener lens.APIOpener, minHeight, maxHeight int64) *Walker {
-	return &Walker{
+// Directions in which a Walker may visit tipsets.
+const (
+	WalkDescending = "descending" // from the highest height to the lowest, so the most recent tipsets are indexed first
+	WalkAscending  = "ascending"  // from the lowest height to the highest
+)
+
+// ValidateWalkDirection returns an error if direction is not one of the known walk directions. An empty direction is
+// treated as descending.
+func ValidateWalkDirection(direction string) error {
+	switch direction {
+	case "", WalkDescending, WalkAscending:
+		return nil
+	default:
+		return xerrors.Errorf("unknown walk direction %q, must be %s or %s", direction, WalkDescending, WalkAscending)
+	}
+}
+
+// A WalkerOpt configures optional behaviour of a Walker.
+type WalkerOpt func(w *Walker)
+
+// WalkDirectionOpt sets the direction in which the walker visits tipsets. The default is WalkDescending.
+func WalkDirectionOpt(direction string) WalkerOpt {
+	return func(w *Walker) {
+		if direction != "" {
+			w.direction = direction
+		}
+	}
+}
+
+// WalkCursorOpt sets a cursor recording the progress of the walk. When the cursor already has a position, because the
+// walk is being resumed or restarted, only the part of the range beyond the cursor is walked. The walk is recorded as
+// complete once the end of its range has been committed.
+func WalkCursorOpt(cursor *WalkCursor) WalkerOpt {
+	return func(w *Walker) {
+		w.cursor = cursor
+	}
+}
+
+// An IndexDeferrer can drop the secondary indexes of the tables written by walks and rebuild them once the walks have
+// finished. holder identifies the walk so indexes deferred by several walks are only rebuilt after the last of them.
+type IndexDeferrer interface {
+	DeferIndexes(ctx context.Context, holder string, tables []string) (int, error)
+	RebuildIndexes(ctx context.Context, holder string, force bool) (int, error)
+}
+
+// DeferIndexesOpt configures the walker to drop the secondary indexes of tables, which should be those written by the
+// walk's tasks as given by TaskTables, before walking and rebuild them once everything walked has been persisted. This
+// is much faster than maintaining them row by row when walking a large range. Queries relying on the indexes are slow
+// until they have been rebuilt.
+func DeferIndexesOpt(d IndexDeferrer, tables []string) WalkerOpt {
+	return func(w *Walker) {
+		w.indexes = d
+		w.indexTables = tables
+	}
+}
+
+func NewWalker(obs TipSetObserver, opener lens.APIOpener, minHeight, maxHeight int64, options ...WalkerOpt) *Walker {
+	w := &Walker{
 		opener:    opener,
 		obs:       obs,
 		finality:  900,
 		minHeight: minHeight,
 		maxHeight: maxHeight,
+		direction: WalkDescending,
 	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w
 }
 
 // Walker is a task that indexes blocks by walking the chain history.
 type Walker struct {
 	opener    lens.APIOpener
 	obs       TipSetObserver
-	finality  int   // epochs after which chain state is considered final
-	minHeight int64 // limit persisting to tipsets equal to or above this height
-	maxHeight int64 // limit persisting to tipsets equal to or below this height}
+	finality  int           // epochs after which chain state is considered final
+	minHeight int64         // limit persisting to tipsets equal to or above this height
+	maxHeight int64         // limit persisting to tipsets equal to or below this height}
+	direction string        // one of WalkDescending or WalkAscending
+	cursor    *WalkCursor   // optional, records the progress of the walk
+	indexes   IndexDeferrer // optional, defers index maintenance until the walk has finished
+
+	indexTables []string // tables whose index maintenance is deferred
 }
 
 func (c *Walker) Params() map[string]interface{} {
@@ -37,40 +103,97 @@ func (c *Walker) Params() map[string]interface{} {
 	out["finality"] = c.finality
 	out["minHeight"] = c.minHeight
 	out["maxHeight"] = c.maxHeight
+	out["direction"] = c.direction
+	out["deferIndexes"] = c.indexes != nil
+	if c.cursor != nil {
+		out["walkID"] = c.cursor.ID()
+		if height, _, ok := c.cursor.Position(); ok {
+			out["cursorHeight"] = height
+		}
+	}
 	return out
 }
 
 // Run starts walking the chain history and continues until the context is done or
 // the start of the chain is reached.
-func (c *Walker) Run(ctx context.Context) error {
+func (c *Walker) Run(ctx context.Context) (rerr error) {
 	node, closer, err := c.opener.Open(ctx)
 	if err != nil {
 		return xerrors.Errorf("open lens: %w", err)
 	}
 
+	var holder string // set once indexes have been deferred
 	defer func() {
 		closer()
 		if err := c.obs.Close(); err != nil {
 			log.Errorw("walker failed to close TipSetObserver", "error", err)
+			// Errors surfaced on close, such as a failure to persist the final tipset, mean the walk is incomplete
+			if rerr == nil {
+				rerr = xerrors.Errorf("close observer: %w", err)
+			}
+		}
+		// Everything in range has been committed once the observer is closed
+		if rerr == nil && c.cursor != nil {
+			rerr = c.cursor.Complete(ctx)
+		}
+		// Indexes are rebuilt even if the walk failed or was stopped so its tables are not left without them
+		if holder != "" {
+			n, err := c.indexes.RebuildIndexes(context.Background(), holder, false)
+			if err != nil {
+				log.Errorw("walker failed to rebuild indexes", "error", err)
+				if rerr == nil {
+					rerr = xerrors.Errorf("rebuild indexes: %w", err)
+				}
+			} else {
+				log.Infow("rebuilt indexes deferred by walk", "indexes", n)
+			}
 		}
 	}()
 
+	minHeight, maxHeight := c.remainingRange()
+	if minHeight > maxHeight {
+		log.Infow("nothing left to walk", "from", minHeight, "to", maxHeight)
+		return nil
+	}
+
+	if c.indexes != nil {
+		id, err := newRunID()
+		if err != nil {
+			return err
+		}
+		n, err := c.indexes.DeferIndexes(ctx, id, c.indexTables)
+		if err != nil {
+			return xerrors.Errorf("defer indexes: %w", err)
+		}
+		holder = id
+		log.Infow("deferred indexes until walk has finished", "indexes", n, "holder", holder)
+	}
+
 	ts, err := node.ChainHead(ctx)
 	if err != nil {
 		return xerrors.Errorf("get chain head: %w", err)
 	}
 
-	if int64(ts.Height()) < c.minHeight {
-		return xerrors.Errorf("cannot walk history, chain head (%d) is earlier than minimum height (%d)", int64(ts.Height()), c.minHeight)
+	if int64(ts.Height()) < minHeight {
+		return xerrors.Errorf("cannot walk history, chain head (%d) is earlier than minimum height (%d)", int64(ts.Height()), minHeight)
 	}
 
-	if int64(ts.Height()) > c.maxHeight {
-		ts, err = node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(c.maxHeight), types.EmptyTSK)
+	if int64(ts.Height()) > maxHeight {
+		ts, err = node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(maxHeight), types.EmptyTSK)
 		if err != nil {
 			return xerrors.Errorf("get tipset by height: %w", err)
 		}
 	}
 
+	c.probeState(ctx, node, minHeight, int64(ts.Height()))
+
+	if c.direction == WalkAscending {
+		if err := c.walkChainAscendingFrom(ctx, node, ts, minHeight); err != nil {
+			return xerrors.Errorf("walk chain ascending: %w", err)
+		}
+		return nil
+	}
+
 	if err := c.WalkChain(ctx, node, ts); err != nil {
 		return xerrors.Errorf("walk chain: %w", err)
 	}
@@ -78,6 +201,53 @@ func (c *Walker) Run(ctx context.Context) error {
 	return nil
 }
 
+// probeState finds which heights between minHeight and maxHeight the lens holds state for before they are walked, when
+// the observer skips tasks for tipsets without state, so ranges the node has pruned are skipped without probing each
+// tipset. The observer falls back to probing each tipset if the range cannot be probed.
+func (c *Walker) probeState(ctx context.Context, node lens.API, minHeight, maxHeight int64) {
+	p, ok := c.obs.(StateProber)
+	if !ok || !p.ProbesState() {
+		return
+	}
+
+	// Both directions also visit the parent of the lowest tipset in range
+	from := minHeight - 1
+	if from < 0 {
+		from = 0
+	}
+	availableFrom, err := StateAvailableFrom(ctx, node, from, maxHeight)
+	if err != nil {
+		log.Warnw("failed to probe state availability of walk, probing each tipset instead", "from", from, "to", maxHeight, "error", err)
+		return
+	}
+	if availableFrom > from {
+		log.Warnw("state not available, tasks that need it will be skipped", "from", from, "to", availableFrom-1)
+	}
+	p.StateProbed(from, maxHeight, availableFrom)
+}
+
+// remainingRange returns the range of heights still to be walked, which excludes any part of the range already
+// committed according to the cursor.
+func (c *Walker) remainingRange() (int64, int64) {
+	minHeight, maxHeight := c.minHeight, c.maxHeight
+	if c.cursor == nil {
+		return minHeight, maxHeight
+	}
+	height, _, ok := c.cursor.Position()
+	if !ok {
+		return minHeight, maxHeight
+	}
+
+	if c.direction == WalkAscending {
+		if height+1 > minHeight {
+			minHeight = height + 1
+		}
+	} else if height-1 < maxHeight {
+		maxHeight = height - 1
+	}
+	return minHeight, maxHeight
+}
+
 func (c *Walker) WalkChain(ctx context.Context, node lens.API, ts *types.TipSet) error {
 	ctx, span := global.Tracer("").Start(ctx, "Walker.WalkChain", trace.WithAttributes(label.Int64("height", c.maxHeight)))
 	defer span.End()
@@ -109,3 +279,67 @@ func (c *Walker) WalkChain(ctx context.Context, node lens.API, ts *types.TipSet)
 
 	return nil
 }
+
+// WalkChainAscending visits the tipsets from the minimum height up to and including ts, in order of increasing height.
+func (c *Walker) WalkChainAscending(ctx context.Context, node lens.API, ts *types.TipSet) error {
+	return c.walkChainAscendingFrom(ctx, node, ts, c.minHeight)
+}
+
+func (c *Walker) walkChainAscendingFrom(ctx context.Context, node lens.API, ts *types.TipSet, minHeight int64) error {
+	ctx, span := global.Tracer("").Start(ctx, "Walker.WalkChainAscending", trace.WithAttributes(label.Int64("height", c.maxHeight)))
+	defer span.End()
+
+	from := abi.ChainEpoch(minHeight)
+	if from < 0 {
+		from = 0
+	}
+
+	// Returns the tipset at the minimum height or, if that is a null round, the tipset before it
+	cur, err := node.ChainGetTipSetByHeight(ctx, from, ts.Key())
+	if err != nil {
+		return xerrors.Errorf("get tipset by height: %w", err)
+	}
+
+	// Actor and message processing compare each tipset with its parent so the walk starts with the parent of the
+	// first tipset in range, as a descending walk ends with it.
+	if cur.Height() == from && from > 0 {
+		parent, err := node.ChainGetTipSet(ctx, cur.Parents())
+		if err != nil {
+			return xerrors.Errorf("get tipset: %w", err)
+		}
+		log.Debugw("found tipset", "height", parent.Height())
+		if err := c.obs.TipSet(ctx, parent); err != nil {
+			return xerrors.Errorf("notify tipset: %w", err)
+		}
+	}
+
+	log.Debugw("found tipset", "height", cur.Height())
+	if err := c.obs.TipSet(ctx, cur); err != nil {
+		return xerrors.Errorf("notify tipset: %w", err)
+	}
+
+	for h := cur.Height() + 1; h <= ts.Height(); h++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		next, err := node.ChainGetTipSetByHeight(ctx, h, ts.Key())
+		if err != nil {
+			return xerrors.Errorf("get tipset by height: %w", err)
+		}
+
+		// Null rounds have no tipset, the tipset before them is returned instead
+		if next.Height() != h {
+			continue
+		}
+
+		log.Debugw("found tipset", "height", next.Height())
+		if err := c.obs.TipSet(ctx, next); err != nil {
+			return xerrors.Errorf("notify tipset: %w", err)
+		}
+	}
+
+	return nil
+}
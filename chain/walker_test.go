@@ -5,13 +5,15 @@ import (
 	"testing"
 	"time"
 
-	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
-
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
 	itestkit "github.com/filecoin-project/lotus/itests/kit"
 	"github.com/go-pg/pg/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/model/blocks"
 	"github.com/filecoin-project/sentinel-visor/storage"
 	"github.com/filecoin-project/sentinel-visor/testutil"
@@ -113,3 +115,82 @@ func TestWalker(t *testing.T) {
 		}
 	})
 }
+
+// fakeChainLens serves the tipsets of a fake chain, all other lens methods are unimplemented.
+type fakeChainLens struct {
+	lens.API
+	c *fakeChain
+}
+
+func (f *fakeChainLens) Open(context.Context) (lens.API, lens.APICloser, error) {
+	return f, func() {}, nil
+}
+
+func (f *fakeChainLens) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return f.c.ChainHead(ctx)
+}
+
+func (f *fakeChainLens) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	return f.c.ChainGetTipSet(ctx, tsk)
+}
+
+func (f *fakeChainLens) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return f.c.ChainGetTipSetByHeight(ctx, h, tsk)
+}
+
+// recordingObserver records the events of a walk in the order they happen.
+type recordingObserver struct {
+	events *[]string
+}
+
+func (o *recordingObserver) TipSet(ctx context.Context, ts *types.TipSet) error {
+	*o.events = append(*o.events, "tipset")
+	return nil
+}
+
+func (o *recordingObserver) SkipTipSet(ctx context.Context, ts *types.TipSet, reason string) error {
+	return nil
+}
+
+func (o *recordingObserver) Close() error {
+	*o.events = append(*o.events, "close")
+	return nil
+}
+
+type fakeIndexDeferrer struct {
+	events  *[]string
+	holders []string
+	tables  []string
+}
+
+func (d *fakeIndexDeferrer) DeferIndexes(ctx context.Context, holder string, tables []string) (int, error) {
+	*d.events = append(*d.events, "defer")
+	d.holders = append(d.holders, holder)
+	d.tables = tables
+	return 2, nil
+}
+
+func (d *fakeIndexDeferrer) RebuildIndexes(ctx context.Context, holder string, force bool) (int, error) {
+	*d.events = append(*d.events, "rebuild")
+	d.holders = append(d.holders, holder)
+	return 2, nil
+}
+
+func TestWalkerDefersIndexes(t *testing.T) {
+	var events []string
+	node := &fakeChainLens{c: newFakeChain(t, 3)}
+	deferrer := &fakeIndexDeferrer{events: &events}
+
+	tables := TaskTables([]string{BlocksTask, ChainEconomicsTask})
+	w := NewWalker(&recordingObserver{events: &events}, node, 2, 3, WalkDirectionOpt(WalkAscending), DeferIndexesOpt(deferrer, tables))
+	require.NoError(t, w.Run(context.Background()))
+
+	// Indexes are rebuilt only once everything walked has been persisted by the observer
+	assert.Equal(t, []string{"defer", "tipset", "tipset", "tipset", "close", "rebuild"}, events)
+	require.Len(t, deferrer.holders, 2)
+	assert.NotEmpty(t, deferrer.holders[0])
+	assert.Equal(t, deferrer.holders[0], deferrer.holders[1])
+
+	// Only the indexes of the tables written by the walk's tasks are deferred
+	assert.Equal(t, []string{"block_headers", "block_parents", "drand_block_entries", "epoch_timestamps", "chain_economics"}, deferrer.tables)
+}
@@ -0,0 +1,78 @@
+package chain
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// A WalkJobStorage records walks and the progress they have made so they can be resumed.
+type WalkJobStorage interface {
+	CreateWalkJob(ctx context.Context, job *visormodel.WalkJob) error
+	WalkJob(ctx context.Context, id int64) (*visormodel.WalkJob, error)
+	UpdateWalkCursor(ctx context.Context, id int64, height int64, tipset string) error
+	CompleteWalkJob(ctx context.Context, id int64) error
+}
+
+var _ CommitObserver = (*WalkCursor)(nil)
+
+// A WalkCursor tracks the last tipset committed by a walk and records it in storage as the walk progresses. It must
+// be given to the walk's indexer using CommitObserverOpt and to the walker using WalkCursorOpt.
+type WalkCursor struct {
+	store WalkJobStorage
+	id    int64
+
+	mu     sync.Mutex // protects following fields
+	height int64
+	tipset string // empty until a tipset has been committed
+}
+
+// NewWalkCursor returns a cursor for the recorded walk job, positioned at the job's cursor if it has one.
+func NewWalkCursor(store WalkJobStorage, job *visormodel.WalkJob) *WalkCursor {
+	return &WalkCursor{
+		store:  store,
+		id:     job.ID,
+		height: job.CursorHeight,
+		tipset: job.CursorTipSet,
+	}
+}
+
+// ID returns the id of the walk job the cursor belongs to.
+func (c *WalkCursor) ID() int64 {
+	return c.id
+}
+
+// Position returns the height and key of the last tipset committed by the walk. ok is false if no tipset has been
+// committed.
+func (c *WalkCursor) Position() (height int64, tipset string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.height, c.tipset, c.tipset != ""
+}
+
+// TipSetCommitted moves the cursor to ts.
+func (c *WalkCursor) TipSetCommitted(ctx context.Context, ts *types.TipSet) {
+	tipset := visormodel.EncodeTipSetKey(ts.Key())
+
+	c.mu.Lock()
+	c.height = int64(ts.Height())
+	c.tipset = tipset
+	c.mu.Unlock()
+
+	// A cursor that falls behind only causes a resumed walk to repeat some tipsets, which are skipped as already complete
+	if err := c.store.UpdateWalkCursor(ctx, c.id, int64(ts.Height()), tipset); err != nil {
+		log.Warnw("failed to record walk cursor", "walk_id", c.id, "height", ts.Height(), "error", err)
+	}
+}
+
+// Complete records that the walk has reached the end of its range.
+func (c *WalkCursor) Complete(ctx context.Context) error {
+	if err := c.store.CompleteWalkJob(ctx, c.id); err != nil {
+		return xerrors.Errorf("complete walk job %d: %w", c.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+type fakeWalkJobStorage struct {
+	height    int64
+	tipset    string
+	completed bool
+}
+
+func (s *fakeWalkJobStorage) CreateWalkJob(ctx context.Context, job *visormodel.WalkJob) error {
+	job.ID = 1
+	return nil
+}
+
+func (s *fakeWalkJobStorage) WalkJob(ctx context.Context, id int64) (*visormodel.WalkJob, error) {
+	return &visormodel.WalkJob{ID: id, CursorHeight: s.height, CursorTipSet: s.tipset}, nil
+}
+
+func (s *fakeWalkJobStorage) UpdateWalkCursor(ctx context.Context, id int64, height int64, tipset string) error {
+	s.height = height
+	s.tipset = tipset
+	return nil
+}
+
+func (s *fakeWalkJobStorage) CompleteWalkJob(ctx context.Context, id int64) error {
+	s.completed = true
+	return nil
+}
+
+func TestWalkCursorCommitted(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeChain(t, 5)
+	store := &fakeWalkJobStorage{}
+	cursor := NewWalkCursor(store, &visormodel.WalkJob{ID: 1})
+
+	_, _, ok := cursor.Position()
+	assert.False(t, ok)
+
+	cursor.TipSetCommitted(ctx, c.tipsets[3])
+	height, tipset, ok := cursor.Position()
+	require.True(t, ok)
+	assert.EqualValues(t, c.tipsets[3].Height(), height)
+	assert.Equal(t, visormodel.EncodeTipSetKey(c.tipsets[3].Key()), tipset)
+	assert.Equal(t, height, store.height)
+	assert.Equal(t, tipset, store.tipset)
+
+	require.NoError(t, cursor.Complete(ctx))
+	assert.True(t, store.completed)
+}
+
+func TestWalkerRemainingRange(t *testing.T) {
+	testCases := []struct {
+		name      string
+		direction string
+		job       *visormodel.WalkJob
+		min, max  int64
+	}{
+		{
+			name:      "no cursor",
+			direction: WalkDescending,
+			job:       &visormodel.WalkJob{ID: 1},
+			min:       10,
+			max:       100,
+		},
+		{
+			name:      "descending",
+			direction: WalkDescending,
+			job:       &visormodel.WalkJob{ID: 1, CursorHeight: 60, CursorTipSet: "bafy"},
+			min:       10,
+			max:       59,
+		},
+		{
+			name:      "ascending",
+			direction: WalkAscending,
+			job:       &visormodel.WalkJob{ID: 1, CursorHeight: 60, CursorTipSet: "bafy"},
+			min:       61,
+			max:       100,
+		},
+		{
+			name:      "ascending complete",
+			direction: WalkAscending,
+			job:       &visormodel.WalkJob{ID: 1, CursorHeight: 100, CursorTipSet: "bafy"},
+			min:       101,
+			max:       100,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWalker(nil, nil, 10, 100, WalkDirectionOpt(tc.direction), WalkCursorOpt(NewWalkCursor(&fakeWalkJobStorage{}, tc.job)))
+			min, max := w.remainingRange()
+			assert.Equal(t, tc.min, min)
+			assert.Equal(t, tc.max, max)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	builtininit "github.com/filecoin-project/sentinel-visor/chain/actors/builtin/init"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/reward"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// A StateRootSource reports the parent state roots of the tipsets most recently indexed by any of a set of tasks.
+type StateRootSource interface {
+	RecentIndexedStateRoots(ctx context.Context, tasks []string, limit int) ([]string, error)
+}
+
+// warmActors are the actors whose state is read for almost every tipset, so their state is loaded when warming the
+// lens cache.
+var warmActors = []address.Address{
+	builtininit.Address,
+	reward.Address,
+	power.Address,
+	market.Address,
+	verifreg.Address,
+}
+
+// CacheWarmingOpt configures the watcher to warm the lens cache before it first follows the chain head. The state of
+// the busiest system actors is loaded at the parent state roots of the last depth tipsets indexed by any of tasks, so
+// the first tipsets indexed after a restart, which are diffed against that state, don't have to fetch it from the
+// node. Warming is skipped when depth is zero.
+func CacheWarmingOpt(opener lens.APIOpener, src StateRootSource, tasks []string, depth int) WatcherOpt {
+	return func(w *Watcher) {
+		w.warmOpener = opener
+		w.warmSource = src
+		w.warmTasks = tasks
+		w.warmDepth = depth
+	}
+}
+
+// warmCache loads recently indexed state into the lens cache. Failures are logged since the cache only affects how
+// quickly the first tipsets are indexed.
+func (c *Watcher) warmCache(ctx context.Context) {
+	if c.warmed || c.warmDepth <= 0 || c.warmSource == nil || c.warmOpener == nil {
+		return
+	}
+	c.warmed = true
+
+	start := time.Now()
+	roots, err := c.warmSource.RecentIndexedStateRoots(ctx, c.warmTasks, c.warmDepth)
+	if err != nil {
+		log.Warnw("failed to find recently indexed state roots, not warming cache", "error", err)
+		return
+	}
+	if len(roots) == 0 {
+		return
+	}
+
+	node, closer, err := c.warmOpener.Open(ctx)
+	if err != nil {
+		log.Warnw("failed to open lens, not warming cache", "error", err)
+		return
+	}
+	defer closer()
+
+	warmed := 0
+	for _, root := range roots {
+		if err := WarmStateRoot(ctx, node, root); err != nil {
+			log.Warnw("failed to warm cache", "state_root", root, "error", err)
+			continue
+		}
+		warmed++
+	}
+	log.Infow("warmed lens cache", "state_roots", warmed, "duration", time.Since(start).String())
+}
+
+// WarmStateRoot reads the state tree with the given root and the state of the busiest system actors through node,
+// leaving them in the lens cache.
+func WarmStateRoot(ctx context.Context, node lens.API, root string) error {
+	c, err := cid.Decode(root)
+	if err != nil {
+		return xerrors.Errorf("decode state root: %w", err)
+	}
+
+	tree, err := state.LoadStateTree(node.Store(), c)
+	if err != nil {
+		return xerrors.Errorf("load state tree: %w", err)
+	}
+
+	for _, addr := range warmActors {
+		act, err := tree.GetActor(addr)
+		if err != nil {
+			return xerrors.Errorf("get actor %s: %w", addr, err)
+		}
+		var head cbg.Deferred
+		if err := node.Store().Get(ctx, act.Head, &head); err != nil {
+			return xerrors.Errorf("load state of actor %s: %w", addr, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// fakeStateRootSource records the requests made for recently indexed state roots.
+type fakeStateRootSource struct {
+	roots  []string
+	limits []int
+}
+
+func (f *fakeStateRootSource) RecentIndexedStateRoots(ctx context.Context, tasks []string, limit int) ([]string, error) {
+	f.limits = append(f.limits, limit)
+	return f.roots, nil
+}
+
+// failingOpener counts attempts to open a lens, all of which fail.
+type failingOpener struct {
+	opens int
+}
+
+func (f *failingOpener) Open(ctx context.Context) (lens.API, lens.APICloser, error) {
+	f.opens++
+	return nil, nil, xerrors.Errorf("unavailable")
+}
+
+func TestWatcherWarmsCacheOnce(t *testing.T) {
+	ctx := context.Background()
+	src := &fakeStateRootSource{roots: []string{"bafy2bzacea"}}
+	opener := &failingOpener{}
+
+	w := NewWatcher(&fakeShedder{}, NullHeadNotifier{}, 2, CacheWarmingOpt(opener, src, []string{BlocksTask}, 3))
+	w.warmCache(ctx)
+	w.warmCache(ctx)
+
+	// A failure to open the lens is not retried when the watcher is restarted
+	assert.Equal(t, []int{3}, src.limits)
+	assert.Equal(t, 1, opener.opens)
+}
+
+func TestWatcherSkipsWarmingWithoutIndexedTipSets(t *testing.T) {
+	ctx := context.Background()
+	src := &fakeStateRootSource{}
+	opener := &failingOpener{}
+
+	w := NewWatcher(&fakeShedder{}, NullHeadNotifier{}, 2, CacheWarmingOpt(opener, src, []string{BlocksTask}, 3))
+	w.warmCache(ctx)
+	assert.Equal(t, 0, opener.opens)
+
+	w = NewWatcher(&fakeShedder{}, NullHeadNotifier{}, 2, CacheWarmingOpt(opener, src, []string{BlocksTask}, 0))
+	w.warmCache(ctx)
+	assert.Len(t, src.limits, 1)
+}
@@ -3,25 +3,31 @@ package chain
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 
 	"github.com/filecoin-project/lotus/chain/types"
 	"go.opencensus.io/stats"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/metrics"
 )
 
 // NewWatcher creates a new Watcher. confidence sets the number of tipsets that will be held
 // in a cache awaiting possible reversion. Tipsets will be written to the database when they are evicted from
 // the cache due to incoming later tipsets.
-func NewWatcher(obs TipSetObserver, hn HeadNotifier, confidence int) *Watcher {
-	return &Watcher{
+func NewWatcher(obs TipSetObserver, hn HeadNotifier, confidence int, options ...WatcherOpt) *Watcher {
+	w := &Watcher{
 		notifier:   hn,
 		obs:        obs,
 		confidence: confidence,
 		cache:      NewTipSetCache(confidence),
 		indexSlot:  make(chan struct{}, 1), // allow one concurrent indexing job
 	}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w
 }
 
 // Watcher is a task that indexes blocks by following the chain head.
@@ -31,17 +37,42 @@ type Watcher struct {
 	confidence int           // size of tipset cache
 	cache      *TipSetCache  // caches tipsets for possible reversion
 	indexSlot  chan struct{} // filled with a token when a goroutine is indexing a tipset
+
+	lastIndexed   int64    // height of the last tipset indexed without error, accessed atomically
+	shedThreshold int64    // lag in epochs beyond which tasks are shed, zero to disable
+	shedTasks     []string // tasks shed when the lag exceeds the threshold
+	shedding      bool     // true while tasks are shed
+	alerter       Alerter  // told when shedding starts and stops, may be nil
+
+	warmOpener lens.APIOpener  // opens the lens whose cache is warmed, may be nil
+	warmSource StateRootSource // finds the recently indexed state roots to warm, may be nil
+	warmTasks  []string        // tasks whose reports are used to find recently indexed state roots
+	warmDepth  int             // number of recently indexed tipsets to warm, zero to disable
+	warmed     bool            // true once the cache has been warmed, so a restarted watcher does not warm it again
+
+	headStore    ObservedHeadStorage // records each observed chain head, may be nil
+	headReporter string              // name under which observed heads are recorded
+	headTasks    []string            // tasks for which observed heads are recorded
 }
 
 func (c *Watcher) Params() map[string]interface{} {
 	out := make(map[string]interface{})
 	out["confidence"] = c.confidence
+	if c.shedThreshold > 0 {
+		out["shedThreshold"] = c.shedThreshold
+		out["shedTasks"] = c.shedTasks
+	}
+	if c.warmDepth > 0 {
+		out["warmDepth"] = c.warmDepth
+	}
 	return out
 }
 
 // Run starts following the chain head and blocks until the context is done or
 // an error occurs.
 func (c *Watcher) Run(ctx context.Context) error {
+	c.warmCache(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -68,6 +99,7 @@ func (c *Watcher) index(ctx context.Context, he *HeadEvent) error {
 		if err != nil {
 			log.Errorw("tipset cache set current", "error", err.Error())
 		}
+		c.recordHead(ctx, he.TipSet)
 
 		// If we have a zero confidence window then we need to notify every tipset we see
 		if c.confidence == 0 {
@@ -80,6 +112,10 @@ func (c *Watcher) index(ctx context.Context, he *HeadEvent) error {
 		if err != nil {
 			log.Errorw("tipset cache add", "error", err.Error())
 		}
+		c.recordHead(ctx, he.TipSet)
+		c.recordLag(ctx, he.TipSet)
+
+		c.checkLag(ctx, he.TipSet)
 
 		// Send the tipset that fell out of the confidence window to the observer
 		if tail != nil {
@@ -98,6 +134,14 @@ func (c *Watcher) index(ctx context.Context, he *HeadEvent) error {
 			}
 			log.Errorw("tipset cache revert", "error", err.Error())
 		}
+
+		// Any data already persisted for the reverted tipset needs to be flagged as non-canonical. Tipsets still in
+		// the cache will usually not have been indexed but may have been when the confidence window is zero.
+		if ro, ok := c.obs.(TipSetRevertObserver); ok {
+			if err := ro.RevertTipSet(ctx, he.TipSet); err != nil {
+				log.Errorw("failed to revert tipset", "error", err, "height", he.TipSet.Height())
+			}
+		}
 	}
 
 	metrics.RecordCount(ctx, metrics.TipSetCacheSize, c.cache.Size())
@@ -108,6 +152,16 @@ func (c *Watcher) index(ctx context.Context, he *HeadEvent) error {
 	return nil
 }
 
+// recordLag records the number of epochs between head and the last tipset indexed without error.
+func (c *Watcher) recordLag(ctx context.Context, head *types.TipSet) {
+	indexed := atomic.LoadInt64(&c.lastIndexed)
+	if indexed == 0 {
+		// nothing indexed yet so lag is meaningless
+		return
+	}
+	metrics.RecordCount(ctx, metrics.WatchLag, int(int64(head.Height())-indexed))
+}
+
 // maybeIndexTipSet is called when a new tipset has been discovered
 func (c *Watcher) maybeIndexTipSet(ctx context.Context, ts *types.TipSet) error {
 	// Process the tipset if we can, otherwise skip it so we don't block if indexing is too slow
@@ -124,7 +178,9 @@ func (c *Watcher) maybeIndexTipSet(ctx context.Context, ts *types.TipSet) error
 
 			if err := c.obs.TipSet(ctx, ts); err != nil {
 				log.Errorw("failed to index tipset", "error", err, "height", ts.Height())
+				return
 			}
+			atomic.StoreInt64(&c.lastIndexed, int64(ts.Height()))
 		}()
 	default:
 		// The indexer is taking longer than one epoch to process. We need to avoid blocking the stream of incoming
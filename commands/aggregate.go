@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+// aggregateCmd describes a command that runs a storage.Aggregator against the database until interrupted.
+type aggregateCmd struct {
+	Name      string
+	Usage     string
+	Job       string // name of the job reported by the scheduler
+	EnvPrefix string // prefix of the environment variables of the command's flags
+	Refreshes string // what each refresh updates, used in the usage of the interval flag
+	Flags     []cli.Flag
+	Check     func(*cli.Context) error // validates the flags before connecting to the database, may be nil
+	New       func(*cli.Context, *storage.Database) *storage.Aggregator
+}
+
+// newAggregateCmd returns the command described by c. It takes the database connection flags and an interval flag in
+// addition to the flags of c.
+func newAggregateCmd(c aggregateCmd) *cli.Command {
+	return &cli.Command{
+		Name:  c.Name,
+		Usage: c.Usage,
+		Flags: flagSet(
+			dbConnectFlags,
+			[]cli.Flag{
+				&cli.DurationFlag{
+					Name:    "interval",
+					Usage:   "Time to wait between refreshes of the " + c.Refreshes + ".",
+					Value:   10 * time.Minute,
+					EnvVars: []string{c.EnvPrefix + "_INTERVAL"},
+				},
+			},
+			c.Flags,
+		),
+		Action: func(cctx *cli.Context) error {
+			if err := setupLogging(cctx); err != nil {
+				return xerrors.Errorf("setup logging: %w", err)
+			}
+
+			if err := setupMetrics(cctx); err != nil {
+				return xerrors.Errorf("setup metrics: %w", err)
+			}
+
+			if c.Check != nil {
+				if err := c.Check(cctx); err != nil {
+					return err
+				}
+			}
+
+			db, err := setupDatabase(cctx)
+			if err != nil {
+				return xerrors.Errorf("setup database: %w", err)
+			}
+			defer db.Close(cctx.Context) // nolint: errcheck
+
+			scheduler := schedule.NewScheduler(0,
+				&schedule.JobConfig{
+					Name:                c.Job,
+					Job:                 c.New(cctx, db),
+					RestartOnFailure:    true,
+					RestartOnCompletion: false,
+					RestartDelay:        time.Minute,
+				})
+
+			err = scheduler.Run(cctx.Context)
+			if !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// newHeightRangeAggregateCmd returns the command described by c with lag and lookback flags, for aggregators that
+// apply extracted data in order of height. lagUsage and lookbackUsage describe the flags.
+func newHeightRangeAggregateCmd(c aggregateCmd, lagUsage, lookbackUsage string,
+	newJob func(db *storage.Database, lag, lookback int64, interval time.Duration) *storage.Aggregator) *cli.Command {
+	c.Flags = append([]cli.Flag{
+		&cli.Int64Flag{
+			Name:    "lag",
+			Usage:   lagUsage,
+			Value:   900,
+			EnvVars: []string{c.EnvPrefix + "_LAG"},
+		},
+		&cli.Int64Flag{
+			Name:    "lookback",
+			Usage:   lookbackUsage,
+			Value:   2880,
+			EnvVars: []string{c.EnvPrefix + "_LOOKBACK"},
+		},
+	}, c.Flags...)
+	c.Check = func(cctx *cli.Context) error {
+		if cctx.Int64("lag") < 0 || cctx.Int64("lookback") < 0 {
+			return xerrors.Errorf("lag and lookback must not be negative")
+		}
+		return nil
+	}
+	c.New = func(cctx *cli.Context, db *storage.Database) *storage.Aggregator {
+		return newJob(db, cctx.Int64("lag"), cctx.Int64("lookback"), cctx.Duration("interval"))
+	}
+	return newAggregateCmd(c)
+}
+
+// newDailyAggregateCmd returns the command described by c with lookback-days and from flags, for aggregators that
+// maintain daily rollups. fromUsage describes the from flag.
+func newDailyAggregateCmd(c aggregateCmd, fromUsage string,
+	newJob func(db *storage.Database, from int64, lookback int, interval time.Duration) *storage.Aggregator) *cli.Command {
+	c.Flags = append([]cli.Flag{
+		&cli.IntFlag{
+			Name:    "lookback-days",
+			Usage:   "Number of days before the last aggregated day to recompute on each refresh.",
+			Value:   1,
+			EnvVars: []string{c.EnvPrefix + "_LOOKBACK_DAYS"},
+		},
+		&cli.Int64Flag{
+			Name:    "from",
+			Usage:   fromUsage,
+			Value:   0,
+			EnvVars: []string{c.EnvPrefix + "_FROM"},
+		},
+	}, c.Flags...)
+	c.Check = func(cctx *cli.Context) error {
+		if cctx.Int("lookback-days") < 0 {
+			return xerrors.Errorf("lookback-days must not be negative")
+		}
+		return nil
+	}
+	c.New = func(cctx *cli.Context, db *storage.Database) *storage.Aggregator {
+		return newJob(db, cctx.Int64("from"), cctx.Int("lookback-days"), cctx.Duration("interval"))
+	}
+	return newAggregateCmd(c)
+}
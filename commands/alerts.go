@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/alerts"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var alertFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "alert-webhook-url",
+		EnvVars: []string{"VISOR_ALERT_WEBHOOK_URL"},
+		Usage:   "Post alerts about failed jobs, gaps and indexing lag to the Slack or Discord webhook at `URL`.",
+	},
+	&cli.StringFlag{
+		Name:    "alert-webhook-format",
+		EnvVars: []string{"VISOR_ALERT_WEBHOOK_FORMAT"},
+		Value:   alerts.FormatSlack,
+		Usage:   "Payload format expected by the alert webhook, either slack or discord.",
+	},
+	&cli.IntFlag{
+		Name:    "alert-gap-threshold",
+		EnvVars: []string{"VISOR_ALERT_GAP_THRESHOLD"},
+		Usage:   "Alert when more than `N` gaps are recorded in the processing reports. Zero disables the check.",
+	},
+	&cli.Int64Flag{
+		Name:    "alert-lag-threshold",
+		EnvVars: []string{"VISOR_ALERT_LAG_THRESHOLD"},
+		Usage:   "Alert when indexing falls more than `N` epochs behind the chain head. Zero disables the check.",
+	},
+	&cli.DurationFlag{
+		Name:    "alert-check-interval",
+		EnvVars: []string{"VISOR_ALERT_CHECK_INTERVAL"},
+		Value:   5 * time.Minute,
+		Usage:   "Time between checks of the gap count and indexing lag.",
+	},
+	&cli.DurationFlag{
+		Name:    "alert-cooldown",
+		EnvVars: []string{"VISOR_ALERT_COOLDOWN"},
+		Value:   time.Hour,
+		Usage:   "Minimum time between repeated alerts for the same condition.",
+	},
+}
+
+// setupAlerts returns a notifier that posts to the configured alert webhook, or nil if no webhook was configured.
+func setupAlerts(cctx *cli.Context) (*alerts.Notifier, error) {
+	url := cctx.String("alert-webhook-url")
+	if url == "" {
+		return nil, nil
+	}
+
+	n, err := alerts.NewNotifier(url, cctx.String("alert-webhook-format"), cctx.String("name"), cctx.Duration("alert-cooldown"), 30*time.Second)
+	if err != nil {
+		return nil, xerrors.Errorf("new notifier: %w", err)
+	}
+	return n, nil
+}
+
+// setupAlertMonitor returns a monitor that checks the configured gap and lag thresholds, or nil if no thresholds were
+// configured.
+func setupAlertMonitor(cctx *cli.Context, n *alerts.Notifier, db *storage.Database, opener lens.APIOpener) (*alerts.Monitor, error) {
+	cfg := alerts.MonitorConfig{
+		Reporter:     cctx.String("name"),
+		GapThreshold: cctx.Int("alert-gap-threshold"),
+		LagThreshold: cctx.Int64("alert-lag-threshold"),
+		Interval:     cctx.Duration("alert-check-interval"),
+	}
+	if cfg.GapThreshold == 0 && cfg.LagThreshold == 0 {
+		return nil, nil
+	}
+	if db == nil {
+		return nil, xerrors.Errorf("gap and lag alerts require a database")
+	}
+	return alerts.NewMonitor(n, db, opener, cfg), nil
+}
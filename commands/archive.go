@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunArchiveCmd = &cli.Command{
+	Name:  "archive",
+	Usage: "Periodically move old processing reports to the visor_processing_reports_history table.",
+	Flags: flagSet(
+		dbConnectFlags,
+		[]cli.Flag{
+			&cli.DurationFlag{
+				Name:    "retention",
+				Usage:   "Archive processing reports that completed more than `DURATION` ago.",
+				Value:   30 * 24 * time.Hour,
+				EnvVars: []string{"VISOR_ARCHIVE_RETENTION"},
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "Time to wait between archival runs.",
+				Value:   time.Hour,
+				EnvVars: []string{"VISOR_ARCHIVE_INTERVAL"},
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		if err := setupMetrics(cctx); err != nil {
+			return xerrors.Errorf("setup metrics: %w", err)
+		}
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "ReportArchiver",
+				Job:                 storage.NewReportArchiver(db, cctx.Duration("retention"), cctx.Duration("interval")),
+				RestartOnFailure:    true,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
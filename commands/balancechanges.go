@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunActorBalanceChangesCmd = newHeightRangeAggregateCmd(aggregateCmd{
+	Name:      "actor-balance-changes",
+	Usage:     "Maintain the change in each actor's balance per epoch, attributed to the messages and rewards that caused it, in the actor_balance_changes table.",
+	Job:       "ActorBalanceChangeAggregator",
+	EnvPrefix: "VISOR_ACTOR_BALANCE_CHANGES",
+	Refreshes: "balance changes",
+},
+	"Number of epochs behind the latest extracted message or internal message to apply balance changes up to.",
+	"Number of epochs before the latest balance change applied to apply again on each refresh.",
+	storage.NewActorBalanceChangeAggregator)
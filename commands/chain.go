@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/export"
+)
+
+var ChainCmd = &cli.Command{
+	Name:  "chain",
+	Usage: "Interact with the chain through a lens.",
+	Subcommands: []*cli.Command{
+		ChainExportCmd,
+	},
+}
+
+var ChainExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "Export a range of the chain to a CAR file that can be read with the carrepo lens.",
+	Flags: flagSet(
+		runLensFlags,
+		[]cli.Flag{
+			&cli.Int64Flag{
+				Name:     "from",
+				Usage:    "Export tipsets at or above `HEIGHT`",
+				Required: true,
+			},
+			&cli.Int64Flag{
+				Name:     "to",
+				Usage:    "Export tipsets at or below `HEIGHT`",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "out",
+				Usage:    "Write the CAR file to `FILE`.",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "include-state",
+				Usage: "Export the state tree of every tipset, needed to extract actor state from the exported range.",
+				Value: false,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		cfg := export.Config{
+			From:         cctx.Int64("from"),
+			To:           cctx.Int64("to"),
+			IncludeState: cctx.Bool("include-state"),
+		}
+		if cfg.From > cfg.To {
+			return xerrors.Errorf("--from must not be greater than --to")
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		node, closer, err := lensOpener.Open(cctx.Context)
+		if err != nil {
+			return xerrors.Errorf("open lens: %w", err)
+		}
+		defer closer()
+
+		// Write to a temporary file so an interrupted export does not leave a truncated CAR at the requested path
+		out := cctx.String("out")
+		f, err := os.Create(out + ".tmp")
+		if err != nil {
+			return xerrors.Errorf("create output: %w", err)
+		}
+		defer os.Remove(f.Name()) // nolint: errcheck
+
+		res, err := export.Export(cctx.Context, node, f, cfg)
+		if err != nil {
+			_ = f.Close() // nolint: errcheck
+			return xerrors.Errorf("export: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return xerrors.Errorf("close output: %w", err)
+		}
+		if err := os.Rename(f.Name(), out); err != nil {
+			return xerrors.Errorf("rename output: %w", err)
+		}
+
+		return printOutput(struct {
+			File    string          `json:"file"`
+			Head    types.TipSetKey `json:"head"`
+			TipSets int             `json:"tipsets"`
+			Blocks  int             `json:"blocks"`
+			Bytes   int64           `json:"bytes"`
+		}{
+			File:    out,
+			Head:    res.Head,
+			TipSets: res.TipSets,
+			Blocks:  res.Blocks,
+			Bytes:   res.Bytes,
+		}, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "exported %d tipsets as %d blocks (%d bytes) to %s, head %s\n", res.TipSets, res.Blocks, res.Bytes, out, res.Head)
+			return err
+		})
+	},
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/storage/chainwatch"
+)
+
+var MigrateFromChainwatchCmd = &cli.Command{
+	Name:  "migrate-from-chainwatch",
+	Usage: "Copy blocks, messages, receipts and actors from a lotus chainwatch database into the visor schema.",
+	Flags: flagSet(
+		dbConnectFlags,
+		dbBehaviourFlags,
+		dbInitFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:     "chainwatch-db",
+				EnvVars:  []string{"VISOR_CHAINWATCH_DB"},
+				Usage:    "A connection string for the postgres database populated by chainwatch.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "chainwatch-schema",
+				EnvVars: []string{"VISOR_CHAINWATCH_SCHEMA"},
+				Value:   "public",
+				Usage:   "The name of the postgresql schema that holds the chainwatch tables.",
+			},
+			&cli.Int64Flag{
+				Name:  "from",
+				Usage: "Migrate data from `HEIGHT` onwards. Defaults to the lowest height in the chainwatch database.",
+			},
+			&cli.Int64Flag{
+				Name:  "to",
+				Usage: "Migrate data up to and including `HEIGHT`. Defaults to the highest height in the chainwatch database.",
+			},
+			&cli.Int64Flag{
+				Name:  "batch-size",
+				Usage: "Number of `EPOCHS` of data to copy in each transaction.",
+				Value: 100,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		src, err := connectChainwatch(ctx, cctx.String("chainwatch-db"), cctx.String("chainwatch-schema"))
+		if err != nil {
+			return xerrors.Errorf("connect chainwatch database: %w", err)
+		}
+		defer src.Close() // nolint: errcheck
+
+		m := chainwatch.NewMigrator(src, db, cctx.Int64("batch-size"))
+
+		from, to, err := m.HeightRange(ctx)
+		if err != nil {
+			return err
+		}
+		if cctx.IsSet("from") {
+			from = cctx.Int64("from")
+		}
+		if cctx.IsSet("to") {
+			to = cctx.Int64("to")
+		}
+		if from > to {
+			return xerrors.Errorf("nothing to migrate, from height %d is after to height %d", from, to)
+		}
+
+		log.Infow("migrating chainwatch data", "from", from, "to", to)
+		return m.Migrate(ctx, from, to)
+	},
+}
+
+func connectChainwatch(ctx context.Context, url string, schemaName string) (*pg.DB, error) {
+	opt, err := pg.ParseURL(url)
+	if err != nil {
+		return nil, xerrors.Errorf("parse database URL: %w", err)
+	}
+	opt.OnConnect = func(ctx context.Context, conn *pg.Conn) error {
+		_, err := conn.Exec("set search_path=?", schemaName)
+		return err
+	}
+
+	db := pg.Connect(opt).WithContext(ctx)
+	if err := db.Ping(ctx); err != nil {
+		_ = db.Close() // nolint: errcheck
+		return nil, xerrors.Errorf("ping database: %w", err)
+	}
+	return db, nil
+}
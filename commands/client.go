@@ -30,3 +30,23 @@ var clientAPIFlagSet = []cli.Flag{
 	clientAPIFlag,
 	clientTokenFlag,
 }
+
+var jobLimitsFlags struct {
+	maxLensCalls      int
+	maxPersistBatches int
+}
+
+// jobLimitsFlagSet are used by commands that start indexing jobs in a daemon, whose load on the daemon's node and
+// storage may need to be bounded so that it does not starve the daemon's other jobs.
+var jobLimitsFlagSet = []cli.Flag{
+	&cli.IntFlag{
+		Name:        "max-lens-calls",
+		Usage:       "Maximum number of calls to the daemon's node that the job may have in progress at once, shared by all of its indexers. Zero means no limit.",
+		Destination: &jobLimitsFlags.maxLensCalls,
+	},
+	&cli.IntFlag{
+		Name:        "max-persist-batches",
+		Usage:       "Maximum number of batches of extracted data that the job may be writing to storage at once, shared by all of its indexers. Zero means no limit.",
+		Destination: &jobLimitsFlags.maxPersistBatches,
+	},
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	lotuscli "github.com/filecoin-project/lotus/cli"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/lens/lily"
+)
+
+var completenessFlags struct {
+	from    int64
+	to      int64
+	tasks   string
+	storage string
+}
+
+var CompletenessCmd = &cli.Command{
+	Name:  "completeness",
+	Usage: "Report whether tasks have completed for every tipset in a range of heights.",
+	Description: `Prints a JSON report listing the tipsets in the range for which any of the tasks has not recorded a successful
+processing report in the storage. Null rounds have no tipset and are not reported. Exits with an error if any task is
+incomplete so scripts can wait for data to be complete before using it.`,
+	Flags: flagSet(
+		clientAPIFlagSet,
+		[]cli.Flag{
+			&cli.Int64Flag{
+				Name:        "from",
+				Usage:       "First height of the range.",
+				Required:    true,
+				Destination: &completenessFlags.from,
+			},
+			&cli.Int64Flag{
+				Name:        "to",
+				Usage:       "Last height of the range.",
+				Required:    true,
+				Destination: &completenessFlags.to,
+			},
+			&cli.StringFlag{
+				Name:        "tasks",
+				Usage:       "Comma separated list of tasks that must have completed. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
+				Value:       strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
+				Destination: &completenessFlags.tasks,
+			},
+			&cli.StringFlag{
+				Name:        "storage",
+				Usage:       "Name of the storage holding the processing reports.",
+				Required:    true,
+				Destination: &completenessFlags.storage,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		ctx := lotuscli.ReqContext(cctx)
+
+		api, closer, err := GetAPI(ctx, clientAPIFlags.apiAddr, clientAPIFlags.apiToken)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		report, err := api.LilyCompleteness(ctx, &lily.LilyCompletenessConfig{
+			From:    completenessFlags.from,
+			To:      completenessFlags.to,
+			Tasks:   strings.Split(completenessFlags.tasks, ","),
+			Storage: completenessFlags.storage,
+		})
+		if err != nil {
+			return err
+		}
+
+		out, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(os.Stdout, string(out)); err != nil {
+			return err
+		}
+
+		if !report.Complete {
+			return xerrors.Errorf("%d of %d tipsets have incomplete tasks", len(report.Missing), report.TipSets)
+		}
+		return nil
+	},
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 
 	paramfetch "github.com/filecoin-project/go-paramfetch"
@@ -20,10 +21,13 @@ import (
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/sentinel-visor/chain"
 	"github.com/filecoin-project/sentinel-visor/commands/util"
 	"github.com/filecoin-project/sentinel-visor/config"
+	"github.com/filecoin-project/sentinel-visor/graphql"
 	"github.com/filecoin-project/sentinel-visor/lens/lily"
 	"github.com/filecoin-project/sentinel-visor/lens/lily/modules"
+	"github.com/filecoin-project/sentinel-visor/rosetta"
 	"github.com/filecoin-project/sentinel-visor/schedule"
 	"github.com/filecoin-project/sentinel-visor/storage"
 )
@@ -33,6 +37,7 @@ type daemonOpts struct {
 	bootstrap bool // TODO: is this necessary - do we want to run visor in this mode?
 	config    string
 	genesis   string
+	liteNode  bool
 }
 
 var daemonFlags daemonOpts
@@ -68,6 +73,12 @@ var DaemonCmd = &cli.Command{
 			EnvVars:     []string{"VISOR_GENESIS"},
 			Destination: &daemonFlags.genesis,
 		},
+		&cli.BoolFlag{
+			Name:        "lite-node",
+			Usage:       "Run the embedded node in a chain sync only mode that follows the chain for visor's jobs but does not follow the message pool, run the markets client or manage payment channels.",
+			EnvVars:     []string{"VISOR_LITE_NODE"},
+			Destination: &daemonFlags.liteNode,
+		},
 	},
 	Action: func(c *cli.Context) error {
 		lotuslog.SetupLogLevels()
@@ -142,6 +153,11 @@ var DaemonCmd = &cli.Command{
 		isBootstrapper := false
 		shutdown := make(chan struct{})
 		liteModeDeps := node.Options()
+		if daemonFlags.liteNode {
+			log.Info("running chain sync only node")
+			liteModeDeps = chainSyncOnlyOptions()
+		}
+		notifier := chain.NewIndexNotifier()
 		var api lily.LilyAPI
 		stop, err := node.New(ctx,
 			// Start Sentinel Dep injection
@@ -150,6 +166,7 @@ var DaemonCmd = &cli.Command{
 			node.Override(new(*events.Events), modules.NewEvents),
 			node.Override(new(*schedule.Scheduler), schedule.NewSchedulerDaemon),
 			node.Override(new(*storage.Catalog), modules.NewStorageCatalog),
+			node.Override(new(*chain.IndexNotifier), notifier),
 			// End Injection
 
 			node.Override(new(dtypes.Bootstrapper), isBootstrapper),
@@ -178,6 +195,15 @@ var DaemonCmd = &cli.Command{
 			return xerrors.Errorf("initializing node: %w", err)
 		}
 
+		mux := http.NewServeMux()
+		mux.Handle("/events/tipsets", util.RequirePermission(api, util.PermRead, util.IndexedTipSetsHandler(notifier)))
+
+		closeQuery, err := setupQueryServer(ctx, daemonFlags.config, api, mux)
+		if err != nil {
+			return xerrors.Errorf("setup query server: %w", err)
+		}
+		defer closeQuery()
+
 		endpoint, err := r.APIEndpoint()
 		if err != nil {
 			return xerrors.Errorf("getting api endpoint: %w", err)
@@ -185,6 +211,69 @@ var DaemonCmd = &cli.Command{
 
 		// TODO: properly parse api endpoint (or make it a URL)
 		maxAPIRequestSize := int64(0)
-		return util.ServeRPC(api, stop, endpoint, shutdown, maxAPIRequestSize)
+		return util.ServeRPC(api, stop, endpoint, shutdown, maxAPIRequestSize, mux)
 	},
 }
+
+// chainSyncOnlyOptions removes the services of a full node that visor does not need to sync the chain and extract
+// data from it: the message pool's gossip subscription, the markets client's fund migration and payment channel
+// management. Blocks are still received from the network and validated, so the node's chain stays at the head.
+func chainSyncOnlyOptions() node.Option {
+	return node.Options(
+		node.Unset(node.HandleIncomingMessagesKey),
+		node.Unset(node.HandleMigrateClientFundsKey),
+		node.Unset(node.HandlePaymentChannelManagerKey),
+		node.Unset(node.SettlePaymentChannelsKey),
+	)
+}
+
+// setupQueryServer registers the GraphQL query handler, and the Rosetta handler if enabled, with mux if a storage has
+// been configured for them. The handlers require an API token with read permission. The query server uses its own
+// connection to the database so queries do not compete with indexing for connections. The returned function closes
+// that connection and must be called once the server has stopped.
+func setupQueryServer(ctx context.Context, configPath string, api lily.LilyAPI, mux *http.ServeMux) (func(), error) {
+	cfg, err := config.FromFile(configPath)
+	if err != nil {
+		return nil, xerrors.Errorf("read config: %w", err)
+	}
+	if cfg.Query.Storage == "" {
+		return func() {}, nil
+	}
+
+	catalog, err := storage.NewCatalog(cfg.Storage)
+	if err != nil {
+		return nil, xerrors.Errorf("storage catalog: %w", err)
+	}
+
+	strg, err := catalog.Connect(ctx, cfg.Query.Storage)
+	if err != nil {
+		return nil, xerrors.Errorf("connect storage %q: %w", cfg.Query.Storage, err)
+	}
+
+	// Queries are served from the read replica of the storage if it has one
+	db, ok := strg.(*storage.Database)
+	if !ok {
+		return nil, xerrors.Errorf("storage %q is not a postgresql database", cfg.Query.Storage)
+	}
+	reader := db.Reader()
+
+	closeQuery := func() {
+		if reader != db {
+			if err := reader.Close(context.Background()); err != nil {
+				log.Errorw("failed to close query read replica", "error", err)
+			}
+		}
+		if err := db.Close(context.Background()); err != nil {
+			log.Errorw("failed to close query storage", "error", err)
+		}
+	}
+
+	mux.Handle("/graphql", util.RequirePermission(api, util.PermRead, graphql.NewHandler(graphql.NewSchema(reader))))
+	log.Infow("serving graphql queries", "storage", cfg.Query.Storage, "replica", reader != db)
+
+	if cfg.Query.Rosetta {
+		mux.Handle("/rosetta/", util.RequirePermission(api, util.PermRead, http.StripPrefix("/rosetta", rosetta.NewHandler(rosetta.NewServer(reader)))))
+		log.Infow("serving rosetta data api", "storage", cfg.Query.Storage)
+	}
+	return closeQuery, nil
+}
@@ -0,0 +1,13 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunDatacapUsageCmd = newDailyAggregateCmd(aggregateCmd{
+	Name:      "datacap-usage",
+	Usage:     "Maintain daily totals of the datacap granted to and used by verified clients in the verified_client_datacap_usage table.",
+	Job:       "DatacapUsageAggregator",
+	EnvPrefix: "VISOR_DATACAP_USAGE",
+	Refreshes: "daily totals",
+}, "Height to start aggregating from when the table is empty.", storage.NewDatacapUsageAggregator)
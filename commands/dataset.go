@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens/lotus"
+	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/storage/dataset"
+)
+
+var RunDatasetArchiveCmd = &cli.Command{
+	Name:  "dataset-archive",
+	Usage: "Periodically export completely indexed ranges of heights as CAR files and optionally make storage deals for them.",
+	Flags: flagSet(
+		dbConnectFlags,
+		dbReadFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:     "path",
+				Usage:    "Write exported files to `DIR`.",
+				EnvVars:  []string{"VISOR_DATASET_PATH"},
+				Required: true,
+			},
+			&cli.Int64Flag{
+				Name:    "from",
+				Usage:   "Start archiving at `HEIGHT` if no archives have been recorded.",
+				EnvVars: []string{"VISOR_DATASET_FROM"},
+			},
+			&cli.Int64Flag{
+				Name:    "range-size",
+				Usage:   "Number of `EPOCHS` in each archive.",
+				Value:   2880,
+				EnvVars: []string{"VISOR_DATASET_RANGE_SIZE"},
+			},
+			&cli.Int64Flag{
+				Name:    "finality",
+				Usage:   "Only archive a range once data has been indexed `EPOCHS` beyond it.",
+				Value:   900,
+				EnvVars: []string{"VISOR_DATASET_FINALITY"},
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "Time to wait between checks for completed ranges.",
+				Value:   time.Hour,
+				EnvVars: []string{"VISOR_DATASET_INTERVAL"},
+			},
+			&cli.StringFlag{
+				Name:    "deal-miner",
+				Usage:   "Make a storage deal for each archive with the miner at `ADDRESS`. Requires a lotus API.",
+				EnvVars: []string{"VISOR_DATASET_DEAL_MINER"},
+			},
+			&cli.StringFlag{
+				Name:    "deal-wallet",
+				Usage:   "Pay for storage deals from the wallet at `ADDRESS`.",
+				EnvVars: []string{"VISOR_DATASET_DEAL_WALLET"},
+			},
+			&cli.StringFlag{
+				Name:    "deal-price",
+				Usage:   "Price per epoch of storage deals in `FIL`.",
+				Value:   "0",
+				EnvVars: []string{"VISOR_DATASET_DEAL_PRICE"},
+			},
+			&cli.Uint64Flag{
+				Name:    "deal-duration",
+				Usage:   "Minimum duration of storage deals in `EPOCHS`.",
+				Value:   518400,
+				EnvVars: []string{"VISOR_DATASET_DEAL_DURATION"},
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		if err := setupMetrics(cctx); err != nil {
+			return xerrors.Errorf("setup metrics: %w", err)
+		}
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		cfg := dataset.Config{
+			Path:      cctx.String("path"),
+			From:      cctx.Int64("from"),
+			RangeSize: cctx.Int64("range-size"),
+			Finality:  cctx.Int64("finality"),
+			Interval:  cctx.Duration("interval"),
+		}
+
+		if cctx.IsSet("deal-miner") {
+			deal, closer, err := setupDealConfig(cctx)
+			if err != nil {
+				return xerrors.Errorf("setup deals: %w", err)
+			}
+			defer closer()
+			cfg.Deal = deal
+		}
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "DatasetArchiver",
+				Job:                 dataset.NewArchiver(db, cfg),
+				RestartOnFailure:    true,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
+
+func setupDealConfig(cctx *cli.Context) (*dataset.DealConfig, func(), error) {
+	miner, err := address.NewFromString(cctx.String("deal-miner"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parse miner address: %w", err)
+	}
+	wallet, err := address.NewFromString(cctx.String("deal-wallet"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parse wallet address: %w", err)
+	}
+	price, err := types.ParseFIL(cctx.String("deal-price"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("parse price: %w", err)
+	}
+
+	opener, closer, err := lotus.NewAPIOpener(cctx, 1)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("setup lotus api: %w", err)
+	}
+	node, apiCloser, err := opener.Open(cctx.Context)
+	if err != nil {
+		closer()
+		return nil, nil, xerrors.Errorf("open lotus api: %w", err)
+	}
+
+	client, ok := node.(dataset.DealClient)
+	if !ok {
+		apiCloser()
+		closer()
+		return nil, nil, xerrors.Errorf("lotus api does not support storage deals")
+	}
+
+	deal := &dataset.DealConfig{
+		Client:     client,
+		Miner:      miner,
+		Wallet:     wallet,
+		EpochPrice: types.BigInt(price),
+		Duration:   cctx.Uint64("deal-duration"),
+	}
+	return deal, func() {
+		apiCloser()
+		closer()
+	}, nil
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var DbCmd = &cli.Command{
+	Name:  "db",
+	Usage: "Copy data between visor databases and maintain their indexes.",
+	Subcommands: []*cli.Command{
+		DbSnapshotCmd,
+		DbRestoreCmd,
+		DbRebuildIndexesCmd,
+	},
+}
+
+var DbSnapshotCmd = &cli.Command{
+	Name:  "snapshot",
+	Usage: "Export the data for a range of heights to a directory.",
+	Description: `Writes one gzip compressed CSV file per table holding the rows with heights in the range, together with the
+processing reports for the range and a manifest.json describing the snapshot. All tables are read in a single
+transaction so the snapshot is consistent while other jobs write to the database. Restore the snapshot with
+visor db restore to seed a new deployment without walking the chain again.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		[]cli.Flag{
+			&cli.Int64Flag{
+				Name:     "from",
+				Usage:    "Include rows at or above `HEIGHT`.",
+				Required: true,
+			},
+			&cli.Int64Flag{
+				Name:     "to",
+				Usage:    "Include rows at or below `HEIGHT`.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "tables",
+				Usage: "Comma separated list of tables to include. All tables holding data extracted from the chain are included if empty.",
+			},
+			&cli.StringFlag{
+				Name:     "out",
+				Usage:    "`DIR` to write the snapshot to. It is created if it does not exist.",
+				Required: true,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if cctx.Int64("from") > cctx.Int64("to") {
+			return xerrors.Errorf("--from must not be greater than --to")
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := storage.NewDatabase(ctx, cctx.String("db"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"), false)
+		if err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		if err := db.Connect(ctx); err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		var tables []string
+		if cctx.String("tables") != "" {
+			tables = strings.Split(cctx.String("tables"), ",")
+		}
+
+		manifest, err := db.Snapshot(ctx, cctx.Int64("from"), cctx.Int64("to"), tables, cctx.String("out"))
+		if err != nil {
+			return xerrors.Errorf("snapshot: %w", err)
+		}
+		log.Infow("snapshot complete", "from", manifest.From, "to", manifest.To, "tables", len(manifest.Tables), "schema_version", manifest.SchemaVersion)
+		return nil
+	},
+}
+
+var DbRestoreCmd = &cli.Command{
+	Name:  "restore",
+	Usage: "Import a snapshot written by visor db snapshot.",
+	Description: `Imports every table in the snapshot in a single transaction. The database schema must be at the version the
+snapshot was taken from, migrate it first if necessary. Rows that already exist are kept.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:     "in",
+				Usage:    "`DIR` holding the snapshot.",
+				Required: true,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := storage.NewDatabase(ctx, cctx.String("db"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"), false)
+		if err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		if err := db.Connect(ctx); err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		manifest, err := db.Restore(ctx, cctx.String("in"))
+		if err != nil {
+			return xerrors.Errorf("restore: %w", err)
+		}
+		log.Infow("restore complete", "from", manifest.From, "to", manifest.To, "tables", len(manifest.Tables))
+		return nil
+	},
+}
+
+var DbRebuildIndexesCmd = &cli.Command{
+	Name:  "rebuild-indexes",
+	Usage: "Rebuild the indexes deferred by walks started with --defer-indexes.",
+	Description: `Indexes deferred by a walk are normally rebuilt when the last walk holding them finishes. Use this command to
+rebuild indexes left deferred by a walk that crashed. Indexes still held by running walks are only rebuilt with --force.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		[]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Rebuild every deferred index, even those held by walks that appear to be running.",
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := storage.NewDatabase(ctx, cctx.String("db"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"), false)
+		if err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		if err := db.Connect(ctx); err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		n, err := db.RebuildIndexes(ctx, "", cctx.Bool("force"))
+		if err != nil {
+			return xerrors.Errorf("rebuild indexes: %w", err)
+		}
+		log.Infow("rebuild complete", "indexes", n)
+		return nil
+	},
+}
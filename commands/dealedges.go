@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunDealEdgesCmd = newAggregateCmd(aggregateCmd{
+	Name:      "deal-edges",
+	Usage:     "Maintain the graph of deals between clients and providers in the market_deal_edges table.",
+	Job:       "MarketDealEdgeAggregator",
+	EnvPrefix: "VISOR_DEAL_EDGES",
+	Refreshes: "graph",
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:    "lag",
+			Usage:   "Number of epochs behind the latest extracted deal proposal to aggregate up to.",
+			Value:   900,
+			EnvVars: []string{"VISOR_DEAL_EDGES_LAG"},
+		},
+		&cli.BoolFlag{
+			Name:    "rebuild",
+			Usage:   "Delete the existing graph and rebuild it from all extracted deal proposals.",
+			EnvVars: []string{"VISOR_DEAL_EDGES_REBUILD"},
+		},
+	},
+	Check: func(cctx *cli.Context) error {
+		if cctx.Int64("lag") < 0 {
+			return xerrors.Errorf("lag must not be negative")
+		}
+		return nil
+	},
+	New: func(cctx *cli.Context, db *storage.Database) *storage.Aggregator {
+		return storage.NewMarketDealEdgeAggregator(db, cctx.Int64("lag"), cctx.Duration("interval"), cctx.Bool("rebuild"))
+	},
+})
@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunDealTimelinesCmd = newHeightRangeAggregateCmd(aggregateCmd{
+	Name:      "deal-timelines",
+	Usage:     "Maintain the timeline of state transitions of each market deal in the market_deal_timelines table.",
+	Job:       "DealTimelineAggregator",
+	EnvPrefix: "VISOR_DEAL_TIMELINES",
+	Refreshes: "timelines",
+},
+	"Number of epochs behind the latest extracted deal data to apply up to.",
+	"Number of epochs before the latest height applied to apply again on each refresh.",
+	storage.NewDealTimelineAggregator)
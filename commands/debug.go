@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var DebugCmd = &cli.Command{
+	Name:  "debug",
+	Usage: "Tools for debugging extraction.",
+	Subcommands: []*cli.Command{
+		DebugExtractCmd,
+	},
+}
+
+// actorStateTaskAliases maps short actor names to their actor state task so tasks can be named as in --task miner.
+var actorStateTaskAliases = map[string]string{
+	"raw":      chain.ActorStatesRawTask,
+	"power":    chain.ActorStatesPowerTask,
+	"reward":   chain.ActorStatesRewardTask,
+	"miner":    chain.ActorStatesMinerTask,
+	"init":     chain.ActorStatesInitTask,
+	"market":   chain.ActorStatesMarketTask,
+	"multisig": chain.ActorStatesMultisigTask,
+	"verifreg": chain.ActorStatesVerifregTask,
+	"untyped":  chain.ActorStatesUntypedTask,
+}
+
+// A debugExtraction is the output of the extract command.
+type debugExtraction struct {
+	Task   string                       `json:"task"`
+	TipSet string                       `json:"tipset"`
+	Height int64                        `json:"height"`
+	Report *visormodel.ProcessingReport `json:"report"`
+	Tables map[string][]interface{}     `json:"tables"` // models that would be persisted, by table
+}
+
+var DebugExtractCmd = &cli.Command{
+	Name:  "extract",
+	Usage: "Run a single task against a tipset and print the models it extracts as JSON without persisting them.",
+	Flags: flagSet(
+		runLensFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:     "task",
+				Usage:    "Name of the `TASK` to run. Actor state tasks may be named by actor, for example miner for actorstatesminer.",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "tipset",
+				Usage: "Key of the tipset to extract, as a comma separated list of block CIDs.",
+			},
+			&cli.Int64Flag{
+				Name:  "height",
+				Usage: "Extract the tipset at `HEIGHT` on the lens's chain, used if --tipset is not given.",
+			},
+			&cli.StringFlag{
+				Name:  "actor",
+				Usage: "Only extract the actor with `ADDRESS`, for actor state tasks.",
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if !cctx.IsSet("tipset") && !cctx.IsSet("height") {
+			return xerrors.Errorf("one of --tipset or --height must be given")
+		}
+
+		task := cctx.String("task")
+		if alias, ok := actorStateTaskAliases[task]; ok {
+			task = alias
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		var opts []chain.TipSetIndexerOpt
+		if cctx.IsSet("actor") {
+			opts = append(opts, chain.AddressFilterOpt(chain.NewAddressFilter(cctx.String("actor"))))
+		}
+
+		// The indexer is given no storage since nothing is persisted
+		idx, err := chain.NewTipSetIndexer(lensOpener, nil, 0, "debug", []string{task}, opts...)
+		if err != nil {
+			return xerrors.Errorf("setup indexer: %w", err)
+		}
+		defer idx.Close() // nolint: errcheck
+
+		node, closer, err := lensOpener.Open(cctx.Context)
+		if err != nil {
+			return xerrors.Errorf("open lens: %w", err)
+		}
+		defer closer()
+
+		var ts *types.TipSet
+		if cctx.IsSet("tipset") {
+			tsk, err := visormodel.DecodeTipSetKey(cctx.String("tipset"))
+			if err != nil {
+				return xerrors.Errorf("parse tipset: %w", err)
+			}
+			ts, err = node.ChainGetTipSet(cctx.Context, tsk)
+			if err != nil {
+				return xerrors.Errorf("get tipset: %w", err)
+			}
+		} else {
+			ts, err = node.ChainGetTipSetByHeight(cctx.Context, abi.ChainEpoch(cctx.Int64("height")), types.EmptyTSK)
+			if err != nil {
+				return xerrors.Errorf("get tipset: %w", err)
+			}
+		}
+
+		data, report, err := idx.ExtractTask(cctx.Context, task, ts)
+		if err != nil {
+			return xerrors.Errorf("extract: %w", err)
+		}
+
+		// Collect the models that would have been persisted
+		mem := storage.NewMemStorageLatest()
+		if data != nil {
+			if err := data.Persist(cctx.Context, mem, mem.Version); err != nil {
+				return xerrors.Errorf("collect models: %w", err)
+			}
+		}
+
+		out := debugExtraction{
+			Task:   task,
+			TipSet: visormodel.EncodeTipSetKey(ts.Key()),
+			Height: int64(ts.Height()),
+			Report: report,
+			Tables: mem.Data,
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	},
+}
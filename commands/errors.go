@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	lotuscli "github.com/filecoin-project/lotus/cli"
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/sentinel-visor/lens/lily"
+)
+
+var ErrorsCmd = &cli.Command{
+	Name:  "errors",
+	Usage: "Inspect the failures recorded in processing reports.",
+	Subcommands: []*cli.Command{
+		ErrorsListCmd,
+	},
+}
+
+var errorsListFlags struct {
+	since   time.Duration
+	tasks   string
+	storage string
+}
+
+var ErrorsListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List recent processing failures grouped by task and error class.",
+	Description: `Prints a JSON list with one entry for each task and error class that has failed within the period given by
+--since, most recently seen first. Each entry gives the range of heights that failed, how many of those heights have
+not since completed successfully and the errors detected by the latest failure.`,
+	Flags: flagSet(
+		clientAPIFlagSet,
+		[]cli.Flag{
+			&cli.DurationFlag{
+				Name:        "since",
+				Usage:       "Include failures reported within this duration before now.",
+				Value:       24 * time.Hour,
+				Destination: &errorsListFlags.since,
+			},
+			&cli.StringFlag{
+				Name:        "tasks",
+				Aliases:     []string{"task"},
+				Usage:       "Comma separated list of tasks whose failures are listed, all tasks if not set. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
+				Destination: &errorsListFlags.tasks,
+			},
+			&cli.StringFlag{
+				Name:        "storage",
+				Usage:       "Name of the storage holding the processing reports.",
+				Required:    true,
+				Destination: &errorsListFlags.storage,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		ctx := lotuscli.ReqContext(cctx)
+
+		api, closer, err := GetAPI(ctx, clientAPIFlags.apiAddr, clientAPIFlags.apiToken)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		cfg := &lily.LilyProcessingErrorsConfig{
+			Since:   errorsListFlags.since,
+			Storage: errorsListFlags.storage,
+		}
+		if errorsListFlags.tasks != "" {
+			cfg.Tasks = strings.Split(errorsListFlags.tasks, ",")
+		}
+
+		groups, err := api.LilyProcessingErrors(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		out, err := json.MarshalIndent(groups, "", "\t")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(os.Stdout, string(out)); err != nil {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/schedule"
+)
+
+var RunObserveFeeMarketCmd = &cli.Command{
+	Name:  "observe-fee-market",
+	Usage: "Record the gas premiums of included and pending messages for each epoch in the fee_market_observations table.",
+	Description: `Each time the head of the lotus node advances, the lowest and median gas premiums of the messages included in
+the head are recorded alongside those of the messages still pending in the node's message pool, together with the base
+fee. Fee estimators can use the records to judge the premium needed for a message to be included promptly. Only the
+lotus lens reports pending messages.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "Time between each poll of the chain head.",
+				Value:   5 * time.Second,
+				EnvVars: []string{"VISOR_OBSERVE_FEE_MARKET_INTERVAL"},
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		if err := setupMetrics(cctx); err != nil {
+			return xerrors.Errorf("setup metrics: %w", err)
+		}
+
+		if cctx.Duration("interval") <= 0 {
+			return xerrors.Errorf("interval must be greater than zero")
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "FeeMarketObserver",
+				Job:                 chain.NewFeeMarketObserver(lensOpener, db, cctx.String("name"), cctx.Duration("interval")),
+				RestartOnFailure:    true,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	lotuscli "github.com/filecoin-project/lotus/cli"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/lens/lily"
+)
+
+var gapFillFlags struct {
+	ranges    cli.StringSlice
+	tasks     string
+	window    time.Duration
+	storage   string
+	name      string
+	overwrite bool
+}
+
+var GapFillCmd = &cli.Command{
+	Name:  "gapfill",
+	Usage: "Start a daemon job to index explicit height ranges of the filecoin blockchain.",
+	Flags: flagSet(
+		clientAPIFlagSet,
+		jobLimitsFlagSet,
+		[]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:        "range",
+				Usage:       "Range of heights to fill, as `FROM-TO` inclusive of both heights. May be repeated.",
+				Required:    true,
+				Destination: &gapFillFlags.ranges,
+			},
+			&cli.StringFlag{
+				Name:        "tasks",
+				Usage:       "Comma separated list of tasks to run. Each task is reported separately in the database. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
+				Value:       strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
+				Destination: &gapFillFlags.tasks,
+			},
+			&cli.DurationFlag{
+				Name:        "window",
+				Usage:       "Duration after which any indexing work not completed will be marked incomplete",
+				Value:       builtin.EpochDurationSeconds * time.Second * 10, // fills don't need to complete within a single epoch
+				Destination: &gapFillFlags.window,
+			},
+			&cli.StringFlag{
+				Name:        "storage",
+				Usage:       "Name of storage that results will be written to.",
+				Value:       "",
+				Destination: &gapFillFlags.storage,
+			},
+			&cli.StringFlag{
+				Name:        "name",
+				Usage:       "Name of job for easy identification later.",
+				Value:       "",
+				Destination: &gapFillFlags.name,
+			},
+			&cli.BoolFlag{
+				Name:        "overwrite",
+				Usage:       "Replace rows that already exist in storage instead of keeping them, so re-running a job after an extractor fix corrects the data.",
+				Destination: &gapFillFlags.overwrite,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		ctx := lotuscli.ReqContext(cctx)
+
+		ranges, err := parseHeightRanges(gapFillFlags.ranges.Value())
+		if err != nil {
+			return err
+		}
+
+		fillName := fmt.Sprintf("gapfill_%d", time.Now().Unix())
+		if gapFillFlags.name != "" {
+			fillName = gapFillFlags.name
+		}
+
+		cfg := &lily.LilyGapFillRangeConfig{
+			Ranges:              ranges,
+			Name:                fillName,
+			Tasks:               strings.Split(gapFillFlags.tasks, ","),
+			Window:              gapFillFlags.window,
+			RestartDelay:        time.Minute,
+			RestartOnCompletion: false,
+			RestartOnFailure:    true, // a restarted fill continues with the range that failed
+			Storage:             gapFillFlags.storage,
+			Overwrite:           gapFillFlags.overwrite,
+			MaxLensCalls:        jobLimitsFlags.maxLensCalls,
+			MaxPersistBatches:   jobLimitsFlags.maxPersistBatches,
+		}
+
+		api, closer, err := GetAPI(ctx, clientAPIFlags.apiAddr, clientAPIFlags.apiToken)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		fillID, err := api.LilyGapFillRange(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		return printJobCreated("Created Gap Fill Job: %d", fillID)
+	},
+}
+
+// parseHeightRanges parses height ranges written as FROM-TO.
+func parseHeightRanges(values []string) ([]chain.HeightRange, error) {
+	ranges := make([]chain.HeightRange, 0, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, "-", 2)
+		if len(parts) != 2 {
+			return nil, xerrors.Errorf("invalid range %q, expected FROM-TO", v)
+		}
+		from, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid range %q: %w", v, err)
+		}
+		to, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid range %q: %w", v, err)
+		}
+		if from > to {
+			return nil, xerrors.Errorf("invalid range %q, start is after end", v)
+		}
+		ranges = append(ranges, chain.HeightRange{From: from, To: to})
+	}
+	return ranges, nil
+}
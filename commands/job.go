@@ -3,11 +3,16 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"strconv"
+	"time"
 
 	lotuscli "github.com/filecoin-project/lotus/cli"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/lens/lily"
 	"github.com/filecoin-project/sentinel-visor/schedule"
 )
 
@@ -18,6 +23,7 @@ var JobCmd = &cli.Command{
 		JobStartCmd,
 		JobStopCmd,
 		JobListCmd,
+		JobResumeCmd,
 	},
 }
 
@@ -95,13 +101,73 @@ var JobListCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
-		prettyJobs, err := json.MarshalIndent(jobs, "", "\t")
+		return printOutput(jobs, func(w io.Writer) error {
+			prettyJobs, err := json.MarshalIndent(jobs, "", "\t")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "List Jobs:\n%s\n", prettyJobs)
+			return err
+		})
+	},
+}
+
+var jobResumeFlags struct {
+	window  time.Duration
+	storage string
+}
+
+var JobResumeCmd = &cli.Command{
+	Name:      "resume",
+	Usage:     "start a job that continues a walk from the last tipset it committed.",
+	ArgsUsage: "<walk-id>",
+	Description: `The id of a walk is shown as walkID in the job list and is recorded in the visor_walk_jobs table. A walk
+is resumed with the tasks, range and direction it was started with, so it may be resumed by a different daemon or a
+newer version of visor that uses the same schema.`,
+	Flags: flagSet(
+		clientAPIFlagSet,
+		jobLimitsFlagSet,
+		[]cli.Flag{
+			&cli.DurationFlag{
+				Name:        "window",
+				Usage:       "Duration after which any indexing work not completed will be marked incomplete",
+				Value:       builtin.EpochDurationSeconds * time.Second * 10, // walks don't need to complete within a single epoch
+				Destination: &jobResumeFlags.window,
+			},
+			&cli.StringFlag{
+				Name:        "storage",
+				Usage:       "Name of storage that holds the walk and that results will be written to.",
+				Value:       "",
+				Destination: &jobResumeFlags.storage,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return xerrors.Errorf("expected the id of the walk to resume")
+		}
+		walkID, err := strconv.ParseInt(cctx.Args().First(), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("invalid walk id: %w", err)
+		}
+
+		ctx := lotuscli.ReqContext(cctx)
+		api, closer, err := GetAPI(ctx, clientAPIFlags.apiAddr, clientAPIFlags.apiToken)
 		if err != nil {
 			return err
 		}
-		if _, err := fmt.Fprintf(os.Stdout, "List Jobs:\n%s\n", prettyJobs); err != nil {
+		defer closer()
+
+		jobID, err := api.LilyJobResume(ctx, &lily.LilyJobResumeConfig{
+			WalkID:            walkID,
+			Window:            jobResumeFlags.window,
+			Storage:           jobResumeFlags.storage,
+			MaxLensCalls:      jobLimitsFlags.maxLensCalls,
+			MaxPersistBatches: jobLimitsFlags.maxPersistBatches,
+		})
+		if err != nil {
 			return err
 		}
-		return nil
+		return printJobCreated("Created Walk Job: %d\n", jobID)
 	},
 }
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/labels"
+)
+
+var LabelsCmd = &cli.Command{
+	Name:  "labels",
+	Usage: "Manage labels for known addresses.",
+	Subcommands: []*cli.Command{
+		LabelsImportCmd,
+	},
+}
+
+var LabelsImportCmd = &cli.Command{
+	Name:      "import",
+	Usage:     "Import a list of labelled addresses into the address_tags table.",
+	ArgsUsage: "<file>",
+	Description: `Reads a CSV file with a header row or a JSON array of objects. Each entry has an address and a label and may
+have a category and source. Use - to read from standard input.
+
+Unless --resolve=false is given, addresses that are not ID addresses are resolved to the ID of their actor at the
+head of the lens's chain so labels can be joined with tables keyed by ID address. Labels that are imported again
+without being resolved keep the ID address recorded when they were last resolved.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Format of the file, csv or json. Detected from the file extension if not given.",
+			},
+			&cli.StringFlag{
+				Name:  "source",
+				Usage: "Source recorded for entries that do not name one. Defaults to the name of the file.",
+			},
+			&cli.BoolFlag{
+				Name:  "resolve",
+				Usage: "Resolve addresses to ID addresses using the lens.",
+				Value: true,
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return xerrors.Errorf("expected the path of one file to import")
+		}
+		path := cctx.Args().First()
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		format := cctx.String("format")
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		}
+		source := cctx.String("source")
+		if source == "" {
+			source = filepath.Base(path)
+		}
+
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return xerrors.Errorf("open file: %w", err)
+			}
+			defer f.Close() // nolint: errcheck
+			r = f
+		}
+
+		tags, err := labels.Read(r, format, source)
+		if err != nil {
+			return xerrors.Errorf("read labels: %w", err)
+		}
+
+		if cctx.Bool("resolve") {
+			lensOpener, lensCloser, err := setupLens(cctx)
+			if err != nil {
+				return xerrors.Errorf("setup lens: %w", err)
+			}
+			defer func() {
+				lensCloser()
+			}()
+
+			node, closer, err := lensOpener.Open(cctx.Context)
+			if err != nil {
+				return xerrors.Errorf("open lens: %w", err)
+			}
+			defer closer()
+
+			head, err := node.ChainHead(cctx.Context)
+			if err != nil {
+				return xerrors.Errorf("get chain head: %w", err)
+			}
+			tree, err := state.LoadStateTree(node.Store(), head.ParentState())
+			if err != nil {
+				return xerrors.Errorf("load state tree: %w", err)
+			}
+			unresolved, err := labels.Resolve(tags, tree)
+			if err != nil {
+				return xerrors.Errorf("resolve addresses: %w", err)
+			}
+			if unresolved > 0 {
+				log.Warnw("some addresses have no actor and were not resolved", "count", unresolved, "height", head.Height())
+			}
+		}
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		if err := db.ImportAddressTags(cctx.Context, tags); err != nil {
+			return xerrors.Errorf("import labels: %w", err)
+		}
+		log.Infow("imported labels", "count", len(tags), "source", source)
+		return nil
+	},
+}
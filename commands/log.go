@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"sort"
 
 	lotuscli "github.com/filecoin-project/lotus/cli"
@@ -40,11 +41,12 @@ var LogList = &cli.Command{
 
 		sort.Strings(systems)
 
-		for _, system := range systems {
-			fmt.Println(system)
-		}
-
-		return nil
+		return printOutput(systems, func(w io.Writer) error {
+			for _, system := range systems {
+				fmt.Fprintln(w, system)
+			}
+			return nil
+		})
 	},
 }
 
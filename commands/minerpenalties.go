@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunMinerPenaltiesCmd = newHeightRangeAggregateCmd(aggregateCmd{
+	Name:      "miner-penalties",
+	Usage:     "Maintain the fault, termination and consensus fault penalties charged to each miner per epoch in the miner_penalties table.",
+	Job:       "MinerPenaltyAggregator",
+	EnvPrefix: "VISOR_MINER_PENALTIES",
+	Refreshes: "penalties",
+},
+	"Number of epochs behind the latest extracted internal message to apply penalties up to.",
+	"Number of epochs before the latest penalty applied to apply again on each refresh.",
+	storage.NewMinerPenaltyAggregator)
@@ -0,0 +1,13 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunMinerStatsCmd = newDailyAggregateCmd(aggregateCmd{
+	Name:      "miner-stats",
+	Usage:     "Maintain daily rollups of miner power, sector and reward activity in the miner_daily_stats table.",
+	Job:       "MinerDailyStatsAggregator",
+	EnvPrefix: "VISOR_MINER_STATS",
+	Refreshes: "rollups",
+}, "Height to start aggregating from when no rollups exist.", storage.NewMinerDailyStatsAggregator)
@@ -3,11 +3,12 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"sort"
 	"strings"
 	"text/tabwriter"
 
+	"github.com/filecoin-project/lotus/api"
 	lotuscli "github.com/filecoin-project/lotus/cli"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/urfave/cli/v2"
@@ -45,8 +46,10 @@ var NetID = &cli.Command{
 			return xerrors.Errorf("get id: %w", err)
 		}
 
-		fmt.Println(pid)
-		return nil
+		return printOutput(pid, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, pid)
+			return err
+		})
 	},
 }
 
@@ -69,10 +72,12 @@ var NetListen = &cli.Command{
 			return err
 		}
 
-		for _, peer := range addrs.Addrs {
-			fmt.Printf("%s/p2p/%s\n", peer, addrs.ID)
-		}
-		return nil
+		return printOutput(addrs, func(w io.Writer) error {
+			for _, peer := range addrs.Addrs {
+				fmt.Fprintf(w, "%s/p2p/%s\n", peer, addrs.ID)
+			}
+			return nil
+		})
 	},
 }
 
@@ -114,6 +119,7 @@ var NetPeers = &cli.Command{
 		if cctx.Bool("extended") {
 			// deduplicate
 			seen := make(map[peer.ID]struct{})
+			infos := []*api.ExtendedPeerInfo{}
 
 			for _, peer := range peers {
 				_, dup := seen[peer.ID]
@@ -125,32 +131,51 @@ var NetPeers = &cli.Command{
 				info, err := lapi.NetPeerInfo(ctx, peer.ID)
 				if err != nil {
 					log.Warnf("error getting extended peer info: %s", err)
-				} else {
-					bytes, err := json.Marshal(&info)
+					continue
+				}
+				infos = append(infos, info)
+			}
+
+			return printOutput(infos, func(w io.Writer) error {
+				for _, info := range infos {
+					bytes, err := json.Marshal(info)
 					if err != nil {
 						log.Warnf("error marshalling extended peer info: %s", err)
-					} else {
-						fmt.Println(string(bytes))
+						continue
 					}
+					fmt.Fprintln(w, string(bytes))
 				}
+				return nil
+			})
+		}
+
+		type netPeer struct {
+			ID    peer.ID  `json:"id"`
+			Addrs []string `json:"addrs"`
+			Agent string   `json:"agent,omitempty"`
+		}
+		out := make([]netPeer, 0, len(peers))
+		for _, p := range peers {
+			np := netPeer{ID: p.ID, Addrs: []string{}}
+			for _, addr := range p.Addrs {
+				np.Addrs = append(np.Addrs, addr.String())
 			}
-		} else {
-			w := tabwriter.NewWriter(os.Stdout, 4, 0, 1, ' ', 0)
-			for _, peer := range peers {
-				var agent string
-				if cctx.Bool("agent") {
-					agent, err = lapi.NetAgentVersion(ctx, peer.ID)
-					if err != nil {
-						log.Warnf("getting agent version: %s", err)
-					}
+			if cctx.Bool("agent") {
+				np.Agent, err = lapi.NetAgentVersion(ctx, p.ID)
+				if err != nil {
+					log.Warnf("getting agent version: %s", err)
 				}
-				fmt.Fprintf(w, "%s\t%s\t%s\n", peer.ID, peer.Addrs, agent)
 			}
-			w.Flush()
-
+			out = append(out, np)
 		}
 
-		return nil
+		return printOutput(out, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 4, 0, 1, ' ', 0)
+			for _, np := range out {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", np.ID, np.Addrs, np.Agent)
+			}
+			return tw.Flush()
+		})
 	},
 }
 
@@ -173,11 +198,19 @@ var NetReachability = &cli.Command{
 			return err
 		}
 
-		fmt.Println("AutoNAT status: ", i.Reachability.String())
-		if i.PublicAddr != "" {
-			fmt.Println("Public address: ", i.PublicAddr)
-		}
-		return nil
+		return printOutput(struct {
+			Reachability string `json:"reachability"`
+			PublicAddr   string `json:"publicAddr,omitempty"`
+		}{
+			Reachability: i.Reachability.String(),
+			PublicAddr:   i.PublicAddr,
+		}, func(w io.Writer) error {
+			fmt.Fprintln(w, "AutoNAT status: ", i.Reachability.String())
+			if i.PublicAddr != "" {
+				fmt.Fprintln(w, "Public address: ", i.PublicAddr)
+			}
+			return nil
+		})
 	},
 }
 
@@ -208,21 +241,33 @@ var NetScores = &cli.Command{
 		}
 
 		if cctx.Bool("extended") {
-			enc := json.NewEncoder(os.Stdout)
-			for _, peer := range scores {
-				err := enc.Encode(peer)
-				if err != nil {
-					return err
+			return printOutput(scores, func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				for _, peer := range scores {
+					err := enc.Encode(peer)
+					if err != nil {
+						return err
+					}
 				}
-			}
-		} else {
-			w := tabwriter.NewWriter(os.Stdout, 4, 0, 1, ' ', 0)
-			for _, peer := range scores {
-				fmt.Fprintf(w, "%s\t%f\n", peer.ID, peer.Score.Score)
-			}
-			w.Flush()
+				return nil
+			})
+		}
+
+		type netScore struct {
+			ID    peer.ID `json:"id"`
+			Score float64 `json:"score"`
+		}
+		out := make([]netScore, 0, len(scores))
+		for _, peer := range scores {
+			out = append(out, netScore{ID: peer.ID, Score: peer.Score.Score})
 		}
 
-		return nil
+		return printOutput(out, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 4, 0, 1, ' ', 0)
+			for _, ns := range out {
+				fmt.Fprintf(tw, "%s\t%f\n", ns.ID, ns.Score)
+			}
+			return tw.Flush()
+		})
 	},
 }
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/schedule"
+)
+
+var RunObserveNodeSyncCmd = &cli.Command{
+	Name:  "observe-node-sync",
+	Usage: "Periodically record the sync state of the lotus node in the observed_node_syncs table.",
+	Description: `Each observation records the height of the node's chain head, how many epochs it is behind the expected current
+epoch and the state of each of its sync workers. The records can be used to tell whether gaps in the indexed data were
+caused by the node falling out of sync. Only the lotus lens reports sync state.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "Time between each observation of the sync state.",
+				Value:   time.Minute,
+				EnvVars: []string{"VISOR_OBSERVE_NODE_SYNC_INTERVAL"},
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		if err := setupMetrics(cctx); err != nil {
+			return xerrors.Errorf("setup metrics: %w", err)
+		}
+
+		if cctx.Duration("interval") <= 0 {
+			return xerrors.Errorf("interval must be greater than zero")
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "NodeSyncObserver",
+				Job:                 chain.NewNodeSyncObserver(lensOpener, db, cctx.String("name"), cctx.Duration("interval")),
+				RestartOnFailure:    true,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	lotuscli "github.com/filecoin-project/lotus/cli"
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/sentinel-visor/lens/lily"
+)
+
+type observeBlocksOps struct {
+	storage  string
+	apiAddr  string
+	apiToken string
+	name     string
+}
+
+var observeBlocksFlags observeBlocksOps
+
+var ObserveBlocksCmd = &cli.Command{
+	Name:  "observe-blocks",
+	Usage: "Start a daemon job to record when blocks are first received from the network and which peer sent them.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "storage",
+			Usage:       "Name of storage that results will be written to.",
+			Value:       "",
+			Destination: &observeBlocksFlags.storage,
+		},
+		&cli.StringFlag{
+			Name:        "api",
+			Usage:       "Address of visor api in multiaddr format.",
+			EnvVars:     []string{"VISOR_API"},
+			Value:       "/ip4/127.0.0.1/tcp/1234",
+			Destination: &observeBlocksFlags.apiAddr,
+		},
+		&cli.StringFlag{
+			Name:        "api-token",
+			Usage:       "Authentication token for visor api.",
+			EnvVars:     []string{"VISOR_API_TOKEN"},
+			Value:       "",
+			Destination: &observeBlocksFlags.apiToken,
+		},
+		&cli.StringFlag{
+			Name:        "name",
+			Usage:       "Name of job for easy identification later. Recorded as the observer of each block.",
+			Value:       "",
+			Destination: &observeBlocksFlags.name,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lotuscli.ReqContext(cctx)
+
+		name := fmt.Sprintf("observe_blocks_%d", time.Now().Unix())
+		if observeBlocksFlags.name != "" {
+			name = observeBlocksFlags.name
+		}
+
+		cfg := &lily.LilyObserveBlocksConfig{
+			Name:                name,
+			RestartDelay:        time.Minute,
+			RestartOnCompletion: false,
+			RestartOnFailure:    true,
+			Storage:             observeBlocksFlags.storage,
+		}
+
+		api, closer, err := GetAPI(ctx, observeBlocksFlags.apiAddr, observeBlocksFlags.apiToken)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		jobID, err := api.LilyObserveBlocks(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		return printJobCreated("Created Observe Blocks Job: %d", jobID)
+	},
+}
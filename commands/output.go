@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/schedule"
+)
+
+// Formats in which commands write their results, selected with the global --output flag.
+const (
+	OutputText = "text" // human readable output, which may change between releases
+	OutputJSON = "json" // a single JSON document per command, for use in scripts
+)
+
+// ValidateOutput checks the output format selected with the global --output flag. It is run before any command.
+func ValidateOutput(cctx *cli.Context) error {
+	switch VisorCmdFlags.Output {
+	case OutputText, OutputJSON:
+		return nil
+	default:
+		return xerrors.Errorf("unknown output format %q, must be %s or %s", VisorCmdFlags.Output, OutputText, OutputJSON)
+	}
+}
+
+// printOutput writes the result of a command to stdout. v is written as indented JSON when JSON output is selected,
+// otherwise text is called to write the human readable form.
+func printOutput(v interface{}, text func(w io.Writer) error) error {
+	if VisorCmdFlags.Output == OutputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	}
+	return text(os.Stdout)
+}
+
+// printJobCreated writes the id of a job started in the daemon, using format for the human readable form.
+func printJobCreated(format string, id schedule.JobID) error {
+	return printOutput(struct {
+		ID schedule.JobID `json:"id"`
+	}{ID: id}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, format, id)
+		return err
+	})
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/plugin"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var pluginFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:    "plugin",
+		EnvVars: []string{"VISOR_PLUGINS"},
+		Usage:   "Run the extractor plugin serving gRPC at `ADDRESS` for each tipset. May be repeated.",
+	},
+	&cli.StringFlag{
+		Name:    "plugin-lens-listen",
+		EnvVars: []string{"VISOR_PLUGIN_LENS_LISTEN"},
+		Value:   "127.0.0.1:0",
+		Usage:   "Address on which to serve the lens to plugins. Port 0 chooses a free port.",
+	},
+}
+
+// setupPlugins connects to the configured plugins and returns indexer options that run them as tasks. Missing plugin
+// tables are created if schema migration is allowed. The returned closer stops the plugins' lens and closes their
+// connections.
+func setupPlugins(cctx *cli.Context, opener lens.APIOpener, db *storage.Database, tasks []string) ([]chain.TipSetIndexerOpt, func(), error) {
+	addrs := cctx.StringSlice("plugin")
+	if len(addrs) == 0 {
+		return nil, func() {}, nil
+	}
+
+	lensService := plugin.NewLensService(opener)
+	var plugins []*plugin.Plugin
+	closer := func() {
+		for _, p := range plugins {
+			if err := p.Close(); err != nil {
+				log.Warnw("failed to close plugin", "plugin", p.Name, "error", err)
+			}
+		}
+		lensService.Close()
+	}
+
+	lensAddr, err := lensService.Listen(cctx.String("plugin-lens-listen"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("serve plugin lens: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, t := range tasks {
+		names[t] = true
+	}
+
+	var opts []chain.TipSetIndexerOpt
+	for _, addr := range addrs {
+		p, err := plugin.Connect(cctx.Context, addr)
+		if err != nil {
+			closer()
+			return nil, nil, xerrors.Errorf("connect to plugin at %s: %w", addr, err)
+		}
+		plugins = append(plugins, p)
+
+		if names[p.Name] {
+			closer()
+			return nil, nil, xerrors.Errorf("plugin at %s has name %q which is already used by another task", addr, p.Name)
+		}
+		names[p.Name] = true
+
+		if db != nil {
+			if err := p.EnsureSchema(cctx.Context, db, cctx.Bool("allow-schema-migration")); err != nil {
+				closer()
+				return nil, nil, xerrors.Errorf("plugin %s schema: %w", p.Name, err)
+			}
+		}
+
+		log.Infow("using plugin", "name", p.Name, "version", p.Version, "address", addr)
+		opts = append(opts, chain.TipSetProcessorOpt(p.Name, p.Task(lensAddr)))
+	}
+
+	return opts, closer, nil
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/metrics/remotewrite"
+)
+
+var remoteWriteFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "remote-write-url",
+		EnvVars: []string{"VISOR_REMOTE_WRITE_URL"},
+		Usage:   "Push metrics derived from indexed tipsets, such as base fee and total power, to the Prometheus remote-write endpoint at `URL`.",
+	},
+	&cli.StringSliceFlag{
+		Name:    "remote-write-header",
+		EnvVars: []string{"VISOR_REMOTE_WRITE_HEADERS"},
+		Usage:   "Add a `NAME:VALUE` header to remote-write requests, such as an Authorization header. May be repeated.",
+	},
+	&cli.StringSliceFlag{
+		Name:    "remote-write-label",
+		EnvVars: []string{"VISOR_REMOTE_WRITE_LABELS"},
+		Usage:   "Add a `NAME=VALUE` label to every pushed series. May be repeated.",
+	},
+	&cli.DurationFlag{
+		Name:    "remote-write-timeout",
+		EnvVars: []string{"VISOR_REMOTE_WRITE_TIMEOUT"},
+		Value:   30 * time.Second,
+		Usage:   "Timeout for remote-write requests.",
+	},
+}
+
+// setupRemoteWrite returns an exporter that pushes chain metrics to a remote-write endpoint, or nil if no endpoint
+// was configured.
+func setupRemoteWrite(cctx *cli.Context) (*remotewrite.Exporter, error) {
+	url := cctx.String("remote-write-url")
+	if url == "" {
+		return nil, nil
+	}
+
+	headers := http.Header{}
+	for _, h := range cctx.StringSlice("remote-write-header") {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			return nil, xerrors.Errorf("invalid header %q, expected NAME:VALUE", h)
+		}
+		headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	var labels []remotewrite.Label
+	for _, l := range cctx.StringSlice("remote-write-label") {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, xerrors.Errorf("invalid label %q, expected NAME=VALUE", l)
+		}
+		labels = append(labels, remotewrite.Label{Name: parts[0], Value: parts[1]})
+	}
+
+	client := remotewrite.NewClient(url, headers, cctx.Duration("remote-write-timeout"))
+	return remotewrite.NewExporter(client, labels), nil
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+	"github.com/filecoin-project/sentinel-visor/schedule"
+)
+
+var RunObserveRetrievalsCmd = &cli.Command{
+	Name:  "observe-retrievals",
+	Usage: "Periodically query a sample of miners for retrieval offers and record their responses in the observed_retrieval_events table.",
+	Description: `Each round chooses active deals whose label is a payload CID, one from each of a random sample of providers, and
+asks the provider for a retrieval offer using the markets client of the lens. Only the lotus lens supports retrieval
+queries, and the lotus node must be able to reach miners over libp2p. No retrievals are made and no funds are spent.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.IntFlag{
+				Name:    "sample",
+				Usage:   "Number of miners to query in each round.",
+				Value:   50,
+				EnvVars: []string{"VISOR_OBSERVE_RETRIEVALS_SAMPLE"},
+			},
+			&cli.DurationFlag{
+				Name:    "interval",
+				Usage:   "Time between the start of each round of queries.",
+				Value:   time.Hour,
+				EnvVars: []string{"VISOR_OBSERVE_RETRIEVALS_INTERVAL"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Usage:   "Time allowed for each query before the miner is recorded as unreachable.",
+				Value:   30 * time.Second,
+				EnvVars: []string{"VISOR_OBSERVE_RETRIEVALS_TIMEOUT"},
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		if err := setupMetrics(cctx); err != nil {
+			return xerrors.Errorf("setup metrics: %w", err)
+		}
+
+		if cctx.Int("sample") <= 0 {
+			return xerrors.Errorf("sample must be greater than zero")
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "RetrievalObserver",
+				Job:                 chain.NewRetrievalObserver(lensOpener, db, db, cctx.String("name"), cctx.Int("sample"), cctx.Duration("interval"), cctx.Duration("timeout")),
+				RestartOnFailure:    true,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
@@ -25,6 +26,19 @@ var RunCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		RunWatchCmd,
 		RunWalkCmd,
+		RunArchiveCmd,
+		RunDatasetArchiveCmd,
+		RunMinerStatsCmd,
+		RunDealEdgesCmd,
+		RunWinStatsCmd,
+		RunSectorLifetimesCmd,
+		RunDatacapUsageCmd,
+		RunDealTimelinesCmd,
+		RunMinerPenaltiesCmd,
+		RunActorBalanceChangesCmd,
+		RunObserveRetrievalsCmd,
+		RunObserveNodeSyncCmd,
+		RunObserveFeeMarketCmd,
 	},
 }
 
@@ -57,8 +71,44 @@ var dbConnectFlags = []cli.Flag{
 var dbBehaviourFlags = []cli.Flag{
 	&cli.BoolFlag{
 		Name:    "db-allow-upsert",
+		Aliases: []string{"overwrite"},
 		EnvVars: []string{"LOTUS_DB_ALLOW_UPSERT"},
 		Value:   false,
+		Usage:   "Replace rows that already exist in the database instead of keeping them.",
+	},
+	&cli.BoolFlag{
+		Name:    "db-notify",
+		EnvVars: []string{"VISOR_DB_NOTIFY"},
+		Value:   false,
+		Usage:   "Send a postgresql notification on the channel visor_<table> whenever data persisted to a table is committed.",
+	},
+	&cli.StringFlag{
+		Name:    "db-isolation-level",
+		EnvVars: []string{"VISOR_DB_ISOLATION_LEVEL"},
+		Value:   "",
+		Usage:   "Isolation level of transactions that persist data: read-committed, repeatable-read or serializable. Defaults to the server's default.",
+	},
+	&cli.IntFlag{
+		Name:    "db-tx-retries",
+		EnvVars: []string{"VISOR_DB_TX_RETRIES"},
+		Value:   3,
+		Usage:   "Number of times a transaction that persists data is retried after a serialization failure or deadlock caused by a concurrent transaction.",
+	},
+	&cli.DurationFlag{
+		Name:    "db-tx-retry-delay",
+		EnvVars: []string{"VISOR_DB_TX_RETRY_DELAY"},
+		Value:   100 * time.Millisecond,
+		Usage:   "Delay before the first retry of a transaction, doubled for each further retry.",
+	},
+	&cli.StringSliceFlag{
+		Name:    "db-redact",
+		EnvVars: []string{"VISOR_DB_REDACT"},
+		Usage:   "Column to omit or hash when persisting data, written as table.column or table.column:hash, for example parsed_messages.params:hash. May be repeated.",
+	},
+	&cli.StringSliceFlag{
+		Name:    "db-conflict-key",
+		EnvVars: []string{"VISOR_DB_CONFLICT_KEY"},
+		Usage:   "Columns identifying the rows of a table whose primary key has been changed from visor's, used when upserting, written as table=column,column, for example messages=cid. May be repeated.",
 	},
 	&cli.BoolFlag{
 		Name:    "allow-schema-migration",
@@ -67,6 +117,48 @@ var dbBehaviourFlags = []cli.Flag{
 	},
 }
 
+var dbInitFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:    "db-auto-init",
+		EnvVars: []string{"VISOR_DB_AUTO_INIT"},
+		Value:   false,
+		Usage:   "Create the postgresql schema and extensions needed by visor and install the latest visor schema if the database has none.",
+	},
+	&cli.StringFlag{
+		Name:    "db-admin",
+		EnvVars: []string{"VISOR_DB_ADMIN"},
+		Value:   "",
+		Usage:   "A connection string for a role that may create databases, roles and extensions, used by --db-auto-init. Defaults to the connection given by --db.",
+	},
+	&cli.BoolFlag{
+		Name:    "db-auto-init-create-database",
+		EnvVars: []string{"VISOR_DB_AUTO_INIT_CREATE_DATABASE"},
+		Value:   false,
+		Usage:   "Confirm that --db-auto-init may create the database named by --db if it does not exist.",
+	},
+	&cli.BoolFlag{
+		Name:    "db-auto-init-create-roles",
+		EnvVars: []string{"VISOR_DB_AUTO_INIT_CREATE_ROLES"},
+		Value:   false,
+		Usage:   "Confirm that --db-auto-init may create the role named by --db, and any reader role, if they do not exist.",
+	},
+	&cli.StringFlag{
+		Name:    "db-auto-init-reader-role",
+		EnvVars: []string{"VISOR_DB_AUTO_INIT_READER_ROLE"},
+		Value:   "",
+		Usage:   "Name of a `ROLE` that --db-auto-init grants read access to all visor tables.",
+	},
+}
+
+var dbReadFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "db-read",
+		EnvVars: []string{"VISOR_DB_READ"},
+		Value:   "",
+		Usage:   "A connection string for a read replica of the postgres database. When set, read only queries are sent to the replica instead of the database given by --db.",
+	},
+}
+
 var runLensFlags = []cli.Flag{
 	&cli.StringFlag{
 		Name:    "lens",
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/schemas"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var SchemaCmd = &cli.Command{
+	Name:  "schema",
+	Usage: "Inspect the database schema used by visor.",
+	Subcommands: []*cli.Command{
+		SchemaDescribeCmd,
+	},
+}
+
+var SchemaDescribeCmd = &cli.Command{
+	Name:  "describe",
+	Usage: "Print the tables, columns, comments and indexes of a schema version without connecting to a database.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "version",
+			Usage: "Describe the schema `VERSION`. Defaults to the latest version.",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format, one of sql or json. SQL output is only available for major version 1. Defaults to json when the global --output is json.",
+			Value: "sql",
+		},
+		&cli.StringFlag{
+			Name:  "schema",
+			Usage: "The name of the postgresql schema used in SQL output.",
+			Value: "public",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		version := storage.LatestSchemaVersion()
+		if cctx.IsSet("version") {
+			var err error
+			version, err = model.ParseVersion(cctx.String("version"))
+			if err != nil {
+				return xerrors.Errorf("invalid schema version: %w", err)
+			}
+		}
+
+		// JSON output describes the schema as JSON unless another format is asked for
+		format := cctx.String("format")
+		if !cctx.IsSet("format") && VisorCmdFlags.Output == OutputJSON {
+			format = "json"
+		}
+
+		switch format {
+		case "sql":
+			ddl, err := storage.SchemaSQL(version, schemas.Config{SchemaName: cctx.String("schema")})
+			if err != nil {
+				return xerrors.Errorf("schema sql: %w", err)
+			}
+			return printOutput(struct {
+				Version string `json:"version"`
+				SQL     string `json:"sql"`
+			}{
+				Version: version.String(),
+				SQL:     ddl,
+			}, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, ddl)
+				return err
+			})
+		case "json":
+			desc, err := storage.DescribeSchema(version)
+			if err != nil {
+				return xerrors.Errorf("describe schema: %w", err)
+			}
+			return printOutput(desc, func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(desc); err != nil {
+					return xerrors.Errorf("encode schema: %w", err)
+				}
+				return nil
+			})
+		default:
+			return xerrors.Errorf("unsupported format: %q", format)
+		}
+	},
+}
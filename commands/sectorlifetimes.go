@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunSectorLifetimesCmd = newHeightRangeAggregateCmd(aggregateCmd{
+	Name:      "sector-lifetimes",
+	Usage:     "Maintain the lifetime of each sector from activation to expiration or termination in the miner_sector_lifetimes table.",
+	Job:       "SectorLifetimeAggregator",
+	EnvPrefix: "VISOR_SECTOR_LIFETIMES",
+	Refreshes: "lifetimes",
+},
+	"Number of epochs behind the latest extracted sector event to apply events up to.",
+	"Number of epochs before the latest event applied to apply again on each refresh.",
+	storage.NewSectorLifetimeAggregator)
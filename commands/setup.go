@@ -41,6 +41,33 @@ func setupDatabase(cctx *cli.Context) (*storage.Database, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("new database: %w", err)
 	}
+	db.Notify = cctx.Bool("db-notify")
+	db.Isolation, err = storage.ParseIsolationLevel(cctx.String("db-isolation-level"))
+	if err != nil {
+		return nil, xerrors.Errorf("db isolation level: %w", err)
+	}
+	db.TxRetries = cctx.Int("db-tx-retries")
+	db.TxRetryDelay = cctx.Duration("db-tx-retry-delay")
+	db.Redactor, err = storage.ParseRedactions(cctx.StringSlice("db-redact"))
+	if err != nil {
+		return nil, xerrors.Errorf("db redact: %w", err)
+	}
+	conflictKeys, err := storage.ParseConflictKeys(cctx.StringSlice("db-conflict-key"))
+	if err != nil {
+		return nil, xerrors.Errorf("db conflict key: %w", err)
+	}
+	db.SetConflictKeys(conflictKeys)
+
+	if cctx.Bool("db-auto-init") {
+		if err := db.Bootstrap(ctx, storage.BootstrapConfig{
+			AdminURL:       cctx.String("db-admin"),
+			CreateDatabase: cctx.Bool("db-auto-init-create-database"),
+			CreateRoles:    cctx.Bool("db-auto-init-create-roles"),
+			ReaderRole:     cctx.String("db-auto-init-reader-role"),
+		}); err != nil {
+			return nil, xerrors.Errorf("initialize database: %w", err)
+		}
+	}
 
 	if err := db.Connect(ctx); err != nil {
 		if !errors.Is(err, storage.ErrSchemaTooOld) || !cctx.Bool("allow-schema-migration") {
@@ -68,9 +95,34 @@ func setupDatabase(cctx *cli.Context) (*storage.Database, error) {
 		return nil, xerrors.Errorf("verify schema: %w", err)
 	}
 
+	if err := setupReadReplica(cctx, db); err != nil {
+		db.Close(ctx) // nolint: errcheck
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// setupReadReplica connects to the read replica named by the db-read flag, if any, and routes the read only queries
+// made against db to it.
+func setupReadReplica(cctx *cli.Context, db *storage.Database) error {
+	if cctx.String("db-read") == "" {
+		return nil
+	}
+
+	ctx := cctx.Context
+	replica, err := storage.NewReadReplica(ctx, cctx.String("db-read"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"))
+	if err != nil {
+		return xerrors.Errorf("new read replica: %w", err)
+	}
+	if err := replica.Connect(ctx); err != nil {
+		return xerrors.Errorf("connect read replica: %w", err)
+	}
+
+	db.SetReader(replica)
+	return nil
+}
+
 func setupLens(cctx *cli.Context) (lens.APIOpener, lens.APICloser, error) {
 	switch cctx.String("lens") {
 	case "lotus":
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/filecoin-project/go-state-types/abi"
@@ -43,40 +44,42 @@ var SyncStatusCmd = &cli.Command{
 			return err
 		}
 
-		fmt.Println("sync status:")
-		for _, ss := range state.ActiveSyncs {
-			fmt.Printf("worker %d:\n", ss.WorkerID)
-			var base, target []cid.Cid
-			var heightDiff int64
-			var theight abi.ChainEpoch
-			if ss.Base != nil {
-				base = ss.Base.Cids()
-				heightDiff = int64(ss.Base.Height())
-			}
-			if ss.Target != nil {
-				target = ss.Target.Cids()
-				heightDiff = int64(ss.Target.Height()) - heightDiff
-				theight = ss.Target.Height()
-			} else {
-				heightDiff = 0
-			}
-			fmt.Printf("\tBase:\t%s\n", base)
-			fmt.Printf("\tTarget:\t%s (%d)\n", target, theight)
-			fmt.Printf("\tHeight diff:\t%d\n", heightDiff)
-			fmt.Printf("\tStage: %s\n", ss.Stage)
-			fmt.Printf("\tHeight: %d\n", ss.Height)
-			if ss.End.IsZero() {
-				if !ss.Start.IsZero() {
-					fmt.Printf("\tElapsed: %s\n", time.Since(ss.Start))
+		return printOutput(state, func(w io.Writer) error {
+			fmt.Fprintln(w, "sync status:")
+			for _, ss := range state.ActiveSyncs {
+				fmt.Fprintf(w, "worker %d:\n", ss.WorkerID)
+				var base, target []cid.Cid
+				var heightDiff int64
+				var theight abi.ChainEpoch
+				if ss.Base != nil {
+					base = ss.Base.Cids()
+					heightDiff = int64(ss.Base.Height())
+				}
+				if ss.Target != nil {
+					target = ss.Target.Cids()
+					heightDiff = int64(ss.Target.Height()) - heightDiff
+					theight = ss.Target.Height()
+				} else {
+					heightDiff = 0
+				}
+				fmt.Fprintf(w, "\tBase:\t%s\n", base)
+				fmt.Fprintf(w, "\tTarget:\t%s (%d)\n", target, theight)
+				fmt.Fprintf(w, "\tHeight diff:\t%d\n", heightDiff)
+				fmt.Fprintf(w, "\tStage: %s\n", ss.Stage)
+				fmt.Fprintf(w, "\tHeight: %d\n", ss.Height)
+				if ss.End.IsZero() {
+					if !ss.Start.IsZero() {
+						fmt.Fprintf(w, "\tElapsed: %s\n", time.Since(ss.Start))
+					}
+				} else {
+					fmt.Fprintf(w, "\tElapsed: %s\n", ss.End.Sub(ss.Start))
+				}
+				if ss.Stage == api.StageSyncErrored {
+					fmt.Fprintf(w, "\tError: %s\n", ss.Message)
 				}
-			} else {
-				fmt.Printf("\tElapsed: %s\n", ss.End.Sub(ss.Start))
-			}
-			if ss.Stage == api.StageSyncErrored {
-				fmt.Printf("\tError: %s\n", ss.Message)
 			}
-		}
-		return nil
+			return nil
+		})
 	},
 }
 
@@ -104,8 +107,21 @@ var SyncWaitCmd = &cli.Command{
 	},
 }
 
+// SyncWait waits until the chain is synced, or until the context is done when watch is true, showing the progress of
+// the sync. JSON output replaces the progress with a single document describing the sync when waiting ends.
 func SyncWait(ctx context.Context, lapi lily.LilyAPI, watch bool) error {
 	tick := time.Second / 4
+	progress := VisorCmdFlags.Output != OutputJSON
+
+	type syncWaitResult struct {
+		Synced  bool           `json:"synced"`
+		Worker  uint64         `json:"worker"`
+		Stage   string         `json:"stage"`
+		Height  abi.ChainEpoch `json:"height"`
+		Target  abi.ChainEpoch `json:"target"`
+		Applied uint64         `json:"applied"` // messages validated while waiting
+	}
+	var res syncWaitResult
 
 	lastLines := 0
 	ticker := time.NewTicker(tick)
@@ -172,19 +188,29 @@ func SyncWait(ctx context.Context, lapi lily.LilyAPI, watch bool) error {
 			heightDiff = 0
 		}
 
-		for i := 0; i < lastLines; i++ {
-			fmt.Print("\r\x1b[2K\x1b[A")
+		res = syncWaitResult{
+			Worker:  workerID,
+			Stage:   ss.Stage.String(),
+			Height:  ss.Height,
+			Target:  theight,
+			Applied: state.VMApplied - firstApp,
 		}
 
-		fmt.Printf("Worker: %d; Base: %d; Target: %d (diff: %d)\n", workerID, baseHeight, theight, heightDiff)
-		fmt.Printf("State: %s; Current Epoch: %d; Todo: %d\n", ss.Stage, ss.Height, theight-ss.Height)
-		lastLines = 2
+		if progress {
+			for i := 0; i < lastLines; i++ {
+				fmt.Print("\r\x1b[2K\x1b[A")
+			}
+
+			fmt.Printf("Worker: %d; Base: %d; Target: %d (diff: %d)\n", workerID, baseHeight, theight, heightDiff)
+			fmt.Printf("State: %s; Current Epoch: %d; Todo: %d\n", ss.Stage, ss.Height, theight-ss.Height)
+			lastLines = 2
+		}
 
 		if i%samples == 0 {
 			lastApp = app
 			app = state.VMApplied - firstApp
 		}
-		if i > 0 {
+		if i > 0 && progress {
 			fmt.Printf("Validated %d messages (%d per second)\n", state.VMApplied-firstApp, (app-lastApp)*uint64(time.Second/tick)/uint64(samples))
 			lastLines++
 		}
@@ -192,14 +218,19 @@ func SyncWait(ctx context.Context, lapi lily.LilyAPI, watch bool) error {
 		_ = target // todo: maybe print? (creates a bunch of line wrapping issues with most tipsets)
 
 		if !watch && time.Now().Unix()-int64(head.MinTimestamp()) < int64(builtin.EpochDurationSeconds) {
-			fmt.Println("\nDone!")
-			return nil
+			res.Synced = true
+			return printOutput(res, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, "\nDone!")
+				return err
+			})
 		}
 
 		select {
 		case <-ctx.Done():
-			fmt.Println("\nExit by user")
-			return nil
+			return printOutput(res, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, "\nExit by user")
+				return err
+			})
 		case <-ticker.C:
 		}
 
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var TenantCmd = &cli.Command{
+	Name:  "tenant",
+	Usage: "Manage tenant schemas that give read only access to visor data.",
+	Subcommands: []*cli.Command{
+		TenantAddCmd,
+		TenantListCmd,
+	},
+}
+
+var TenantAddCmd = &cli.Command{
+	Name:      "add",
+	Usage:     "Provision a tenant schema and reader role, or refresh the views of an existing tenant.",
+	ArgsUsage: "<name>",
+	Description: `Creates a postgresql schema named after the tenant holding a read only view of each data table in the visor
+schema, and grants a reader role access to it. visor's bookkeeping tables, whose names start with visor_, are not
+exposed. The views of every tenant are recreated after each schema migration.`,
+	Flags: flagSet(
+		dbConnectFlags,
+		[]cli.Flag{
+			&cli.StringFlag{
+				Name:  "reader-role",
+				Usage: "Name of the `ROLE` granted read access to the tenant's schema. Defaults to the tenant name followed by _reader.",
+			},
+			&cli.BoolFlag{
+				Name:  "create-role",
+				Usage: "Create the reader role if it does not exist.",
+				Value: false,
+			},
+			&cli.StringFlag{
+				Name:    "db-admin",
+				EnvVars: []string{"VISOR_DB_ADMIN"},
+				Usage:   "A connection string for a role that may create roles, used by --create-role. Defaults to the connection given by --db.",
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return xerrors.Errorf("expected the name of one tenant")
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := storage.NewDatabase(ctx, cctx.String("db"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"), false)
+		if err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		if err := db.Connect(ctx); err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		return db.ProvisionTenant(ctx, storage.TenantConfig{
+			Name:       cctx.Args().First(),
+			ReaderRole: cctx.String("reader-role"),
+			CreateRole: cctx.Bool("create-role"),
+			AdminURL:   cctx.String("db-admin"),
+		})
+	},
+}
+
+var TenantListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List the provisioned tenants.",
+	Flags: dbConnectFlags,
+	Action: func(cctx *cli.Context) error {
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := storage.NewDatabase(ctx, cctx.String("db"), cctx.Int("db-pool-size"), cctx.String("name"), cctx.String("schema"), false)
+		if err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		if err := db.Connect(ctx); err != nil {
+			return xerrors.Errorf("connect database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		tenants, err := db.Tenants(ctx)
+		if err != nil {
+			return xerrors.Errorf("list tenants: %w", err)
+		}
+
+		return printOutput(tenants, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 2, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "NAME\tREADER ROLE\tCREATED\n")
+			for _, t := range tenants {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.ReaderRole, t.CreatedAt.Format(time.RFC3339))
+			}
+			return tw.Flush()
+		})
+	},
+}
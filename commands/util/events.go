@@ -0,0 +1,59 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/filecoin-project/sentinel-visor/chain"
+)
+
+// keepAliveInterval is the time between comments sent to keep idle event streams open through proxies.
+const keepAliveInterval = 30 * time.Second
+
+// IndexedTipSetsHandler returns an http.Handler that streams an event each time an indexer in the process finishes
+// persisting a tipset, using server-sent events. Each event has the type "tipset" and a JSON encoded
+// chain.IndexedTipSet as its data. Events are only sent while the client is connected.
+func IndexedTipSetsHandler(n *chain.IndexNotifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := n.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case ev := <-events:
+				data, err := json.Marshal(ev)
+				if err != nil {
+					log.Errorw("failed to marshal indexed tipset event", "error", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: tipset\nid: %d\ndata: %s\n\n", ev.Height, data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
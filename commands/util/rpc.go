@@ -82,7 +82,24 @@ func PermissionedSentinelAPI(a lily.LilyAPI) lily.LilyAPI {
 	return &out
 }
 
-func ServeRPC(a lily.LilyAPI, stop node.StopFunc, addr multiaddr.Multiaddr, shutdownCh <-chan struct{}, maxRequestSize int64) error {
+// RequirePermission returns a handler that passes requests to next only if they carry an API token granting perm.
+// Other requests are refused with 401 Unauthorized.
+func RequirePermission(a lily.LilyAPI, perm auth.Permission, next http.Handler) http.Handler {
+	return &auth.Handler{
+		Verify: a.AuthVerify,
+		Next: func(w http.ResponseWriter, r *http.Request) {
+			if !auth.HasPerm(r.Context(), nil, perm) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		},
+	}
+}
+
+// ServeRPC serves the API at /rpc/v0 on addr, together with any handlers already registered with mux, until a
+// shutdown is requested.
+func ServeRPC(a lily.LilyAPI, stop node.StopFunc, addr multiaddr.Multiaddr, shutdownCh <-chan struct{}, maxRequestSize int64, mux *http.ServeMux) error {
 	serverOptions := make([]jsonrpc.ServerOption, 0)
 	if maxRequestSize != 0 { // config set
 		serverOptions = append(serverOptions, jsonrpc.WithMaxRequestSize(maxRequestSize))
@@ -95,14 +112,14 @@ func ServeRPC(a lily.LilyAPI, stop node.StopFunc, addr multiaddr.Multiaddr, shut
 		Next:   rpcServer.ServeHTTP,
 	}
 
-	http.Handle("/rpc/v0", ah)
+	mux.Handle("/rpc/v0", ah)
 	lst, err := manet.Listen(addr)
 	if err != nil {
 		return xerrors.Errorf("could not listen: %w", err)
 	}
 
 	srv := &http.Server{
-		Handler: http.DefaultServeMux,
+		Handler: mux,
 		BaseContext: func(listener net.Listener) context.Context {
 			ctx, _ := tag.New(context.Background(), tag.Upsert(metrics.APIInterface, "lotus-daemon"))
 			return ctx
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/validation"
+)
+
+var VerifyCmd = &cli.Command{
+	Name:  "verify",
+	Usage: "Verify the data held in the database.",
+	Subcommands: []*cli.Command{
+		VerifyRulesCmd,
+		VerifyReconcileCmd,
+	},
+}
+
+var VerifyRulesCmd = &cli.Command{
+	Name:  "rules",
+	Usage: "Check data in a range of heights against validation rules and record violations in the visor_validation_failures table.",
+	Flags: flagSet(
+		dbConnectFlags,
+		dbReadFlags,
+		[]cli.Flag{
+			&cli.Int64Flag{
+				Name:  "from",
+				Usage: "Check data at or above `HEIGHT`",
+			},
+			&cli.Int64Flag{
+				Name:        "to",
+				Usage:       "Check data at or below `HEIGHT`",
+				Value:       estimateCurrentEpoch(),
+				DefaultText: "current epoch",
+			},
+			&cli.StringFlag{
+				Name:  "rules",
+				Usage: "Comma separated list of rules to check. All rules are checked if none are given.",
+			},
+			&cli.BoolFlag{
+				Name:  "list",
+				Usage: "List the available rules and exit.",
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		if cctx.Bool("list") {
+			return printOutput(validation.BuiltinRules, func(w io.Writer) error {
+				tw := tabwriter.NewWriter(w, 2, 4, 2, ' ', 0)
+				for _, r := range validation.BuiltinRules {
+					fmt.Fprintf(tw, "%s\t%s\n", r.Name, r.Description)
+				}
+				return tw.Flush()
+			})
+		}
+
+		heightFrom := cctx.Int64("from")
+		heightTo := cctx.Int64("to")
+		if heightFrom > heightTo {
+			return xerrors.Errorf("--from must not be greater than --to")
+		}
+
+		var names []string
+		if cctx.String("rules") != "" {
+			names = strings.Split(cctx.String("rules"), ",")
+		}
+		rules, err := validation.RulesByName(names)
+		if err != nil {
+			return err
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		ctx := cctx.Context
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(ctx) // nolint: errcheck
+
+		violations, err := validation.NewValidator(db, cctx.String("name"), rules).Run(ctx, heightFrom, heightTo)
+		if err != nil {
+			return xerrors.Errorf("verify rules: %w", err)
+		}
+
+		if violations > 0 {
+			return xerrors.Errorf("found %d rule violations between heights %d and %d", violations, heightFrom, heightTo)
+		}
+		log.Infof("no rule violations found between heights %d and %d", heightFrom, heightTo)
+		return nil
+	},
+}
+
+var VerifyReconcileCmd = &cli.Command{
+	Name:  "reconcile",
+	Usage: "Compare per-epoch counts of blocks, messages and receipts in the database with counts computed from the lens and record any gaps or mismatches in the visor_validation_failures table.",
+	Flags: flagSet(
+		dbConnectFlags,
+		dbReadFlags,
+		runLensFlags,
+		[]cli.Flag{
+			&cli.Int64Flag{
+				Name:  "from",
+				Usage: "Reconcile tipsets at or above `HEIGHT`",
+			},
+			&cli.Int64Flag{
+				Name:        "to",
+				Usage:       "Reconcile tipsets at or below `HEIGHT`",
+				Value:       estimateCurrentEpoch(),
+				DefaultText: "current epoch",
+			},
+		},
+	),
+	Action: func(cctx *cli.Context) error {
+		heightFrom := cctx.Int64("from")
+		heightTo := cctx.Int64("to")
+		if heightFrom > heightTo {
+			return xerrors.Errorf("--from must not be greater than --to")
+		}
+
+		if err := setupLogging(cctx); err != nil {
+			return xerrors.Errorf("setup logging: %w", err)
+		}
+
+		lensOpener, lensCloser, err := setupLens(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup lens: %w", err)
+		}
+		defer func() {
+			lensCloser()
+		}()
+
+		db, err := setupDatabase(cctx)
+		if err != nil {
+			return xerrors.Errorf("setup database: %w", err)
+		}
+		defer db.Close(cctx.Context) // nolint: errcheck
+
+		scheduler := schedule.NewScheduler(0,
+			&schedule.JobConfig{
+				Name:                "Reconciler",
+				Job:                 validation.NewReconciler(db, lensOpener, cctx.String("name"), heightFrom, heightTo),
+				RestartOnFailure:    false,
+				RestartOnCompletion: false,
+				RestartDelay:        time.Minute,
+			})
+
+		err = scheduler.Run(cctx.Context)
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	},
+}
@@ -26,6 +26,8 @@ type VisorCmdOpts struct {
 	JaegerSamplerParam float64
 
 	PrometheusPort string
+
+	Output string
 }
 
 var VisorCmdFlags VisorCmdOpts
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -21,14 +20,19 @@ import (
 )
 
 type walkOps struct {
-	from     int64
-	to       int64
-	tasks    string
-	window   time.Duration
-	storage  string
-	apiAddr  string
-	apiToken string
-	name     string
+	from       int64
+	to         int64
+	tasks      string
+	window     time.Duration
+	storage    string
+	apiAddr    string
+	apiToken   string
+	name       string
+	strict     bool
+	direction  string
+	overwrite  bool
+	actorTypes string
+	deferIdx   bool
 }
 
 var walkFlags walkOps
@@ -36,10 +40,10 @@ var walkFlags walkOps
 var WalkCmd = &cli.Command{
 	Name:  "walk",
 	Usage: "Start a daemon job to walk a range of the filecoin blockchain.",
-	Flags: []cli.Flag{
+	Flags: flagSet(jobLimitsFlagSet, []cli.Flag{
 		&cli.StringFlag{
 			Name:        "tasks",
-			Usage:       "Comma separated list of tasks to run. Each task is reported separately in the database.",
+			Usage:       "Comma separated list of tasks to run. Each task is reported separately in the database. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
 			Value:       strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
 			Destination: &walkFlags.tasks,
 		},
@@ -87,7 +91,34 @@ var WalkCmd = &cli.Command{
 			Value:       "",
 			Destination: &walkFlags.name,
 		},
-	},
+		&cli.BoolFlag{
+			Name:        "strict",
+			Usage:       "Abort the walk on the first extraction or persistence error instead of continuing.",
+			Value:       false,
+			Destination: &walkFlags.strict,
+		},
+		&cli.StringFlag{
+			Name:        "direction",
+			Usage:       "Direction to walk the chain, either descending to index the most recent tipsets first or ascending.",
+			Value:       chain.WalkDescending,
+			Destination: &walkFlags.direction,
+		},
+		&cli.BoolFlag{
+			Name:        "overwrite",
+			Usage:       "Replace rows that already exist in storage instead of keeping them, so re-running a job after an extractor fix corrects the data.",
+			Destination: &walkFlags.overwrite,
+		},
+		&cli.StringFlag{
+			Name:        "actor-types",
+			Usage:       "Comma separated list of the families of actors whose state is extracted by actor state tasks, such as miner,market. The state of every actor is extracted if not set. Tasks limited by this list report a PARTIAL status, which does not count as complete.",
+			Destination: &walkFlags.actorTypes,
+		},
+		&cli.BoolFlag{
+			Name:        "defer-indexes",
+			Usage:       "Drop the secondary indexes of the tables written by the walk and rebuild them concurrently once it has finished, which speeds up walks of large ranges.",
+			Destination: &walkFlags.deferIdx,
+		},
+	}),
 	Action: func(cctx *cli.Context) error {
 		ctx := lotuscli.ReqContext(cctx)
 
@@ -106,6 +137,15 @@ var WalkCmd = &cli.Command{
 			RestartOnCompletion: false,
 			RestartOnFailure:    false,
 			Storage:             walkFlags.storage,
+			Strict:              walkFlags.strict,
+			Direction:           walkFlags.direction,
+			Overwrite:           walkFlags.overwrite,
+			DeferIndexes:        walkFlags.deferIdx,
+			MaxLensCalls:        jobLimitsFlags.maxLensCalls,
+			MaxPersistBatches:   jobLimitsFlags.maxPersistBatches,
+		}
+		if walkFlags.actorTypes != "" {
+			cfg.ActorTypes = strings.Split(walkFlags.actorTypes, ",")
 		}
 
 		api, closer, err := GetAPI(ctx, walkFlags.apiAddr, walkFlags.apiToken)
@@ -118,10 +158,7 @@ var WalkCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
-		if _, err := fmt.Fprintf(os.Stdout, "Created Watch Job: %d", watchID); err != nil {
-			return err
-		}
-		return nil
+		return printJobCreated("Created Watch Job: %d", watchID)
 	},
 }
 
@@ -131,7 +168,9 @@ var RunWalkCmd = &cli.Command{
 	Flags: flagSet(
 		dbConnectFlags,
 		dbBehaviourFlags,
+		dbInitFlags,
 		runLensFlags,
+		pluginFlags,
 		[]cli.Flag{
 			&cli.Int64Flag{
 				Name:    "from",
@@ -147,7 +186,7 @@ var RunWalkCmd = &cli.Command{
 			},
 			&cli.StringFlag{
 				Name:    "tasks",
-				Usage:   "Comma separated list of tasks to run. Each task is reported separately in the database.",
+				Usage:   "Comma separated list of tasks to run. Each task is reported separately in the database. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
 				Value:   strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
 				EnvVars: []string{"VISOR_WALK_TASKS"},
 			},
@@ -156,6 +195,27 @@ var RunWalkCmd = &cli.Command{
 				Usage:  "Path to write csv files.",
 				Hidden: true,
 			},
+			&cli.BoolFlag{
+				Name:    "strict",
+				Usage:   "Abort the walk on the first extraction or persistence error and exit with a non-zero status.",
+				EnvVars: []string{"VISOR_WALK_STRICT"},
+			},
+			&cli.StringFlag{
+				Name:    "direction",
+				Usage:   "Direction to walk the chain, either descending to index the most recent tipsets first or ascending.",
+				Value:   chain.WalkDescending,
+				EnvVars: []string{"VISOR_WALK_DIRECTION"},
+			},
+			&cli.StringFlag{
+				Name:    "actor-types",
+				Usage:   "Comma separated list of the families of actors whose state is extracted by actor state tasks, such as miner,market. The state of every actor is extracted if not set. Tasks limited by this list report a PARTIAL status, which does not count as complete.",
+				EnvVars: []string{"VISOR_WALK_ACTOR_TYPES"},
+			},
+			&cli.BoolFlag{
+				Name:    "defer-indexes",
+				Usage:   "Drop the secondary indexes of the tables written by the walk and rebuild them concurrently once it has finished, which speeds up walks of large ranges. Requires a database.",
+				EnvVars: []string{"VISOR_WALK_DEFER_INDEXES"},
+			},
 		},
 	),
 	Action: func(cctx *cli.Context) error {
@@ -167,7 +227,14 @@ var RunWalkCmd = &cli.Command{
 			return xerrors.Errorf("--from must not be greater than --to")
 		}
 
-		tasks := strings.Split(cctx.String("tasks"), ",")
+		if err := chain.ValidateWalkDirection(cctx.String("direction")); err != nil {
+			return err
+		}
+
+		tasks, err := chain.ExpandTasks(strings.Split(cctx.String("tasks"), ","))
+		if err != nil {
+			return xerrors.Errorf("tasks: %w", err)
+		}
 
 		if err := setupLogging(cctx); err != nil {
 			return xerrors.Errorf("setup logging: %w", err)
@@ -191,6 +258,7 @@ var RunWalkCmd = &cli.Command{
 			lensCloser()
 		}()
 
+		var db *storage.Database
 		var strg model.Storage = &storage.NullStorage{}
 		if cctx.String("csv") != "" {
 			csvStorage, err := storage.NewCSVStorageLatest(cctx.String("csv"))
@@ -202,7 +270,7 @@ var RunWalkCmd = &cli.Command{
 			if cctx.String("db") == "" {
 				log.Warnw("database not specified, data will not be persisted")
 			} else {
-				db, err := setupDatabase(cctx)
+				db, err = setupDatabase(cctx)
 				if err != nil {
 					return xerrors.Errorf("setup database: %w", err)
 				}
@@ -210,24 +278,60 @@ var RunWalkCmd = &cli.Command{
 			}
 		}
 
-		tsIndexer, err := chain.NewTipSetIndexer(lensOpener, strg, 0, cctx.String("name"), tasks)
+		opts, pluginCloser, err := setupPlugins(cctx, lensOpener, db, tasks)
+		if err != nil {
+			return xerrors.Errorf("setup plugins: %w", err)
+		}
+		defer pluginCloser()
+
+		// walks reach back to tipsets whose state the node may have pruned
+		opts = append(opts, chain.StateProbeOpt())
+		if cctx.Bool("strict") {
+			opts = append(opts, chain.StrictOpt())
+		}
+		if cctx.String("actor-types") != "" {
+			filter, err := chain.NewActorTypeFilter(strings.Split(cctx.String("actor-types"), ","))
+			if err != nil {
+				return xerrors.Errorf("actor types: %w", err)
+			}
+			opts = append(opts, chain.ActorTypeFilterOpt(filter))
+		}
+
+		tsIndexer, err := chain.NewTipSetIndexer(lensOpener, strg, 0, cctx.String("name"), tasks, opts...)
 		if err != nil {
 			return xerrors.Errorf("setup indexer: %w", err)
 		}
 
+		walkerOpts := []chain.WalkerOpt{chain.WalkDirectionOpt(cctx.String("direction"))}
+		if cctx.Bool("defer-indexes") {
+			if db == nil {
+				return xerrors.Errorf("--defer-indexes requires a database")
+			}
+			walkerOpts = append(walkerOpts, chain.DeferIndexesOpt(db, chain.TaskTables(tasks)))
+		}
+
 		scheduler := schedule.NewScheduler(cctx.Duration("task-delay"),
 			&schedule.JobConfig{
 				Name:                "Walker",
-				Job:                 chain.NewWalker(tsIndexer, lensOpener, heightFrom, heightTo),
+				Job:                 chain.NewWalker(tsIndexer, lensOpener, heightFrom, heightTo, walkerOpts...),
 				RestartOnFailure:    false, // Don't restart after a failure otherwise the walk will start from the beginning again
 				RestartOnCompletion: false,
 				RestartDelay:        time.Minute,
 			})
 
 		err = scheduler.Run(cctx.Context)
-		if !errors.Is(err, context.Canceled) {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			return err
 		}
+
+		// In strict mode a failed walk must be visible to the caller
+		if cctx.Bool("strict") {
+			for _, job := range scheduler.Jobs() {
+				if job.Error != "" {
+					return xerrors.Errorf("walk failed: %s", job.Error)
+				}
+			}
+		}
 		return nil
 	},
 }
@@ -4,12 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
-	"sync"
 	"time"
 
 	store "github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
 	lotuscli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/sentinel-visor/schedule"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
@@ -31,6 +30,12 @@ type watchOps struct {
 	apiAddr    string
 	apiToken   string
 	name       string
+	catchUp    bool
+	overwrite  bool
+	shedLag    int64
+	shedTasks  string
+	warmCache  int
+	actorTypes string
 }
 
 var watchFlags watchOps
@@ -38,7 +43,7 @@ var watchFlags watchOps
 var WatchCmd = &cli.Command{
 	Name:  "watch",
 	Usage: "Start a daemon job to watch the head of the filecoin blockchain.",
-	Flags: []cli.Flag{
+	Flags: flagSet(jobLimitsFlagSet, []cli.Flag{
 		&cli.IntFlag{
 			Name:        "confidence",
 			Usage:       "Sets the size of the cache used to hold tipsets for possible reversion before being committed to the database",
@@ -47,7 +52,7 @@ var WatchCmd = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:        "tasks",
-			Usage:       "Comma separated list of tasks to run. Each task is reported separately in the database.",
+			Usage:       "Comma separated list of tasks to run. Each task is reported separately in the database. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
 			Value:       strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
 			Destination: &watchFlags.tasks,
 		},
@@ -83,7 +88,37 @@ var WatchCmd = &cli.Command{
 			Value:       "",
 			Destination: &watchFlags.name,
 		},
-	},
+		&cli.BoolFlag{
+			Name:        "catch-up",
+			Usage:       "Walk the tipsets between the highest height already indexed by the watch's tasks under its name and the chain head while following the head. The range is recorded as a walk job so a catch up that is interrupted is resumed when the watch restarts. Tipsets dropped because the watch fell too far behind the chain head are also walked. Requires storage.",
+			Destination: &watchFlags.catchUp,
+		},
+		&cli.BoolFlag{
+			Name:        "overwrite",
+			Usage:       "Replace rows that already exist in storage instead of keeping them, so re-running a job after an extractor fix corrects the data.",
+			Destination: &watchFlags.overwrite,
+		},
+		&cli.Int64Flag{
+			Name:        "shed-lag-threshold",
+			Usage:       "Stop running the tasks given by --shed-tasks while indexing is more than `N` epochs behind the chain head, restoring them once the lag is back to half of N. Zero disables shedding.",
+			Destination: &watchFlags.shedLag,
+		},
+		&cli.StringFlag{
+			Name:        "shed-tasks",
+			Usage:       "Comma separated list of low priority tasks to shed when indexing falls behind the chain head.",
+			Destination: &watchFlags.shedTasks,
+		},
+		&cli.IntFlag{
+			Name:        "warm-cache",
+			Usage:       "Load the state of the last `N` tipsets indexed by the watch's tasks into the lens cache before following the chain head, so the first tipsets indexed after a restart are not slowed by a cold cache. Requires storage. Zero disables warming.",
+			Destination: &watchFlags.warmCache,
+		},
+		&cli.StringFlag{
+			Name:        "actor-types",
+			Usage:       "Comma separated list of the families of actors whose state is extracted by actor state tasks, such as miner,market. The state of every actor is extracted if not set. Tasks limited by this list report a PARTIAL status, which does not count as complete.",
+			Destination: &watchFlags.actorTypes,
+		},
+	}),
 	Action: func(cctx *cli.Context) error {
 		ctx := lotuscli.ReqContext(cctx)
 
@@ -101,6 +136,18 @@ var WatchCmd = &cli.Command{
 			RestartOnCompletion: false,
 			RestartOnFailure:    true,
 			Storage:             watchFlags.storage,
+			CatchUp:             watchFlags.catchUp,
+			Overwrite:           watchFlags.overwrite,
+			ShedLagThreshold:    watchFlags.shedLag,
+			WarmCache:           watchFlags.warmCache,
+			MaxLensCalls:        jobLimitsFlags.maxLensCalls,
+			MaxPersistBatches:   jobLimitsFlags.maxPersistBatches,
+		}
+		if watchFlags.actorTypes != "" {
+			cfg.ActorTypes = strings.Split(watchFlags.actorTypes, ",")
+		}
+		if watchFlags.shedTasks != "" {
+			cfg.ShedTasks = strings.Split(watchFlags.shedTasks, ",")
 		}
 
 		api, closer, err := GetAPI(ctx, watchFlags.apiAddr, watchFlags.apiToken)
@@ -113,10 +160,7 @@ var WatchCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
-		if _, err := fmt.Fprintf(os.Stdout, "Created Watch Job: %d", watchID); err != nil {
-			return err
-		}
-		return nil
+		return printJobCreated("Created Watch Job: %d", watchID)
 	},
 }
 
@@ -126,7 +170,11 @@ var RunWatchCmd = &cli.Command{
 	Flags: flagSet(
 		dbConnectFlags,
 		dbBehaviourFlags,
+		dbInitFlags,
 		runLensFlags,
+		remoteWriteFlags,
+		alertFlags,
+		pluginFlags,
 		[]cli.Flag{
 			&cli.IntFlag{
 				Name:    "indexhead-confidence",
@@ -136,7 +184,7 @@ var RunWatchCmd = &cli.Command{
 			},
 			&cli.StringFlag{
 				Name:    "tasks",
-				Usage:   "Comma separated list of tasks to run. Each task is reported separately in the database.",
+				Usage:   "Comma separated list of tasks to run. Each task is reported separately in the database. Task groups such as actorstates-all and wildcards such as actorstates* may be used.",
 				Value:   strings.Join([]string{chain.BlocksTask, chain.MessagesTask, chain.ChainEconomicsTask, chain.ActorStatesRawTask}, ","),
 				EnvVars: []string{"VISOR_WATCH_TASKS"},
 			},
@@ -146,13 +194,41 @@ var RunWatchCmd = &cli.Command{
 				Value:  builtin.EpochDurationSeconds * time.Second,
 				Hidden: true,
 			},
+			&cli.BoolFlag{
+				Name:    "catch-up",
+				Usage:   "Walk the tipsets between the highest height already indexed under the watch's name and the chain head while following the head. The range is recorded as a walk job so a catch up that is interrupted is resumed when the watch restarts. Tipsets dropped because the watch fell too far behind the chain head are also walked. Requires a database.",
+				EnvVars: []string{"VISOR_WATCH_CATCH_UP"},
+			},
+			&cli.Int64Flag{
+				Name:    "shed-lag-threshold",
+				Usage:   "Stop running the tasks given by --shed-tasks while indexing is more than `N` epochs behind the chain head, restoring them once the lag is back to half of N. An alert is sent when shedding starts and stops if an alert webhook is configured. Zero disables shedding.",
+				EnvVars: []string{"VISOR_WATCH_SHED_LAG_THRESHOLD"},
+			},
+			&cli.StringFlag{
+				Name:    "shed-tasks",
+				Usage:   "Comma separated list of low priority tasks to shed when indexing falls behind the chain head. Task groups and wildcards may be used.",
+				EnvVars: []string{"VISOR_WATCH_SHED_TASKS"},
+			},
+			&cli.IntFlag{
+				Name:    "warm-cache",
+				Usage:   "Load the state of the last `N` tipsets indexed by the watch's tasks into the lens cache before following the chain head, so the first tipsets indexed after a restart are not slowed by a cold cache. Requires a database. Zero disables warming.",
+				EnvVars: []string{"VISOR_WATCH_WARM_CACHE"},
+			},
+			&cli.StringFlag{
+				Name:    "actor-types",
+				Usage:   "Comma separated list of the families of actors whose state is extracted by actor state tasks, such as miner,market. The state of every actor is extracted if not set. Tasks limited by this list report a PARTIAL status, which does not count as complete.",
+				EnvVars: []string{"VISOR_WATCH_ACTOR_TYPES"},
+			},
 		},
 	),
 	Action: runWatch,
 }
 
 func runWatch(cctx *cli.Context) error {
-	tasks := strings.Split(cctx.String("tasks"), ",")
+	tasks, err := chain.ExpandTasks(strings.Split(cctx.String("tasks"), ","))
+	if err != nil {
+		return xerrors.Errorf("tasks: %w", err)
+	}
 
 	if err := setupLogging(cctx); err != nil {
 		return xerrors.Errorf("setup logging: %w", err)
@@ -176,41 +252,147 @@ func runWatch(cctx *cli.Context) error {
 		lensCloser()
 	}()
 
-	var storage model.Storage = &storage.NullStorage{}
+	var db *storage.Database
+	var strg model.Storage = &storage.NullStorage{}
 	if cctx.String("db") == "" {
+		if cctx.Bool("catch-up") {
+			return xerrors.Errorf("catch up requires a database")
+		}
 		log.Warnw("database not specified, data will not be persisted")
 	} else {
-		db, err := setupDatabase(cctx)
+		db, err = setupDatabase(cctx)
 		if err != nil {
 			return xerrors.Errorf("setup database: %w", err)
 		}
-		storage = db
+		strg = db
+	}
+
+	exporter, err := setupRemoteWrite(cctx)
+	if err != nil {
+		return xerrors.Errorf("setup remote write: %w", err)
+	}
+
+	opts, pluginCloser, err := setupPlugins(cctx, lensOpener, db, tasks)
+	if err != nil {
+		return xerrors.Errorf("setup plugins: %w", err)
+	}
+	defer pluginCloser()
+
+	if exporter != nil {
+		opts = append(opts, chain.PersistObserverOpt(exporter))
+	}
+	if cctx.String("actor-types") != "" {
+		filter, err := chain.NewActorTypeFilter(strings.Split(cctx.String("actor-types"), ","))
+		if err != nil {
+			return xerrors.Errorf("actor types: %w", err)
+		}
+		opts = append(opts, chain.ActorTypeFilterOpt(filter))
 	}
 
-	tsIndexer, err := chain.NewTipSetIndexer(lensOpener, storage, cctx.Duration("window"), cctx.String("name"), tasks)
+	tsIndexer, err := chain.NewTipSetIndexer(lensOpener, strg, cctx.Duration("window"), cctx.String("name"), tasks, opts...)
 	if err != nil {
 		return xerrors.Errorf("setup indexer: %w", err)
 	}
 
+	alerter, err := setupAlerts(cctx)
+	if err != nil {
+		return xerrors.Errorf("setup alerts: %w", err)
+	}
+
+	var watcherOpts []chain.WatcherOpt
+	if cctx.Int64("shed-lag-threshold") > 0 && cctx.String("shed-tasks") != "" {
+		shedTasks, err := chain.ExpandTasks(strings.Split(cctx.String("shed-tasks"), ","))
+		if err != nil {
+			return xerrors.Errorf("shed tasks: %w", err)
+		}
+		var a chain.Alerter
+		if alerter != nil {
+			a = alerter
+		}
+		watcherOpts = append(watcherOpts, chain.LagSheddingOpt(cctx.Int64("shed-lag-threshold"), shedTasks, a))
+	}
+	if cctx.Int("warm-cache") > 0 {
+		if db == nil {
+			return xerrors.Errorf("cache warming requires a database")
+		}
+		watcherOpts = append(watcherOpts, chain.CacheWarmingOpt(lensOpener, db, tasks, cctx.Int("warm-cache")))
+	}
+
+	if db != nil {
+		watcherOpts = append(watcherOpts, chain.HeadTrackingOpt(db, cctx.String("name"), tasks))
+	}
+
 	notifier := NewLotusChainNotifier(lensOpener)
 
+	watcher := chain.NewWatcher(tsIndexer, notifier, cctx.Int("indexhead-confidence"), watcherOpts...)
+	var watchJob schedule.Job = watcher
+	if cctx.Bool("catch-up") {
+		// Each walk uses its own indexer since an indexer tracks the previous tipset it was given. It has no time
+		// window since the walk is not bound to the arrival of new tipsets.
+		newWalkObs := func(cursor *chain.WalkCursor) (chain.TipSetObserver, error) {
+			walkOpts := append(append([]chain.TipSetIndexerOpt{}, opts...), chain.CommitObserverOpt(cursor))
+			walkIndexer, err := chain.NewTipSetIndexer(lensOpener, strg, 0, cctx.String("name"), tasks, walkOpts...)
+			if err != nil {
+				return nil, xerrors.Errorf("setup catch up indexer: %w", err)
+			}
+			return walkIndexer, nil
+		}
+		catchUp := chain.NewCatchUpWatcher(watcher, newWalkObs, lensOpener, db, cctx.String("name"), tasks)
+		notifier.OnDrop(catchUp.Dropped)
+		watchJob = catchUp
+	}
+
 	// TODO scheduler does not respect the ordering of these jobs, make it respect jobID when starting.
 	// Subscribe to chain head events to be passed to the watcher
-	scheduler := schedule.NewScheduler(cctx.Duration("task-delay"), &schedule.JobConfig{
-		Name:                "ChainHeadNotifier",
-		Job:                 notifier,
-		RestartOnFailure:    true,
-		RestartOnCompletion: true, // we always want the notifier to be running
-		RestartDelay:        time.Minute,
-	}, &schedule.JobConfig{
-		Name: "Watcher",
-		Job:  chain.NewWatcher(tsIndexer, notifier, cctx.Int("indexhead-confidence")),
-		// TODO: add locker
-		// Locker:              NewGlobalSingleton(ChainHeadIndexerLockID, rctx.db), // only want one forward indexer anywhere to be running
-		RestartOnFailure:    true,
-		RestartOnCompletion: true, // we always want the indexer to be running
-		RestartDelay:        time.Minute,
-	})
+	jobs := []*schedule.JobConfig{
+		{
+			Name:                "ChainHeadNotifier",
+			Job:                 notifier,
+			RestartOnFailure:    true,
+			RestartOnCompletion: true, // we always want the notifier to be running
+			RestartDelay:        time.Minute,
+		},
+		{
+			Name: "Watcher",
+			Job:  watchJob,
+			// TODO: add locker
+			// Locker:              NewGlobalSingleton(ChainHeadIndexerLockID, rctx.db), // only want one forward indexer anywhere to be running
+			RestartOnFailure:    true,
+			RestartOnCompletion: true, // we always want the indexer to be running
+			RestartDelay:        time.Minute,
+		},
+	}
+
+	if exporter != nil {
+		jobs = append(jobs, &schedule.JobConfig{
+			Name:                "RemoteWriteExporter",
+			Job:                 exporter,
+			RestartOnFailure:    true,
+			RestartOnCompletion: true,
+			RestartDelay:        time.Minute,
+		})
+	}
+
+	if alerter != nil {
+		monitor, err := setupAlertMonitor(cctx, alerter, db, lensOpener)
+		if err != nil {
+			return xerrors.Errorf("setup alert monitor: %w", err)
+		}
+		if monitor != nil {
+			jobs = append(jobs, &schedule.JobConfig{
+				Name:                "AlertMonitor",
+				Job:                 monitor,
+				RestartOnFailure:    true,
+				RestartOnCompletion: true,
+				RestartDelay:        time.Minute,
+			})
+		}
+	}
+
+	scheduler := schedule.NewScheduler(cctx.Duration("task-delay"), jobs...)
+	if alerter != nil {
+		scheduler.SetJobFailureHandler(alerter)
+	}
 
 	// Start the scheduler and wait for it to complete or to be cancelled.
 	err = scheduler.Run(cctx.Context)
@@ -221,48 +403,21 @@ func runWatch(cctx *cli.Context) error {
 }
 
 // LotusChainNotifier is a head event notifier that subscribes to a lens's ChainNotify method and adapts the
-// events received for use by a chain.Watcher
+// events received for use by a chain.Watcher. Events are queued in the order they are received so a slow watcher does
+// not hold up the subscription, and applies that skip over tipsets, which ChainNotify may send after the node has
+// fallen behind the chain, are expanded into one apply per tipset.
 // NOTE: this functionality will be probably folded into the Lotus API lens since other lenses will support more
 // direct methods of accessing new tipsets
 type LotusChainNotifier struct {
+	*chain.HeadEventQueue
 	opener lens.APIOpener
-
-	mu     sync.Mutex            // protects following fields
-	events chan *chain.HeadEvent // initialised in NewLotusChainNotifier and never mutated but may be closed
-	err    error                 // set to non-nil by the first cancel call. If non-nil then events channel has been closed.
 }
 
 func NewLotusChainNotifier(opener lens.APIOpener) *LotusChainNotifier {
 	return &LotusChainNotifier{
-		opener: opener,
-		events: make(chan *chain.HeadEvent),
-	}
-}
-
-func (c *LotusChainNotifier) HeadEvents() <-chan *chain.HeadEvent {
-	return c.events
-}
-
-func (c *LotusChainNotifier) Err() error {
-	c.mu.Lock()
-	err := c.err
-	c.mu.Unlock()
-	return err
-}
-
-func (c *LotusChainNotifier) Cancel(err error) {
-	c.mu.Lock()
-	if c.err != nil {
-		c.mu.Unlock()
-		return
+		HeadEventQueue: chain.NewHeadEventQueue(5),
+		opener:         opener,
 	}
-	if err != nil {
-		c.err = err
-	} else {
-		c.err = fmt.Errorf("canceled")
-	}
-	close(c.events)
-	c.mu.Unlock()
 }
 
 // Run subscribes to ChainNotify and blocks until the context is done or
@@ -280,6 +435,9 @@ func (c *LotusChainNotifier) Run(ctx context.Context) error {
 		return xerrors.Errorf("chain notify: %w", err)
 	}
 
+	// key of the tipset at the head of the chain after the events sent so far
+	head := types.EmptyTSK
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -290,24 +448,35 @@ func (c *LotusChainNotifier) Run(ctx context.Context) error {
 			}
 
 			for _, ch := range headEvents {
-				he := &chain.HeadEvent{
-					TipSet: ch.Val,
-				}
+				var events []*chain.HeadEvent
 				switch ch.Type {
 				case store.HCCurrent:
-					he.Type = chain.HeadEventCurrent
+					events = append(events, &chain.HeadEvent{Type: chain.HeadEventCurrent, TipSet: ch.Val})
+					head = ch.Val.Key()
 				case store.HCApply:
-					he.Type = chain.HeadEventApply
+					path, err := chain.ApplyPath(ctx, node, head, ch.Val)
+					if err != nil {
+						return xerrors.Errorf("apply path: %w", err)
+					}
+					if len(path) > 1 {
+						log.Infow("expanding apply over multiple tipsets", "from", path[0].Height(), "to", ch.Val.Height())
+					}
+					for _, ts := range path {
+						events = append(events, &chain.HeadEvent{Type: chain.HeadEventApply, TipSet: ts})
+					}
+					head = ch.Val.Key()
 				case store.HCRevert:
-					he.Type = chain.HeadEventRevert
+					events = append(events, &chain.HeadEvent{Type: chain.HeadEventRevert, TipSet: ch.Val})
+					head = ch.Val.Parents()
+				default:
+					continue
 				}
 
-				// Must take the lock here to test if events channel has been closed by a call to cancel
-				c.mu.Lock()
-				if c.err == nil {
-					c.events <- he
+				for _, he := range events {
+					if err := c.Push(he); err != nil {
+						return err
+					}
 				}
-				c.mu.Unlock()
 			}
 		}
 	}
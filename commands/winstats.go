@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var RunWinStatsCmd = newAggregateCmd(aggregateCmd{
+	Name:      "win-stats",
+	Usage:     "Maintain a comparison of expected and actual block wins for each miner in the miner_win_stats table.",
+	Job:       "MinerWinStatsAggregator",
+	EnvPrefix: "VISOR_WIN_STATS",
+	Refreshes: "stats",
+	Flags: []cli.Flag{
+		&cli.Int64SliceFlag{
+			Name:    "windows",
+			Usage:   "Lengths of the windows to compute, in epochs.",
+			Value:   cli.NewInt64Slice(2880, 20160),
+			EnvVars: []string{"VISOR_WIN_STATS_WINDOWS"},
+		},
+		&cli.Int64Flag{
+			Name:    "step",
+			Usage:   "Number of epochs between the ends of consecutive windows.",
+			Value:   2880,
+			EnvVars: []string{"VISOR_WIN_STATS_STEP"},
+		},
+		&cli.Int64Flag{
+			Name:    "lag",
+			Usage:   "Number of epochs behind the latest indexed height to compute windows up to.",
+			Value:   900,
+			EnvVars: []string{"VISOR_WIN_STATS_LAG"},
+		},
+		&cli.Int64Flag{
+			Name:    "from",
+			Usage:   "Lowest height at which a window may end.",
+			Value:   0,
+			EnvVars: []string{"VISOR_WIN_STATS_FROM"},
+		},
+	},
+	Check: func(cctx *cli.Context) error {
+		for _, w := range cctx.Int64Slice("windows") {
+			if w <= 0 {
+				return xerrors.Errorf("windows must be positive")
+			}
+		}
+		if cctx.Int64("step") <= 0 {
+			return xerrors.Errorf("step must be positive")
+		}
+		if cctx.Int64("lag") < 0 {
+			return xerrors.Errorf("lag must not be negative")
+		}
+		return nil
+	},
+	New: func(cctx *cli.Context, db *storage.Database) *storage.Aggregator {
+		return storage.NewMinerWinStatsAggregator(db, cctx.Int64Slice("windows"), cctx.Int64("step"), cctx.Int64("lag"), cctx.Int64("from"), cctx.Duration("interval"))
+	},
+})
@@ -20,6 +20,7 @@ type Conf struct {
 	Metrics    config.Metrics
 	Chainstore config.Chainstore
 	Storage    StorageConf
+	Query      QueryConf
 }
 
 type StorageConf struct {
@@ -34,11 +35,31 @@ type PgStorageConf struct {
 	SchemaName      string
 	PoolSize        int
 	AllowUpsert     bool
+	Notify          bool   // send a postgres notification for each table written to when a batch of data commits
+	ReadOnly        bool   // the database is a read replica, all writes to it are refused
+	ReadReplica     string // name of a ReadOnly Postgresql storage that read only queries against this database are sent to
+
+	IsolationLevel string          // isolation level of transactions persisting data: read committed, repeatable read or serializable
+	TxRetries      int             // number of times a transaction persisting data is retried after a serialization failure or deadlock
+	TxRetryDelay   config.Duration // delay before the first retry of a transaction, doubled for each further retry
+
+	Redact []string // columns to omit or hash when persisting, written as table.column or table.column:hash
+
+	ConflictKeys []string // conflict targets of tables whose primary key differs from visor's, written as table=column,column
 }
 
 type FileStorageConf struct {
-	Format string
-	Path   string
+	Format        string // CSV or Parquet
+	Path          string
+	Redact        []string // columns to omit or hash when persisting, written as table.column or table.column:hash
+	PartitionSize int64    // number of epochs in each height range partition of Parquet files, one day if zero
+}
+
+// QueryConf configures the GraphQL query server, which is served at /graphql on the API listen address. The server
+// is read only and requires an API token with read permission.
+type QueryConf struct {
+	Storage string // name of the postgresql storage to query, the query server is disabled if empty
+	Rosetta bool   // also serve the Rosetta Data API from the storage at /rosetta/
 }
 
 func DefaultConf() *Conf {
@@ -84,7 +105,10 @@ func SampleConf() *Conf {
 				PoolSize:        20,
 				ApplicationName: "visor",
 				AllowUpsert:     false,
+				Notify:          false,
 				SchemaName:      "public",
+				TxRetries:       3,
+				TxRetryDelay:    config.Duration(100 * time.Millisecond),
 			},
 			// this second database is only here to give an example to the user
 			"Database2": {
@@ -93,6 +117,8 @@ func SampleConf() *Conf {
 				ApplicationName: "visor",
 				AllowUpsert:     false,
 				SchemaName:      "public",
+				TxRetries:       3,
+				TxRetryDelay:    config.Duration(100 * time.Millisecond),
 			},
 		},
 
@@ -101,8 +127,17 @@ func SampleConf() *Conf {
 				Format: "CSV",
 				Path:   "/tmp",
 			},
+			"Parquet": {
+				Format:        "Parquet",
+				Path:          "/tmp/parquet",
+				PartitionSize: 2880,
+			},
 		},
 	}
+	cfg.Query = QueryConf{
+		Storage: "Database1",
+		Rosetta: false,
+	}
 
 	return &cfg
 }
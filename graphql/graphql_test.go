@@ -0,0 +1,112 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+)
+
+type fakeQuerier struct {
+	query  string
+	params []interface{}
+	rows   []interface{}
+}
+
+func (q *fakeQuerier) QueryContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error) {
+	q.query = query.(string)
+	q.params = params
+	slice := reflect.ValueOf(model).Elem()
+	for _, row := range q.rows {
+		slice.Set(reflect.Append(slice, reflect.ValueOf(row)))
+	}
+	return nil, nil
+}
+
+func TestExecute(t *testing.T) {
+	db := &fakeQuerier{
+		rows: []interface{}{
+			&blocks.BlockHeader{Height: 20, Cid: "bafy2", Miner: "f01000", ParentBaseFee: "100"},
+			&blocks.BlockHeader{Height: 10, Cid: "bafy1", Miner: "f01000", ParentBaseFee: "100"},
+		},
+	}
+	s := NewSchema(db)
+
+	resp := s.Execute(context.Background(), &Request{
+		Query:     `query($miner: String) { blocks(miner: $miner, maxHeight: 20, limit: 2) { height parentBaseFee __typename } }`,
+		Variables: map[string]interface{}{"miner": "f01000"},
+	})
+	require.Empty(t, resp.Errors)
+
+	assert.Equal(t, `SELECT "height", "parent_base_fee" FROM "block_headers" WHERE height <= ? AND is_canonical AND "miner" = ? ORDER BY height DESC, "cid" LIMIT ? OFFSET ?`, db.query)
+	assert.Equal(t, []interface{}{int64(20), "f01000", int64(2), int64(0)}, db.params)
+
+	out, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"blocks":[
+		{"height":20,"parentBaseFee":"100","__typename":"Block"},
+		{"height":10,"parentBaseFee":"100","__typename":"Block"}
+	]}}`, string(out))
+}
+
+func TestExecuteFragments(t *testing.T) {
+	db := &fakeQuerier{
+		rows: []interface{}{
+			&blocks.BlockHeader{Height: 10, Cid: "bafy1", Miner: "f01000"},
+		},
+	}
+	s := NewSchema(db)
+
+	resp := s.Execute(context.Background(), &Request{
+		Query: `
+query Blocks($withMiner: Boolean!) {
+	recent: blocks(limit: 1) {
+		...blockFields
+		... on Block { miner @include(if: $withMiner) }
+	}
+}
+query Other { messages { cid } }
+fragment blockFields on Block { cid height }`,
+		OperationName: "Blocks",
+		Variables:     map[string]interface{}{"withMiner": false},
+	})
+	require.Empty(t, resp.Errors)
+
+	assert.Equal(t, `SELECT "cid", "height" FROM "block_headers" WHERE is_canonical ORDER BY height DESC, "cid" LIMIT ? OFFSET ?`, db.query)
+
+	out, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"recent":[{"cid":"bafy1","height":10}]}}`, string(out))
+}
+
+func TestExecuteValidation(t *testing.T) {
+	s := NewSchema(&fakeQuerier{})
+
+	testCases := []string{
+		`{ nosuchfield { cid } }`,
+		`{ blocks { nosuchfield } }`,
+		`{ blocks }`,
+		`{ blocks(limit: 100000) { cid } }`,
+		`{ blocks(miner: 1000) { cid } }`,
+		`{ messages(includeReverted: 1) { cid } }`,
+		`query($h: Int!) { blocks(minHeight: $h) { cid } }`,
+		``,
+		`{ blocks { cid }`,
+		`mutation { blocks { cid } }`,
+		`{ blocks { ...unknownFragment } }`,
+		`{ blocks(miner: "f01 { cid } }`,
+		`{ blocks {} }`,
+		`{ __schema { types { name } } }`,
+	}
+	for _, tc := range testCases {
+		resp := s.Execute(context.Background(), &Request{Query: tc})
+		assert.Nil(t, resp.Data, tc)
+		assert.NotEmpty(t, resp.Errors, tc)
+	}
+}
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("visor/graphql")
+
+// maxRequestSize is the maximum size of a request body accepted by the handler.
+const maxRequestSize = 1 << 20
+
+// NewHandler returns an http.Handler that serves GraphQL queries against the schema. Queries may be sent as a JSON
+// encoded Request in the body of a POST request or in the query, operationName and variables parameters of a GET
+// request.
+func NewHandler(s *Schema) http.Handler {
+	return &handler{schema: s}
+}
+
+type handler struct {
+	schema *Schema
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				writeResponse(w, http.StatusBadRequest, errorResponse(err))
+				return
+			}
+		}
+	case http.MethodPost:
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeResponse(w, http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := h.schema.Execute(r.Context(), &req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeResponse(w, status, resp)
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorw("failed to write graphql response", "error", err)
+	}
+}
@@ -0,0 +1,553 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/validator"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/actors/common"
+	"github.com/filecoin-project/sentinel-visor/model/actors/market"
+	"github.com/filecoin-project/sentinel-visor/model/actors/miner"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+)
+
+const (
+	DefaultLimit = 100  // number of rows returned by a list field when no limit is given
+	MaxLimit     = 1000 // maximum number of rows that may be requested from a list field
+)
+
+// A Querier executes SQL queries against a database holding the latest visor schema. It is satisfied by
+// *storage.Database.
+type Querier interface {
+	QueryContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error)
+}
+
+// An objectType is a GraphQL object type backed by a visor model. Each column of the model's table is exposed as a
+// field named in lower camel case.
+type objectType struct {
+	name      string
+	modelType reflect.Type          // struct type of the model
+	table     string                // quoted table name
+	fields    map[string]*orm.Field // fields by GraphQL name
+	pks       []string              // quoted primary key columns
+}
+
+func newObjectType(name string, m interface{}) *objectType {
+	tbl := orm.NewQuery(nil, m).TableModel().Table()
+	ot := &objectType{
+		name:      name,
+		modelType: reflect.TypeOf(m).Elem(),
+		table:     string(tbl.SQLName),
+		fields:    map[string]*orm.Field{},
+	}
+	for _, f := range tbl.Fields {
+		ot.fields[fieldName(f.SQLName)] = f
+	}
+	for _, f := range tbl.PKs {
+		ot.pks = append(ot.pks, string(f.Column))
+	}
+	return ot
+}
+
+// fieldName converts a snake case column name to the lower camel case used for GraphQL field names.
+func fieldName(column string) string {
+	parts := strings.Split(column, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+type argType int
+
+const (
+	argInt argType = iota
+	argString
+	argBoolean
+)
+
+func (t argType) String() string {
+	switch t {
+	case argInt:
+		return "Int"
+	case argString:
+		return "String"
+	case argBoolean:
+		return "Boolean"
+	default:
+		return "Unknown"
+	}
+}
+
+// A listField is a field of the root query type that returns a list of objects filtered by height and by equality
+// on selected columns, ordered by descending height.
+type listField struct {
+	typ       *objectType
+	canonical bool               // true if the table has an is_canonical column
+	filters   map[string]argType // fields that may be used as equality filter arguments
+}
+
+// A Schema is the fixed GraphQL schema exposing the core visor models.
+type Schema struct {
+	db     Querier
+	roots  map[string]*listField
+	schema *ast.Schema // schema definition used to parse and validate queries
+}
+
+// NewSchema returns the visor GraphQL schema resolving queries using the given database.
+func NewSchema(db Querier) *Schema {
+	s := &Schema{
+		db: db,
+		roots: map[string]*listField{
+			"blocks": {
+				typ:       newObjectType("Block", (*blocks.BlockHeader)(nil)),
+				canonical: true,
+				filters: map[string]argType{
+					"cid":   argString,
+					"miner": argString,
+				},
+			},
+			"messages": {
+				typ:       newObjectType("Message", (*messages.Message)(nil)),
+				canonical: true,
+				filters: map[string]argType{
+					"cid":  argString,
+					"from": argString,
+					"to":   argString,
+				},
+			},
+			"actors": {
+				typ:       newObjectType("Actor", (*common.Actor)(nil)),
+				canonical: true,
+				filters: map[string]argType{
+					"id":   argString,
+					"code": argString,
+				},
+			},
+			"miners": {
+				typ:       newObjectType("Miner", (*miner.MinerInfo)(nil)),
+				canonical: true,
+				filters: map[string]argType{
+					"minerId": argString,
+					"ownerId": argString,
+				},
+			},
+			"deals": {
+				typ:       newObjectType("Deal", (*market.MarketDealProposal)(nil)),
+				canonical: true,
+				filters: map[string]argType{
+					"dealId":     argInt,
+					"clientId":   argString,
+					"providerId": argString,
+				},
+			},
+		},
+	}
+	s.schema = gqlparser.MustLoadSchema(&ast.Source{Name: "visor.graphql", Input: s.definition()})
+	return s
+}
+
+// definition returns the schema definition language document describing the root query type and the object types
+// it returns.
+func (s *Schema) definition() string {
+	var b strings.Builder
+	b.WriteString("type Query {\n")
+	for _, name := range sortedNames(s.roots) {
+		lf := s.roots[name]
+		args := []string{"minHeight: Int", "maxHeight: Int", "limit: Int", "offset: Int"}
+		if lf.canonical {
+			args = append(args, "includeReverted: Boolean")
+		}
+		for _, filter := range sortedNames(lf.filters) {
+			args = append(args, filter+": "+lf.filters[filter].String())
+		}
+		fmt.Fprintf(&b, "\t%s(%s): [%s!]\n", name, strings.Join(args, ", "), lf.typ.name)
+	}
+	b.WriteString("}\n")
+
+	for _, name := range sortedNames(s.roots) {
+		ot := s.roots[name].typ
+		fmt.Fprintf(&b, "\ntype %s {\n", ot.name)
+		for _, field := range sortedNames(ot.fields) {
+			fmt.Fprintf(&b, "\t%s: %s\n", field, scalarType(ot.fields[field].Type))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// scalarType returns the name of the GraphQL scalar type used for a model field of type t. Types without a
+// numeric or boolean representation, such as big integers, are serialized as strings.
+func scalarType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Bool:
+		return "Boolean"
+	default:
+		return "String"
+	}
+}
+
+// sortedNames returns the keys of a map with string keys in sorted order.
+func sortedNames(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// A Response is a GraphQL response. Data is nil if the request could not be executed.
+type Response struct {
+	Data   *object         `json:"data"`
+	Errors []ResponseError `json:"errors,omitempty"`
+}
+
+type ResponseError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Execute parses, validates and executes a request. Errors resolving a root field are reported in the response and
+// the field's value is null, other root fields are still resolved.
+func (s *Schema) Execute(ctx context.Context, req *Request) *Response {
+	doc, errs := gqlparser.LoadQuery(s.schema, req.Query)
+	if len(errs) > 0 {
+		resp := &Response{}
+		for _, err := range errs {
+			resp.Errors = append(resp.Errors, ResponseError{Message: err.Message})
+		}
+		return resp
+	}
+
+	op := doc.Operations.ForName(req.OperationName)
+	if op == nil {
+		if req.OperationName == "" {
+			return errorResponse(xerrors.Errorf("operation name is required when the query contains more than one operation"))
+		}
+		return errorResponse(xerrors.Errorf("unknown operation %q", req.OperationName))
+	}
+	if op.Operation != ast.Query {
+		return errorResponse(xerrors.Errorf("only query operations are supported"))
+	}
+
+	vars, verr := validator.VariableValues(s.schema, op, req.Variables)
+	if verr != nil {
+		return errorResponse(verr)
+	}
+
+	roots := collectFields(op.SelectionSet, vars)
+
+	// Validate the whole operation before touching the database
+	for _, f := range roots {
+		if f.Name == "__typename" {
+			continue
+		}
+		if _, ok := s.roots[f.Name]; !ok {
+			return errorResponse(xerrors.Errorf("field %q is not supported", f.Name))
+		}
+		if _, err := s.roots[f.Name].arguments(f, vars); err != nil {
+			return errorResponse(err)
+		}
+	}
+
+	resp := &Response{Data: &object{}}
+	for _, f := range roots {
+		if f.Name == "__typename" {
+			resp.Data.set(f.Alias, "Query")
+			continue
+		}
+
+		value, err := s.resolveRoot(ctx, f, vars)
+		if err != nil {
+			resp.Errors = append(resp.Errors, ResponseError{Message: err.Error(), Path: []interface{}{f.Alias}})
+			resp.Data.set(f.Alias, nil)
+			continue
+		}
+		resp.Data.set(f.Alias, value)
+	}
+	return resp
+}
+
+func errorResponse(err error) *Response {
+	return &Response{Errors: []ResponseError{{Message: err.Error()}}}
+}
+
+// collectFields returns the fields of a selection set in order, expanding fragments and dropping fields excluded by
+// the @skip and @include directives.
+func collectFields(set ast.SelectionSet, vars map[string]interface{}) []*ast.Field {
+	var fields []*ast.Field
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if included(sel.Directives, vars) {
+				fields = append(fields, sel)
+			}
+		case *ast.InlineFragment:
+			if included(sel.Directives, vars) {
+				fields = append(fields, collectFields(sel.SelectionSet, vars)...)
+			}
+		case *ast.FragmentSpread:
+			if included(sel.Directives, vars) {
+				fields = append(fields, collectFields(sel.Definition.SelectionSet, vars)...)
+			}
+		}
+	}
+	return fields
+}
+
+func included(directives ast.DirectiveList, vars map[string]interface{}) bool {
+	if d := directives.ForName("skip"); d != nil {
+		if skip, _ := d.ArgumentMap(vars)["if"].(bool); skip {
+			return false
+		}
+	}
+	if d := directives.ForName("include"); d != nil {
+		if include, _ := d.ArgumentMap(vars)["if"].(bool); !include {
+			return false
+		}
+	}
+	return true
+}
+
+// listArgs are the coerced arguments of a list field.
+type listArgs struct {
+	minHeight       *int64
+	maxHeight       *int64
+	limit           int64
+	offset          int64
+	includeReverted bool
+	filters         map[string]interface{} // filter values by field name
+}
+
+// arguments coerces the arguments of a list field. The names and types of the arguments have already been checked
+// against the schema by the validator.
+func (lf *listField) arguments(f *ast.Field, vars map[string]interface{}) (*listArgs, error) {
+	args := &listArgs{
+		limit:   DefaultLimit,
+		filters: map[string]interface{}{},
+	}
+
+	for name, v := range f.ArgumentMap(vars) {
+		if v == nil {
+			// A null argument is the same as not supplying the argument
+			continue
+		}
+
+		var typ argType
+		switch name {
+		case "minHeight", "maxHeight", "limit", "offset":
+			typ = argInt
+		case "includeReverted":
+			typ = argBoolean
+		default:
+			typ = lf.filters[name]
+		}
+
+		cv, err := coerce(v, typ)
+		if err != nil {
+			return nil, xerrors.Errorf("argument %q on field %q: %w", name, f.Name, err)
+		}
+
+		switch name {
+		case "minHeight":
+			h := cv.(int64)
+			args.minHeight = &h
+		case "maxHeight":
+			h := cv.(int64)
+			args.maxHeight = &h
+		case "limit":
+			args.limit = cv.(int64)
+			if args.limit < 0 || args.limit > MaxLimit {
+				return nil, xerrors.Errorf("argument \"limit\" on field %q must be between 0 and %d", f.Name, MaxLimit)
+			}
+		case "offset":
+			args.offset = cv.(int64)
+			if args.offset < 0 {
+				return nil, xerrors.Errorf("argument \"offset\" on field %q must not be negative", f.Name)
+			}
+		case "includeReverted":
+			args.includeReverted = cv.(bool)
+		default:
+			args.filters[name] = cv
+		}
+	}
+	return args, nil
+}
+
+// coerce converts an argument or variable value to the given type.
+func coerce(v interface{}, typ argType) (interface{}, error) {
+	switch typ {
+	case argInt:
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case int:
+			return int64(n), nil
+		case float64:
+			// Variables decoded from JSON without UseNumber
+			if n == float64(int64(n)) {
+				return int64(n), nil
+			}
+		case json.Number:
+			if i, err := n.Int64(); err == nil {
+				return i, nil
+			}
+		}
+	case argString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case argBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, xerrors.Errorf("expected value of type %s, found %v", typ, v)
+}
+
+func (s *Schema) resolveRoot(ctx context.Context, f *ast.Field, vars map[string]interface{}) ([]*object, error) {
+	lf := s.roots[f.Name]
+	selections := collectFields(f.SelectionSet, vars)
+	args, err := lf.arguments(f, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select only the requested columns
+	var columns []string
+	seen := map[string]bool{}
+	for _, sel := range selections {
+		if sel.Name == "__typename" || seen[sel.Name] {
+			continue
+		}
+		seen[sel.Name] = true
+		columns = append(columns, string(lf.typ.fields[sel.Name].Column))
+	}
+	if len(columns) == 0 {
+		// Only __typename was requested but we still need a row count
+		columns = append(columns, lf.typ.pks[0])
+	}
+
+	var where []string
+	var params []interface{}
+	if args.minHeight != nil {
+		where = append(where, "height >= ?")
+		params = append(params, *args.minHeight)
+	}
+	if args.maxHeight != nil {
+		where = append(where, "height <= ?")
+		params = append(params, *args.maxHeight)
+	}
+	if lf.canonical && !args.includeReverted {
+		where = append(where, "is_canonical")
+	}
+	for _, name := range sortedNames(args.filters) {
+		where = append(where, string(lf.typ.fields[name].Column)+" = ?")
+		params = append(params, args.filters[name])
+	}
+
+	var query bytes.Buffer
+	fmt.Fprintf(&query, "SELECT %s FROM %s", strings.Join(columns, ", "), lf.typ.table)
+	if len(where) > 0 {
+		fmt.Fprintf(&query, " WHERE %s", strings.Join(where, " AND "))
+	}
+	// Order by the primary key so pages are stable
+	order := []string{"height DESC"}
+	for _, pk := range lf.typ.pks {
+		if pk != `"height"` {
+			order = append(order, pk)
+		}
+	}
+	fmt.Fprintf(&query, " ORDER BY %s LIMIT ? OFFSET ?", strings.Join(order, ", "))
+	params = append(params, args.limit, args.offset)
+
+	rows := reflect.New(reflect.SliceOf(reflect.PtrTo(lf.typ.modelType)))
+	if _, err := s.db.QueryContext(ctx, rows.Interface(), query.String(), params...); err != nil {
+		return nil, xerrors.Errorf("query %s: %w", f.Name, err)
+	}
+
+	out := make([]*object, 0, rows.Elem().Len())
+	for i := 0; i < rows.Elem().Len(); i++ {
+		row := rows.Elem().Index(i).Elem()
+		obj := &object{}
+		for _, sel := range selections {
+			if sel.Name == "__typename" {
+				obj.set(sel.Alias, lf.typ.name)
+				continue
+			}
+			obj.set(sel.Alias, row.FieldByName(lf.typ.fields[sel.Name].GoName).Interface())
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
+
+// An object is a JSON object that preserves the order in which fields were selected, as required by GraphQL.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *object) set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = map[string]interface{}{}
+	}
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+// Package labels reads lists of labelled addresses and resolves them to actor IDs so they can be stored in the
+// address_tags table.
+package labels
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+
+	labelmodel "github.com/filecoin-project/sentinel-visor/model/labels"
+)
+
+// Formats of label lists that can be read.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// A Label is one entry of a label list.
+type Label struct {
+	Address  string `json:"address"`
+	Label    string `json:"label"`
+	Category string `json:"category,omitempty"`
+	Source   string `json:"source,omitempty"`
+}
+
+// Read reads a label list in the given format and returns address tags for its entries. Entries without a source are
+// given source. Addresses are validated and written in their canonical form so they can be joined with extracted data.
+func Read(r io.Reader, format string, source string) ([]*labelmodel.AddressTag, error) {
+	var entries []Label
+	var err error
+	switch format {
+	case FormatCSV:
+		entries, err = readCSV(r)
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&entries)
+	default:
+		return nil, xerrors.Errorf("unsupported label format: %s", format)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("read %s: %w", format, err)
+	}
+
+	tags := make([]*labelmodel.AddressTag, 0, len(entries))
+	for i, e := range entries {
+		addr, err := address.NewFromString(strings.TrimSpace(e.Address))
+		if err != nil {
+			return nil, xerrors.Errorf("entry %d: invalid address %q: %w", i+1, e.Address, err)
+		}
+		label := strings.TrimSpace(e.Label)
+		if label == "" {
+			return nil, xerrors.Errorf("entry %d: missing label for %s", i+1, addr)
+		}
+		tag := &labelmodel.AddressTag{
+			Address:  addr.String(),
+			Label:    label,
+			Category: strings.TrimSpace(e.Category),
+			Source:   strings.TrimSpace(e.Source),
+		}
+		if tag.Source == "" {
+			tag.Source = source
+		}
+		if addr.Protocol() == address.ID {
+			tag.IDAddress = tag.Address
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// readCSV reads a label list with a header row naming its columns. The address and label columns are required.
+func readCSV(r io.Reader) ([]Label, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "address", "label", "category", "source":
+			columns[name] = i
+		default:
+			return nil, xerrors.Errorf("unknown column %q", name)
+		}
+	}
+	for _, required := range []string{"address", "label"} {
+		if _, ok := columns[required]; !ok {
+			return nil, xerrors.Errorf("missing %s column", required)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return rec[i]
+		}
+		return ""
+	}
+
+	var entries []Label
+	for {
+		rec, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		entries = append(entries, Label{
+			Address:  field(rec, "address"),
+			Label:    field(rec, "label"),
+			Category: field(rec, "category"),
+			Source:   field(rec, "source"),
+		})
+	}
+}
+
+// An IDResolver looks up the ID address of an actor, such as a lotus state tree.
+type IDResolver interface {
+	LookupID(address.Address) (address.Address, error)
+}
+
+// Resolve sets the ID address of each tag whose address is not already an ID address and returns the number of tags
+// that could not be resolved because their actor does not exist in the state.
+func Resolve(tags []*labelmodel.AddressTag, r IDResolver) (int, error) {
+	unresolved := 0
+	for _, tag := range tags {
+		if tag.IDAddress != "" {
+			continue
+		}
+		addr, err := address.NewFromString(tag.Address)
+		if err != nil {
+			return 0, xerrors.Errorf("parse address: %w", err)
+		}
+		id, err := r.LookupID(addr)
+		if err != nil {
+			if errors.Is(err, types.ErrActorNotFound) {
+				unresolved++
+				continue
+			}
+			return 0, xerrors.Errorf("lookup id of %s: %w", addr, err)
+		}
+		tag.IDAddress = id.String()
+	}
+	return unresolved, nil
+}
@@ -0,0 +1,75 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+const keyAddress = "f1aaaqeayeaudaocajbifqydiob4ibceqt2oc2pvy"
+
+// canonical returns an address in the form written for the current network.
+func canonical(t *testing.T, s string) string {
+	addr, err := address.NewFromString(s)
+	require.NoError(t, err)
+	return addr.String()
+}
+
+func TestReadCSV(t *testing.T) {
+	tags, err := Read(strings.NewReader("address,label,category\nf01234, Big Miner ,miner\n"+keyAddress+",Exchange,exchange\n"), FormatCSV, "list.csv")
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+
+	assert.Equal(t, canonical(t, "f01234"), tags[0].Address)
+	assert.Equal(t, "Big Miner", tags[0].Label)
+	assert.Equal(t, "miner", tags[0].Category)
+	assert.Equal(t, "list.csv", tags[0].Source)
+	assert.Equal(t, tags[0].Address, tags[0].IDAddress, "id addresses need no resolution")
+
+	assert.Equal(t, canonical(t, keyAddress), tags[1].Address)
+	assert.Equal(t, "", tags[1].IDAddress)
+
+	_, err = Read(strings.NewReader("address,name\nf01234,x\n"), FormatCSV, "")
+	assert.Error(t, err, "unknown column")
+
+	_, err = Read(strings.NewReader("address,label\nnotanaddress,x\n"), FormatCSV, "")
+	assert.Error(t, err, "invalid address")
+
+	_, err = Read(strings.NewReader("address,label\nf01234,\n"), FormatCSV, "")
+	assert.Error(t, err, "missing label")
+}
+
+func TestReadJSON(t *testing.T) {
+	tags, err := Read(strings.NewReader(`[{"address":"f099","label":"Burnt Funds","category":"system","source":"builtin"}]`), FormatJSON, "list.json")
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, canonical(t, "f099"), tags[0].Address)
+	assert.Equal(t, "builtin", tags[0].Source)
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) LookupID(addr address.Address) (address.Address, error) {
+	id, ok := f[addr.String()]
+	if !ok {
+		return address.Undef, xerrors.Errorf("load actor: %w", types.ErrActorNotFound)
+	}
+	return address.NewFromString(id)
+}
+
+func TestResolve(t *testing.T) {
+	tags, err := Read(strings.NewReader("address,label\nf01234,Miner\n"+keyAddress+",Exchange\nf3aaaqeayeaudaocajbifqydiob4ibceqtcqkrmfyydenbwha5dypsaijcemsckjrhfausukzmfuxc7xayzmkq,Unknown\n"), FormatCSV, "")
+	require.NoError(t, err)
+
+	unresolved, err := Resolve(tags, fakeResolver{canonical(t, keyAddress): "f0100"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, unresolved)
+	assert.Equal(t, canonical(t, "f01234"), tags[0].IDAddress)
+	assert.Equal(t, canonical(t, "f0100"), tags[1].IDAddress)
+	assert.Equal(t, "", tags[2].IDAddress)
+}
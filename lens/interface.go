@@ -2,6 +2,7 @@ package lens
 
 import (
 	"context"
+	"time"
 
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 type API interface {
@@ -58,6 +60,49 @@ type StateAPI interface {
 	StateNetworkName(context.Context) (dtypes.NetworkName, error)
 }
 
+// A BlockGossipAPI observes blocks as they are received from the network's gossipsub blocks topic. It is only
+// available from lenses that run a libp2p host.
+type BlockGossipAPI interface {
+	// SubscribeBlockGossip returns a channel of blocks that have passed validation, in the order they were received.
+	// The channel is closed when the context is done or the subscription fails.
+	SubscribeBlockGossip(ctx context.Context) (<-chan *GossipBlock, error)
+}
+
+// A GossipBlock is a block received from the gossipsub blocks topic.
+type GossipBlock struct {
+	Header     *types.BlockHeader
+	Peer       peer.ID   // peer the block was received from
+	ReceivedAt time.Time // time the block was delivered to the subscription
+}
+
+// A RetrievalMarketAPI queries storage providers for retrieval offers. It is only available from lenses connected to a
+// node running the markets client, such as the lotus lens.
+type RetrievalMarketAPI interface {
+	// ClientMinerQueryOffer asks a miner whether it will serve a retrieval of the payload root, optionally from a
+	// specific piece. Errors contacting the miner and refusals are reported in the offer's Err field.
+	ClientMinerQueryOffer(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (api.QueryOffer, error)
+}
+
+// A SyncStateAPI reports the state of the chain sync system of the node. It is only available from lenses connected to
+// a syncing node, such as the lotus lens.
+type SyncStateAPI interface {
+	SyncState(ctx context.Context) (*api.SyncState, error)
+}
+
+// A MempoolAPI lists the messages waiting in the message pool of the node to be included in the chain. It is only
+// available from lenses connected to a node that follows the message pool, such as the lotus lens.
+type MempoolAPI interface {
+	MpoolPending(ctx context.Context, tsk types.TipSetKey) ([]*types.SignedMessage, error)
+}
+
+// An ExecutionTraceAPI replays the messages of a tipset to obtain their execution traces. It is available from lenses
+// backed by a full node, which must hold the state the messages are applied to.
+type ExecutionTraceAPI interface {
+	// StateCompute executes the messages of the tipset with key tsk, followed by msgs, returning a trace of each
+	// message executed.
+	StateCompute(ctx context.Context, height abi.ChainEpoch, msgs []*types.Message, tsk types.TipSetKey) (*api.ComputeStateOutput, error)
+}
+
 type APICloser func()
 
 type APIOpener interface {
@@ -9,7 +9,9 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"github.com/filecoin-project/sentinel-visor/chain"
 	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/storage"
 )
 
 type LilyAPI interface {
@@ -19,11 +21,22 @@ type LilyAPI interface {
 
 	LilyWatch(ctx context.Context, cfg *LilyWatchConfig) (schedule.JobID, error)
 	LilyWalk(ctx context.Context, cfg *LilyWalkConfig) (schedule.JobID, error)
+	LilyObserveBlocks(ctx context.Context, cfg *LilyObserveBlocksConfig) (schedule.JobID, error)
+	LilyGapFillRange(ctx context.Context, cfg *LilyGapFillRangeConfig) (schedule.JobID, error)
+
+	// LilyCompleteness reports whether tasks have completed for every tipset in a range of heights.
+	LilyCompleteness(ctx context.Context, cfg *LilyCompletenessConfig) (*chain.CompletenessReport, error)
+
+	// LilyProcessingErrors summarises the failures recorded in processing reports, grouped by task and error class.
+	LilyProcessingErrors(ctx context.Context, cfg *LilyProcessingErrorsConfig) ([]storage.ProcessingErrorGroup, error)
 
 	LilyJobStart(ctx context.Context, ID schedule.JobID) error
 	LilyJobStop(ctx context.Context, ID schedule.JobID) error
 	LilyJobList(ctx context.Context) ([]schedule.JobResult, error)
 
+	// LilyJobResume starts a new job that continues a recorded walk from the last tipset it committed.
+	LilyJobResume(ctx context.Context, cfg *LilyJobResumeConfig) (schedule.JobID, error)
+
 	// SyncState returns the current status of the chain sync system.
 	SyncState(context.Context) (*api.SyncState, error) //perm:read
 
@@ -54,7 +67,15 @@ type LilyWatchConfig struct {
 	RestartOnFailure    bool
 	RestartOnCompletion bool
 	RestartDelay        time.Duration
-	Storage             string // name of storage system to use, may be empty
+	Storage             string   // name of storage system to use, may be empty
+	CatchUp             bool     // walk the tipsets between the highest height indexed under the job's name and the chain head
+	Overwrite           bool     // replace rows that already exist in storage instead of keeping them
+	ShedLagThreshold    int64    // shed ShedTasks while indexing is more than this many epochs behind head, zero to disable
+	ShedTasks           []string // low priority tasks to shed when indexing falls behind
+	WarmCache           int      // number of recently indexed tipsets whose state is loaded into the cache on start, zero to disable
+	ActorTypes          []string // families of actors whose state is extracted, all actors if empty
+	MaxLensCalls        int      // lens calls the job may have in progress at once, zero for no limit
+	MaxPersistBatches   int      // batches of data the job may be persisting at once, zero for no limit
 }
 
 type LilyWalkConfig struct {
@@ -66,5 +87,58 @@ type LilyWalkConfig struct {
 	RestartOnFailure    bool
 	RestartOnCompletion bool
 	RestartDelay        time.Duration
+	Storage             string   // name of storage system to use, may be empty
+	Strict              bool     // abort the walk on the first extraction or persistence error
+	Direction           string   // direction of the walk, chain.WalkDescending if empty
+	Overwrite           bool     // replace rows that already exist in storage instead of keeping them
+	ActorTypes          []string // families of actors whose state is extracted, all actors if empty
+	DeferIndexes        bool     // drop secondary indexes while walking and rebuild them when the walk finishes
+	MaxLensCalls        int      // lens calls the job may have in progress at once, zero for no limit
+	MaxPersistBatches   int      // batches of data the job may be persisting at once, zero for no limit
+}
+
+type LilyJobResumeConfig struct {
+	WalkID              int64 // id of the walk to resume, as recorded in the visor_walk_jobs table
+	Window              time.Duration
+	RestartOnFailure    bool
+	RestartOnCompletion bool
+	RestartDelay        time.Duration
+	Storage             string // name of the storage holding the walk, may be empty
+	MaxLensCalls        int    // lens calls the job may have in progress at once, zero for no limit
+	MaxPersistBatches   int    // batches of data the job may be persisting at once, zero for no limit
+}
+
+type LilyGapFillRangeConfig struct {
+	Ranges              []chain.HeightRange // ranges of heights to fill, inclusive of both ends
+	Name                string
+	Tasks               []string
+	Window              time.Duration
+	RestartOnFailure    bool
+	RestartOnCompletion bool
+	RestartDelay        time.Duration
+	Storage             string // name of storage system to use, may be empty
+	Overwrite           bool   // replace rows that already exist in storage instead of keeping them
+	MaxLensCalls        int    // lens calls the job may have in progress at once, zero for no limit
+	MaxPersistBatches   int    // batches of data the job may be persisting at once, zero for no limit
+}
+
+type LilyCompletenessConfig struct {
+	From    int64    // first height of the range
+	To      int64    // last height of the range
+	Tasks   []string // tasks that must have completed, task groups and wildcards are expanded
+	Storage string   // name of the storage holding the processing reports
+}
+
+type LilyProcessingErrorsConfig struct {
+	Since   time.Duration // only failures reported within this duration before now are included
+	Tasks   []string      // tasks whose failures are included, all tasks if empty
+	Storage string        // name of the storage holding the processing reports
+}
+
+type LilyObserveBlocksConfig struct {
+	Name                string
+	RestartOnFailure    bool
+	RestartOnCompletion bool
+	RestartDelay        time.Duration
 	Storage             string // name of storage system to use, may be empty
 }
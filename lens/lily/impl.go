@@ -2,9 +2,10 @@ package lily
 
 import (
 	"context"
-	"sync"
+	"time"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/events"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/node/impl/common"
@@ -13,10 +14,13 @@ import (
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
 	"go.uber.org/fx"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/chain"
 	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/lens/util"
+	"github.com/filecoin-project/sentinel-visor/model"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
 	"github.com/filecoin-project/sentinel-visor/schedule"
 	"github.com/filecoin-project/sentinel-visor/storage"
 )
@@ -33,32 +37,87 @@ type LilyNodeAPI struct {
 	Events         *events.Events
 	Scheduler      *schedule.Scheduler
 	StorageCatalog *storage.Catalog
+	IndexNotifier  *chain.IndexNotifier `optional:"true"`
 }
 
+var _ lens.BlockGossipAPI = (*LilyNodeAPI)(nil)
+
 func (m *LilyNodeAPI) LilyWatch(_ context.Context, cfg *LilyWatchConfig) (schedule.JobID, error) {
 	// the context's passed to these methods live for the duration of the clients request, so make a new one.
 	ctx := context.Background()
 
+	tasks, err := chain.ExpandTasks(cfg.Tasks)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	// validate the rest of the configuration before connecting to storage or observing the chain so a rejected job
+	// leaves nothing behind
+	var filter *chain.ActorTypeFilter
+	if len(cfg.ActorTypes) > 0 {
+		if filter, err = chain.NewActorTypeFilter(cfg.ActorTypes); err != nil {
+			return schedule.InvalidJobID, xerrors.Errorf("actor types: %w", err)
+		}
+	}
+
+	var watcherOpts []chain.WatcherOpt
+	if cfg.ShedLagThreshold > 0 && len(cfg.ShedTasks) > 0 {
+		shedTasks, err := chain.ExpandTasks(cfg.ShedTasks)
+		if err != nil {
+			return schedule.InvalidJobID, xerrors.Errorf("shed tasks: %w", err)
+		}
+		watcherOpts = append(watcherOpts, chain.LagSheddingOpt(cfg.ShedLagThreshold, shedTasks, nil))
+	}
+
 	// create a database connection for this watch, ensure its pingable, and run migrations if needed/configured to.
-	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	strg, err := m.connectStorage(ctx, cfg.Storage, cfg.Overwrite)
 	if err != nil {
 		return schedule.InvalidJobID, err
 	}
 
+	// Catching up walks the missed tipsets with other indexers since an indexer tracks the previous tipset it was given.
+	var catchUpStore chain.CatchUpStorage
+	if cfg.CatchUp {
+		var ok bool
+		if catchUpStore, ok = strg.(chain.CatchUpStorage); !ok {
+			return schedule.InvalidJobID, xerrors.Errorf("catch up requires storage that records indexed heights and walk jobs")
+		}
+	}
+
+	var warmRoots chain.StateRootSource
+	if cfg.WarmCache > 0 {
+		var ok bool
+		if warmRoots, ok = strg.(chain.StateRootSource); !ok {
+			return schedule.InvalidJobID, xerrors.Errorf("cache warming requires storage that records indexed tipsets")
+		}
+	}
+
+	// the indexers, catch up and cache warming of the job share its limits
+	limits := chain.NewJobLimits(cfg.MaxLensCalls, cfg.MaxPersistBatches)
+	opener := limits.Opener(m)
+	if warmRoots != nil {
+		watcherOpts = append(watcherOpts, chain.CacheWarmingOpt(opener, warmRoots, tasks, cfg.WarmCache))
+	}
+
 	// instantiate an indexer to extract block, message, and actor state data from observed tipsets and persists it to the storage.
-	indexer, err := chain.NewTipSetIndexer(m, strg, cfg.Window, cfg.Name, cfg.Tasks)
+	opts := append(m.indexerOpts(), chain.PersistLimitOpt(limits))
+	if filter != nil {
+		opts = append(opts, chain.ActorTypeFilterOpt(filter))
+	}
+	indexer, err := chain.NewTipSetIndexer(opener, strg, cfg.Window, cfg.Name, tasks, opts...)
 	if err != nil {
 		return schedule.InvalidJobID, err
 	}
 
 	// HeadNotifier bridges between the event system and the watcher
 	obs := &HeadNotifier{
-		bufferSize: 5,
+		HeadEventQueue: chain.NewHeadEventQueue(5),
 	}
 
 	// get the current head and set it on the tipset cache (mimic chain.watcher behaviour)
 	head, err := m.ChainModuleAPI.ChainHead(ctx)
 	if err != nil {
+		closeIndexer(indexer)
 		return schedule.InvalidJobID, err
 	}
 
@@ -69,16 +128,38 @@ func (m *LilyNodeAPI) LilyWatch(_ context.Context, cfg *LilyWatchConfig) (schedu
 
 	// Hook up the notifier to the event system
 	if err := m.Events.Observe(obs); err != nil {
+		closeIndexer(indexer)
 		return schedule.InvalidJobID, err
 	}
 
+	if hs, ok := strg.(chain.ObservedHeadStorage); ok {
+		watcherOpts = append(watcherOpts, chain.HeadTrackingOpt(hs, cfg.Name, tasks))
+	}
+
+	watcher := chain.NewWatcher(indexer, obs, cfg.Confidence, watcherOpts...)
+	var job schedule.Job = watcher
+	if cfg.CatchUp {
+		newWalkObs := func(cursor *chain.WalkCursor) (chain.TipSetObserver, error) {
+			walkOpts := append(append([]chain.TipSetIndexerOpt{}, opts...), chain.CommitObserverOpt(cursor))
+			walkIndexer, err := chain.NewTipSetIndexer(opener, strg, 0, cfg.Name, tasks, walkOpts...)
+			if err != nil {
+				return nil, err
+			}
+			return walkIndexer, nil
+		}
+		catchUp := chain.NewCatchUpWatcher(watcher, newWalkObs, opener, catchUpStore, cfg.Name, tasks)
+		obs.OnDrop(catchUp.Dropped)
+		job = catchUp
+	}
+
 	id := m.Scheduler.Submit(&schedule.JobConfig{
 		Name:                cfg.Name,
-		Tasks:               cfg.Tasks,
-		Job:                 chain.NewWatcher(indexer, obs, cfg.Confidence),
+		Tasks:               tasks,
+		Job:                 job,
 		RestartOnFailure:    cfg.RestartOnFailure,
 		RestartOnCompletion: cfg.RestartOnCompletion,
 		RestartDelay:        cfg.RestartDelay,
+		Limits:              limits,
 	})
 
 	return id, nil
@@ -88,30 +169,341 @@ func (m *LilyNodeAPI) LilyWalk(_ context.Context, cfg *LilyWalkConfig) (schedule
 	// the context's passed to these methods live for the duration of the clients request, so make a new one.
 	ctx := context.Background()
 
+	tasks, err := chain.ExpandTasks(cfg.Tasks)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
 	// create a database connection for this watch, ensure its pingable, and run migrations if needed/configured to.
-	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	strg, err := m.connectStorage(ctx, cfg.Storage, cfg.Overwrite)
 	if err != nil {
 		return schedule.InvalidJobID, err
 	}
 
+	if err := chain.ValidateWalkDirection(cfg.Direction); err != nil {
+		return schedule.InvalidJobID, err
+	}
+
 	// instantiate an indexer to extract block, message, and actor state data from observed tipsets and persists it to the storage.
-	indexer, err := chain.NewTipSetIndexer(m, strg, cfg.Window, cfg.Name, cfg.Tasks)
+	// walks reach back to tipsets whose state the node may have pruned
+	limits := chain.NewJobLimits(cfg.MaxLensCalls, cfg.MaxPersistBatches)
+	opts := append(m.indexerOpts(), chain.StateProbeOpt(), chain.PersistLimitOpt(limits))
+	if cfg.Strict {
+		opts = append(opts, chain.StrictOpt())
+	}
+	if len(cfg.ActorTypes) > 0 {
+		filter, err := chain.NewActorTypeFilter(cfg.ActorTypes)
+		if err != nil {
+			return schedule.InvalidJobID, xerrors.Errorf("actor types: %w", err)
+		}
+		opts = append(opts, chain.ActorTypeFilterOpt(filter))
+	}
+
+	walkerOpts := []chain.WalkerOpt{chain.WalkDirectionOpt(cfg.Direction)}
+	if cfg.DeferIndexes {
+		ix, ok := strg.(chain.IndexDeferrer)
+		if !ok {
+			return schedule.InvalidJobID, xerrors.Errorf("storage %q cannot defer indexes", cfg.Storage)
+		}
+		walkerOpts = append(walkerOpts, chain.DeferIndexesOpt(ix, chain.TaskTables(tasks)))
+	}
+
+	// record the walk so it can be resumed if it is interrupted
+	if store, ok := strg.(chain.WalkJobStorage); ok {
+		job := &visormodel.WalkJob{
+			Name:         cfg.Name,
+			Tasks:        tasks,
+			MinHeight:    cfg.From,
+			MaxHeight:    cfg.To,
+			Direction:    cfg.Direction,
+			Strict:       cfg.Strict,
+			Overwrite:    cfg.Overwrite,
+			ActorTypes:   cfg.ActorTypes,
+			DeferIndexes: cfg.DeferIndexes,
+		}
+		if job.Direction == "" {
+			job.Direction = chain.WalkDescending
+		}
+		if err := store.CreateWalkJob(ctx, job); err != nil {
+			log.Warnw("failed to record walk, it will not be resumable", "name", cfg.Name, "error", err)
+		} else {
+			log.Infow("recorded walk", "name", cfg.Name, "walk_id", job.ID)
+			cursor := chain.NewWalkCursor(store, job)
+			opts = append(opts, chain.CommitObserverOpt(cursor))
+			walkerOpts = append(walkerOpts, chain.WalkCursorOpt(cursor))
+		}
+	}
+
+	opener := limits.Opener(m)
+	indexer, err := chain.NewTipSetIndexer(opener, strg, cfg.Window, cfg.Name, tasks, opts...)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	id := m.Scheduler.Submit(&schedule.JobConfig{
+		Name:                cfg.Name,
+		Tasks:               tasks,
+		Job:                 chain.NewWalker(indexer, opener, cfg.From, cfg.To, walkerOpts...),
+		RestartOnFailure:    cfg.RestartOnFailure,
+		RestartOnCompletion: cfg.RestartOnCompletion,
+		RestartDelay:        cfg.RestartDelay,
+		Limits:              limits,
+	})
+
+	return id, nil
+}
+
+func (m *LilyNodeAPI) LilyJobResume(_ context.Context, cfg *LilyJobResumeConfig) (schedule.JobID, error) {
+	// the context's passed to these methods live for the duration of the clients request, so make a new one.
+	ctx := context.Background()
+
+	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+	store, ok := strg.(chain.WalkJobStorage)
+	if !ok {
+		return schedule.InvalidJobID, xerrors.Errorf("storage %q does not record walks", cfg.Storage)
+	}
+
+	job, err := store.WalkJob(ctx, cfg.WalkID)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+	if job.Completed() {
+		return schedule.InvalidJobID, xerrors.Errorf("walk %d already completed at %s", job.ID, job.CompletedAt)
+	}
+
+	// a walk is resumed with the same tasks and behaviour, including overwriting, as when it was started
+	tasks, err := chain.ExpandTasks(job.Tasks)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+	if job.Overwrite {
+		strg, err = m.connectStorage(ctx, cfg.Storage, true)
+		if err != nil {
+			return schedule.InvalidJobID, err
+		}
+	}
+
+	limits := chain.NewJobLimits(cfg.MaxLensCalls, cfg.MaxPersistBatches)
+	opts := append(m.indexerOpts(), chain.StateProbeOpt(), chain.PersistLimitOpt(limits))
+	if job.Strict {
+		opts = append(opts, chain.StrictOpt())
+	}
+	if len(job.ActorTypes) > 0 {
+		filter, err := chain.NewActorTypeFilter(job.ActorTypes)
+		if err != nil {
+			return schedule.InvalidJobID, xerrors.Errorf("actor types: %w", err)
+		}
+		opts = append(opts, chain.ActorTypeFilterOpt(filter))
+	}
+	cursor := chain.NewWalkCursor(store, job)
+	opts = append(opts, chain.CommitObserverOpt(cursor))
+
+	walkerOpts := []chain.WalkerOpt{chain.WalkDirectionOpt(job.Direction), chain.WalkCursorOpt(cursor)}
+	if job.DeferIndexes {
+		ix, ok := strg.(chain.IndexDeferrer)
+		if !ok {
+			return schedule.InvalidJobID, xerrors.Errorf("storage %q cannot defer indexes", cfg.Storage)
+		}
+		walkerOpts = append(walkerOpts, chain.DeferIndexesOpt(ix, chain.TaskTables(tasks)))
+	}
+
+	opener := limits.Opener(m)
+	indexer, err := chain.NewTipSetIndexer(opener, strg, cfg.Window, job.Name, tasks, opts...)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	if height, _, ok := cursor.Position(); ok {
+		log.Infow("resuming walk", "walk_id", job.ID, "name", job.Name, "cursor_height", height)
+	} else {
+		log.Infow("resuming walk from the start of its range", "walk_id", job.ID, "name", job.Name)
+	}
+
+	id := m.Scheduler.Submit(&schedule.JobConfig{
+		Name:                job.Name,
+		Tasks:               tasks,
+		Job:                 chain.NewWalker(indexer, opener, job.MinHeight, job.MaxHeight, walkerOpts...),
+		RestartOnFailure:    cfg.RestartOnFailure,
+		RestartOnCompletion: cfg.RestartOnCompletion,
+		RestartDelay:        cfg.RestartDelay,
+		Limits:              limits,
+	})
+
+	return id, nil
+}
+
+func (m *LilyNodeAPI) LilyGapFillRange(_ context.Context, cfg *LilyGapFillRangeConfig) (schedule.JobID, error) {
+	// the context's passed to these methods live for the duration of the clients request, so make a new one.
+	ctx := context.Background()
+
+	tasks, err := chain.ExpandTasks(cfg.Tasks)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	strg, err := m.connectStorage(ctx, cfg.Storage, cfg.Overwrite)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	// each range is walked with a new indexer since the ranges need not be contiguous, all sharing the job's limits.
+	limits := chain.NewJobLimits(cfg.MaxLensCalls, cfg.MaxPersistBatches)
+	opener := limits.Opener(m)
+	newIndexer := func() (chain.TipSetObserver, error) {
+		indexer, err := chain.NewTipSetIndexer(opener, strg, cfg.Window, cfg.Name, tasks, append(m.indexerOpts(), chain.StateProbeOpt(), chain.PersistLimitOpt(limits))...)
+		if err != nil {
+			return nil, err
+		}
+		return indexer, nil
+	}
+
+	// create one indexer now so an invalid configuration is reported to the caller, closing it since each range
+	// creates its own
+	probe, err := newIndexer()
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+	if err := probe.Close(); err != nil {
+		return schedule.InvalidJobID, xerrors.Errorf("close indexer: %w", err)
+	}
+
+	filler, err := chain.NewGapFiller(newIndexer, opener, cfg.Ranges)
 	if err != nil {
 		return schedule.InvalidJobID, err
 	}
 
 	id := m.Scheduler.Submit(&schedule.JobConfig{
 		Name:                cfg.Name,
-		Tasks:               cfg.Tasks,
-		Job:                 chain.NewWalker(indexer, m, cfg.From, cfg.To),
+		Tasks:               tasks,
+		Job:                 filler,
 		RestartOnFailure:    cfg.RestartOnFailure,
 		RestartOnCompletion: cfg.RestartOnCompletion,
 		RestartDelay:        cfg.RestartDelay,
+		Limits:              limits,
 	})
 
 	return id, nil
 }
 
+func (m *LilyNodeAPI) LilyCompleteness(ctx context.Context, cfg *LilyCompletenessConfig) (*chain.CompletenessReport, error) {
+	tasks, err := chain.ExpandTasks(cfg.Tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	src, ok := strg.(chain.CompletionSource)
+	if !ok {
+		return nil, xerrors.Errorf("storage %q does not record processing reports", cfg.Storage)
+	}
+
+	return chain.CheckCompleteness(ctx, m, src, cfg.From, cfg.To, tasks)
+}
+
+func (m *LilyNodeAPI) LilyProcessingErrors(ctx context.Context, cfg *LilyProcessingErrorsConfig) ([]storage.ProcessingErrorGroup, error) {
+	var tasks []string
+	if len(cfg.Tasks) > 0 {
+		var err error
+		tasks, err = chain.ExpandTasks(cfg.Tasks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := strg.(storage.ProcessingErrorLister)
+	if !ok {
+		return nil, xerrors.Errorf("storage %q does not record processing reports", cfg.Storage)
+	}
+
+	return lister.ProcessingErrors(ctx, time.Now().Add(-cfg.Since), tasks)
+}
+
+func (m *LilyNodeAPI) LilyObserveBlocks(_ context.Context, cfg *LilyObserveBlocksConfig) (schedule.JobID, error) {
+	// the context's passed to these methods live for the duration of the clients request, so make a new one.
+	ctx := context.Background()
+
+	strg, err := m.StorageCatalog.Connect(ctx, cfg.Storage)
+	if err != nil {
+		return schedule.InvalidJobID, err
+	}
+
+	id := m.Scheduler.Submit(&schedule.JobConfig{
+		Name:                cfg.Name,
+		Job:                 chain.NewBlockPropagationObserver(m, strg, cfg.Name),
+		RestartOnFailure:    cfg.RestartOnFailure,
+		RestartOnCompletion: cfg.RestartOnCompletion,
+		RestartDelay:        cfg.RestartDelay,
+	})
+
+	return id, nil
+}
+
+// SubscribeBlockGossip subscribes to the blocks topic alongside the node's own subscription. Blocks are only delivered
+// after passing the node's validation.
+func (m *LilyNodeAPI) SubscribeBlockGossip(ctx context.Context) (<-chan *lens.GossipBlock, error) {
+	// Subscribe is deprecated in favour of joining the topic but the node has already joined it
+	sub, err := m.SyncAPI.PubSub.Subscribe(build.BlocksTopic(m.SyncAPI.NetName)) //nolint:staticcheck
+	if err != nil {
+		return nil, xerrors.Errorf("subscribe to blocks topic: %w", err)
+	}
+
+	out := make(chan *lens.GossipBlock, 16)
+	go func() {
+		defer close(out)
+		defer sub.Cancel()
+
+		for {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorw("block gossip subscription failed", "error", err)
+				}
+				return
+			}
+			received := time.Now()
+
+			// The node's validator leaves the decoded block in ValidatorData
+			blk, ok := msg.ValidatorData.(*types.BlockMsg)
+			if !ok {
+				blk, err = types.DecodeBlockMsg(msg.GetData())
+				if err != nil {
+					log.Warnw("failed to decode block message", "from", msg.ReceivedFrom, "error", err)
+					continue
+				}
+			}
+
+			select {
+			case out <- &lens.GossipBlock{Header: blk.Header, Peer: msg.ReceivedFrom, ReceivedAt: received}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// indexerOpts returns the options common to all indexers created by the node.
+func (m *LilyNodeAPI) indexerOpts() []chain.TipSetIndexerOpt {
+	var opts []chain.TipSetIndexerOpt
+	if m.IndexNotifier != nil {
+		opts = append(opts, chain.IndexNotifierOpt(m.IndexNotifier))
+	}
+	return opts
+}
+
 func (m *LilyNodeAPI) LilyJobStart(_ context.Context, ID schedule.JobID) error {
 	if err := m.Scheduler.StartJob(ID); err != nil {
 		return err
@@ -168,117 +560,55 @@ func (m *LilyNodeAPI) Shutdown(ctx context.Context) error {
 	return nil
 }
 
-type HeadNotifier struct {
-	mu     sync.Mutex            // protects following fields
-	events chan *chain.HeadEvent // created lazily, closed by first cancel call
-	err    error                 // set to non-nil by the first cancel call
-
-	// size of the buffer to maintain for events. Using a buffer reduces chance
-	// that the emitter of events will block when sending to this notifier.
-	bufferSize int
-}
-
-func (h *HeadNotifier) eventsCh() chan *chain.HeadEvent {
-	// caller must hold mu
-	if h.events == nil {
-		h.events = make(chan *chain.HeadEvent, h.bufferSize)
+// connectStorage connects the named storage. If overwrite is true the returned storage replaces rows that already
+// exist instead of keeping them, without affecting other jobs using the same storage.
+func (m *LilyNodeAPI) connectStorage(ctx context.Context, name string, overwrite bool) (model.Storage, error) {
+	strg, err := m.StorageCatalog.Connect(ctx, name)
+	if err != nil {
+		return nil, err
 	}
-	return h.events
-}
-
-func (h *HeadNotifier) HeadEvents() <-chan *chain.HeadEvent {
-	h.mu.Lock()
-	ev := h.eventsCh()
-	h.mu.Unlock()
-	return ev
-}
-
-func (h *HeadNotifier) Err() error {
-	h.mu.Lock()
-	err := h.err
-	h.mu.Unlock()
-	return err
-}
-
-func (h *HeadNotifier) Cancel(err error) {
-	h.mu.Lock()
-	if h.err != nil {
-		h.mu.Unlock()
-		return
+	if !overwrite {
+		return strg, nil
 	}
-	h.err = err
-	if h.events == nil {
-		h.events = make(chan *chain.HeadEvent, h.bufferSize)
+	ow, ok := strg.(storage.Overwriter)
+	if !ok {
+		return nil, xerrors.Errorf("storage %q does not support overwriting existing rows", name)
 	}
-	close(h.events)
-	h.mu.Unlock()
+	return ow.Overwriting(), nil
 }
 
-func (h *HeadNotifier) SetCurrent(ctx context.Context, ts *types.TipSet) error {
-	h.mu.Lock()
-	if h.err != nil {
-		err := h.err
-		h.mu.Unlock()
-		return err
+// closeIndexer releases an indexer of a job that failed to start. Storage is not closed since the catalog shares it
+// between jobs.
+func closeIndexer(indexer *chain.TipSetIndexer) {
+	if err := indexer.Close(); err != nil {
+		log.Warnw("failed to close indexer", "error", err)
 	}
-	ev := h.eventsCh()
-	h.mu.Unlock()
+}
 
-	// This is imprecise since it's inherently racy but good enough to emit
-	// a warning that the event may block the sender
-	if len(ev) == cap(ev) {
-		log.Warnw("head notifier buffer at capacity", "queued", len(ev))
-	}
+// A HeadNotifier bridges between the event system and the watcher of a single watch job. The event system is shared
+// by every job running in the daemon, so events are queued rather than blocking the emitter and delaying the delivery
+// of events to other jobs.
+type HeadNotifier struct {
+	*chain.HeadEventQueue
+}
 
-	ev <- &chain.HeadEvent{
+func (h *HeadNotifier) SetCurrent(ctx context.Context, ts *types.TipSet) error {
+	return h.Push(&chain.HeadEvent{
 		Type:   chain.HeadEventCurrent,
 		TipSet: ts,
-	}
-	return nil
+	})
 }
 
 func (h *HeadNotifier) Apply(ctx context.Context, ts *types.TipSet) error {
-	h.mu.Lock()
-	if h.err != nil {
-		err := h.err
-		h.mu.Unlock()
-		return err
-	}
-	ev := h.eventsCh()
-	h.mu.Unlock()
-
-	// This is imprecise since it's inherently racy but good enough to emit
-	// a warning that the event may block the sender
-	if len(ev) == cap(ev) {
-		log.Warnw("head notifier buffer at capacity", "queued", len(ev))
-	}
-
-	ev <- &chain.HeadEvent{
+	return h.Push(&chain.HeadEvent{
 		Type:   chain.HeadEventApply,
 		TipSet: ts,
-	}
-	return nil
+	})
 }
 
 func (h *HeadNotifier) Revert(ctx context.Context, ts *types.TipSet) error {
-	h.mu.Lock()
-	if h.err != nil {
-		err := h.err
-		h.mu.Unlock()
-		return err
-	}
-	ev := h.eventsCh()
-	h.mu.Unlock()
-
-	// This is imprecise since it's inherently racy but good enough to emit
-	// a warning that the event may block the sender
-	if len(ev) == cap(ev) {
-		log.Warnw("head notifier buffer at capacity", "queued", len(ev))
-	}
-
-	ev <- &chain.HeadEvent{
+	return h.Push(&chain.HeadEvent{
 		Type:   chain.HeadEventRevert,
 		TipSet: ts,
-	}
-	return nil
+	})
 }
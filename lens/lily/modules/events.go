@@ -5,6 +5,7 @@ import (
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
 	"go.uber.org/fx"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/config"
 	"github.com/filecoin-project/sentinel-visor/storage"
@@ -22,8 +23,17 @@ func NewEvents(mctx helpers.MetricsCtx, lc fx.Lifecycle, chainAPI full.ChainModu
 	return events.NewEventsWithConfidence(mctx, api, 10)
 }
 
+// NewStorageCatalog creates the catalog of configured storages. The daemon refuses to start if any database that can
+// be reached has a schema that is not compatible with this version of visor.
 func NewStorageCatalog(mctx helpers.MetricsCtx, lc fx.Lifecycle, cfg *config.Conf) (*storage.Catalog, error) {
-	return storage.NewCatalog(cfg.Storage)
+	catalog, err := storage.NewCatalog(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := catalog.CheckSchemas(mctx); err != nil {
+		return nil, xerrors.Errorf("check storage schemas: %w", err)
+	}
+	return catalog, nil
 }
 
 func LoadConf(path string) func(mctx helpers.MetricsCtx, lc fx.Lifecycle) (*config.Conf, error) {
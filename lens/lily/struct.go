@@ -10,8 +10,10 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"github.com/filecoin-project/sentinel-visor/chain"
 	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/schedule"
+	"github.com/filecoin-project/sentinel-visor/storage"
 )
 
 var log = logging.Logger("visor/lens/lily")
@@ -27,13 +29,20 @@ type LilyAPIStruct struct {
 		Store                                func() adt.Store                                                                  `perm:"read"`
 		GetExecutedAndBlockMessagesForTipset func(context.Context, *types.TipSet, *types.TipSet) (*lens.TipSetMessages, error) `perm:"read"`
 
-		LilyWatch func(context.Context, *LilyWatchConfig) (schedule.JobID, error) `perm:"read"`
-		LilyWalk  func(context.Context, *LilyWalkConfig) (schedule.JobID, error)  `perm:"read"`
+		LilyWatch         func(context.Context, *LilyWatchConfig) (schedule.JobID, error)                   `perm:"read"`
+		LilyWalk          func(context.Context, *LilyWalkConfig) (schedule.JobID, error)                    `perm:"read"`
+		LilyObserveBlocks func(context.Context, *LilyObserveBlocksConfig) (schedule.JobID, error)           `perm:"read"`
+		LilyGapFillRange  func(context.Context, *LilyGapFillRangeConfig) (schedule.JobID, error)            `perm:"read"`
+		LilyCompleteness  func(context.Context, *LilyCompletenessConfig) (*chain.CompletenessReport, error) `perm:"read"`
+
+		LilyProcessingErrors func(context.Context, *LilyProcessingErrorsConfig) ([]storage.ProcessingErrorGroup, error) `perm:"read"`
 
 		LilyJobStart func(ctx context.Context, ID schedule.JobID) error      `perm:"read"`
 		LilyJobStop  func(ctx context.Context, ID schedule.JobID) error      `perm:"read"`
 		LilyJobList  func(ctx context.Context) ([]schedule.JobResult, error) `perm:"read"`
 
+		LilyJobResume func(ctx context.Context, cfg *LilyJobResumeConfig) (schedule.JobID, error) `perm:"read"`
+
 		Shutdown func(context.Context) error `perm:"read"`
 
 		SyncState func(ctx context.Context) (*api.SyncState, error) `perm:"read"`
@@ -64,6 +73,22 @@ func (s *LilyAPIStruct) LilyWalk(ctx context.Context, cfg *LilyWalkConfig) (sche
 	return s.Internal.LilyWalk(ctx, cfg)
 }
 
+func (s *LilyAPIStruct) LilyObserveBlocks(ctx context.Context, cfg *LilyObserveBlocksConfig) (schedule.JobID, error) {
+	return s.Internal.LilyObserveBlocks(ctx, cfg)
+}
+
+func (s *LilyAPIStruct) LilyGapFillRange(ctx context.Context, cfg *LilyGapFillRangeConfig) (schedule.JobID, error) {
+	return s.Internal.LilyGapFillRange(ctx, cfg)
+}
+
+func (s *LilyAPIStruct) LilyCompleteness(ctx context.Context, cfg *LilyCompletenessConfig) (*chain.CompletenessReport, error) {
+	return s.Internal.LilyCompleteness(ctx, cfg)
+}
+
+func (s *LilyAPIStruct) LilyProcessingErrors(ctx context.Context, cfg *LilyProcessingErrorsConfig) ([]storage.ProcessingErrorGroup, error) {
+	return s.Internal.LilyProcessingErrors(ctx, cfg)
+}
+
 func (s *LilyAPIStruct) LilyJobStart(ctx context.Context, ID schedule.JobID) error {
 	return s.Internal.LilyJobStart(ctx, ID)
 }
@@ -76,6 +101,10 @@ func (s *LilyAPIStruct) LilyJobList(ctx context.Context) ([]schedule.JobResult,
 	return s.Internal.LilyJobList(ctx)
 }
 
+func (s *LilyAPIStruct) LilyJobResume(ctx context.Context, cfg *LilyJobResumeConfig) (schedule.JobID, error) {
+	return s.Internal.LilyJobResume(ctx, cfg)
+}
+
 func (s *LilyAPIStruct) Shutdown(ctx context.Context) error {
 	return s.Internal.Shutdown(ctx)
 }
@@ -30,7 +30,12 @@ func NewAPIWrapper(node v0api.FullNode, store adt.Store) *APIWrapper {
 	}
 }
 
-var _ lens.API = &APIWrapper{}
+var (
+	_ lens.API                = &APIWrapper{}
+	_ lens.RetrievalMarketAPI = &APIWrapper{}
+	_ lens.SyncStateAPI       = &APIWrapper{}
+	_ lens.MempoolAPI         = &APIWrapper{}
+)
 
 type APIWrapper struct {
 	v0api.FullNode
@@ -41,6 +46,33 @@ func (aw *APIWrapper) Store() adt.Store {
 	return aw.store
 }
 
+func (aw *APIWrapper) ClientMinerQueryOffer(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (api.QueryOffer, error) {
+	ctx, span := global.Tracer("").Start(ctx, "Lotus.ClientMinerQueryOffer")
+	defer span.End()
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.API, "ClientMinerQueryOffer"))
+	stop := metrics.Timer(ctx, metrics.LensRequestDuration)
+	defer stop()
+	return aw.FullNode.ClientMinerQueryOffer(ctx, miner, root, piece)
+}
+
+func (aw *APIWrapper) SyncState(ctx context.Context) (*api.SyncState, error) {
+	ctx, span := global.Tracer("").Start(ctx, "Lotus.SyncState")
+	defer span.End()
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.API, "SyncState"))
+	stop := metrics.Timer(ctx, metrics.LensRequestDuration)
+	defer stop()
+	return aw.FullNode.SyncState(ctx)
+}
+
+func (aw *APIWrapper) MpoolPending(ctx context.Context, tsk types.TipSetKey) ([]*types.SignedMessage, error) {
+	ctx, span := global.Tracer("").Start(ctx, "Lotus.MpoolPending")
+	defer span.End()
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.API, "MpoolPending"))
+	stop := metrics.Timer(ctx, metrics.LensRequestDuration)
+	defer stop()
+	return aw.FullNode.MpoolPending(ctx, tsk)
+}
+
 func (aw *APIWrapper) ChainGetBlock(ctx context.Context, msg cid.Cid) (*types.BlockHeader, error) {
 	ctx, span := global.Tracer("").Start(ctx, "Lotus.ChainGetBlock")
 	defer span.End()
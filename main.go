@@ -14,7 +14,10 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/urfave/cli/v2"
 
+	"github.com/filecoin-project/sentinel-visor/chain/actors/adt/diff"
 	"github.com/filecoin-project/sentinel-visor/commands"
+	"github.com/filecoin-project/sentinel-visor/tasks/marketsnapshots"
+	"github.com/filecoin-project/sentinel-visor/tasks/minersnapshots"
 	"github.com/filecoin-project/sentinel-visor/version"
 )
 
@@ -130,18 +133,59 @@ func main() {
 				Value:       ":9991",
 				Destination: &commands.VisorCmdFlags.PrometheusPort,
 			},
+			&cli.Uint64Flag{
+				Name:        "diff-adaptive-threshold",
+				EnvVars:     []string{"VISOR_DIFF_ADAPTIVE_THRESHOLD"},
+				Value:       0,
+				Usage:       "Diff actor state arrays holding fewer than this many elements by iterating them rather than comparing their nodes. Zero disables the adaptive strategy.",
+				Destination: &diff.AdaptiveArrayThreshold,
+			},
+			&cli.Int64Flag{
+				Name:        "miner-snapshot-interval",
+				EnvVars:     []string{"VISOR_MINER_SNAPSHOT_INTERVAL"},
+				Value:       minersnapshots.Interval,
+				Usage:       "Number of epochs between the snapshots of miner sectors and precommits taken by the minersnapshots task. Snapshots are taken at heights that are a multiple of the interval.",
+				Destination: &minersnapshots.Interval,
+			},
+			&cli.Int64Flag{
+				Name:        "market-snapshot-interval",
+				EnvVars:     []string{"VISOR_MARKET_SNAPSHOT_INTERVAL"},
+				Value:       marketsnapshots.Interval,
+				Usage:       "Number of epochs between the snapshots of market deals taken by the marketsnapshots task. Snapshots are taken at heights that are a multiple of the interval.",
+				Destination: &marketsnapshots.Interval,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				EnvVars:     []string{"VISOR_OUTPUT"},
+				Value:       commands.OutputText,
+				Usage:       "Format of the results written by commands, either text or json. JSON output is intended for scripts.",
+				Destination: &commands.VisorCmdFlags.Output,
+			},
 		},
+		Before: commands.ValidateOutput,
 		Commands: []*cli.Command{
+			commands.ChainCmd,
+			commands.CompletenessCmd,
 			commands.DaemonCmd,
+			commands.DbCmd,
+			commands.DebugCmd,
+			commands.ErrorsCmd,
+			commands.GapFillCmd,
 			commands.InitCmd,
 			commands.JobCmd,
+			commands.LabelsCmd,
 			commands.LogCmd,
 			commands.MigrateCmd,
+			commands.MigrateFromChainwatchCmd,
 			commands.NetCmd,
+			commands.ObserveBlocksCmd,
 			commands.RunCmd,
+			commands.SchemaCmd,
 			commands.StopCmd,
 			commands.SyncCmd,
+			commands.TenantCmd,
 			commands.VectorCmd,
+			commands.VerifyCmd,
 			commands.WaitApiCmd,
 			commands.WatchCmd,
 			commands.WalkCmd,
@@ -12,13 +12,16 @@ import (
 var defaultMillisecondsDistribution = view.Distribution(0.01, 0.05, 0.1, 0.3, 0.6, 0.8, 1, 2, 3, 4, 5, 6, 8, 10, 13, 16, 20, 25, 30, 40, 50, 65, 80, 100, 130, 160, 200, 250, 300, 400, 500, 650, 800, 1000, 2000, 5000, 10000, 20000, 30000, 50000, 100000, 200000, 500000, 1000000, 2000000, 5000000, 10000000, 10000000)
 
 var (
-	TaskType, _  = tag.NewKey("task")  // name of task processor
-	Job, _       = tag.NewKey("job")   // name of job
-	Name, _      = tag.NewKey("name")  // name of running instance of visor
-	Table, _     = tag.NewKey("table") // name of table data is persisted for
-	ConnState, _ = tag.NewKey("conn_state")
-	API, _       = tag.NewKey("api")        // name of method on lotus api
-	ActorCode, _ = tag.NewKey("actor_code") // human readable code of actor being processed
+	TaskType, _    = tag.NewKey("task")  // name of task processor
+	Job, _         = tag.NewKey("job")   // name of job
+	Name, _        = tag.NewKey("name")  // name of running instance of visor
+	Table, _       = tag.NewKey("table") // name of table data is persisted for
+	ConnState, _   = tag.NewKey("conn_state")
+	API, _         = tag.NewKey("api")          // name of method on lotus api
+	ActorCode, _   = tag.NewKey("actor_code")   // human readable code of actor being processed
+	ActorFamily, _ = tag.NewKey("actor_family") // family of actor being processed, independent of actors version
+	Structure, _   = tag.NewKey("structure")    // name of the actor state structure being diffed
+	Strategy, _    = tag.NewKey("strategy")     // strategy used to diff an actor state structure
 
 )
 
@@ -40,18 +43,23 @@ var (
 	TipSetCacheSize        = stats.Int64("tipset_cache_size", "Configured size of the tipset cache (aka confidence).", stats.UnitDimensionless)
 	TipSetCacheDepth       = stats.Int64("tipset_cache_depth", "Number of tipsets currently in the tipset cache.", stats.UnitDimensionless)
 	TipSetCacheEmptyRevert = stats.Int64("tipset_cache_empty_revert", "Number of revert operations performed on an empty tipset cache. This is an indication that a chain reorg is underway that is deeper than the cache size and includes tipsets that have already been read from the cache.", stats.UnitDimensionless)
+	TipSetsPending         = stats.Int64("tipsets_pending", "Number of tipsets accepted by the indexer that have not yet completed extraction and persistence.", stats.UnitDimensionless)
+	PersistBatchInFlight   = stats.Int64("persist_batch_inflight", "Number of batches of extracted data currently being persisted.", stats.UnitDimensionless)
+	ActorDiff              = stats.Int64("actor_diff", "Number of actor state structures diffed, by the strategy used.", stats.UnitDimensionless)
+	WatchLag               = stats.Int64("watch_lag", "Number of epochs between the chain head seen by the watch command and the last tipset it indexed without error.", stats.UnitDimensionless)
+	WatchShedTasks         = stats.Int64("watch_shed_tasks", "Number of tasks shed by the watch command because indexing fell too far behind the chain head.", stats.UnitDimensionless)
 )
 
 var (
 	ProcessingDurationView = &view.View{
 		Measure:     ProcessingDuration,
 		Aggregation: defaultMillisecondsDistribution,
-		TagKeys:     []tag.Key{TaskType, ActorCode},
+		TagKeys:     []tag.Key{TaskType, ActorCode, ActorFamily},
 	}
 	PersistDurationView = &view.View{
 		Measure:     PersistDuration,
 		Aggregation: defaultMillisecondsDistribution,
-		TagKeys:     []tag.Key{TaskType, Table, ActorCode},
+		TagKeys:     []tag.Key{TaskType, Table, ActorCode, ActorFamily},
 	}
 	DBConnsView = &view.View{
 		Measure:     DBConns,
@@ -61,13 +69,13 @@ var (
 	LensRequestDurationView = &view.View{
 		Measure:     LensRequestDuration,
 		Aggregation: defaultMillisecondsDistribution,
-		TagKeys:     []tag.Key{TaskType, API, ActorCode},
+		TagKeys:     []tag.Key{TaskType, API, ActorCode, ActorFamily},
 	}
 	LensRequestTotal = &view.View{
 		Name:        "lens_request_total",
 		Measure:     LensRequestDuration,
 		Aggregation: view.Count(),
-		TagKeys:     []tag.Key{TaskType, API, ActorCode},
+		TagKeys:     []tag.Key{TaskType, API, ActorCode, ActorFamily},
 	}
 	TipsetHeightView = &view.View{
 		Measure:     TipsetHeight,
@@ -78,13 +86,13 @@ var (
 		Name:        ProcessingFailure.Name() + "_total",
 		Measure:     ProcessingFailure,
 		Aggregation: view.Count(),
-		TagKeys:     []tag.Key{TaskType, ActorCode},
+		TagKeys:     []tag.Key{TaskType, ActorCode, ActorFamily},
 	}
 	PersistFailureTotalView = &view.View{
 		Name:        PersistFailure.Name() + "_total",
 		Measure:     PersistFailure,
 		Aggregation: view.Count(),
-		TagKeys:     []tag.Key{TaskType, Table, ActorCode},
+		TagKeys:     []tag.Key{TaskType, Table, ActorCode, ActorFamily},
 	}
 	WatchHeightView = &view.View{
 		Measure:     WatchHeight,
@@ -146,6 +154,32 @@ var (
 		Aggregation: view.Sum(),
 		TagKeys:     []tag.Key{Job},
 	}
+	TipSetsPendingView = &view.View{
+		Measure:     TipSetsPending,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Job},
+	}
+	PersistBatchInFlightView = &view.View{
+		Measure:     PersistBatchInFlight,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Job},
+	}
+	ActorDiffTotalView = &view.View{
+		Name:        ActorDiff.Name() + "_total",
+		Measure:     ActorDiff,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{TaskType, ActorCode, ActorFamily, Structure, Strategy},
+	}
+	WatchLagView = &view.View{
+		Measure:     WatchLag,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Job},
+	}
+	WatchShedTasksView = &view.View{
+		Measure:     WatchShedTasks,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Job},
+	}
 )
 
 var DefaultViews = []*view.View{
@@ -166,6 +200,11 @@ var DefaultViews = []*view.View{
 	TipSetCacheSizeView,
 	TipSetCacheDepthView,
 	TipSetCacheEmptyRevertTotalView,
+	TipSetsPendingView,
+	PersistBatchInFlightView,
+	WatchLagView,
+	WatchShedTasksView,
+	ActorDiffTotalView,
 }
 
 // SinceInMilliseconds returns the duration of time since the provide time as a float64.
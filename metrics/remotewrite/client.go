@@ -0,0 +1,120 @@
+// Package remotewrite pushes metrics derived from indexed chain data to a Prometheus remote-write endpoint.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"golang.org/x/xerrors"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/filecoin-project/sentinel-visor/version"
+)
+
+// A Label is a name and value pair identifying a time series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// A Sample is the value of a time series at a point in time.
+type Sample struct {
+	Value     float64
+	Timestamp int64 // milliseconds since the unix epoch
+}
+
+// A TimeSeries is a set of samples for a series identified by its labels, which must include the __name__ label.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// A Client sends time series to a Prometheus remote-write endpoint.
+type Client struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+// NewClient returns a client that posts to url. The headers are added to each request and may be used for
+// authentication.
+func NewClient(url string, headers http.Header, timeout time.Duration) *Client {
+	return &Client{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Write sends the time series in a single remote-write request.
+func (c *Client) Write(ctx context.Context, series []TimeSeries) error {
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return xerrors.Errorf("new request: %w", err)
+	}
+	for k, vs := range c.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "visor/"+version.String())
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return xerrors.Errorf("remote write failed with status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protocol buffer message.
+func encodeWriteRequest(series []TimeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		labels := append([]Label{}, ts.Labels...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		var tsb []byte
+		for _, l := range labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.Value)
+
+			tsb = protowire.AppendTag(tsb, 1, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, lb)
+		}
+		for _, s := range ts.Samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(s.Value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(s.Timestamp))
+
+			tsb = protowire.AppendTag(tsb, 2, protowire.BytesType)
+			tsb = protowire.AppendBytes(tsb, sb)
+		}
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, tsb)
+	}
+	return b
+}
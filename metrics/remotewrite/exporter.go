@@ -0,0 +1,164 @@
+package remotewrite
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/sentinel-visor/model/actors/power"
+	"github.com/filecoin-project/sentinel-visor/model/actors/verifreg"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+)
+
+var log = logging.Logger("visor/remotewrite")
+
+// Names of the metrics derived from indexed data.
+const (
+	MetricHeight           = "filecoin_chain_height"
+	MetricBaseFee          = "filecoin_chain_base_fee_attofil"
+	MetricRawPower         = "filecoin_chain_raw_power_bytes"
+	MetricQAPower          = "filecoin_chain_qa_power_bytes"
+	MetricMessages         = "filecoin_chain_messages"
+	MetricPledgeCollateral = "filecoin_chain_pledge_collateral_attofil"
+	MetricDataCapIssued    = "filecoin_verifreg_datacap_issued_bytes"
+)
+
+const (
+	queueSize    = 256 // number of tipsets whose series may wait to be sent
+	maxBatchSize = 64  // maximum number of tipsets sent in a single request
+)
+
+// An Exporter derives metrics from the data persisted for each tipset and sends them to a remote-write endpoint.
+// Samples are timestamped with the time of their tipset. Sending happens in the background and failures are logged
+// without affecting indexing.
+type Exporter struct {
+	client *Client
+	labels []Label // added to every series
+	queue  chan []TimeSeries
+}
+
+func NewExporter(client *Client, labels []Label) *Exporter {
+	return &Exporter{
+		client: client,
+		labels: labels,
+		queue:  make(chan []TimeSeries, queueSize),
+	}
+}
+
+// TipSetPersisted queues the metrics derived from the models persisted for a tipset. It satisfies
+// chain.PersistObserver.
+func (e *Exporter) TipSetPersisted(ctx context.Context, ts *types.TipSet, models []interface{}) {
+	series := e.seriesFor(ts, models)
+	if len(series) == 0 {
+		return
+	}
+
+	select {
+	case e.queue <- series:
+	default:
+		log.Warnw("remote write queue is full, dropping metrics", "height", ts.Height())
+	}
+}
+
+// Run sends queued metrics until the context is done.
+func (e *Exporter) Run(ctx context.Context) error {
+	for {
+		var batch []TimeSeries
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case series := <-e.queue:
+			batch = append(batch, series...)
+		}
+
+		// Include any other tipsets that are waiting
+	drain:
+		for i := 1; i < maxBatchSize; i++ {
+			select {
+			case series := <-e.queue:
+				batch = append(batch, series...)
+			default:
+				break drain
+			}
+		}
+
+		if err := e.client.Write(ctx, batch); err != nil {
+			log.Errorw("failed to send metrics", "series", len(batch), "error", err)
+		}
+	}
+}
+
+func (e *Exporter) seriesFor(ts *types.TipSet, models []interface{}) []TimeSeries {
+	timestamp := int64(ts.MinTimestamp()) * 1000
+	var series []TimeSeries
+	add := func(name string, value float64) {
+		labels := append([]Label{{Name: "__name__", Value: name}}, e.labels...)
+		series = append(series, TimeSeries{
+			Labels:  labels,
+			Samples: []Sample{{Value: value, Timestamp: timestamp}},
+		})
+	}
+
+	add(MetricHeight, float64(ts.Height()))
+
+	var baseFeeSeen bool
+	var messageCount int
+	var dataCapIssued float64
+	var dataCapSeen bool
+	for _, m := range models {
+		switch v := m.(type) {
+		case *blocks.BlockHeader:
+			// All blocks in a tipset share the same parent base fee
+			if !baseFeeSeen {
+				if f, ok := parseAmount(v.ParentBaseFee); ok {
+					add(MetricBaseFee, f)
+					baseFeeSeen = true
+				}
+			}
+		case *power.ChainPower:
+			if f, ok := parseAmount(v.TotalRawBytesPower); ok {
+				add(MetricRawPower, f)
+			}
+			if f, ok := parseAmount(v.TotalQABytesPower); ok {
+				add(MetricQAPower, f)
+			}
+			if f, ok := parseAmount(v.TotalPledgeCollateral); ok {
+				add(MetricPledgeCollateral, f)
+			}
+		case *messages.Message:
+			messageCount++
+		case *verifreg.VerifiedRegistryGovernance:
+			// Only datacap given to verifiers by the root key is counted, not its removal with a verifier
+			switch v.Event {
+			case verifreg.VerifierAdded:
+				if f, ok := parseAmount(v.DataCap); ok {
+					dataCapIssued += f
+					dataCapSeen = true
+				}
+			case verifreg.VerifierDataCapIncrease:
+				after, okAfter := parseAmount(v.DataCap)
+				before, okBefore := parseAmount(v.PreviousDataCap)
+				if okAfter && okBefore {
+					dataCapIssued += after - before
+					dataCapSeen = true
+				}
+			}
+		}
+	}
+	if messageCount > 0 {
+		add(MetricMessages, float64(messageCount))
+	}
+	if dataCapSeen {
+		add(MetricDataCapIssued, dataCapIssued)
+	}
+
+	return series
+}
+
+func parseAmount(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
@@ -0,0 +1,144 @@
+package remotewrite
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/actors/power"
+	"github.com/filecoin-project/sentinel-visor/model/actors/verifreg"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+// decodeWriteRequest decodes an encoded WriteRequest, failing the test if it is malformed.
+func decodeWriteRequest(t *testing.T, b []byte) []TimeSeries {
+	var series []TimeSeries
+	for len(b) > 0 {
+		_, _, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		tsb, m := protowire.ConsumeBytes(b[n:])
+		require.True(t, m > 0)
+		b = b[n+m:]
+
+		var ts TimeSeries
+		for len(tsb) > 0 {
+			num, _, n := protowire.ConsumeTag(tsb)
+			require.True(t, n > 0)
+			fb, m := protowire.ConsumeBytes(tsb[n:])
+			require.True(t, m > 0)
+			tsb = tsb[n+m:]
+
+			switch num {
+			case 1:
+				var l Label
+				_, _, n := protowire.ConsumeTag(fb)
+				name, m := protowire.ConsumeString(fb[n:])
+				fb = fb[n+m:]
+				_, _, n = protowire.ConsumeTag(fb)
+				value, _ := protowire.ConsumeString(fb[n:])
+				l.Name, l.Value = name, value
+				ts.Labels = append(ts.Labels, l)
+			case 2:
+				var s Sample
+				_, _, n := protowire.ConsumeTag(fb)
+				v, m := protowire.ConsumeFixed64(fb[n:])
+				fb = fb[n+m:]
+				_, _, n = protowire.ConsumeTag(fb)
+				ts0, _ := protowire.ConsumeVarint(fb[n:])
+				s.Value, s.Timestamp = math.Float64frombits(v), int64(ts0)
+				ts.Samples = append(ts.Samples, s)
+			}
+		}
+		series = append(series, ts)
+	}
+	return series
+}
+
+func TestClientWrite(t *testing.T) {
+	var got []TimeSeries
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		got = decodeWriteRequest(t, data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, http.Header{"Authorization": []string{"Bearer secret"}}, 0)
+	err := c.Write(context.Background(), []TimeSeries{{
+		Labels:  []Label{{Name: "network", Value: "mainnet"}, {Name: "__name__", Value: MetricHeight}},
+		Samples: []Sample{{Value: 10, Timestamp: 1000}},
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	// Labels are sorted by name
+	assert.Equal(t, []Label{{Name: "__name__", Value: MetricHeight}, {Name: "network", Value: "mainnet"}}, got[0].Labels)
+	assert.Equal(t, []Sample{{Value: 10, Timestamp: 1000}}, got[0].Samples)
+}
+
+func TestClientWriteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	err := NewClient(srv.URL, nil, 0).Write(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+}
+
+func TestExporterSeries(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	e := NewExporter(nil, []Label{{Name: "network", Value: "mainnet"}})
+
+	data := model.PersistableList{
+		blocks.BlockHeaders{{Cid: "a", ParentBaseFee: "100"}, {Cid: "b", ParentBaseFee: "100"}},
+		&power.ChainPower{TotalRawBytesPower: "2048", TotalQABytesPower: "4096", TotalPledgeCollateral: "5"},
+		messages.Messages{{Cid: "m1"}, {Cid: "m2"}, {Cid: "m3"}},
+		verifreg.VerifiedRegistryGovernanceList{
+			{Event: verifreg.VerifierAdded, Address: "f01", DataCap: "100"},
+			{Event: verifreg.VerifierDataCapIncrease, Address: "f02", DataCap: "70", PreviousDataCap: "50"},
+			{Event: verifreg.VerifierRemoved, Address: "f03", DataCap: "0", PreviousDataCap: "30"},
+		},
+	}
+	mc := model.NewCollector(nil)
+	require.NoError(t, data.Persist(context.Background(), mc, model.Version{Major: 1, Patch: 47}))
+
+	var models []interface{}
+	mc.Each(func(m interface{}) { models = append(models, m) })
+	series := e.seriesFor(ts, models)
+
+	values := map[string]float64{}
+	for _, s := range series {
+		require.Len(t, s.Samples, 1)
+		assert.Equal(t, int64(ts.MinTimestamp())*1000, s.Samples[0].Timestamp)
+		assert.Contains(t, s.Labels, Label{Name: "network", Value: "mainnet"})
+		values[s.Labels[0].Value] = s.Samples[0].Value
+	}
+
+	assert.Equal(t, map[string]float64{
+		MetricHeight:           float64(ts.Height()),
+		MetricBaseFee:          100,
+		MetricRawPower:         2048,
+		MetricQAPower:          4096,
+		MetricPledgeCollateral: 5,
+		MetricMessages:         3,
+		MetricDataCapIssued:    120,
+	}, values)
+}
@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/otel/api/global"
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
@@ -18,10 +19,46 @@ type Actor struct {
 	StateRoot string `pg:",pk,notnull"`
 	Code      string `pg:",notnull"`
 	Head      string `pg:",notnull"`
-	Balance   string `pg:",notnull"`
+	Balance   string `pg:"type:numeric,notnull"`
 	Nonce     uint64 `pg:",use_zero"`
 }
 
+// numericAmountsVersion is the first schema version in which all token amounts are stored as numeric.
+var numericAmountsVersion = model.Version{Major: 1, Patch: 14}
+
+// ActorV1 is the form of an Actor persisted before schema 1.14, when balances were stored as text.
+type ActorV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"actors"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	ID        string   `pg:",pk,notnull"`
+	StateRoot string   `pg:",pk,notnull"`
+	Code      string   `pg:",notnull"`
+	Head      string   `pg:",notnull"`
+	Balance   string   `pg:",notnull"`
+	Nonce     uint64   `pg:",use_zero"`
+}
+
+func (a *Actor) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(numericAmountsVersion) {
+		return a, true
+	}
+
+	if a == nil {
+		return (*ActorV1)(nil), true
+	}
+
+	return &ActorV1{
+		Height:    a.Height,
+		ID:        a.ID,
+		StateRoot: a.StateRoot,
+		Code:      a.Code,
+		Head:      a.Head,
+		Balance:   a.Balance,
+		Nonce:     a.Nonce,
+	}, true
+}
+
 func (a *Actor) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
 	ctx, span := global.Tracer("").Start(ctx, "Actor.Persist")
 	defer span.End()
@@ -31,7 +68,12 @@ func (a *Actor) Persist(ctx context.Context, s model.StorageBatch, version model
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
-	return s.PersistModel(ctx, a)
+	va, ok := a.AsVersion(version)
+	if !ok {
+		return xerrors.Errorf("Actor not supported for schema version %s", version)
+	}
+
+	return s.PersistModel(ctx, va)
 }
 
 // ActorList is a slice of Actors persistable in a single batch.
@@ -50,6 +92,16 @@ func (actors ActorList) Persist(ctx context.Context, s model.StorageBatch, versi
 	if len(actors) == 0 {
 		return nil
 	}
+
+	if version.Before(numericAmountsVersion) {
+		vactors := make([]*ActorV1, 0, len(actors))
+		for _, a := range actors {
+			va, _ := a.AsVersion(version)
+			vactors = append(vactors, va.(*ActorV1))
+		}
+		return s.PersistModel(ctx, vactors)
+	}
+
 	return s.PersistModel(ctx, actors)
 }
 
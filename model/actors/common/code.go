@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// builtinActorCodesVersion is the first schema version containing the builtin_actor_codes table.
+var builtinActorCodesVersion = model.Version{Major: 1, Patch: 8}
+
+// A BuiltinActorCode describes the code CID of a builtin actor.
+type BuiltinActorCode struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"builtin_actor_codes"`
+	Code      string   `pg:",pk,notnull"`
+	Name      string   `pg:",notnull"`
+	Family    string   `pg:",notnull"`
+	Version   int      `pg:",notnull,use_zero"`
+}
+
+func (c *BuiltinActorCode) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(builtinActorCodesVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "builtin_actor_codes"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, c)
+}
+
+type BuiltinActorCodeList []*BuiltinActorCode
+
+func (l BuiltinActorCodeList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(builtinActorCodesVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "BuiltinActorCodeList.Persist", trace.WithAttributes(label.Int("count", len(l))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "builtin_actor_codes"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// actorDeletionsVersion is the first schema version containing the actor_deletions table.
+var actorDeletionsVersion = model.Version{Major: 1, Patch: 3}
+
+// An ActorDeletion records an actor that was present in the parent state of a tipset but is missing from the state
+// of its child.
+type ActorDeletion struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"actor_deletions"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	ID        string   `pg:",pk,notnull"`
+	StateRoot string   `pg:",pk,notnull"`
+	Code      string   `pg:",notnull"`
+	Balance   string   `pg:"type:numeric,notnull"` // balance in the last state the actor was present in
+}
+
+func (a *ActorDeletion) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(actorDeletionsVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "actor_deletions"))
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	return s.PersistModel(ctx, a)
+}
+
+// ActorDeletionList is a slice of ActorDeletions persistable in a single batch.
+type ActorDeletionList []*ActorDeletion
+
+func (l ActorDeletionList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(actorDeletionsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "ActorDeletionList.Persist", trace.WithAttributes(label.Int("count", len(l))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "actor_deletions"))
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	return s.PersistModel(ctx, l)
+}
@@ -0,0 +1,44 @@
+package market
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// dealPiecesVersion is the first schema version containing the market_deal_pieces table.
+var dealPiecesVersion = model.Version{Major: 1, Patch: 46}
+
+// MarketDealPiece records the content addresses of the data stored by a deal so deals can be found by CID. The payload
+// CID is only known when the client gave it in the deal's label and is empty otherwise.
+type MarketDealPiece struct {
+	Height    int64  `pg:",pk,notnull,use_zero"`
+	DealID    uint64 `pg:",pk,use_zero"`
+	StateRoot string `pg:",pk,notnull"`
+
+	PieceCID   string `pg:",notnull"`
+	PayloadCID string
+}
+
+type MarketDealPieces []*MarketDealPiece
+
+func (dps MarketDealPieces) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(dps) == 0 || version.Before(dealPiecesVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MarketDealPieces.Persist", trace.WithAttributes(label.Int("count", len(dps))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "market_deal_pieces"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(dps))
+	return s.PersistModel(ctx, dps)
+}
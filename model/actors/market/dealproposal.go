@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/otel/api/global"
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
@@ -23,6 +24,35 @@ type MarketDealProposal struct {
 	StartEpoch int64 `pg:",use_zero"`
 	EndEpoch   int64 `pg:",use_zero"`
 
+	ClientID             string `pg:",notnull"`
+	ProviderID           string `pg:",notnull"`
+	ClientCollateral     string `pg:"type:numeric,notnull"`
+	ProviderCollateral   string `pg:"type:numeric,notnull"`
+	StoragePricePerEpoch string `pg:"type:numeric,notnull"`
+	PieceCID             string `pg:",notnull"`
+
+	IsVerified bool `pg:",notnull,use_zero"`
+	Label      string
+}
+
+// numericAmountsVersion is the first schema version in which all token amounts are stored as numeric.
+var numericAmountsVersion = model.Version{Major: 1, Patch: 14}
+
+// MarketDealProposalV1 is the form of a MarketDealProposal persisted before schema 1.14, when collateral and prices
+// were stored as text.
+type MarketDealProposalV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"market_deal_proposals"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	DealID    uint64   `pg:",pk,use_zero"`
+	StateRoot string   `pg:",notnull"`
+
+	PaddedPieceSize   uint64 `pg:",use_zero"`
+	UnpaddedPieceSize uint64 `pg:",use_zero"`
+
+	StartEpoch int64 `pg:",use_zero"`
+	EndEpoch   int64 `pg:",use_zero"`
+
 	ClientID             string `pg:",notnull"`
 	ProviderID           string `pg:",notnull"`
 	ClientCollateral     string `pg:",notnull"`
@@ -34,13 +64,46 @@ type MarketDealProposal struct {
 	Label      string
 }
 
+func (dp *MarketDealProposal) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(numericAmountsVersion) {
+		return dp, true
+	}
+
+	if dp == nil {
+		return (*MarketDealProposalV1)(nil), true
+	}
+
+	return &MarketDealProposalV1{
+		Height:               dp.Height,
+		DealID:               dp.DealID,
+		StateRoot:            dp.StateRoot,
+		PaddedPieceSize:      dp.PaddedPieceSize,
+		UnpaddedPieceSize:    dp.UnpaddedPieceSize,
+		StartEpoch:           dp.StartEpoch,
+		EndEpoch:             dp.EndEpoch,
+		ClientID:             dp.ClientID,
+		ProviderID:           dp.ProviderID,
+		ClientCollateral:     dp.ClientCollateral,
+		ProviderCollateral:   dp.ProviderCollateral,
+		StoragePricePerEpoch: dp.StoragePricePerEpoch,
+		PieceCID:             dp.PieceCID,
+		IsVerified:           dp.IsVerified,
+		Label:                dp.Label,
+	}, true
+}
+
 func (dp *MarketDealProposal) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
 	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "market_deal_proposals"))
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vdp, ok := dp.AsVersion(version)
+	if !ok {
+		return xerrors.Errorf("MarketDealProposal not supported for schema version %s", version)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, dp)
+	return s.PersistModel(ctx, vdp)
 }
 
 type MarketDealProposals []*MarketDealProposal
@@ -53,6 +116,16 @@ func (dps MarketDealProposals) Persist(ctx context.Context, s model.StorageBatch
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Before(numericAmountsVersion) {
+		vdps := make([]*MarketDealProposalV1, 0, len(dps))
+		for _, dp := range dps {
+			vdp, _ := dp.AsVersion(version)
+			vdps = append(vdps, vdp.(*MarketDealProposalV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vdps))
+		return s.PersistModel(ctx, vdps)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(dps))
 	return s.PersistModel(ctx, dps)
 }
@@ -0,0 +1,61 @@
+package market
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// dealSnapshotsVersion is the first schema version containing the market_deal_snapshots table.
+var dealSnapshotsVersion = model.Version{Major: 1, Patch: 38}
+
+// MarketDealSnapshot records a deal held by the storage market and its state at a snapshot epoch. Deals that have not
+// yet been included in a proven sector have a sector start epoch of -1. The deal's label is not included and may be
+// found in market_deal_proposals.
+type MarketDealSnapshot struct {
+	Height    int64  `pg:",pk,notnull,use_zero"`
+	DealID    uint64 `pg:",pk,use_zero"`
+	StateRoot string `pg:",pk,notnull"`
+
+	PieceCID          string `pg:",notnull"`
+	PaddedPieceSize   uint64 `pg:",notnull,use_zero"`
+	UnpaddedPieceSize uint64 `pg:",notnull,use_zero"`
+	IsVerified        bool   `pg:",notnull,use_zero"`
+
+	ClientID   string `pg:",notnull"`
+	ProviderID string `pg:",notnull"`
+
+	StartEpoch int64 `pg:",notnull,use_zero"`
+	EndEpoch   int64 `pg:",notnull,use_zero"`
+
+	StoragePricePerEpoch string `pg:"type:numeric,notnull"`
+	ProviderCollateral   string `pg:"type:numeric,notnull"`
+	ClientCollateral     string `pg:"type:numeric,notnull"`
+
+	SectorStartEpoch int64 `pg:",notnull,use_zero"`
+	LastUpdateEpoch  int64 `pg:",notnull,use_zero"`
+	SlashEpoch       int64 `pg:",notnull,use_zero"`
+}
+
+type MarketDealSnapshotList []*MarketDealSnapshot
+
+func (ml MarketDealSnapshotList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(ml) == 0 || version.Before(dealSnapshotsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MarketDealSnapshotList.Persist", trace.WithAttributes(label.Int("count", len(ml))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "market_deal_snapshots"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
+	return s.PersistModel(ctx, ml)
+}
@@ -9,6 +9,7 @@ import (
 type MarketTaskResult struct {
 	Proposals MarketDealProposals
 	States    MarketDealStates
+	Pieces    MarketDealPieces
 }
 
 func (mtr *MarketTaskResult) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
@@ -18,5 +19,8 @@ func (mtr *MarketTaskResult) Persist(ctx context.Context, s model.StorageBatch,
 	if err := mtr.States.Persist(ctx, s, version); err != nil {
 		return err
 	}
+	if err := mtr.Pieces.Persist(ctx, s, version); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,49 @@
+package miner
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// deadlineSchedulesVersion is the first schema version containing the miner_deadline_schedules table.
+var deadlineSchedulesVersion = model.Version{Major: 1, Patch: 33}
+
+// MinerDeadlineSchedule records when a deadline of a miner that has partitions to prove opens and closes in the
+// miner's current proving period.
+type MinerDeadlineSchedule struct {
+	Height        int64  `pg:",pk,notnull,use_zero"`
+	MinerID       string `pg:",pk,notnull"`
+	StateRoot     string `pg:",pk,notnull"`
+	DeadlineIndex uint64 `pg:",pk,notnull,use_zero"`
+
+	PeriodStart   int64  `pg:",notnull,use_zero"`
+	Open          int64  `pg:",notnull,use_zero"`
+	Close         int64  `pg:",notnull,use_zero"`
+	Challenge     int64  `pg:",notnull,use_zero"`
+	FaultCutoff   int64  `pg:",notnull,use_zero"`
+	Partitions    uint64 `pg:",notnull,use_zero"`
+	LiveSectors   uint64 `pg:",notnull,use_zero"`
+	FaultySectors uint64 `pg:",notnull,use_zero"`
+}
+
+type MinerDeadlineScheduleList []*MinerDeadlineSchedule
+
+func (ml MinerDeadlineScheduleList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(ml) == 0 || version.Before(deadlineSchedulesVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MinerDeadlineScheduleList.Persist")
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "miner_deadline_schedules"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
+	return s.PersistModel(ctx, ml)
+}
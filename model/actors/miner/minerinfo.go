@@ -5,6 +5,7 @@ import (
 
 	"go.opencensus.io/tag"
 	"go.opentelemetry.io/otel/api/global"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
@@ -28,6 +29,66 @@ type MinerInfo struct {
 	MultiAddresses   []string
 
 	SectorSize uint64 `pg:",notnull,use_zero"`
+
+	// WindowPoStProofType is recorded with every change of miner info so that proof types changed by network upgrades
+	// can be seen alongside the sector size.
+	WindowPoStProofType int64 `pg:",use_zero"`
+}
+
+// minerInfoWindowPoStVersion is the first schema version in which miner infos carry the window PoSt proof type.
+var minerInfoWindowPoStVersion = model.Version{Major: 1, Patch: 43}
+
+// MinerInfoV1 is the form of a MinerInfo persisted before the window PoSt proof type was added.
+type MinerInfoV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"miner_infos"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	MinerID   string   `pg:",pk,notnull"`
+	StateRoot string   `pg:",pk,notnull"`
+
+	OwnerID  string `pg:",notnull"`
+	WorkerID string `pg:",notnull"`
+
+	NewWorker         string
+	WorkerChangeEpoch int64 `pg:",notnull,use_zero"`
+
+	ConsensusFaultedElapsed int64 `pg:",notnull,use_zero"`
+
+	PeerID           string
+	ControlAddresses []string
+	MultiAddresses   []string
+
+	SectorSize uint64 `pg:",notnull,use_zero"`
+}
+
+func (m *MinerInfo) AsVersion(version model.Version) (interface{}, bool) {
+	switch version.Major {
+	case 0, 1:
+		if version.Major == 1 && !version.Before(minerInfoWindowPoStVersion) {
+			return m, true
+		}
+
+		if m == nil {
+			return (*MinerInfoV1)(nil), true
+		}
+
+		return &MinerInfoV1{
+			Height:                  m.Height,
+			MinerID:                 m.MinerID,
+			StateRoot:               m.StateRoot,
+			OwnerID:                 m.OwnerID,
+			WorkerID:                m.WorkerID,
+			NewWorker:               m.NewWorker,
+			WorkerChangeEpoch:       m.WorkerChangeEpoch,
+			ConsensusFaultedElapsed: m.ConsensusFaultedElapsed,
+			PeerID:                  m.PeerID,
+			ControlAddresses:        m.ControlAddresses,
+			MultiAddresses:          m.MultiAddresses,
+			SectorSize:              m.SectorSize,
+		}, true
+	default:
+		return nil, false
+	}
 }
 
 func (m *MinerInfo) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
@@ -38,8 +99,13 @@ func (m *MinerInfo) Persist(ctx context.Context, s model.StorageBatch, version m
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vm, ok := m.AsVersion(version)
+	if !ok {
+		return xerrors.Errorf("MinerInfo not supported for schema version %s", version)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, m)
+	return s.PersistModel(ctx, vm)
 }
 
 type MinerInfoList []*MinerInfo
@@ -55,6 +121,17 @@ func (ml MinerInfoList) Persist(ctx context.Context, s model.StorageBatch, versi
 	if len(ml) == 0 {
 		return nil
 	}
+
+	if version.Major != 1 || version.Before(minerInfoWindowPoStVersion) {
+		// Support older versions, but in a non-optimal way
+		for _, m := range ml {
+			if err := m.Persist(ctx, s, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
 	return s.PersistModel(ctx, ml)
 }
@@ -30,6 +30,36 @@ type MinerSectorInfo struct {
 	InitialPledge         string `pg:"type:numeric,notnull"`
 	ExpectedDayReward     string `pg:"type:numeric,notnull"`
 	ExpectedStoragePledge string `pg:"type:numeric,notnull"`
+
+	// SealProof and QAPower are derived the same way for every actors version so queries need not depend on the
+	// network version in force when the sector was committed.
+	SealProof int64  `pg:",use_zero"`
+	QAPower   string `pg:"type:numeric,notnull"`
+}
+
+// minerSectorInfoPowerVersion is the first schema version in which miner sector infos carry a seal proof and power.
+var minerSectorInfoPowerVersion = model.Version{Major: 1, Patch: 9}
+
+// MinerSectorInfoV1 is the form of a MinerSectorInfo persisted in schema 1 before seal proofs and power were added.
+type MinerSectorInfoV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"miner_sector_infos"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	MinerID   string   `pg:",pk,notnull"`
+	SectorID  uint64   `pg:",pk,use_zero"`
+	StateRoot string   `pg:",pk,notnull"`
+
+	SealedCID string `pg:",notnull"`
+
+	ActivationEpoch int64 `pg:",use_zero"`
+	ExpirationEpoch int64 `pg:",use_zero"`
+
+	DealWeight         string `pg:"type:numeric,notnull"`
+	VerifiedDealWeight string `pg:"type:numeric,notnull"`
+
+	InitialPledge         string `pg:"type:numeric,notnull"`
+	ExpectedDayReward     string `pg:"type:numeric,notnull"`
+	ExpectedStoragePledge string `pg:"type:numeric,notnull"`
 }
 
 type MinerSectorInfoV0 struct {
@@ -75,7 +105,28 @@ func (msi *MinerSectorInfo) AsVersion(version model.Version) (interface{}, bool)
 			ExpectedStoragePledge: msi.ExpectedStoragePledge,
 		}, true
 	case 1:
-		return msi, true
+		if !version.Before(minerSectorInfoPowerVersion) {
+			return msi, true
+		}
+
+		if msi == nil {
+			return (*MinerSectorInfoV1)(nil), true
+		}
+
+		return &MinerSectorInfoV1{
+			Height:                msi.Height,
+			MinerID:               msi.MinerID,
+			SectorID:              msi.SectorID,
+			StateRoot:             msi.StateRoot,
+			SealedCID:             msi.SealedCID,
+			ActivationEpoch:       msi.ActivationEpoch,
+			ExpirationEpoch:       msi.ExpirationEpoch,
+			DealWeight:            msi.DealWeight,
+			VerifiedDealWeight:    msi.VerifiedDealWeight,
+			InitialPledge:         msi.InitialPledge,
+			ExpectedDayReward:     msi.ExpectedDayReward,
+			ExpectedStoragePledge: msi.ExpectedStoragePledge,
+		}, true
 	default:
 		return nil, false
 	}
@@ -111,7 +162,7 @@ func (ml MinerSectorInfoList) Persist(ctx context.Context, s model.StorageBatch,
 		return nil
 	}
 
-	if version.Major != 1 {
+	if version.Major != 1 || version.Before(minerSectorInfoPowerVersion) {
 		// Support older versions, but in a non-optimal way
 		for _, m := range ml {
 			if err := m.Persist(ctx, s, version); err != nil {
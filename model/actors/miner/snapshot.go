@@ -0,0 +1,109 @@
+package miner
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// sectorSnapshotsVersion is the first schema version containing the miner sector and precommit snapshot tables.
+var sectorSnapshotsVersion = model.Version{Major: 1, Patch: 37}
+
+// MinerSectorSnapshot records a sector held by a miner at a snapshot epoch. Unlike MinerSectorInfo, which is only
+// recorded when a sector is added or extended, every sector in the miner's state is recorded.
+type MinerSectorSnapshot struct {
+	Height    int64  `pg:",pk,notnull,use_zero"`
+	MinerID   string `pg:",pk,notnull"`
+	SectorID  uint64 `pg:",pk,use_zero"`
+	StateRoot string `pg:",pk,notnull"`
+
+	SealedCID string `pg:",notnull"`
+
+	ActivationEpoch int64 `pg:",use_zero"`
+	ExpirationEpoch int64 `pg:",use_zero"`
+
+	DealWeight         string `pg:"type:numeric,notnull"`
+	VerifiedDealWeight string `pg:"type:numeric,notnull"`
+
+	InitialPledge         string `pg:"type:numeric,notnull"`
+	ExpectedDayReward     string `pg:"type:numeric,notnull"`
+	ExpectedStoragePledge string `pg:"type:numeric,notnull"`
+
+	SealProof int64  `pg:",use_zero"`
+	QAPower   string `pg:"type:numeric,notnull"`
+}
+
+type MinerSectorSnapshotList []*MinerSectorSnapshot
+
+func (ml MinerSectorSnapshotList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(ml) == 0 || version.Before(sectorSnapshotsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MinerSectorSnapshotList.Persist", trace.WithAttributes(label.Int("count", len(ml))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "miner_sector_snapshots"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
+	return s.PersistModel(ctx, ml)
+}
+
+// MinerPreCommitSnapshot records a sector precommitted by a miner at a snapshot epoch.
+type MinerPreCommitSnapshot struct {
+	Height    int64  `pg:",pk,notnull,use_zero"`
+	MinerID   string `pg:",pk,notnull"`
+	SectorID  uint64 `pg:",pk,use_zero"`
+	StateRoot string `pg:",pk,notnull"`
+
+	SealedCID       string `pg:",notnull"`
+	SealRandEpoch   int64  `pg:",use_zero"`
+	ExpirationEpoch int64  `pg:",use_zero"`
+
+	PreCommitDeposit   string `pg:"type:numeric,notnull"`
+	PreCommitEpoch     int64  `pg:",use_zero"`
+	DealWeight         string `pg:"type:numeric,notnull"`
+	VerifiedDealWeight string `pg:"type:numeric,notnull"`
+
+	IsReplaceCapacity      bool
+	ReplaceSectorDeadline  uint64 `pg:",use_zero"`
+	ReplaceSectorPartition uint64 `pg:",use_zero"`
+	ReplaceSectorNumber    uint64 `pg:",use_zero"`
+}
+
+type MinerPreCommitSnapshotList []*MinerPreCommitSnapshot
+
+func (ml MinerPreCommitSnapshotList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(ml) == 0 || version.Before(sectorSnapshotsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MinerPreCommitSnapshotList.Persist", trace.WithAttributes(label.Int("count", len(ml))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "miner_pre_commit_snapshots"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
+	return s.PersistModel(ctx, ml)
+}
+
+// MinerSnapshot holds the sectors and precommits of every miner at a snapshot epoch.
+type MinerSnapshot struct {
+	Sectors    MinerSectorSnapshotList
+	PreCommits MinerPreCommitSnapshotList
+}
+
+func (ms *MinerSnapshot) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if err := ms.Sectors.Persist(ctx, s, version); err != nil {
+		return err
+	}
+	return ms.PreCommits.Persist(ctx, s, version)
+}
@@ -17,6 +17,7 @@ type MinerTaskResult struct {
 	FeeDebtModel             *MinerFeeDebt
 	LockedFundsModel         *MinerLockedFund
 	CurrentDeadlineInfoModel *MinerCurrentDeadlineInfo
+	DeadlineSchedulesModel   MinerDeadlineScheduleList
 	PreCommitsModel          MinerPreCommitInfoList
 	SectorsModel             MinerSectorInfoList
 	SectorEventsModel        MinerSectorEventList
@@ -59,6 +60,11 @@ func (res *MinerTaskResult) Persist(ctx context.Context, s model.StorageBatch, v
 			return err
 		}
 	}
+	if res.DeadlineSchedulesModel != nil {
+		if err := res.DeadlineSchedulesModel.Persist(ctx, s, version); err != nil {
+			return err
+		}
+	}
 	if res.SectorDealsModel != nil {
 		if err := res.SectorDealsModel.Persist(ctx, s, version); err != nil {
 			return err
@@ -92,6 +98,7 @@ type MinerTaskLists struct {
 	FeeDebtModel             MinerFeeDebtList
 	LockedFundsModel         MinerLockedFundsList
 	CurrentDeadlineInfoModel MinerCurrentDeadlineInfoList
+	DeadlineSchedulesModel   MinerDeadlineScheduleList
 	PreCommitsModel          MinerPreCommitInfoList
 	SectorsModel             MinerSectorInfoList
 	SectorEventsModel        MinerSectorEventList
@@ -136,6 +143,11 @@ func (mtl *MinerTaskLists) Persist(ctx context.Context, s model.StorageBatch, ve
 			return err
 		}
 	}
+	if mtl.DeadlineSchedulesModel != nil {
+		if err := mtl.DeadlineSchedulesModel.Persist(ctx, s, version); err != nil {
+			return err
+		}
+	}
 	if mtl.SectorDealsModel != nil {
 		if err := mtl.SectorDealsModel.Persist(ctx, s, version); err != nil {
 			return err
@@ -6,6 +6,7 @@ import (
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
 	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
 )
 
 type MultisigTransaction struct {
@@ -14,6 +15,27 @@ type MultisigTransaction struct {
 	Height        int64  `pg:",pk,notnull,use_zero"`
 	TransactionID int64  `pg:",pk,notnull,use_zero"`
 
+	// Transaction State
+	To       string `pg:",notnull"`
+	Value    string `pg:"type:numeric,notnull"`
+	Method   uint64 `pg:",notnull,use_zero"`
+	Params   []byte
+	Approved []string `pg:",notnull"`
+}
+
+// numericAmountsVersion is the first schema version in which all token amounts are stored as numeric.
+var numericAmountsVersion = model.Version{Major: 1, Patch: 14}
+
+// MultisigTransactionV1 is the form of a MultisigTransaction persisted before schema 1.14, when values were stored
+// as text.
+type MultisigTransactionV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName     struct{} `pg:"multisig_transactions"`
+	MultisigID    string   `pg:",pk,notnull"`
+	StateRoot     string   `pg:",pk,notnull"`
+	Height        int64    `pg:",pk,notnull,use_zero"`
+	TransactionID int64    `pg:",pk,notnull,use_zero"`
+
 	// Transaction State
 	To       string `pg:",notnull"`
 	Value    string `pg:",notnull"`
@@ -22,13 +44,40 @@ type MultisigTransaction struct {
 	Approved []string `pg:",notnull"`
 }
 
+func (m *MultisigTransaction) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(numericAmountsVersion) {
+		return m, true
+	}
+
+	if m == nil {
+		return (*MultisigTransactionV1)(nil), true
+	}
+
+	return &MultisigTransactionV1{
+		MultisigID:    m.MultisigID,
+		StateRoot:     m.StateRoot,
+		Height:        m.Height,
+		TransactionID: m.TransactionID,
+		To:            m.To,
+		Value:         m.Value,
+		Method:        m.Method,
+		Params:        m.Params,
+		Approved:      m.Approved,
+	}, true
+}
+
 func (m *MultisigTransaction) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
 	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "multisig_transactions"))
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vm, ok := m.AsVersion(version)
+	if !ok {
+		return xerrors.Errorf("MultisigTransaction not supported for schema version %s", version)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, m)
+	return s.PersistModel(ctx, vm)
 }
 
 type MultisigTransactionList []*MultisigTransaction
@@ -38,6 +87,16 @@ func (ml MultisigTransactionList) Persist(ctx context.Context, s model.StorageBa
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Before(numericAmountsVersion) {
+		vml := make([]*MultisigTransactionV1, 0, len(ml))
+		for _, m := range ml {
+			vm, _ := m.AsVersion(version)
+			vml = append(vml, vm.(*MultisigTransactionV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vml))
+		return s.PersistModel(ctx, vml)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
 	return s.PersistModel(ctx, ml)
 }
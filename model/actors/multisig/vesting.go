@@ -0,0 +1,44 @@
+package multisig
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// vestingVersion is the first schema version containing the multisig_vesting table.
+var vestingVersion = model.Version{Major: 1, Patch: 26}
+
+// MultisigVesting records the locked and vested balance of a multisig created at genesis with a vesting schedule.
+type MultisigVesting struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{} `pg:"multisig_vesting"`
+	Height     int64    `pg:",pk,notnull,use_zero"`
+	StateRoot  string   `pg:",pk,notnull"`
+	MultisigID string   `pg:",pk,notnull"`
+
+	StartEpoch     int64  `pg:",notnull,use_zero"`
+	UnlockDuration int64  `pg:",notnull,use_zero"`
+	InitialBalance string `pg:"type:numeric,notnull"`
+	Balance        string `pg:"type:numeric,notnull"`
+	LockedBalance  string `pg:"type:numeric,notnull"`
+	VestedBalance  string `pg:"type:numeric,notnull"`
+	Available      string `pg:"type:numeric,notnull"`
+}
+
+type MultisigVestingList []*MultisigVesting
+
+func (l MultisigVestingList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(vestingVersion) {
+		return nil
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "multisig_vesting"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
@@ -0,0 +1,58 @@
+package verifreg
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// governanceVersion is the first schema version containing the verifreg_governance table.
+var governanceVersion = model.Version{Major: 1, Patch: 47}
+
+// Governance events recorded for the verified registry.
+const (
+	RootKeyChanged          = "ROOT_KEY_CHANGED"          // the root key was set at genesis or replaced
+	VerifierAdded           = "VERIFIER_ADDED"            // the root key added a verifier
+	VerifierRemoved         = "VERIFIER_REMOVED"          // the root key removed a verifier
+	VerifierDataCapIncrease = "VERIFIER_DATACAP_INCREASE" // the root key increased the datacap allowance of a verifier
+)
+
+// A VerifiedRegistryGovernance records a change to the verified registry made by its root key: a change of the root key
+// itself or of the set of verifiers and their allowances. Decreases in the allowance of a verifier are not recorded
+// since they are made by the verifier granting datacap to clients.
+type VerifiedRegistryGovernance struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName    struct{} `pg:"verifreg_governance"`
+	Height       int64    `pg:",pk,notnull,use_zero"`
+	StateRoot    string   `pg:",pk,notnull"`
+	Event        string   `pg:",pk,notnull"`
+	Address      string   `pg:",pk,notnull"` // verifier affected, or the new root key
+	ParentHeight int64    `pg:",notnull,use_zero"`
+	RootKey      string   `pg:",notnull"`
+
+	DataCap         string `pg:"type:numeric"` // allowance of the verifier after the change
+	PreviousDataCap string `pg:"type:numeric"` // allowance of the verifier before the change
+}
+
+type VerifiedRegistryGovernanceList []*VerifiedRegistryGovernance
+
+func (l VerifiedRegistryGovernanceList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(governanceVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "VerifiedRegistryGovernanceList.Persist", trace.WithAttributes(label.Int("count", len(l))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "verifreg_governance"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
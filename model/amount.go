@@ -0,0 +1,14 @@
+package model
+
+import (
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+// TokenAmount returns the decimal form of a token amount for persisting to a numeric column. The zero value of
+// big.Int has no underlying integer and is persisted as zero.
+func TokenAmount(v big.Int) string {
+	if v.Int == nil {
+		return "0"
+	}
+	return v.String()
+}
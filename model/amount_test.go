@@ -0,0 +1,18 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenAmount(t *testing.T) {
+	assert.Equal(t, "0", TokenAmount(big.Int{}))
+	assert.Equal(t, "0", TokenAmount(big.Zero()))
+	assert.Equal(t, "1000000000000000000", TokenAmount(big.NewInt(1_000_000_000_000_000_000)))
+
+	supply, err := big.FromString("2000000000000000000000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, "2000000000000000000000000000", TokenAmount(supply))
+}
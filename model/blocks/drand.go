@@ -26,7 +26,7 @@ func NewDrandBlockEntries(header *types.BlockHeader) DrandBlockEntries {
 
 type DrandBlockEntrie struct {
 	Round uint64 `pg:",pk,use_zero"`
-	Block string `pg:",notnull"`
+	Block string `pg:",pk,notnull"`
 }
 
 func (dbe *DrandBlockEntrie) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
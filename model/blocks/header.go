@@ -8,6 +8,7 @@ import (
 	"go.opentelemetry.io/otel/api/global"
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
@@ -18,7 +19,7 @@ type BlockHeader struct {
 	Cid             string `pg:",pk,notnull"`
 	Miner           string `pg:",notnull"`
 	ParentWeight    string `pg:",notnull"`
-	ParentBaseFee   string `pg:",notnull"`
+	ParentBaseFee   string `pg:"type:numeric,notnull"`
 	ParentStateRoot string `pg:",notnull"`
 
 	WinCount      int64  `pg:",use_zero"`
@@ -26,12 +27,58 @@ type BlockHeader struct {
 	ForkSignaling uint64 `pg:",use_zero"`
 }
 
+// ConflictTargets allows block headers to be upserted into a table keyed by cid alone, without the height.
+func (bh *BlockHeader) ConflictTargets() [][]string {
+	return [][]string{{"cid"}}
+}
+
+// numericAmountsVersion is the first schema version in which all token amounts are stored as numeric.
+var numericAmountsVersion = model.Version{Major: 1, Patch: 14}
+
+// BlockHeaderV1 is the form of a BlockHeader persisted before schema 1.14, when the parent base fee was stored as text.
+type BlockHeaderV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName       struct{} `pg:"block_headers"`
+	Height          int64    `pg:",pk,use_zero,notnull"`
+	Cid             string   `pg:",pk,notnull"`
+	Miner           string   `pg:",notnull"`
+	ParentWeight    string   `pg:",notnull"`
+	ParentBaseFee   string   `pg:",notnull"`
+	ParentStateRoot string   `pg:",notnull"`
+
+	WinCount      int64  `pg:",use_zero"`
+	Timestamp     uint64 `pg:",use_zero"`
+	ForkSignaling uint64 `pg:",use_zero"`
+}
+
+func (bh *BlockHeader) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(numericAmountsVersion) {
+		return bh, true
+	}
+
+	if bh == nil {
+		return (*BlockHeaderV1)(nil), true
+	}
+
+	return &BlockHeaderV1{
+		Height:          bh.Height,
+		Cid:             bh.Cid,
+		Miner:           bh.Miner,
+		ParentWeight:    bh.ParentWeight,
+		ParentBaseFee:   bh.ParentBaseFee,
+		ParentStateRoot: bh.ParentStateRoot,
+		WinCount:        bh.WinCount,
+		Timestamp:       bh.Timestamp,
+		ForkSignaling:   bh.ForkSignaling,
+	}, true
+}
+
 func NewBlockHeader(bh *types.BlockHeader) *BlockHeader {
 	return &BlockHeader{
 		Cid:             bh.Cid().String(),
 		Miner:           bh.Miner.String(),
 		ParentWeight:    bh.ParentWeight.String(),
-		ParentBaseFee:   bh.ParentBaseFee.String(),
+		ParentBaseFee:   model.TokenAmount(bh.ParentBaseFee),
 		ParentStateRoot: bh.ParentStateRoot.String(),
 		Height:          int64(bh.Height),
 		WinCount:        bh.ElectionProof.WinCount,
@@ -45,8 +92,13 @@ func (bh *BlockHeader) Persist(ctx context.Context, s model.StorageBatch, versio
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vbh, ok := bh.AsVersion(version)
+	if !ok {
+		return xerrors.Errorf("BlockHeader not supported for schema version %s", version)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, bh)
+	return s.PersistModel(ctx, vbh)
 }
 
 type BlockHeaders []*BlockHeader
@@ -62,6 +114,16 @@ func (bhl BlockHeaders) Persist(ctx context.Context, s model.StorageBatch, versi
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Before(numericAmountsVersion) {
+		vbhl := make([]*BlockHeaderV1, 0, len(bhl))
+		for _, bh := range bhl {
+			vbh, _ := bh.AsVersion(version)
+			vbhl = append(vbhl, vbh.(*BlockHeaderV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vbhl))
+		return s.PersistModel(ctx, vbhl)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(bhl))
 	return s.PersistModel(ctx, bhl)
 }
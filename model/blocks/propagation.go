@@ -0,0 +1,40 @@
+package blocks
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// blockPropagationVersion is the first schema version containing the observed_block_propagation table.
+var blockPropagationVersion = model.Version{Major: 1, Patch: 16}
+
+// An ObservedBlockPropagation records when a block was first received from the network by an observer.
+type ObservedBlockPropagation struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{}  `pg:"observed_block_propagation"`
+	Height     int64     `pg:",pk,notnull,use_zero"`
+	Cid        string    `pg:",pk,notnull"`
+	Observer   string    `pg:",pk,notnull"`
+	Miner      string    `pg:",notnull"`
+	PeerID     string    `pg:",notnull"`
+	BlockTime  time.Time `pg:",notnull"`
+	ReceivedAt time.Time `pg:",notnull"`
+}
+
+func (o *ObservedBlockPropagation) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(blockPropagationVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "observed_block_propagation"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, o)
+}
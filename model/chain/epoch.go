@@ -0,0 +1,45 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// epochTimestampsVersion is the first schema version containing the epoch_timestamps table.
+var epochTimestampsVersion = model.Version{Major: 1, Patch: 6}
+
+// An EpochTimestamp maps an epoch to the time at which it began.
+type EpochTimestamp struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{}  `pg:"epoch_timestamps"`
+	Height    int64     `pg:",pk,notnull,use_zero"`
+	Timestamp time.Time `pg:",notnull"`
+}
+
+// NewEpochTimestamp returns the timestamp of the epoch of ts. Block timestamps are required by consensus to equal the
+// genesis time plus the height multiplied by the block delay so any block in the tipset can be used.
+func NewEpochTimestamp(ts *types.TipSet) *EpochTimestamp {
+	return &EpochTimestamp{
+		Height:    int64(ts.Height()),
+		Timestamp: time.Unix(int64(ts.MinTimestamp()), 0).UTC(),
+	}
+}
+
+func (e *EpochTimestamp) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(epochTimestampsVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "epoch_timestamps"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, e)
+}
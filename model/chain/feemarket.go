@@ -0,0 +1,47 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// feeMarketObservationsVersion is the first schema version containing the fee_market_observations table.
+var feeMarketObservationsVersion = model.Version{Major: 1, Patch: 48}
+
+// A FeeMarketObservation compares the gas premiums of the messages included in a tipset with those of the messages
+// still pending in the message pool of the node a lens is connected to when the tipset became its head.
+type FeeMarketObservation struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{}  `pg:"fee_market_observations"`
+	Height     int64     `pg:",pk,notnull,use_zero"`
+	Observer   string    `pg:",pk,notnull"`
+	ObservedAt time.Time `pg:",notnull"`
+	StateRoot  string    `pg:",notnull"`
+	BaseFee    string    `pg:"type:numeric,notnull"` // base fee paid by the messages included in the tipset
+
+	IncludedMessages      int64  `pg:",use_zero,notnull"`
+	IncludedMinPremium    string `pg:"type:numeric"` // null if no messages were included
+	IncludedMedianPremium string `pg:"type:numeric"`
+
+	PendingMessages      int64  `pg:",use_zero,notnull"`
+	PendingMinPremium    string `pg:"type:numeric"` // null if no messages were pending
+	PendingMedianPremium string `pg:"type:numeric"`
+}
+
+func (o *FeeMarketObservation) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(feeMarketObservationsVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "fee_market_observations"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, o)
+}
@@ -0,0 +1,53 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// observedNodeSyncsVersion is the first schema version containing the observed_node_syncs table.
+var observedNodeSyncsVersion = model.Version{Major: 1, Patch: 45}
+
+// An ObservedNodeSync records the sync state of the node a lens is connected to, as observed at a point in time.
+type ObservedNodeSync struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{}  `pg:"observed_node_syncs"`
+	ObservedAt time.Time `pg:",pk,notnull"`
+	Observer   string    `pg:",pk,notnull"`
+
+	HeadHeight    int64     `pg:",use_zero,notnull"`
+	HeadTimestamp time.Time `pg:",notnull"`
+	Behind        int64     `pg:",use_zero,notnull"` // epochs between the expected current epoch and the head
+
+	Workers []NodeSyncWorker `pg:",type:jsonb,notnull"`
+}
+
+// A NodeSyncWorker is the state of one of the node's sync workers.
+type NodeSyncWorker struct {
+	WorkerID     uint64     `json:"worker_id"`
+	Stage        string     `json:"stage"`
+	BaseHeight   int64      `json:"base_height"`
+	TargetHeight int64      `json:"target_height"`
+	Height       int64      `json:"height"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+}
+
+func (o *ObservedNodeSync) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(observedNodeSyncsVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "observed_node_syncs"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, o)
+}
@@ -0,0 +1,39 @@
+package chain
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// systemBalancesVersion is the first schema version containing the chain_system_balances table.
+var systemBalancesVersion = model.Version{Major: 1, Patch: 32}
+
+// SystemBalance records the balance of a system actor in a tipset's parent state.
+type SystemBalance struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"chain_system_balances"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	StateRoot string   `pg:",pk,notnull"`
+	ActorName string   `pg:",pk,notnull"`
+
+	ActorID string `pg:",notnull"`
+	Balance string `pg:"type:numeric,notnull"`
+}
+
+type SystemBalanceList []*SystemBalance
+
+func (l SystemBalanceList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(systemBalancesVersion) {
+		return nil
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "chain_system_balances"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
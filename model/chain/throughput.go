@@ -0,0 +1,45 @@
+package chain
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// throughputVersion is the first schema version containing the chain_throughput table.
+var throughputVersion = model.Version{Major: 1, Patch: 27}
+
+// ChainThroughput summarises the messages included in the blocks of a tipset and how much of the available block
+// space they used.
+type ChainThroughput struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"chain_throughput"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	StateRoot string   `pg:",pk,notnull"`
+
+	BlockCount           int64 `pg:",use_zero,notnull"`
+	BlsMessageCount      int64 `pg:",use_zero,notnull"`
+	SecpMessageCount     int64 `pg:",use_zero,notnull"`
+	IncludedMessageCount int64 `pg:",use_zero,notnull"`
+	MessageBytes         int64 `pg:",use_zero,notnull"`
+	IncludedMessageBytes int64 `pg:",use_zero,notnull"`
+	GasLimitUniqueTotal  int64 `pg:",use_zero,notnull"`
+	GasLimitCapacity     int64 `pg:",use_zero,notnull"`
+
+	Utilization float64 `pg:",use_zero,notnull"`
+}
+
+func (c *ChainThroughput) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(throughputVersion) {
+		return nil
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "chain_throughput"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, c)
+}
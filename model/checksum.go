@@ -0,0 +1,74 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"golang.org/x/xerrors"
+)
+
+// Checksum returns a hex encoded sha256 hash of models, which are typically those collected by a Collector. Models
+// are hashed in the order given, with lists sorted in the same way as when they are written to storage, so two
+// instances that extract identical data produce the same checksum.
+func Checksum(models []interface{}) (string, error) {
+	h := sha256.New()
+	for _, m := range models {
+		if err := hashModel(h, m); err != nil {
+			return "", xerrors.Errorf("hash models: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashModel(h hashWriter, m interface{}) error {
+	if rm, ok := m.(RawModel); ok {
+		data, err := json.Marshal(struct {
+			Table   string
+			Columns []string
+			Rows    [][]*string
+		}{rm.RawTable(), rm.RawColumns(), rm.RawRows()})
+		if err != nil {
+			return xerrors.Errorf("marshal %T: %w", m, err)
+		}
+		return writeHash(h, rm.RawTable(), data)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(m))
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		SortModels(m)
+		for i := 0; i < value.Len(); i++ {
+			if err := hashModel(h, value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if value.Kind() != reflect.Struct {
+		return xerrors.Errorf("unsupported model type %T", m)
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return xerrors.Errorf("marshal %T: %w", m, err)
+	}
+
+	// Include the type of the model so identical values in different tables hash differently
+	return writeHash(h, value.Type().String(), data)
+}
+
+type hashWriter interface {
+	Write(p []byte) (int, error)
+}
+
+func writeHash(h hashWriter, kind string, data []byte) error {
+	if _, err := fmt.Fprintf(h, "%s:%d:", kind, len(data)); err != nil {
+		return err
+	}
+	_, err := h.Write(data)
+	return err
+}
@@ -0,0 +1,70 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/actors/common"
+)
+
+func collect(t *testing.T, p model.Persistable) *model.Collector {
+	c := model.NewCollector(nil)
+	require.NoError(t, p.Persist(context.Background(), c, model.Version{Major: 1, Patch: 49}))
+	return c
+}
+
+func TestChecksum(t *testing.T) {
+	a := common.ActorList{
+		{Height: 1, ID: "f01", StateRoot: "root", Code: "fil/3/account", Head: "head1", Balance: "10"},
+		{Height: 1, ID: "f02", StateRoot: "root", Code: "fil/3/account", Head: "head2", Balance: "20"},
+	}
+	b := common.ActorList{a[1], a[0]}
+
+	sumA, err := model.Checksum(collect(t, a).Models())
+	require.NoError(t, err)
+	sumB, err := model.Checksum(collect(t, b).Models())
+	require.NoError(t, err)
+	assert.Equal(t, sumA, sumB, "checksum should not depend on the order models were extracted")
+
+	c := common.ActorList{
+		{Height: 1, ID: "f01", StateRoot: "root", Code: "fil/3/account", Head: "head1", Balance: "10"},
+		{Height: 1, ID: "f02", StateRoot: "root", Code: "fil/3/account", Head: "head2", Balance: "21"},
+	}
+	sumC, err := model.Checksum(collect(t, c).Models())
+	require.NoError(t, err)
+	assert.NotEqual(t, sumA, sumC, "checksum should change when data changes")
+}
+
+func TestCollector(t *testing.T) {
+	actors := common.ActorList{
+		{Height: 1, ID: "f01", StateRoot: "root"},
+		{Height: 1, ID: "f02", StateRoot: "root"},
+	}
+	deletion := &common.ActorDeletion{Height: 1, ID: "f03", StateRoot: "root"}
+
+	c := collect(t, model.PersistableList{actors, deletion})
+	assert.Equal(t, 3, c.Rows())
+	assert.Len(t, c.Models(), 2)
+
+	var each []interface{}
+	c.Each(func(m interface{}) { each = append(each, m) })
+	assert.Equal(t, []interface{}{actors[0], actors[1], deletion}, each)
+}
+
+type failingBatch struct{}
+
+func (failingBatch) PersistModel(ctx context.Context, m interface{}) error {
+	return assert.AnError
+}
+
+func TestCollectorKeepsOnlyPersistedModels(t *testing.T) {
+	c := model.NewCollector(failingBatch{})
+	err := c.PersistModel(context.Background(), &common.ActorDeletion{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Empty(t, c.Models())
+	assert.Equal(t, 0, c.Rows())
+}
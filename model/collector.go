@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+var _ StorageBatch = (*Collector)(nil)
+
+// A Collector is a StorageBatch that passes each model on to another batch and keeps the models that were persisted
+// successfully, so the data written to storage can be counted, hashed or inspected without persisting it a second
+// time.
+type Collector struct {
+	batch StorageBatch
+
+	mu     sync.Mutex
+	models []interface{}
+}
+
+// NewCollector returns a Collector that persists models to batch. A nil batch collects the models without persisting
+// them.
+func NewCollector(batch StorageBatch) *Collector {
+	return &Collector{batch: batch}
+}
+
+func (c *Collector) PersistModel(ctx context.Context, m interface{}) error {
+	if c.batch != nil {
+		if err := c.batch.PersistModel(ctx, m); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.models = append(c.models, m)
+	c.mu.Unlock()
+	return nil
+}
+
+// Models returns the models persisted through the collector in the order they were persisted. Lists are returned as
+// they were passed to PersistModel.
+func (c *Collector) Models() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]interface{}(nil), c.models...)
+}
+
+// Each calls fn with every individual model persisted through the collector, including each element of a list.
+// Raw models are passed as they are.
+func (c *Collector) Each(fn func(m interface{})) {
+	for _, m := range c.Models() {
+		if _, ok := m.(RawModel); ok {
+			fn(m)
+			continue
+		}
+		value := reflect.Indirect(reflect.ValueOf(m))
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			for i := 0; i < value.Len(); i++ {
+				fn(value.Index(i).Interface())
+			}
+			continue
+		}
+		fn(m)
+	}
+}
+
+// Rows returns the number of rows held by the models persisted through the collector.
+func (c *Collector) Rows() int {
+	rows := 0
+	for _, m := range c.Models() {
+		if rm, ok := m.(RawModel); ok {
+			rows += len(rm.RawRows())
+			continue
+		}
+		value := reflect.Indirect(reflect.ValueOf(m))
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			rows += value.Len()
+			continue
+		}
+		rows++
+	}
+	return rows
+}
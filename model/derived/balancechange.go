@@ -0,0 +1,21 @@
+package derived
+
+import (
+	"time"
+)
+
+// ActorBalanceChange is the part of the change in an actor's balance at an epoch that was caused by one message or by
+// the implicit messages of that epoch. Rows are computed in the database from the derived gas outputs and internal
+// messages extracted by other tasks, see storage.NewActorBalanceChangeAggregator.
+type ActorBalanceChange struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{} `pg:"actor_balance_changes"`
+	Height     int64    `pg:",pk,use_zero,notnull"`
+	Address    string   `pg:",pk,notnull"`
+	Kind       string   `pg:",pk,notnull"`
+	MessageCid string   `pg:",pk,use_zero,notnull"`
+
+	Amount string `pg:"type:numeric,notnull"`
+
+	UpdatedAt time.Time `pg:",notnull"`
+}
@@ -0,0 +1,27 @@
+package derived
+
+import (
+	"time"
+)
+
+// VerifiedClientDatacapUsage summarises the datacap granted to and used by a verified client during a UTC day. Rows
+// are computed in the database from the verified registry messages and deal proposals extracted by other tasks, see
+// storage.NewDatacapUsageAggregator.
+type VerifiedClientDatacapUsage struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{}  `pg:"verified_client_datacap_usage"`
+	Day       time.Time `pg:",pk,type:date,notnull"`
+	ClientID  string    `pg:",pk,notnull"`
+
+	AllowanceGranted string `pg:"type:numeric,notnull"`
+	DatacapUsed      string `pg:"type:numeric,notnull"`
+	VerifiedDeals    int64  `pg:",use_zero,notnull"`
+
+	CumulativeGranted string `pg:"type:numeric,notnull"`
+	CumulativeUsed    string `pg:"type:numeric,notnull"`
+	Remaining         string `pg:"type:numeric,notnull"`
+
+	FromHeight int64     `pg:",use_zero,notnull"`
+	ToHeight   int64     `pg:",use_zero,notnull"`
+	UpdatedAt  time.Time `pg:",notnull"`
+}
@@ -0,0 +1,23 @@
+package derived
+
+import (
+	"time"
+)
+
+// MarketDealEdge aggregates the storage deals made between a client and a provider. Rows are computed in the database
+// from the deal proposals extracted by the market task, see storage.NewMarketDealEdgeAggregator.
+type MarketDealEdge struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{} `pg:"market_deal_edges"`
+	ClientID   string   `pg:",pk,notnull"`
+	ProviderID string   `pg:",pk,notnull"`
+
+	DealCount     int64  `pg:",use_zero,notnull"`
+	TotalBytes    string `pg:"type:numeric,notnull"`
+	VerifiedBytes string `pg:"type:numeric,notnull"`
+	TotalPrice    string `pg:"type:numeric,notnull"`
+
+	FirstHeight int64     `pg:",use_zero,notnull"`
+	LastHeight  int64     `pg:",use_zero,notnull"`
+	UpdatedAt   time.Time `pg:",notnull"`
+}
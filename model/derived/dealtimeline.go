@@ -0,0 +1,43 @@
+package derived
+
+import (
+	"time"
+)
+
+// States of a deal recorded in a MarketDealTimeline.
+const (
+	DealPublished = "published" // proposal published but the deal has not been activated
+	DealActive    = "active"    // deal activated in a sector
+	DealSlashed   = "slashed"   // deal terminated early because its sector was terminated
+	DealExpired   = "expired"   // deal reached its end epoch after being activated
+	DealTimedOut  = "timed_out" // deal reached its start epoch without being activated
+)
+
+// MarketDealTimeline records the epochs at which a deal moved between states so its lifecycle can be read from a
+// single row. Rows are computed in the database from the deal proposals and deal states extracted by the market task,
+// see storage.NewDealTimelineAggregator.
+type MarketDealTimeline struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"market_deal_timelines"`
+	DealID    uint64   `pg:",pk,use_zero"`
+
+	ClientID        string `pg:",notnull"`
+	ProviderID      string `pg:",notnull"`
+	PieceCID        string `pg:",notnull"`
+	PaddedPieceSize uint64 `pg:",use_zero,notnull"`
+	IsVerified      bool   `pg:",use_zero,notnull"`
+	StartEpoch      int64  `pg:",use_zero,notnull"`
+	EndEpoch        int64  `pg:",use_zero,notnull"`
+
+	PublishedHeight  int64 `pg:",use_zero,notnull"`
+	ActivatedHeight  *int64
+	SectorStartEpoch *int64
+	SlashedHeight    *int64
+	SlashEpoch       *int64
+
+	State      string `pg:",notnull"`
+	EndedEpoch *int64
+
+	LastHeight int64     `pg:",use_zero,notnull"`
+	UpdatedAt  time.Time `pg:",notnull"`
+}
@@ -0,0 +1,30 @@
+package derived
+
+import (
+	"time"
+)
+
+// MinerDailyStats summarises the activity of a miner during a UTC day. Rows are computed in the database from the
+// power claims, sector events and internal messages extracted by other tasks, see storage.NewMinerDailyStatsAggregator.
+type MinerDailyStats struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{}  `pg:"miner_daily_stats"`
+	Day       time.Time `pg:",pk,type:date,notnull"`
+	MinerID   string    `pg:",pk,notnull"`
+
+	RawBytePower         string `pg:"type:numeric,notnull"`
+	QualityAdjPower      string `pg:"type:numeric,notnull"`
+	RawBytePowerDelta    string `pg:"type:numeric,notnull"`
+	QualityAdjPowerDelta string `pg:"type:numeric,notnull"`
+
+	SectorsAdded      int64 `pg:",use_zero,notnull"`
+	SectorsFaulted    int64 `pg:",use_zero,notnull"`
+	SectorsTerminated int64 `pg:",use_zero,notnull"`
+
+	Rewards   string `pg:"type:numeric,notnull"`
+	Penalties string `pg:"type:numeric,notnull"`
+
+	FromHeight int64     `pg:",use_zero,notnull"`
+	ToHeight   int64     `pg:",use_zero,notnull"`
+	UpdatedAt  time.Time `pg:",notnull"`
+}
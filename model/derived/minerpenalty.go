@@ -0,0 +1,24 @@
+package derived
+
+import (
+	"time"
+)
+
+// MinerPenalty totals the penalties charged to a miner at an epoch. Rows are computed in the database from the
+// internal messages, messages, sector events and fee debts extracted by other tasks, see
+// storage.NewMinerPenaltyAggregator.
+type MinerPenalty struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"miner_penalties"`
+	Height    int64    `pg:",pk,use_zero,notnull"`
+	MinerID   string   `pg:",pk,notnull"`
+
+	FaultFee              string `pg:"type:numeric,notnull"`
+	TerminationFee        string `pg:"type:numeric,notnull"`
+	ConsensusFaultPenalty string `pg:"type:numeric,notnull"`
+	DebtRepaid            string `pg:"type:numeric,notnull"`
+	TotalBurnt            string `pg:"type:numeric,notnull"`
+	FeeDebtChange         string `pg:"type:numeric,notnull"`
+
+	UpdatedAt time.Time `pg:",notnull"`
+}
@@ -0,0 +1,22 @@
+package derived
+
+import (
+	"time"
+)
+
+// MinerWinStats compares the number of blocks a miner was expected to win during a window of epochs, given its share
+// of network power, with the number it actually won. Rows are computed in the database from the power claims and block
+// headers extracted by other tasks, see storage.NewMinerWinStatsAggregator.
+type MinerWinStats struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName    struct{} `pg:"miner_win_stats"`
+	Height       int64    `pg:",pk,notnull,use_zero"`
+	WindowEpochs int64    `pg:",pk,notnull,use_zero"`
+	MinerID      string   `pg:",pk,notnull"`
+
+	ExpectedWins float64 `pg:",use_zero,notnull"`
+	ActualWins   int64   `pg:",use_zero,notnull"`
+	Blocks       int64   `pg:",use_zero,notnull"`
+
+	UpdatedAt time.Time `pg:",notnull"`
+}
@@ -0,0 +1,35 @@
+package derived
+
+import (
+	"time"
+)
+
+// MinerSectorLifetime tracks a sector from activation to expiration or termination. Rows are computed in the
+// database from the sector events and sector infos extracted by the miner task, see
+// storage.NewSectorLifetimeAggregator.
+type MinerSectorLifetime struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"miner_sector_lifetimes"`
+	MinerID   string   `pg:",pk,notnull"`
+	SectorID  uint64   `pg:",pk,use_zero"`
+
+	AddedHeight     int64 `pg:",use_zero,notnull"`
+	ActivationEpoch int64 `pg:",use_zero,notnull"`
+	ExpirationEpoch int64 `pg:",use_zero,notnull"`
+	PlannedDuration int64 `pg:",use_zero,notnull"`
+
+	ExtendedHeight   *int64
+	ExpiredHeight    *int64
+	TerminatedHeight *int64
+	ActualDuration   *int64
+	TerminatedEarly  bool `pg:",use_zero,notnull"`
+
+	InitialPledge               string  `pg:"type:numeric,notnull"`
+	ExpectedDayReward           string  `pg:"type:numeric,notnull"`
+	ExpectedStoragePledge       string  `pg:"type:numeric,notnull"`
+	QAPower                     string  `pg:"type:numeric,notnull"`
+	EstimatedTerminationPenalty *string `pg:"type:numeric"`
+
+	LastEventHeight int64     `pg:",use_zero,notnull"`
+	UpdatedAt       time.Time `pg:",notnull"`
+}
@@ -19,11 +19,39 @@ type Storage interface {
 	PersistBatch(ctx context.Context, ps ...Persistable) error
 }
 
+// A TaskStorage persists a batch of models extracted by a named task. Storage that organises data by the task that
+// extracted it implements TaskStorage so the task is passed with the batch rather than inferred from it.
+type TaskStorage interface {
+	PersistTaskBatch(ctx context.Context, task string, ps ...Persistable) error
+}
+
+// A ReorgStorage can flag previously persisted data as belonging to a tipset that is no longer part of the
+// canonical chain. The tipset is identified by its height, parent state root and the CIDs of its blocks.
+type ReorgStorage interface {
+	MarkNonCanonical(ctx context.Context, height int64, stateRoot string, blocks []string) error
+}
+
 // A StorageBatch persists a model to storage as part of a batch such as a transaction.
 type StorageBatch interface {
 	PersistModel(ctx context.Context, m interface{}) error
 }
 
+// A RawModel may be passed to StorageBatch.PersistModel in place of a go-pg model. It holds rows for a table whose
+// structure is not known at compile time, such as a table owned by an extractor plugin. Each value is the text form of
+// the column value, or nil for null, and is converted to the column's type by the database.
+type RawModel interface {
+	RawTable() string
+	RawColumns() []string
+	RawRows() [][]*string
+}
+
+// A ConflictTargeter is a model that may be persisted to a table whose rows are identified by columns other than the
+// model's primary key, for example when an operator has partitioned the table or changed its primary key. The schema
+// config selects which of the alternative sets of columns is used as the conflict target of the table.
+type ConflictTargeter interface {
+	ConflictTargets() [][]string
+}
+
 // A Persistable can persist a full copy of itself or its components as part of a storage batch using a specific
 // version of a schema. Persist should call PersistModel on s with a model containing data that should be persisted.
 // ErrUnsupportedSchemaVersion should be retuned if the Persistable cannot provide a model compatible with the requested
@@ -0,0 +1,19 @@
+package labels
+
+import (
+	"time"
+)
+
+// An AddressTag attaches a human readable label to an address, such as the name of an exchange or a well-known miner,
+// so it can be joined with extracted data. Tags are imported with the labels import command rather than extracted from
+// the chain.
+type AddressTag struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{}  `pg:"address_tags"`
+	Address   string    `pg:",pk,notnull"`
+	Label     string    `pg:",pk,notnull"`
+	Category  string    `pg:",use_zero,notnull"`
+	IDAddress string    `pg:",use_zero"`
+	Source    string    `pg:",use_zero,notnull"`
+	UpdatedAt time.Time `pg:",notnull"`
+}
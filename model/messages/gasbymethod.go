@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// gasByMethodVersion is the first schema version containing the message_gas_by_method table.
+var gasByMethodVersion = model.Version{Major: 1, Patch: 21}
+
+// MessageGasByMethod aggregates the gas of the messages in a tipset sent to the same method of the same actor family.
+type MessageGasByMethod struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName   struct{} `pg:"message_gas_by_method"`
+	Height      int64    `pg:",pk,notnull,use_zero"`
+	StateRoot   string   `pg:",pk,notnull"`
+	ActorFamily string   `pg:",pk,notnull"`
+	Method      uint64   `pg:",pk,use_zero"`
+
+	MessageCount int64 `pg:",use_zero,notnull"`
+	GasUsed      int64 `pg:",use_zero,notnull"`
+	GasLimit     int64 `pg:",use_zero,notnull"`
+}
+
+type MessageGasByMethodList []*MessageGasByMethod
+
+func (l MessageGasByMethodList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(gasByMethodVersion) {
+		return nil
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "message_gas_by_method"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
@@ -0,0 +1,53 @@
+package messages
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// messageGasTracesVersion is the first schema version containing the message_gas_traces table.
+var messageGasTracesVersion = model.Version{Major: 1, Patch: 39}
+
+// MessageGasTrace records a single gas charge made while executing a message or one of the calls it made to other
+// actors. Calls are numbered in the order they were made, starting with the message itself.
+type MessageGasTrace struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName   struct{} `pg:"message_gas_traces"`
+	Height      int64    `pg:",pk,notnull,use_zero"`
+	StateRoot   string   `pg:",pk,notnull"`
+	Message     string   `pg:",pk,notnull"`
+	CallIndex   int      `pg:",pk,notnull,use_zero"`
+	ChargeIndex int      `pg:",pk,notnull,use_zero"`
+
+	CallDepth  int    `pg:",notnull,use_zero"`
+	To         string `pg:",notnull"`
+	Method     uint64 `pg:",notnull,use_zero"`
+	Name       string `pg:",notnull"`
+	TotalGas   int64  `pg:",notnull,use_zero"`
+	ComputeGas int64  `pg:",notnull,use_zero"`
+	StorageGas int64  `pg:",notnull,use_zero"`
+}
+
+type MessageGasTraceList []*MessageGasTrace
+
+func (ml MessageGasTraceList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(ml) == 0 || version.Before(messageGasTracesVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MessageGasTraceList.Persist", trace.WithAttributes(label.Int("count", len(ml))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "message_gas_traces"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(ml))
+	return s.PersistModel(ctx, ml)
+}
@@ -29,6 +29,11 @@ type Message struct {
 	Method    uint64 `pg:",use_zero"`
 }
 
+// ConflictTargets allows messages to be upserted into a table keyed by cid alone, without the height.
+func (m *Message) ConflictTargets() [][]string {
+	return [][]string{{"cid"}}
+}
+
 type MessageV0 struct {
 	//lint:ignore U1000 tableName is a convention used by go-pg
 	tableName struct{} `pg:"messages"`
@@ -0,0 +1,45 @@
+package messages
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// messageHeightsVersion is the first schema version containing the message_heights table.
+var messageHeightsVersion = model.Version{Major: 1, Patch: 35}
+
+// MessageHeight records where an executed message was included in the chain and which tipset carries the result of
+// its execution, so a message can be located by CID without scanning block_messages.
+type MessageHeight struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName       struct{} `pg:"message_heights"`
+	Cid             string   `pg:",pk,notnull"`
+	Height          int64    `pg:",pk,notnull,use_zero"`
+	StateRoot       string   `pg:",pk,notnull"`
+	ExecutionHeight int64    `pg:",notnull,use_zero"`
+	ExecutionTipSet string   `pg:"execution_tipset,notnull"`
+}
+
+type MessageHeights []*MessageHeight
+
+func (mhs MessageHeights) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(mhs) == 0 || version.Before(messageHeightsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "MessageHeights.Persist", trace.WithAttributes(label.Int("count", len(mhs))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "message_heights"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(mhs))
+	return s.PersistModel(ctx, mhs)
+}
@@ -21,6 +21,29 @@ type ParsedMessage struct {
 	Value  string `pg:"type:numeric,notnull"`
 	Method string `pg:",notnull"`
 	Params string `pg:",type:jsonb"`
+
+	RawParams []byte
+}
+
+// ConflictTargets allows parsed messages to be upserted into a table keyed by cid alone, without the height.
+func (pm *ParsedMessage) ConflictTargets() [][]string {
+	return [][]string{{"cid"}}
+}
+
+// parsedMessageRawParamsVersion is the first schema version in which parsed messages carry their raw parameters.
+var parsedMessageRawParamsVersion = model.Version{Major: 1, Patch: 11}
+
+// ParsedMessageV1 is the form of a ParsedMessage persisted in schema 1 before raw parameters were added.
+type ParsedMessageV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"parsed_messages"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	Cid       string   `pg:",pk,notnull"`
+	From      string   `pg:",notnull"`
+	To        string   `pg:",notnull"`
+	Value     string   `pg:"type:numeric,notnull"`
+	Method    string   `pg:",notnull"`
+	Params    string   `pg:",type:jsonb"`
 }
 
 type ParsedMessageV0 struct {
@@ -52,7 +75,23 @@ func (pm *ParsedMessage) AsVersion(version model.Version) (interface{}, bool) {
 			Params: pm.Params,
 		}, true
 	case 1:
-		return pm, true
+		if !version.Before(parsedMessageRawParamsVersion) {
+			return pm, true
+		}
+
+		if pm == nil {
+			return (*ParsedMessageV1)(nil), true
+		}
+
+		return &ParsedMessageV1{
+			Height: pm.Height,
+			Cid:    pm.Cid,
+			From:   pm.From,
+			To:     pm.To,
+			Value:  pm.Value,
+			Method: pm.Method,
+			Params: pm.Params,
+		}, true
 	default:
 		return nil, false
 	}
@@ -85,6 +124,16 @@ func (pms ParsedMessages) Persist(ctx context.Context, s model.StorageBatch, ver
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Major == 1 && version.Before(parsedMessageRawParamsVersion) {
+		vpms := make([]*ParsedMessageV1, 0, len(pms))
+		for _, m := range pms {
+			vpm, _ := m.AsVersion(version)
+			vpms = append(vpms, vpm.(*ParsedMessageV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vpms))
+		return s.PersistModel(ctx, vpms)
+	}
+
 	if version.Major != 1 {
 		vpms := make([]interface{}, 0, len(pms))
 		for _, m := range pms {
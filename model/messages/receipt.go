@@ -12,6 +12,9 @@ import (
 	"github.com/filecoin-project/sentinel-visor/model"
 )
 
+// A Receipt records the result of executing a message. Height and StateRoot identify the tipset whose parent state
+// contains the result while InclusionHeight and InclusionStateRoot identify the tipset that included the message, in
+// which it was executed.
 type Receipt struct {
 	Height    int64  `pg:",pk,notnull,use_zero"` // note this is the height of the receipt not the message
 	Message   string `pg:",pk,notnull"`
@@ -20,6 +23,83 @@ type Receipt struct {
 	Idx      int   `pg:",use_zero"`
 	ExitCode int64 `pg:",use_zero"`
 	GasUsed  int64 `pg:",use_zero"`
+
+	RawReturn    []byte
+	ParsedReturn string `pg:",type:jsonb"`
+
+	InclusionHeight    int64  `pg:",use_zero"` // height of the tipset that included the message
+	InclusionStateRoot string // parent state root of the tipset that included the message
+}
+
+// receiptReturnVersion is the first schema version in which receipts carry the value returned by the method.
+var receiptReturnVersion = model.Version{Major: 1, Patch: 11}
+
+// receiptInclusionVersion is the first schema version in which receipts record the tipset that included their message.
+var receiptInclusionVersion = model.Version{Major: 1, Patch: 31}
+
+// ReceiptV1 is the form of a Receipt persisted before return values were added.
+type ReceiptV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"receipts"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	Message   string   `pg:",pk,notnull"`
+	StateRoot string   `pg:",pk,notnull"`
+
+	Idx      int   `pg:",use_zero"`
+	ExitCode int64 `pg:",use_zero"`
+	GasUsed  int64 `pg:",use_zero"`
+}
+
+// ReceiptV2 is the form of a Receipt persisted before the tipset that included the message was recorded.
+type ReceiptV2 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"receipts"`
+	Height    int64    `pg:",pk,notnull,use_zero"`
+	Message   string   `pg:",pk,notnull"`
+	StateRoot string   `pg:",pk,notnull"`
+
+	Idx      int   `pg:",use_zero"`
+	ExitCode int64 `pg:",use_zero"`
+	GasUsed  int64 `pg:",use_zero"`
+
+	RawReturn    []byte
+	ParsedReturn string `pg:",type:jsonb"`
+}
+
+func (r *Receipt) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(receiptInclusionVersion) {
+		return r, true
+	}
+
+	if !version.Before(receiptReturnVersion) {
+		if r == nil {
+			return (*ReceiptV2)(nil), true
+		}
+
+		return &ReceiptV2{
+			Height:       r.Height,
+			Message:      r.Message,
+			StateRoot:    r.StateRoot,
+			Idx:          r.Idx,
+			ExitCode:     r.ExitCode,
+			GasUsed:      r.GasUsed,
+			RawReturn:    r.RawReturn,
+			ParsedReturn: r.ParsedReturn,
+		}, true
+	}
+
+	if r == nil {
+		return (*ReceiptV1)(nil), true
+	}
+
+	return &ReceiptV1{
+		Height:    r.Height,
+		Message:   r.Message,
+		StateRoot: r.StateRoot,
+		Idx:       r.Idx,
+		ExitCode:  r.ExitCode,
+		GasUsed:   r.GasUsed,
+	}, true
 }
 
 func (r *Receipt) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
@@ -27,8 +107,10 @@ func (r *Receipt) Persist(ctx context.Context, s model.StorageBatch, version mod
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vr, _ := r.AsVersion(version)
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, r)
+	return s.PersistModel(ctx, vr)
 }
 
 type Receipts []*Receipt
@@ -44,6 +126,26 @@ func (rs Receipts) Persist(ctx context.Context, s model.StorageBatch, version mo
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Before(receiptReturnVersion) {
+		vrs := make([]*ReceiptV1, 0, len(rs))
+		for _, r := range rs {
+			vr, _ := r.AsVersion(version)
+			vrs = append(vrs, vr.(*ReceiptV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vrs))
+		return s.PersistModel(ctx, vrs)
+	}
+
+	if version.Before(receiptInclusionVersion) {
+		vrs := make([]*ReceiptV2, 0, len(rs))
+		for _, r := range rs {
+			vr, _ := r.AsVersion(version)
+			vrs = append(vrs, vr.(*ReceiptV2))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vrs))
+		return s.PersistModel(ctx, vrs)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(rs))
 	return s.PersistModel(ctx, rs)
 }
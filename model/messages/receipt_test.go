@@ -0,0 +1,43 @@
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+func TestReceiptAsVersion(t *testing.T) {
+	r := &Receipt{
+		Height:             12,
+		Message:            "bafymsg",
+		StateRoot:          "bafychild",
+		ExitCode:           1,
+		RawReturn:          []byte{1},
+		InclusionHeight:    10,
+		InclusionStateRoot: "bafyparent",
+	}
+
+	v, ok := r.AsVersion(model.Version{Major: 1, Patch: 31})
+	require.True(t, ok)
+	assert.Same(t, r, v)
+
+	// Older schemas do not record the tipset that included the message
+	v, ok = r.AsVersion(model.Version{Major: 1, Patch: 30})
+	require.True(t, ok)
+	v2, ok := v.(*ReceiptV2)
+	require.True(t, ok)
+	assert.EqualValues(t, 12, v2.Height)
+	assert.Equal(t, "bafychild", v2.StateRoot)
+	assert.Equal(t, []byte{1}, v2.RawReturn)
+
+	v, ok = r.AsVersion(model.Version{Major: 1, Patch: 10})
+	require.True(t, ok)
+	v1, ok := v.(*ReceiptV1)
+	require.True(t, ok)
+	assert.EqualValues(t, 12, v1.Height)
+	assert.Equal(t, "bafychild", v1.StateRoot)
+	assert.EqualValues(t, 1, v1.ExitCode)
+}
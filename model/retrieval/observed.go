@@ -0,0 +1,85 @@
+package retrieval
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// observedRetrievalEventsVersion is the first schema version containing the observed_retrieval_events table.
+var observedRetrievalEventsVersion = model.Version{Major: 1, Patch: 30}
+
+// Kinds of observed retrieval event.
+const (
+	EventOffer       = "offer"       // the miner offered to serve the retrieval
+	EventUnavailable = "unavailable" // the miner answered that the payload is not available for retrieval
+	EventError       = "error"       // the miner could not be queried or answered with an error
+)
+
+// A RetrievalTarget is the payload of a deal that may be queried for retrieval from the deal's provider.
+type RetrievalTarget struct {
+	DealID     int64
+	ProviderID string
+	PayloadCid string
+	PieceCid   string
+}
+
+// An ObservedRetrievalEvent records the response of a miner to a retrieval query made by an observer.
+type ObservedRetrievalEvent struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName  struct{}  `pg:"observed_retrieval_events"`
+	ObservedAt time.Time `pg:",pk,notnull"`
+	Observer   string    `pg:",pk,notnull"`
+	MinerID    string    `pg:",pk,notnull"`
+	PayloadCid string    `pg:",pk,notnull"`
+	PieceCid   string
+	DealID     int64  `pg:",use_zero,notnull"`
+	Event      string `pg:",notnull"`
+	PeerID     string
+
+	Size                    uint64 `pg:",use_zero"`
+	MinPrice                string `pg:"type:numeric"`
+	UnsealPrice             string `pg:"type:numeric"`
+	PaymentInterval         uint64 `pg:",use_zero"`
+	PaymentIntervalIncrease uint64 `pg:",use_zero"`
+
+	ResponseTimeMs int64 `pg:",use_zero,notnull"`
+	Error          string
+}
+
+func (o *ObservedRetrievalEvent) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(observedRetrievalEventsVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "observed_retrieval_events"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, o)
+}
+
+type ObservedRetrievalEventList []*ObservedRetrievalEvent
+
+func (l ObservedRetrievalEventList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(observedRetrievalEventsVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "ObservedRetrievalEventList.Persist", trace.WithAttributes(label.Int("count", len(l))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "observed_retrieval_events"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
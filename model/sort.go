@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// SortModels sorts a slice of models in place by the values of their primary key fields so that the rows of a batch
+// are always written in the same order, regardless of the order in which they were extracted. m may be a slice or a
+// pointer to a slice. Models with no primary key and values that are not slices are left untouched.
+func SortModels(m interface{}) {
+	value := reflect.Indirect(reflect.ValueOf(m))
+	if value.Kind() != reflect.Slice || value.Len() < 2 {
+		return
+	}
+
+	// go-pg expects pointers to slices. The copy shares the slice's backing array so sorting it sorts the original.
+	if reflect.ValueOf(m).Kind() != reflect.Ptr {
+		p := reflect.New(value.Type())
+		p.Elem().Set(value)
+		m = p.Interface()
+	}
+
+	pks := pg.Model(m).TableModel().Table().PKs
+	if len(pks) == 0 {
+		return
+	}
+
+	sort.Stable(&modelSorter{
+		value: value,
+		pks:   pks,
+		swap:  reflect.Swapper(value.Interface()),
+	})
+}
+
+type modelSorter struct {
+	value reflect.Value
+	pks   []*orm.Field
+	swap  func(i, j int)
+}
+
+func (s *modelSorter) Len() int      { return s.value.Len() }
+func (s *modelSorter) Swap(i, j int) { s.swap(i, j) }
+
+func (s *modelSorter) Less(i, j int) bool {
+	a := reflect.Indirect(s.value.Index(i))
+	b := reflect.Indirect(s.value.Index(j))
+	for _, pk := range s.pks {
+		if c := compareValues(pk.Value(a), pk.Value(b)); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// compareValues returns -1 if a sorts before b, +1 if a sorts after b and 0 if they are equal.
+func compareValues(a, b reflect.Value) int {
+	a = reflect.Indirect(a)
+	b = reflect.Indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return compareBools(a.IsValid(), b.IsValid())
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int() < b.Int(), a.Int() > b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float() < b.Float(), a.Float() > b.Float())
+	case reflect.String:
+		return compareOrdered(a.String() < b.String(), a.String() > b.String())
+	case reflect.Bool:
+		return compareBools(a.Bool(), b.Bool())
+	}
+
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb := b.Interface().(time.Time)
+		return compareOrdered(ta.Before(tb), ta.After(tb))
+	}
+
+	sa, sb := fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface())
+	return compareOrdered(sa < sb, sa > sb)
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	return compareOrdered(!a && b, a && !b)
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sortableModel struct {
+	Height  int64  `pg:",pk,use_zero"`
+	Address string `pg:",pk"`
+	Value   string
+}
+
+func TestSortModels(t *testing.T) {
+	models := []*sortableModel{
+		{Height: 2, Address: "f01", Value: "d"},
+		{Height: 1, Address: "f02", Value: "b"},
+		{Height: 1, Address: "f01", Value: "a"},
+		{Height: 2, Address: "f00", Value: "c"},
+	}
+
+	SortModels(&models)
+
+	values := make([]string, len(models))
+	for i, m := range models {
+		values[i] = m.Value
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, values)
+}
+
+func TestSortModelsWithoutPrimaryKey(t *testing.T) {
+	type unkeyed struct {
+		Value string
+	}
+
+	models := []unkeyed{{Value: "b"}, {Value: "a"}}
+	SortModels(models)
+	assert.Equal(t, []unkeyed{{Value: "b"}, {Value: "a"}}, models)
+}
@@ -0,0 +1,22 @@
+package visor
+
+import (
+	"time"
+)
+
+// A ChainHead records how far a task run by a job has progressed along the chain. The observed head is updated as the
+// job follows the chain head and the indexed tipset is updated in the same transaction that persists the outputs of
+// the task, so the difference between the two is the lag of the task.
+type ChainHead struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"chain_visor_head"`
+
+	Reporter         string `pg:",pk,notnull"`
+	Task             string `pg:",pk,notnull"`
+	ObservedHeight   *int64
+	ObservedTipSet   string `pg:"observed_tipset"`
+	ObservedAt       *time.Time
+	IndexedHeight    *int64
+	IndexedStateRoot string
+	IndexedAt        *time.Time
+}
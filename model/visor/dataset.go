@@ -0,0 +1,35 @@
+package visor
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// A DatasetArchive records a range of heights whose data has been exported to a CAR file.
+type DatasetArchive struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_dataset_archives"`
+
+	FromHeight   int64     `pg:",pk,use_zero,notnull"`
+	ToHeight     int64     `pg:",pk,use_zero,notnull"`
+	Root         string    `pg:",notnull"`
+	Path         string    `pg:",notnull"`
+	SizeBytes    int64     `pg:",use_zero,notnull"`
+	Files        string    `pg:",type:jsonb,notnull"`
+	DealProposal string    // empty if no storage deal was made
+	ArchivedAt   time.Time `pg:",notnull"`
+}
+
+func (a *DatasetArchive) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "visor_dataset_archives"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, a)
+}
@@ -0,0 +1,18 @@
+package visor
+
+import (
+	"time"
+)
+
+// A DeferredIndex is a secondary index that has been dropped while bulk walks write to its table. It is rebuilt from
+// its definition once every walk holding it has finished.
+type DeferredIndex struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_deferred_indexes"`
+
+	IndexName  string    `pg:",pk,notnull"`
+	TableName  string    `pg:",notnull"`
+	Definition string    `pg:",notnull"`
+	Holders    []string  `pg:",array,notnull"`
+	DeferredAt time.Time `pg:",use_zero"`
+}
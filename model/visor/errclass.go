@@ -0,0 +1,93 @@
+package visor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error classes recorded in the ErrorClass column of a processing report. They allow gap filling and alerting to
+// react differently to each kind of failure without parsing error messages.
+const (
+	ErrorClassLensUnavailable = "lens_unavailable" // the lotus node or lens could not be reached
+	ErrorClassStateMissing    = "state_missing"    // state or chain data needed by the task is not in the blockstore
+	ErrorClassDecodeError     = "decode_error"     // chain data could not be decoded
+	ErrorClassDBError         = "db_error"         // extracted data could not be persisted
+	ErrorClassTimeout         = "timeout"          // the task did not complete within its time window
+	ErrorClassUnknown         = "unknown"          // the error did not match any other class
+)
+
+// errorClassPatterns maps fragments of error messages to the class they indicate. Errors that have been flattened
+// to strings, such as those collected by tasks in the ErrorsDetected column, can only be classified this way. The
+// patterns are checked in order so more specific classes come first.
+var errorClassPatterns = []struct {
+	class    string
+	patterns []string
+}{
+	{
+		class:    ErrorClassTimeout,
+		patterns: []string{"context deadline exceeded", "i/o timeout"},
+	},
+	{
+		class:    ErrorClassLensUnavailable,
+		patterns: []string{"open lens", "connection refused", "connection reset", "broken pipe", "websocket", "rpc client error", "handler: websocket connection closed"},
+	},
+	{
+		class:    ErrorClassStateMissing,
+		patterns: []string{"blockstore: block not found", "ipld: could not find", "not found in blockstore", "failed to load state tree", "load state tree", "actor not found"},
+	},
+	{
+		class:    ErrorClassDecodeError,
+		patterns: []string{"cbor", "unmarshal", "decode", "failed to parse", "failed to serialize", "unexpected eof"},
+	},
+	{
+		class:    ErrorClassDBError,
+		patterns: []string{"pg: ", "error #"},
+	},
+}
+
+// ClassifyError returns the error class of err or an empty string if err is nil.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	return ClassifyErrorMessage(err.Error())
+}
+
+// ClassifyErrorMessage returns the error class indicated by an error message.
+func ClassifyErrorMessage(msg string) string {
+	msg = strings.ToLower(msg)
+	for _, ecp := range errorClassPatterns {
+		for _, p := range ecp.patterns {
+			if strings.Contains(msg, p) {
+				return ecp.class
+			}
+		}
+	}
+	return ErrorClassUnknown
+}
+
+// ClassifyErrorsDetected returns the error class of the value recorded in the ErrorsDetected column of a processing
+// report, which may be a single error or a list of task specific error records.
+func ClassifyErrorsDetected(v interface{}) string {
+	switch e := v.(type) {
+	case nil:
+		return ""
+	case error:
+		return ClassifyError(e)
+	case string:
+		return ClassifyErrorMessage(e)
+	default:
+		// Task error records are persisted as json so classify the same representation
+		b, err := json.Marshal(e)
+		if err != nil {
+			return ClassifyErrorMessage(fmt.Sprint(e))
+		}
+		return ClassifyErrorMessage(string(b))
+	}
+}
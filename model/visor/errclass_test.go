@@ -0,0 +1,42 @@
+package visor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "deadline", err: xerrors.Errorf("extract: %w", context.DeadlineExceeded), want: ErrorClassTimeout},
+		{name: "lens", err: xerrors.Errorf("unable to open lens: dial tcp 127.0.0.1:1234: connect: connection refused"), want: ErrorClassLensUnavailable},
+		{name: "state", err: xerrors.Errorf("failed to load state tree: blockstore: block not found"), want: ErrorClassStateMissing},
+		{name: "decode", err: xerrors.Errorf("failed to parse message params: cbor input had wrong number of fields"), want: ErrorClassDecodeError},
+		{name: "db", err: xerrors.Errorf(`ERROR #23505 duplicate key value violates unique constraint`), want: ErrorClassDBError},
+		{name: "other", err: xerrors.Errorf("something unexpected"), want: ErrorClassUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ClassifyError(tc.err))
+		})
+	}
+}
+
+func TestClassifyErrorsDetected(t *testing.T) {
+	type taskError struct {
+		Addr  string
+		Error string
+	}
+
+	assert.Equal(t, "", ClassifyErrorsDetected(nil))
+	assert.Equal(t, ErrorClassDecodeError, ClassifyErrorsDetected([]*taskError{{Addr: "f01", Error: "failed to unmarshal state"}}))
+	assert.Equal(t, ErrorClassTimeout, ClassifyErrorsDetected(xerrors.Errorf("wrapped: %w", context.DeadlineExceeded)))
+}
@@ -0,0 +1,67 @@
+package visor
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// lineageVersion is the first schema version containing the visor_lineage table.
+var lineageVersion = model.Version{Major: 1, Patch: 17}
+
+// A Lineage records which job, and which version of visor, produced the data persisted by a task for a tipset. It
+// shares its key with the ProcessingReport persisted in the same transaction and lists the tables and number of rows
+// written, so rows can be traced back to the extraction that produced them.
+type Lineage struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_lineage"`
+
+	Height    int64     `pg:",pk,use_zero"`
+	StateRoot string    `pg:",pk,notnull"`
+	Reporter  string    `pg:",pk,notnull"`
+	Task      string    `pg:",pk,notnull"`
+	StartedAt time.Time `pg:",pk,use_zero"`
+
+	JobID        int64  `pg:",use_zero,notnull"` // zero if the data was not persisted by a scheduled job
+	JobName      string `pg:",notnull"`
+	VisorVersion string `pg:",notnull"`
+
+	ExtractedAt time.Time      `pg:",notnull"`
+	Tables      map[string]int `pg:",type:jsonb,notnull"` // number of rows written to each table
+}
+
+func (l *Lineage) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(lineageVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "visor_lineage"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, l)
+}
+
+type jobContextKey struct{}
+
+// A Job identifies the job that data is being extracted by.
+type Job struct {
+	ID   int64
+	Name string
+}
+
+// WithJob returns a context that identifies the job running with it, for inclusion in the lineage of persisted data.
+func WithJob(ctx context.Context, job Job) context.Context {
+	return context.WithValue(ctx, jobContextKey{}, job)
+}
+
+// JobFromContext returns the job set by WithJob, or a zero Job if there is none.
+func JobFromContext(ctx context.Context) Job {
+	job, _ := ctx.Value(jobContextKey{}).(Job)
+	return job
+}
@@ -0,0 +1,23 @@
+package visor
+
+import (
+	"context"
+	"time"
+)
+
+// A Network identifies the network that the data held in a schema was extracted from. A schema holds data for at
+// most one network.
+type Network struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_network"`
+
+	Name       string    `pg:",pk,notnull"`
+	Genesis    string    `pg:",pk,notnull"`
+	RecordedAt time.Time `pg:",use_zero"`
+}
+
+// A NetworkStorage can verify that it only holds data extracted from a single network. VerifyNetwork returns an error
+// if the storage already holds data for a network other than the one named with the given genesis block.
+type NetworkStorage interface {
+	VerifyNetwork(ctx context.Context, name string, genesis string) error
+}
@@ -14,12 +14,25 @@ import (
 )
 
 const (
-	ProcessingStatusOK    = "OK"
-	ProcessingStatusInfo  = "INFO"  // Processing was successful but the task reported information in the StatusInformation column
-	ProcessingStatusError = "ERROR" // one or more errors were encountered, data may be incomplete
-	ProcessingStatusSkip  = "SKIP"  // no processing was attempted, a reason may be given in the StatusInformation column
+	ProcessingStatusOK      = "OK"
+	ProcessingStatusInfo    = "INFO"    // Processing was successful but the task reported information in the StatusInformation column
+	ProcessingStatusError   = "ERROR"   // one or more errors were encountered, data may be incomplete
+	ProcessingStatusSkip    = "SKIP"    // no processing was attempted, a reason may be given in the StatusInformation column
+	ProcessingStatusPartial = "PARTIAL" // processing was successful but limited, such as to some types of actor, as given in the StatusInformation column. It does not count as completion of the task.
 )
 
+// A TaskCompletion records that a task completed successfully for the tipset with a parent state root at a height.
+type TaskCompletion struct {
+	Height    int64
+	StateRoot string
+	Task      string
+}
+
+// A ProcessingReport records the outcome of a single task for a single tipset. It is the only bookkeeping written by
+// the indexer and is persisted in the same transaction as the data produced by the task, so a report with an OK or
+// INFO status guarantees the task's data for the tipset is present. Height and StateRoot identify the tipset the task
+// extracted data from: message tasks report against the tipset containing the messages, actor state tasks against
+// the tipset whose parent state contains the changes and all other tasks against the tipset being indexed.
 type ProcessingReport struct {
 	//lint:ignore U1000 tableName is a convention used by go-pg
 	tableName struct{} `pg:"visor_processing_reports"`
@@ -39,6 +52,184 @@ type ProcessingReport struct {
 	Status            string `pg:",notnull"`
 	StatusInformation string
 	ErrorsDetected    interface{} `pg:",type:jsonb"`
+
+	// Checksum is a hash of the data extracted by the task, see model.Checksum
+	Checksum string
+
+	// ErrorClass categorises the errors detected by the task, see the ErrorClass constants
+	ErrorClass string
+
+	// RunID identifies the Run that wrote the report
+	RunID string
+
+	// TipSet is the key of the tipset identified by Height and StateRoot, encoded using EncodeTipSetKey
+	TipSet string `pg:"tipset"`
+}
+
+var (
+	// reportChecksumVersion is the first schema version in which processing reports carry a checksum.
+	reportChecksumVersion = model.Version{Major: 1, Patch: 4}
+
+	// reportErrorClassVersion is the first schema version in which processing reports carry an error class.
+	reportErrorClassVersion = model.Version{Major: 1, Patch: 5}
+
+	// reportRunVersion is the first schema version in which processing reports carry the id of the run that wrote them.
+	reportRunVersion = model.Version{Major: 1, Patch: 41}
+
+	// reportTipSetVersion is the first schema version in which processing reports carry the key of their tipset.
+	reportTipSetVersion = model.Version{Major: 1, Patch: 49}
+)
+
+// ProcessingReportV0 is the form of a ProcessingReport persisted in schema versions before checksums were added.
+type ProcessingReportV0 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_processing_reports"`
+
+	Height            int64     `pg:",pk,use_zero"`
+	StateRoot         string    `pg:",pk,notnull"`
+	Reporter          string    `pg:",pk,notnull"`
+	Task              string    `pg:",pk,notnull"`
+	StartedAt         time.Time `pg:",pk,use_zero"`
+	CompletedAt       time.Time `pg:",use_zero"`
+	Status            string    `pg:",notnull"`
+	StatusInformation string
+	ErrorsDetected    interface{} `pg:",type:jsonb"`
+}
+
+// ProcessingReportV1 is the form of a ProcessingReport persisted in schema versions before error classes were added.
+type ProcessingReportV1 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_processing_reports"`
+
+	Height            int64     `pg:",pk,use_zero"`
+	StateRoot         string    `pg:",pk,notnull"`
+	Reporter          string    `pg:",pk,notnull"`
+	Task              string    `pg:",pk,notnull"`
+	StartedAt         time.Time `pg:",pk,use_zero"`
+	CompletedAt       time.Time `pg:",use_zero"`
+	Status            string    `pg:",notnull"`
+	StatusInformation string
+	ErrorsDetected    interface{} `pg:",type:jsonb"`
+	Checksum          string
+}
+
+// ProcessingReportV2 is the form of a ProcessingReport persisted in schema versions before runs were recorded.
+type ProcessingReportV2 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_processing_reports"`
+
+	Height            int64     `pg:",pk,use_zero"`
+	StateRoot         string    `pg:",pk,notnull"`
+	Reporter          string    `pg:",pk,notnull"`
+	Task              string    `pg:",pk,notnull"`
+	StartedAt         time.Time `pg:",pk,use_zero"`
+	CompletedAt       time.Time `pg:",use_zero"`
+	Status            string    `pg:",notnull"`
+	StatusInformation string
+	ErrorsDetected    interface{} `pg:",type:jsonb"`
+	Checksum          string
+	ErrorClass        string
+}
+
+// ProcessingReportV3 is the form of a ProcessingReport persisted in schema versions before tipsets were recorded.
+type ProcessingReportV3 struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_processing_reports"`
+
+	Height            int64     `pg:",pk,use_zero"`
+	StateRoot         string    `pg:",pk,notnull"`
+	Reporter          string    `pg:",pk,notnull"`
+	Task              string    `pg:",pk,notnull"`
+	StartedAt         time.Time `pg:",pk,use_zero"`
+	CompletedAt       time.Time `pg:",use_zero"`
+	Status            string    `pg:",notnull"`
+	StatusInformation string
+	ErrorsDetected    interface{} `pg:",type:jsonb"`
+	Checksum          string
+	ErrorClass        string
+	RunID             string
+}
+
+func (p *ProcessingReport) AsVersion(version model.Version) (interface{}, bool) {
+	if !version.Before(reportTipSetVersion) {
+		return p, true
+	}
+
+	if !version.Before(reportRunVersion) {
+		if p == nil {
+			return (*ProcessingReportV3)(nil), true
+		}
+
+		return &ProcessingReportV3{
+			Height:            p.Height,
+			StateRoot:         p.StateRoot,
+			Reporter:          p.Reporter,
+			Task:              p.Task,
+			StartedAt:         p.StartedAt,
+			CompletedAt:       p.CompletedAt,
+			Status:            p.Status,
+			StatusInformation: p.StatusInformation,
+			ErrorsDetected:    p.ErrorsDetected,
+			Checksum:          p.Checksum,
+			ErrorClass:        p.ErrorClass,
+			RunID:             p.RunID,
+		}, true
+	}
+
+	if !version.Before(reportErrorClassVersion) {
+		if p == nil {
+			return (*ProcessingReportV2)(nil), true
+		}
+
+		return &ProcessingReportV2{
+			Height:            p.Height,
+			StateRoot:         p.StateRoot,
+			Reporter:          p.Reporter,
+			Task:              p.Task,
+			StartedAt:         p.StartedAt,
+			CompletedAt:       p.CompletedAt,
+			Status:            p.Status,
+			StatusInformation: p.StatusInformation,
+			ErrorsDetected:    p.ErrorsDetected,
+			Checksum:          p.Checksum,
+			ErrorClass:        p.ErrorClass,
+		}, true
+	}
+
+	if !version.Before(reportChecksumVersion) {
+		if p == nil {
+			return (*ProcessingReportV1)(nil), true
+		}
+
+		return &ProcessingReportV1{
+			Height:            p.Height,
+			StateRoot:         p.StateRoot,
+			Reporter:          p.Reporter,
+			Task:              p.Task,
+			StartedAt:         p.StartedAt,
+			CompletedAt:       p.CompletedAt,
+			Status:            p.Status,
+			StatusInformation: p.StatusInformation,
+			ErrorsDetected:    p.ErrorsDetected,
+			Checksum:          p.Checksum,
+		}, true
+	}
+
+	if p == nil {
+		return (*ProcessingReportV0)(nil), true
+	}
+
+	return &ProcessingReportV0{
+		Height:            p.Height,
+		StateRoot:         p.StateRoot,
+		Reporter:          p.Reporter,
+		Task:              p.Task,
+		StartedAt:         p.StartedAt,
+		CompletedAt:       p.CompletedAt,
+		Status:            p.Status,
+		StatusInformation: p.StatusInformation,
+		ErrorsDetected:    p.ErrorsDetected,
+	}, true
 }
 
 func (p *ProcessingReport) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
@@ -46,8 +237,17 @@ func (p *ProcessingReport) Persist(ctx context.Context, s model.StorageBatch, ve
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	vp, _ := p.AsVersion(version)
+
 	metrics.RecordCount(ctx, metrics.PersistModel, 1)
-	return s.PersistModel(ctx, p)
+	return s.PersistModel(ctx, vp)
+}
+
+// A CompletionStorage persists the data produced by a task together with the report recording its completion in a
+// single transaction. PersistCompletion returns false without persisting anything if an earlier report already
+// records the successful completion of the same task for the same tipset.
+type CompletionStorage interface {
+	PersistCompletion(ctx context.Context, report *ProcessingReport, data model.Persistable) (bool, error)
 }
 
 type ProcessingReportList []*ProcessingReport
@@ -63,6 +263,46 @@ func (pl ProcessingReportList) Persist(ctx context.Context, s model.StorageBatch
 	stop := metrics.Timer(ctx, metrics.PersistDuration)
 	defer stop()
 
+	if version.Before(reportChecksumVersion) {
+		vpl := make([]*ProcessingReportV0, 0, len(pl))
+		for _, p := range pl {
+			vp, _ := p.AsVersion(version)
+			vpl = append(vpl, vp.(*ProcessingReportV0))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vpl))
+		return s.PersistModel(ctx, vpl)
+	}
+
+	if version.Before(reportErrorClassVersion) {
+		vpl := make([]*ProcessingReportV1, 0, len(pl))
+		for _, p := range pl {
+			vp, _ := p.AsVersion(version)
+			vpl = append(vpl, vp.(*ProcessingReportV1))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vpl))
+		return s.PersistModel(ctx, vpl)
+	}
+
+	if version.Before(reportRunVersion) {
+		vpl := make([]*ProcessingReportV2, 0, len(pl))
+		for _, p := range pl {
+			vp, _ := p.AsVersion(version)
+			vpl = append(vpl, vp.(*ProcessingReportV2))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vpl))
+		return s.PersistModel(ctx, vpl)
+	}
+
+	if version.Before(reportTipSetVersion) {
+		vpl := make([]*ProcessingReportV3, 0, len(pl))
+		for _, p := range pl {
+			vp, _ := p.AsVersion(version)
+			vpl = append(vpl, vp.(*ProcessingReportV3))
+		}
+		metrics.RecordCount(ctx, metrics.PersistModel, len(vpl))
+		return s.PersistModel(ctx, vpl)
+	}
+
 	metrics.RecordCount(ctx, metrics.PersistModel, len(pl))
 	return s.PersistModel(ctx, pl)
 }
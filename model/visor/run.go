@@ -0,0 +1,26 @@
+package visor
+
+import (
+	"context"
+	"time"
+)
+
+// A Run records the build of visor that ran an indexer and the revision of each of its tasks. Processing reports
+// carry the id of the run that wrote them, so data produced by a release or task revision later found to be faulty can
+// be found and extracted again.
+type Run struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_runs"`
+
+	ID            string         `pg:",pk,notnull"`
+	Reporter      string         `pg:",notnull"`
+	Version       string         `pg:",notnull"`
+	Commit        string         // empty when built from a tagged commit or the commit is not known
+	TaskRevisions map[string]int `pg:",type:jsonb,notnull"`
+	StartedAt     time.Time      `pg:",use_zero"`
+}
+
+// A RunStorage can record the runs that write processing reports to it.
+type RunStorage interface {
+	RecordRun(ctx context.Context, run *Run) error
+}
@@ -0,0 +1,15 @@
+package visor
+
+import "time"
+
+// A Tenant is a consumer of the data in a schema that is given its own postgresql schema of read only views over the
+// data tables, and a role that may read them, so it can be granted access without exposing visor's bookkeeping
+// tables.
+type Tenant struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_tenants"`
+
+	Name       string    `pg:",pk,notnull"`
+	ReaderRole string    `pg:",notnull"`
+	CreatedAt  time.Time `pg:",use_zero"`
+}
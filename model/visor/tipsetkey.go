@@ -0,0 +1,48 @@
+package visor
+
+import (
+	"strings"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// TipSetKeySeparator separates the block CIDs of an encoded tipset key.
+const TipSetKeySeparator = ","
+
+// EncodeTipSetKey returns the canonical text encoding of a tipset key for storage in a text column: the string form
+// of each block CID, in the order used by the key, joined by TipSetKeySeparator. Unlike types.TipSetKey.String, the
+// encoding has no surrounding braces, so it can be compared, split and indexed without further processing. The empty
+// key is encoded as the empty string.
+func EncodeTipSetKey(tsk types.TipSetKey) string {
+	cids := tsk.Cids()
+	strs := make([]string, len(cids))
+	for i, c := range cids {
+		strs[i] = c.String()
+	}
+	return strings.Join(strs, TipSetKeySeparator)
+}
+
+// DecodeTipSetKey parses a tipset key produced by EncodeTipSetKey. For compatibility with values written before the
+// encoding was standardised it also accepts the brace-wrapped form produced by types.TipSetKey.String and ignores
+// whitespace around each CID.
+func DecodeTipSetKey(s string) (types.TipSetKey, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+	if strings.TrimSpace(s) == "" {
+		return types.EmptyTSK, nil
+	}
+
+	parts := strings.Split(s, TipSetKeySeparator)
+	cids := make([]cid.Cid, len(parts))
+	for i, p := range parts {
+		c, err := cid.Decode(strings.TrimSpace(p))
+		if err != nil {
+			return types.EmptyTSK, xerrors.Errorf("decode cid %d of tipset key: %w", i, err)
+		}
+		cids[i] = c
+	}
+	return types.NewTipSetKey(cids...), nil
+}
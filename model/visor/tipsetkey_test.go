@@ -0,0 +1,39 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTipSetKeyEncoding(t *testing.T) {
+	c1, err := cid.Decode("bafy2bzacecnamqgqmifpluoeldx7zzglxcljo6oja4vrmtj7432rphldpdmm2")
+	require.NoError(t, err)
+	c2, err := cid.Decode("bafy2bzaceblpb6p7rzjwlu6erhvb6pnsz7ls3z4rwjpg4sxm4jwvdccq3l2vk")
+	require.NoError(t, err)
+
+	tsk := types.NewTipSetKey(c1, c2)
+	encoded := EncodeTipSetKey(tsk)
+	assert.Equal(t, c1.String()+","+c2.String(), encoded)
+
+	testCases := []string{
+		encoded,
+		tsk.String(),
+		"{" + c1.String() + ", " + c2.String() + "}",
+	}
+	for _, tc := range testCases {
+		decoded, err := DecodeTipSetKey(tc)
+		require.NoError(t, err, tc)
+		assert.Equal(t, tsk, decoded, tc)
+	}
+
+	empty, err := DecodeTipSetKey(EncodeTipSetKey(types.EmptyTSK))
+	require.NoError(t, err)
+	assert.Equal(t, types.EmptyTSK, empty)
+
+	_, err = DecodeTipSetKey("{not-a-cid}")
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package visor
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// validationFailuresVersion is the first schema version containing the visor_validation_failures table.
+var validationFailuresVersion = model.Version{Major: 1, Patch: 2}
+
+// A ValidationFailure records a violation of a data validation rule found at a particular height.
+type ValidationFailure struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_validation_failures"`
+
+	Height  int64  `pg:",pk,use_zero"`
+	Rule    string `pg:",pk,notnull"`
+	Subject string `pg:",pk,notnull"`
+	Details string
+
+	// Reporter is the name of the instance that detected the violation
+	Reporter   string    `pg:",notnull"`
+	DetectedAt time.Time `pg:",use_zero"`
+}
+
+func (f *ValidationFailure) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if version.Before(validationFailuresVersion) {
+		return nil
+	}
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "visor_validation_failures"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, 1)
+	return s.PersistModel(ctx, f)
+}
+
+type ValidationFailureList []*ValidationFailure
+
+func (l ValidationFailureList) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	if len(l) == 0 || version.Before(validationFailuresVersion) {
+		return nil
+	}
+	ctx, span := global.Tracer("").Start(ctx, "ValidationFailureList.Persist", trace.WithAttributes(label.Int("count", len(l))))
+	defer span.End()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.Table, "visor_validation_failures"))
+	stop := metrics.Timer(ctx, metrics.PersistDuration)
+	defer stop()
+
+	metrics.RecordCount(ctx, metrics.PersistModel, len(l))
+	return s.PersistModel(ctx, l)
+}
@@ -0,0 +1,47 @@
+package visor
+
+import "time"
+
+// A WalkJob records a walk run by the daemon together with a cursor marking the last tipset whose task outputs were
+// all committed, so the walk can be resumed from where it stopped by a later job, even one run by a newer version of
+// visor using the same schema.
+type WalkJob struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"visor_walk_jobs"`
+
+	ID        int64    `pg:",pk"`
+	Name      string   `pg:",notnull"`
+	Tasks     []string `pg:",array,notnull"`
+	MinHeight int64    `pg:",use_zero,notnull"`
+	MaxHeight int64    `pg:",use_zero,notnull"`
+	Direction string   `pg:",notnull"`
+	Strict    bool     `pg:",use_zero,notnull"`
+	Overwrite bool     `pg:",use_zero,notnull"`
+
+	// ActorTypes lists the families of actors whose state is extracted, see chain.ActorFamilies. The state of every
+	// actor is extracted if it is empty.
+	ActorTypes []string `pg:",array"`
+
+	// DeferIndexes records that the walk drops the secondary indexes of the tables it writes and rebuilds them once
+	// it has finished.
+	DeferIndexes bool `pg:",use_zero,notnull"`
+
+	// CursorHeight and CursorTipSet identify the last tipset committed by the walk. CursorTipSet is empty if no tipset
+	// has been committed.
+	CursorHeight int64
+	CursorTipSet string
+
+	CreatedAt   time.Time `pg:",notnull"`
+	UpdatedAt   time.Time `pg:",notnull"`
+	CompletedAt time.Time // zero until the walk reaches the end of its range
+}
+
+// HasCursor reports whether the walk has committed any tipsets.
+func (w *WalkJob) HasCursor() bool {
+	return w.CursorTipSet != ""
+}
+
+// Completed reports whether the walk has reached the end of its range.
+func (w *WalkJob) Completed() bool {
+	return !w.CompletedAt.IsZero()
+}
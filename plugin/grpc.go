@@ -0,0 +1,15 @@
+package plugin
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative plugin.proto
+
+// Dial connects to a plugin or to visor's Lens service. The connection is not encrypted so plugins are expected to run
+// on the same host or a trusted network.
+func Dial(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, addr, grpc.WithInsecure())
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"context"
+	"net"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+)
+
+// NewLensService returns a service that answers plugins' Lens requests using the opener.
+func NewLensService(opener lens.APIOpener) *LensService {
+	return &LensService{opener: opener}
+}
+
+// LensService serves the Lens service to plugins, giving them read access to chain data through visor's lens.
+type LensService struct {
+	UnimplementedLensServer
+
+	opener lens.APIOpener
+	server *grpc.Server
+	addr   string
+}
+
+var _ LensServer = (*LensService)(nil)
+
+// Listen starts serving on addr and returns the address plugins should dial. Use port 0 to choose a free port.
+func (s *LensService) Listen(addr string) (string, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", xerrors.Errorf("listen: %w", err)
+	}
+	s.server = grpc.NewServer()
+	RegisterLensServer(s.server, s)
+	s.addr = l.Addr().String()
+
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			log.Errorw("plugin lens server stopped", "error", err)
+		}
+	}()
+	return s.addr, nil
+}
+
+// Close stops the server, waiting for requests in progress to complete.
+func (s *LensService) Close() {
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
+}
+
+func (s *LensService) ChainReadObj(ctx context.Context, req *ChainReadObjRequest) (*ChainReadObjResponse, error) {
+	c, err := cid.Cast(req.Cid)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid cid: %w", err)
+	}
+
+	node, closer, err := s.opener.Open(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	data, err := node.ChainReadObj(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainReadObjResponse{Data: data}, nil
+}
+
+func (s *LensService) StateGetActor(ctx context.Context, req *StateGetActorRequest) (*StateGetActorResponse, error) {
+	addr, err := address.NewFromString(req.Address)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid address: %w", err)
+	}
+	tsk, err := tipSetKey(req.TipsetKey)
+	if err != nil {
+		return nil, err
+	}
+
+	node, closer, err := s.opener.Open(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	act, err := node.StateGetActor(ctx, addr, tsk)
+	if err != nil {
+		return nil, err
+	}
+	return &StateGetActorResponse{
+		Code:    act.Code.Bytes(),
+		Head:    act.Head.Bytes(),
+		Nonce:   act.Nonce,
+		Balance: act.Balance.String(),
+	}, nil
+}
+
+// tipSetKey decodes a list of binary cids into a tipset key. An empty list is the empty key, which lenses interpret
+// as the chain head.
+func tipSetKey(key [][]byte) (types.TipSetKey, error) {
+	cids := make([]cid.Cid, 0, len(key))
+	for _, b := range key {
+		c, err := cid.Cast(b)
+		if err != nil {
+			return types.EmptyTSK, xerrors.Errorf("invalid tipset key cid: %w", err)
+		}
+		cids = append(cids, c)
+	}
+	return types.NewTipSetKey(cids...), nil
+}
@@ -0,0 +1,131 @@
+// Package plugin runs extractors provided by third parties in separate processes. A plugin serves the Extractor gRPC
+// service defined in plugin.proto and is called once for each tipset indexed, returning rows for tables it owns.
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var log = logging.Logger("visor/plugin")
+
+// A Plugin is a connection to an extractor plugin.
+type Plugin struct {
+	Name    string
+	Version string
+	Tables  []*TableSchema
+
+	conn   *grpc.ClientConn
+	client ExtractorClient
+}
+
+// Connect dials the plugin at addr and asks it to describe itself.
+func Connect(ctx context.Context, addr string) (*Plugin, error) {
+	conn, err := Dial(ctx, addr)
+	if err != nil {
+		return nil, xerrors.Errorf("dial: %w", err)
+	}
+	client := NewExtractorClient(conn)
+
+	desc, err := client.Describe(ctx, &DescribeRequest{})
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, xerrors.Errorf("describe: %w", err)
+	}
+	if desc.Name == "" {
+		conn.Close() // nolint: errcheck
+		return nil, xerrors.Errorf("plugin at %s did not give a name", addr)
+	}
+	for _, t := range desc.Tables {
+		if err := checkTable(t); err != nil {
+			conn.Close() // nolint: errcheck
+			return nil, xerrors.Errorf("plugin %s: %w", desc.Name, err)
+		}
+	}
+
+	return &Plugin{
+		Name:    desc.Name,
+		Version: desc.Version,
+		Tables:  desc.Tables,
+		conn:    conn,
+		client:  client,
+	}, nil
+}
+
+// Task returns a task that extracts data using the plugin. lensAddr is the address of the Lens service the plugin
+// may call back to.
+func (p *Plugin) Task(lensAddr string) *Task {
+	tables := make([]string, 0, len(p.Tables))
+	for _, t := range p.Tables {
+		tables = append(tables, t.Name)
+	}
+	return NewTask(p.Name, p.client, lensAddr, tables)
+}
+
+func (p *Plugin) Close() error {
+	return p.conn.Close()
+}
+
+// createTableRe matches the start of a statement creating a table, capturing the name of the table.
+var createTableRe = regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?"?(\w+)"?\s*\(`)
+
+// checkTable returns an error if a plugin describes a table belonging to visor's own schema, which it may not write
+// to, or gives schema SQL that does anything other than create the table.
+func checkTable(t *TableSchema) error {
+	if t.Name == "" {
+		return xerrors.Errorf("table has no name")
+	}
+	if storage.IsVisorTable(t.Name) {
+		return xerrors.Errorf("table %s belongs to visor's schema", t.Name)
+	}
+	if t.Sql == "" {
+		return nil
+	}
+	m := createTableRe.FindStringSubmatch(t.Sql)
+	if m == nil || m[1] != t.Name {
+		return xerrors.Errorf("schema for table %s must be a CREATE TABLE statement for the table", t.Name)
+	}
+	if strings.Contains(strings.TrimRight(strings.TrimSpace(t.Sql), ";"), ";") {
+		return xerrors.Errorf("schema for table %s must be a single statement", t.Name)
+	}
+	return nil
+}
+
+// SchemaDB is the subset of the database used to prepare a plugin's tables. It is satisfied by storage.Database.
+type SchemaDB interface {
+	TableExists(ctx context.Context, name string) (bool, error)
+	ExecContext(c context.Context, query interface{}, params ...interface{}) (pg.Result, error)
+}
+
+// EnsureSchema checks that each of the plugin's tables exists, creating any that are missing using the plugin's schema
+// fragments when allowCreate is true.
+func (p *Plugin) EnsureSchema(ctx context.Context, db SchemaDB, allowCreate bool) error {
+	for _, t := range p.Tables {
+		exists, err := db.TableExists(ctx, t.Name)
+		if err != nil {
+			return xerrors.Errorf("check table %s: %w", t.Name, err)
+		}
+		if exists {
+			continue
+		}
+		if !allowCreate {
+			return xerrors.Errorf("table %s required by plugin %s does not exist, enable schema migration to create it", t.Name, p.Name)
+		}
+		if t.Sql == "" {
+			return xerrors.Errorf("plugin %s gave no schema for table %s", p.Name, t.Name)
+		}
+		log.Infow("creating plugin table", "plugin", p.Name, "table", t.Name)
+		if _, err := db.ExecContext(ctx, t.Sql); err != nil {
+			return xerrors.Errorf("create table %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
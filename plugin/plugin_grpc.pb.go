@@ -0,0 +1,273 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: plugin.proto
+
+package plugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ExtractorClient is the client API for Extractor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ExtractorClient interface {
+	// Describe returns the plugin's name and the schema of the tables it writes to. It is called once when visor
+	// connects to the plugin.
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error)
+	// Extract returns the rows extracted from a single tipset.
+	Extract(ctx context.Context, in *ExtractRequest, opts ...grpc.CallOption) (*ExtractResponse, error)
+}
+
+type extractorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExtractorClient(cc grpc.ClientConnInterface) ExtractorClient {
+	return &extractorClient{cc}
+}
+
+func (c *extractorClient) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error) {
+	out := new(DescribeResponse)
+	err := c.cc.Invoke(ctx, "/visor.plugin.v1.Extractor/Describe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extractorClient) Extract(ctx context.Context, in *ExtractRequest, opts ...grpc.CallOption) (*ExtractResponse, error) {
+	out := new(ExtractResponse)
+	err := c.cc.Invoke(ctx, "/visor.plugin.v1.Extractor/Extract", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExtractorServer is the server API for Extractor service.
+// All implementations must embed UnimplementedExtractorServer
+// for forward compatibility
+type ExtractorServer interface {
+	// Describe returns the plugin's name and the schema of the tables it writes to. It is called once when visor
+	// connects to the plugin.
+	Describe(context.Context, *DescribeRequest) (*DescribeResponse, error)
+	// Extract returns the rows extracted from a single tipset.
+	Extract(context.Context, *ExtractRequest) (*ExtractResponse, error)
+	mustEmbedUnimplementedExtractorServer()
+}
+
+// UnimplementedExtractorServer must be embedded to have forward compatible implementations.
+type UnimplementedExtractorServer struct {
+}
+
+func (UnimplementedExtractorServer) Describe(context.Context, *DescribeRequest) (*DescribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Describe not implemented")
+}
+func (UnimplementedExtractorServer) Extract(context.Context, *ExtractRequest) (*ExtractResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Extract not implemented")
+}
+func (UnimplementedExtractorServer) mustEmbedUnimplementedExtractorServer() {}
+
+// UnsafeExtractorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExtractorServer will
+// result in compilation errors.
+type UnsafeExtractorServer interface {
+	mustEmbedUnimplementedExtractorServer()
+}
+
+func RegisterExtractorServer(s grpc.ServiceRegistrar, srv ExtractorServer) {
+	s.RegisterService(&Extractor_ServiceDesc, srv)
+}
+
+func _Extractor_Describe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtractorServer).Describe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/visor.plugin.v1.Extractor/Describe",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtractorServer).Describe(ctx, req.(*DescribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Extractor_Extract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtractRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtractorServer).Extract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/visor.plugin.v1.Extractor/Extract",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtractorServer).Extract(ctx, req.(*ExtractRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Extractor_ServiceDesc is the grpc.ServiceDesc for Extractor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Extractor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "visor.plugin.v1.Extractor",
+	HandlerType: (*ExtractorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Describe",
+			Handler:    _Extractor_Describe_Handler,
+		},
+		{
+			MethodName: "Extract",
+			Handler:    _Extractor_Extract_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugin.proto",
+}
+
+// LensClient is the client API for Lens service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LensClient interface {
+	// ChainReadObj returns the raw bytes of the IPLD block with the given cid.
+	ChainReadObj(ctx context.Context, in *ChainReadObjRequest, opts ...grpc.CallOption) (*ChainReadObjResponse, error)
+	// StateGetActor returns an actor as it was in the state of the given tipset.
+	StateGetActor(ctx context.Context, in *StateGetActorRequest, opts ...grpc.CallOption) (*StateGetActorResponse, error)
+}
+
+type lensClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLensClient(cc grpc.ClientConnInterface) LensClient {
+	return &lensClient{cc}
+}
+
+func (c *lensClient) ChainReadObj(ctx context.Context, in *ChainReadObjRequest, opts ...grpc.CallOption) (*ChainReadObjResponse, error) {
+	out := new(ChainReadObjResponse)
+	err := c.cc.Invoke(ctx, "/visor.plugin.v1.Lens/ChainReadObj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lensClient) StateGetActor(ctx context.Context, in *StateGetActorRequest, opts ...grpc.CallOption) (*StateGetActorResponse, error) {
+	out := new(StateGetActorResponse)
+	err := c.cc.Invoke(ctx, "/visor.plugin.v1.Lens/StateGetActor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LensServer is the server API for Lens service.
+// All implementations must embed UnimplementedLensServer
+// for forward compatibility
+type LensServer interface {
+	// ChainReadObj returns the raw bytes of the IPLD block with the given cid.
+	ChainReadObj(context.Context, *ChainReadObjRequest) (*ChainReadObjResponse, error)
+	// StateGetActor returns an actor as it was in the state of the given tipset.
+	StateGetActor(context.Context, *StateGetActorRequest) (*StateGetActorResponse, error)
+	mustEmbedUnimplementedLensServer()
+}
+
+// UnimplementedLensServer must be embedded to have forward compatible implementations.
+type UnimplementedLensServer struct {
+}
+
+func (UnimplementedLensServer) ChainReadObj(context.Context, *ChainReadObjRequest) (*ChainReadObjResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChainReadObj not implemented")
+}
+func (UnimplementedLensServer) StateGetActor(context.Context, *StateGetActorRequest) (*StateGetActorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StateGetActor not implemented")
+}
+func (UnimplementedLensServer) mustEmbedUnimplementedLensServer() {}
+
+// UnsafeLensServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LensServer will
+// result in compilation errors.
+type UnsafeLensServer interface {
+	mustEmbedUnimplementedLensServer()
+}
+
+func RegisterLensServer(s grpc.ServiceRegistrar, srv LensServer) {
+	s.RegisterService(&Lens_ServiceDesc, srv)
+}
+
+func _Lens_ChainReadObj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChainReadObjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LensServer).ChainReadObj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/visor.plugin.v1.Lens/ChainReadObj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LensServer).ChainReadObj(ctx, req.(*ChainReadObjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lens_StateGetActor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StateGetActorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LensServer).StateGetActor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/visor.plugin.v1.Lens/StateGetActor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LensServer).StateGetActor(ctx, req.(*StateGetActorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Lens_ServiceDesc is the grpc.ServiceDesc for Lens service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Lens_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "visor.plugin.v1.Lens",
+	HandlerType: (*LensServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ChainReadObj",
+			Handler:    _Lens_ChainReadObj_Handler,
+		},
+		{
+			MethodName: "StateGetActor",
+			Handler:    _Lens_StateGetActor_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugin.proto",
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/storage"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+type fakeExtractor struct {
+	UnimplementedExtractorServer
+	requests []*ExtractRequest
+	table    string         // table rows are returned for
+	tables   []*TableSchema // tables described, defaults to miner_notes
+}
+
+func (f *fakeExtractor) Describe(context.Context, *DescribeRequest) (*DescribeResponse, error) {
+	tables := f.tables
+	if tables == nil {
+		tables = []*TableSchema{{Name: "miner_notes", Sql: "CREATE TABLE miner_notes (height bigint, blocks int)"}}
+	}
+	return &DescribeResponse{
+		Name:   "notes",
+		Tables: tables,
+	}, nil
+}
+
+func (f *fakeExtractor) Extract(ctx context.Context, req *ExtractRequest) (*ExtractResponse, error) {
+	f.requests = append(f.requests, req)
+	return &ExtractResponse{
+		Tables: []*TableRows{
+			{
+				Table:   f.table,
+				Columns: []string{"height", "blocks"},
+				Rows:    []*Row{{Values: []*Value{Text(strconv.FormatInt(req.Height, 10)), Text(strconv.Itoa(len(req.BlockHeaders)))}}},
+			},
+		},
+	}, nil
+}
+
+func startExtractor(t *testing.T, ext *fakeExtractor) *Plugin {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	RegisterExtractorServer(srv, ext)
+	go srv.Serve(l) // nolint: errcheck
+	t.Cleanup(srv.Stop)
+
+	p, err := Connect(context.Background(), l.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close() }) // nolint: errcheck
+	return p
+}
+
+func TestPluginTask(t *testing.T) {
+	ctx := context.Background()
+
+	ext := &fakeExtractor{table: "miner_notes"}
+	p := startExtractor(t, ext)
+	assert.Equal(t, "notes", p.Name)
+	require.Len(t, p.Tables, 1)
+
+	ts := testutil.FakeTipset(t)
+	data, report, err := p.Task("127.0.0.1:9999").ProcessTipSet(ctx, ts)
+	require.NoError(t, err)
+	assert.Nil(t, report.ErrorsDetected)
+	assert.Equal(t, int64(ts.Height()), report.Height)
+
+	require.Len(t, ext.requests, 1)
+	assert.Equal(t, "127.0.0.1:9999", ext.requests[0].LensAddress)
+	assert.Len(t, ext.requests[0].TipsetKey, len(ts.Cids()))
+
+	mc := model.NewCollector(nil)
+	require.NoError(t, data.Persist(ctx, mc, storage.LatestSchemaVersion()))
+	assert.Equal(t, 1, mc.Rows())
+
+	mem := storage.NewMemStorageLatest()
+	require.NoError(t, data.Persist(ctx, mem, mem.Version))
+	require.Len(t, mem.Data["miner_notes"], 1)
+	raw := mem.Data["miner_notes"][0].(model.RawModel)
+	assert.Equal(t, strconv.Itoa(len(ts.Blocks())), *raw.RawRows()[0][1])
+}
+
+func TestPluginTaskRejectsUndescribedTable(t *testing.T) {
+	ext := &fakeExtractor{table: "visor_processing_reports"}
+	p := startExtractor(t, ext)
+
+	_, _, err := p.Task("").ProcessTipSet(context.Background(), testutil.FakeTipset(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "visor_processing_reports")
+}
+
+func TestConnectRejectsVisorTables(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	ext := &fakeExtractor{}
+	RegisterExtractorServer(srv, ext)
+	go srv.Serve(l) // nolint: errcheck
+	defer srv.Stop()
+
+	for _, table := range []*TableSchema{
+		{Name: "actors"},
+		{Name: "visor_processing_reports"},
+		{Name: "miner_daily_stats"},
+		{Name: "miner_notes", Sql: "DROP TABLE actors"},
+		{Name: "miner_notes", Sql: "CREATE TABLE actors (height bigint)"},
+		{Name: "miner_notes", Sql: "CREATE TABLE miner_notes (height bigint); DROP TABLE actors;"},
+	} {
+		ext.tables = []*TableSchema{table}
+		_, err := Connect(context.Background(), l.Addr().String())
+		assert.Error(t, err, "table %s with sql %q", table.Name, table.Sql)
+	}
+
+	ext.tables = []*TableSchema{{Name: "miner_notes", Sql: "CREATE TABLE IF NOT EXISTS miner_notes (height bigint);"}}
+	p, err := Connect(context.Background(), l.Addr().String())
+	require.NoError(t, err)
+	p.Close() // nolint: errcheck
+}
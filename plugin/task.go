@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// A Task extracts data from tipsets by calling a plugin's Extractor service.
+type Task struct {
+	name     string
+	client   ExtractorClient
+	lensAddr string          // address of the Lens service passed to the plugin, may be empty
+	tables   map[string]bool // tables the plugin described, the only tables it may return rows for
+}
+
+// NewTask returns a task that calls the plugin using client. lensAddr is passed to the plugin in each request so it
+// can call back to visor's Lens service. Rows returned for tables other than those named in tables are rejected.
+func NewTask(name string, client ExtractorClient, lensAddr string, tables []string) *Task {
+	t := &Task{
+		name:     name,
+		client:   client,
+		lensAddr: lensAddr,
+		tables:   make(map[string]bool, len(tables)),
+	}
+	for _, table := range tables {
+		t.tables[table] = true
+	}
+	return t
+}
+
+func (t *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessPlugin")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())), label.String("plugin", t.name))
+	}
+	defer span.End()
+
+	req, err := NewExtractRequest(ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.LensAddress = t.lensAddr
+
+	resp, err := t.client.Extract(ctx, req)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("plugin %s extract: %w", t.name, err)
+	}
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+	if len(resp.Errors) > 0 {
+		report.ErrorsDetected = resp.Errors
+	}
+
+	data := make(model.PersistableList, 0, len(resp.Tables))
+	for _, tr := range resp.Tables {
+		if !t.tables[tr.Table] {
+			// A plugin may only write to the tables it described, not to visor's own tables
+			return nil, nil, xerrors.Errorf("plugin %s returned rows for table %q which it did not describe", t.name, tr.Table)
+		}
+		rows, err := NewRows(tr)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("plugin %s: %w", t.name, err)
+		}
+		data = append(data, rows)
+	}
+
+	return data, report, nil
+}
+
+func (t *Task) Close() error {
+	return nil
+}
+
+// NewExtractRequest returns a request describing the tipset.
+func NewExtractRequest(ts *types.TipSet) (*ExtractRequest, error) {
+	req := &ExtractRequest{
+		Height:          int64(ts.Height()),
+		ParentStateRoot: ts.ParentState().Bytes(),
+	}
+	for _, c := range ts.Cids() {
+		req.TipsetKey = append(req.TipsetKey, c.Bytes())
+	}
+	for _, c := range ts.Parents().Cids() {
+		req.ParentTipsetKey = append(req.ParentTipsetKey, c.Bytes())
+	}
+	for _, bh := range ts.Blocks() {
+		buf, err := bh.Serialize()
+		if err != nil {
+			return nil, xerrors.Errorf("serialize block header: %w", err)
+		}
+		req.BlockHeaders = append(req.BlockHeaders, buf)
+	}
+	return req, nil
+}
+
+var (
+	_ model.Persistable = (*Rows)(nil)
+	_ model.RawModel    = (*Rows)(nil)
+)
+
+// Rows are the rows returned by a plugin for one of its tables. They are persisted as a raw model since the structure
+// of the table is only known to the plugin.
+type Rows struct {
+	Table   string
+	Columns []string
+	Values  [][]*string
+}
+
+// NewRows validates the rows returned by a plugin.
+func NewRows(t *TableRows) (*Rows, error) {
+	if t.Table == "" {
+		return nil, xerrors.Errorf("rows returned without a table name")
+	}
+	if len(t.Columns) == 0 {
+		return nil, xerrors.Errorf("rows for table %s returned without columns", t.Table)
+	}
+	r := &Rows{
+		Table:   t.Table,
+		Columns: t.Columns,
+		Values:  make([][]*string, 0, len(t.Rows)),
+	}
+	for i, row := range t.Rows {
+		if len(row.Values) != len(t.Columns) {
+			return nil, xerrors.Errorf("row %d for table %s has %d values, expected %d", i, t.Table, len(row.Values), len(t.Columns))
+		}
+		values := make([]*string, len(row.Values))
+		for j, v := range row.Values {
+			if !v.Null {
+				text := v.Text
+				values[j] = &text
+			}
+		}
+		r.Values = append(r.Values, values)
+	}
+	return r, nil
+}
+
+// Text returns a value holding the text representation of a column value, for use by plugins written in Go.
+func Text(s string) *Value {
+	return &Value{Text: s}
+}
+
+// Null returns a null column value, for use by plugins written in Go.
+func Null() *Value {
+	return &Value{Null: true}
+}
+
+func (r *Rows) RawTable() string     { return r.Table }
+func (r *Rows) RawColumns() []string { return r.Columns }
+func (r *Rows) RawRows() [][]*string { return r.Values }
+
+func (r *Rows) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	return s.PersistModel(ctx, r)
+}
@@ -0,0 +1,624 @@
+// Package rosetta implements the Rosetta Data API (https://www.rosetta-api.org) using the data extracted by visor, so
+// exchanges and other integrators can follow Filecoin balances and transfers with visor's database as the source of
+// truth. Only the read only Data API is provided; the Construction API is out of scope.
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-pg/pg/v10"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/version"
+)
+
+var log = logging.Logger("visor/rosetta")
+
+const (
+	Blockchain     = "Filecoin"
+	RosettaVersion = "1.4.10"
+)
+
+// FIL is the currency of all amounts returned by the server.
+var FIL = Currency{Symbol: "FIL", Decimals: 18}
+
+// Operation types and statuses reported in transactions.
+const (
+	OpTransfer           = "Transfer"
+	OpBaseFeeBurn        = "BaseFeeBurn"
+	OpOverEstimationBurn = "OverEstimationBurn"
+	OpMinerTip           = "MinerTip"
+	OpInternalTransfer   = "InternalTransfer"
+	OpBlockReward        = "BlockReward"
+	OpImplicitTransfer   = "ImplicitTransfer"
+
+	StatusOK     = "OK"
+	StatusFailed = "FAILED"
+)
+
+var (
+	ErrInvalidNetwork   = &Error{Code: 1, Message: "invalid network identifier"}
+	ErrInvalidRequest   = &Error{Code: 2, Message: "invalid request"}
+	ErrBlockNotFound    = &Error{Code: 3, Message: "block not found"}
+	ErrTxNotFound       = &Error{Code: 4, Message: "transaction not found"}
+	ErrAccountNotFound  = &Error{Code: 5, Message: "account not found"}
+	ErrNotIndexed       = &Error{Code: 6, Message: "no data has been indexed", Retriable: true}
+	ErrInternal         = &Error{Code: 7, Message: "internal error", Retriable: true}
+	ErrMempoolUnsupport = &Error{Code: 8, Message: "mempool is not available from indexed data"}
+)
+
+var allErrors = []*Error{ErrInvalidNetwork, ErrInvalidRequest, ErrBlockNotFound, ErrTxNotFound, ErrAccountNotFound, ErrNotIndexed, ErrInternal, ErrMempoolUnsupport}
+
+// A Querier executes SQL queries against a database holding the latest visor schema. It is satisfied by
+// *storage.Database.
+type Querier interface {
+	QueryContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error)
+}
+
+// NewServer returns a server answering Rosetta Data API requests from the database.
+func NewServer(db Querier) *Server {
+	return &Server{db: db}
+}
+
+// A Server answers Rosetta Data API requests. Blocks are tipsets identified by their height and a hash formed from
+// the CIDs of their blocks. Only tipsets on the canonical chain are visible.
+type Server struct {
+	db Querier
+
+	mu      sync.Mutex
+	network *networkRow // cached once read since the network of a schema never changes
+}
+
+type networkRow struct {
+	Name    string
+	Genesis string
+}
+
+func (s *Server) networkInfo(ctx context.Context) (*networkRow, *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.network != nil {
+		return s.network, nil
+	}
+
+	var rows []networkRow
+	if _, err := s.db.QueryContext(ctx, &rows, `SELECT "name", genesis FROM visor_network LIMIT 1`); err != nil {
+		return nil, internalError(err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotIndexed
+	}
+	s.network = &rows[0]
+	return s.network, nil
+}
+
+func (s *Server) checkNetwork(ctx context.Context, id NetworkIdentifier) *Error {
+	n, rerr := s.networkInfo(ctx)
+	if rerr != nil {
+		return rerr
+	}
+	if id.Blockchain != Blockchain || id.Network != n.Name {
+		return withDetails(ErrInvalidNetwork, map[string]interface{}{"blockchain": id.Blockchain, "network": id.Network})
+	}
+	return nil
+}
+
+func (s *Server) NetworkList(ctx context.Context, _ *MetadataRequest) (*NetworkListResponse, *Error) {
+	n, rerr := s.networkInfo(ctx)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return &NetworkListResponse{
+		NetworkIdentifiers: []NetworkIdentifier{{Blockchain: Blockchain, Network: n.Name}},
+	}, nil
+}
+
+func (s *Server) NetworkStatus(ctx context.Context, req *NetworkRequest) (*NetworkStatusResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+	n, rerr := s.networkInfo(ctx)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	current, rerr := s.currentTipSet(ctx)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	return &NetworkStatusResponse{
+		CurrentBlockIdentifier: current.identifier(),
+		CurrentBlockTimestamp:  current.Timestamp * 1000,
+		GenesisBlockIdentifier: BlockIdentifier{Index: 0, Hash: n.Genesis},
+		Peers:                  []Peer{},
+	}, nil
+}
+
+func (s *Server) NetworkOptions(ctx context.Context, req *NetworkRequest) (*NetworkOptionsResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+	return &NetworkOptionsResponse{
+		Version: Version{
+			RosettaVersion:    RosettaVersion,
+			NodeVersion:       version.String(),
+			MiddlewareVersion: version.String(),
+		},
+		Allow: Allow{
+			OperationStatuses: []OperationStatus{
+				{Status: StatusOK, Successful: true},
+				{Status: StatusFailed, Successful: false},
+			},
+			OperationTypes:          []string{OpTransfer, OpBaseFeeBurn, OpOverEstimationBurn, OpMinerTip, OpInternalTransfer, OpBlockReward, OpImplicitTransfer},
+			Errors:                  allErrors,
+			HistoricalBalanceLookup: true,
+		},
+	}, nil
+}
+
+func (s *Server) AccountBalance(ctx context.Context, req *AccountBalanceRequest) (*AccountBalanceResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+	if req.AccountIdentifier.Address == "" {
+		return nil, withDetails(ErrInvalidRequest, map[string]interface{}{"reason": "account address is required"})
+	}
+
+	var ts *tipSetRow
+	var rerr *Error
+	if req.BlockIdentifier == nil || (req.BlockIdentifier.Index == nil && req.BlockIdentifier.Hash == nil) {
+		ts, rerr = s.currentTipSet(ctx)
+	} else {
+		ts, rerr = s.findTipSet(ctx, req.BlockIdentifier)
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	id, rerr := s.resolveAddress(ctx, req.AccountIdentifier.Address, ts.Height)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	// The balance is taken from the latest state of the actor at or before the tipset, unless the actor has since been
+	// deleted.
+	var rows []struct {
+		Balance string
+		Deleted bool
+	}
+	if _, err := s.db.QueryContext(ctx, &rows, `
+SELECT a.balance, EXISTS (
+	SELECT 1 FROM actor_deletions d
+	WHERE d.id = a.id AND d.is_canonical AND d.height > a.height AND d.height <= ?1
+) AS deleted
+FROM actors a
+WHERE a.id = ?0 AND a.is_canonical AND a.height <= ?1
+ORDER BY a.height DESC
+LIMIT 1`, id, ts.Height); err != nil {
+		return nil, internalError(err)
+	}
+	if len(rows) == 0 {
+		return nil, withDetails(ErrAccountNotFound, map[string]interface{}{"address": req.AccountIdentifier.Address})
+	}
+
+	balance := rows[0].Balance
+	if rows[0].Deleted {
+		balance = "0"
+	}
+
+	return &AccountBalanceResponse{
+		BlockIdentifier: ts.identifier(),
+		Balances:        []Amount{{Value: balance, Currency: FIL}},
+		Metadata:        map[string]interface{}{"id": id},
+	}, nil
+}
+
+func (s *Server) Block(ctx context.Context, req *BlockRequest) (*BlockResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+
+	ts, rerr := s.findTipSet(ctx, &req.BlockIdentifier)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	parent := ts.identifier()
+	var pts *tipSetRow
+	if ts.Height > 0 {
+		pts, rerr = s.tipSetBefore(ctx, ts.Height)
+		if rerr != nil {
+			return nil, rerr
+		}
+		parent = pts.identifier()
+	}
+
+	txs, rerr := s.transactions(ctx, ts, pts, "")
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	return &BlockResponse{
+		Block: &Block{
+			BlockIdentifier:       ts.identifier(),
+			ParentBlockIdentifier: parent,
+			Timestamp:             ts.Timestamp * 1000,
+			Transactions:          txs,
+		},
+	}, nil
+}
+
+func (s *Server) BlockTransaction(ctx context.Context, req *BlockTransactionRequest) (*BlockTransactionResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+
+	ts, rerr := s.findTipSet(ctx, &PartialBlockIdentifier{Index: &req.BlockIdentifier.Index, Hash: &req.BlockIdentifier.Hash})
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	var pts *tipSetRow
+	if ts.Height > 0 {
+		pts, rerr = s.tipSetBefore(ctx, ts.Height)
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+
+	txs, rerr := s.transactions(ctx, ts, pts, req.TransactionIdentifier.Hash)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if len(txs) == 0 {
+		return nil, withDetails(ErrTxNotFound, map[string]interface{}{"hash": req.TransactionIdentifier.Hash})
+	}
+	return &BlockTransactionResponse{Transaction: txs[0]}, nil
+}
+
+func (s *Server) Mempool(ctx context.Context, req *NetworkRequest) (*MempoolResponse, *Error) {
+	if rerr := s.checkNetwork(ctx, req.NetworkIdentifier); rerr != nil {
+		return nil, rerr
+	}
+	// Visor only sees messages once they have been included in a tipset
+	return &MempoolResponse{TransactionIdentifiers: []TransactionIdentifier{}}, nil
+}
+
+type tipSetRow struct {
+	Height    int64
+	Hash      string
+	Timestamp int64 // seconds since the unix epoch
+}
+
+func (t *tipSetRow) identifier() BlockIdentifier {
+	return BlockIdentifier{Index: t.Height, Hash: t.Hash}
+}
+
+// tipSetQuery selects the canonical tipset at the height given by the condition. The hash is formed from the CIDs of
+// the tipset's blocks in lexical order.
+const tipSetQuery = `SELECT height, string_agg(cid, ',' ORDER BY cid) AS hash, max("timestamp") AS "timestamp"
+FROM block_headers
+WHERE is_canonical AND height = (%s)
+GROUP BY height`
+
+func (s *Server) queryTipSet(ctx context.Context, heightExpr string, params ...interface{}) (*tipSetRow, *Error) {
+	var rows []tipSetRow
+	if _, err := s.db.QueryContext(ctx, &rows, fmt.Sprintf(tipSetQuery, heightExpr), params...); err != nil {
+		return nil, internalError(err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+func (s *Server) currentTipSet(ctx context.Context) (*tipSetRow, *Error) {
+	ts, rerr := s.queryTipSet(ctx, `SELECT max(height) FROM block_headers WHERE is_canonical`)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if ts == nil {
+		return nil, ErrNotIndexed
+	}
+	return ts, nil
+}
+
+// tipSetBefore returns the canonical tipset preceding the height, skipping null rounds.
+func (s *Server) tipSetBefore(ctx context.Context, height int64) (*tipSetRow, *Error) {
+	ts, rerr := s.queryTipSet(ctx, `SELECT max(height) FROM block_headers WHERE is_canonical AND height < ?`, height)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if ts == nil {
+		return nil, withDetails(ErrBlockNotFound, map[string]interface{}{"before": height})
+	}
+	return ts, nil
+}
+
+// findTipSet returns the canonical tipset identified by index, hash or both.
+func (s *Server) findTipSet(ctx context.Context, id *PartialBlockIdentifier) (*tipSetRow, *Error) {
+	var ts *tipSetRow
+	var rerr *Error
+	switch {
+	case id.Index != nil:
+		ts, rerr = s.queryTipSet(ctx, `SELECT ?::bigint`, *id.Index)
+	case id.Hash != nil:
+		// Any block of the tipset gives its height
+		first := strings.SplitN(*id.Hash, ",", 2)[0]
+		ts, rerr = s.queryTipSet(ctx, `SELECT height FROM block_headers WHERE cid = ? AND is_canonical LIMIT 1`, first)
+	default:
+		return nil, withDetails(ErrInvalidRequest, map[string]interface{}{"reason": "block index or hash is required"})
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	if ts == nil || (id.Hash != nil && *id.Hash != "" && *id.Hash != ts.Hash) {
+		return nil, ErrBlockNotFound
+	}
+	return ts, nil
+}
+
+// resolveAddress returns the ID address of an account as of the given height.
+func (s *Server) resolveAddress(ctx context.Context, addr string, height int64) (string, *Error) {
+	if len(addr) > 2 && addr[1] == '0' {
+		// already an ID address
+		return addr, nil
+	}
+	var ids []string
+	if _, err := s.db.QueryContext(ctx, &ids, `SELECT id FROM id_addresses WHERE address = ? AND is_canonical AND height <= ? ORDER BY height DESC LIMIT 1`, addr, height); err != nil {
+		return "", internalError(err)
+	}
+	if len(ids) == 0 {
+		return "", withDetails(ErrAccountNotFound, map[string]interface{}{"address": addr})
+	}
+	return ids[0], nil
+}
+
+type gasOutputRow struct {
+	Cid                string
+	From               string
+	To                 string
+	Value              string
+	Method             int64
+	ExitCode           int64
+	BaseFeeBurn        string
+	OverEstimationBurn string
+	MinerTip           string
+	ActorName          string
+}
+
+type internalMessageRow struct {
+	Cid           string
+	SourceMessage string
+	From          string
+	To            string
+	Value         string
+	Method        int64
+	ActorName     string
+}
+
+// transactions returns the transactions of the block for tipset ts, whose parent tipset is parent or nil at genesis.
+// Balances at ts are read from its parent state, which is the result of executing the messages included in the parent
+// tipset along with the cron and reward messages of each epoch up to ts. Those make up the block's transactions, so
+// applying them to the balances at the parent gives the balances at ts. Rows of derived_gas_outputs and
+// internal_messages are recorded at the height of the tipset whose messages produced them. If hash is not empty only
+// the transaction with that hash is returned.
+func (s *Server) transactions(ctx context.Context, ts, parent *tipSetRow, hash string) ([]Transaction, *Error) {
+	if parent == nil {
+		return []Transaction{}, nil
+	}
+
+	var rows []gasOutputRow
+	if _, err := s.db.QueryContext(ctx, &rows, `SELECT cid, "from", "to", value, method, exit_code, base_fee_burn, over_estimation_burn, miner_tip, actor_name
+FROM derived_gas_outputs
+WHERE height >= ? AND height < ? AND is_canonical
+ORDER BY cid`, parent.Height, ts.Height); err != nil {
+		return nil, internalError(err)
+	}
+
+	var internals []internalMessageRow
+	if _, err := s.db.QueryContext(ctx, &internals, `SELECT cid, coalesce(source_message, '') AS source_message, "from", "to", value, method, actor_name
+FROM internal_messages
+WHERE height >= ? AND height < ? AND is_canonical AND exit_code = 0 AND value <> 0
+ORDER BY cid`, parent.Height, ts.Height); err != nil {
+		return nil, internalError(err)
+	}
+
+	txs := make([]Transaction, 0, len(rows))
+	sources := make(map[string]int, len(rows)) // index of each message's transaction
+	for i := range rows {
+		sources[rows[i].Cid] = len(txs)
+		txs = append(txs, transaction(&rows[i]))
+	}
+
+	for i := range internals {
+		im := &internals[i]
+		if im.SourceMessage == "" {
+			txs = append(txs, implicitTransaction(im))
+			continue
+		}
+		idx, ok := sources[im.SourceMessage]
+		if !ok {
+			// The message that sent it has not been indexed
+			txs = append(txs, implicitTransaction(im))
+			continue
+		}
+		// Sends made by a message that failed were reverted with it
+		if rows[idx].ExitCode != 0 {
+			continue
+		}
+		addTransfer(&txs[idx], OpInternalTransfer, StatusOK, im.From, im.To, im.Value)
+	}
+
+	if hash == "" {
+		return txs, nil
+	}
+	for _, tx := range txs {
+		if tx.TransactionIdentifier.Hash == hash {
+			return []Transaction{tx}, nil
+		}
+	}
+	return []Transaction{}, nil
+}
+
+// addTransfer adds a debit of value from one account and a matching credit to another, linked as related operations.
+func addTransfer(tx *Transaction, opType, status, from, to, value string) {
+	debit := OperationIdentifier{Index: int64(len(tx.Operations))}
+	tx.Operations = append(tx.Operations, Operation{
+		OperationIdentifier: debit,
+		Type:                opType,
+		Status:              status,
+		Account:             &AccountIdentifier{Address: from},
+		Amount:              &Amount{Value: "-" + value, Currency: FIL},
+	}, Operation{
+		OperationIdentifier: OperationIdentifier{Index: debit.Index + 1},
+		RelatedOperations:   []OperationIdentifier{debit},
+		Type:                opType,
+		Status:              status,
+		Account:             &AccountIdentifier{Address: to},
+		Amount:              &Amount{Value: value, Currency: FIL},
+	})
+}
+
+// transaction converts a message's gas outputs to a transaction. The transfer of value is reported as a pair of
+// operations that fail if the message did not execute successfully. Fees are always charged to the sender and each
+// fee is reported as a balanced pair: burns are credited to the burnt funds actor and the miner tip to the reward
+// actor, which is where the VM sends them when the message is applied.
+func transaction(r *gasOutputRow) Transaction {
+	tx := Transaction{
+		TransactionIdentifier: TransactionIdentifier{Hash: r.Cid},
+		Operations:            []Operation{},
+		Metadata: map[string]interface{}{
+			"method":    r.Method,
+			"exit_code": r.ExitCode,
+			"to_actor":  r.ActorName,
+		},
+	}
+
+	if isPositive(r.Value) {
+		status := StatusOK
+		if r.ExitCode != 0 {
+			status = StatusFailed
+		}
+		addTransfer(&tx, OpTransfer, status, r.From, r.To, r.Value)
+	}
+	if isPositive(r.BaseFeeBurn) {
+		addTransfer(&tx, OpBaseFeeBurn, StatusOK, r.From, builtin.BurntFundsActorAddr.String(), r.BaseFeeBurn)
+	}
+	if isPositive(r.OverEstimationBurn) {
+		addTransfer(&tx, OpOverEstimationBurn, StatusOK, r.From, builtin.BurntFundsActorAddr.String(), r.OverEstimationBurn)
+	}
+	if isPositive(r.MinerTip) {
+		addTransfer(&tx, OpMinerTip, StatusOK, r.From, builtin.RewardActorAddr.String(), r.MinerTip)
+	}
+	return tx
+}
+
+// implicitTransaction converts a value transfer sent by the system rather than by a message included in the chain,
+// such as a block reward or a payment made by cron, to a transaction identified by the CID of the internal message.
+func implicitTransaction(im *internalMessageRow) Transaction {
+	tx := Transaction{
+		TransactionIdentifier: TransactionIdentifier{Hash: im.Cid},
+		Operations:            []Operation{},
+		Metadata: map[string]interface{}{
+			"method":   im.Method,
+			"to_actor": im.ActorName,
+		},
+	}
+
+	opType := OpImplicitTransfer
+	if len(im.From) > 1 && im.From[1:] == "02" {
+		opType = OpBlockReward
+	}
+	addTransfer(&tx, opType, StatusOK, im.From, im.To, im.Value)
+	return tx
+}
+
+// isPositive reports whether a decimal amount is greater than zero.
+func isPositive(v string) bool {
+	return v != "" && !strings.HasPrefix(v, "-") && strings.Trim(v, "0") != ""
+}
+
+func internalError(err error) *Error {
+	log.Errorw("rosetta query failed", "error", err)
+	return withDetails(ErrInternal, map[string]interface{}{"error": err.Error()})
+}
+
+func withDetails(e *Error, details map[string]interface{}) *Error {
+	out := *e
+	out.Details = details
+	return &out
+}
+
+// NewHandler returns an http.Handler serving the Rosetta Data API endpoints. All requests are JSON encoded POSTs.
+func NewHandler(s *Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/network/list", endpoint(s.NetworkList))
+	mux.Handle("/network/status", endpoint(s.NetworkStatus))
+	mux.Handle("/network/options", endpoint(s.NetworkOptions))
+	mux.Handle("/account/balance", endpoint(s.AccountBalance))
+	mux.Handle("/block", endpoint(s.Block))
+	mux.Handle("/block/transaction", endpoint(s.BlockTransaction))
+	mux.Handle("/mempool", endpoint(s.Mempool))
+	mux.Handle("/mempool/transaction", endpoint(func(ctx context.Context, req *NetworkRequest) (*struct{}, *Error) {
+		return nil, ErrMempoolUnsupport
+	}))
+	return mux
+}
+
+// maxRequestSize is the maximum size of a request body accepted by the handler.
+const maxRequestSize = 1 << 20
+
+// endpoint adapts a server method with the signature func(context.Context, *Request) (*Response, *Error) to an
+// http.Handler.
+func endpoint(fn interface{}) http.Handler {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 2 || t.In(1).Kind() != reflect.Ptr || t.Out(1) != reflect.TypeOf((*Error)(nil)) {
+		panic(fmt.Sprintf("unsupported endpoint function %T", fn))
+	}
+	return &endpointHandler{fn: v, reqType: t.In(1).Elem()}
+}
+
+type endpointHandler struct {
+	fn      reflect.Value
+	reqType reflect.Type
+}
+
+func (h *endpointHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := reflect.New(h.reqType)
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(req.Interface()); err != nil {
+		writeJSON(w, http.StatusBadRequest, withDetails(ErrInvalidRequest, map[string]interface{}{"error": err.Error()}))
+		return
+	}
+
+	out := h.fn.Call([]reflect.Value{reflect.ValueOf(r.Context()), req})
+	if rerr := out[1].Interface().(*Error); rerr != nil {
+		writeJSON(w, http.StatusInternalServerError, rerr)
+		return
+	}
+	writeJSON(w, http.StatusOK, out[0].Interface())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorw("failed to write rosetta response", "error", err)
+	}
+}
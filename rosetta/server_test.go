@@ -0,0 +1,149 @@
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier answers queries with JSON encoded rows chosen by a fragment of the query text.
+type fakeQuerier struct {
+	results map[string]string // query fragment to JSON rows
+	queries []string
+	params  [][]interface{}
+}
+
+func (q *fakeQuerier) QueryContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error) {
+	qs := query.(string)
+	q.queries = append(q.queries, qs)
+	q.params = append(q.params, params)
+	for fragment, rows := range q.results {
+		if strings.Contains(qs, fragment) {
+			return nil, json.Unmarshal([]byte(rows), model)
+		}
+	}
+	return nil, nil
+}
+
+var mainnet = NetworkIdentifier{Blockchain: Blockchain, Network: "testnetnet"}
+
+func newFakeQuerier() *fakeQuerier {
+	return &fakeQuerier{
+		results: map[string]string{
+			"FROM visor_network":  `[{"Name": "testnetnet", "Genesis": "bafygenesis"}]`,
+			"FROM block_headers":  `[{"Height": 100, "Hash": "bafy1,bafy2", "Timestamp": 1600000000}]`,
+			"FROM id_addresses":   `["f01234"]`,
+			"FROM actors":         `[{"Balance": "5000", "Deleted": false}]`,
+			"derived_gas_outputs": `[{"Cid": "bafymsg", "From": "f1abc", "To": "f1def", "Value": "10", "ExitCode": 0, "BaseFeeBurn": "3", "OverEstimationBurn": "0", "MinerTip": "1"}]`,
+			"FROM internal_messages": `[
+				{"Cid": "bafyint", "SourceMessage": "bafymsg", "From": "f1def", "To": "f01000", "Value": "4"},
+				{"Cid": "bafyreward", "SourceMessage": "", "From": "f02", "To": "f01000", "Value": "20"}
+			]`,
+		},
+	}
+}
+
+func TestAccountBalance(t *testing.T) {
+	db := newFakeQuerier()
+	s := NewServer(db)
+
+	height := int64(100)
+	resp, rerr := s.AccountBalance(context.Background(), &AccountBalanceRequest{
+		NetworkIdentifier: mainnet,
+		AccountIdentifier: AccountIdentifier{Address: "f1abc"},
+		BlockIdentifier:   &PartialBlockIdentifier{Index: &height},
+	})
+	require.Nil(t, rerr)
+	assert.Equal(t, BlockIdentifier{Index: 100, Hash: "bafy1,bafy2"}, resp.BlockIdentifier)
+	assert.Equal(t, []Amount{{Value: "5000", Currency: FIL}}, resp.Balances)
+	assert.Equal(t, "f01234", resp.Metadata["id"])
+
+	// the balance was looked up using the resolved id at the requested height
+	last := len(db.params) - 1
+	assert.Equal(t, []interface{}{"f01234", int64(100)}, db.params[last])
+
+	_, rerr = s.AccountBalance(context.Background(), &AccountBalanceRequest{
+		NetworkIdentifier: NetworkIdentifier{Blockchain: Blockchain, Network: "calibrationnet"},
+		AccountIdentifier: AccountIdentifier{Address: "f1abc"},
+	})
+	require.NotNil(t, rerr)
+	assert.Equal(t, ErrInvalidNetwork.Code, rerr.Code)
+}
+
+func TestBlockTransactions(t *testing.T) {
+	s := NewServer(newFakeQuerier())
+
+	height := int64(100)
+	resp, rerr := s.Block(context.Background(), &BlockRequest{
+		NetworkIdentifier: mainnet,
+		BlockIdentifier:   PartialBlockIdentifier{Index: &height},
+	})
+	require.Nil(t, rerr)
+	assert.Equal(t, int64(1600000000000), resp.Block.Timestamp)
+	require.Len(t, resp.Block.Transactions, 2)
+
+	ops := func(tx Transaction) []string {
+		var out []string
+		for _, op := range tx.Operations {
+			out = append(out, op.Type+" "+op.Account.Address+" "+op.Amount.Value)
+		}
+		return out
+	}
+
+	tx := resp.Block.Transactions[0]
+	assert.Equal(t, "bafymsg", tx.TransactionIdentifier.Hash)
+	assert.Equal(t, []string{
+		"Transfer f1abc -10",
+		"Transfer f1def 10",
+		"BaseFeeBurn f1abc -3",
+		"BaseFeeBurn f099 3",
+		"MinerTip f1abc -1",
+		"MinerTip f02 1",
+		"InternalTransfer f1def -4",
+		"InternalTransfer f01000 4",
+	}, ops(tx))
+
+	// sends made by the system are transactions of their own
+	tx = resp.Block.Transactions[1]
+	assert.Equal(t, "bafyreward", tx.TransactionIdentifier.Hash)
+	assert.Equal(t, []string{
+		"BlockReward f02 -20",
+		"BlockReward f01000 20",
+	}, ops(tx))
+}
+
+func TestFailedTransfer(t *testing.T) {
+	tx := transaction(&gasOutputRow{Cid: "bafymsg", From: "f1abc", To: "f1def", Value: "10", ExitCode: 16, BaseFeeBurn: "3"})
+	require.Len(t, tx.Operations, 4)
+	assert.Equal(t, StatusFailed, tx.Operations[0].Status)
+	assert.Equal(t, StatusFailed, tx.Operations[1].Status)
+	assert.Equal(t, StatusOK, tx.Operations[2].Status)
+	assert.Equal(t, StatusOK, tx.Operations[3].Status)
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(NewServer(newFakeQuerier()))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/network/list", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"network_identifiers":[{"blockchain":"Filecoin","network":"testnetnet"}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/block", strings.NewReader(`{"network_identifier":{"blockchain":"Filecoin","network":"testnetnet"},"block_identifier":{}}`)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var rerr Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rerr))
+	assert.Equal(t, ErrInvalidRequest.Code, rerr.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/network/list", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
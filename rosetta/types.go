@@ -0,0 +1,150 @@
+package rosetta
+
+// The types below are the subset of the Rosetta Data API object model used by the server. Field names follow the
+// Rosetta specification at https://www.rosetta-api.org/docs/Reference.html.
+
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+type BlockIdentifier struct {
+	Index int64  `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+type PartialBlockIdentifier struct {
+	Index *int64  `json:"index,omitempty"`
+	Hash  *string `json:"hash,omitempty"`
+}
+
+type AccountIdentifier struct {
+	Address string `json:"address"`
+}
+
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+type Amount struct {
+	Value    string   `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+type Operation struct {
+	OperationIdentifier OperationIdentifier   `json:"operation_identifier"`
+	RelatedOperations   []OperationIdentifier `json:"related_operations,omitempty"`
+	Type                string                `json:"type"`
+	Status              string                `json:"status"`
+	Account             *AccountIdentifier    `json:"account,omitempty"`
+	Amount              *Amount               `json:"amount,omitempty"`
+}
+
+type Transaction struct {
+	TransactionIdentifier TransactionIdentifier  `json:"transaction_identifier"`
+	Operations            []Operation            `json:"operations"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type Block struct {
+	BlockIdentifier       BlockIdentifier `json:"block_identifier"`
+	ParentBlockIdentifier BlockIdentifier `json:"parent_block_identifier"`
+	Timestamp             int64           `json:"timestamp"` // milliseconds since the unix epoch
+	Transactions          []Transaction   `json:"transactions"`
+}
+
+type Peer struct {
+	PeerID string `json:"peer_id"`
+}
+
+type Version struct {
+	RosettaVersion    string `json:"rosetta_version"`
+	NodeVersion       string `json:"node_version"`
+	MiddlewareVersion string `json:"middleware_version,omitempty"`
+}
+
+type OperationStatus struct {
+	Status     string `json:"status"`
+	Successful bool   `json:"successful"`
+}
+
+type Allow struct {
+	OperationStatuses       []OperationStatus `json:"operation_statuses"`
+	OperationTypes          []string          `json:"operation_types"`
+	Errors                  []*Error          `json:"errors"`
+	HistoricalBalanceLookup bool              `json:"historical_balance_lookup"`
+}
+
+// An Error is returned in the body of any request that fails.
+type Error struct {
+	Code      int32                  `json:"code"`
+	Message   string                 `json:"message"`
+	Retriable bool                   `json:"retriable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type MetadataRequest struct{}
+
+type NetworkRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+}
+
+type NetworkListResponse struct {
+	NetworkIdentifiers []NetworkIdentifier `json:"network_identifiers"`
+}
+
+type NetworkStatusResponse struct {
+	CurrentBlockIdentifier BlockIdentifier `json:"current_block_identifier"`
+	CurrentBlockTimestamp  int64           `json:"current_block_timestamp"`
+	GenesisBlockIdentifier BlockIdentifier `json:"genesis_block_identifier"`
+	Peers                  []Peer          `json:"peers"`
+}
+
+type NetworkOptionsResponse struct {
+	Version Version `json:"version"`
+	Allow   Allow   `json:"allow"`
+}
+
+type AccountBalanceRequest struct {
+	NetworkIdentifier NetworkIdentifier       `json:"network_identifier"`
+	AccountIdentifier AccountIdentifier       `json:"account_identifier"`
+	BlockIdentifier   *PartialBlockIdentifier `json:"block_identifier,omitempty"`
+}
+
+type AccountBalanceResponse struct {
+	BlockIdentifier BlockIdentifier        `json:"block_identifier"`
+	Balances        []Amount               `json:"balances"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type BlockRequest struct {
+	NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+	BlockIdentifier   PartialBlockIdentifier `json:"block_identifier"`
+}
+
+type BlockResponse struct {
+	Block *Block `json:"block,omitempty"`
+}
+
+type BlockTransactionRequest struct {
+	NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+	BlockIdentifier       BlockIdentifier       `json:"block_identifier"`
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+}
+
+type BlockTransactionResponse struct {
+	Transaction Transaction `json:"transaction"`
+}
+
+type MempoolResponse struct {
+	TransactionIdentifiers []TransactionIdentifier `json:"transaction_identifiers"`
+}
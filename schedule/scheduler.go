@@ -14,7 +14,9 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
 	"github.com/filecoin-project/sentinel-visor/storage"
+	"github.com/filecoin-project/sentinel-visor/validation"
 	"github.com/filecoin-project/sentinel-visor/wait"
 )
 
@@ -64,6 +66,15 @@ type JobConfig struct {
 
 	// RestartDelay is the amount of time to wait before restarting a stopped job
 	RestartDelay time.Duration
+
+	// Limits optionally bounds the lens calls and persist batches the job may have in progress at once. They are
+	// reported with the job's parameters.
+	Limits *chain.JobLimits
+}
+
+// A JobFailureHandler is notified when a job stops with an error other than cancellation.
+type JobFailureHandler interface {
+	JobFailed(ctx context.Context, name string, err error)
 }
 
 // Locker represents a general lock that a job may need to take before operating.
@@ -146,6 +157,14 @@ type Scheduler struct {
 	// if daemonMode is set to true the scheduler will continue to run until its context is canceled.
 	// else the scheduler will exit when all scheduled jobs are complete.
 	daemonMode bool
+
+	failureHandler JobFailureHandler // optional, notified of job failures
+}
+
+// SetJobFailureHandler sets a handler that is notified whenever a job stops with an error. It must be called before
+// Run.
+func (s *Scheduler) SetJobFailureHandler(h JobFailureHandler) {
+	s.failureHandler = h
 }
 
 func (s *Scheduler) Submit(jc *JobConfig) JobID {
@@ -309,6 +328,7 @@ func (s *Scheduler) Jobs() []JobResult {
 func (s *Scheduler) execute(jc *JobConfig, complete chan struct{}) {
 	ctx, cancel := context.WithCancel(s.context)
 	ctx = metrics.WithTagValue(ctx, metrics.Job, jc.Name)
+	ctx = visor.WithJob(ctx, visor.Job{ID: int64(jc.id), Name: jc.Name})
 
 	jc.lk.Lock()
 	jc.cancel = cancel
@@ -383,6 +403,9 @@ func (s *Scheduler) execute(jc *JobConfig, complete chan struct{}) {
 			}
 			jc.log.Errorw("job exited with failure", "error", err.Error())
 			jc.errorMsg = err.Error()
+			if s.failureHandler != nil {
+				s.failureHandler.JobFailed(ctx, jc.Name, err)
+			}
 
 			if !jc.RestartOnFailure {
 				// Exit the job
@@ -401,12 +424,32 @@ func (s *Scheduler) execute(jc *JobConfig, complete chan struct{}) {
 }
 
 func jobDetails(j *JobConfig) (string, map[string]interface{}) {
+	jobType, params := jobTypeParams(j)
+	if j.Limits != nil {
+		if params == nil {
+			params = make(map[string]interface{})
+		}
+		for k, v := range j.Limits.Params() {
+			params[k] = v
+		}
+	}
+	return jobType, params
+}
+
+func jobTypeParams(j *JobConfig) (string, map[string]interface{}) {
 	switch job := j.Job.(type) {
 	case *chain.Walker:
-		job.Params()
 		return "walker", job.Params()
 	case *chain.Watcher:
 		return "watcher", job.Params()
+	case *chain.GapFiller:
+		return "gapfiller", job.Params()
+	case *validation.Reconciler:
+		return "reconciler", job.Params()
+	case *storage.ReportArchiver:
+		return "archiver", job.Params()
+	case *storage.Aggregator:
+		return "aggregator", job.Params()
 	default:
 		return "unknown", nil
 	}
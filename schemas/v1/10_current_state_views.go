@@ -0,0 +1,71 @@
+package v1
+
+// Schema version 1.10 adds views exposing the latest row for each key of tables that record a history of state
+// changes. Rows from reverted tipsets are excluded.
+
+func init() {
+	patches.Register(
+		10,
+		`
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_actors AS
+SELECT DISTINCT ON (a.id) a.*
+FROM {{ .SchemaName | default "public"}}.actors a
+WHERE a.is_canonical
+AND NOT EXISTS (
+    SELECT 1 FROM {{ .SchemaName | default "public"}}.actor_deletions d
+    WHERE d.id = a.id AND d.is_canonical AND d.height > a.height
+)
+ORDER BY a.id, a.height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_actors IS 'Latest state of each actor that has not since been deleted. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_miner_infos AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.miner_infos
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_miner_infos IS 'Latest info of each miner. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_power_actor_claims AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.power_actor_claims
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_power_actor_claims IS 'Latest power claim of each miner. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_miner_locked_funds AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.miner_locked_funds
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_miner_locked_funds IS 'Latest locked funds of each miner. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_miner_fee_debts AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.miner_fee_debts
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_miner_fee_debts IS 'Latest fee debt of each miner. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_miner_current_deadline_infos AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.miner_current_deadline_infos
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_miner_current_deadline_infos IS 'Latest deadline info of each miner. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_market_deal_states AS
+SELECT DISTINCT ON (deal_id) *
+FROM {{ .SchemaName | default "public"}}.market_deal_states
+WHERE is_canonical
+ORDER BY deal_id, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_market_deal_states IS 'Latest state of each market deal. Rows from reverted tipsets are excluded.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_id_addresses AS
+SELECT DISTINCT ON (address) *
+FROM {{ .SchemaName | default "public"}}.id_addresses
+WHERE is_canonical
+ORDER BY address, height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_id_addresses IS 'Latest ID address assigned to each robust address. Rows from reverted tipsets are excluded.';
+`,
+	)
+}
@@ -0,0 +1,22 @@
+package v1
+
+// Schema version 1.11 stores the raw parameters and return values of messages alongside their decoded forms and
+// indexes the decoded forms so they can be queried directly.
+
+func init() {
+	patches.Register(
+		11,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.parsed_messages ADD COLUMN IF NOT EXISTS raw_params bytea;
+CREATE INDEX IF NOT EXISTS parsed_messages_params_idx ON {{ .SchemaName | default "public"}}.parsed_messages USING gin (params jsonb_path_ops);
+
+ALTER TABLE {{ .SchemaName | default "public"}}.receipts ADD COLUMN IF NOT EXISTS raw_return bytea;
+ALTER TABLE {{ .SchemaName | default "public"}}.receipts ADD COLUMN IF NOT EXISTS parsed_return jsonb;
+CREATE INDEX IF NOT EXISTS receipts_parsed_return_idx ON {{ .SchemaName | default "public"}}.receipts USING gin (parsed_return jsonb_path_ops);
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.parsed_messages.raw_params IS 'Method parameters of the message as cbor encoded bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.raw_return IS 'Value returned by the method as cbor encoded bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.parsed_return IS 'Value returned by the method decoded and serialized as JSON.';
+`,
+	)
+}
@@ -0,0 +1,20 @@
+package v1
+
+// Schema version 1.12 adds a history table that old processing reports are archived to so the table consulted while
+// indexing stays small. Any column added to visor_processing_reports must also be added to the history table.
+
+func init() {
+	patches.Register(
+		12,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_processing_reports_history (
+    LIKE {{ .SchemaName | default "public"}}.visor_processing_reports INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES
+);
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports_history ADD COLUMN IF NOT EXISTS archived_at timestamp with time zone NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS visor_processing_reports_completed_at_idx ON {{ .SchemaName | default "public"}}.visor_processing_reports USING btree (completed_at);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_processing_reports_history IS 'Processing reports that have been archived from visor_processing_reports after the retention period.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports_history.archived_at IS 'Time the report was moved to the history table.';
+`,
+	)
+}
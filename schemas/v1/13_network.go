@@ -0,0 +1,24 @@
+package v1
+
+// Schema version 1.13 records the network that the data in the schema was extracted from so that indexers connected
+// to a different network can refuse to write to it. The unique index on a constant allows at most one row.
+
+func init() {
+	patches.Register(
+		13,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_network (
+	"name" text NOT NULL,
+	genesis text NOT NULL,
+	recorded_at timestamp with time zone NOT NULL,
+	PRIMARY KEY ("name", genesis)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS visor_network_single_row_idx ON {{ .SchemaName | default "public"}}.visor_network USING btree ((true));
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_network IS 'The network that all data in this schema was extracted from. Data from different networks must be held in separate schemas.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_network.name IS 'Name of the network as reported by the node, such as testnetnet for mainnet or calibrationnet for calibnet.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_network.genesis IS 'CID of the genesis block of the network.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_network.recorded_at IS 'Time the network was first recorded by an indexer.';
+`,
+	)
+}
@@ -0,0 +1,53 @@
+package v1
+
+// Schema version 1.14 converts the remaining token amount columns from text to numeric so they can be aggregated
+// without casting, and adds constraints rejecting negative amounts. Views that depend on the converted columns are
+// dropped and recreated unchanged.
+
+func init() {
+	patches.Register(
+		14,
+		`
+DROP VIEW IF EXISTS {{ .SchemaName | default "public"}}.current_actors;
+DROP VIEW IF EXISTS {{ .SchemaName | default "public"}}.chain_visualizer_blocks_view;
+
+ALTER TABLE {{ .SchemaName | default "public"}}.actors ALTER COLUMN balance TYPE numeric USING balance::numeric;
+ALTER TABLE {{ .SchemaName | default "public"}}.actors ADD CONSTRAINT actors_balance_check CHECK (balance >= 0);
+
+ALTER TABLE {{ .SchemaName | default "public"}}.block_headers ALTER COLUMN parent_base_fee TYPE numeric USING parent_base_fee::numeric;
+ALTER TABLE {{ .SchemaName | default "public"}}.block_headers ADD CONSTRAINT block_headers_parent_base_fee_check CHECK (parent_base_fee >= 0);
+
+ALTER TABLE {{ .SchemaName | default "public"}}.market_deal_proposals
+	ALTER COLUMN storage_price_per_epoch TYPE numeric USING storage_price_per_epoch::numeric,
+	ALTER COLUMN provider_collateral TYPE numeric USING provider_collateral::numeric,
+	ALTER COLUMN client_collateral TYPE numeric USING client_collateral::numeric;
+ALTER TABLE {{ .SchemaName | default "public"}}.market_deal_proposals ADD CONSTRAINT market_deal_proposals_amounts_check CHECK (storage_price_per_epoch >= 0 AND provider_collateral >= 0 AND client_collateral >= 0);
+
+ALTER TABLE {{ .SchemaName | default "public"}}.multisig_transactions ALTER COLUMN value TYPE numeric USING value::numeric;
+ALTER TABLE {{ .SchemaName | default "public"}}.multisig_transactions ADD CONSTRAINT multisig_transactions_value_check CHECK (value >= 0);
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_actors AS
+SELECT DISTINCT ON (a.id) a.*
+FROM {{ .SchemaName | default "public"}}.actors a
+WHERE a.is_canonical
+AND NOT EXISTS (
+    SELECT 1 FROM {{ .SchemaName | default "public"}}.actor_deletions d
+    WHERE d.id = a.id AND d.is_canonical AND d.height > a.height
+)
+ORDER BY a.id, a.height DESC;
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.current_actors IS 'Latest state of each actor that has not since been deleted. Rows from reverted tipsets are excluded.';
+
+CREATE VIEW {{ .SchemaName | default "public"}}.chain_visualizer_blocks_view AS
+ SELECT block_headers.cid,
+    block_headers.parent_weight,
+    block_headers.parent_state_root,
+    block_headers.height,
+    block_headers.miner,
+    block_headers."timestamp",
+    block_headers.win_count,
+    block_headers.parent_base_fee,
+    block_headers.fork_signaling
+   FROM {{ .SchemaName | default "public"}}.block_headers;
+`,
+	)
+}
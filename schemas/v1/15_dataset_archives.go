@@ -0,0 +1,33 @@
+package v1
+
+// Schema version 1.15 records the ranges of heights whose data has been exported to CAR files by the dataset
+// archiver, so that consumers can find published datasets and the archiver can resume where it stopped.
+
+func init() {
+	patches.Register(
+		15,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_dataset_archives (
+	from_height bigint NOT NULL,
+	to_height bigint NOT NULL,
+	root text NOT NULL,
+	path text NOT NULL,
+	size_bytes bigint NOT NULL,
+	files jsonb NOT NULL,
+	deal_proposal text,
+	archived_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (from_height, to_height)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_dataset_archives IS 'Ranges of heights whose data has been exported as compressed CSV files packed into a CAR file.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.from_height IS 'Lowest height included in the archive.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.to_height IS 'Highest height included in the archive.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.root IS 'CID of the manifest at the root of the CAR file, which links to every exported file.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.path IS 'Path of the CAR file on the host that created it.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.size_bytes IS 'Size of the CAR file in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.files IS 'Table name, CID, row count and compressed size of each file in the archive.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.deal_proposal IS 'CID of the storage deal proposal made for the CAR file, null if no deal was made.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_dataset_archives.archived_at IS 'Time the archive was created.';
+`,
+	)
+}
@@ -0,0 +1,33 @@
+package v1
+
+// Schema version 1.16 records when each block was first received from the gossipsub network, and from which peer, so
+// that propagation latency can be analysed.
+
+func init() {
+	patches.Register(
+		16,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.observed_block_propagation (
+	height bigint NOT NULL,
+	cid text NOT NULL,
+	observer text NOT NULL,
+	miner text NOT NULL,
+	peer_id text NOT NULL,
+	block_time timestamp with time zone NOT NULL,
+	received_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (height, cid, observer)
+);
+CREATE INDEX IF NOT EXISTS observed_block_propagation_miner_idx ON {{ .SchemaName | default "public"}}.observed_block_propagation USING btree (miner);
+CREATE INDEX IF NOT EXISTS observed_block_propagation_received_at_idx ON {{ .SchemaName | default "public"}}.observed_block_propagation USING btree (received_at);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.observed_block_propagation IS 'Time each block was first received by an observer from the gossipsub blocks topic. Subtract block_time from received_at to find the propagation delay.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.height IS 'Epoch of the block.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.cid IS 'CID of the block.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.observer IS 'Name of the visor job that observed the block.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.miner IS 'Address of the miner who mined the block.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.peer_id IS 'ID of the peer the block was first received from.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.block_time IS 'Time the epoch of the block began, taken from the block timestamp.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_block_propagation.received_at IS 'Time the observer first received the block.';
+`,
+	)
+}
@@ -0,0 +1,39 @@
+package v1
+
+// Schema version 1.17 records the lineage of the data persisted by each task so it can be audited and re-extracted if
+// a version of visor is found to have extracted it incorrectly.
+
+func init() {
+	patches.Register(
+		17,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_lineage (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	reporter text NOT NULL,
+	task text NOT NULL,
+	started_at timestamp with time zone NOT NULL,
+	job_id bigint NOT NULL,
+	job_name text NOT NULL,
+	visor_version text NOT NULL,
+	extracted_at timestamp with time zone NOT NULL,
+	tables jsonb NOT NULL,
+	PRIMARY KEY (height, state_root, reporter, task, started_at)
+);
+CREATE INDEX IF NOT EXISTS visor_lineage_visor_version_idx ON {{ .SchemaName | default "public"}}.visor_lineage USING btree (visor_version, height);
+CREATE INDEX IF NOT EXISTS visor_lineage_tables_idx ON {{ .SchemaName | default "public"}}.visor_lineage USING gin (tables);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_lineage IS 'Job and version of visor that produced the data persisted by a task for a tipset. Each row shares its key with the processing report persisted with the data.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.height IS 'Height of the tipset the task reported against.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.state_root IS 'State root of the tipset the task reported against.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.reporter IS 'Name of the visor instance that persisted the data.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.task IS 'Name of the task that extracted the data.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.started_at IS 'Time the task started, matching the started_at of the processing report.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.job_id IS 'ID of the job within the visor instance, zero if the data was not persisted by a scheduled job.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.job_name IS 'Name of the job that persisted the data.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.visor_version IS 'Version of visor that extracted the data.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.extracted_at IS 'Time the data was persisted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_lineage.tables IS 'Object mapping the name of each table written to the number of rows written.';
+`,
+	)
+}
@@ -0,0 +1,45 @@
+package v1
+
+// Schema version 1.18 adds daily rollups of miner power, sector and reward activity.
+
+func init() {
+	patches.Register(
+		18,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_daily_stats (
+	day date NOT NULL,
+	miner_id text NOT NULL,
+	raw_byte_power numeric NOT NULL,
+	quality_adj_power numeric NOT NULL,
+	raw_byte_power_delta numeric NOT NULL,
+	quality_adj_power_delta numeric NOT NULL,
+	sectors_added bigint NOT NULL,
+	sectors_faulted bigint NOT NULL,
+	sectors_terminated bigint NOT NULL,
+	rewards numeric NOT NULL,
+	penalties numeric NOT NULL,
+	from_height bigint NOT NULL,
+	to_height bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (day, miner_id)
+);
+CREATE INDEX IF NOT EXISTS miner_daily_stats_miner_id_idx ON {{ .SchemaName | default "public"}}.miner_daily_stats USING btree (miner_id, day);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_daily_stats IS 'Activity of each miner during a UTC day. Only miners whose power, sectors, rewards or penalties changed during the day have a row. Maintained by visor run miner-stats.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.day IS 'UTC date of the epochs summarised.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.miner_id IS 'Address of the miner.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.raw_byte_power IS 'Raw byte power claimed by the miner at the end of the day.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.quality_adj_power IS 'Quality adjusted power claimed by the miner at the end of the day.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.raw_byte_power_delta IS 'Change in raw byte power during the day.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.quality_adj_power_delta IS 'Change in quality adjusted power during the day.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.sectors_added IS 'Number of sectors added, including committed capacity sectors.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.sectors_faulted IS 'Number of sectors that became faulty.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.sectors_terminated IS 'Number of sectors terminated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.rewards IS 'Block rewards and gas rewards sent to the miner by the reward actor, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.penalties IS 'Funds burned by the miner, such as fault and termination fees, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.from_height IS 'First epoch of the day that had been indexed when the row was computed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.to_height IS 'Last epoch of the day that had been indexed when the row was computed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_daily_stats.updated_at IS 'Time the row was computed.';
+`,
+	)
+}
@@ -0,0 +1,36 @@
+package v1
+
+// Schema version 1.19 adds a graph of the storage deals made between clients and providers.
+
+func init() {
+	patches.Register(
+		19,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.market_deal_edges (
+	client_id text NOT NULL,
+	provider_id text NOT NULL,
+	deal_count bigint NOT NULL,
+	total_bytes numeric NOT NULL,
+	verified_bytes numeric NOT NULL,
+	total_price numeric NOT NULL,
+	first_height bigint NOT NULL,
+	last_height bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (client_id, provider_id)
+);
+CREATE INDEX IF NOT EXISTS market_deal_edges_provider_id_idx ON {{ .SchemaName | default "public"}}.market_deal_edges USING btree (provider_id);
+CREATE INDEX IF NOT EXISTS market_deal_edges_last_height_idx ON {{ .SchemaName | default "public"}}.market_deal_edges USING btree (last_height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.market_deal_edges IS 'Storage deals made between each client and provider, aggregated from market_deal_proposals. Maintained by visor run deal-edges.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.client_id IS 'Address of the client.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.provider_id IS 'Address of the storage provider.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.deal_count IS 'Number of deals proposed between the client and provider.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.total_bytes IS 'Sum of the padded piece sizes of the deals, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.verified_bytes IS 'Sum of the padded piece sizes of the verified deals, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.total_price IS 'Sum of the storage price of each deal over its full duration, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.first_height IS 'Epoch at which the first deal between the client and provider was extracted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.last_height IS 'Epoch at which the latest deal between the client and provider was extracted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_edges.updated_at IS 'Time the row was last updated.';
+`,
+	)
+}
@@ -0,0 +1,69 @@
+package v1
+
+// Schema version 1.1 adds an is_canonical flag to the tables holding rows extracted from tipsets, both height keyed and
+// block keyed, so rows extracted from reverted tipsets can be excluded from queries.
+
+func init() {
+	patches.Register(
+		1,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.actor_states ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.actors ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.block_headers ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.block_messages ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.block_parents ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.chain_economics ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.chain_powers ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.chain_rewards ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.derived_gas_outputs ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.drand_block_entries ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.id_addresses ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.internal_messages ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.market_deal_proposals ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.market_deal_states ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.message_gas_economy ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.messages ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_current_deadline_infos ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_fee_debts ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_infos ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_locked_funds ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_pre_commit_infos ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_sector_events ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_sector_infos ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.multisig_approvals ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.multisig_transactions ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.parsed_messages ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.power_actor_claims ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+ALTER TABLE {{ .SchemaName | default "public"}}.receipts ADD COLUMN IF NOT EXISTS is_canonical boolean NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_states.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actors.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.block_headers.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.block_messages.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.block_parents.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_economics.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_powers.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_rewards.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.derived_gas_outputs.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.drand_block_entries.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.id_addresses.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.internal_messages.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_proposals.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_states.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_economy.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.messages.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_current_deadline_infos.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_fee_debts.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_infos.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_locked_funds.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_infos.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_events.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_infos.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_approvals.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_transactions.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.parsed_messages.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.power_actor_claims.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,30 @@
+package v1
+
+// Schema version 1.20 adds a registry of labels for known addresses.
+
+func init() {
+	patches.Register(
+		20,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.address_tags (
+	address text NOT NULL,
+	label text NOT NULL,
+	category text NOT NULL,
+	id_address text,
+	source text NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (address, label)
+);
+CREATE INDEX IF NOT EXISTS address_tags_id_address_idx ON {{ .SchemaName | default "public"}}.address_tags USING btree (id_address);
+CREATE INDEX IF NOT EXISTS address_tags_category_idx ON {{ .SchemaName | default "public"}}.address_tags USING btree (category);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.address_tags IS 'Labels attached to known addresses such as exchanges, foundation wallets and well-known miners. Imported by visor labels import.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.address IS 'Address as given when the label was imported.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.label IS 'Human readable label for the address.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.category IS 'Kind of entity the address belongs to, such as exchange, foundation or miner. Empty if not known.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.id_address IS 'ID address of the actor, resolved through the lens when the label was imported. Null if the address was not resolved.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.source IS 'Name of the file or list the label was imported from.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.address_tags.updated_at IS 'Time the label was last imported.';
+`,
+	)
+}
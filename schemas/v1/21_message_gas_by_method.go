@@ -0,0 +1,33 @@
+package v1
+
+// Schema version 1.21 adds gas usage aggregated by actor family and method.
+
+func init() {
+	patches.Register(
+		21,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.message_gas_by_method (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	actor_family text NOT NULL,
+	method bigint NOT NULL,
+	message_count bigint NOT NULL,
+	gas_used bigint NOT NULL,
+	gas_limit bigint NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, state_root, actor_family, method)
+);
+CREATE INDEX IF NOT EXISTS message_gas_by_method_height_idx ON {{ .SchemaName | default "public"}}.message_gas_by_method USING btree (height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.message_gas_by_method IS 'Gas used by the messages of each tipset, aggregated by the actor family and method they were sent to.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.height IS 'Epoch of the tipset that included the messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.state_root IS 'CID of the parent state root of the tipset that included the messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.actor_family IS 'Family of the actor the messages were sent to, such as storageminer, or <unknown> if the actor is not a builtin actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.method IS 'Method number the messages invoked. 0 is a plain value transfer.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.message_count IS 'Number of unique messages executed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.gas_used IS 'Total gas used by the messages, from their receipts.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.gas_limit IS 'Total gas limit of the messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_by_method.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,31 @@
+package v1
+
+// Schema version 1.22 adds a comparison of expected and actual block wins for each miner.
+
+func init() {
+	patches.Register(
+		22,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_win_stats (
+	height bigint NOT NULL,
+	window_epochs bigint NOT NULL,
+	miner_id text NOT NULL,
+	expected_wins double precision NOT NULL,
+	actual_wins bigint NOT NULL,
+	blocks bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (height, window_epochs, miner_id)
+);
+CREATE INDEX IF NOT EXISTS miner_win_stats_miner_id_idx ON {{ .SchemaName | default "public"}}.miner_win_stats USING btree (miner_id, window_epochs, height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_win_stats IS 'Expected and actual block wins of each miner over windows of epochs. Only miners with power or blocks in the window have a row. Maintained by visor run win-stats.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.height IS 'Last epoch of the window.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.window_epochs IS 'Number of epochs in the window, which starts at height - window_epochs + 1.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.miner_id IS 'Address of the miner.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.expected_wins IS 'Sum over the non-null epochs of the window of the expected number of wins per epoch multiplied by the share of network quality adjusted power claimed by the miner.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.actual_wins IS 'Sum of the win counts of the canonical blocks mined by the miner in the window.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.blocks IS 'Number of canonical blocks mined by the miner in the window.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_win_stats.updated_at IS 'Time the row was computed.';
+`,
+	)
+}
@@ -0,0 +1,70 @@
+package v1
+
+// Schema version 1.23 adds sector lifetimes and the distribution of the remaining lifetime of active sectors.
+
+func init() {
+	patches.Register(
+		23,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_sector_lifetimes (
+	miner_id text NOT NULL,
+	sector_id bigint NOT NULL,
+	added_height bigint NOT NULL,
+	activation_epoch bigint NOT NULL,
+	expiration_epoch bigint NOT NULL,
+	planned_duration bigint NOT NULL,
+	extended_height bigint,
+	expired_height bigint,
+	terminated_height bigint,
+	actual_duration bigint,
+	terminated_early boolean NOT NULL,
+	initial_pledge numeric NOT NULL,
+	expected_day_reward numeric NOT NULL,
+	expected_storage_pledge numeric NOT NULL,
+	qa_power numeric NOT NULL,
+	estimated_termination_penalty numeric,
+	last_event_height bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (miner_id, sector_id)
+);
+CREATE INDEX IF NOT EXISTS miner_sector_lifetimes_last_event_height_idx ON {{ .SchemaName | default "public"}}.miner_sector_lifetimes USING btree (last_event_height DESC);
+CREATE INDEX IF NOT EXISTS miner_sector_lifetimes_terminated_height_idx ON {{ .SchemaName | default "public"}}.miner_sector_lifetimes USING btree (terminated_height) WHERE terminated_height IS NOT NULL;
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_sector_lifetimes IS 'Lifetime of each sector whose activation has been indexed, from activation to expiration or termination. Maintained by visor run sector-lifetimes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.miner_id IS 'Address of the miner who owns the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.sector_id IS 'Numeric identifier of the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.added_height IS 'Epoch at which the sector was added to the miner state.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.activation_epoch IS 'Epoch at which the sector was activated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.expiration_epoch IS 'Epoch at which the sector is scheduled to expire, including any extensions.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.planned_duration IS 'Number of epochs from activation to the scheduled expiration.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.extended_height IS 'Epoch at which the sector was last extended, null if never extended.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.expired_height IS 'Epoch at which the sector expired, null if it has not expired.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.terminated_height IS 'Epoch at which the sector was terminated, null if it has not been terminated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.actual_duration IS 'Number of epochs from activation to expiration or termination, null while the sector is active.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.terminated_early IS 'True if the sector was terminated before its scheduled expiration.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.initial_pledge IS 'Pledge collected to commit the sector, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.expected_day_reward IS 'Expected one day projection of reward for the sector at activation, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.expected_storage_pledge IS 'Expected twenty day projection of reward for the sector at activation, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.qa_power IS 'Quality adjusted power of the sector, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.estimated_termination_penalty IS 'Estimated termination fee paid when the sector was terminated, in attoFIL. Null if the sector has not been terminated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.last_event_height IS 'Epoch of the latest sector event applied to the row.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_lifetimes.updated_at IS 'Time the row was last updated.';
+
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.miner_sector_remaining_lifetimes AS
+WITH head AS (
+	SELECT max(last_event_height) AS height FROM {{ .SchemaName | default "public"}}.miner_sector_lifetimes
+)
+SELECT
+	l.miner_id,
+	((l.expiration_epoch - head.height) / 86400) * 30 AS remaining_days,
+	count(*) AS sectors,
+	sum(l.qa_power) AS qa_power,
+	sum(l.initial_pledge) AS initial_pledge
+FROM {{ .SchemaName | default "public"}}.miner_sector_lifetimes l, head
+WHERE l.expired_height IS NULL AND l.terminated_height IS NULL AND l.expiration_epoch > head.height
+GROUP BY l.miner_id, remaining_days;
+
+COMMENT ON VIEW {{ .SchemaName | default "public"}}.miner_sector_remaining_lifetimes IS 'Active sectors of each miner grouped by remaining lifetime in 30 day buckets, relative to the latest sector event applied to miner_sector_lifetimes.';
+`,
+	)
+}
@@ -0,0 +1,39 @@
+package v1
+
+// Schema version 1.24 adds daily tracking of the datacap granted to and used by verified clients.
+
+func init() {
+	patches.Register(
+		24,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.verified_client_datacap_usage (
+	day date NOT NULL,
+	client_id text NOT NULL,
+	allowance_granted numeric NOT NULL,
+	datacap_used numeric NOT NULL,
+	verified_deals bigint NOT NULL,
+	cumulative_granted numeric NOT NULL,
+	cumulative_used numeric NOT NULL,
+	remaining numeric NOT NULL,
+	from_height bigint NOT NULL,
+	to_height bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (day, client_id)
+);
+CREATE INDEX IF NOT EXISTS verified_client_datacap_usage_client_id_idx ON {{ .SchemaName | default "public"}}.verified_client_datacap_usage USING btree (client_id, day);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.verified_client_datacap_usage IS 'Datacap granted to and used by each verified client during a UTC day. Only clients granted or using datacap during the day have a row. Maintained by visor run datacap-usage.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.day IS 'UTC date of the epochs summarised.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.client_id IS 'ID address of the verified client, or the address given in the grant if it could not be resolved.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.allowance_granted IS 'Datacap granted to the client by notaries during the day, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.datacap_used IS 'Padded size of the verified deals proposed by the client during the day, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.verified_deals IS 'Number of verified deals proposed by the client during the day.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.cumulative_granted IS 'Datacap granted to the client up to the end of the day, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.cumulative_used IS 'Datacap used by the client up to the end of the day, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.remaining IS 'Cumulative datacap granted less cumulative datacap used, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.from_height IS 'First epoch of the day that had been indexed when the row was computed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.to_height IS 'Last epoch of the day that had been indexed when the row was computed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verified_client_datacap_usage.updated_at IS 'Time the row was computed.';
+`,
+	)
+}
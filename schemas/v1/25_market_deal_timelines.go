@@ -0,0 +1,54 @@
+package v1
+
+// Schema version 1.25 adds a timeline of the state transitions of each market deal.
+
+func init() {
+	patches.Register(
+		25,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.market_deal_timelines (
+	deal_id bigint NOT NULL,
+	client_id text NOT NULL,
+	provider_id text NOT NULL,
+	piece_cid text NOT NULL,
+	padded_piece_size bigint NOT NULL,
+	is_verified boolean NOT NULL,
+	start_epoch bigint NOT NULL,
+	end_epoch bigint NOT NULL,
+	published_height bigint NOT NULL,
+	activated_height bigint,
+	sector_start_epoch bigint,
+	slashed_height bigint,
+	slash_epoch bigint,
+	state text NOT NULL,
+	ended_epoch bigint,
+	last_height bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (deal_id)
+);
+CREATE INDEX IF NOT EXISTS market_deal_timelines_client_id_idx ON {{ .SchemaName | default "public"}}.market_deal_timelines USING btree (client_id);
+CREATE INDEX IF NOT EXISTS market_deal_timelines_provider_id_idx ON {{ .SchemaName | default "public"}}.market_deal_timelines USING btree (provider_id);
+CREATE INDEX IF NOT EXISTS market_deal_timelines_last_height_idx ON {{ .SchemaName | default "public"}}.market_deal_timelines USING btree (last_height DESC);
+CREATE INDEX IF NOT EXISTS market_deal_timelines_open_idx ON {{ .SchemaName | default "public"}}.market_deal_timelines USING btree (state) WHERE state IN ('published', 'active');
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.market_deal_timelines IS 'State transitions of each market deal whose proposal has been indexed. Maintained by visor run deal-timelines.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.deal_id IS 'Identifier for the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.client_id IS 'Address of the client.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.provider_id IS 'Address of the storage provider.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.piece_cid IS 'CID of the piece stored by the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.padded_piece_size IS 'Padded size of the piece, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.is_verified IS 'True if the deal is a verified deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.start_epoch IS 'Epoch by which the deal must be activated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.end_epoch IS 'Epoch at which the deal expires.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.published_height IS 'Epoch at which the deal proposal was first extracted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.activated_height IS 'Epoch at which the deal state first recorded the deal as activated, null if not activated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.sector_start_epoch IS 'Epoch at which the sector containing the deal was activated, null if not activated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.slashed_height IS 'Epoch at which the deal state first recorded the deal as slashed, null if not slashed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.slash_epoch IS 'Epoch at which the deal was slashed, null if not slashed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.state IS 'State of the deal: published, active, slashed, expired or timed_out.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.ended_epoch IS 'Epoch at which the deal was slashed, expired or timed out, null while the deal is published or active.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.last_height IS 'Epoch of the latest proposal or deal state applied to the row.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_timelines.updated_at IS 'Time the row was last updated.';
+`,
+	)
+}
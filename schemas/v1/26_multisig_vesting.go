@@ -0,0 +1,38 @@
+package v1
+
+// Schema version 1.26 adds the vesting balances of genesis multisigs.
+
+func init() {
+	patches.Register(
+		26,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.multisig_vesting (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	multisig_id text NOT NULL,
+	start_epoch bigint NOT NULL,
+	unlock_duration bigint NOT NULL,
+	initial_balance numeric NOT NULL,
+	balance numeric NOT NULL,
+	locked_balance numeric NOT NULL,
+	vested_balance numeric NOT NULL,
+	available numeric NOT NULL,
+	PRIMARY KEY (height, state_root, multisig_id)
+);
+CREATE INDEX IF NOT EXISTS multisig_vesting_height_idx ON {{ .SchemaName | default "public"}}.multisig_vesting USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS multisig_vesting_multisig_id_idx ON {{ .SchemaName | default "public"}}.multisig_vesting USING btree (multisig_id);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.multisig_vesting IS 'Locked and vested balances of the multisigs created at genesis with a vesting schedule, extracted at each tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.height IS 'Epoch at which the balances were calculated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.multisig_id IS 'ID address of the multisig actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.start_epoch IS 'Epoch at which the vesting schedule starts.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.unlock_duration IS 'Number of epochs over which the initial balance vests.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.initial_balance IS 'Balance subject to the vesting schedule, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.balance IS 'Balance of the multisig actor, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.locked_balance IS 'Part of the initial balance that has not yet vested, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.vested_balance IS 'Part of the initial balance that has vested, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.multisig_vesting.available IS 'Balance that may be spent, the balance less the locked balance, in attoFIL.';
+`,
+	)
+}
@@ -0,0 +1,41 @@
+package v1
+
+// Schema version 1.27 adds message throughput and block space utilization for each tipset.
+
+func init() {
+	patches.Register(
+		27,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.chain_throughput (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	block_count bigint NOT NULL,
+	bls_message_count bigint NOT NULL,
+	secp_message_count bigint NOT NULL,
+	included_message_count bigint NOT NULL,
+	message_bytes bigint NOT NULL,
+	included_message_bytes bigint NOT NULL,
+	gas_limit_unique_total bigint NOT NULL,
+	gas_limit_capacity bigint NOT NULL,
+	utilization double precision NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, state_root)
+);
+CREATE INDEX IF NOT EXISTS chain_throughput_height_idx ON {{ .SchemaName | default "public"}}.chain_throughput USING btree (height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.chain_throughput IS 'Messages included in the blocks of each tipset and the block space they used.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.height IS 'Epoch of the tipset that included the messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.state_root IS 'CID of the parent state root of the tipset that included the messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.block_count IS 'Number of blocks in the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.bls_message_count IS 'Number of unique BLS signed messages included in the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.secp_message_count IS 'Number of unique secp256k1 signed messages included in the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.included_message_count IS 'Number of messages included in the blocks of the tipset, counting a message once for each block that included it.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.message_bytes IS 'Total serialized size of the unique messages, including the signatures of secp256k1 signed messages, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.included_message_bytes IS 'Total serialized size of the messages included in the blocks of the tipset, counting a message once for each block that included it, in bytes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.gas_limit_unique_total IS 'Total gas limit of the unique messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.gas_limit_capacity IS 'Maximum gas limit of the messages that could have been included in the tipset: the block gas limit multiplied by the number of blocks.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.utilization IS 'Fraction of the block space used: the total gas limit of the unique messages divided by the gas limit capacity.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_throughput.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,23 @@
+package v1
+
+// Schema version 1.28 records the tenants that have been provisioned with their own schema of views over the data
+// tables so the views can be recreated after each migration.
+
+func init() {
+	patches.Register(
+		28,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_tenants (
+	"name" text NOT NULL,
+	reader_role text NOT NULL,
+	created_at timestamp with time zone NOT NULL,
+	PRIMARY KEY ("name")
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_tenants IS 'Tenants provisioned with a postgresql schema of read only views over the data tables in this schema.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_tenants.name IS 'Name of the tenant, which is also the name of its postgresql schema.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_tenants.reader_role IS 'Role granted read access to the views in the tenant schema.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_tenants.created_at IS 'Time the tenant was first provisioned.';
+`,
+	)
+}
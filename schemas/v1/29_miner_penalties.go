@@ -0,0 +1,35 @@
+package v1
+
+// Schema version 1.29 adds the penalties charged to each miner per epoch.
+
+func init() {
+	patches.Register(
+		29,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_penalties (
+	height bigint NOT NULL,
+	miner_id text NOT NULL,
+	fault_fee numeric NOT NULL,
+	termination_fee numeric NOT NULL,
+	consensus_fault_penalty numeric NOT NULL,
+	debt_repaid numeric NOT NULL,
+	total_burnt numeric NOT NULL,
+	fee_debt_change numeric NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (height, miner_id)
+);
+CREATE INDEX IF NOT EXISTS miner_penalties_miner_id_idx ON {{ .SchemaName | default "public"}}.miner_penalties USING btree (miner_id, height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_penalties IS 'Penalties charged to each miner per epoch, derived from transfers to the burnt funds actor and changes in fee debt. Maintained by visor run miner-penalties.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.height IS 'Epoch at which the penalties were charged.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.miner_id IS 'Address of the miner that was penalized.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.fault_fee IS 'Fault fees and other penalties charged by cron or declared faults that were burnt, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.termination_fee IS 'Termination fees burnt for sectors terminated by the miner or by cron, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.consensus_fault_penalty IS 'Penalties burnt as a result of reported consensus faults, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.debt_repaid IS 'Fee debt from earlier penalties burnt while executing other messages sent to the miner, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.total_burnt IS 'Total transferred by the miner to the burnt funds actor, in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.fee_debt_change IS 'Change in the fee debt of the miner, in attoFIL. Positive when penalties exceeded the funds available to pay them.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_penalties.updated_at IS 'Time the row was last computed.';
+`,
+	)
+}
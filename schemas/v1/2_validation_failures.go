@@ -0,0 +1,29 @@
+package v1
+
+// Schema version 1.2 adds a table to record violations of the data validation rules.
+
+func init() {
+	patches.Register(
+		2,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_validation_failures (
+    height bigint NOT NULL,
+    rule text NOT NULL,
+    subject text NOT NULL,
+    details text,
+    reporter text NOT NULL,
+    detected_at timestamp with time zone NOT NULL
+);
+ALTER TABLE ONLY {{ .SchemaName | default "public"}}.visor_validation_failures ADD CONSTRAINT visor_validation_failures_pkey PRIMARY KEY (height, rule, subject);
+CREATE INDEX IF NOT EXISTS visor_validation_failures_rule_idx ON {{ .SchemaName | default "public"}}.visor_validation_failures USING btree (rule, height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_validation_failures IS 'Violations of data validation rules found by visor verify rules.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.height IS 'Epoch of the data that violated the rule.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.rule IS 'Name of the rule that was violated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.subject IS 'Identifier of the entity that violated the rule, such as an actor address.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.details IS 'Description of the violation.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.reporter IS 'Name of the visor instance that detected the violation.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_validation_failures.detected_at IS 'Time the violation was detected.';
+`,
+	)
+}
@@ -0,0 +1,48 @@
+package v1
+
+// Schema version 1.30 records the responses of miners to retrieval queries made by an observer, so that the
+// availability and price of retrievals can be analysed.
+
+func init() {
+	patches.Register(
+		30,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.observed_retrieval_events (
+	observed_at timestamp with time zone NOT NULL,
+	observer text NOT NULL,
+	miner_id text NOT NULL,
+	payload_cid text NOT NULL,
+	piece_cid text,
+	deal_id bigint NOT NULL,
+	event text NOT NULL,
+	peer_id text,
+	size bigint NOT NULL,
+	min_price numeric,
+	unseal_price numeric,
+	payment_interval bigint NOT NULL,
+	payment_interval_increase bigint NOT NULL,
+	response_time_ms bigint NOT NULL,
+	error text,
+	PRIMARY KEY (observed_at, observer, miner_id, payload_cid)
+);
+CREATE INDEX IF NOT EXISTS observed_retrieval_events_miner_id_idx ON {{ .SchemaName | default "public"}}.observed_retrieval_events USING btree (miner_id, observed_at DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.observed_retrieval_events IS 'Responses of miners to retrieval queries for the payload of one of their active deals, made by an observer using the markets client of the lens.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.observed_at IS 'Time the query was made.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.observer IS 'Name of the visor job that made the query.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.miner_id IS 'Address of the miner that was queried.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.payload_cid IS 'CID of the payload root that was queried, taken from the label of the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.piece_cid IS 'CID of the piece holding the payload.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.deal_id IS 'Identifier of the deal the payload was chosen from.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.event IS 'Outcome of the query: offer if the miner offered to serve the retrieval, unavailable if it answered that the payload is unavailable, error if it could not be queried.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.peer_id IS 'ID of the peer that answered for the miner.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.size IS 'Size of the payload offered in bytes, zero if no offer was made.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.min_price IS 'Minimum price asked by the miner for the whole payload, including unsealing, in attoFIL. Null if no offer was made.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.unseal_price IS 'Price asked by the miner to unseal the payload in attoFIL, null if no offer was made.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.payment_interval IS 'Number of bytes the miner sends before requesting the first payment.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.payment_interval_increase IS 'Number of bytes by which the payment interval grows after each payment.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.response_time_ms IS 'Time taken for the query to complete in milliseconds.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_retrieval_events.error IS 'Error or refusal returned for the query, null if an offer was made.';
+`,
+	)
+}
@@ -0,0 +1,19 @@
+package v1
+
+// Schema version 1.31 records the tipset that included the message of each receipt, in which the message was executed,
+// alongside the tipset whose parent state contains the result. The inclusion height and state root match the height
+// and state root of derived_gas_outputs and of the processing reports of the messages task.
+
+func init() {
+	patches.Register(
+		31,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.receipts ADD COLUMN IF NOT EXISTS inclusion_height bigint;
+ALTER TABLE {{ .SchemaName | default "public"}}.receipts ADD COLUMN IF NOT EXISTS inclusion_state_root text;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.state_root IS 'CID of the parent state root of the tipset at height, which contains the result of executing the message.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.inclusion_height IS 'Epoch of the tipset that included the message, in which the message was executed. Null for receipts extracted before schema version 1.31.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.inclusion_state_root IS 'CID of the parent state root of the tipset that included the message. Null for receipts extracted before schema version 1.31.';
+`,
+	)
+}
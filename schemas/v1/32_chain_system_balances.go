@@ -0,0 +1,30 @@
+package v1
+
+// Schema version 1.32 adds the balances of system actors at each epoch so that the supply held by the network can be
+// reconciled with chain economics.
+
+func init() {
+	patches.Register(
+		32,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.chain_system_balances (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	actor_name text NOT NULL,
+	actor_id text NOT NULL,
+	balance numeric NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, state_root, actor_name)
+);
+CREATE INDEX IF NOT EXISTS chain_system_balances_height_idx ON {{ .SchemaName | default "public"}}.chain_system_balances USING btree (height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.chain_system_balances IS 'Balances of the system actors that hold network funds, recorded at every epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.height IS 'Epoch of the tipset whose parent state holds the balance.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.state_root IS 'CID of the parent state root of the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.actor_name IS 'Role of the actor: reward, power, market, burnt_funds, reserve or verifreg_root.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.actor_id IS 'Address of the actor. The verified registry root key is recorded as held in the verified registry state.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.balance IS 'Balance of the actor in attoFIL.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_system_balances.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,44 @@
+package v1
+
+// Schema version 1.33 adds the open and close epochs of each miner's deadlines so the next WindowPoSt due for a
+// miner can be computed from the database.
+
+func init() {
+	patches.Register(
+		33,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_deadline_schedules (
+	height bigint NOT NULL,
+	miner_id text NOT NULL,
+	state_root text NOT NULL,
+	deadline_index bigint NOT NULL,
+	period_start bigint NOT NULL,
+	open bigint NOT NULL,
+	close bigint NOT NULL,
+	challenge bigint NOT NULL,
+	fault_cutoff bigint NOT NULL,
+	partitions bigint NOT NULL,
+	live_sectors bigint NOT NULL,
+	faulty_sectors bigint NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, miner_id, state_root, deadline_index)
+);
+CREATE INDEX IF NOT EXISTS miner_deadline_schedules_height_idx ON {{ .SchemaName | default "public"}}.miner_deadline_schedules USING btree (height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_deadline_schedules IS 'Schedule of the deadlines with partitions to prove in a miner''s current proving period, recorded whenever the miner''s deadlines or proving period start change. A deadline whose close epoch has passed is next due one proving period (2880 epochs) later.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.height IS 'Epoch at which the schedule was recorded.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.miner_id IS 'Address of the miner.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.deadline_index IS 'Index of the deadline within the proving period, from 0 to 47.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.period_start IS 'Epoch at which the miner''s current proving period started.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.open IS 'First epoch at which a WindowPoSt for the deadline may be submitted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.close IS 'First epoch at which a WindowPoSt for the deadline may no longer be submitted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.challenge IS 'Epoch at which the randomness for the deadline''s challenge is drawn.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.fault_cutoff IS 'First epoch at which a fault declaration for the deadline is rejected.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.partitions IS 'Number of partitions assigned to the deadline.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.live_sectors IS 'Number of live sectors in the deadline''s partitions.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.faulty_sectors IS 'Number of faulty sectors in the deadline''s partitions.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_deadline_schedules.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,31 @@
+package v1
+
+// Schema version 1.34 adds the change in each actor's balance per epoch attributed to the messages that caused it, and
+// indexes id_addresses by robust address so the actors can be resolved to their ID addresses.
+
+func init() {
+	patches.Register(
+		34,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.actor_balance_changes (
+	height bigint NOT NULL,
+	address text NOT NULL,
+	kind text NOT NULL,
+	message_cid text NOT NULL,
+	amount numeric NOT NULL,
+	updated_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (height, address, kind, message_cid)
+);
+CREATE INDEX IF NOT EXISTS actor_balance_changes_address_idx ON {{ .SchemaName | default "public"}}.actor_balance_changes USING btree (address, height DESC);
+CREATE INDEX IF NOT EXISTS id_addresses_address_idx ON {{ .SchemaName | default "public"}}.id_addresses USING hash (address);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.actor_balance_changes IS 'Changes in the balance of each actor per epoch, attributed to the message or block reward that caused them. Derived from derived_gas_outputs and internal_messages. Maintained by visor run actor-balance-changes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.height IS 'Epoch recorded for the message or internal message that caused the change.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.address IS 'ID address of the actor whose balance changed. A robust address that id_addresses does not map to an ID is kept as recorded in the source table.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.kind IS 'Cause of the change: transfer for the value of a message, gas for the fees paid by its sender, internal for value sent by actors while executing a message, reward for block rewards paid by the reward actor and implicit for other value sent by cron or system messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.message_cid IS 'CID of the message that caused the change. Empty for changes caused by implicit messages.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.amount IS 'Net change in the balance of the actor, in attoFIL. Negative when funds left the actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_balance_changes.updated_at IS 'Time the row was last computed.';
+`,
+	)
+}
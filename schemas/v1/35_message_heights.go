@@ -0,0 +1,28 @@
+package v1
+
+// Schema version 1.35 adds a lookup table locating each executed message by CID.
+
+func init() {
+	patches.Register(
+		35,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.message_heights (
+	cid text NOT NULL,
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	execution_height bigint NOT NULL,
+	execution_tipset text NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (cid, height, state_root)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.message_heights IS 'Location of each executed message in the chain, keyed by message CID for point lookups. A message included in a tipset that was later reverted may have more than one row, the canonical one is flagged by is_canonical.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.cid IS 'CID of the message.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.height IS 'Epoch of the tipset in which the message was first included.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.state_root IS 'CID of the parent state root of the tipset in which the message was included.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.execution_height IS 'Epoch of the tipset whose parent state contains the result of executing the message.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.execution_tipset IS 'Key of the tipset whose parent state contains the result of executing the message, as a comma separated list of block CIDs.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_heights.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,42 @@
+package v1
+
+// Schema version 1.36 adds a table recording each walk run by the daemon and how far it has progressed, so an
+// interrupted walk can be resumed.
+
+func init() {
+	patches.Register(
+		36,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_walk_jobs (
+	id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	tasks text[] NOT NULL,
+	min_height bigint NOT NULL,
+	max_height bigint NOT NULL,
+	direction text NOT NULL,
+	strict boolean NOT NULL,
+	overwrite boolean NOT NULL,
+	cursor_height bigint,
+	cursor_tipset text,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	completed_at timestamptz
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_walk_jobs IS 'Walks run by the daemon and the last tipset each has fully committed, used to resume interrupted walks.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.id IS 'Identifier of the walk, used to resume it.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.name IS 'Name of the job that ran the walk, also used as the reporter of its processing reports.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.tasks IS 'Tasks performed by the walk.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.min_height IS 'Lowest height of the range of tipsets walked.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.max_height IS 'Highest height of the range of tipsets walked.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.direction IS 'Direction of the walk, either descending or ascending.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.strict IS 'True if the walk stops on the first extraction or persistence error.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.overwrite IS 'True if the walk replaces rows that already exist.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.cursor_height IS 'Height of the last tipset whose task outputs were all committed, null if none has been committed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.cursor_tipset IS 'Key of the last tipset whose task outputs were all committed, as a comma separated list of block CIDs.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.created_at IS 'Time the walk was first started.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.updated_at IS 'Time the cursor was last moved.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.completed_at IS 'Time the walk reached the end of its range, null while it is incomplete.';
+`,
+	)
+}
@@ -0,0 +1,89 @@
+package v1
+
+// Schema version 1.37 adds periodic snapshots of the sectors and precommits of every miner so that full sector sets
+// can be read without replaying the changes recorded in miner_sector_infos and miner_pre_commit_infos from genesis.
+
+func init() {
+	patches.Register(
+		37,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_sector_snapshots (
+	height bigint NOT NULL,
+	miner_id text NOT NULL,
+	sector_id bigint NOT NULL,
+	state_root text NOT NULL,
+	sealed_cid text NOT NULL,
+	activation_epoch bigint,
+	expiration_epoch bigint,
+	deal_weight numeric NOT NULL,
+	verified_deal_weight numeric NOT NULL,
+	initial_pledge numeric NOT NULL,
+	expected_day_reward numeric NOT NULL,
+	expected_storage_pledge numeric NOT NULL,
+	seal_proof bigint,
+	qa_power numeric NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, miner_id, sector_id, state_root)
+);
+CREATE INDEX IF NOT EXISTS miner_sector_snapshots_height_idx ON {{ .SchemaName | default "public"}}.miner_sector_snapshots USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS miner_sector_snapshots_miner_id_idx ON {{ .SchemaName | default "public"}}.miner_sector_snapshots USING hash (miner_id);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_sector_snapshots IS 'Every sector held by every miner, recorded at snapshot epochs.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.height IS 'Epoch of the snapshot.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.miner_id IS 'Address of the miner who owns the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.sector_id IS 'Numeric identifier of the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.sealed_cid IS 'The root CID of the Sealed Sector’s merkle tree. Also called CommR, or "replica commitment".';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.activation_epoch IS 'Epoch during which the sector proof was accepted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.expiration_epoch IS 'Epoch during which the sector expires.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.deal_weight IS 'Integral of active deals over sector lifetime.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.verified_deal_weight IS 'Integral of active verified deals over sector lifetime.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.initial_pledge IS 'Pledge collected to commit this sector (in attoFIL).';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.expected_day_reward IS 'Expected one day projection of reward for sector computed at activation time (in attoFIL).';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.expected_storage_pledge IS 'Expected twenty day projection of reward for sector computed at activation time (in attoFIL).';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.seal_proof IS 'Registered seal proof type of the sector, which determines its size.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.qa_power IS 'Quality adjusted power of the sector in bytes, computed from its size, duration and deal weights in the same way for every actors version.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_snapshots.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots (
+	height bigint NOT NULL,
+	miner_id text NOT NULL,
+	sector_id bigint NOT NULL,
+	state_root text NOT NULL,
+	sealed_cid text NOT NULL,
+	seal_rand_epoch bigint,
+	expiration_epoch bigint,
+	pre_commit_deposit numeric NOT NULL,
+	pre_commit_epoch bigint,
+	deal_weight numeric NOT NULL,
+	verified_deal_weight numeric NOT NULL,
+	is_replace_capacity boolean,
+	replace_sector_deadline bigint,
+	replace_sector_partition bigint,
+	replace_sector_number bigint,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, miner_id, sector_id, state_root)
+);
+CREATE INDEX IF NOT EXISTS miner_pre_commit_snapshots_height_idx ON {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS miner_pre_commit_snapshots_miner_id_idx ON {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots USING hash (miner_id);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots IS 'Every sector precommitted by every miner and not yet proven or expired, recorded at snapshot epochs.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.height IS 'Epoch of the snapshot.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.miner_id IS 'Address of the miner who owns the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.sector_id IS 'Numeric identifier for the sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.sealed_cid IS 'CID of the sealed sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.seal_rand_epoch IS 'Seal challenge epoch. Epoch at which randomness should be drawn to tie Proof-of-Replication to a chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.expiration_epoch IS 'Epoch this sector expires.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.pre_commit_deposit IS 'Amount of FIL (in attoFIL) used as a PreCommit deposit. If the Sector is not ProveCommitted on time, this deposit is removed and burned.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.pre_commit_epoch IS 'Epoch this PreCommit was created.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.deal_weight IS 'Total space*time of submitted deals.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.verified_deal_weight IS 'Total space*time of submitted verified deals.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.is_replace_capacity IS 'Whether to replace a "committed capacity" no-deal sector (requires non-empty DealIDs).';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.replace_sector_deadline IS 'The deadline location of the sector to replace.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.replace_sector_partition IS 'The partition location of the sector to replace.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.replace_sector_number IS 'ID of the committed capacity sector to replace.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_pre_commit_snapshots.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
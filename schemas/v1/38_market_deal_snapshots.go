@@ -0,0 +1,55 @@
+package v1
+
+// Schema version 1.38 adds periodic snapshots of every deal held by the storage market so that the deals active at an
+// epoch can be read without folding the history recorded in market_deal_proposals and market_deal_states.
+
+func init() {
+	patches.Register(
+		38,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.market_deal_snapshots (
+	height bigint NOT NULL,
+	deal_id bigint NOT NULL,
+	state_root text NOT NULL,
+	piece_cid text NOT NULL,
+	padded_piece_size bigint NOT NULL,
+	unpadded_piece_size bigint NOT NULL,
+	is_verified boolean NOT NULL,
+	client_id text NOT NULL,
+	provider_id text NOT NULL,
+	start_epoch bigint NOT NULL,
+	end_epoch bigint NOT NULL,
+	storage_price_per_epoch numeric NOT NULL,
+	provider_collateral numeric NOT NULL,
+	client_collateral numeric NOT NULL,
+	sector_start_epoch bigint NOT NULL,
+	last_update_epoch bigint NOT NULL,
+	slash_epoch bigint NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, deal_id, state_root)
+);
+CREATE INDEX IF NOT EXISTS market_deal_snapshots_height_idx ON {{ .SchemaName | default "public"}}.market_deal_snapshots USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS market_deal_snapshots_provider_id_idx ON {{ .SchemaName | default "public"}}.market_deal_snapshots USING hash (provider_id);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.market_deal_snapshots IS 'Every deal held by the storage market, with its current state, recorded at snapshot epochs.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.height IS 'Epoch of the snapshot.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.deal_id IS 'Identifier for the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.piece_cid IS 'CID of a sector piece. A Piece is an object that represents a whole or part of a File.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.padded_piece_size IS 'The piece size in bytes with padding.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.unpadded_piece_size IS 'The piece size in bytes without padding.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.is_verified IS 'Deal is with a verified provider.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.client_id IS 'Address of the actor proposing the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.provider_id IS 'Address of the actor providing the services.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.start_epoch IS 'The epoch at which this deal with begin. Storage deal must appear in a sealed (proven) sector no later than start_epoch, otherwise it is invalid.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.end_epoch IS 'The epoch at which this deal with end.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.storage_price_per_epoch IS 'The amount of FIL (in attoFIL) that will be transferred from the client to the provider every epoch this deal is active for.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.provider_collateral IS 'The amount of FIL (in attoFIL) the provider has pledged as collateral. The Provider deal collateral is only slashed when a sector is terminated before the deal expires.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.client_collateral IS 'The amount of FIL (in attoFIL) the client has pledged as collateral.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.sector_start_epoch IS 'Epoch this deal was included in a proven sector. -1 if not yet included in proven sector.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.last_update_epoch IS 'Epoch this deal was last updated at. -1 if deal state never updated.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.slash_epoch IS 'Epoch this deal was slashed at. -1 if deal was never slashed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_snapshots.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,45 @@
+package v1
+
+// Schema version 1.39 adds the gas charges made while executing each message, taken from its execution trace, for
+// analysis of changes to the gas model.
+
+func init() {
+	patches.Register(
+		39,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.message_gas_traces (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	message text NOT NULL,
+	call_index bigint NOT NULL,
+	charge_index bigint NOT NULL,
+	call_depth bigint NOT NULL,
+	"to" text NOT NULL,
+	method bigint NOT NULL,
+	name text NOT NULL,
+	total_gas bigint NOT NULL,
+	compute_gas bigint NOT NULL,
+	storage_gas bigint NOT NULL,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, state_root, message, call_index, charge_index)
+);
+CREATE INDEX IF NOT EXISTS message_gas_traces_height_idx ON {{ .SchemaName | default "public"}}.message_gas_traces USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS message_gas_traces_message_idx ON {{ .SchemaName | default "public"}}.message_gas_traces USING hash (message);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.message_gas_traces IS 'Gas charges made while executing each message and the calls it made to other actors, taken from the message execution traces.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.height IS 'Epoch of the tipset the message was included in.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.state_root IS 'CID of the parent state root the message was applied to.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.message IS 'CID of the message.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.call_index IS 'Position of the call in the order calls were made while executing the message, starting from zero for the message itself.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.charge_index IS 'Position of the charge among the charges made by the call.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.call_depth IS 'Depth of the call, zero for the message itself and one for calls made directly by the receiving actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces."to" IS 'Address of the actor receiving the call.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.method IS 'Method number invoked by the call.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.name IS 'Name of the gas charge, such as OnChainMessage, OnMethodInvocation or OnIpldGet.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.total_gas IS 'Total gas charged.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.compute_gas IS 'Gas charged for computation.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.storage_gas IS 'Gas charged for storage.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.message_gas_traces.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
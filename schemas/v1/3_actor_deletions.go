@@ -0,0 +1,29 @@
+package v1
+
+// Schema version 1.3 adds a table to record actors that were removed from the state tree.
+
+func init() {
+	patches.Register(
+		3,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.actor_deletions (
+    height bigint NOT NULL,
+    id text NOT NULL,
+    state_root text NOT NULL,
+    code text NOT NULL,
+    balance numeric NOT NULL,
+    is_canonical boolean NOT NULL DEFAULT true
+);
+ALTER TABLE ONLY {{ .SchemaName | default "public"}}.actor_deletions ADD CONSTRAINT actor_deletions_pkey PRIMARY KEY (height, id, state_root);
+CREATE INDEX IF NOT EXISTS actor_deletions_height_idx ON {{ .SchemaName | default "public"}}.actor_deletions USING btree (height DESC);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.actor_deletions IS 'Actors that were removed from the state tree at an epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.height IS 'Epoch at which the actor was no longer present in the state tree.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.id IS 'Address of the deleted actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.state_root IS 'CID of the state root from which the actor was missing.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.code IS 'Human readable identifier for the type of the actor.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.balance IS 'Balance of the actor in attoFIL in the last state in which it was present.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.actor_deletions.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
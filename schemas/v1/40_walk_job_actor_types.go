@@ -0,0 +1,17 @@
+package v1
+
+// Schema version 1.40 records the types of actor a walk is limited to and whether it defers indexes, so a resumed walk
+// extracts the same actors and maintains indexes in the same way.
+
+func init() {
+	patches.Register(
+		40,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_walk_jobs ADD COLUMN IF NOT EXISTS actor_types text[];
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_walk_jobs ADD COLUMN IF NOT EXISTS defer_indexes boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.actor_types IS 'Families of actors, such as miner or market, whose state is extracted by the walk. Null if the state of every actor is extracted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_walk_jobs.defer_indexes IS 'True if the walk drops the secondary indexes of the tables it writes and rebuilds them once it has finished.';
+`,
+	)
+}
@@ -0,0 +1,35 @@
+package v1
+
+// Schema version 1.41 records the build of visor and the task revisions used by each indexer run and links processing
+// reports to the run that wrote them.
+
+func init() {
+	patches.Register(
+		41,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_runs (
+	id text NOT NULL,
+	reporter text NOT NULL,
+	version text NOT NULL,
+	"commit" text,
+	task_revisions jsonb NOT NULL,
+	started_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (id)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_runs IS 'Indexer runs that have written processing reports, with the build of visor and task revisions they used.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.id IS 'Random identifier of the run, recorded in the run_id column of its processing reports.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.reporter IS 'Name of the indexer, recorded as the reporter of its processing reports.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.version IS 'Version of visor that ran the indexer, in semver format.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.commit IS 'Abbreviated hash of the commit visor was built from. Null when built from a tagged commit or when the commit is not known.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.task_revisions IS 'Revision of the extraction logic of each task run by the indexer, as a JSON object keyed by task name.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_runs.started_at IS 'Time the run first wrote to the database.';
+
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports ADD COLUMN IF NOT EXISTS run_id text;
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports_history ADD COLUMN IF NOT EXISTS run_id text;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports.run_id IS 'Identifier of the run in visor_runs that wrote the report. Null for reports written before runs were recorded.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports_history.run_id IS 'Identifier of the run in visor_runs that wrote the report. Null for reports written before runs were recorded.';
+`,
+	)
+}
@@ -0,0 +1,27 @@
+package v1
+
+// Schema version 1.42 records the secondary indexes dropped for the duration of bulk walks so they can be rebuilt
+// once every walk that deferred them has finished.
+
+func init() {
+	patches.Register(
+		42,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.visor_deferred_indexes (
+	index_name text NOT NULL,
+	table_name text NOT NULL,
+	definition text NOT NULL,
+	holders text[] NOT NULL,
+	deferred_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (index_name)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.visor_deferred_indexes IS 'Secondary indexes dropped while bulk walks write to their tables, to be rebuilt when the walks finish.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_deferred_indexes.index_name IS 'Name of the dropped index.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_deferred_indexes.table_name IS 'Name of the table the index was defined on.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_deferred_indexes.definition IS 'CREATE INDEX statement that recreates the index, as returned by pg_get_indexdef.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_deferred_indexes.holders IS 'Identifiers of the walks still running that deferred the index. The index is rebuilt when the last of them finishes.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_deferred_indexes.deferred_at IS 'Time the index was dropped.';
+`,
+	)
+}
@@ -0,0 +1,22 @@
+package v1
+
+// Schema version 1.43 adds the window PoSt proof type to miner_infos so changes of proof type made by network
+// upgrades are recorded.
+
+func init() {
+	patches.Register(
+		43,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_infos ADD COLUMN IF NOT EXISTS window_post_proof_type bigint;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_infos.window_post_proof_type IS 'Registered proof type used by the miner for window PoSt. Null for rows extracted before the column was added.';
+
+-- Views over miner_infos list its columns as they were when the view was created
+CREATE OR REPLACE VIEW {{ .SchemaName | default "public"}}.current_miner_infos AS
+SELECT DISTINCT ON (miner_id) *
+FROM {{ .SchemaName | default "public"}}.miner_infos
+WHERE is_canonical
+ORDER BY miner_id, height DESC;
+`,
+	)
+}
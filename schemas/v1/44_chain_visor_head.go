@@ -0,0 +1,33 @@
+package v1
+
+// Schema version 1.44 records the latest tipset observed by each watch and the latest tipset indexed by each of its
+// tasks.
+
+func init() {
+	patches.Register(
+		44,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.chain_visor_head (
+	reporter text NOT NULL,
+	task text NOT NULL,
+	observed_height bigint,
+	observed_tipset text,
+	observed_at timestamp with time zone,
+	indexed_height bigint,
+	indexed_state_root text,
+	indexed_at timestamp with time zone,
+	PRIMARY KEY (reporter, task)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.chain_visor_head IS 'Latest chain head observed by each reporter and latest tipset indexed by each of its tasks, for monitoring how far indexing lags behind the chain.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.reporter IS 'Name of the job, as recorded as the reporter of its processing reports.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.task IS 'Name of the task.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.observed_height IS 'Height of the latest chain head observed by the reporter. Null if the reporter does not follow the chain head.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.observed_tipset IS 'Key of the latest chain head observed by the reporter.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.observed_at IS 'Time the latest chain head was observed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.indexed_height IS 'Greatest height of a tipset whose outputs of the task have been persisted by the reporter. Null if none has been persisted.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.indexed_state_root IS 'Parent state root of the tipset at indexed_height.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.chain_visor_head.indexed_at IS 'Time the outputs of the task for the tipset at indexed_height were persisted.';
+`,
+	)
+}
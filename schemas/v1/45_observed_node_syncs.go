@@ -0,0 +1,29 @@
+package v1
+
+// Schema version 1.45 records the sync state of the node a lens is connected to, so that gaps in the data can be
+// correlated with periods when the node was not in sync.
+
+func init() {
+	patches.Register(
+		45,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.observed_node_syncs (
+	observed_at timestamp with time zone NOT NULL,
+	observer text NOT NULL,
+	head_height bigint NOT NULL,
+	head_timestamp timestamp with time zone NOT NULL,
+	behind bigint NOT NULL,
+	workers jsonb NOT NULL,
+	PRIMARY KEY (observed_at, observer)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.observed_node_syncs IS 'Sync state of the node a lens is connected to, recorded periodically by an observer.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.observed_at IS 'Time the sync state was observed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.observer IS 'Name of the visor job that observed the sync state.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.head_height IS 'Height of the chain head of the node.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.head_timestamp IS 'Time at which the chain head of the node was mined.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.behind IS 'Number of epochs between the epoch expected from the time of the observation and the chain head of the node. Values above a few epochs mean the node was not in sync.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.observed_node_syncs.workers IS 'State of each sync worker of the node as a JSON array of objects with worker_id, stage, base_height, target_height, height, error, started_at and ended_at.';
+`,
+	)
+}
@@ -0,0 +1,40 @@
+package v1
+
+// Schema version 1.46 adds a lookup table locating the deals that store a piece or payload by CID, filled from the
+// deal proposals already extracted.
+
+func init() {
+	patches.Register(
+		46,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.market_deal_pieces (
+	height bigint NOT NULL,
+	deal_id bigint NOT NULL,
+	state_root text NOT NULL,
+	piece_cid text NOT NULL,
+	payload_cid text,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, deal_id, state_root)
+);
+CREATE INDEX IF NOT EXISTS market_deal_pieces_piece_cid_idx ON {{ .SchemaName | default "public"}}.market_deal_pieces USING hash (piece_cid);
+CREATE INDEX IF NOT EXISTS market_deal_pieces_payload_cid_idx ON {{ .SchemaName | default "public"}}.market_deal_pieces USING hash (payload_cid) WHERE payload_cid IS NOT NULL;
+
+-- Backfill from the deal proposals already extracted. Labels that are a CIDv0 or a base32 CIDv1 are taken as the
+-- payload CID, others are left null since decoding them needs the market task.
+INSERT INTO {{ .SchemaName | default "public"}}.market_deal_pieces (height, deal_id, state_root, piece_cid, payload_cid, is_canonical)
+SELECT height, deal_id, state_root, piece_cid,
+	CASE WHEN btrim(label) ~ '^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[a-z2-7]{58,})$' THEN btrim(label) END,
+	is_canonical
+FROM {{ .SchemaName | default "public"}}.market_deal_proposals
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.market_deal_pieces IS 'Content addresses of the data stored by each deal published to the storage market, indexed for lookups by CID.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.height IS 'Epoch at which the deal proposal was added to the storage market.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.deal_id IS 'Identifier for the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.piece_cid IS 'CID of the piece stored by the deal.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.payload_cid IS 'CID of the root of the payload stored in the piece, decoded from the deal label. Null if the label does not name a payload CID.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.market_deal_pieces.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,37 @@
+package v1
+
+// Schema version 1.47 records the governance actions of the verified registry root key so that changes to verifiers
+// and their allowances can be audited together with the multisig approvals that made them.
+
+func init() {
+	patches.Register(
+		47,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.verifreg_governance (
+	height bigint NOT NULL,
+	state_root text NOT NULL,
+	event text NOT NULL,
+	address text NOT NULL,
+	parent_height bigint NOT NULL,
+	root_key text NOT NULL,
+	data_cap numeric,
+	previous_data_cap numeric,
+	is_canonical boolean NOT NULL DEFAULT true,
+	PRIMARY KEY (height, state_root, event, address)
+);
+CREATE INDEX IF NOT EXISTS verifreg_governance_height_idx ON {{ .SchemaName | default "public"}}.verifreg_governance USING btree (height DESC);
+CREATE INDEX IF NOT EXISTS verifreg_governance_address_idx ON {{ .SchemaName | default "public"}}.verifreg_governance USING hash (address);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.verifreg_governance IS 'Changes to the verified registry made by its root key. The multisig approvals that applied a change are found in multisig_approvals with multisig_id equal to root_key and height equal to parent_height.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.height IS 'Epoch at which the change was first visible in the state of the verified registry.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.state_root IS 'CID of the parent state root at this epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.event IS 'Name of the change, one of ROOT_KEY_CHANGED, VERIFIER_ADDED, VERIFIER_REMOVED or VERIFIER_DATACAP_INCREASE.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.address IS 'Address of the verifier affected by the change, or of the new root key for ROOT_KEY_CHANGED.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.parent_height IS 'Epoch of the tipset containing the messages that made the change.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.root_key IS 'Address of the root key of the verified registry after the change.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.data_cap IS 'Datacap allowance of the verifier after the change, in bytes. 0 when the verifier was removed and null for ROOT_KEY_CHANGED.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.previous_data_cap IS 'Datacap allowance of the verifier before the change, in bytes. Null for VERIFIER_ADDED and ROOT_KEY_CHANGED.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.verifreg_governance.is_canonical IS 'False if the tipset this row was extracted from has been reverted from the canonical chain.';
+`,
+	)
+}
@@ -0,0 +1,39 @@
+package v1
+
+// Schema version 1.48 records the gas premiums of the messages included in each epoch alongside those of the messages
+// still pending in the message pool, for use by fee estimators.
+
+func init() {
+	patches.Register(
+		48,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.fee_market_observations (
+	height bigint NOT NULL,
+	observer text NOT NULL,
+	observed_at timestamp with time zone NOT NULL,
+	state_root text NOT NULL,
+	base_fee numeric NOT NULL,
+	included_messages bigint NOT NULL,
+	included_min_premium numeric,
+	included_median_premium numeric,
+	pending_messages bigint NOT NULL,
+	pending_min_premium numeric,
+	pending_median_premium numeric,
+	PRIMARY KEY (height, observer)
+);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.fee_market_observations IS 'Gas premiums of the messages included in each epoch compared with those of the messages still pending in the message pool of the node a lens is connected to, recorded by an observer when the epoch became the head of the node.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.height IS 'Epoch of the tipset whose messages were included.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.observer IS 'Name of the visor job that made the observation.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.observed_at IS 'Time the message pool was observed.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.state_root IS 'CID of the parent state root of the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.base_fee IS 'Base fee in attoFIL per unit of gas paid by the messages included in the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.included_messages IS 'Number of distinct messages included in the blocks of the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.included_min_premium IS 'Lowest gas premium in attoFIL per unit of gas of the messages included in the tipset, null if there were none.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.included_median_premium IS 'Median gas premium in attoFIL per unit of gas of the messages included in the tipset, null if there were none.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.pending_messages IS 'Number of messages pending in the message pool after the tipset.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.pending_min_premium IS 'Lowest gas premium in attoFIL per unit of gas of the messages pending in the message pool, null if there were none.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.fee_market_observations.pending_median_premium IS 'Median gas premium in attoFIL per unit of gas of the messages pending in the message pool, null if there were none.';
+`,
+	)
+}
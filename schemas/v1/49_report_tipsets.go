@@ -0,0 +1,17 @@
+package v1
+
+// Schema version 1.49 records the key of the tipset each processing report was written for, since sibling tipsets at
+// the same height usually share a parent state root.
+
+func init() {
+	patches.Register(
+		49,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports ADD COLUMN IF NOT EXISTS tipset text;
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports_history ADD COLUMN IF NOT EXISTS tipset text;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports.tipset IS 'Key of the tipset identified by height and state_root, as a comma separated list of block CIDs. Null for reports written before tipsets were recorded.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports_history.tipset IS 'Key of the tipset identified by height and state_root, as a comma separated list of block CIDs. Null for reports written before tipsets were recorded.';
+`,
+	)
+}
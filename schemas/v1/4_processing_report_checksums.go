@@ -0,0 +1,14 @@
+package v1
+
+// Schema version 1.4 adds a checksum of the data extracted by each task to the processing reports.
+
+func init() {
+	patches.Register(
+		4,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports ADD COLUMN IF NOT EXISTS checksum text;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports.checksum IS 'Hex encoded sha256 hash of the data extracted by the task, used to compare the output of independent visor instances.';
+`,
+	)
+}
@@ -0,0 +1,14 @@
+package v1
+
+// Schema version 1.5 adds a machine readable classification of the errors recorded in processing reports.
+
+func init() {
+	patches.Register(
+		5,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.visor_processing_reports ADD COLUMN IF NOT EXISTS error_class text;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.visor_processing_reports.error_class IS 'Category of the errors detected by the task, one of lens_unavailable, state_missing, decode_error, db_error, timeout or unknown. Null when no errors were detected.';
+`,
+	)
+}
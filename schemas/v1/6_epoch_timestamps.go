@@ -0,0 +1,27 @@
+package v1
+
+// Schema version 1.6 adds a table mapping epochs to their timestamps so time series queries need not join
+// block_headers.
+
+func init() {
+	patches.Register(
+		6,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.epoch_timestamps (
+    height bigint NOT NULL,
+    "timestamp" timestamp with time zone NOT NULL
+);
+ALTER TABLE ONLY {{ .SchemaName | default "public"}}.epoch_timestamps ADD CONSTRAINT epoch_timestamps_pkey PRIMARY KEY (height);
+CREATE INDEX IF NOT EXISTS epoch_timestamps_timestamp_idx ON {{ .SchemaName | default "public"}}.epoch_timestamps USING btree ("timestamp");
+
+-- Backfill from the blocks already indexed. All blocks at an epoch share the same timestamp.
+INSERT INTO {{ .SchemaName | default "public"}}.epoch_timestamps (height, "timestamp")
+SELECT height, to_timestamp(min("timestamp")) FROM {{ .SchemaName | default "public"}}.block_headers GROUP BY height
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.epoch_timestamps IS 'Time at which each epoch with at least one block began. Join on height to convert other tables to a time series.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.epoch_timestamps.height IS 'Epoch.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.epoch_timestamps.timestamp IS 'Time the epoch began, derived from the genesis time and the height.';
+`,
+	)
+}
@@ -0,0 +1,17 @@
+package v1
+
+// Schema version 1.7 indexes messages and receipts by message CID so they can be matched within a height range
+// without scanning every chunk, and corrects the description of the height of each table.
+
+func init() {
+	patches.Register(
+		7,
+		`
+CREATE INDEX IF NOT EXISTS messages_cid_idx ON {{ .SchemaName | default "public"}}.messages USING hash (cid);
+CREATE INDEX IF NOT EXISTS receipts_message_idx ON {{ .SchemaName | default "public"}}.receipts USING hash (message);
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.messages.height IS 'Epoch of the tipset that included this message. The message is executed when the next tipset is produced, see receipts.height.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.receipts.height IS 'Epoch of the tipset whose parent state contains the result of executing the message.';
+`,
+	)
+}
@@ -0,0 +1,24 @@
+package v1
+
+// Schema version 1.8 adds a table describing the code CIDs of the builtin actors.
+
+func init() {
+	patches.Register(
+		8,
+		`
+CREATE TABLE IF NOT EXISTS {{ .SchemaName | default "public"}}.builtin_actor_codes (
+    code text NOT NULL,
+    name text NOT NULL,
+    family text NOT NULL,
+    version bigint NOT NULL
+);
+ALTER TABLE ONLY {{ .SchemaName | default "public"}}.builtin_actor_codes ADD CONSTRAINT builtin_actor_codes_pkey PRIMARY KEY (code);
+
+COMMENT ON TABLE {{ .SchemaName | default "public"}}.builtin_actor_codes IS 'Code CIDs of every builtin actor known to visor. Join on code to avoid hard coding CIDs in queries.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.builtin_actor_codes.code IS 'CID of the actor code.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.builtin_actor_codes.name IS 'Human readable identifier for the actor code, for example fil/3/storageminer.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.builtin_actor_codes.family IS 'Type of the actor independent of version, for example storageminer.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.builtin_actor_codes.version IS 'Actor version encoded in the name, for example 3 for fil/3/storageminer.';
+`,
+	)
+}
@@ -0,0 +1,16 @@
+package v1
+
+// Schema version 1.9 adds version independent fields to miner_sector_infos.
+
+func init() {
+	patches.Register(
+		9,
+		`
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_sector_infos ADD COLUMN IF NOT EXISTS seal_proof bigint;
+ALTER TABLE {{ .SchemaName | default "public"}}.miner_sector_infos ADD COLUMN IF NOT EXISTS qa_power numeric;
+
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_infos.seal_proof IS 'Registered seal proof type of the sector, which determines its size.';
+COMMENT ON COLUMN {{ .SchemaName | default "public"}}.miner_sector_infos.qa_power IS 'Quality adjusted power of the sector in bytes, computed from its size, duration and deal weights in the same way for every actors version.';
+`,
+	)
+}
@@ -35,6 +35,20 @@ func GetPatches(cfg schemas.Config) (*migrations.Collection, error) {
 	return patches.Collection(cfg)
 }
 
+// GetPatch returns the SQL of a single schema patch.
+func GetPatch(cfg schemas.Config, seq int) (string, error) {
+	p, ok := patches.pm[seq]
+	if !ok {
+		return "", xerrors.Errorf("patch %d not found", seq)
+	}
+
+	var buf strings.Builder
+	if err := p.tmpl.Execute(&buf, cfg); err != nil {
+		return "", xerrors.Errorf("execute patch template: %w", err)
+	}
+	return buf.String(), nil
+}
+
 func Version() model.Version {
 	return model.Version{
 		Major: MajorVersion,
@@ -10,4 +10,8 @@ func RegisterSchema(major int) {
 
 type Config struct {
 	SchemaName string // name of the postgresql schema in which any database objects should be created
+
+	// ConflictKeys holds the columns used as the conflict target when upserting into a table, keyed by table name.
+	// Tables that are not listed use the primary key of their model.
+	ConflictKeys map[string][]string
 }
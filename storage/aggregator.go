@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// aggregatorBatchEpochs is the number of epochs applied in each transaction by aggregators that work through ranges of
+// heights.
+const aggregatorBatchEpochs = 2880
+
+// An Aggregator is a job that keeps a table derived from the data extracted by other tasks up to date. Each refresh
+// brings the part of the table that is out of date in line with the data extracted so far.
+type Aggregator struct {
+	table    string                          // name of the table maintained
+	interval time.Duration                   // time between refreshes
+	params   map[string]interface{}          // parameters reported in addition to the interval
+	setup    func(ctx context.Context) error // run once before the first refresh, may be nil
+	refresh  func(ctx context.Context) error
+}
+
+func (a *Aggregator) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	for k, v := range a.params {
+		out[k] = v
+	}
+	out["table"] = a.table
+	out["interval"] = a.interval.String()
+	return out
+}
+
+// Run refreshes the table until the context is done.
+func (a *Aggregator) Run(ctx context.Context) error {
+	if a.setup != nil {
+		if err := a.setup(ctx); err != nil {
+			return xerrors.Errorf("set up %s: %w", a.table, err)
+		}
+		// Only set up once, not when the job is restarted after a failure.
+		a.setup = nil
+	}
+
+	for {
+		if err := a.refresh(ctx); err != nil {
+			return xerrors.Errorf("refresh %s: %w", a.table, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.interval):
+		}
+	}
+}
+
+// newHeightRangeAggregator returns an Aggregator that applies the data extracted at each height to a table in order of
+// height. bounds returns the latest height applied to the table, or -1 if none has been, and the latest height
+// extracted, or -1 if none has been. Heights are only applied once they are lag epochs behind the latest extracted
+// and each refresh applies again the lookback epochs before the latest height applied, so apply must be idempotent
+// unless lookback is zero.
+func newHeightRangeAggregator(table string, lag, lookback int64, interval time.Duration,
+	bounds func(ctx context.Context) (int64, int64, error),
+	apply func(ctx context.Context, from, to int64) error) *Aggregator {
+	return &Aggregator{
+		table:    table,
+		interval: interval,
+		params: map[string]interface{}{
+			"lag":      lag,
+			"lookback": lookback,
+		},
+		refresh: func(ctx context.Context) error {
+			last, latest, err := bounds(ctx)
+			if err != nil {
+				return err
+			}
+
+			ranges := heightRanges(last, latest, lag, lookback, aggregatorBatchEpochs)
+			for _, r := range ranges {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := apply(ctx, r[0], r[1]); err != nil {
+					return xerrors.Errorf("apply heights %d-%d: %w", r[0]+1, r[1], err)
+				}
+			}
+			log.Infow("refreshed aggregate", "table", table, "from", last+1, "to", latest-lag, "batches", len(ranges))
+			return nil
+		},
+	}
+}
+
+// heightRanges returns the ranges of heights to apply given the latest height applied and the latest height
+// extracted, as pairs of the height before the range and the last height of the range. Ranges are at most batch
+// epochs long.
+func heightRanges(last, latest, lag, lookback, batch int64) [][2]int64 {
+	start := last
+	if start >= 0 {
+		start -= lookback
+		if start < -1 {
+			start = -1
+		}
+	}
+	until := latest - lag
+
+	var ranges [][2]int64
+	for from := start; from < until; from += batch {
+		to := from + batch
+		if to > until {
+			to = until
+		}
+		ranges = append(ranges, [2]int64{from, to})
+	}
+	return ranges
+}
+
+// newDailyAggregator returns an Aggregator that maintains a table of daily rollups with a day column by recomputing
+// whole UTC days with refreshDay, which returns the number of rows written. Each refresh recomputes the last day in the
+// table, which may have been incomplete, and the lookback days before it so data indexed late is included. If the
+// table is empty the days start from the day containing the epoch at height from.
+func newDailyAggregator(db *Database, table string, from int64, lookback int, interval time.Duration,
+	refreshDay func(ctx context.Context, day time.Time) (int, error)) *Aggregator {
+	return &Aggregator{
+		table:    table,
+		interval: interval,
+		params: map[string]interface{}{
+			"from":     from,
+			"lookback": lookback,
+		},
+		refresh: func(ctx context.Context) error {
+			days, err := db.rollupDays(ctx, table, lookback, from)
+			if err != nil {
+				return err
+			}
+
+			for _, day := range days {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				rows, err := refreshDay(ctx, day)
+				if err != nil {
+					return xerrors.Errorf("refresh %s: %w", day.Format("2006-01-02"), err)
+				}
+				log.Debugw("refreshed aggregate day", "table", table, "day", day.Format("2006-01-02"), "rows", rows)
+			}
+			log.Infow("refreshed aggregate", "table", table, "days", len(days))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,495 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	init_ "github.com/filecoin-project/sentinel-visor/model/actors/init"
+	"github.com/filecoin-project/sentinel-visor/model/actors/market"
+	"github.com/filecoin-project/sentinel-visor/model/actors/miner"
+	"github.com/filecoin-project/sentinel-visor/model/actors/power"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/chain"
+	"github.com/filecoin-project/sentinel-visor/model/derived"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestHeightRanges(t *testing.T) {
+	// First run starts from the lowest height
+	assert.Equal(t, [][2]int64{{-1, 9}, {9, 19}, {19, 25}}, heightRanges(-1, 30, 5, 100, 10))
+
+	// Later runs apply the lookback again
+	assert.Equal(t, [][2]int64{{17, 27}, {27, 35}}, heightRanges(20, 40, 5, 3, 10))
+
+	// The lookback never goes below the lowest height
+	assert.Equal(t, [][2]int64{{-1, 35}}, heightRanges(20, 40, 5, 100, 100))
+
+	// Nothing to do until heights are lag epochs behind the latest
+	assert.Empty(t, heightRanges(20, 25, 5, 0, 10))
+}
+
+// emptyTestDatabase returns a Database for the test database, which must be at the latest schema version, after
+// emptying the given tables.
+func emptyTestDatabase(ctx context.Context, t *testing.T, tables ...string) (*Database, func()) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("short testing requested")
+	}
+
+	db, cleanup, err := testutil.WaitForExclusiveDatabase(ctx, t)
+	require.NoError(t, err)
+
+	for _, table := range tables {
+		_, err := db.ExecContext(ctx, `TRUNCATE TABLE ?`, pg.Ident(table))
+		require.NoError(t, err, "truncating %s", table)
+	}
+
+	d, err := NewDatabaseFromDB(ctx, db, "public")
+	require.NoError(t, err)
+
+	return d, func() { require.NoError(t, cleanup()) }
+}
+
+func insertModels(ctx context.Context, t *testing.T, d *Database, ms ...interface{}) {
+	t.Helper()
+	for _, m := range ms {
+		_, err := d.db.ModelContext(ctx, m).Insert()
+		require.NoError(t, err, "inserting %T", m)
+	}
+}
+
+func epochTimestamps(day time.Time, from, to int64) []interface{} {
+	var out []interface{}
+	for h := from; h <= to; h++ {
+		out = append(out, &chain.EpochTimestamp{Height: h, Timestamp: day.Add(time.Duration(h-from) * 30 * time.Second)})
+	}
+	return out
+}
+
+func powerClaim(height int64, minerID, raw, qa string) *power.PowerActorClaim {
+	return &power.PowerActorClaim{Height: height, MinerID: minerID, StateRoot: "root", RawBytePower: raw, QualityAdjPower: qa}
+}
+
+func sectorEvent(height int64, minerID string, sectorID uint64, event string) *miner.MinerSectorEvent {
+	return &miner.MinerSectorEvent{Height: height, MinerID: minerID, SectorID: sectorID, StateRoot: "root", Event: event}
+}
+
+func internalMessage(height int64, cid, source, from, to, value string) *messages.InternalMessage {
+	return &messages.InternalMessage{Height: height, Cid: cid, StateRoot: "root", SourceMessage: source, From: from, To: to, Value: value}
+}
+
+func dealProposal(height int64, dealID uint64, client, provider string, size uint64, verified bool, price string, start, end int64) *market.MarketDealProposal {
+	return &market.MarketDealProposal{
+		Height:               height,
+		DealID:               dealID,
+		StateRoot:            "root",
+		PaddedPieceSize:      size,
+		UnpaddedPieceSize:    size,
+		StartEpoch:           start,
+		EndEpoch:             end,
+		ClientID:             client,
+		ProviderID:           provider,
+		ClientCollateral:     "0",
+		ProviderCollateral:   "0",
+		StoragePricePerEpoch: price,
+		PieceCID:             "piece",
+		IsVerified:           verified,
+	}
+}
+
+func gasOutputs(height int64, cid, from, to, value string, exitCode int64, baseFeeBurn, overEstimationBurn, minerTip string) *derived.GasOutputs {
+	return &derived.GasOutputs{
+		Height:             height,
+		Cid:                cid,
+		StateRoot:          "root",
+		From:               from,
+		To:                 to,
+		Value:              value,
+		GasFeeCap:          "0",
+		GasPremium:         "0",
+		ExitCode:           exitCode,
+		ParentBaseFee:      "0",
+		BaseFeeBurn:        baseFeeBurn,
+		OverEstimationBurn: overEstimationBurn,
+		MinerPenalty:       "0",
+		MinerTip:           minerTip,
+		Refund:             "0",
+	}
+}
+
+func TestRefreshMinerDailyStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "epoch_timestamps", "power_actor_claims", "miner_sector_events", "internal_messages", "miner_daily_stats")
+	defer cleanup()
+
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	insertModels(ctx, t, d, epochTimestamps(day.Add(-30*time.Second), 99, 102)...)
+	insertModels(ctx, t, d,
+		powerClaim(99, "f01000", "10", "10"),
+		powerClaim(101, "f01000", "30", "40"),
+		sectorEvent(100, "f01000", 1, miner.SectorAdded),
+		sectorEvent(101, "f01000", 2, miner.CommitCapacityAdded),
+		sectorEvent(101, "f01000", 1, miner.SectorFaulted),
+		internalMessage(100, "reward", "", "f02", "f01000", "5"),
+		internalMessage(102, "penalty", "", "f01000", "f099", "2"),
+	)
+
+	// Recomputing a day replaces its rows
+	for i := 0; i < 2; i++ {
+		n, err := d.RefreshMinerDailyStats(ctx, day.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	var stats []derived.MinerDailyStats
+	require.NoError(t, d.db.ModelContext(ctx, &stats).Select())
+	require.Len(t, stats, 1)
+	s := stats[0]
+	assert.Equal(t, "f01000", s.MinerID)
+	assert.Equal(t, "30", s.RawBytePower)
+	assert.Equal(t, "40", s.QualityAdjPower)
+	assert.Equal(t, "20", s.RawBytePowerDelta)
+	assert.Equal(t, "30", s.QualityAdjPowerDelta)
+	assert.EqualValues(t, 2, s.SectorsAdded)
+	assert.EqualValues(t, 1, s.SectorsFaulted)
+	assert.EqualValues(t, 0, s.SectorsTerminated)
+	assert.Equal(t, "5", s.Rewards)
+	assert.Equal(t, "2", s.Penalties)
+	assert.EqualValues(t, 100, s.FromHeight)
+	assert.EqualValues(t, 102, s.ToHeight)
+}
+
+func TestAddMarketDealEdges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "market_deal_proposals", "market_deal_edges")
+	defer cleanup()
+
+	insertModels(ctx, t, d,
+		dealProposal(10, 1, "f0100", "f0200", 32, true, "2", 100, 110),
+		dealProposal(11, 1, "f0100", "f0200", 32, true, "2", 100, 110), // extracted again
+		dealProposal(12, 2, "f0100", "f0200", 64, false, "1", 100, 150),
+		dealProposal(20, 3, "f0100", "f0200", 16, false, "0", 100, 150),
+	)
+
+	n, err := d.AddMarketDealEdges(ctx, 9, 12)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var edges []derived.MarketDealEdge
+	require.NoError(t, d.db.ModelContext(ctx, &edges).Select())
+	require.Len(t, edges, 1)
+	assert.EqualValues(t, 2, edges[0].DealCount)
+	assert.Equal(t, "96", edges[0].TotalBytes)
+	assert.Equal(t, "32", edges[0].VerifiedBytes)
+	assert.Equal(t, "70", edges[0].TotalPrice)
+	assert.EqualValues(t, 10, edges[0].FirstHeight)
+	assert.EqualValues(t, 12, edges[0].LastHeight)
+
+	// Later heights are added to the existing edge
+	_, err = d.AddMarketDealEdges(ctx, 12, 20)
+	require.NoError(t, err)
+	edges = nil
+	require.NoError(t, d.db.ModelContext(ctx, &edges).Select())
+	require.Len(t, edges, 1)
+	assert.EqualValues(t, 3, edges[0].DealCount)
+	assert.Equal(t, "112", edges[0].TotalBytes)
+	assert.EqualValues(t, 20, edges[0].LastHeight)
+
+	require.NoError(t, d.ResetMarketDealEdges(ctx))
+	count, err := d.db.ModelContext(ctx, (*derived.MarketDealEdge)(nil)).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestRefreshMinerWinStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "chain_powers", "power_actor_claims", "block_headers", "miner_win_stats")
+	defer cleanup()
+
+	for h := int64(100); h < 110; h++ {
+		insertModels(ctx, t, d, &power.ChainPower{
+			Height:                     h,
+			StateRoot:                  "root",
+			TotalRawBytesPower:         "100",
+			TotalQABytesPower:          "100",
+			TotalRawBytesCommitted:     "0",
+			TotalQABytesCommitted:      "0",
+			TotalPledgeCollateral:      "0",
+			QASmoothedPositionEstimate: "0",
+			QASmoothedVelocityEstimate: "0",
+		})
+	}
+	block := func(height int64, cid, minerID string, wins int64) *blocks.BlockHeader {
+		return &blocks.BlockHeader{Height: height, Cid: cid, Miner: minerID, ParentBaseFee: "0", ParentStateRoot: "root", WinCount: wins}
+	}
+	insertModels(ctx, t, d,
+		powerClaim(95, "f01000", "20", "20"),
+		powerClaim(105, "f01000", "40", "40"),
+		block(101, "b1", "f01000", 1),
+		block(102, "b2", "f01000", 2),
+		block(103, "b3", "f01001", 1),
+	)
+
+	n, err := d.RefreshMinerWinStats(ctx, 109, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var stats []derived.MinerWinStats
+	require.NoError(t, d.db.ModelContext(ctx, &stats).Order("miner_id").Select())
+	require.Len(t, stats, 2)
+
+	// 20 of 100 power for 5 epochs then 40 of 100 for 5 epochs at 5 wins per epoch
+	assert.Equal(t, "f01000", stats[0].MinerID)
+	assert.InDelta(t, 15, stats[0].ExpectedWins, 1e-9)
+	assert.EqualValues(t, 3, stats[0].ActualWins)
+	assert.EqualValues(t, 2, stats[0].Blocks)
+
+	// Blocks are counted for miners without a power claim
+	assert.Equal(t, "f01001", stats[1].MinerID)
+	assert.Zero(t, stats[1].ExpectedWins)
+	assert.EqualValues(t, 1, stats[1].ActualWins)
+	assert.EqualValues(t, 1, stats[1].Blocks)
+}
+
+func TestApplySectorLifetimes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "miner_sector_events", "miner_sector_infos", "miner_sector_lifetimes")
+	defer cleanup()
+
+	info := func(height int64, activation, expiration int64) *miner.MinerSectorInfo {
+		return &miner.MinerSectorInfo{
+			Height:                height,
+			MinerID:               "f01000",
+			SectorID:              1,
+			StateRoot:             "root",
+			ActivationEpoch:       activation,
+			ExpirationEpoch:       expiration,
+			DealWeight:            "0",
+			VerifiedDealWeight:    "0",
+			InitialPledge:         "100",
+			ExpectedDayReward:     "10",
+			ExpectedStoragePledge: "20",
+			QAPower:               "32",
+		}
+	}
+	insertModels(ctx, t, d,
+		sectorEvent(100, "f01000", 1, miner.SectorAdded),
+		info(100, 100, 1000),
+		sectorEvent(200, "f01000", 1, miner.SectorExtended),
+		info(200, 100, 2000),
+		sectorEvent(300, "f01000", 1, miner.SectorTerminated),
+		sectorEvent(300, "f01000", 2, miner.SectorTerminated), // never added
+	)
+
+	// Applying a range again changes nothing
+	for i := 0; i < 2; i++ {
+		require.NoError(t, d.ApplySectorLifetimes(ctx, 99, 300))
+	}
+
+	var lifetimes []derived.MinerSectorLifetime
+	require.NoError(t, d.db.ModelContext(ctx, &lifetimes).Select())
+	require.Len(t, lifetimes, 1)
+	l := lifetimes[0]
+	assert.EqualValues(t, 1, l.SectorID)
+	assert.EqualValues(t, 100, l.AddedHeight)
+	assert.EqualValues(t, 100, l.ActivationEpoch)
+	assert.EqualValues(t, 2000, l.ExpirationEpoch)
+	assert.EqualValues(t, 1900, l.PlannedDuration)
+	require.NotNil(t, l.ExtendedHeight)
+	assert.EqualValues(t, 200, *l.ExtendedHeight)
+	assert.Nil(t, l.ExpiredHeight)
+	require.NotNil(t, l.TerminatedHeight)
+	assert.EqualValues(t, 300, *l.TerminatedHeight)
+	require.NotNil(t, l.ActualDuration)
+	assert.EqualValues(t, 200, *l.ActualDuration)
+	assert.True(t, l.TerminatedEarly)
+	assert.NotNil(t, l.EstimatedTerminationPenalty)
+	assert.EqualValues(t, 300, l.LastEventHeight)
+}
+
+func TestRefreshDatacapUsage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "epoch_timestamps", "parsed_messages", "derived_gas_outputs", "internal_parsed_messages",
+		"internal_messages", "id_addresses", "market_deal_proposals", "verified_client_datacap_usage")
+	defer cleanup()
+
+	day1 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	insertModels(ctx, t, d, epochTimestamps(day1, 100, 102)...)
+	insertModels(ctx, t, d, epochTimestamps(day2, 200, 200)...)
+	insertModels(ctx, t, d,
+		&messages.ParsedMessage{Height: 100, Cid: "grant", From: "f0400", To: "f06", Value: "0", Method: addVerifiedClientMethod,
+			Params: `{"Address":"f1client","Allowance":"1000"}`},
+		gasOutputs(100, "grant", "f0400", "f06", "0", 0, "0", "0", "0"),
+		&messages.ParsedMessage{Height: 101, Cid: "failed", From: "f0400", To: "f06", Value: "0", Method: addVerifiedClientMethod,
+			Params: `{"Address":"f1client","Allowance":"5000"}`},
+		gasOutputs(101, "failed", "f0400", "f06", "0", 16, "0", "0", "0"),
+		&init_.IdAddress{Height: 90, ID: "f0300", Address: "f1client", StateRoot: "root"},
+		dealProposal(101, 1, "f0300", "f0200", 256, true, "0", 500, 1000),
+		dealProposal(101, 2, "f0300", "f0200", 512, false, "0", 500, 1000),
+		dealProposal(200, 3, "f0300", "f0200", 100, true, "0", 500, 1000),
+	)
+
+	for _, day := range []time.Time{day1, day2} {
+		n, err := d.RefreshDatacapUsage(ctx, day)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	var usage []derived.VerifiedClientDatacapUsage
+	require.NoError(t, d.db.ModelContext(ctx, &usage).Order("day").Select())
+	require.Len(t, usage, 2)
+
+	// Grants to robust addresses are attributed to the client's ID address
+	assert.Equal(t, "f0300", usage[0].ClientID)
+	assert.Equal(t, "1000", usage[0].AllowanceGranted)
+	assert.Equal(t, "256", usage[0].DatacapUsed)
+	assert.EqualValues(t, 1, usage[0].VerifiedDeals)
+	assert.Equal(t, "744", usage[0].Remaining)
+
+	// Totals accumulate across days
+	assert.Equal(t, "0", usage[1].AllowanceGranted)
+	assert.Equal(t, "100", usage[1].DatacapUsed)
+	assert.Equal(t, "1000", usage[1].CumulativeGranted)
+	assert.Equal(t, "356", usage[1].CumulativeUsed)
+	assert.Equal(t, "644", usage[1].Remaining)
+}
+
+func TestApplyDealTimelines(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "market_deal_proposals", "market_deal_states", "market_deal_timelines")
+	defer cleanup()
+
+	insertModels(ctx, t, d,
+		dealProposal(10, 1, "f0100", "f0200", 32, false, "0", 100, 200),
+		dealProposal(10, 2, "f0100", "f0200", 32, false, "0", 30, 60),
+		&market.MarketDealState{Height: 50, DealID: 1, SectorStartEpoch: 40, LastUpdateEpoch: -1, SlashEpoch: -1, StateRoot: "root"},
+	)
+
+	timelines := func() map[uint64]derived.MarketDealTimeline {
+		var ts []derived.MarketDealTimeline
+		require.NoError(t, d.db.ModelContext(ctx, &ts).Select())
+		out := make(map[uint64]derived.MarketDealTimeline)
+		for _, tl := range ts {
+			out[tl.DealID] = tl
+		}
+		return out
+	}
+
+	require.NoError(t, d.ApplyDealTimelines(ctx, 0, 10))
+	ts := timelines()
+	require.Len(t, ts, 2)
+	assert.Equal(t, derived.DealPublished, ts[1].State)
+	assert.Equal(t, derived.DealPublished, ts[2].State)
+
+	require.NoError(t, d.ApplyDealTimelines(ctx, 10, 50))
+	ts = timelines()
+	assert.Equal(t, derived.DealActive, ts[1].State)
+	require.NotNil(t, ts[1].ActivatedHeight)
+	assert.EqualValues(t, 50, *ts[1].ActivatedHeight)
+	assert.Equal(t, derived.DealTimedOut, ts[2].State)
+	require.NotNil(t, ts[2].EndedEpoch)
+	assert.EqualValues(t, 30, *ts[2].EndedEpoch)
+
+	require.NoError(t, d.ApplyDealTimelines(ctx, 50, 250))
+	ts = timelines()
+	assert.Equal(t, derived.DealExpired, ts[1].State)
+	require.NotNil(t, ts[1].EndedEpoch)
+	assert.EqualValues(t, 200, *ts[1].EndedEpoch)
+}
+
+func TestApplyMinerPenalties(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "power_actor_claims", "messages", "internal_messages", "miner_sector_events",
+		"miner_fee_debts", "miner_penalties")
+	defer cleanup()
+
+	insertModels(ctx, t, d,
+		powerClaim(1, "f01000", "0", "0"),
+		&messages.Message{Height: 100, Cid: "report", From: "f0300", To: "f01000", Value: "0", GasFeeCap: "0", GasPremium: "0",
+			Method: minerReportConsensusFaultMethod},
+		internalMessage(101, "fault", "report", "f01000", "f099", "10"),
+		internalMessage(101, "cron", "", "f01000", "f099", "3"),
+		sectorEvent(101, "f01000", 1, miner.SectorTerminated),
+		&miner.MinerFeeDebt{Height: 100, MinerID: "f01000", StateRoot: "root", FeeDebt: "0"},
+		&miner.MinerFeeDebt{Height: 101, MinerID: "f01000", StateRoot: "root", FeeDebt: "5"},
+	)
+
+	// Applying a range again replaces its rows
+	for i := 0; i < 2; i++ {
+		require.NoError(t, d.ApplyMinerPenalties(ctx, 99, 101))
+	}
+
+	var penalties []derived.MinerPenalty
+	require.NoError(t, d.db.ModelContext(ctx, &penalties).Select())
+	require.Len(t, penalties, 1)
+	p := penalties[0]
+	assert.EqualValues(t, 101, p.Height)
+	assert.Equal(t, "10", p.ConsensusFaultPenalty)
+	assert.Equal(t, "3", p.TerminationFee) // sent by cron when sectors were terminated
+	assert.Equal(t, "0", p.FaultFee)
+	assert.Equal(t, "0", p.DebtRepaid)
+	assert.Equal(t, "13", p.TotalBurnt)
+	assert.Equal(t, "5", p.FeeDebtChange)
+}
+
+func TestApplyActorBalanceChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "derived_gas_outputs", "internal_messages", "id_addresses", "actor_balance_changes")
+	defer cleanup()
+
+	// Messages sent to and from the robust address of f0200 are recorded against its ID address
+	insertModels(ctx, t, d,
+		&init_.IdAddress{Height: 5, ID: "f0200", Address: "f1receiver", StateRoot: "root"},
+		gasOutputs(10, "send", "f0100", "f1receiver", "50", 0, "1", "2", "3"),
+		gasOutputs(10, "failed", "f0100", "f0200", "40", 16, "1", "0", "0"),
+		internalMessage(10, "forward", "send", "f1receiver", "f0300", "20"),
+		internalMessage(10, "unknown", "send", "f0300", "f1unknown", "5"),
+		internalMessage(10, "reward", "", "f02", "f01000", "7"),
+	)
+
+	// Applying a range again replaces its rows
+	for i := 0; i < 2; i++ {
+		require.NoError(t, d.ApplyActorBalanceChanges(ctx, 9, 10))
+	}
+
+	var changes []derived.ActorBalanceChange
+	require.NoError(t, d.db.ModelContext(ctx, &changes).Select())
+	got := make(map[[3]string]string)
+	for _, c := range changes {
+		got[[3]string{c.Address, c.Kind, c.MessageCid}] = c.Amount
+	}
+	assert.Equal(t, map[[3]string]string{
+		{"f0100", "transfer", "send"}:     "-50",
+		{"f0200", "transfer", "send"}:     "50",
+		{"f0100", "gas", "send"}:          "-6",
+		{"f0100", "gas", "failed"}:        "-1",
+		{"f0200", "internal", "send"}:     "-20",
+		{"f0300", "internal", "send"}:     "15",
+		{"f1unknown", "internal", "send"}: "5",
+		{"f02", "reward", ""}:             "-7",
+		{"f01000", "reward", ""}:          "7",
+	}, got)
+}
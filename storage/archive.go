@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// reportsHistoryVersion is the first schema version containing the visor_processing_reports_history table.
+var reportsHistoryVersion = model.Version{Major: 1, Patch: 12}
+
+// reportsSource returns a FROM item holding the named columns of every processing report, including those archived to
+// the history table, so queries about which tasks have completed are not affected by archival. Callers must give it an
+// alias.
+func (d *Database) reportsSource(columns string) string {
+	if d.version.Before(reportsHistoryVersion) {
+		return "visor_processing_reports"
+	}
+	return "(SELECT " + columns + " FROM visor_processing_reports UNION ALL SELECT " + columns + " FROM visor_processing_reports_history)"
+}
+
+// ArchiveProcessingReports moves processing reports that completed before the given time into the
+// visor_processing_reports_history table and returns the number of reports moved. Error reports are kept until a
+// later report records the successful completion of the same task so that gaps remain visible.
+func (d *Database) ArchiveProcessingReports(ctx context.Context, before time.Time) (int, error) {
+	if d.version.Before(reportsHistoryVersion) {
+		return 0, xerrors.Errorf("archiving processing reports requires schema version %s or later", reportsHistoryVersion)
+	}
+
+	var archived int
+	err := d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// Name the columns explicitly since the history table has an extra column and columns added by later schema
+		// versions may not be in the same position in both tables
+		var columns []string
+		if _, err := tx.QueryContext(ctx, &columns, `SELECT quote_ident(column_name::text) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'visor_processing_reports' ORDER BY ordinal_position`); err != nil {
+			return xerrors.Errorf("query report columns: %w", err)
+		}
+		if len(columns) == 0 {
+			return xerrors.Errorf("visor_processing_reports table not found")
+		}
+		cols := strings.Join(columns, ", ")
+
+		res, err := tx.ExecContext(ctx, `
+WITH moved AS (
+	DELETE FROM visor_processing_reports r
+	WHERE r.completed_at < ?0
+	AND (r.status <> ?1 OR EXISTS (
+		SELECT 1 FROM visor_processing_reports s
+		WHERE s.height = r.height AND s.state_root = r.state_root AND s.task = r.task AND s.status IN (?2, ?3)
+	))
+	RETURNING r.*
+)
+INSERT INTO visor_processing_reports_history (`+cols+`, archived_at) SELECT `+cols+`, now() FROM moved`,
+			before, visor.ProcessingStatusError, visor.ProcessingStatusOK, visor.ProcessingStatusInfo)
+		if err != nil {
+			return xerrors.Errorf("move reports: %w", err)
+		}
+		archived = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// NewReportArchiver creates a ReportArchiver that archives processing reports older than retention every interval.
+func NewReportArchiver(db *Database, retention, interval time.Duration) *ReportArchiver {
+	return &ReportArchiver{
+		db:        db,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// A ReportArchiver is a job that periodically moves old processing reports to the history table.
+type ReportArchiver struct {
+	db        *Database
+	retention time.Duration // age after which reports are archived
+	interval  time.Duration // time between archival runs
+}
+
+func (a *ReportArchiver) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["retention"] = a.retention.String()
+	out["interval"] = a.interval.String()
+	return out
+}
+
+// Run archives reports until the context is done.
+func (a *ReportArchiver) Run(ctx context.Context) error {
+	for {
+		archived, err := a.db.ArchiveProcessingReports(ctx, time.Now().Add(-a.retention))
+		if err != nil {
+			return xerrors.Errorf("archive processing reports: %w", err)
+		}
+		log.Infow("archived processing reports", "count", archived, "retention", a.retention)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.interval):
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// actorBalanceChangesVersion is the first schema version containing the actor_balance_changes table.
+var actorBalanceChangesVersion = model.Version{Major: 1, Patch: 34}
+
+// applyActorBalanceChangesSQL computes the rows of actor_balance_changes for heights after ?0 up to and including ?1.
+// The ledger is made of the value of each successful message and the gas fees charged to its sender, taken from
+// derived_gas_outputs, and the value of each successful internal message. Internal messages sent by the reward actor
+// (ID 02) are block rewards, those without a source message were sent by cron or another implicit message. Robust
+// addresses are resolved to ID addresses using id_addresses so the changes of an actor are recorded under one address
+// whichever form the source tables use. Entries for the same actor, kind and message are summed and entries that
+// cancel out are dropped.
+const applyActorBalanceChangesSQL = `
+WITH entries AS (
+	SELECT height, "from" AS address, 'transfer' AS kind, cid AS message_cid, -value AS amount
+	FROM derived_gas_outputs
+	WHERE height > ?0 AND height <= ?1 AND is_canonical AND exit_code = 0 AND value <> 0
+	UNION ALL
+	SELECT height, "to", 'transfer', cid, value
+	FROM derived_gas_outputs
+	WHERE height > ?0 AND height <= ?1 AND is_canonical AND exit_code = 0 AND value <> 0
+	UNION ALL
+	SELECT height, "from", 'gas', cid, -(base_fee_burn + over_estimation_burn + miner_tip)
+	FROM derived_gas_outputs
+	WHERE height > ?0 AND height <= ?1 AND is_canonical
+	UNION ALL
+	SELECT height, address, kind, message_cid, amount
+	FROM (
+		SELECT i.height, i.value,
+			coalesce(i.source_message, '') AS message_cid,
+			CASE
+				WHEN substr(i."from", 2) = '02' THEN 'reward'
+				WHEN coalesce(i.source_message, '') = '' THEN 'implicit'
+				ELSE 'internal'
+			END AS kind,
+			i."from", i."to"
+		FROM internal_messages i
+		WHERE i.height > ?0 AND i.height <= ?1 AND i.is_canonical AND i.exit_code = 0 AND i.value <> 0
+	) t
+	CROSS JOIN LATERAL (VALUES (t."from", -t.value), (t."to", t.value)) AS side(address, amount)
+), resolved AS (
+	SELECT e.height, coalesce(a.id, e.address) AS address, e.kind, e.message_cid, e.amount
+	FROM entries e
+	LEFT JOIN LATERAL (
+		SELECT id FROM id_addresses WHERE address = e.address AND substr(e.address, 2, 1) <> '0' LIMIT 1
+	) a ON true
+)
+INSERT INTO actor_balance_changes (height, address, kind, message_cid, amount, updated_at)
+SELECT height, address, kind, message_cid, sum(amount), now()
+FROM resolved
+GROUP BY height, address, kind, message_cid
+HAVING sum(amount) <> 0`
+
+// ApplyActorBalanceChanges recomputes the actor_balance_changes rows for heights after from up to and including to.
+func (d *Database) ApplyActorBalanceChanges(ctx context.Context, from, to int64) error {
+	if d.version.Before(actorBalanceChangesVersion) {
+		return xerrors.Errorf("actor balance changes require schema version %s or later", actorBalanceChangesVersion)
+	}
+
+	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM actor_balance_changes WHERE height > ? AND height <= ?`, from, to); err != nil {
+			return xerrors.Errorf("delete heights: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, applyActorBalanceChangesSQL, from, to); err != nil {
+			return xerrors.Errorf("insert heights: %w", err)
+		}
+		return nil
+	})
+}
+
+// actorBalanceChangesBounds returns the latest height of any balance change applied, or -1 if there are none, and the
+// latest height extracted to both derived_gas_outputs and internal_messages, or -1 if either is empty.
+func (d *Database) actorBalanceChangesBounds(ctx context.Context) (int64, int64, error) {
+	var last, latest int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last, &latest), `SELECT (SELECT coalesce(max(height), -1) FROM actor_balance_changes), least((SELECT coalesce(max(height), -1) FROM derived_gas_outputs), (SELECT coalesce(max(height), -1) FROM internal_messages))`); err != nil {
+		return 0, 0, xerrors.Errorf("query actor balance changes progress: %w", err)
+	}
+	return last, latest, nil
+}
+
+// NewActorBalanceChangeAggregator returns an Aggregator that keeps the actor_balance_changes table up to date as
+// messages and internal messages are extracted. Changes are only applied once they are lag epochs behind the latest
+// extracted ledger entry. It refreshes every interval and each refresh applies again the lookback epochs before the
+// latest height applied so data persisted out of order is picked up.
+func NewActorBalanceChangeAggregator(db *Database, lag int64, lookback int64, interval time.Duration) *Aggregator {
+	return newHeightRangeAggregator("actor_balance_changes", lag, lookback, interval, db.actorBalanceChangesBounds, db.ApplyActorBalanceChanges)
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+)
+
+// maintenanceDatabase is the database connected to when checking for, or creating, the visor database.
+const maintenanceDatabase = "postgres"
+
+// BootstrapConfig controls which objects Bootstrap may create. Creating the database and roles must be explicitly
+// requested since they are server wide and usually managed separately from visor.
+type BootstrapConfig struct {
+	// AdminURL is the connection string of a role that may create databases, roles and extensions. The database's own
+	// connection is used if empty.
+	AdminURL string
+
+	// CreateDatabase allows the database to be created if it does not exist.
+	CreateDatabase bool
+
+	// CreateRoles allows the role named in the database connection string, and ReaderRole, to be created if they
+	// do not exist.
+	CreateRoles bool
+
+	// ReaderRole is the name of an optional role that is granted read access to all tables in the schema.
+	ReaderRole string
+}
+
+// Bootstrap prepares a new deployment by creating any missing role, database, extension and postgresql schema needed
+// by the database, then installs the latest visor schema if none is installed. It is safe to call against a database
+// that is already set up.
+func (d *Database) Bootstrap(ctx context.Context, cfg BootstrapConfig) error {
+	adminOpt := *d.opt
+	if cfg.AdminURL != "" {
+		opt, err := pg.ParseURL(cfg.AdminURL)
+		if err != nil {
+			return xerrors.Errorf("parse admin url: %w", err)
+		}
+		adminOpt = *opt
+	}
+	adminOpt.OnConnect = nil
+	adminOpt.PoolSize = 1
+
+	owner := d.opt.User
+	dbName := d.opt.Database
+	if dbName == "" {
+		return xerrors.Errorf("database url does not name a database")
+	}
+
+	serverOpt := adminOpt
+	serverOpt.Database = maintenanceDatabase
+	if err := withAdminConn(ctx, &serverOpt, func(db *pg.DB) error {
+		if err := ensureRole(ctx, db, owner, d.opt.Password, true, cfg.CreateRoles); err != nil {
+			return err
+		}
+		if cfg.ReaderRole != "" {
+			if err := ensureRole(ctx, db, cfg.ReaderRole, "", false, cfg.CreateRoles); err != nil {
+				return err
+			}
+		}
+		return ensureDatabase(ctx, db, dbName, owner, cfg.CreateDatabase)
+	}); err != nil {
+		return err
+	}
+
+	dbOpt := adminOpt
+	dbOpt.Database = dbName
+	if err := withAdminConn(ctx, &dbOpt, func(db *pg.DB) error {
+		return ensureSchema(ctx, db, d.SchemaConfig().SchemaName, owner, cfg.ReaderRole)
+	}); err != nil {
+		return err
+	}
+
+	installed, err := d.schemaInstalled(ctx)
+	if err != nil {
+		return err
+	}
+	if installed {
+		return nil
+	}
+
+	log.Infow("installing latest schema", "database", dbName, "schema", d.SchemaConfig().SchemaName)
+	return d.MigrateSchema(ctx)
+}
+
+// schemaInstalled reports whether a visor schema is installed in the database.
+func (d *Database) schemaInstalled(ctx context.Context) (bool, error) {
+	db, err := connect(ctx, d.opt)
+	if err != nil {
+		return false, xerrors.Errorf("connect: %w", err)
+	}
+	defer db.Close() // nolint: errcheck
+
+	_, initialized, err := getDatabaseSchemaVersion(ctx, db, d.SchemaConfig())
+	if err != nil {
+		return false, xerrors.Errorf("get schema version: %w", err)
+	}
+	return initialized, nil
+}
+
+// withAdminConn runs fn with a connection made using opt. Unlike connect it takes no schema lock.
+func withAdminConn(ctx context.Context, opt *pg.Options, fn func(db *pg.DB) error) error {
+	db := pg.Connect(opt).WithContext(ctx)
+	defer db.Close() // nolint: errcheck
+
+	if err := db.Ping(ctx); err != nil {
+		return xerrors.Errorf("connect to %s as %s: %w", opt.Database, opt.User, err)
+	}
+	return fn(db)
+}
+
+func ensureRole(ctx context.Context, db *pg.DB, name, password string, login bool, create bool) error {
+	var exists bool
+	if _, err := db.QueryOneContext(ctx, pg.Scan(&exists), `SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = ?)`, name); err != nil {
+		return xerrors.Errorf("query role %s: %w", name, err)
+	}
+	if exists {
+		return nil
+	}
+	if !create {
+		return xerrors.Errorf("role %s does not exist and creating roles is not allowed", name)
+	}
+
+	log.Infow("creating role", "role", name)
+	var err error
+	switch {
+	case !login:
+		_, err = db.ExecContext(ctx, `CREATE ROLE ? NOLOGIN`, pg.Ident(name))
+	case password != "":
+		_, err = db.ExecContext(ctx, `CREATE ROLE ? LOGIN PASSWORD ?`, pg.Ident(name), password)
+	default:
+		_, err = db.ExecContext(ctx, `CREATE ROLE ? LOGIN`, pg.Ident(name))
+	}
+	if err != nil {
+		return xerrors.Errorf("create role %s: %w", name, err)
+	}
+	return nil
+}
+
+func ensureDatabase(ctx context.Context, db *pg.DB, name, owner string, create bool) error {
+	var exists bool
+	if _, err := db.QueryOneContext(ctx, pg.Scan(&exists), `SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = ?)`, name); err != nil {
+		return xerrors.Errorf("query database %s: %w", name, err)
+	}
+	if exists {
+		return nil
+	}
+	if !create {
+		return xerrors.Errorf("database %s does not exist and creating databases is not allowed", name)
+	}
+
+	log.Infow("creating database", "database", name, "owner", owner)
+	if _, err := db.ExecContext(ctx, `CREATE DATABASE ? OWNER ?`, pg.Ident(name), pg.Ident(owner)); err != nil {
+		return xerrors.Errorf("create database %s: %w", name, err)
+	}
+	return nil
+}
+
+// ensureSchema creates the extensions and postgresql schema used by visor and grants access to them.
+func ensureSchema(ctx context.Context, db *pg.DB, schemaName, owner, reader string) error {
+	if _, err := db.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS timescaledb WITH SCHEMA public`); err != nil {
+		return xerrors.Errorf("create timescaledb extension: %w", err)
+	}
+
+	if schemaName == "public" {
+		if _, err := db.ExecContext(ctx, `GRANT ALL ON SCHEMA public TO ?`, pg.Ident(owner)); err != nil {
+			return xerrors.Errorf("grant schema: %w", err)
+		}
+	} else {
+		if _, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS ? AUTHORIZATION ?`, pg.Ident(schemaName), pg.Ident(owner)); err != nil {
+			return xerrors.Errorf("create schema: %w", err)
+		}
+	}
+
+	if reader == "" {
+		return nil
+	}
+
+	stmts := []string{
+		`GRANT USAGE ON SCHEMA ?0 TO ?2`,
+		`GRANT SELECT ON ALL TABLES IN SCHEMA ?0 TO ?2`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE ?1 IN SCHEMA ?0 GRANT SELECT ON TABLES TO ?2`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt, pg.Ident(schemaName), pg.Ident(owner), pg.Ident(reader)); err != nil {
+			return xerrors.Errorf("grant read access to %s: %w", reader, err)
+		}
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/filecoin-project/sentinel-visor/config"
 	"github.com/filecoin-project/sentinel-visor/model"
@@ -15,6 +18,12 @@ type Connector interface {
 	Close(context.Context) error
 }
 
+// An Overwriter is storage that can provide a view of itself that replaces existing rows with newly persisted ones
+// instead of keeping them.
+type Overwriter interface {
+	Overwriting() model.Storage
+}
+
 func NewCatalog(cfg config.StorageConf) (*Catalog, error) {
 	c := &Catalog{
 		storages: make(map[string]model.Storage),
@@ -34,14 +43,51 @@ func NewCatalog(cfg config.StorageConf) (*Catalog, error) {
 			dburl = sc.URL
 		}
 
-		db, err := NewDatabase(context.TODO(), dburl, sc.PoolSize, sc.ApplicationName, sc.SchemaName, sc.AllowUpsert)
+		var db *Database
+		var err error
+		if sc.ReadOnly {
+			db, err = NewReadReplica(context.TODO(), dburl, sc.PoolSize, sc.ApplicationName, sc.SchemaName)
+		} else {
+			db, err = NewDatabase(context.TODO(), dburl, sc.PoolSize, sc.ApplicationName, sc.SchemaName, sc.AllowUpsert)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create postgresql storage %q: %w", name, err)
 		}
+		db.Notify = sc.Notify
+		db.Isolation, err = ParseIsolationLevel(sc.IsolationLevel)
+		if err != nil {
+			return nil, fmt.Errorf("postgresql storage %q: %w", name, err)
+		}
+		db.TxRetries = sc.TxRetries
+		db.TxRetryDelay = time.Duration(sc.TxRetryDelay)
+		db.Redactor, err = ParseRedactions(sc.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("postgresql storage %q: %w", name, err)
+		}
+		conflictKeys, err := ParseConflictKeys(sc.ConflictKeys)
+		if err != nil {
+			return nil, fmt.Errorf("postgresql storage %q: %w", name, err)
+		}
+		db.SetConflictKeys(conflictKeys)
 
 		c.storages[name] = db
 	}
 
+	// Link databases to their read replicas once all have been registered
+	for name, sc := range cfg.Postgresql {
+		if sc.ReadReplica == "" {
+			continue
+		}
+		replica, ok := c.storages[sc.ReadReplica].(*Database)
+		if !ok {
+			return nil, fmt.Errorf("read replica %q for storage %q is not a postgresql storage", sc.ReadReplica, name)
+		}
+		if !replica.ReadOnly() {
+			return nil, fmt.Errorf("read replica %q for storage %q must be configured with ReadOnly", sc.ReadReplica, name)
+		}
+		c.storages[name].(*Database).SetReader(replica)
+	}
+
 	for name, sc := range cfg.File {
 		if _, exists := c.storages[name]; exists {
 			return nil, fmt.Errorf("duplicate storage name: %q", name)
@@ -55,6 +101,23 @@ func NewCatalog(cfg config.StorageConf) (*Catalog, error) {
 			if err != nil {
 				return nil, fmt.Errorf("failed to create postgresql storage %q: %w", name, err)
 			}
+			db.Redactor, err = ParseRedactions(sc.Redact)
+			if err != nil {
+				return nil, fmt.Errorf("file storage %q: %w", name, err)
+			}
+			c.storages[name] = db
+
+		case "Parquet":
+			log.Debugw("registering storage", "name", name, "type", "parquet")
+
+			db, err := NewParquetStorageLatest(sc.Path, sc.PartitionSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create parquet storage %q: %w", name, err)
+			}
+			db.Redactor, err = ParseRedactions(sc.Redact)
+			if err != nil {
+				return nil, fmt.Errorf("file storage %q: %w", name, err)
+			}
 			c.storages[name] = db
 
 		default:
@@ -82,16 +145,67 @@ func (c *Catalog) Connect(ctx context.Context, name string) (model.Storage, erro
 		return nil, fmt.Errorf("unknown storage: %q", name)
 	}
 
-	// Does this storage need to be connected?
-	cs, ok := s.(Connector)
-	if ok {
-		if !cs.IsConnected(ctx) {
-			err := cs.Connect(ctx)
-			if err != nil {
-				return nil, err
+	if err := connectStorage(ctx, s); err != nil {
+		return nil, err
+	}
+
+	if db, ok := s.(*Database); ok {
+		// Jobs must not start writing to a schema that their models do not match
+		if err := db.CheckSchemaCompatibility(ctx); err != nil {
+			return nil, fmt.Errorf("storage %q: %w", name, err)
+		}
+
+		// Read paths of jobs using this storage will use its replica so it must be ready too
+		if db.reader != nil {
+			if err := connectStorage(ctx, db.reader); err != nil {
+				return nil, fmt.Errorf("connect read replica: %w", err)
+			}
+			if err := db.reader.CheckSchemaCompatibility(ctx); err != nil {
+				return nil, fmt.Errorf("read replica of storage %q: %w", name, err)
 			}
 		}
 	}
 
 	return s, nil
 }
+
+// CheckSchemas connects to every configured postgresql storage and verifies that its schema is compatible with this
+// version of visor. Storages that cannot be reached are skipped with a warning since they may become available before
+// a job uses them, but an incompatible schema is returned as an error.
+func (c *Catalog) CheckSchemas(ctx context.Context) error {
+	names := make([]string, 0, len(c.storages))
+	for name := range c.storages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		db, ok := c.storages[name].(*Database)
+		if !ok {
+			continue
+		}
+		if err := connectStorage(ctx, db); err != nil {
+			if errors.Is(err, ErrSchemaTooOld) || errors.Is(err, ErrSchemaTooNew) {
+				return fmt.Errorf("storage %q: %w", name, err)
+			}
+			log.Warnw("storage not available, its schema will be checked when a job uses it", "storage", name, "error", err)
+			continue
+		}
+		if err := db.CheckSchemaCompatibility(ctx); err != nil {
+			return fmt.Errorf("storage %q: %w", name, err)
+		}
+		log.Infow("storage schema is compatible", "storage", name, "schema_version", db.version.String())
+	}
+	return nil
+}
+
+// connectStorage connects s if it needs to be connected.
+func connectStorage(ctx context.Context, s model.Storage) error {
+	cs, ok := s.(Connector)
+	if ok {
+		if !cs.IsConnected(ctx) {
+			return cs.Connect(ctx)
+		}
+	}
+	return nil
+}
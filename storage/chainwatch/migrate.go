@@ -0,0 +1,156 @@
+// Package chainwatch copies data from a database populated by lotus chainwatch into the visor schema.
+package chainwatch
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/actors/common"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+)
+
+var log = logging.Logger("visor/chainwatch")
+
+// Querier is the subset of a go-pg database used to read the chainwatch schema.
+type Querier interface {
+	QueryContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error)
+	QueryOneContext(ctx context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error)
+}
+
+// A Migrator reads blocks, messages, receipts and actors from a chainwatch database and persists them to visor
+// storage, one range of heights at a time. Chainwatch does not record heights for messages, receipts or actors so
+// they are derived from the blocks that include them or whose parent state they belong to, matching the heights visor
+// records for the same data.
+type Migrator struct {
+	src       Querier
+	dst       model.Storage
+	batchSize int64
+}
+
+func NewMigrator(src Querier, dst model.Storage, batchSize int64) *Migrator {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &Migrator{
+		src:       src,
+		dst:       dst,
+		batchSize: batchSize,
+	}
+}
+
+// HeightRange returns the lowest and highest heights of blocks in the chainwatch database.
+func (m *Migrator) HeightRange(ctx context.Context) (int64, int64, error) {
+	var minHeight, maxHeight int64
+	if _, err := m.src.QueryOneContext(ctx, pg.Scan(&minHeight, &maxHeight), `SELECT COALESCE(min(height), 0), COALESCE(max(height), -1) FROM blocks`); err != nil {
+		return 0, 0, xerrors.Errorf("query height range: %w", err)
+	}
+	return minHeight, maxHeight, nil
+}
+
+// Migrate copies all data between the from and to heights inclusive. Data that already exists in visor storage is
+// left unchanged unless the storage is configured to upsert, so an interrupted migration may be safely restarted.
+func (m *Migrator) Migrate(ctx context.Context, from, to int64) error {
+	for start := from; start <= to; start += m.batchSize {
+		end := start + m.batchSize - 1
+		if end > to {
+			end = to
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, err := m.readBatch(ctx, start, end)
+		if err != nil {
+			return xerrors.Errorf("read heights %d-%d: %w", start, end, err)
+		}
+
+		if err := m.dst.PersistBatch(ctx, batch...); err != nil {
+			return xerrors.Errorf("persist heights %d-%d: %w", start, end, err)
+		}
+
+		log.Infow("migrated chainwatch data", "from", start, "to", end)
+	}
+	return nil
+}
+
+func (m *Migrator) readBatch(ctx context.Context, from, to int64) ([]model.Persistable, error) {
+	var headers blocks.BlockHeaders
+	if _, err := m.src.QueryContext(ctx, &headers, `
+SELECT b.height, b.cid, b.miner, b.parentweight::text AS parent_weight, b.parent_base_fee,
+	b.parentstateroot AS parent_state_root, COALESCE(b.win_count, 0) AS win_count, b.timestamp, b.forksig AS fork_signaling
+FROM blocks b
+WHERE b.height BETWEEN ? AND ?`, from, to); err != nil {
+		return nil, xerrors.Errorf("query blocks: %w", err)
+	}
+
+	var parents blocks.BlockParents
+	if _, err := m.src.QueryContext(ctx, &parents, `
+SELECT b.height, bp.block, bp.parent
+FROM block_parents bp JOIN blocks b ON b.cid = bp.block
+WHERE b.height BETWEEN ? AND ?`, from, to); err != nil {
+		return nil, xerrors.Errorf("query block parents: %w", err)
+	}
+
+	var blockMessages messages.BlockMessages
+	if _, err := m.src.QueryContext(ctx, &blockMessages, `
+SELECT b.height, bm.block, bm.message
+FROM block_messages bm JOIN blocks b ON b.cid = bm.block
+WHERE b.height BETWEEN ? AND ?`, from, to); err != nil {
+		return nil, xerrors.Errorf("query block messages: %w", err)
+	}
+
+	// Visor records a message once for each tipset that includes it
+	var msgs messages.Messages
+	if _, err := m.src.QueryContext(ctx, &msgs, `
+SELECT DISTINCT ON (b.height, m.cid) b.height, m.cid, m."from", m."to", m.value, m.gas_fee_cap, m.gas_premium,
+	m.gas_limit, m.size_bytes, m.nonce, COALESCE(m.method, 0) AS method
+FROM messages m JOIN block_messages bm ON bm.message = m.cid JOIN blocks b ON b.cid = bm.block
+WHERE b.height BETWEEN ? AND ?
+ORDER BY b.height, m.cid`, from, to); err != nil {
+		return nil, xerrors.Errorf("query messages: %w", err)
+	}
+
+	// Receipts and actors are recorded against the state root that contains them, which is the parent state root of
+	// the blocks in the following tipset. The tipset that included the message of a receipt is the parent of that
+	// tipset.
+	var receipts messages.Receipts
+	if _, err := m.src.QueryContext(ctx, &receipts, `
+SELECT DISTINCT ON (r.msg, r.state) b.height, r.msg AS message, r.state AS state_root, r.idx, r.exit AS exit_code, r.gas_used,
+	r."return" AS raw_return, p.height AS inclusion_height, p.parentstateroot AS inclusion_state_root
+FROM receipts r
+JOIN blocks b ON b.parentstateroot = r.state
+LEFT JOIN block_parents bp ON bp.block = b.cid
+LEFT JOIN blocks p ON p.cid = bp.parent
+WHERE b.height BETWEEN ? AND ?
+ORDER BY r.msg, r.state, b.height`, from, to); err != nil {
+		return nil, xerrors.Errorf("query receipts: %w", err)
+	}
+
+	var actors common.ActorList
+	if _, err := m.src.QueryContext(ctx, &actors, `
+SELECT DISTINCT ON (a.id, a.stateroot) b.height, a.id, a.stateroot AS state_root, a.code, a.head, a.balance, a.nonce
+FROM actors a JOIN blocks b ON b.parentstateroot = a.stateroot
+WHERE b.height BETWEEN ? AND ?
+ORDER BY a.id, a.stateroot, b.height`, from, to); err != nil {
+		return nil, xerrors.Errorf("query actors: %w", err)
+	}
+
+	// Chainwatch stores actor codes as CIDs, visor uses the actor name
+	for _, a := range actors {
+		c, err := cid.Decode(a.Code)
+		if err != nil {
+			return nil, xerrors.Errorf("decode code of actor %s: %w", a.ID, err)
+		}
+		a.Code = builtin.ActorNameByCode(c)
+	}
+
+	return []model.Persistable{headers, parents, msgs, blockMessages, receipts, actors}, nil
+}
@@ -0,0 +1,55 @@
+package chainwatch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sa2builtin "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/actors/common"
+)
+
+type fakeQuerier struct {
+	ranges [][2]int64
+}
+
+func (q *fakeQuerier) QueryContext(ctx context.Context, m interface{}, query interface{}, params ...interface{}) (pg.Result, error) {
+	if strings.Contains(query.(string), "FROM actors") {
+		q.ranges = append(q.ranges, [2]int64{params[0].(int64), params[1].(int64)})
+		*m.(*common.ActorList) = common.ActorList{{Height: params[0].(int64), ID: "f01000", Code: sa2builtin.StorageMinerActorCodeID.String()}}
+	}
+	return nil, nil
+}
+
+func (q *fakeQuerier) QueryOneContext(ctx context.Context, m interface{}, query interface{}, params ...interface{}) (pg.Result, error) {
+	return nil, nil
+}
+
+type fakeStorage struct {
+	batches [][]model.Persistable
+}
+
+func (s *fakeStorage) PersistBatch(ctx context.Context, ps ...model.Persistable) error {
+	s.batches = append(s.batches, ps)
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+	src := &fakeQuerier{}
+	dst := &fakeStorage{}
+
+	err := NewMigrator(src, dst, 10).Migrate(context.Background(), 5, 27)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]int64{{5, 14}, {15, 24}, {25, 27}}, src.ranges)
+	require.Len(t, dst.batches, 3)
+
+	actors := dst.batches[0][len(dst.batches[0])-1].(common.ActorList)
+	require.Len(t, actors, 1)
+	assert.Equal(t, "fil/2/storageminer", actors[0].Code)
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// ParseConflictKey parses a conflict key written as table=column,column and returns the table and its columns.
+func ParseConflictKey(s string) (string, []string, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", nil, xerrors.Errorf("conflict key %q: expected table=column,column", s)
+	}
+
+	var columns []string
+	for _, c := range strings.Split(parts[1], ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			return "", nil, xerrors.Errorf("conflict key %q: empty column name", s)
+		}
+		columns = append(columns, c)
+	}
+	return strings.TrimSpace(parts[0]), columns, nil
+}
+
+// ParseConflictKeys parses a list of conflict keys and checks that each selects one of the conflict targets declared
+// by the model persisted to its table. It returns nil if the list is empty.
+func ParseConflictKeys(ss []string) (map[string][]string, error) {
+	var keys map[string][]string
+	for _, s := range ss {
+		if s == "" {
+			continue
+		}
+		table, columns, err := ParseConflictKey(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keys[table]; exists {
+			return nil, xerrors.Errorf("conflict key %q: table %s already has a conflict key", s, table)
+		}
+		if err := checkConflictTarget(table, columns); err != nil {
+			return nil, xerrors.Errorf("conflict key %q: %w", s, err)
+		}
+		if keys == nil {
+			keys = map[string][]string{}
+		}
+		keys[table] = columns
+	}
+	return keys, nil
+}
+
+// checkConflictTarget returns an error unless columns is one of the conflict targets declared by a model persisted to
+// table. The order of the columns is not significant.
+func checkConflictTarget(table string, columns []string) error {
+	candidates := append([]interface{}{}, models...)
+	for _, dm := range describedModels {
+		candidates = append(candidates, dm.model)
+	}
+
+	want := strings.Join(sortedColumns(columns), ", ")
+	found := false
+	for _, m := range candidates {
+		if stripQuotes(pg.Model(m).TableModel().Table().SQLNameForSelects) != table {
+			continue
+		}
+		found = true
+		ct, ok := m.(model.ConflictTargeter)
+		if !ok {
+			continue
+		}
+		for _, target := range ct.ConflictTargets() {
+			if strings.Join(sortedColumns(target), ", ") == want {
+				return nil
+			}
+		}
+	}
+	if !found {
+		return xerrors.Errorf("no model is persisted to table %s", table)
+	}
+	return xerrors.Errorf("(%s) is not a conflict target declared for table %s", want, table)
+}
+
+// sortedColumns returns a sorted copy of columns.
+func sortedColumns(columns []string) []string {
+	sorted := append([]string{}, columns...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// verifyConflictKeys checks that each configured conflict key is backed by a unique index or constraint on exactly its
+// columns, without which postgresql rejects the upsert.
+func verifyConflictKeys(ctx context.Context, db *pg.DB, schemaName string, keys map[string][]string) error {
+	for table, columns := range keys {
+		sorted := sortedColumns(columns)
+		var exists bool
+		if _, err := db.QueryOneContext(ctx, pg.Scan(&exists), `
+SELECT EXISTS (
+	SELECT 1 FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = ? AND c.relname = ? AND i.indisunique
+	AND (SELECT array_agg(a.attname::text ORDER BY a.attname::text COLLATE "C") FROM pg_attribute a WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)) = ?
+)`, schemaName, table, pg.Array(sorted)); err != nil {
+			return xerrors.Errorf("querying conflict key of %s: %w", table, err)
+		}
+		if !exists {
+			return xerrors.Errorf("conflict key (%s) of table %s does not match a unique index", strings.Join(sorted, ", "), table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConflictKey(t *testing.T) {
+	table, columns, err := ParseConflictKey("messages=cid")
+	require.NoError(t, err)
+	assert.Equal(t, "messages", table)
+	assert.Equal(t, []string{"cid"}, columns)
+
+	table, columns, err = ParseConflictKey("actors=height, id")
+	require.NoError(t, err)
+	assert.Equal(t, "actors", table)
+	assert.Equal(t, []string{"height", "id"}, columns)
+
+	for _, s := range []string{"messages", "messages=", "=cid", "messages=cid,,height"} {
+		_, _, err := ParseConflictKey(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseConflictKeys(t *testing.T) {
+	keys, err := ParseConflictKeys(nil)
+	require.NoError(t, err)
+	assert.Nil(t, keys)
+
+	keys, err = ParseConflictKeys([]string{"messages=cid", "block_headers=cid"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"messages": {"cid"}, "block_headers": {"cid"}}, keys)
+
+	// Targets must be declared by the model persisted to the table
+	_, err = ParseConflictKeys([]string{"messages=from"})
+	assert.Error(t, err)
+	_, err = ParseConflictKeys([]string{"actors=id"})
+	assert.Error(t, err)
+	_, err = ParseConflictKeys([]string{"no_such_table=cid"})
+	assert.Error(t, err)
+
+	// Each table may only have one conflict key
+	_, err = ParseConflictKeys([]string{"messages=cid", "messages=cid"})
+	assert.Error(t, err)
+}
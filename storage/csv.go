@@ -80,6 +80,9 @@ func getCSVModelTableByName(name string, version model.Version) (table, bool) {
 type CSVStorage struct {
 	path    string
 	version model.Version // schema version
+
+	// Redactor removes or hashes the values of configured columns before they are written.
+	Redactor *Redactor
 }
 
 // A table is a list of columns and corresponding field names in the Go struct
@@ -110,7 +113,7 @@ func (c *CSVStorage) PersistBatch(ctx context.Context, ps ...model.Persistable)
 	}
 
 	for _, p := range ps {
-		if err := p.Persist(ctx, batch, c.version); err != nil {
+		if err := p.Persist(ctx, c.Redactor.Batch(batch), c.version); err != nil {
 			return err
 		}
 	}
@@ -178,6 +181,11 @@ func (c *CSVBatch) PersistModel(ctx context.Context, m interface{}) error {
 		return nil
 	}
 
+	if _, ok := m.(model.RawModel); ok {
+		// The columns of raw models are not known when the csv files are created
+		return ErrMarshalUnsupportedType
+	}
+
 	value := reflect.ValueOf(m)
 	if value.Kind() == reflect.Ptr {
 		value = value.Elem()
@@ -185,6 +193,8 @@ func (c *CSVBatch) PersistModel(ctx context.Context, m interface{}) error {
 
 	switch value.Kind() {
 	case reflect.Slice, reflect.Array:
+		// Write rows in a consistent order so repeated runs produce identical files
+		model.SortModels(m)
 		for i := 0; i < value.Len(); i++ {
 			if err := c.PersistModel(ctx, value.Index(i).Interface()); err != nil {
 				return err
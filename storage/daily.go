@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+)
+
+// dayHeights returns the lowest and highest indexed heights of the UTC day starting at day. Epochs are assigned to days
+// using epoch_timestamps, which is written by the blocks task. It returns false if no epochs of the day have been
+// indexed.
+func dayHeights(ctx context.Context, tx *pg.Tx, day time.Time) (int64, int64, bool, error) {
+	var from, to *int64
+	if _, err := tx.QueryOneContext(ctx, pg.Scan(&from, &to), `SELECT min(height), max(height) FROM epoch_timestamps WHERE timestamp >= ? AND timestamp < ?`, day, day.Add(24*time.Hour)); err != nil {
+		return 0, 0, false, xerrors.Errorf("query day heights: %w", err)
+	}
+	if from == nil || to == nil {
+		return 0, 0, false, nil
+	}
+	return *from, *to, true, nil
+}
+
+// rollupDays returns the UTC days of a table of daily rollups with a day column that should be recomputed, in order.
+// These are the last day in the table and the lookback days before it, followed by every day up to that of the latest
+// indexed epoch. If the table is empty the days start from the day containing the epoch at height from.
+func (d *Database) rollupDays(ctx context.Context, table string, lookback int, from int64) ([]time.Time, error) {
+	var lastDay, latest *time.Time
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&lastDay, &latest), `SELECT (SELECT max(day)::timestamptz FROM ?), (SELECT max(timestamp) FROM epoch_timestamps)`, pg.Ident(table)); err != nil {
+		return nil, xerrors.Errorf("query %s progress: %w", table, err)
+	}
+	if latest == nil {
+		log.Infow("no epoch timestamps indexed, skipping rollup", "table", table)
+		return nil, nil
+	}
+
+	var start time.Time
+	if lastDay != nil {
+		start = lastDay.AddDate(0, 0, -lookback)
+	} else {
+		var first *time.Time
+		if _, err := d.db.QueryOneContext(ctx, pg.Scan(&first), `SELECT min(timestamp) FROM epoch_timestamps WHERE height >= ?`, from); err != nil {
+			return nil, xerrors.Errorf("query first epoch: %w", err)
+		}
+		if first == nil {
+			return nil, nil
+		}
+		start = *first
+	}
+
+	return daysBetween(start, *latest), nil
+}
+
+// daysBetween returns the start of each UTC day from the day containing start to the day containing end.
+func daysBetween(start, end time.Time) []time.Time {
+	var days []time.Time
+	for day := start.UTC().Truncate(24 * time.Hour); !day.After(end); day = day.Add(24 * time.Hour) {
+		days = append(days, day)
+	}
+	return days
+}
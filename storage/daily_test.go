@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaysBetween(t *testing.T) {
+	start := time.Date(2021, 6, 1, 23, 59, 30, 0, time.UTC)
+	end := time.Date(2021, 6, 3, 0, 0, 30, 0, time.UTC)
+	assert.Equal(t, []time.Time{
+		time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 3, 0, 0, 0, 0, time.UTC),
+	}, daysBetween(start, end))
+
+	assert.Empty(t, daysBetween(end, start))
+}
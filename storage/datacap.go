@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// datacapUsageVersion is the first schema version containing the verified_client_datacap_usage table.
+var datacapUsageVersion = model.Version{Major: 1, Patch: 24}
+
+// refreshDatacapUsageSQL recomputes the rows of verified_client_datacap_usage for a day whose epochs span heights ?1
+// to ?2. Grants are successful AddVerifiedClient messages, sent directly or as internal messages such as those sent by
+// a multisig notary. Their client addresses are resolved to ID addresses using id_addresses. Datacap is used by the
+// verified deal proposals of the client. Cumulative totals continue from the client's latest row before the day, so
+// days must be recomputed in order.
+const refreshDatacapUsageSQL = `
+WITH grants AS (
+	SELECT coalesce(a.id, c.client) AS client_id, sum(c.allowance) AS granted
+	FROM (
+		SELECT p.params->>'Address' AS client, (p.params->>'Allowance')::numeric AS allowance
+		FROM parsed_messages p
+		JOIN derived_gas_outputs g ON g.height = p.height AND g.cid = p.cid
+		WHERE p.height BETWEEN ?1 AND ?2 AND p.method = ?3 AND g.is_canonical AND g.exit_code = 0
+		UNION ALL
+		SELECT p.params->>'Address', (p.params->>'Allowance')::numeric
+		FROM internal_parsed_messages p
+		JOIN internal_messages i ON i.height = p.height AND i.cid = p.cid
+		WHERE p.height BETWEEN ?1 AND ?2 AND p.method = ?3 AND i.is_canonical AND i.exit_code = 0
+	) c
+	LEFT JOIN LATERAL (
+		SELECT id FROM id_addresses WHERE address = c.client LIMIT 1
+	) a ON true
+	GROUP BY 1
+), used AS (
+	SELECT client_id, sum(padded_piece_size) AS used, count(*) AS deals
+	FROM (
+		SELECT DISTINCT ON (deal_id) client_id, padded_piece_size FROM market_deal_proposals
+		WHERE height BETWEEN ?1 AND ?2 AND is_canonical AND is_verified
+		ORDER BY deal_id, height
+	) d
+	GROUP BY client_id
+), today AS (
+	SELECT client_id, coalesce(g.granted, 0) AS granted, coalesce(u.used, 0) AS used, coalesce(u.deals, 0) AS deals
+	FROM grants g FULL OUTER JOIN used u USING (client_id)
+)
+INSERT INTO verified_client_datacap_usage (day, client_id, allowance_granted, datacap_used, verified_deals,
+	cumulative_granted, cumulative_used, remaining, from_height, to_height, updated_at)
+SELECT ?0::date, t.client_id, t.granted, t.used, t.deals,
+	coalesce(prev.cumulative_granted, 0) + t.granted,
+	coalesce(prev.cumulative_used, 0) + t.used,
+	coalesce(prev.cumulative_granted, 0) + t.granted - coalesce(prev.cumulative_used, 0) - t.used,
+	?1, ?2, now()
+FROM today t
+LEFT JOIN LATERAL (
+	SELECT cumulative_granted, cumulative_used FROM verified_client_datacap_usage v
+	WHERE v.client_id = t.client_id AND v.day < ?0::date
+	ORDER BY v.day DESC LIMIT 1
+) prev ON true`
+
+// addVerifiedClientMethod is the name recorded in parsed_messages for the verified registry method granting datacap.
+const addVerifiedClientMethod = "AddVerifiedClient"
+
+// RefreshDatacapUsage recomputes the verified_client_datacap_usage rows for the UTC day containing day from the data
+// indexed so far and returns the number of rows written. It returns zero if no epochs of the day have been indexed.
+func (d *Database) RefreshDatacapUsage(ctx context.Context, day time.Time) (int, error) {
+	if d.version.Before(datacapUsageVersion) {
+		return 0, xerrors.Errorf("datacap usage requires schema version %s or later", datacapUsageVersion)
+	}
+
+	start := day.UTC().Truncate(24 * time.Hour)
+
+	var written int
+	err := d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		from, to, ok, err := dayHeights(ctx, tx, start)
+		if err != nil || !ok {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM verified_client_datacap_usage WHERE day = ?::date`, start); err != nil {
+			return xerrors.Errorf("delete day: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, refreshDatacapUsageSQL, start, from, to, addVerifiedClientMethod)
+		if err != nil {
+			return xerrors.Errorf("insert day: %w", err)
+		}
+		written = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// NewDatacapUsageAggregator returns an Aggregator that keeps the verified_client_datacap_usage table up to date as
+// epochs are indexed. It starts from the day containing the epoch at height from and refreshes every interval,
+// recomputing the last lookback days already aggregated so data indexed late is included.
+func NewDatacapUsageAggregator(db *Database, from int64, lookback int, interval time.Duration) *Aggregator {
+	return newDailyAggregator(db, "verified_client_datacap_usage", from, lookback, interval, db.RefreshDatacapUsage)
+}
@@ -0,0 +1,334 @@
+// Package dataset exports ranges of indexed data as compressed CSV files packed into CAR files so they can be
+// published to IPFS or stored with Filecoin storage deals.
+package dataset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/go-pg/pg/v10"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var log = logging.Logger("visor/dataset")
+
+// A DealClient can make storage deals for CAR files. It is satisfied by the lotus full node API.
+type DealClient interface {
+	ClientImport(ctx context.Context, ref api.FileRef) (*api.ImportRes, error)
+	ClientStartDeal(ctx context.Context, params *api.StartDealParams) (*cid.Cid, error)
+}
+
+// DealConfig configures the storage deals made for archives.
+type DealConfig struct {
+	Client     DealClient
+	Miner      address.Address
+	Wallet     address.Address
+	EpochPrice big.Int
+	Duration   uint64 // minimum duration of the deal in epochs
+}
+
+// Config configures an Archiver.
+type Config struct {
+	Path      string        // directory that exported files and CAR files are written to
+	From      int64         // first height to archive if no archives have been recorded
+	RangeSize int64         // number of heights in each archive
+	Finality  int64         // number of heights the indexer must have progressed beyond a range before it is archived
+	Interval  time.Duration // time to wait between checks for completed ranges
+	Deal      *DealConfig   // nil if no storage deals should be made
+}
+
+// An Archiver is a job that periodically exports ranges of heights that have been completely indexed. Each range is
+// exported as one gzip compressed CSV file per table, packed into a CAR file whose root CID identifies the archive,
+// and recorded in the visor_dataset_archives table. Ranges are archived in order, starting after the highest range
+// already recorded.
+type Archiver struct {
+	db  *storage.Database
+	cfg Config
+}
+
+func NewArchiver(db *storage.Database, cfg Config) *Archiver {
+	return &Archiver{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (a *Archiver) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["path"] = a.cfg.Path
+	out["rangeSize"] = a.cfg.RangeSize
+	out["finality"] = a.cfg.Finality
+	out["interval"] = a.cfg.Interval.String()
+	if a.cfg.Deal != nil {
+		out["dealMiner"] = a.cfg.Deal.Miner.String()
+	}
+	return out
+}
+
+// Run archives completed ranges until the context is done.
+func (a *Archiver) Run(ctx context.Context) error {
+	if a.cfg.RangeSize < 1 {
+		return xerrors.Errorf("invalid range size: %d", a.cfg.RangeSize)
+	}
+	if err := os.MkdirAll(a.cfg.Path, 0o755); err != nil {
+		return xerrors.Errorf("create archive directory: %w", err)
+	}
+
+	for {
+		if err := a.archiveCompleted(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.cfg.Interval):
+		}
+	}
+}
+
+// archiveCompleted archives each completed range following the last recorded archive.
+func (a *Archiver) archiveCompleted(ctx context.Context) error {
+	from, err := a.nextHeight(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		to := from + a.cfg.RangeSize - 1
+		complete, err := a.rangeComplete(ctx, from, to)
+		if err != nil {
+			return xerrors.Errorf("check range %d-%d: %w", from, to, err)
+		}
+		if !complete {
+			log.Debugw("range is not yet complete", "from", from, "to", to)
+			return nil
+		}
+
+		if err := a.archive(ctx, from, to); err != nil {
+			return xerrors.Errorf("archive range %d-%d: %w", from, to, err)
+		}
+		from = to + 1
+	}
+}
+
+// nextHeight returns the first height of the next range to archive.
+func (a *Archiver) nextHeight(ctx context.Context) (int64, error) {
+	var next int64
+	if _, err := a.db.QueryContext(ctx, pg.Scan(&next), `SELECT COALESCE(max(to_height) + 1, ?) FROM visor_dataset_archives`, a.cfg.From); err != nil {
+		return 0, xerrors.Errorf("query last archive: %w", err)
+	}
+	return next, nil
+}
+
+// rangeComplete reports whether indexing has progressed at least Finality heights beyond the range, every height in
+// the range is either a canonical tipset or a null round, every canonical tipset has a successful report for its
+// parent state root and every task that failed for one of those tipsets has since succeeded. Archived reports in the
+// history table are counted too.
+func (a *Archiver) rangeComplete(ctx context.Context, from, to int64) (bool, error) {
+	var complete bool
+	// Completeness is judged from the same database the range will be exported from
+	if _, err := a.db.Reader().QueryContext(ctx, pg.Scan(&complete), `
+WITH reports AS (
+	SELECT height, state_root, task, status FROM visor_processing_reports WHERE height BETWEEN ?0 AND ?1
+	UNION ALL
+	SELECT height, state_root, task, status FROM visor_processing_reports_history WHERE height BETWEEN ?0 AND ?1
+), tipsets AS (
+	SELECT DISTINCT height, parent_state_root AS state_root FROM block_headers WHERE height BETWEEN ?0 AND ?1 AND is_canonical
+)
+SELECT GREATEST(
+		(SELECT COALESCE(max(height), -1) FROM visor_processing_reports WHERE status IN (?2, ?5)),
+		(SELECT COALESCE(max(height), -1) FROM visor_processing_reports_history WHERE status IN (?2, ?5))
+	) >= ?1 + ?3
+	AND NOT EXISTS (
+		-- a height without a canonical tipset must be a null round skipped by the next canonical tipset
+		SELECT 1 FROM generate_series(?0::bigint, ?1::bigint) AS h
+		WHERE NOT EXISTS (SELECT 1 FROM tipsets t WHERE t.height = h)
+		AND NOT EXISTS (
+			SELECT 1 FROM block_headers c
+			JOIN block_parents bp ON bp.height = c.height AND bp.block = c.cid
+			JOIN block_headers p ON p.cid = bp.parent
+			WHERE c.is_canonical AND c.height > h AND c.height <= ?1 + ?3 AND p.height < h
+		)
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM tipsets t
+		WHERE NOT EXISTS (SELECT 1 FROM reports r WHERE r.height = t.height AND r.state_root = t.state_root AND r.status IN (?2, ?5))
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM reports r
+		JOIN tipsets t ON t.height = r.height AND t.state_root = r.state_root
+		WHERE r.status = ?4
+		AND NOT EXISTS (SELECT 1 FROM reports o WHERE o.height = r.height AND o.state_root = r.state_root AND o.task = r.task AND o.status IN (?2, ?5))
+	)`, from, to, visor.ProcessingStatusOK, a.cfg.Finality, visor.ProcessingStatusError, visor.ProcessingStatusInfo); err != nil {
+		return false, err
+	}
+	return complete, nil
+}
+
+func (a *Archiver) archive(ctx context.Context, from, to int64) error {
+	dir := filepath.Join(a.cfg.Path, fmt.Sprintf("%d-%d", from, to))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("create directory: %w", err)
+	}
+
+	var files []*File
+	for _, table := range a.db.ExportTables() {
+		f, err := a.export(ctx, dir, table, from, to)
+		if err != nil {
+			return xerrors.Errorf("export %s: %w", table, err)
+		}
+		files = append(files, f)
+	}
+
+	carPath := dir + ".car"
+	root, size, err := writeCAR(carPath, from, to, files)
+	if err != nil {
+		return xerrors.Errorf("write car: %w", err)
+	}
+
+	filesJSON, err := json.Marshal(files)
+	if err != nil {
+		return xerrors.Errorf("marshal files: %w", err)
+	}
+
+	rec := &visor.DatasetArchive{
+		FromHeight: from,
+		ToHeight:   to,
+		Root:       root.String(),
+		Path:       carPath,
+		SizeBytes:  size,
+		Files:      string(filesJSON),
+		ArchivedAt: time.Now(),
+	}
+
+	if a.cfg.Deal != nil {
+		proposal, err := a.makeDeal(ctx, carPath, root)
+		if err != nil {
+			return xerrors.Errorf("make deal: %w", err)
+		}
+		rec.DealProposal = proposal.String()
+	}
+
+	if err := a.db.PersistBatch(ctx, rec); err != nil {
+		return xerrors.Errorf("persist archive: %w", err)
+	}
+
+	log.Infow("archived dataset", "from", from, "to", to, "root", rec.Root, "size", size, "deal", rec.DealProposal)
+	return nil
+}
+
+// export writes the rows of a table in the range to a gzip compressed CSV file in dir.
+func (a *Archiver) export(ctx context.Context, dir, table string, from, to int64) (*File, error) {
+	name := table + ".csv.gz"
+	path := filepath.Join(dir, name)
+
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close() // nolint: errcheck
+
+	bw := bufio.NewWriter(out)
+	// The header is left empty so identical data always produces identical files
+	gz := gzip.NewWriter(bw)
+
+	rows, err := a.db.Reader().ExportRange(ctx, table, from, to, gz)
+	if err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Table: table,
+		Name:  name,
+		Rows:  rows,
+		Size:  info.Size(),
+		path:  path,
+	}, nil
+}
+
+// writeCAR packs the files into a CAR file at path, returning its root CID and size.
+func writeCAR(path string, from, to int64, files []*File) (cid.Cid, int64, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	defer out.Close() // nolint: errcheck
+
+	bw := bufio.NewWriter(out)
+	root, err := packCAR(bw, from, to, files)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return cid.Undef, 0, err
+	}
+	if err := out.Close(); err != nil {
+		return cid.Undef, 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	return root, info.Size(), nil
+}
+
+// makeDeal imports the CAR file into the lotus node and proposes a storage deal for it, returning the CID of the
+// deal proposal.
+func (a *Archiver) makeDeal(ctx context.Context, carPath string, root cid.Cid) (cid.Cid, error) {
+	dc := a.cfg.Deal
+
+	res, err := dc.Client.ClientImport(ctx, api.FileRef{Path: carPath, IsCAR: true})
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("import: %w", err)
+	}
+	if !res.Root.Equals(root) {
+		return cid.Undef, xerrors.Errorf("imported root %s does not match archive root %s", res.Root, root)
+	}
+
+	proposal, err := dc.Client.ClientStartDeal(ctx, &api.StartDealParams{
+		Data: &storagemarket.DataRef{
+			TransferType: storagemarket.TTGraphsync,
+			Root:         root,
+		},
+		Wallet:             dc.Wallet,
+		Miner:              dc.Miner,
+		EpochPrice:         dc.EpochPrice,
+		MinBlocksDuration:  dc.Duration,
+		ProviderCollateral: big.Zero(),
+	})
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("start deal: %w", err)
+	}
+	return *proposal, nil
+}
@@ -0,0 +1,126 @@
+package dataset
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	mh "github.com/multiformats/go-multihash"
+	"golang.org/x/xerrors"
+)
+
+// chunkSize is the maximum size of the raw blocks that files are split into. Blocks larger than 1MiB cannot be
+// transferred by graphsync when making storage deals.
+const chunkSize = 1 << 20
+
+var rawPrefix = cid.Prefix{
+	Version:  1,
+	Codec:    cid.Raw,
+	MhType:   mh.SHA2_256,
+	MhLength: -1,
+}
+
+// A File is an exported file included in an archive.
+type File struct {
+	Table string `json:"table"`
+	Name  string `json:"name"`
+	Cid   string `json:"cid"`
+	Rows  int    `json:"rows"`
+	Size  int64  `json:"size"`
+
+	path string
+}
+
+// forEachChunk calls fn with each chunk of the file at path, in order.
+func forEachChunk(path string, fn func(c cid.Cid, data []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	r := bufio.NewReaderSize(f, chunkSize)
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			c, cerr := rawPrefix.Sum(buf[:n])
+			if cerr != nil {
+				return cerr
+			}
+			if err := fn(c, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// packCAR writes a CAR file to w containing each of the files split into raw blocks. Each file is described by a
+// dag-cbor node linking to its blocks and the root of the CAR is a dag-cbor manifest linking to the file nodes, so
+// the whole archive can be retrieved by its root CID. The CID of each file node is recorded in the file and the CID
+// of the manifest is returned.
+func packCAR(w io.Writer, from, to int64, files []*File) (cid.Cid, error) {
+	var nodes []*cbor.Node
+	var links []cid.Cid
+	for _, f := range files {
+		var chunks []cid.Cid
+		if err := forEachChunk(f.path, func(c cid.Cid, _ []byte) error {
+			chunks = append(chunks, c)
+			return nil
+		}); err != nil {
+			return cid.Undef, xerrors.Errorf("read %s: %w", f.Name, err)
+		}
+
+		node, err := cbor.WrapObject(map[string]interface{}{
+			"name":   f.Name,
+			"table":  f.Table,
+			"rows":   f.Rows,
+			"size":   f.Size,
+			"chunks": chunks,
+		}, mh.SHA2_256, -1)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("encode node for %s: %w", f.Name, err)
+		}
+		f.Cid = node.Cid().String()
+		nodes = append(nodes, node)
+		links = append(links, node.Cid())
+	}
+
+	manifest, err := cbor.WrapObject(map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"files": links,
+	}, mh.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("encode manifest: %w", err)
+	}
+
+	if err := car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{manifest.Cid()}, Version: 1}, w); err != nil {
+		return cid.Undef, xerrors.Errorf("write header: %w", err)
+	}
+	if err := carutil.LdWrite(w, manifest.Cid().Bytes(), manifest.RawData()); err != nil {
+		return cid.Undef, xerrors.Errorf("write manifest: %w", err)
+	}
+	for i, f := range files {
+		if err := carutil.LdWrite(w, nodes[i].Cid().Bytes(), nodes[i].RawData()); err != nil {
+			return cid.Undef, xerrors.Errorf("write node for %s: %w", f.Name, err)
+		}
+		if err := forEachChunk(f.path, func(c cid.Cid, data []byte) error {
+			return carutil.LdWrite(w, c.Bytes(), data)
+		}); err != nil {
+			return cid.Undef, xerrors.Errorf("write %s: %w", f.Name, err)
+		}
+	}
+
+	return manifest.Cid(), nil
+}
@@ -0,0 +1,62 @@
+package dataset
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipld/go-car"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackCAR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dataset")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	small := filepath.Join(dir, "small.csv.gz")
+	require.NoError(t, ioutil.WriteFile(small, []byte("small"), 0o644))
+
+	// Large enough to be split into three chunks
+	large := filepath.Join(dir, "large.csv.gz")
+	require.NoError(t, ioutil.WriteFile(large, bytes.Repeat([]byte{1}, 2*chunkSize+10), 0o644))
+
+	files := []*File{
+		{Table: "small", Name: "small.csv.gz", Size: 5, path: small},
+		{Table: "large", Name: "large.csv.gz", Size: 2*chunkSize + 10, path: large},
+	}
+
+	var buf bytes.Buffer
+	root, err := packCAR(&buf, 10, 20, files)
+	require.NoError(t, err)
+	assert.NotEmpty(t, files[0].Cid)
+	assert.NotEqual(t, files[0].Cid, files[1].Cid)
+
+	// Packing the same files again produces the same root
+	var buf2 bytes.Buffer
+	root2, err := packCAR(&buf2, 10, 20, files)
+	require.NoError(t, err)
+	assert.Equal(t, root, root2)
+
+	cr, err := car.NewCarReader(&buf)
+	require.NoError(t, err)
+	require.Len(t, cr.Header.Roots, 1)
+	assert.Equal(t, root, cr.Header.Roots[0])
+
+	var blocks int
+	for {
+		blk, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(blk.RawData()), chunkSize)
+		blocks++
+	}
+	// manifest, two file nodes, one chunk for the small file and three for the large file
+	assert.Equal(t, 7, blocks)
+}
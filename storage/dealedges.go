@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// marketDealEdgesVersion is the first schema version containing the market_deal_edges table.
+var marketDealEdgesVersion = model.Version{Major: 1, Patch: 19}
+
+// addMarketDealEdgesSQL adds the canonical deal proposals extracted at heights after ?0 up to and including ?1 to the
+// edges between their client and provider. Each deal is counted once even if its proposal was extracted more than
+// once in the range.
+const addMarketDealEdgesSQL = `
+INSERT INTO market_deal_edges AS e (client_id, provider_id, deal_count, total_bytes, verified_bytes, total_price, first_height, last_height, updated_at)
+SELECT client_id, provider_id,
+	count(*),
+	sum(padded_piece_size),
+	coalesce(sum(padded_piece_size) FILTER (WHERE is_verified), 0),
+	sum(storage_price_per_epoch::numeric * greatest(end_epoch - start_epoch, 0)),
+	min(height), max(height), now()
+FROM (
+	SELECT DISTINCT ON (deal_id) * FROM market_deal_proposals
+	WHERE height > ?0 AND height <= ?1 AND is_canonical
+	ORDER BY deal_id, height
+) p
+GROUP BY client_id, provider_id
+ON CONFLICT (client_id, provider_id) DO UPDATE SET
+	deal_count = e.deal_count + EXCLUDED.deal_count,
+	total_bytes = e.total_bytes + EXCLUDED.total_bytes,
+	verified_bytes = e.verified_bytes + EXCLUDED.verified_bytes,
+	total_price = e.total_price + EXCLUDED.total_price,
+	first_height = least(e.first_height, EXCLUDED.first_height),
+	last_height = greatest(e.last_height, EXCLUDED.last_height),
+	updated_at = EXCLUDED.updated_at`
+
+// AddMarketDealEdges adds the deal proposals extracted at heights after from up to and including to into the
+// market_deal_edges table and returns the number of edges created or updated. Heights must not be added more than
+// once, otherwise their deals are counted twice.
+func (d *Database) AddMarketDealEdges(ctx context.Context, from, to int64) (int, error) {
+	if d.version.Before(marketDealEdgesVersion) {
+		return 0, xerrors.Errorf("market deal edges require schema version %s or later", marketDealEdgesVersion)
+	}
+
+	res, err := d.db.ExecContext(ctx, addMarketDealEdgesSQL, from, to)
+	if err != nil {
+		return 0, xerrors.Errorf("add deal edges: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// ResetMarketDealEdges deletes all rows from the market_deal_edges table so it can be rebuilt.
+func (d *Database) ResetMarketDealEdges(ctx context.Context) error {
+	if d.version.Before(marketDealEdgesVersion) {
+		return xerrors.Errorf("market deal edges require schema version %s or later", marketDealEdgesVersion)
+	}
+
+	if _, err := d.db.ExecContext(ctx, `TRUNCATE market_deal_edges`); err != nil {
+		return xerrors.Errorf("truncate deal edges: %w", err)
+	}
+	return nil
+}
+
+// marketDealEdgesBounds returns the last height added to the deal edges, or -1 if there are none, and the latest
+// height of any extracted deal proposal, or -1 if there are none.
+func (d *Database) marketDealEdgesBounds(ctx context.Context) (int64, int64, error) {
+	var last, latest int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last, &latest), `SELECT (SELECT coalesce(max(last_height), -1) FROM market_deal_edges), (SELECT coalesce(max(height), -1) FROM market_deal_proposals)`); err != nil {
+		return 0, 0, xerrors.Errorf("query deal edges progress: %w", err)
+	}
+	return last, latest, nil
+}
+
+// NewMarketDealEdgeAggregator returns an Aggregator that keeps the market_deal_edges table up to date as deal proposals
+// are extracted, refreshing every interval. Deal proposals are only aggregated once they are lag epochs behind the
+// latest extracted proposal, which gives time for reverted tipsets to be marked as non-canonical and for tipsets
+// processed out of order to be persisted. Heights are never aggregated twice since deals would be counted again. If
+// rebuild is true the table is emptied and rebuilt from all extracted proposals when the job starts.
+func NewMarketDealEdgeAggregator(db *Database, lag int64, interval time.Duration, rebuild bool) *Aggregator {
+	a := newHeightRangeAggregator("market_deal_edges", lag, 0, interval, db.marketDealEdgesBounds, func(ctx context.Context, from, to int64) error {
+		_, err := db.AddMarketDealEdges(ctx, from, to)
+		return err
+	})
+	a.params["rebuild"] = rebuild
+	if rebuild {
+		a.setup = db.ResetMarketDealEdges
+	}
+	return a
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/derived"
+)
+
+// dealTimelinesVersion is the first schema version containing the market_deal_timelines table.
+var dealTimelinesVersion = model.Version{Major: 1, Patch: 25}
+
+// The statements below apply the canonical deal proposals and states extracted at heights after ?0 up to and
+// including ?1 to market_deal_timelines. Each is idempotent so a range of heights may be applied more than once.
+const (
+	publishDealTimelinesSQL = `
+INSERT INTO market_deal_timelines AS t (deal_id, client_id, provider_id, piece_cid, padded_piece_size, is_verified,
+	start_epoch, end_epoch, published_height, state, last_height, updated_at)
+SELECT DISTINCT ON (deal_id) deal_id, client_id, provider_id, piece_cid, padded_piece_size, is_verified,
+	start_epoch, end_epoch, height, ?2, height, now()
+FROM market_deal_proposals
+WHERE height > ?0 AND height <= ?1 AND is_canonical
+ORDER BY deal_id, height
+ON CONFLICT (deal_id) DO UPDATE SET
+	published_height = least(t.published_height, EXCLUDED.published_height),
+	last_height = greatest(t.last_height, EXCLUDED.last_height),
+	updated_at = EXCLUDED.updated_at`
+
+	// least and greatest ignore nulls so the earliest height at which a change was seen is kept.
+	updateDealTimelinesSQL = `
+UPDATE market_deal_timelines t SET
+	activated_height = least(t.activated_height, s.activated_height),
+	sector_start_epoch = coalesce(t.sector_start_epoch, s.sector_start_epoch),
+	slashed_height = least(t.slashed_height, s.slashed_height),
+	slash_epoch = coalesce(t.slash_epoch, s.slash_epoch),
+	last_height = greatest(t.last_height, s.height),
+	updated_at = now()
+FROM (
+	SELECT deal_id,
+		min(height) FILTER (WHERE sector_start_epoch >= 0) AS activated_height,
+		min(sector_start_epoch) FILTER (WHERE sector_start_epoch >= 0) AS sector_start_epoch,
+		min(height) FILTER (WHERE slash_epoch >= 0) AS slashed_height,
+		min(slash_epoch) FILTER (WHERE slash_epoch >= 0) AS slash_epoch,
+		max(height) AS height
+	FROM market_deal_states
+	WHERE height > ?0 AND height <= ?1 AND is_canonical
+	GROUP BY deal_id
+) s
+WHERE t.deal_id = s.deal_id`
+
+	// Deals that are published or active move to their next state once the chain has reached the epoch of the
+	// transition. Parameters ?2 to ?6 are the published, active, slashed, expired and timed out states.
+	transitionDealTimelinesSQL = `
+UPDATE market_deal_timelines t SET
+	state = n.state,
+	ended_epoch = CASE n.state
+		WHEN ?4 THEN t.slash_epoch
+		WHEN ?5 THEN t.end_epoch
+		WHEN ?6 THEN t.start_epoch
+	END,
+	updated_at = now()
+FROM (
+	SELECT deal_id, CASE
+		WHEN slash_epoch IS NOT NULL THEN ?4
+		WHEN sector_start_epoch IS NOT NULL AND end_epoch <= ?1 THEN ?5
+		WHEN sector_start_epoch IS NOT NULL THEN ?3
+		WHEN start_epoch <= ?1 THEN ?6
+		ELSE ?2
+	END AS state
+	FROM market_deal_timelines
+	WHERE state IN (?2, ?3)
+) n
+WHERE t.deal_id = n.deal_id AND t.state <> n.state`
+)
+
+// ApplyDealTimelines applies the deal proposals and states extracted at heights after from up to and including to to
+// the market_deal_timelines table. Only deals whose proposal has been applied are tracked.
+func (d *Database) ApplyDealTimelines(ctx context.Context, from, to int64) error {
+	if d.version.Before(dealTimelinesVersion) {
+		return xerrors.Errorf("deal timelines require schema version %s or later", dealTimelinesVersion)
+	}
+
+	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, publishDealTimelinesSQL, from, to, derived.DealPublished); err != nil {
+			return xerrors.Errorf("publish deals: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, updateDealTimelinesSQL, from, to); err != nil {
+			return xerrors.Errorf("update deals: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, transitionDealTimelinesSQL, from, to,
+			derived.DealPublished, derived.DealActive, derived.DealSlashed, derived.DealExpired, derived.DealTimedOut); err != nil {
+			return xerrors.Errorf("transition deals: %w", err)
+		}
+		return nil
+	})
+}
+
+// dealTimelinesBounds returns the latest height applied to the deal timelines, or -1 if there is none, and the latest
+// height of any extracted deal state or proposal, or -1 if there is none.
+func (d *Database) dealTimelinesBounds(ctx context.Context) (int64, int64, error) {
+	var last, latest int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last, &latest), `SELECT
+	(SELECT coalesce(max(last_height), -1) FROM market_deal_timelines),
+	greatest((SELECT coalesce(max(height), -1) FROM market_deal_states), (SELECT coalesce(max(height), -1) FROM market_deal_proposals))`); err != nil {
+		return 0, 0, xerrors.Errorf("query deal timelines progress: %w", err)
+	}
+	return last, latest, nil
+}
+
+// NewDealTimelineAggregator returns an Aggregator that keeps the market_deal_timelines table up to date as deal
+// proposals and states are extracted. Deal data is only applied once it is lag epochs behind the latest extracted. It
+// refreshes every interval and each refresh applies again the lookback epochs before the latest height applied so data
+// persisted out of order is picked up.
+func NewDealTimelineAggregator(db *Database, lag int64, lookback int64, interval time.Duration) *Aggregator {
+	return newHeightRangeAggregator("market_deal_timelines", lag, lookback, interval, db.dealTimelinesBounds, db.ApplyDealTimelines)
+}
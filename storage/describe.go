@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-pg/pg/v10/orm"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/actors/common"
+	"github.com/filecoin-project/sentinel-visor/model/actors/market"
+	"github.com/filecoin-project/sentinel-visor/model/actors/miner"
+	"github.com/filecoin-project/sentinel-visor/model/actors/multisig"
+	"github.com/filecoin-project/sentinel-visor/model/actors/verifreg"
+	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	"github.com/filecoin-project/sentinel-visor/model/chain"
+	"github.com/filecoin-project/sentinel-visor/model/derived"
+	"github.com/filecoin-project/sentinel-visor/model/labels"
+	"github.com/filecoin-project/sentinel-visor/model/messages"
+	"github.com/filecoin-project/sentinel-visor/model/retrieval"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/schemas"
+	v1 "github.com/filecoin-project/sentinel-visor/schemas/v1"
+)
+
+// describedModels lists models that are not verified against the database schema, together with the first schema
+// version that contains their table, so they can be included when describing a schema.
+var describedModels = []struct {
+	model interface{}
+	since model.Version
+}{
+	{model: (*visor.ProcessingReport)(nil), since: model.Version{Major: 0}},
+	{model: (*visor.ValidationFailure)(nil), since: model.Version{Major: 1, Patch: 2}},
+	{model: (*common.ActorDeletion)(nil), since: model.Version{Major: 1, Patch: 3}},
+	{model: (*chain.EpochTimestamp)(nil), since: model.Version{Major: 1, Patch: 6}},
+	{model: (*common.BuiltinActorCode)(nil), since: model.Version{Major: 1, Patch: 8}},
+	{model: (*visor.Network)(nil), since: model.Version{Major: 1, Patch: 13}},
+	{model: (*visor.DatasetArchive)(nil), since: model.Version{Major: 1, Patch: 15}},
+	{model: (*blocks.ObservedBlockPropagation)(nil), since: model.Version{Major: 1, Patch: 16}},
+	{model: (*visor.Lineage)(nil), since: model.Version{Major: 1, Patch: 17}},
+	{model: (*derived.MinerDailyStats)(nil), since: model.Version{Major: 1, Patch: 18}},
+	{model: (*derived.MarketDealEdge)(nil), since: model.Version{Major: 1, Patch: 19}},
+	{model: (*labels.AddressTag)(nil), since: model.Version{Major: 1, Patch: 20}},
+	{model: (*messages.MessageGasByMethod)(nil), since: model.Version{Major: 1, Patch: 21}},
+	{model: (*derived.MinerWinStats)(nil), since: model.Version{Major: 1, Patch: 22}},
+	{model: (*derived.MinerSectorLifetime)(nil), since: model.Version{Major: 1, Patch: 23}},
+	{model: (*derived.VerifiedClientDatacapUsage)(nil), since: model.Version{Major: 1, Patch: 24}},
+	{model: (*derived.MarketDealTimeline)(nil), since: model.Version{Major: 1, Patch: 25}},
+	{model: (*multisig.MultisigVesting)(nil), since: model.Version{Major: 1, Patch: 26}},
+	{model: (*chain.ChainThroughput)(nil), since: model.Version{Major: 1, Patch: 27}},
+	{model: (*visor.Tenant)(nil), since: model.Version{Major: 1, Patch: 28}},
+	{model: (*derived.MinerPenalty)(nil), since: model.Version{Major: 1, Patch: 29}},
+	{model: (*retrieval.ObservedRetrievalEvent)(nil), since: model.Version{Major: 1, Patch: 30}},
+	{model: (*chain.SystemBalance)(nil), since: model.Version{Major: 1, Patch: 32}},
+	{model: (*miner.MinerDeadlineSchedule)(nil), since: model.Version{Major: 1, Patch: 33}},
+	{model: (*derived.ActorBalanceChange)(nil), since: model.Version{Major: 1, Patch: 34}},
+	{model: (*messages.MessageHeight)(nil), since: model.Version{Major: 1, Patch: 35}},
+	{model: (*visor.WalkJob)(nil), since: model.Version{Major: 1, Patch: 36}},
+	{model: (*miner.MinerSectorSnapshot)(nil), since: model.Version{Major: 1, Patch: 37}},
+	{model: (*miner.MinerPreCommitSnapshot)(nil), since: model.Version{Major: 1, Patch: 37}},
+	{model: (*market.MarketDealSnapshot)(nil), since: model.Version{Major: 1, Patch: 38}},
+	{model: (*messages.MessageGasTrace)(nil), since: model.Version{Major: 1, Patch: 39}},
+	{model: (*visor.Run)(nil), since: model.Version{Major: 1, Patch: 41}},
+	{model: (*visor.DeferredIndex)(nil), since: model.Version{Major: 1, Patch: 42}},
+	{model: (*visor.ChainHead)(nil), since: model.Version{Major: 1, Patch: 44}},
+	{model: (*chain.ObservedNodeSync)(nil), since: model.Version{Major: 1, Patch: 45}},
+	{model: (*market.MarketDealPiece)(nil), since: model.Version{Major: 1, Patch: 46}},
+	{model: (*verifreg.VerifiedRegistryGovernance)(nil), since: model.Version{Major: 1, Patch: 47}},
+	{model: (*chain.FeeMarketObservation)(nil), since: model.Version{Major: 1, Patch: 48}},
+}
+
+// A SchemaDescription describes the tables persisted by visor in a particular schema version.
+type SchemaDescription struct {
+	Version string             `json:"version"`
+	Tables  []TableDescription `json:"tables"`
+}
+
+type TableDescription struct {
+	Name    string              `json:"name"`
+	Comment string              `json:"comment,omitempty"`
+	Columns []ColumnDescription `json:"columns"`
+	Indexes []string            `json:"indexes,omitempty"`
+}
+
+type ColumnDescription struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// DescribeSchema describes the tables used by the registered models in the given schema version. Comments and
+// indexes are taken from the schema definition and are only available for schema version 1.
+func DescribeSchema(version model.Version) (*SchemaDescription, error) {
+	if version.Major != 0 && version.Major != 1 {
+		return nil, xerrors.Errorf("unsupported major version: %d", version.Major)
+	}
+	if latest := latestSchemaVersionForMajor(version.Major); latest.Patch < version.Patch {
+		return nil, xerrors.Errorf("schema version %s does not exist, latest is %s", version, latest)
+	}
+
+	type versionable interface {
+		AsVersion(model.Version) (interface{}, bool)
+	}
+
+	candidates := append([]interface{}{}, models...)
+	for _, dm := range describedModels {
+		if !version.Before(dm.since) {
+			candidates = append(candidates, dm.model)
+		}
+	}
+
+	tables := map[string]*TableDescription{}
+	for _, m := range candidates {
+		if vm, ok := m.(versionable); ok {
+			vmodel, ok := vm.AsVersion(version)
+			if !ok {
+				return nil, xerrors.Errorf("model %T does not support version %s", m, version)
+			}
+			m = vmodel
+		}
+
+		tm := orm.NewQuery(nil, m).TableModel().Table()
+		td := &TableDescription{
+			Name: stripQuotes(tm.SQLNameForSelects),
+		}
+		pks := map[string]bool{}
+		for _, f := range tm.PKs {
+			pks[f.SQLName] = true
+		}
+		for _, f := range tm.Fields {
+			td.Columns = append(td.Columns, ColumnDescription{
+				Name:       f.SQLName,
+				Type:       f.SQLType,
+				PrimaryKey: pks[f.SQLName],
+			})
+		}
+		tables[td.Name] = td
+	}
+
+	if version.Major == 1 {
+		ddl, err := SchemaSQL(version, schemas.Config{})
+		if err != nil {
+			return nil, err
+		}
+		annotateTables(tables, ddl)
+	}
+
+	desc := &SchemaDescription{
+		Version: version.String(),
+	}
+	for _, td := range tables {
+		desc.Tables = append(desc.Tables, *td)
+	}
+	sort.Slice(desc.Tables, func(i, j int) bool {
+		return desc.Tables[i].Name < desc.Tables[j].Name
+	})
+	return desc, nil
+}
+
+// SchemaSQL returns the SQL that creates the given version of the schema, consisting of the base schema followed by
+// each patch up to the version. Only schema version 1 is defined by SQL.
+func SchemaSQL(version model.Version, cfg schemas.Config) (string, error) {
+	if version.Major != 1 {
+		return "", xerrors.Errorf("schema version %s is not defined by SQL", version)
+	}
+	if latest := v1.Version(); latest.Patch < version.Patch {
+		return "", xerrors.Errorf("schema version %s does not exist, latest is %s", version, latest)
+	}
+
+	base, err := v1.GetBase(cfg)
+	if err != nil {
+		return "", xerrors.Errorf("base schema: %w", err)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(base)
+	for seq := 1; seq <= version.Patch; seq++ {
+		patch, err := v1.GetPatch(cfg, seq)
+		if err != nil {
+			return "", xerrors.Errorf("schema patch: %w", err)
+		}
+		fmt.Fprintf(&buf, "\n-- Patch %d.%d\n%s", version.Major, seq, patch)
+	}
+	return buf.String(), nil
+}
+
+var createRe = regexp.MustCompile(`(?mi)^\s*CREATE (?:OR REPLACE )?(?:TABLE|VIEW|MATERIALIZED VIEW) (?:IF NOT EXISTS )?(?:[^\s.]+\.)?"?(\w+)"?`)
+
+var (
+	visorTablesOnce sync.Once
+	visorTables     map[string]bool
+)
+
+// IsVisorTable reports whether name is a table or view of visor's own schema: a visor_ bookkeeping table, the table
+// of one of visor's models or a table or view created by the schema SQL of any version.
+func IsVisorTable(name string) bool {
+	if strings.HasPrefix(name, "visor_") {
+		return true
+	}
+
+	visorTablesOnce.Do(func() {
+		visorTables = map[string]bool{}
+		candidates := append([]interface{}{}, models...)
+		for _, dm := range describedModels {
+			candidates = append(candidates, dm.model)
+		}
+		for _, m := range candidates {
+			visorTables[stripQuotes(orm.NewQuery(nil, m).TableModel().Table().SQLNameForSelects)] = true
+		}
+
+		ddl, err := SchemaSQL(v1.Version(), schemas.Config{SchemaName: "public"})
+		if err != nil {
+			log.Errorw("failed to read schema sql", "error", err)
+			return
+		}
+		for _, m := range createRe.FindAllStringSubmatch(ddl, -1) {
+			visorTables[m[1]] = true
+		}
+	})
+	return visorTables[name]
+}
+
+var (
+	tableCommentRe  = regexp.MustCompile(`(?m)^\s*COMMENT ON TABLE (?:\S+\.)?(\w+) IS '((?:[^']|'')*)';`)
+	columnCommentRe = regexp.MustCompile(`(?m)^\s*COMMENT ON COLUMN (?:[^\s.]+\.)?(\w+)\.("?\w+"?) IS '((?:[^']|'')*)';`)
+	indexRe         = regexp.MustCompile(`(?m)^\s*CREATE (?:UNIQUE )?INDEX (?:IF NOT EXISTS )?(\w+) ON (?:\S+\.)?(\w+) `)
+)
+
+// annotateTables adds the comments and indexes found in the schema SQL to the table descriptions. Later statements
+// override earlier ones, matching the effect of applying the SQL in order.
+func annotateTables(tables map[string]*TableDescription, ddl string) {
+	for _, m := range tableCommentRe.FindAllStringSubmatch(ddl, -1) {
+		if td, ok := tables[m[1]]; ok {
+			td.Comment = strings.ReplaceAll(m[2], "''", "'")
+		}
+	}
+
+	for _, m := range columnCommentRe.FindAllStringSubmatch(ddl, -1) {
+		td, ok := tables[m[1]]
+		if !ok {
+			continue
+		}
+		column := strings.Trim(m[2], `"`)
+		for i := range td.Columns {
+			if td.Columns[i].Name == column {
+				td.Columns[i].Comment = strings.ReplaceAll(m[3], "''", "'")
+			}
+		}
+	}
+
+	for _, m := range indexRe.FindAllStringSubmatch(ddl, -1) {
+		if td, ok := tables[m[2]]; ok {
+			td.Indexes = append(td.Indexes, m[1])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/schemas"
+	v1 "github.com/filecoin-project/sentinel-visor/schemas/v1"
+)
+
+func TestSchemaSQLIncludesPatches(t *testing.T) {
+	ddl, err := SchemaSQL(v1.Version(), schemas.Config{SchemaName: "visor"})
+	require.NoError(t, err)
+	assert.Contains(t, ddl, "visor.actors")
+	assert.Contains(t, ddl, "visor_processing_reports_history")
+
+	_, err = SchemaSQL(model.Version{Major: 1, Patch: v1.Version().Patch + 1}, schemas.Config{})
+	assert.Error(t, err)
+
+	_, err = SchemaSQL(model.Version{Major: 0, Patch: 1}, schemas.Config{})
+	assert.Error(t, err)
+}
+
+func TestDescribeSchemaVersions(t *testing.T) {
+	findTable := func(desc *SchemaDescription, name string) *TableDescription {
+		for i := range desc.Tables {
+			if desc.Tables[i].Name == name {
+				return &desc.Tables[i]
+			}
+		}
+		return nil
+	}
+
+	latest, err := DescribeSchema(v1.Version())
+	require.NoError(t, err)
+
+	actors := findTable(latest, "actors")
+	require.NotNil(t, actors)
+	assert.True(t, strings.HasPrefix(actors.Comment, "Actors on chain"))
+	assert.Contains(t, actors.Indexes, "actors_height_idx")
+	assert.NotNil(t, findTable(latest, "epoch_timestamps"))
+
+	early, err := DescribeSchema(model.Version{Major: 1, Patch: 0})
+	require.NoError(t, err)
+	assert.Nil(t, findTable(early, "epoch_timestamps"))
+	assert.NotNil(t, findTable(early, "visor_processing_reports"))
+}
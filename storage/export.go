@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// An exportTable is a table holding data extracted from the chain that can be exported by height.
+type exportTable struct {
+	name string
+	pks  []string // primary key columns, used to order exported rows
+}
+
+// exportTables returns the tables present in the given schema version that hold data extracted from the chain,
+// ordered by name. Visor's own bookkeeping tables are excluded.
+func exportTables(version model.Version) []exportTable {
+	type versionable interface {
+		AsVersion(model.Version) (interface{}, bool)
+	}
+
+	candidates := append([]interface{}{}, models...)
+	for _, dm := range describedModels {
+		if !version.Before(dm.since) {
+			candidates = append(candidates, dm.model)
+		}
+	}
+
+	var tables []exportTable
+	for _, m := range candidates {
+		if vm, ok := m.(versionable); ok {
+			vmodel, ok := vm.AsVersion(version)
+			if !ok {
+				continue
+			}
+			m = vmodel
+		}
+
+		tm := orm.NewQuery(nil, m).TableModel().Table()
+		name := stripQuotes(tm.SQLNameForSelects)
+		if strings.HasPrefix(name, "visor_") || tm.FieldsMap["height"] == nil {
+			continue
+		}
+
+		et := exportTable{name: name}
+		for _, pk := range tm.PKs {
+			et.pks = append(et.pks, pk.SQLName)
+		}
+		tables = append(tables, et)
+	}
+
+	sort.Slice(tables, func(i, j int) bool { return tables[i].name < tables[j].name })
+	return tables
+}
+
+// ExportTables returns the names of the tables in the database that hold data extracted from the chain and can be
+// exported with ExportRange, in alphabetical order.
+func (d *Database) ExportTables() []string {
+	var names []string
+	for _, et := range exportTables(d.version) {
+		names = append(names, et.name)
+	}
+	return names
+}
+
+// ExportRange writes the rows of the named table with heights between from and to inclusive to w as CSV with a
+// header row and returns the number of rows written. Rows are ordered by primary key so that exporting the same data
+// twice produces identical output.
+func (d *Database) ExportRange(ctx context.Context, table string, from, to int64, w io.Writer) (int, error) {
+	tables := exportTables(d.version)
+	idx := sort.Search(len(tables), func(i int) bool { return tables[i].name >= table })
+	if idx == len(tables) || tables[idx].name != table {
+		return 0, xerrors.Errorf("table %q cannot be exported", table)
+	}
+
+	return copyRange(d.db.WithContext(ctx), tables[idx], from, to, w)
+}
+
+// A copier is a database connection or transaction that can copy the results of a query.
+type copier interface {
+	CopyTo(w io.Writer, query interface{}, params ...interface{}) (pg.Result, error)
+}
+
+// copyRange writes the rows of a table with heights between from and to inclusive to w as CSV with a header row,
+// ordered by primary key.
+func copyRange(c copier, et exportTable, from, to int64, w io.Writer) (int, error) {
+	order := make([]pg.Ident, 0, len(et.pks))
+	for _, pk := range et.pks {
+		order = append(order, pg.Ident(pk))
+	}
+
+	res, err := c.CopyTo(w, `COPY (SELECT * FROM ? WHERE height BETWEEN ? AND ? ORDER BY ?) TO STDOUT WITH CSV HEADER`,
+		pg.Ident(et.name), from, to, pg.In(order))
+	if err != nil {
+		return 0, xerrors.Errorf("copy %s: %w", et.name, err)
+	}
+	return res.RowsAffected(), nil
+}
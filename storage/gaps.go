@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// CountGaps returns the number of tasks at each height that have reported an error without a later report recording
+// their successful completion, which may have been archived. These are the gaps that gap filling would need to revisit.
+func (d *Database) CountGaps(ctx context.Context) (int, error) {
+	var count int
+	_, err := d.db.QueryOneContext(ctx, pg.Scan(&count), `
+SELECT count(*) FROM (
+	SELECT DISTINCT r.height, r.task FROM visor_processing_reports r
+	WHERE r.status = ?0
+	AND NOT EXISTS (
+		SELECT 1 FROM `+d.reportsSource("height, state_root, task, status")+` s
+		WHERE s.height = r.height AND s.state_root = r.state_root AND s.task = r.task AND s.status IN (?1, ?2)
+	)
+) gaps`, visor.ProcessingStatusError, visor.ProcessingStatusOK, visor.ProcessingStatusInfo)
+	if err != nil {
+		return 0, xerrors.Errorf("count gaps: %w", err)
+	}
+	return count, nil
+}
+
+// IndexedHeight returns the greatest height for which the named reporter has recorded a successfully completed task,
+// or zero if it has recorded none. Archived reports are included, as are tasks reported as partial since they show the
+// reporter's progress.
+func (d *Database) IndexedHeight(ctx context.Context, reporter string) (int64, error) {
+	var height int64
+	_, err := d.db.QueryOneContext(ctx, pg.Scan(&height), `SELECT coalesce(max(height), 0) FROM `+d.reportsSource("height, reporter, status")+` r WHERE reporter = ? AND status IN (?, ?, ?)`,
+		reporter, visor.ProcessingStatusOK, visor.ProcessingStatusInfo, visor.ProcessingStatusPartial)
+	if err != nil {
+		return 0, xerrors.Errorf("query indexed height: %w", err)
+	}
+	return height, nil
+}
+
+// LatestIndexedHeight returns the greatest height for which the named reporter has recorded a successfully completed
+// task among the named tasks, or zero if none has been recorded. Other reporters and tasks performed only by other jobs
+// do not count, so jobs track their progress independently. The height is read from the chain_visor_head table when it
+// has recorded any of the tasks for the reporter, falling back to the processing reports, including those archived,
+// otherwise. Tasks reported as partial count as progress.
+func (d *Database) LatestIndexedHeight(ctx context.Context, reporter string, tasks []string) (int64, error) {
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	var height int64
+	if !d.version.Before(chainHeadVersion) {
+		_, err := d.db.QueryOneContext(ctx, pg.Scan(&height), `SELECT coalesce(max(indexed_height), 0) FROM chain_visor_head WHERE reporter = ? AND task IN (?)`, reporter, pg.In(tasks))
+		if err != nil {
+			return 0, xerrors.Errorf("query latest indexed head: %w", err)
+		}
+		if height > 0 {
+			return height, nil
+		}
+	}
+	_, err := d.db.QueryOneContext(ctx, pg.Scan(&height), `SELECT coalesce(max(height), 0) FROM `+d.reportsSource("height, reporter, task, status")+` r WHERE reporter = ? AND task IN (?) AND status IN (?, ?, ?)`,
+		reporter, pg.In(tasks), visor.ProcessingStatusOK, visor.ProcessingStatusInfo, visor.ProcessingStatusPartial)
+	if err != nil {
+		return 0, xerrors.Errorf("query latest indexed height: %w", err)
+	}
+	return height, nil
+}
+
+// RecentIndexedStateRoots returns the parent state roots of the limit most recent tipsets for which any reporter has
+// recorded a successfully completed task among the named tasks, most recent first. Archived reports are included.
+func (d *Database) RecentIndexedStateRoots(ctx context.Context, tasks []string, limit int) ([]string, error) {
+	if len(tasks) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	var roots []string
+	_, err := d.db.QueryContext(ctx, &roots, `SELECT state_root FROM `+d.reportsSource("height, state_root, task, status")+` r WHERE task IN (?) AND status IN (?, ?) GROUP BY state_root ORDER BY max(height) DESC LIMIT ?`,
+		pg.In(tasks), visor.ProcessingStatusOK, visor.ProcessingStatusInfo, limit)
+	if err != nil {
+		return nil, xerrors.Errorf("query recent indexed state roots: %w", err)
+	}
+	return roots, nil
+}
+
+// CompletedTasks returns the named tasks that have recorded a successful completion at heights between from and to
+// inclusive. Archived reports are included.
+func (d *Database) CompletedTasks(ctx context.Context, from, to int64, tasks []string) ([]visor.TaskCompletion, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	var completions []visor.TaskCompletion
+	_, err := d.db.QueryContext(ctx, &completions, `SELECT DISTINCT height, state_root, task FROM `+d.reportsSource("height, state_root, task, status")+` r WHERE height BETWEEN ? AND ? AND task IN (?) AND status IN (?, ?)`,
+		from, to, pg.In(tasks), visor.ProcessingStatusOK, visor.ProcessingStatusInfo)
+	if err != nil {
+		return nil, xerrors.Errorf("query completed tasks: %w", err)
+	}
+	return completions, nil
+}
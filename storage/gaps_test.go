@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+func TestArchivedReportsCountAsCompleted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "visor_processing_reports", "visor_processing_reports_history", "chain_visor_head")
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := now.Add(-48 * time.Hour)
+	report := func(height int64, root, reporter, status string, at time.Time) *visor.ProcessingReport {
+		return &visor.ProcessingReport{
+			Height:      height,
+			StateRoot:   root,
+			Reporter:    reporter,
+			Task:        "messages",
+			StartedAt:   at,
+			CompletedAt: at,
+			Status:      status,
+		}
+	}
+
+	insertModels(ctx, t, d,
+		report(10, "a", "watch", visor.ProcessingStatusOK, old),
+		report(20, "b", "watch", visor.ProcessingStatusError, old),
+		report(20, "b", "fill", visor.ProcessingStatusOK, old),
+		report(30, "c", "watch", visor.ProcessingStatusError, now),
+	)
+
+	archived, err := d.ArchiveProcessingReports(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 3, archived)
+
+	height, err := d.IndexedHeight(ctx, "watch")
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, height)
+
+	height, err = d.LatestIndexedHeight(ctx, "watch", []string{"messages"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, height)
+
+	completions, err := d.CompletedTasks(ctx, 0, 100, []string{"messages"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []visor.TaskCompletion{
+		{Height: 10, StateRoot: "a", Task: "messages"},
+		{Height: 20, StateRoot: "b", Task: "messages"},
+	}, completions)
+
+	roots, err := d.RecentIndexedStateRoots(ctx, []string{"messages"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, roots)
+
+	gaps, err := d.CountGaps(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, gaps)
+
+	groups, err := d.ProcessingErrors(ctx, now.Add(-72*time.Hour), nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].Reports)
+	assert.Equal(t, 2, groups[0].Heights)
+	assert.Equal(t, 1, groups[0].Unresolved)
+}
+
+func TestPartialReportsAreNotCompletions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "visor_processing_reports", "visor_processing_reports_history", "chain_visor_head")
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	insertModels(ctx, t, d,
+		&visor.ProcessingReport{Height: 10, StateRoot: "a", Reporter: "watch", Task: "actorstatesraw", StartedAt: now, CompletedAt: now, Status: visor.ProcessingStatusOK},
+		&visor.ProcessingReport{Height: 20, StateRoot: "b", Reporter: "watch", Task: "actorstatesraw", StartedAt: now, CompletedAt: now, Status: visor.ProcessingStatusPartial, StatusInformation: "actor states limited to types: miner"},
+	)
+
+	// A partial report shows the reporter's progress
+	height, err := d.IndexedHeight(ctx, "watch")
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, height)
+
+	height, err = d.LatestIndexedHeight(ctx, "watch", []string{"actorstatesraw"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, height)
+
+	// but does not complete the task
+	completions, err := d.CompletedTasks(ctx, 0, 100, []string{"actorstatesraw"})
+	require.NoError(t, err)
+	assert.Equal(t, []visor.TaskCompletion{{Height: 10, StateRoot: "a", Task: "actorstatesraw"}}, completions)
+}
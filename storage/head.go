@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// chainHeadVersion is the first schema version containing the chain_visor_head table.
+var chainHeadVersion = model.Version{Major: 1, Patch: 44}
+
+// RecordObservedHead records the chain head most recently observed by reporter for each of its tasks. Schemas older
+// than version 1.44 cannot record the head and nothing is persisted.
+func (d *Database) RecordObservedHead(ctx context.Context, reporter string, tasks []string, height int64, tipset string) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if d.version.Before(chainHeadVersion) || len(tasks) == 0 {
+		return nil
+	}
+
+	if _, err := d.db.ExecContext(ctx, `
+INSERT INTO chain_visor_head (reporter, task, observed_height, observed_tipset, observed_at)
+SELECT ?0, task, ?2, ?3, ?4 FROM unnest(?1::text[]) AS task
+ON CONFLICT (reporter, task) DO UPDATE SET observed_height = EXCLUDED.observed_height, observed_tipset = EXCLUDED.observed_tipset, observed_at = EXCLUDED.observed_at
+`, reporter, pg.Array(tasks), height, tipset, d.Clock.Now()); err != nil {
+		return xerrors.Errorf("record observed head: %w", err)
+	}
+	return nil
+}
+
+// recordIndexedHead records the tipset of a completed task as the latest indexed by its reporter, unless a later one
+// has already been recorded. It must be called in the transaction that persists the outputs of the task.
+func (d *Database) recordIndexedHead(ctx context.Context, tx *pg.Tx, report *visor.ProcessingReport) error {
+	if d.version.Before(chainHeadVersion) {
+		return nil
+	}
+	switch report.Status {
+	case visor.ProcessingStatusOK, visor.ProcessingStatusInfo, visor.ProcessingStatusPartial:
+		// A partial report still shows how far its reporter has indexed
+	default:
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO chain_visor_head AS h (reporter, task, indexed_height, indexed_state_root, indexed_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (reporter, task) DO UPDATE SET indexed_height = EXCLUDED.indexed_height, indexed_state_root = EXCLUDED.indexed_state_root, indexed_at = EXCLUDED.indexed_at
+WHERE h.indexed_height IS NULL OR h.indexed_height < EXCLUDED.indexed_height
+`, report.Reporter, report.Task, report.Height, report.StateRoot, d.Clock.Now()); err != nil {
+		return xerrors.Errorf("record indexed head: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// deferredIndexesVersion is the first schema version containing the visor_deferred_indexes table.
+var deferredIndexesVersion = model.Version{Major: 1, Patch: 42}
+
+// DeferIndexes drops the secondary indexes of the named tables, which must hold data extracted from the chain, so that
+// a bulk walk writing to them does not pay for maintaining the indexes with every row it writes. The indexes of other
+// tables are kept so walks of other tasks and queries of other data are unaffected. The definition of each index is
+// recorded with holder, an identifier of the walk, so the index can be rebuilt by RebuildIndexes once every walk that
+// deferred it has finished. A walk starting while indexes of its tables are already deferred becomes a holder of them
+// too. Primary keys and unique indexes are kept since they are needed to resolve conflicting rows. It returns the
+// number of indexes dropped.
+func (d *Database) DeferIndexes(ctx context.Context, holder string, tables []string) (int, error) {
+	if d.readOnly {
+		return 0, ErrReadOnly
+	}
+	if d.version.Before(deferredIndexesVersion) {
+		return 0, xerrors.Errorf("database schema version %s cannot defer indexes, version %s is required", d.version, deferredIndexesVersion)
+	}
+
+	// Only the tables of chain data may have their indexes dropped
+	named := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		named[t] = true
+	}
+	tables = nil
+	for _, et := range exportTables(d.version) {
+		if named[et.name] {
+			tables = append(tables, et.name)
+		}
+	}
+	if len(tables) == 0 {
+		return 0, nil
+	}
+
+	dropped := 0
+	err := d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		dropped = 0
+		if err := lockDeferredIndexes(ctx, tx); err != nil {
+			return err
+		}
+
+		// Hold the indexes of the same tables deferred by walks that are still running
+		if _, err := tx.ExecContext(ctx, `UPDATE visor_deferred_indexes SET holders = array_append(holders, ?0) WHERE table_name IN (?1) AND NOT ?0 = ANY(holders)`, holder, pg.In(tables)); err != nil {
+			return xerrors.Errorf("hold deferred indexes: %w", err)
+		}
+
+		var indexes []*visor.DeferredIndex
+		if _, err := tx.QueryContext(ctx, &indexes, `
+SELECT ic.relname AS index_name, c.relname AS table_name, pg_get_indexdef(i.indexrelid) AS definition
+FROM pg_index i
+JOIN pg_class ic ON ic.oid = i.indexrelid
+JOIN pg_class c ON c.oid = i.indrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ? AND c.relname IN (?) AND NOT i.indisprimary AND NOT i.indisunique
+AND NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conindid = i.indexrelid)
+`, d.schemaConfig.SchemaName, pg.In(tables)); err != nil {
+			return xerrors.Errorf("query indexes: %w", err)
+		}
+
+		now := d.Clock.Now()
+		for _, idx := range indexes {
+			idx.Holders = []string{holder}
+			idx.DeferredAt = now
+			if _, err := tx.ModelContext(ctx, idx).OnConflict("do nothing").Insert(); err != nil {
+				return xerrors.Errorf("record index %s: %w", idx.IndexName, err)
+			}
+			if _, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS ?.?`, pg.Ident(d.schemaConfig.SchemaName), pg.Ident(idx.IndexName)); err != nil {
+				return xerrors.Errorf("drop index %s: %w", idx.IndexName, err)
+			}
+			log.Infow("deferred index", "table", idx.TableName, "index", idx.IndexName, "holder", holder)
+			dropped++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return dropped, nil
+}
+
+// RebuildIndexes releases the indexes held by holder and rebuilds those no longer held by any walk. When force is true
+// every deferred index is rebuilt regardless of its holders, for example after a walk that deferred them has crashed.
+// Indexes are built concurrently so the tables remain writable, except on hypertables which do not support concurrent
+// builds. An index that fails to build remains recorded so it can be rebuilt later. It returns the number of indexes
+// rebuilt.
+func (d *Database) RebuildIndexes(ctx context.Context, holder string, force bool) (int, error) {
+	if d.readOnly {
+		return 0, ErrReadOnly
+	}
+	if d.version.Before(deferredIndexesVersion) {
+		return 0, nil
+	}
+
+	var ready []*visor.DeferredIndex
+	err := d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		ready = nil
+		if err := lockDeferredIndexes(ctx, tx); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE visor_deferred_indexes SET holders = array_remove(holders, ?)`, holder); err != nil {
+			return xerrors.Errorf("release deferred indexes: %w", err)
+		}
+
+		// Claim the indexes to rebuild so no other walk rebuilds them at the same time
+		query := `DELETE FROM visor_deferred_indexes WHERE cardinality(holders) = 0 RETURNING *`
+		if force {
+			query = `DELETE FROM visor_deferred_indexes RETURNING *`
+		}
+		if _, err := tx.QueryContext(ctx, &ready, query); err != nil {
+			return xerrors.Errorf("claim deferred indexes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rebuilt := 0
+	var rerr error
+	for _, idx := range ready {
+		if err := d.rebuildIndex(ctx, idx); err != nil {
+			log.Errorw("failed to rebuild index", "table", idx.TableName, "index", idx.IndexName, "error", err)
+			if rerr == nil {
+				rerr = xerrors.Errorf("rebuild index %s: %w", idx.IndexName, err)
+			}
+
+			// Record the index again so it is not lost
+			idx.Holders = []string{}
+			if _, err := d.db.ModelContext(ctx, idx).OnConflict("do nothing").Insert(); err != nil {
+				log.Errorw("failed to record index that was not rebuilt", "table", idx.TableName, "index", idx.IndexName, "definition", idx.Definition, "error", err)
+			}
+			continue
+		}
+		log.Infow("rebuilt index", "table", idx.TableName, "index", idx.IndexName)
+		rebuilt++
+	}
+	return rebuilt, rerr
+}
+
+// rebuildIndex creates a deferred index from its definition.
+func (d *Database) rebuildIndex(ctx context.Context, idx *visor.DeferredIndex) error {
+	// The timescaledb catalog only exists when the extension is installed
+	var timescale, hypertable bool
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&timescale), `SELECT to_regclass('_timescaledb_catalog.hypertable') IS NOT NULL`); err != nil {
+		return xerrors.Errorf("query timescaledb: %w", err)
+	}
+	if timescale {
+		if _, err := d.db.QueryOneContext(ctx, pg.Scan(&hypertable), `SELECT EXISTS (SELECT 1 FROM _timescaledb_catalog.hypertable WHERE schema_name = ? AND table_name = ?)`,
+			d.schemaConfig.SchemaName, idx.TableName); err != nil {
+			return xerrors.Errorf("query hypertable: %w", err)
+		}
+	}
+
+	// An interrupted concurrent build leaves an invalid index behind that must be dropped before building it again
+	drop, create := `DROP INDEX CONCURRENTLY IF EXISTS ?.?`, strings.Replace(idx.Definition, "CREATE INDEX ", "CREATE INDEX CONCURRENTLY ", 1)
+	if hypertable {
+		drop, create = `DROP INDEX IF EXISTS ?.?`, idx.Definition
+	}
+	if _, err := d.db.ExecContext(ctx, drop, pg.Ident(d.schemaConfig.SchemaName), pg.Ident(idx.IndexName)); err != nil {
+		return xerrors.Errorf("drop index: %w", err)
+	}
+	if _, err := d.db.ExecContext(ctx, create); err != nil {
+		return xerrors.Errorf("create index: %w", err)
+	}
+	return nil
+}
+
+// lockDeferredIndexes serializes changes to the deferred indexes across all visor instances sharing the database.
+func lockDeferredIndexes(ctx context.Context, tx *pg.Tx) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('visor_deferred_indexes'))`); err != nil {
+		return xerrors.Errorf("acquire deferred indexes lock: %w", err)
+	}
+	return nil
+}
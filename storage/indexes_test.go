@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestDeferIndexesOfNamedTables(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "visor_deferred_indexes")
+	defer cleanup()
+	d.Clock = testutil.NewMockClock()
+
+	// Tables that do not hold chain data are ignored
+	deferred, err := d.DeferIndexes(ctx, "walk", []string{"block_headers", "visor_processing_reports"})
+	require.NoError(t, err)
+	defer func() {
+		_, err := d.RebuildIndexes(context.Background(), "", true)
+		require.NoError(t, err)
+	}()
+
+	var indexes []*visor.DeferredIndex
+	require.NoError(t, d.db.ModelContext(ctx, &indexes).Select())
+	assert.Len(t, indexes, deferred)
+	for _, idx := range indexes {
+		assert.Equal(t, "block_headers", idx.TableName, "index %s", idx.IndexName)
+		assert.Equal(t, []string{"walk"}, idx.Holders)
+	}
+
+	// A walk of other tables does not hold the deferred indexes
+	_, err = d.DeferIndexes(ctx, "other", []string{"chain_economics"})
+	require.NoError(t, err)
+	_, err = d.RebuildIndexes(ctx, "other", false)
+	require.NoError(t, err)
+
+	rebuilt, err := d.RebuildIndexes(ctx, "walk", false)
+	require.NoError(t, err)
+	assert.Equal(t, deferred, rebuilt)
+}
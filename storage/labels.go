@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	labelmodel "github.com/filecoin-project/sentinel-visor/model/labels"
+)
+
+// addressTagsVersion is the first schema version containing the address_tags table.
+var addressTagsVersion = model.Version{Major: 1, Patch: 20}
+
+// ImportAddressTags writes address tags to the address_tags table in a single transaction. Tags that already exist for
+// an address and label are replaced, except that a known ID address is kept when the new tag has none. If tags contains
+// more than one tag for an address and label the last one is written.
+func (d *Database) ImportAddressTags(ctx context.Context, tags []*labelmodel.AddressTag) error {
+	if d.version.Before(addressTagsVersion) {
+		return xerrors.Errorf("address tags require schema version %s or later", addressTagsVersion)
+	}
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// A single insert may not update the same row twice
+	tags = dedupeAddressTags(tags)
+
+	now := d.Clock.Now()
+	for _, tag := range tags {
+		tag.UpdatedAt = now
+	}
+
+	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ModelContext(ctx, &tags).
+			OnConflict("(address, label) DO UPDATE").
+			Set("category = EXCLUDED.category, id_address = coalesce(nullif(EXCLUDED.id_address, ''), address_tag.id_address), source = EXCLUDED.source, updated_at = EXCLUDED.updated_at").
+			Insert(); err != nil {
+			return xerrors.Errorf("insert address tags: %w", err)
+		}
+		return nil
+	})
+}
+
+// dedupeAddressTags returns tags with only the last tag for each address and label, in the order they first appear.
+func dedupeAddressTags(tags []*labelmodel.AddressTag) []*labelmodel.AddressTag {
+	type key struct{ address, label string }
+	index := make(map[key]int, len(tags))
+	out := make([]*labelmodel.AddressTag, 0, len(tags))
+	for _, tag := range tags {
+		k := key{address: tag.Address, label: tag.Label}
+		if i, ok := index[k]; ok {
+			out[i] = tag
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, tag)
+	}
+	return out
+}
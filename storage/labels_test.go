@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	labelmodel "github.com/filecoin-project/sentinel-visor/model/labels"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestDedupeAddressTags(t *testing.T) {
+	tags := dedupeAddressTags([]*labelmodel.AddressTag{
+		{Address: "f01000", Label: "a", Source: "first"},
+		{Address: "f01000", Label: "b"},
+		{Address: "f01000", Label: "a", Source: "second"},
+	})
+	require.Len(t, tags, 2)
+	assert.Equal(t, "a", tags[0].Label)
+	assert.Equal(t, "second", tags[0].Source)
+	assert.Equal(t, "b", tags[1].Label)
+}
+
+func TestImportAddressTags(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	d, cleanup := emptyTestDatabase(ctx, t, "address_tags")
+	defer cleanup()
+	d.Clock = testutil.NewMockClock()
+
+	// Duplicates within a batch do not fail the import
+	require.NoError(t, d.ImportAddressTags(ctx, []*labelmodel.AddressTag{
+		{Address: "f1abc", Label: "exchange", IDAddress: "f0100", Source: "first"},
+		{Address: "f1abc", Label: "exchange", IDAddress: "f0100", Source: "second"},
+	}))
+
+	// Importing without resolving keeps the known ID address
+	require.NoError(t, d.ImportAddressTags(ctx, []*labelmodel.AddressTag{
+		{Address: "f1abc", Label: "exchange", Category: "cex", Source: "third"},
+	}))
+
+	var tags []labelmodel.AddressTag
+	require.NoError(t, d.db.ModelContext(ctx, &tags).Select())
+	require.Len(t, tags, 1)
+	assert.Equal(t, "f0100", tags[0].IDAddress)
+	assert.Equal(t, "cex", tags[0].Category)
+	assert.Equal(t, "third", tags[0].Source)
+}
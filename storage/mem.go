@@ -33,6 +33,13 @@ func (j *MemStorage) PersistModel(ctx context.Context, m interface{}) error {
 		return nil
 	}
 
+	if rm, ok := m.(model.RawModel); ok {
+		j.DataMu.Lock()
+		j.Data[rm.RawTable()] = append(j.Data[rm.RawTable()], m)
+		j.DataMu.Unlock()
+		return nil
+	}
+
 	value := reflect.ValueOf(m)
 	if value.Kind() == reflect.Ptr {
 		value = value.Elem()
@@ -133,17 +133,19 @@ func validateDatabaseSchemaVersion(ctx context.Context, db *pg.DB, cfg schemas.C
 	}
 
 	if !initialized {
-		return model.Version{}, xerrors.Errorf("schema not installed in database")
+		return model.Version{}, xerrors.Errorf("schema %q not installed in database, run visor migrate --latest to install it", cfg.SchemaName)
 	}
 
 	latestVersion := LatestSchemaVersion()
 	switch {
 	case latestVersion.Before(dbVersion):
 		// porridge too hot
-		return model.Version{}, ErrSchemaTooNew
+		return model.Version{}, xerrors.Errorf("%w: schema %q is version %s but this version of visor supports at most %s, upgrade visor to a release that supports the schema",
+			ErrSchemaTooNew, cfg.SchemaName, dbVersion, latestVersion)
 	case dbVersion.Before(model.OldestSupportedSchemaVersion):
 		// porridge too cold
-		return model.Version{}, ErrSchemaTooOld
+		return model.Version{}, xerrors.Errorf("%w: schema %q is version %s but this version of visor requires at least %s, run visor migrate --latest to upgrade the schema",
+			ErrSchemaTooOld, cfg.SchemaName, dbVersion, model.OldestSupportedSchemaVersion)
 	default:
 		// just right
 		return dbVersion, nil
@@ -225,8 +227,9 @@ func (d *Database) MigrateSchemaTo(ctx context.Context, target model.Version) er
 		return xerrors.Errorf("initializing schema version tables: %w", err)
 	}
 
-	// Check if we need to create the base schema
-	if dbVersion.Patch == 0 {
+	// Check if we need to create the base schema. A major version with no patches applied is still initialized
+	// so we can't rely on the patch number alone.
+	if !initialized {
 		log.Infof("creating base schema for major version %d", target.Major)
 
 		base, err := baseForVersion(target, d.SchemaConfig())
@@ -249,6 +252,11 @@ func (d *Database) MigrateSchemaTo(ctx context.Context, target model.Version) er
 		}
 	}()
 
+	// Tenant views read from the data tables so must be removed while migrations alter them
+	if err := dropTenantsViews(ctx, db, d.SchemaConfig().SchemaName); err != nil {
+		return xerrors.Errorf("drop tenant views: %w", err)
+	}
+
 	// Do we need to rollback schema version
 	if dbVersion.Patch > target.Patch {
 		for dbVersion.Patch > target.Patch {
@@ -260,6 +268,9 @@ func (d *Database) MigrateSchemaTo(ctx context.Context, target model.Version) er
 			dbVersion.Patch = int(newDBPatch)
 			log.Infof("current database schema is now version %s", dbVersion)
 		}
+		if err := refreshTenants(ctx, db, d.SchemaConfig().SchemaName); err != nil {
+			return xerrors.Errorf("refresh tenant views: %w", err)
+		}
 		return nil
 	}
 
@@ -274,6 +285,10 @@ func (d *Database) MigrateSchemaTo(ctx context.Context, target model.Version) er
 
 	log.Infof("current database schema is now version %s", dbVersion)
 
+	if err := refreshTenants(ctx, db, d.SchemaConfig().SchemaName); err != nil {
+		return xerrors.Errorf("refresh tenant views: %w", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	minermodel "github.com/filecoin-project/sentinel-visor/model/actors/miner"
+)
+
+// minerPenaltiesVersion is the first schema version containing the miner_penalties table.
+var minerPenaltiesVersion = model.Version{Major: 1, Patch: 29}
+
+// Method numbers of the storage miner actor used to classify penalties. They are the same in every actors version.
+const (
+	minerTerminateSectorsMethod     = 9
+	minerDeclareFaultsMethod        = 10
+	minerReportConsensusFaultMethod = 15
+)
+
+// applyMinerPenaltiesSQL computes the rows of miner_penalties for heights after ?0 up to and including ?1. Penalties
+// are transfers from a miner to the burnt funds actor (ID 99), compared without its network prefix. Each transfer is
+// classified by the message that caused it: a consensus fault report, a termination or a fault declaration sent to
+// the miner. Transfers caused by any other message sent to the miner repay fee debt. Transfers made by cron are
+// termination fees if sectors of the miner were terminated at the same epoch, and fault fees otherwise. Penalties that
+// could not be paid from the miner's balance are added to its fee debt instead of being burnt.
+const applyMinerPenaltiesSQL = `
+WITH burns AS (
+	SELECT i.height, i."from" AS miner_id, i.value,
+		CASE
+			WHEN m."to" = i."from" AND m.method = ?2 THEN 'consensus_fault'
+			WHEN m."to" = i."from" AND m.method = ?3 THEN 'termination'
+			WHEN m."to" = i."from" AND m.method = ?4 THEN 'fault'
+			WHEN m.cid IS NOT NULL THEN 'debt'
+			WHEN EXISTS (
+				SELECT 1 FROM miner_sector_events e
+				WHERE e.miner_id = i."from" AND e.height = i.height AND e.is_canonical AND e.event = ?5
+			) THEN 'termination'
+			ELSE 'fault'
+		END AS kind
+	FROM internal_messages i
+	LEFT JOIN LATERAL (
+		SELECT cid, "to", method FROM messages
+		WHERE cid = i.source_message AND height BETWEEN i.height - 1 AND i.height
+		LIMIT 1
+	) m ON true
+	WHERE i.height > ?0 AND i.height <= ?1 AND i.is_canonical AND i.exit_code = 0 AND substr(i."to", 2) = '099'
+		AND i."from" IN (SELECT miner_id FROM power_actor_claims)
+), penalties AS (
+	SELECT height, miner_id,
+		coalesce(sum(value) FILTER (WHERE kind = 'fault'), 0) AS fault_fee,
+		coalesce(sum(value) FILTER (WHERE kind = 'termination'), 0) AS termination_fee,
+		coalesce(sum(value) FILTER (WHERE kind = 'consensus_fault'), 0) AS consensus_fault_penalty,
+		coalesce(sum(value) FILTER (WHERE kind = 'debt'), 0) AS debt_repaid,
+		sum(value) AS total_burnt
+	FROM burns
+	GROUP BY height, miner_id
+), debts AS (
+	SELECT f.height, f.miner_id, f.fee_debt - coalesce(prev.fee_debt, 0) AS fee_debt_change
+	FROM miner_fee_debts f
+	LEFT JOIN LATERAL (
+		SELECT fee_debt FROM miner_fee_debts p
+		WHERE p.miner_id = f.miner_id AND p.height < f.height AND p.is_canonical
+		ORDER BY p.height DESC LIMIT 1
+	) prev ON true
+	WHERE f.height > ?0 AND f.height <= ?1 AND f.is_canonical
+)
+INSERT INTO miner_penalties (height, miner_id, fault_fee, termination_fee, consensus_fault_penalty, debt_repaid,
+	total_burnt, fee_debt_change, updated_at)
+SELECT height, miner_id,
+	coalesce(p.fault_fee, 0), coalesce(p.termination_fee, 0), coalesce(p.consensus_fault_penalty, 0), coalesce(p.debt_repaid, 0),
+	coalesce(p.total_burnt, 0), coalesce(d.fee_debt_change, 0), now()
+FROM penalties p
+FULL OUTER JOIN debts d USING (height, miner_id)
+WHERE p.total_burnt IS NOT NULL OR d.fee_debt_change <> 0`
+
+// ApplyMinerPenalties recomputes the miner_penalties rows for heights after from up to and including to.
+func (d *Database) ApplyMinerPenalties(ctx context.Context, from, to int64) error {
+	if d.version.Before(minerPenaltiesVersion) {
+		return xerrors.Errorf("miner penalties require schema version %s or later", minerPenaltiesVersion)
+	}
+
+	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM miner_penalties WHERE height > ? AND height <= ?`, from, to); err != nil {
+			return xerrors.Errorf("delete heights: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, applyMinerPenaltiesSQL, from, to, minerReportConsensusFaultMethod,
+			minerTerminateSectorsMethod, minerDeclareFaultsMethod, minermodel.SectorTerminated); err != nil {
+			return xerrors.Errorf("insert heights: %w", err)
+		}
+		return nil
+	})
+}
+
+// minerPenaltiesBounds returns the latest height of any penalty applied, or -1 if there are none, and the latest height
+// of any extracted internal message, or -1 if there are none.
+func (d *Database) minerPenaltiesBounds(ctx context.Context) (int64, int64, error) {
+	var last, latest int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last, &latest), `SELECT (SELECT coalesce(max(height), -1) FROM miner_penalties), (SELECT coalesce(max(height), -1) FROM internal_messages)`); err != nil {
+		return 0, 0, xerrors.Errorf("query miner penalties progress: %w", err)
+	}
+	return last, latest, nil
+}
+
+// NewMinerPenaltyAggregator returns an Aggregator that keeps the miner_penalties table up to date as internal messages
+// and miner state are extracted. Penalties are only applied once they are lag epochs behind the latest extracted
+// internal message. It refreshes every interval and each refresh applies again the lookback epochs before the latest
+// height applied so data persisted out of order is picked up.
+func NewMinerPenaltyAggregator(db *Database, lag int64, lookback int64, interval time.Duration) *Aggregator {
+	return newHeightRangeAggregator("miner_penalties", lag, lookback, interval, db.minerPenaltiesBounds, db.ApplyMinerPenalties)
+}
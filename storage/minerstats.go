@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	minermodel "github.com/filecoin-project/sentinel-visor/model/actors/miner"
+)
+
+// minerDailyStatsVersion is the first schema version containing the miner_daily_stats table.
+var minerDailyStatsVersion = model.Version{Major: 1, Patch: 18}
+
+// refreshMinerDailyStatsSQL recomputes the rows of miner_daily_stats for a day whose epochs span heights ?1 to ?2.
+// Rows are produced for every miner with a power claim, sector event, reward or penalty during the day. Rewards are
+// transfers from the reward actor (ID 2) and penalties are transfers to the burnt funds actor (ID 99); actor IDs are
+// compared without their network prefix.
+const refreshMinerDailyStatsSQL = `
+INSERT INTO miner_daily_stats (day, miner_id, raw_byte_power, quality_adj_power, raw_byte_power_delta, quality_adj_power_delta,
+	sectors_added, sectors_faulted, sectors_terminated, rewards, penalties, from_height, to_height, updated_at)
+SELECT ?0::date, m.miner_id,
+	coalesce(p.raw_byte_power, prev.raw_byte_power, 0),
+	coalesce(p.quality_adj_power, prev.quality_adj_power, 0),
+	coalesce(p.raw_byte_power - coalesce(prev.raw_byte_power, 0), 0),
+	coalesce(p.quality_adj_power - coalesce(prev.quality_adj_power, 0), 0),
+	s.added, s.faulted, s.terminated,
+	r.rewards, pen.penalties,
+	?1, ?2, now()
+FROM (
+	SELECT miner_id FROM power_actor_claims WHERE height BETWEEN ?1 AND ?2 AND is_canonical
+	UNION SELECT miner_id FROM miner_sector_events WHERE height BETWEEN ?1 AND ?2 AND is_canonical
+	UNION SELECT "to" FROM internal_messages WHERE height BETWEEN ?1 AND ?2 AND is_canonical AND exit_code = 0 AND substr("from", 2) = '02'
+	UNION SELECT "from" FROM internal_messages WHERE height BETWEEN ?1 AND ?2 AND is_canonical AND exit_code = 0 AND substr("to", 2) = '099'
+		AND "from" IN (SELECT miner_id FROM power_actor_claims)
+) m
+LEFT JOIN LATERAL (
+	SELECT raw_byte_power, quality_adj_power FROM power_actor_claims c
+	WHERE c.miner_id = m.miner_id AND c.height BETWEEN ?1 AND ?2 AND c.is_canonical
+	ORDER BY c.height DESC LIMIT 1
+) p ON true
+LEFT JOIN LATERAL (
+	SELECT raw_byte_power, quality_adj_power FROM power_actor_claims c
+	WHERE c.miner_id = m.miner_id AND c.height < ?1 AND c.is_canonical
+	ORDER BY c.height DESC LIMIT 1
+) prev ON true
+CROSS JOIN LATERAL (
+	SELECT
+		count(*) FILTER (WHERE e.event IN (?3, ?4)) AS added,
+		count(*) FILTER (WHERE e.event = ?5) AS faulted,
+		count(*) FILTER (WHERE e.event = ?6) AS terminated
+	FROM miner_sector_events e
+	WHERE e.miner_id = m.miner_id AND e.height BETWEEN ?1 AND ?2 AND e.is_canonical
+) s
+CROSS JOIN LATERAL (
+	SELECT coalesce(sum(i.value), 0) AS rewards FROM internal_messages i
+	WHERE i."to" = m.miner_id AND i.height BETWEEN ?1 AND ?2 AND i.is_canonical AND i.exit_code = 0 AND substr(i."from", 2) = '02'
+) r
+CROSS JOIN LATERAL (
+	SELECT coalesce(sum(i.value), 0) AS penalties FROM internal_messages i
+	WHERE i."from" = m.miner_id AND i.height BETWEEN ?1 AND ?2 AND i.is_canonical AND i.exit_code = 0 AND substr(i."to", 2) = '099'
+) pen`
+
+// RefreshMinerDailyStats recomputes the miner_daily_stats rows for the UTC day containing day from the data indexed
+// so far and returns the number of rows written. Epochs are assigned to days using epoch_timestamps, which is written
+// by the blocks task. It returns zero if no epochs of the day have been indexed.
+func (d *Database) RefreshMinerDailyStats(ctx context.Context, day time.Time) (int, error) {
+	if d.version.Before(minerDailyStatsVersion) {
+		return 0, xerrors.Errorf("miner daily stats require schema version %s or later", minerDailyStatsVersion)
+	}
+
+	start := day.UTC().Truncate(24 * time.Hour)
+
+	var written int
+	err := d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		from, to, ok, err := dayHeights(ctx, tx, start)
+		if err != nil || !ok {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM miner_daily_stats WHERE day = ?::date`, start); err != nil {
+			return xerrors.Errorf("delete day: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, refreshMinerDailyStatsSQL, start, from, to,
+			minermodel.SectorAdded, minermodel.CommitCapacityAdded, minermodel.SectorFaulted, minermodel.SectorTerminated)
+		if err != nil {
+			return xerrors.Errorf("insert day: %w", err)
+		}
+		written = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// NewMinerDailyStatsAggregator returns an Aggregator that keeps the miner_daily_stats table up to date as epochs are
+// indexed. It starts from the day containing the epoch at height from and refreshes every interval, recomputing the
+// last lookback days already aggregated so data indexed late, for example by a walk, is included.
+func NewMinerDailyStatsAggregator(db *Database, from int64, lookback int, interval time.Duration) *Aggregator {
+	return newDailyAggregator(db, "miner_daily_stats", from, lookback, interval, db.RefreshMinerDailyStats)
+}
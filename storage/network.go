@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// networkVersion is the first schema version containing the visor_network table.
+var networkVersion = model.Version{Major: 1, Patch: 13}
+
+var ErrNetworkMismatch = errors.New("database schema holds data for a different network")
+
+// VerifyNetwork checks that the database schema holds data for the network with the given name and genesis block.
+// The network is recorded if the schema does not yet have one. ErrNetworkMismatch is returned if the schema holds
+// data for a different network. Schemas older than version 1.13 cannot record a network and are not checked.
+func (d *Database) VerifyNetwork(ctx context.Context, name string, genesis string) error {
+	if d.version.Before(networkVersion) {
+		log.Warnw("database schema does not record the network, data from different networks may be mixed", "schema_version", d.version.String())
+		return nil
+	}
+
+	// At most one row can exist so a concurrent insert for another network will be ignored and detected below
+	if _, err := d.db.ModelContext(ctx, &visor.Network{
+		Name:       name,
+		Genesis:    genesis,
+		RecordedAt: d.Clock.Now(),
+	}).OnConflict("do nothing").Insert(); err != nil {
+		return xerrors.Errorf("record network: %w", err)
+	}
+
+	var recorded visor.Network
+	if err := d.db.ModelContext(ctx, &recorded).Limit(1).Select(); err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return xerrors.Errorf("network not recorded")
+		}
+		return xerrors.Errorf("query network: %w", err)
+	}
+
+	if recorded.Name != name || recorded.Genesis != genesis {
+		return xerrors.Errorf("%w: schema %q holds %s (genesis %s) recorded at %s, lens is connected to %s (genesis %s)", ErrNetworkMismatch,
+			d.schemaConfig.SchemaName, recorded.Name, recorded.Genesis, recorded.RecordedAt.Format(time.RFC3339), name, genesis)
+	}
+	return nil
+}
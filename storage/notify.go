@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// NotifyChannelPrefix is prepended to the name of a table to form the name of the channel that is notified when rows
+// persisted to the table are committed. For example, consumers of block headers should LISTEN on
+// visor_block_headers.
+const NotifyChannelPrefix = "visor_"
+
+// A PersistedNotification is the JSON payload of the notification sent for each table written to by a transaction.
+type PersistedNotification struct {
+	Table     string `json:"table"`
+	MinHeight int64  `json:"min_height"` // lowest height of the persisted rows, zero if the table has no height
+	MaxHeight int64  `json:"max_height"` // highest height of the persisted rows, zero if the table has no height
+	Rows      int    `json:"rows"`
+}
+
+// NotifyChannel returns the name of the channel notified when rows persisted to the named table are committed.
+func NotifyChannel(table string) string {
+	return NotifyChannelPrefix + table
+}
+
+// notifications accumulates the notifications to be sent when a transaction commits.
+type notifications map[string]*PersistedNotification
+
+// record adds the rows held by m, which must be a pointer to a model or a pointer to a slice of models, to the
+// notification for its table.
+func (n notifications) record(m interface{}) {
+	tbl := pg.Model(m).TableModel().Table()
+	name := stripQuotes(tbl.SQLNameForSelects)
+	height := tbl.FieldsMap["height"]
+
+	pn, ok := n[name]
+	if !ok {
+		pn = &PersistedNotification{Table: name}
+		n[name] = pn
+	}
+
+	add := func(v reflect.Value) {
+		pn.Rows++
+		if height == nil {
+			return
+		}
+		h := height.Value(reflect.Indirect(v)).Int()
+		if pn.Rows == 1 || h < pn.MinHeight {
+			pn.MinHeight = h
+		}
+		if pn.Rows == 1 || h > pn.MaxHeight {
+			pn.MaxHeight = h
+		}
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(m))
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			add(value.Index(i))
+		}
+		return
+	}
+	add(value)
+}
+
+// recordRaw adds the rows of a raw model to the notification for its table. Heights are taken from a height column
+// if the model has one.
+func (n notifications) recordRaw(rm model.RawModel) {
+	name := rm.RawTable()
+	pn, ok := n[name]
+	if !ok {
+		pn = &PersistedNotification{Table: name}
+		n[name] = pn
+	}
+
+	heightCol := -1
+	for i, c := range rm.RawColumns() {
+		if c == "height" {
+			heightCol = i
+		}
+	}
+
+	for _, row := range rm.RawRows() {
+		pn.Rows++
+		if heightCol == -1 || row[heightCol] == nil {
+			continue
+		}
+		h, err := strconv.ParseInt(*row[heightCol], 10, 64)
+		if err != nil {
+			continue
+		}
+		if pn.Rows == 1 || h < pn.MinHeight {
+			pn.MinHeight = h
+		}
+		if pn.Rows == 1 || h > pn.MaxHeight {
+			pn.MaxHeight = h
+		}
+	}
+}
+
+// send queues a notification for each table on the transaction. Postgres delivers them to listeners only if the
+// transaction commits.
+func (n notifications) send(ctx context.Context, tx *pg.Tx) error {
+	names := make([]string, 0, len(n))
+	for name := range n {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		payload, err := json.Marshal(n[name])
+		if err != nil {
+			return xerrors.Errorf("marshal notification: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `SELECT pg_notify(?, ?)`, NotifyChannel(name), string(payload)); err != nil {
+			return xerrors.Errorf("notify %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/version"
+)
+
+type unheightedModel struct {
+	Address string `pg:",pk"`
+}
+
+func TestNotificationsRecord(t *testing.T) {
+	n := notifications{}
+
+	n.record(&[]*sortableModel{
+		{Height: 12, Address: "f01"},
+		{Height: 10, Address: "f02"},
+	})
+	n.record(&sortableModel{Height: 11, Address: "f03"})
+	n.record(&unheightedModel{Address: "f01"})
+
+	require.Len(t, n, 2)
+	assert.Equal(t, &PersistedNotification{Table: "sortable_models", MinHeight: 10, MaxHeight: 12, Rows: 3}, n["sortable_models"])
+	assert.Equal(t, &PersistedNotification{Table: "unheighted_models", Rows: 1}, n["unheighted_models"])
+	assert.Equal(t, "visor_sortable_models", NotifyChannel("sortable_models"))
+}
+
+func TestLineageTables(t *testing.T) {
+	n := notifications{}
+	n.record(&[]*sortableModel{{Height: 12, Address: "f01"}, {Height: 12, Address: "f02"}})
+
+	d := &Database{Clock: clock.NewMock()}
+	ctx := visor.WithJob(context.Background(), visor.Job{ID: 3, Name: "watcher"})
+	report := &visor.ProcessingReport{Height: 12, StateRoot: "bafyroot", Reporter: "visor", Task: "blocks"}
+
+	l := d.newLineage(ctx, report, n)
+	assert.Equal(t, int64(12), l.Height)
+	assert.Equal(t, "bafyroot", l.StateRoot)
+	assert.Equal(t, int64(3), l.JobID)
+	assert.Equal(t, "watcher", l.JobName)
+	assert.Equal(t, version.String(), l.VisorVersion)
+	assert.Equal(t, map[string]int{"sortable_models": 2}, l.Tables)
+}
@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/storage/parquet"
+	"github.com/filecoin-project/sentinel-visor/version"
+)
+
+var (
+	_ model.Storage     = (*ParquetStorage)(nil)
+	_ model.TaskStorage = (*ParquetStorage)(nil)
+)
+
+// DefaultParquetPartitionSize is the number of epochs in each height range partition of Parquet storage, one day.
+const DefaultParquetPartitionSize = 2880
+
+// unknownPartition is the partition value used for rows whose task or height is not known.
+const unknownPartition = "unknown"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ParquetStorage persists models to Parquet files on local disk so that engines such as Spark and Trino can read the
+// extracted data without a database. Each table is a directory of files partitioned by the task that extracted the rows
+// and by height range, using the key=value directory names those engines understand:
+//
+//	<path>/<table>/task=<task>/height_range=<from>-<to>/part-<time>-<seq>.parquet
+//
+// Tables without a height column are partitioned by task only. Every batch is written to new files since Parquet files
+// cannot be appended to. Models are mapped to rows in the same way as they are by go-pg, so columns have the names and
+// hold the nulls they would have in the database.
+type ParquetStorage struct {
+	path          string
+	version       model.Version // schema version
+	partitionSize int64         // number of epochs in each height range partition
+	seq           uint64        // accessed atomically, distinguishes files written at the same time
+
+	// Redactor removes or hashes the values of configured columns before they are written.
+	Redactor *Redactor
+}
+
+// NewParquetStorage returns a storage writing files below path. A partitionSize of zero selects
+// DefaultParquetPartitionSize.
+func NewParquetStorage(path string, version model.Version, partitionSize int64) (*ParquetStorage, error) {
+	if partitionSize < 0 {
+		return nil, xerrors.Errorf("partition size must not be negative")
+	}
+	if partitionSize == 0 {
+		partitionSize = DefaultParquetPartitionSize
+	}
+	return &ParquetStorage{
+		path:          path,
+		version:       version,
+		partitionSize: partitionSize,
+	}, nil
+}
+
+func NewParquetStorageLatest(path string, partitionSize int64) (*ParquetStorage, error) {
+	return NewParquetStorage(path, LatestSchemaVersion(), partitionSize)
+}
+
+// PersistBatch persists a batch of models that were not extracted by a task, such as those of observers, to the
+// unknown task partition.
+func (p *ParquetStorage) PersistBatch(ctx context.Context, ps ...model.Persistable) error {
+	return p.PersistTaskBatch(ctx, unknownPartition, ps...)
+}
+
+// PersistTaskBatch persists a batch of models extracted by task to new Parquet files, one for each table and partition
+// the batch has rows for.
+func (p *ParquetStorage) PersistTaskBatch(ctx context.Context, task string, ps ...model.Persistable) error {
+	if task == "" {
+		task = unknownPartition
+	}
+
+	batch := &ParquetBatch{
+		tables:  map[string]*parquetTable{},
+		version: p.version,
+	}
+
+	for _, persistable := range ps {
+		if err := persistable.Persist(ctx, p.Redactor.Batch(batch), p.version); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(batch.tables))
+	for name := range batch.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := batch.tables[name]
+		if len(t.rows) == 0 {
+			continue
+		}
+
+		partitions := map[string][][]interface{}{}
+		for _, row := range t.rows {
+			dir := p.partitionDir(t, task, row)
+			partitions[dir] = append(partitions[dir], row)
+		}
+
+		for dir, rows := range partitions {
+			if err := p.writeFile(dir, t.columns, rows); err != nil {
+				return xerrors.Errorf("write %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// partitionDir returns the directory holding the files of the partition a row of t belongs to.
+func (p *ParquetStorage) partitionDir(t *parquetTable, task string, row []interface{}) string {
+	dir := filepath.Join(p.path, t.name, "task="+task)
+	if t.height < 0 {
+		return dir
+	}
+
+	var height int64
+	switch v := row[t.height].(type) {
+	case int64:
+		height = v
+	case string: // raw models hold text values
+		var err error
+		if height, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filepath.Join(dir, "height_range="+unknownPartition)
+		}
+	default:
+		return filepath.Join(dir, "height_range="+unknownPartition)
+	}
+
+	from := height - height%p.partitionSize
+	return filepath.Join(dir, fmt.Sprintf("height_range=%010d-%010d", from, from+p.partitionSize-1))
+}
+
+// writeFile writes rows to a new file in dir. The file is written under a hidden name and renamed once complete so
+// readers never see a partial file.
+func (p *ParquetStorage) writeFile(dir string, columns []parquet.Column, rows [][]interface{}) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("create directory: %w", err)
+	}
+
+	name := fmt.Sprintf("part-%d-%d.parquet", time.Now().UnixNano(), atomic.AddUint64(&p.seq, 1))
+	tmp := filepath.Join(dir, "."+name)
+
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return xerrors.Errorf("create file: %w", err)
+	}
+	if err := parquet.Write(f, columns, rows, "visor version "+version.String()); err != nil {
+		f.Close()      // nolint: errcheck
+		os.Remove(tmp) // nolint: errcheck
+		return xerrors.Errorf("encode file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()      // nolint: errcheck
+		os.Remove(tmp) // nolint: errcheck
+		return xerrors.Errorf("sync file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp) // nolint: errcheck
+		return xerrors.Errorf("close file: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp) // nolint: errcheck
+		return xerrors.Errorf("rename file: %w", err)
+	}
+	return nil
+}
+
+// A parquetTable holds the rows of a table persisted in a batch.
+type parquetTable struct {
+	name    string
+	columns []parquet.Column
+	height  int // index of the height column, -1 if the table has none
+	rows    [][]interface{}
+}
+
+type ParquetBatch struct {
+	tables  map[string]*parquetTable
+	version model.Version // schema version used when persisting the batch
+}
+
+func (b *ParquetBatch) PersistModel(ctx context.Context, m interface{}) error {
+	if rm, ok := m.(model.RawModel); ok {
+		return b.persistRaw(rm)
+	}
+
+	value := reflect.ValueOf(m)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		// Write rows in a consistent order so repeated runs produce identical files
+		model.SortModels(m)
+		for i := 0; i < value.Len(); i++ {
+			if err := b.PersistModel(ctx, value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		// go-pg may allocate embedded structs when reading fields so it needs an addressable value
+		if !value.CanAddr() {
+			cp := reflect.New(value.Type()).Elem()
+			cp.Set(value)
+			value = cp
+		}
+
+		tbl := orm.GetTable(value.Type())
+		name := stripQuotes(tbl.SQLNameForSelects)
+		t, ok := b.tables[name]
+		if !ok {
+			t = &parquetTable{name: name, height: -1}
+			for i, fld := range tbl.Fields {
+				t.columns = append(t.columns, parquetColumn(fld.SQLName, fld.Type, fld.SQLType))
+				if fld.SQLName == "height" {
+					t.height = i
+				}
+			}
+			b.tables[name] = t
+		}
+		if len(tbl.Fields) != len(t.columns) {
+			return xerrors.Errorf("model %s has %d columns for table %s, expected %d", value.Type(), len(tbl.Fields), name, len(t.columns))
+		}
+
+		row := make([]interface{}, len(tbl.Fields))
+		for i, fld := range tbl.Fields {
+			// go-pg writes zero values as null unless the field is marked use_zero
+			if fld.NullZero() && fld.HasZeroValue(value) {
+				continue
+			}
+			v, err := parquetValue(fld.Value(value), t.columns[i], fld.SQLType)
+			if err != nil {
+				return xerrors.Errorf("%s.%s: %w", name, fld.SQLName, err)
+			}
+			row[i] = v
+		}
+		t.rows = append(t.rows, row)
+		return nil
+	default:
+		return ErrMarshalUnsupportedType
+	}
+}
+
+// persistRaw adds the rows of a raw model. Their values are written as text since the types of the columns are not
+// known.
+func (b *ParquetBatch) persistRaw(rm model.RawModel) error {
+	name := rm.RawTable()
+	t, ok := b.tables[name]
+	if !ok {
+		t = &parquetTable{name: name, height: -1}
+		for i, c := range rm.RawColumns() {
+			t.columns = append(t.columns, parquet.Column{Name: c, Type: parquet.ByteArray, Annotation: parquet.String})
+			if c == "height" {
+				t.height = i
+			}
+		}
+		b.tables[name] = t
+	}
+
+	for _, raw := range rm.RawRows() {
+		if len(raw) != len(t.columns) {
+			return xerrors.Errorf("raw row for table %s has %d values, expected %d", name, len(raw), len(t.columns))
+		}
+		row := make([]interface{}, len(raw))
+		for i, v := range raw {
+			if v != nil {
+				row[i] = *v
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	return nil
+}
+
+// parquetColumn returns the Parquet column used for a field of type typ whose column has type sqlType.
+func parquetColumn(name string, typ reflect.Type, sqlType string) parquet.Column {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	col := parquet.Column{Name: name}
+	switch {
+	case typ == timeType:
+		col.Type, col.Annotation = parquet.Int64, parquet.TimestampMicros
+	case typ.Kind() == reflect.Bool:
+		col.Type = parquet.Boolean
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		col.Type = parquet.Int64
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		col.Type = parquet.Double
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 && sqlType != "json" && sqlType != "jsonb":
+		col.Type = parquet.ByteArray
+	default:
+		col.Type, col.Annotation = parquet.ByteArray, parquet.String
+	}
+	return col
+}
+
+// parquetValue converts the value of a field to the value written in col.
+func parquetValue(v reflect.Value, col parquet.Column, sqlType string) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch col.Type {
+	case parquet.Boolean:
+		return v.Bool(), nil
+	case parquet.Double:
+		return v.Float(), nil
+	case parquet.Int64:
+		if col.Annotation == parquet.TimestampMicros {
+			t := v.Interface().(time.Time)
+			return t.Unix()*1e6 + int64(t.Nanosecond()/1e3), nil
+		}
+		if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64 {
+			return int64(v.Uint()), nil
+		}
+		return v.Int(), nil
+	}
+
+	if col.Annotation == parquet.NoAnnotation {
+		return v.Bytes(), nil
+	}
+
+	// Strings marked as json type are assumed to already be encoded
+	switch {
+	case v.Kind() == reflect.String:
+		return v.String(), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return string(v.Bytes()), nil
+	case sqlType == "json" || sqlType == "jsonb" || v.Kind() == reflect.Interface || v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
@@ -0,0 +1,138 @@
+// Package parquet writes the flat tables persisted by visor as Parquet files using github.com/xitongsys/parquet-go, so
+// they can be read by Spark, Trino and other engines that consume Parquet. Every column is optional and pages are
+// compressed with snappy.
+package parquet
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	format "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+	"golang.org/x/xerrors"
+)
+
+// A Type is the physical type of the values of a column.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	ByteArray
+)
+
+// typeNames maps types to their names in the schema metadata of the writer.
+var typeNames = map[Type]string{
+	Boolean:   "BOOLEAN",
+	Int64:     "INT64",
+	Double:    "DOUBLE",
+	ByteArray: "BYTE_ARRAY",
+}
+
+// An Annotation tells readers how to interpret the values of a column.
+type Annotation int
+
+const (
+	NoAnnotation    Annotation = iota
+	String                     // a ByteArray holding UTF-8 text
+	TimestampMicros            // an Int64 holding microseconds since the unix epoch in UTC
+)
+
+// convertedTypes maps annotations to the converted types written in the schema.
+var convertedTypes = map[Annotation]string{
+	String:          "UTF8",
+	TimestampMicros: "TIMESTAMP_MICROS",
+}
+
+// A Column describes a column of a file. Every column is optional so may hold nulls.
+type Column struct {
+	Name       string
+	Type       Type
+	Annotation Annotation
+}
+
+// metadata returns the description of the column used by the writer to build the schema.
+func (c Column) metadata() string {
+	md := fmt.Sprintf("name=%s, type=%s, repetitiontype=OPTIONAL", c.Name, typeNames[c.Type])
+	if ct, ok := convertedTypes[c.Annotation]; ok {
+		md += ", convertedtype=" + ct
+	}
+	return md
+}
+
+// Write writes a Parquet file holding rows to w. Each row holds a value for each column that is nil for a null or
+// otherwise a bool, int64, float64, string or []byte according to the type of the column. createdBy is recorded as
+// the application that wrote the file.
+func Write(w io.Writer, columns []Column, rows [][]interface{}, createdBy string) error {
+	md := make([]string, len(columns))
+	for i, col := range columns {
+		if _, ok := typeNames[col.Type]; !ok {
+			return xerrors.Errorf("column %s: unsupported type %d", col.Name, col.Type)
+		}
+		md[i] = col.metadata()
+	}
+
+	// Check the values before handing them to the writer, which panics on values of the wrong type
+	values := make([][]interface{}, len(rows))
+	for r, row := range rows {
+		if len(row) != len(columns) {
+			return xerrors.Errorf("row %d has %d values, expected %d", r, len(row), len(columns))
+		}
+		values[r] = make([]interface{}, len(row))
+		for i, v := range row {
+			cv, err := columnValue(columns[i], v)
+			if err != nil {
+				return xerrors.Errorf("column %s: %w", columns[i].Name, err)
+			}
+			values[r][i] = cv
+		}
+	}
+
+	pw, err := writer.NewCSVWriter(md, writerfile.NewWriterFile(w), 1)
+	if err != nil {
+		return xerrors.Errorf("new writer: %w", err)
+	}
+	pw.CompressionType = format.CompressionCodec_SNAPPY
+	pw.Footer.CreatedBy = &createdBy
+
+	for _, row := range values {
+		if err := pw.Write(row); err != nil {
+			return xerrors.Errorf("write row: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return xerrors.Errorf("write footer: %w", err)
+	}
+	return nil
+}
+
+// columnValue returns v in the form expected by the writer for a value of col.
+func columnValue(col Column, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var ok bool
+	switch col.Type {
+	case Boolean:
+		_, ok = v.(bool)
+	case Int64:
+		_, ok = v.(int64)
+	case Double:
+		_, ok = v.(float64)
+	case ByteArray:
+		// The writer holds byte arrays as strings
+		switch b := v.(type) {
+		case []byte:
+			return string(b), nil
+		case string:
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, xerrors.Errorf("unexpected %T value for %s", v, typeNames[col.Type])
+	}
+	return v, nil
+}
@@ -0,0 +1,72 @@
+package parquet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	format "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestWrite(t *testing.T) {
+	columns := []Column{
+		{Name: "height", Type: Int64},
+		{Name: "cid", Type: ByteArray, Annotation: String},
+		{Name: "ok", Type: Boolean},
+		{Name: "ratio", Type: Double},
+		{Name: "processed", Type: Int64, Annotation: TimestampMicros},
+		{Name: "raw", Type: ByteArray},
+	}
+	rows := [][]interface{}{
+		{int64(1), "bafya", true, 0.5, int64(1614600000000001), []byte{1, 2}},
+		{int64(2), nil, false, nil, nil, nil},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, columns, rows, "test"))
+
+	pf, err := buffer.NewBufferFile(buf.Bytes())
+	require.NoError(t, err)
+	pr, err := reader.NewParquetReader(pf, nil, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	assert.EqualValues(t, 2, pr.GetNumRows())
+	require.NotNil(t, pr.Footer.CreatedBy)
+	assert.Equal(t, "test", *pr.Footer.CreatedBy)
+
+	// The first schema element is the root
+	schema := pr.Footer.Schema[1:]
+	require.Len(t, schema, len(columns))
+	for i, col := range columns {
+		assert.Equal(t, col.Name, schema[i].Name)
+		assert.Equal(t, format.FieldRepetitionType_OPTIONAL, schema[i].GetRepetitionType(), "column %s", col.Name)
+	}
+	assert.Equal(t, format.ConvertedType_UTF8, schema[1].GetConvertedType())
+	assert.Equal(t, format.ConvertedType_TIMESTAMP_MICROS, schema[4].GetConvertedType())
+
+	expected := [][]interface{}{
+		{int64(1), int64(2)},
+		{"bafya", nil},
+		{true, false},
+		{0.5, nil},
+		{int64(1614600000000001), nil},
+		{string([]byte{1, 2}), nil},
+	}
+	for i := range columns {
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), 2)
+		require.NoError(t, err)
+		assert.Equal(t, expected[i], values, "column %s", columns[i].Name)
+	}
+}
+
+func TestWriteInvalid(t *testing.T) {
+	columns := []Column{{Name: "height", Type: Int64}}
+
+	var buf bytes.Buffer
+	assert.Error(t, Write(&buf, columns, [][]interface{}{{int64(1), "extra"}}, "test"))
+	assert.Error(t, Write(&buf, columns, [][]interface{}{{"1"}}, "test"))
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/storage/parquet"
+)
+
+type NullableModel struct {
+	Height  int64  `pg:",pk,notnull,use_zero"`
+	Count   int64  `pg:",use_zero"`
+	Amount  string `pg:"type:numeric"`
+	Address *string
+}
+
+func (nm *NullableModel) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	return s.PersistModel(ctx, nm)
+}
+
+type UnpartitionedModel struct {
+	Name string `pg:",pk,notnull"`
+}
+
+func (um *UnpartitionedModel) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	return s.PersistModel(ctx, um)
+}
+
+func TestParquetBatch(t *testing.T) {
+	ctx := context.Background()
+	batch := &ParquetBatch{tables: map[string]*parquetTable{}, version: model.Version{Major: 1}}
+
+	processed := time.Date(2021, 3, 1, 12, 0, 0, 1500, time.UTC)
+	addr := "f01000"
+	require.NoError(t, batch.PersistModel(ctx, []*TestModel{
+		{Height: 43, Block: "blockb", Message: "msg2"},
+		{Height: 42, Block: "blocka", Message: "msg1"},
+	}))
+	require.NoError(t, batch.PersistModel(ctx, &TimeModel{Height: 42, Processed: processed}))
+	require.NoError(t, batch.PersistModel(ctx, &StringSliceModel{Height: 42, Addresses: []string{"a", "b"}}))
+	require.NoError(t, batch.PersistModel(ctx, &JSONModel{Height: 42, Value: `{"a":1}`}))
+	require.NoError(t, batch.PersistModel(ctx, &NullableModel{Height: 42}))
+	require.NoError(t, batch.PersistModel(ctx, &NullableModel{Height: 43, Amount: "12", Address: &addr}))
+
+	tm := batch.tables["test_models"]
+	require.NotNil(t, tm)
+	assert.Equal(t, 0, tm.height)
+	assert.Equal(t, []parquet.Column{
+		{Name: "height", Type: parquet.Int64},
+		{Name: "block", Type: parquet.ByteArray, Annotation: parquet.String},
+		{Name: "message", Type: parquet.ByteArray, Annotation: parquet.String},
+	}, tm.columns)
+	// rows are sorted by primary key
+	assert.Equal(t, [][]interface{}{
+		{int64(42), "blocka", "msg1"},
+		{int64(43), "blockb", "msg2"},
+	}, tm.rows)
+
+	tim := batch.tables["time_models"]
+	require.NotNil(t, tim)
+	assert.Equal(t, parquet.Column{Name: "processed", Type: parquet.Int64, Annotation: parquet.TimestampMicros}, tim.columns[1])
+	assert.Equal(t, [][]interface{}{{int64(42), processed.UnixNano() / 1000}}, tim.rows)
+
+	assert.Equal(t, [][]interface{}{{int64(42), `["a","b"]`}}, batch.tables["string_slice_models"].rows)
+	assert.Equal(t, [][]interface{}{{int64(42), `{"a":1}`}}, batch.tables["json_models"].rows)
+
+	// zero values of fields without use_zero are null, as they are in the database
+	assert.Equal(t, [][]interface{}{
+		{int64(42), int64(0), nil, nil},
+		{int64(43), int64(0), "12", "f01000"},
+	}, batch.tables["nullable_models"].rows)
+}
+
+func TestParquetBatchRaw(t *testing.T) {
+	batch := &ParquetBatch{tables: map[string]*parquetTable{}, version: model.Version{Major: 1}}
+
+	v1, v2 := "10", "x"
+	require.NoError(t, batch.PersistModel(context.Background(), &rawRows{
+		table:   "plugin_rows",
+		columns: []string{"height", "value"},
+		rows:    [][]*string{{&v1, &v2}, {&v1, nil}},
+	}))
+
+	pt := batch.tables["plugin_rows"]
+	require.NotNil(t, pt)
+	assert.Equal(t, 0, pt.height)
+	assert.Equal(t, [][]interface{}{{"10", "x"}, {"10", nil}}, pt.rows)
+
+	err := batch.PersistModel(context.Background(), &rawRows{
+		table:   "plugin_rows",
+		columns: []string{"height", "value"},
+		rows:    [][]*string{{&v1}},
+	})
+	assert.Error(t, err)
+}
+
+func TestParquetPersistPartitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	st, err := NewParquetStorage(dir, model.Version{Major: 1}, 100)
+	require.NoError(t, err)
+
+	err = st.PersistTaskBatch(context.Background(), "blocks",
+		&TestModel{Height: 42, Block: "blocka", Message: "msg1"},
+		&TestModel{Height: 99, Block: "blockb", Message: "msg2"},
+		&TestModel{Height: 100, Block: "blockc", Message: "msg3"},
+		&UnpartitionedModel{Name: "a"},
+	)
+	require.NoError(t, err)
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.Dir(rel))
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		assert.True(t, strings.HasSuffix(info.Name(), ".parquet"), "file %s", rel)
+		assert.Equal(t, "PAR1", string(data[:4]), "file %s", rel)
+		assert.Equal(t, "PAR1", string(data[len(data)-4:]), "file %s", rel)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join("test_models", "task=blocks", "height_range=0000000000-0000000099"),
+		filepath.Join("test_models", "task=blocks", "height_range=0000000100-0000000199"),
+		filepath.Join("unpartitioned_models", "task=blocks"),
+	}, files)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// A ProcessingErrorLister is storage that can summarise the failures recorded in processing reports.
+type ProcessingErrorLister interface {
+	ProcessingErrors(ctx context.Context, since time.Time, tasks []string) ([]ProcessingErrorGroup, error)
+}
+
+// A ProcessingErrorGroup summarises the failures of a task that share an error class.
+type ProcessingErrorGroup struct {
+	Task       string
+	ErrorClass string
+	FromHeight int64     // lowest height at which the task failed
+	ToHeight   int64     // highest height at which the task failed
+	Heights    int       // number of distinct heights at which the task failed
+	Reports    int       // number of failed reports, a height may have failed more than once
+	Unresolved int       // number of heights at which the task has not since completed successfully
+	FirstSeen  time.Time // start time of the earliest failure
+	LastSeen   time.Time // start time of the latest failure
+	Example    string    // errors detected by the latest failure
+}
+
+// ProcessingErrors returns the failures reported since the given time grouped by task and error class, most recently
+// seen first. Failures of all tasks are returned if tasks is empty. Archived reports are included, both as failures and
+// as later completions that resolve them.
+func (d *Database) ProcessingErrors(ctx context.Context, since time.Time, tasks []string) ([]ProcessingErrorGroup, error) {
+	taskFilter := ""
+	if len(tasks) > 0 {
+		taskFilter = "AND r.task IN (?4)"
+	}
+
+	var groups []ProcessingErrorGroup
+	_, err := d.db.QueryContext(ctx, &groups, `
+SELECT
+	r.task,
+	coalesce(r.error_class, ?3) AS error_class,
+	min(r.height) AS from_height,
+	max(r.height) AS to_height,
+	count(DISTINCT r.height) AS heights,
+	count(*) AS reports,
+	count(DISTINCT r.height) FILTER (WHERE NOT EXISTS (
+		SELECT 1 FROM `+d.reportsSource("height, state_root, task, status")+` s
+		WHERE s.height = r.height AND s.state_root = r.state_root AND s.task = r.task AND s.status IN (?1, ?2)
+	)) AS unresolved,
+	min(r.started_at) AS first_seen,
+	max(r.started_at) AS last_seen,
+	(array_agg(coalesce(r.errors_detected::text, r.status_information, '') ORDER BY r.started_at DESC))[1] AS example
+FROM `+d.reportsSource("height, state_root, task, status, error_class, errors_detected, status_information, started_at")+` r
+WHERE r.status = ?0 AND r.started_at >= ?5 `+taskFilter+`
+GROUP BY 1, 2
+ORDER BY last_seen DESC`,
+		visor.ProcessingStatusError, visor.ProcessingStatusOK, visor.ProcessingStatusInfo, visor.ErrorClassUnknown, pg.In(tasks), since)
+	if err != nil {
+		return nil, xerrors.Errorf("query processing errors: %w", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-pg/pg/v10/orm"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// Ways in which the values of a redacted column may be replaced.
+const (
+	RedactOmit = "omit" // the value is not persisted, leaving the column null
+	RedactHash = "hash" // the value is replaced by the hex encoded sha256 hash of the value
+)
+
+// A Redaction removes or hashes the values of a column whenever rows are persisted, for deployments that must not
+// store some of the data extracted from the chain or want to reduce its size.
+type Redaction struct {
+	Table  string
+	Column string
+	Mode   string // one of RedactOmit or RedactHash
+}
+
+// ParseRedaction parses a redaction written as table.column or table.column:mode. The mode defaults to RedactOmit.
+func ParseRedaction(s string) (Redaction, error) {
+	r := Redaction{Mode: RedactOmit}
+	target := s
+	if idx := strings.LastIndex(s, ":"); idx != -1 {
+		target, r.Mode = s[:idx], s[idx+1:]
+	}
+	if r.Mode != RedactOmit && r.Mode != RedactHash {
+		return Redaction{}, xerrors.Errorf("redaction %q: unknown mode %q, must be %s or %s", s, r.Mode, RedactOmit, RedactHash)
+	}
+
+	parts := strings.Split(target, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Redaction{}, xerrors.Errorf("redaction %q: expected table.column", s)
+	}
+	r.Table, r.Column = parts[0], parts[1]
+	return r, nil
+}
+
+// ParseRedactions parses a list of redactions and returns a Redactor that applies them, or nil if the list is empty.
+func ParseRedactions(ss []string) (*Redactor, error) {
+	var rs []Redaction
+	for _, s := range ss {
+		if s == "" {
+			continue
+		}
+		r, err := ParseRedaction(s)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	if len(rs) == 0 {
+		return nil, nil
+	}
+	return NewRedactor(rs), nil
+}
+
+// A Redactor applies redactions to the models persisted through a storage batch. A nil Redactor applies none.
+type Redactor struct {
+	rules map[string]map[string]string // mode of each redacted column by table
+}
+
+func NewRedactor(rs []Redaction) *Redactor {
+	r := &Redactor{rules: map[string]map[string]string{}}
+	for _, rd := range rs {
+		if r.rules[rd.Table] == nil {
+			r.rules[rd.Table] = map[string]string{}
+		}
+		r.rules[rd.Table][rd.Column] = rd.Mode
+	}
+	return r
+}
+
+// Batch returns a storage batch that redacts models before passing them to b.
+func (r *Redactor) Batch(b model.StorageBatch) model.StorageBatch {
+	if r == nil || len(r.rules) == 0 {
+		return b
+	}
+	return &redactingBatch{r: r, b: b}
+}
+
+type redactingBatch struct {
+	r *Redactor
+	b model.StorageBatch
+}
+
+// PersistModel persists a redacted copy of m. The model itself is not changed, so it may be persisted again if a
+// transaction is retried.
+func (rb *redactingBatch) PersistModel(ctx context.Context, m interface{}) error {
+	if rm, ok := m.(model.RawModel); ok {
+		return rb.b.PersistModel(ctx, rb.r.redactRaw(rm))
+	}
+
+	redacted, err := rb.r.redact(m)
+	if err != nil {
+		return err
+	}
+	return rb.b.PersistModel(ctx, redacted)
+}
+
+// redact returns a copy of m, which may be a struct or a list of structs or pointers to structs, with the redacted
+// columns replaced. m is returned unchanged if its table has no redacted columns.
+func (r *Redactor) redact(m interface{}) (interface{}, error) {
+	value := reflect.ValueOf(m)
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return m, nil
+	}
+	value = reflect.Indirect(value)
+
+	elemType := value.Type()
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return m, nil
+	}
+
+	tbl := orm.GetTable(elemType)
+	rules := r.rules[stripQuotes(tbl.SQLNameForSelects)]
+	if len(rules) == 0 {
+		return m, nil
+	}
+
+	var fields []*orm.Field
+	var modes []string
+	for column, mode := range rules {
+		fld, ok := tbl.FieldsMap[column]
+		if !ok {
+			continue
+		}
+		if fld.HasFlag(orm.PrimaryKeyFlag) {
+			return nil, xerrors.Errorf("cannot redact primary key column %s of %s", column, stripQuotes(tbl.SQLNameForSelects))
+		}
+		// Omitting a value persists its zero value, which is not null for columns that must not be null or that
+		// persist zero values.
+		if mode == RedactOmit && (fld.HasFlag(orm.NotNullFlag) || fld.HasFlag(orm.UseZeroFlag)) {
+			return nil, xerrors.Errorf("cannot omit column %s of %s since it must not be null, hash it instead", column, stripQuotes(tbl.SQLNameForSelects))
+		}
+		fields = append(fields, fld)
+		modes = append(modes, mode)
+	}
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	redactStruct := func(strct reflect.Value) error {
+		for i, fld := range fields {
+			if err := redactValue(fld.Value(strct), fld.SQLType, modes[i]); err != nil {
+				return xerrors.Errorf("redact %s.%s: %w", stripQuotes(tbl.SQLNameForSelects), fld.SQLName, err)
+			}
+		}
+		return nil
+	}
+
+	if value.Kind() == reflect.Struct {
+		cp := reflect.New(value.Type())
+		cp.Elem().Set(value)
+		if err := redactStruct(cp.Elem()); err != nil {
+			return nil, err
+		}
+		return cp.Interface(), nil
+	}
+
+	list := reflect.MakeSlice(reflect.SliceOf(value.Type().Elem()), value.Len(), value.Len())
+	for i := 0; i < value.Len(); i++ {
+		elem := value.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			cp := reflect.New(elem.Type().Elem())
+			cp.Elem().Set(elem.Elem())
+			if err := redactStruct(cp.Elem()); err != nil {
+				return nil, err
+			}
+			list.Index(i).Set(cp)
+			continue
+		}
+		list.Index(i).Set(elem)
+		if err := redactStruct(list.Index(i)); err != nil {
+			return nil, err
+		}
+	}
+	return list.Interface(), nil
+}
+
+// redactValue replaces the value of a field in place.
+func redactValue(v reflect.Value, sqlType string, mode string) error {
+	if mode == RedactOmit {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return nil
+		}
+		digest := hashValue([]byte(v.String()))
+		if sqlType == "jsonb" {
+			// The hash must remain a valid json value
+			digest = strconv.Quote(digest)
+		}
+		v.SetString(digest)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return xerrors.Errorf("cannot hash values of type %s", v.Type())
+		}
+		if v.Len() == 0 {
+			return nil
+		}
+		sum := sha256.Sum256(v.Bytes())
+		v.SetBytes(sum[:])
+	default:
+		return xerrors.Errorf("cannot hash values of type %s", v.Type())
+	}
+	return nil
+}
+
+func hashValue(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// redactRaw returns a raw model with the redacted columns of rm replaced.
+func (r *Redactor) redactRaw(rm model.RawModel) model.RawModel {
+	rules := r.rules[rm.RawTable()]
+	if len(rules) == 0 {
+		return rm
+	}
+
+	columns := rm.RawColumns()
+	modes := make([]string, len(columns))
+	redacted := false
+	for i, c := range columns {
+		modes[i] = rules[c]
+		redacted = redacted || modes[i] != ""
+	}
+	if !redacted {
+		return rm
+	}
+
+	rows := rm.RawRows()
+	out := make([][]*string, len(rows))
+	for i, row := range rows {
+		out[i] = make([]*string, len(row))
+		for j, val := range row {
+			if j >= len(modes) || modes[j] == "" || val == nil {
+				out[i][j] = val
+				continue
+			}
+			if modes[j] == RedactHash {
+				digest := hashValue([]byte(*val))
+				out[i][j] = &digest
+			}
+		}
+	}
+	return &rawRows{table: rm.RawTable(), columns: columns, rows: out}
+}
+
+// rawRows is a raw model holding redacted rows.
+type rawRows struct {
+	table   string
+	columns []string
+	rows    [][]*string
+}
+
+func (r *rawRows) RawTable() string     { return r.table }
+func (r *rawRows) RawColumns() []string { return r.columns }
+func (r *rawRows) RawRows() [][]*string { return r.rows }
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type redactModel struct {
+	//lint:ignore U1000 tableName is a convention used by go-pg
+	tableName struct{} `pg:"redact_test"`
+
+	Height int64 `pg:",pk,use_zero"`
+	Label  string
+	Method string `pg:",notnull"`
+	Nonce  int64  `pg:",use_zero"`
+	Params string `pg:",type:jsonb"`
+	Raw    []byte
+}
+
+type captureBatch struct {
+	models []interface{}
+}
+
+func (c *captureBatch) PersistModel(ctx context.Context, m interface{}) error {
+	c.models = append(c.models, m)
+	return nil
+}
+
+func TestParseRedaction(t *testing.T) {
+	r, err := ParseRedaction("parsed_messages.params:hash")
+	require.NoError(t, err)
+	assert.Equal(t, Redaction{Table: "parsed_messages", Column: "params", Mode: RedactHash}, r)
+
+	r, err = ParseRedaction("market_deal_proposals.label")
+	require.NoError(t, err)
+	assert.Equal(t, Redaction{Table: "market_deal_proposals", Column: "label", Mode: RedactOmit}, r)
+
+	for _, s := range []string{"params", "parsed_messages.params:scramble", ".params", "a.b.c"} {
+		_, err := ParseRedaction(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRedactorBatch(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := ParseRedactions([]string{"redact_test.label", "redact_test.params:hash", "redact_test.raw:hash"})
+	require.NoError(t, err)
+
+	cb := &captureBatch{}
+	original := []*redactModel{{Height: 1, Label: "secret", Params: `{"a":1}`, Raw: []byte{1, 2}}}
+	require.NoError(t, r.Batch(cb).PersistModel(ctx, original))
+
+	// The persisted models are copies
+	assert.Equal(t, "secret", original[0].Label)
+	assert.Equal(t, `{"a":1}`, original[0].Params)
+
+	require.Len(t, cb.models, 1)
+	redacted := cb.models[0].([]*redactModel)
+	require.Len(t, redacted, 1)
+	assert.EqualValues(t, 1, redacted[0].Height)
+	assert.Equal(t, "", redacted[0].Label)
+	assert.Equal(t, `"`+hashValue([]byte(`{"a":1}`))+`"`, redacted[0].Params)
+	assert.Len(t, redacted[0].Raw, 32)
+
+	// Primary keys cannot be redacted
+	r, err = ParseRedactions([]string{"redact_test.height"})
+	require.NoError(t, err)
+	assert.Error(t, r.Batch(cb).PersistModel(ctx, &redactModel{Height: 1}))
+
+	// Columns that must not be null can be hashed but not omitted
+	for _, s := range []string{"redact_test.method", "redact_test.nonce"} {
+		r, err = ParseRedactions([]string{s})
+		require.NoError(t, err)
+		assert.Error(t, r.Batch(cb).PersistModel(ctx, &redactModel{Height: 1}), s)
+	}
+	r, err = ParseRedactions([]string{"redact_test.method:hash"})
+	require.NoError(t, err)
+	assert.NoError(t, r.Batch(cb).PersistModel(ctx, &redactModel{Height: 1, Method: "Send"}))
+
+	// A nil redactor leaves the batch unchanged
+	var nr *Redactor
+	assert.Equal(t, cb, nr.Batch(cb))
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+)
+
+// ErrReadOnly is returned when writing to a database that was opened as a read replica.
+var ErrReadOnly = errors.New("database is read only")
+
+// NewReadReplica creates a Database for a read replica of a visor database. Every session is made read only so
+// writes are refused even if the replica is a writable server, and the Database rejects persistence before it
+// reaches the server.
+func NewReadReplica(ctx context.Context, url string, poolSize int, name string, schemaName string) (*Database, error) {
+	d, err := NewDatabase(ctx, url, poolSize, name, schemaName, false)
+	if err != nil {
+		return nil, err
+	}
+	d.readOnly = true
+
+	prevOnConnect := d.opt.OnConnect
+	d.opt.OnConnect = func(ctx context.Context, conn *pg.Conn) error {
+		if err := prevOnConnect(ctx, conn); err != nil {
+			return err
+		}
+		if _, err := conn.Exec("SET default_transaction_read_only = on"); err != nil {
+			return xerrors.Errorf("set read only: %w", err)
+		}
+		return nil
+	}
+	return d, nil
+}
+
+// ReadOnly reports whether the database was opened as a read replica.
+func (d *Database) ReadOnly() bool {
+	return d.readOnly
+}
+
+// SetReader routes the read paths of jobs using d, such as verification, export and query serving, to r. Writes
+// made by those jobs still go to d. r must hold the same schema version as d.
+func (d *Database) SetReader(r *Database) {
+	d.reader = r
+}
+
+// Reader returns the database that read only queries should be sent to: the read replica set with SetReader, or d
+// itself if there is none. Queries that must see data just written by the caller should use d directly since a
+// replica may lag behind.
+func (d *Database) Reader() *Database {
+	if d.reader != nil {
+		return d.reader
+	}
+	return d
+}
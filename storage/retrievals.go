@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model/retrieval"
+)
+
+// retrievalTargetsSQL chooses one active deal at random from each of a random sample of providers. Only deals whose
+// label looks like a payload CID are chosen.
+const retrievalTargetsSQL = `
+SELECT deal_id, provider_id, payload_cid, piece_cid FROM (
+	SELECT DISTINCT ON (provider_id) deal_id, provider_id, label AS payload_cid, piece_cid
+	FROM market_deal_proposals
+	WHERE is_canonical AND start_epoch <= ?0 AND end_epoch > ?0 AND slashed_epoch IS NULL AND label ~ '^(baf|Qm)'
+	ORDER BY provider_id, random()
+) d
+ORDER BY random()
+LIMIT ?1`
+
+// RetrievalTargets returns up to limit deals that are active at height, each with a different provider.
+func (d *Database) RetrievalTargets(ctx context.Context, height int64, limit int) ([]retrieval.RetrievalTarget, error) {
+	var targets []retrieval.RetrievalTarget
+	if _, err := d.db.QueryContext(ctx, &targets, retrievalTargetsSQL, height, limit); err != nil {
+		return nil, xerrors.Errorf("query retrieval targets: %w", err)
+	}
+	return targets, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// runsVersion is the first schema version containing the visor_runs table.
+var runsVersion = model.Version{Major: 1, Patch: 41}
+
+// RecordRun records a run of an indexer. A run that has already been recorded is left unchanged. Schemas older than
+// version 1.41 cannot record runs and nothing is persisted.
+func (d *Database) RecordRun(ctx context.Context, run *visor.Run) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if d.version.Before(runsVersion) {
+		log.Warnw("database schema does not record runs, processing reports will not be linked to a visor version", "schema_version", d.version.String())
+		return nil
+	}
+
+	if _, err := d.db.ModelContext(ctx, run).OnConflict("do nothing").Insert(); err != nil {
+		return xerrors.Errorf("record run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	minermodel "github.com/filecoin-project/sentinel-visor/model/actors/miner"
+)
+
+// sectorLifetimesVersion is the first schema version containing the miner_sector_lifetimes table.
+var sectorLifetimesVersion = model.Version{Major: 1, Patch: 23}
+
+// The statements below apply the canonical sector events at heights after ?0 up to and including ?1 to
+// miner_sector_lifetimes. Each is idempotent so a range of heights may be applied more than once. Sector infos are
+// written by the miner task in the same tipset as the event that added or extended the sector.
+const (
+	addSectorLifetimesSQL = `
+INSERT INTO miner_sector_lifetimes AS l (miner_id, sector_id, added_height, activation_epoch, expiration_epoch, planned_duration,
+	terminated_early, initial_pledge, expected_day_reward, expected_storage_pledge, qa_power, last_event_height, updated_at)
+SELECT DISTINCT ON (e.miner_id, e.sector_id) e.miner_id, e.sector_id, e.height, i.activation_epoch, i.expiration_epoch,
+	i.expiration_epoch - i.activation_epoch, false, i.initial_pledge, i.expected_day_reward, i.expected_storage_pledge, i.qa_power, e.height, now()
+FROM miner_sector_events e
+JOIN miner_sector_infos i ON i.height = e.height AND i.state_root = e.state_root AND i.miner_id = e.miner_id AND i.sector_id = e.sector_id
+WHERE e.height > ?0 AND e.height <= ?1 AND e.is_canonical AND e.event IN (?2, ?3)
+ORDER BY e.miner_id, e.sector_id, e.height DESC
+ON CONFLICT (miner_id, sector_id) DO UPDATE SET
+	added_height = EXCLUDED.added_height,
+	activation_epoch = EXCLUDED.activation_epoch,
+	expiration_epoch = EXCLUDED.expiration_epoch,
+	planned_duration = EXCLUDED.planned_duration,
+	initial_pledge = EXCLUDED.initial_pledge,
+	expected_day_reward = EXCLUDED.expected_day_reward,
+	expected_storage_pledge = EXCLUDED.expected_storage_pledge,
+	qa_power = EXCLUDED.qa_power,
+	last_event_height = greatest(l.last_event_height, EXCLUDED.last_event_height),
+	updated_at = EXCLUDED.updated_at`
+
+	extendSectorLifetimesSQL = `
+UPDATE miner_sector_lifetimes l SET
+	expiration_epoch = x.expiration_epoch,
+	planned_duration = x.expiration_epoch - l.activation_epoch,
+	extended_height = x.height,
+	last_event_height = greatest(l.last_event_height, x.height),
+	updated_at = now()
+FROM (
+	SELECT DISTINCT ON (e.miner_id, e.sector_id) e.miner_id, e.sector_id, e.height, i.expiration_epoch
+	FROM miner_sector_events e
+	JOIN miner_sector_infos i ON i.height = e.height AND i.state_root = e.state_root AND i.miner_id = e.miner_id AND i.sector_id = e.sector_id
+	WHERE e.height > ?0 AND e.height <= ?1 AND e.is_canonical AND e.event = ?2
+	ORDER BY e.miner_id, e.sector_id, e.height DESC
+) x
+WHERE l.miner_id = x.miner_id AND l.sector_id = x.sector_id AND (l.extended_height IS NULL OR l.extended_height <= x.height)`
+
+	expireSectorLifetimesSQL = `
+UPDATE miner_sector_lifetimes l SET
+	expired_height = x.height,
+	actual_duration = x.height - l.activation_epoch,
+	last_event_height = greatest(l.last_event_height, x.height),
+	updated_at = now()
+FROM (
+	SELECT miner_id, sector_id, min(height) AS height FROM miner_sector_events
+	WHERE height > ?0 AND height <= ?1 AND is_canonical AND event = ?2
+	GROUP BY miner_id, sector_id
+) x
+WHERE l.miner_id = x.miner_id AND l.sector_id = x.sector_id`
+
+	// The termination fee is estimated as the twenty day reward projected at activation plus half the expected day
+	// reward for each day of the sector's age, capped at 140 days. This is the fee charged from actors version 2
+	// onwards, ignoring the lower bound of twenty days of the reward expected at termination.
+	terminateSectorLifetimesSQL = `
+UPDATE miner_sector_lifetimes l SET
+	terminated_height = x.height,
+	actual_duration = x.height - l.activation_epoch,
+	terminated_early = x.height < l.expiration_epoch,
+	estimated_termination_penalty = l.expected_storage_pledge + l.expected_day_reward * least(greatest(x.height - l.activation_epoch, 0) / 2880.0, 140) / 2,
+	last_event_height = greatest(l.last_event_height, x.height),
+	updated_at = now()
+FROM (
+	SELECT miner_id, sector_id, min(height) AS height FROM miner_sector_events
+	WHERE height > ?0 AND height <= ?1 AND is_canonical AND event = ?2
+	GROUP BY miner_id, sector_id
+) x
+WHERE l.miner_id = x.miner_id AND l.sector_id = x.sector_id`
+)
+
+// ApplySectorLifetimes applies the sector events extracted at heights after from up to and including to to the
+// miner_sector_lifetimes table. Only sectors whose addition has been applied are tracked.
+func (d *Database) ApplySectorLifetimes(ctx context.Context, from, to int64) error {
+	if d.version.Before(sectorLifetimesVersion) {
+		return xerrors.Errorf("sector lifetimes require schema version %s or later", sectorLifetimesVersion)
+	}
+
+	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, addSectorLifetimesSQL, from, to, minermodel.SectorAdded, minermodel.CommitCapacityAdded); err != nil {
+			return xerrors.Errorf("add sectors: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, extendSectorLifetimesSQL, from, to, minermodel.SectorExtended); err != nil {
+			return xerrors.Errorf("extend sectors: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, expireSectorLifetimesSQL, from, to, minermodel.SectorExpired); err != nil {
+			return xerrors.Errorf("expire sectors: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, terminateSectorLifetimesSQL, from, to, minermodel.SectorTerminated); err != nil {
+			return xerrors.Errorf("terminate sectors: %w", err)
+		}
+		return nil
+	})
+}
+
+// sectorLifetimesBounds returns the latest sector event height applied to the lifetimes, or -1 if there are none, and
+// the latest height of any extracted sector event, or -1 if there are none.
+func (d *Database) sectorLifetimesBounds(ctx context.Context) (int64, int64, error) {
+	var last, latest int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last, &latest), `SELECT (SELECT coalesce(max(last_event_height), -1) FROM miner_sector_lifetimes), (SELECT coalesce(max(height), -1) FROM miner_sector_events)`); err != nil {
+		return 0, 0, xerrors.Errorf("query sector lifetimes progress: %w", err)
+	}
+	return last, latest, nil
+}
+
+// NewSectorLifetimeAggregator returns an Aggregator that keeps the miner_sector_lifetimes table up to date as sector
+// events are extracted. Sector events are only applied once they are lag epochs behind the latest extracted event. It
+// refreshes every interval and each refresh applies again the lookback epochs before the latest height applied so data
+// persisted out of order is picked up.
+func NewSectorLifetimeAggregator(db *Database, lag int64, lookback int64, interval time.Duration) *Aggregator {
+	return newHeightRangeAggregator("miner_sector_lifetimes", lag, lookback, interval, db.sectorLifetimesBounds, db.ApplySectorLifetimes)
+}
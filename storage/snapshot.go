@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// SnapshotManifestFile is the name of the file describing the contents of a snapshot directory.
+const SnapshotManifestFile = "manifest.json"
+
+// snapshotReportsTable holds the processing reports included in a snapshot so that a deployment seeded from it knows
+// which tasks have completed and does not walk the same tipsets again.
+var snapshotReportsTable = exportTable{
+	name: "visor_processing_reports",
+	pks:  []string{"height", "state_root", "reporter", "task", "started_at"},
+}
+
+// snapshotReportsHistoryTable holds the archived processing reports included in a snapshot, which record completions
+// just as the reports in snapshotReportsTable do.
+var snapshotReportsHistoryTable = exportTable{
+	name: "visor_processing_reports_history",
+	pks:  []string{"height", "state_root", "reporter", "task", "started_at"},
+}
+
+// A SnapshotManifest describes a snapshot of a range of heights taken from a database.
+type SnapshotManifest struct {
+	SchemaVersion string          `json:"schema_version"`
+	From          int64           `json:"from"`
+	To            int64           `json:"to"`
+	Tables        []SnapshotTable `json:"tables"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// A SnapshotTable describes the file holding the rows of one table in a snapshot.
+type SnapshotTable struct {
+	Name string `json:"name"`
+	File string `json:"file"` // name of a gzip compressed CSV file with a header row, relative to the snapshot directory
+	Rows int    `json:"rows"`
+}
+
+// snapshotTables returns the tables that can be included in a snapshot of the given schema version, ordered by name.
+func snapshotTables(version model.Version) []exportTable {
+	tables := append(exportTables(version), snapshotReportsTable)
+	if !version.Before(reportsHistoryVersion) {
+		tables = append(tables, snapshotReportsHistoryTable)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].name < tables[j].name })
+	return tables
+}
+
+// SnapshotTables returns the names of the tables in the database that can be included in a snapshot, in alphabetical
+// order.
+func (d *Database) SnapshotTables() []string {
+	var names []string
+	for _, et := range snapshotTables(d.version) {
+		names = append(names, et.name)
+	}
+	return names
+}
+
+// Snapshot writes the rows of the named tables with heights between from and to inclusive to dir, one file per
+// table, together with a manifest. All tables are read in a single repeatable read transaction so the snapshot is
+// consistent even while data is being written. Every table that can be snapshotted is included if tables is empty.
+func (d *Database) Snapshot(ctx context.Context, from, to int64, tables []string, dir string) (*SnapshotManifest, error) {
+	available := snapshotTables(d.version)
+	selected := available
+	if len(tables) > 0 {
+		byName := map[string]exportTable{}
+		for _, et := range available {
+			byName[et.name] = et
+		}
+		selected = nil
+		for _, name := range tables {
+			et, ok := byName[name]
+			if !ok {
+				return nil, xerrors.Errorf("table %q cannot be snapshotted", name)
+			}
+			selected = append(selected, et)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, xerrors.Errorf("create directory: %w", err)
+	}
+
+	manifest := &SnapshotManifest{
+		SchemaVersion: d.version.String(),
+		From:          from,
+		To:            to,
+		CreatedAt:     d.Clock.Now(),
+	}
+
+	err := d.Reader().db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY"); err != nil {
+			return xerrors.Errorf("set isolation level: %w", err)
+		}
+		for _, et := range selected {
+			st, err := snapshotTable(tx, et, from, to, dir)
+			if err != nil {
+				return xerrors.Errorf("snapshot %s: %w", et.name, err)
+			}
+			log.Infow("snapshotted table", "table", et.name, "rows", st.Rows)
+			manifest.Tables = append(manifest.Tables, *st)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, xerrors.Errorf("marshal manifest: %w", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, SnapshotManifestFile), data, 0o644); err != nil {
+		return nil, xerrors.Errorf("write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// snapshotTable writes the rows of a table in the range to a gzip compressed CSV file in dir.
+func snapshotTable(tx *pg.Tx, et exportTable, from, to int64, dir string) (*SnapshotTable, error) {
+	st := &SnapshotTable{
+		Name: et.name,
+		File: et.name + ".csv.gz",
+	}
+
+	out, err := os.Create(filepath.Join(dir, st.File))
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close() // nolint: errcheck
+
+	bw := bufio.NewWriter(out)
+	gz := gzip.NewWriter(bw)
+
+	st.Rows, err = copyRange(tx, et, from, to, gz)
+	if err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// ReadSnapshotManifest reads the manifest of the snapshot in dir.
+func ReadSnapshotManifest(dir string) (*SnapshotManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SnapshotManifestFile))
+	if err != nil {
+		return nil, xerrors.Errorf("read manifest: %w", err)
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, xerrors.Errorf("unmarshal manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Restore imports the snapshot in dir in a single transaction. The database schema must be at the version the
+// snapshot was taken from. Rows that already exist are kept, so a snapshot may be restored into a database that
+// already holds some of its data.
+func (d *Database) Restore(ctx context.Context, dir string) (*SnapshotManifest, error) {
+	if d.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	manifest, err := ReadSnapshotManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	version, err := model.ParseVersion(manifest.SchemaVersion)
+	if err != nil {
+		return nil, xerrors.Errorf("snapshot schema version: %w", err)
+	}
+	if version != d.version {
+		return nil, xerrors.Errorf("snapshot was taken from schema version %s but the database schema is version %s", version, d.version)
+	}
+
+	available := map[string]bool{}
+	for _, et := range snapshotTables(d.version) {
+		available[et.name] = true
+	}
+	for _, st := range manifest.Tables {
+		if !available[st.Name] {
+			return nil, xerrors.Errorf("table %q cannot be restored", st.Name)
+		}
+	}
+
+	err = d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		for _, st := range manifest.Tables {
+			rows, err := restoreTable(ctx, tx, st, dir)
+			if err != nil {
+				return xerrors.Errorf("restore %s: %w", st.Name, err)
+			}
+			log.Infow("restored table", "table", st.Name, "rows", st.Rows, "inserted", rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restoreTable copies the rows in the file of a snapshotted table into a temporary table and inserts those that are
+// not already present. It returns the number of rows inserted. Columns are matched by the names in the header row so
+// the order of columns in the table does not matter.
+func restoreTable(ctx context.Context, tx *pg.Tx, st SnapshotTable, dir string) (int, error) {
+	if filepath.Base(st.File) != st.File {
+		return 0, xerrors.Errorf("invalid file name %q", st.File)
+	}
+	in, err := os.Open(filepath.Join(dir, st.File))
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close() // nolint: errcheck
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close() // nolint: errcheck
+
+	br := bufio.NewReader(gz)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return 0, xerrors.Errorf("read header: %w", err)
+	}
+	names, err := csv.NewReader(strings.NewReader(header)).Read()
+	if err != nil {
+		return 0, xerrors.Errorf("parse header: %w", err)
+	}
+	columns := make([]pg.Ident, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, pg.Ident(name))
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMPORARY TABLE visor_restore (LIKE ? INCLUDING DEFAULTS) ON COMMIT DROP`, pg.Ident(st.Name)); err != nil {
+		return 0, xerrors.Errorf("create temporary table: %w", err)
+	}
+	if _, err := tx.CopyFrom(br, `COPY visor_restore (?) FROM STDIN WITH CSV`, pg.In(columns)); err != nil {
+		return 0, xerrors.Errorf("copy: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO ? (?) SELECT ? FROM visor_restore ON CONFLICT DO NOTHING`, pg.Ident(st.Name), pg.In(columns), pg.In(columns))
+	if err != nil {
+		return 0, xerrors.Errorf("insert: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE visor_restore`); err != nil {
+		return 0, xerrors.Errorf("drop temporary table: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
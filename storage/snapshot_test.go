@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+func TestSnapshotTables(t *testing.T) {
+	version := model.Version{Major: 1, Patch: 35}
+	tables := snapshotTables(version)
+	require.Len(t, tables, len(exportTables(version))+2)
+
+	names := map[string]bool{}
+	for i, et := range tables {
+		names[et.name] = true
+		if i > 0 {
+			assert.Less(t, tables[i-1].name, et.name)
+		}
+	}
+	assert.True(t, names["visor_processing_reports"])
+	assert.True(t, names["visor_processing_reports_history"])
+	assert.True(t, names["message_heights"])
+	assert.False(t, names["visor_lineage"])
+}
+
+func TestReadSnapshotManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	_, err = ReadSnapshotManifest(dir)
+	assert.Error(t, err)
+
+	data := `{"schema_version":"1.35","from":10,"to":20,"tables":[{"name":"messages","file":"messages.csv.gz","rows":3}],"created_at":"2021-06-01T00:00:00Z"}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, SnapshotManifestFile), []byte(data), 0o644))
+
+	manifest, err := ReadSnapshotManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, &SnapshotManifest{
+		SchemaVersion: "1.35",
+		From:          10,
+		To:            20,
+		Tables:        []SnapshotTable{{Name: "messages", File: "messages.csv.gz", Rows: 3}},
+		CreatedAt:     time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, manifest)
+}
@@ -3,9 +3,11 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/go-pg/pg/v10/orm"
@@ -28,7 +30,9 @@ import (
 	"github.com/filecoin-project/sentinel-visor/model/derived"
 	"github.com/filecoin-project/sentinel-visor/model/messages"
 	"github.com/filecoin-project/sentinel-visor/model/msapprovals"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
 	"github.com/filecoin-project/sentinel-visor/schemas"
+	"github.com/filecoin-project/sentinel-visor/version"
 )
 
 var models = []interface{}{
@@ -78,9 +82,10 @@ var (
 )
 
 var (
-	ErrSchemaTooOld = errors.New("database schema is too old and requires migration")
-	ErrSchemaTooNew = errors.New("database schema is too new for this version of visor")
-	ErrNameTooLong  = errors.New("name exceeds maximum length for postgres application names")
+	ErrSchemaTooOld       = errors.New("database schema is too old and requires migration")
+	ErrSchemaTooNew       = errors.New("database schema is too new for this version of visor")
+	ErrSchemaIncompatible = errors.New("database schema is not compatible with this version of visor")
+	ErrNameTooLong        = errors.New("name exceeds maximum length for postgres application names")
 )
 
 const MaxPostgresNameLength = 64
@@ -148,7 +153,14 @@ func NewDatabaseFromDB(ctx context.Context, db *pg.DB, schemaName string) (*Data
 	}, nil
 }
 
-var _ Connector = (*Database)(nil)
+var (
+	_ Connector               = (*Database)(nil)
+	_ model.ReorgStorage      = (*Database)(nil)
+	_ visor.CompletionStorage = (*Database)(nil)
+	_ Overwriter              = (*Database)(nil)
+	_ ProcessingErrorLister   = (*Database)(nil)
+	_ visor.RunStorage        = (*Database)(nil)
+)
 
 type Database struct {
 	db           *pg.DB
@@ -156,7 +168,31 @@ type Database struct {
 	schemaConfig schemas.Config
 	Clock        clock.Clock
 	Upsert       bool
+	Notify       bool          // send a notification for each table written to when a batch commits
 	version      model.Version // schema version identified in the database
+	readOnly     bool          // true if the database was opened as a read replica
+	reader       *Database     // optional read replica used for read only queries, see Reader
+
+	// Isolation is the isolation level of transactions that persist data, one of the Isolation constants. The
+	// server's default is used if it is empty.
+	Isolation string
+
+	// TxRetries is the number of times a transaction that persists data is retried after failing because of a
+	// concurrent transaction. TxRetryDelay is the delay before the first retry, which doubles for each further retry.
+	TxRetries    int
+	TxRetryDelay time.Duration
+
+	// Redactor removes or hashes the values of configured columns in the data persisted by tasks. Checksums in
+	// processing reports are computed before redaction.
+	Redactor *Redactor
+}
+
+// Overwriting returns a copy of the database that replaces rows that already exist instead of keeping them, sharing
+// the same connection. It is used by jobs that re-extract data after an extractor has been fixed.
+func (d *Database) Overwriting() model.Storage {
+	od := *d
+	od.Upsert = true
+	return &od
 }
 
 // Connect opens a connection to the database and checks that the schema is compatible with the version required
@@ -226,6 +262,12 @@ func (d *Database) SchemaConfig() schemas.Config {
 	return d.schemaConfig
 }
 
+// SetConflictKeys sets the columns used as the conflict target when upserting into each of the given tables, as
+// returned by ParseConflictKeys.
+func (d *Database) SetConflictKeys(keys map[string][]string) {
+	d.schemaConfig.ConflictKeys = keys
+}
+
 // VerifyCurrentSchema compares the schema present in the database with the models used by visor
 // and returns an error if they are incompatible
 func (d *Database) VerifyCurrentSchema(ctx context.Context) error {
@@ -243,6 +285,30 @@ func (d *Database) VerifyCurrentSchema(ctx context.Context) error {
 	return verifyCurrentSchema(ctx, db, schemas.Config{SchemaName: "public"})
 }
 
+// CheckSchemaCompatibility verifies that the schema of a connected database is at a version supported by this version
+// of visor and that its tables have the columns visor's models expect, so jobs can refuse to start instead of failing
+// part way through with insert errors. The error returned describes how to resolve the incompatibility.
+func (d *Database) CheckSchemaCompatibility(ctx context.Context) error {
+	if d.db == nil {
+		return xerrors.Errorf("database not connected")
+	}
+
+	version, err := validateDatabaseSchemaVersion(ctx, d.db, d.SchemaConfig())
+	if err != nil {
+		return err
+	}
+	if version != d.version {
+		return xerrors.Errorf("%w: schema %q changed from version %s to %s after visor connected, restart visor to use the new version",
+			ErrSchemaIncompatible, d.SchemaConfig().SchemaName, d.version, version)
+	}
+
+	if err := verifyCurrentSchema(ctx, d.db, d.SchemaConfig()); err != nil {
+		return xerrors.Errorf("%w: %v, the schema may have been altered outside of visor's migrations and must be restored to version %s",
+			ErrSchemaIncompatible, err, version)
+	}
+	return nil
+}
+
 func verifyCurrentSchema(ctx context.Context, db *pg.DB, cfg schemas.Config) error {
 	type versionable interface {
 		AsVersion(model.Version) (interface{}, bool)
@@ -257,7 +323,7 @@ func verifyCurrentSchema(ctx context.Context, db *pg.DB, cfg schemas.Config) err
 		return xerrors.Errorf("schema not installed in database")
 	}
 
-	valid := true
+	var problems []string
 	for _, model := range models {
 		if vm, ok := model.(versionable); ok {
 			m, ok := vm.AsVersion(version)
@@ -272,13 +338,17 @@ func verifyCurrentSchema(ctx context.Context, db *pg.DB, cfg schemas.Config) err
 		m := tm.Table()
 		err := verifyModel(ctx, db, cfg.SchemaName, m)
 		if err != nil {
-			valid = false
+			problems = append(problems, err.Error())
 			log.Errorf("verify schema: %v", err)
 		}
 
 	}
-	if !valid {
-		return xerrors.Errorf("database schema was not compatible with current models")
+	if err := verifyConflictKeys(ctx, db, cfg.SchemaName, cfg.ConflictKeys); err != nil {
+		problems = append(problems, err.Error())
+		log.Errorf("verify schema: %v", err)
+	}
+	if len(problems) > 0 {
+		return xerrors.Errorf("database schema was not compatible with current models: %s", strings.Join(problems, "; "))
 	}
 	return nil
 }
@@ -345,33 +415,397 @@ func stripQuotes(s types.Safe) string {
 
 // PersistBatch persists a batch of persistables in a single transaction
 func (d *Database) PersistBatch(ctx context.Context, ps ...model.Persistable) error {
-	return d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
-		txs := &TxStorage{
-			tx:     tx,
-			upsert: d.Upsert,
-		}
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	return d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		txs := d.newTxStorage(tx)
+		batch := d.Redactor.Batch(txs)
 
 		for _, p := range ps {
-			if err := p.Persist(ctx, txs, d.version); err != nil {
+			if err := p.Persist(ctx, batch, d.version); err != nil {
+				return err
+			}
+		}
+
+		return txs.sendNotifications(ctx)
+	})
+}
+
+// PersistCompletion persists the data produced by a task and the processing report that records its completion in a
+// single transaction. If a report already records the successful completion of the same task for the same tipset then
+// nothing is persisted and false is returned, unless the database upserts, in which case the data of a completed task
+// replaces the rows already persisted so that re-running a job corrects them. Together with conflicting rows being
+// ignored or upserted this ensures that a tipset re-indexed after a crash or restart never produces duplicated or
+// partial data.
+func (d *Database) PersistCompletion(ctx context.Context, report *visor.ProcessingReport, data model.Persistable) (bool, error) {
+	if d.readOnly {
+		return false, ErrReadOnly
+	}
+	persisted := false
+	err := d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		// A commit that failed in an earlier attempt persisted nothing
+		persisted = false
+
+		// Serialize completions of the same task and tipset across all visor instances sharing the database so the
+		// check below cannot race with a concurrent transaction
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext(?))`, completionKey(report)); err != nil {
+			return xerrors.Errorf("acquire completion lock: %w", err)
+		}
+
+		// Data persisted with upserts replaces the rows of an earlier completion so the check is skipped
+		if !d.Upsert {
+			completed, err := d.isCompleted(ctx, tx, report)
+			if err != nil {
 				return err
 			}
+			if completed {
+				return nil
+			}
+		}
+
+		txs := d.newTxStorage(tx)
+
+		if data != nil {
+			if err := data.Persist(ctx, d.Redactor.Batch(txs), d.version); err != nil {
+				return err
+			}
+		}
+		if err := d.restoreCanonical(ctx, tx, report, txs.written); err != nil {
+			return err
+		}
+		if err := d.newLineage(ctx, report, txs.written).Persist(ctx, txs, d.version); err != nil {
+			return err
+		}
+		if err := report.Persist(ctx, txs, d.version); err != nil {
+			return err
+		}
+		if err := d.recordIndexedHead(ctx, tx, report); err != nil {
+			return err
+		}
+		if err := txs.sendNotifications(ctx); err != nil {
+			return err
+		}
+
+		persisted = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return persisted, nil
+}
+
+// isCompleted reports whether a report already records the successful completion of the task of report for the same
+// tipset.
+func (d *Database) isCompleted(ctx context.Context, tx *pg.Tx, report *visor.ProcessingReport) (bool, error) {
+	cond := `height = ?0 AND state_root = ?1 AND task = ?2 AND status IN (?3, ?4)`
+	if !d.version.Before(reportTipSetVersion) && report.TipSet != "" {
+		// Sibling tipsets usually share a state root so a completion must be for the same tipset. Reports written
+		// before tipsets were recorded can't be distinguished and still count.
+		cond += ` AND (tipset = ?5 OR tipset IS NULL)`
+	}
+	query := `SELECT EXISTS (SELECT 1 FROM visor_processing_reports WHERE ` + cond + `)`
+	if !d.version.Before(reportsHistoryVersion) {
+		// Completions may have been archived
+		query += ` OR EXISTS (SELECT 1 FROM visor_processing_reports_history WHERE ` + cond + `)`
+	}
+
+	var completed bool
+	if _, err := tx.QueryOneContext(ctx, pg.Scan(&completed), query,
+		report.Height, report.StateRoot, report.Task, visor.ProcessingStatusOK, visor.ProcessingStatusInfo, report.TipSet); err != nil {
+		return false, xerrors.Errorf("query completion: %w", err)
+	}
+	return completed, nil
+}
+
+// newLineage returns the lineage of the data persisted for a report, given the tables written so far.
+func (d *Database) newLineage(ctx context.Context, report *visor.ProcessingReport, written notifications) *visor.Lineage {
+	job := visor.JobFromContext(ctx)
+	tables := make(map[string]int, len(written))
+	for name, pn := range written {
+		tables[name] = pn.Rows
+	}
+	return &visor.Lineage{
+		Height:       report.Height,
+		StateRoot:    report.StateRoot,
+		Reporter:     report.Reporter,
+		Task:         report.Task,
+		StartedAt:    report.StartedAt,
+		JobID:        job.ID,
+		JobName:      job.Name,
+		VisorVersion: version.String(),
+		ExtractedAt:  d.Clock.Now(),
+		Tables:       tables,
+	}
+}
+
+// reportTipSetVersion is the first schema version in which processing reports carry the key of their tipset.
+var reportTipSetVersion = model.Version{Major: 1, Patch: 49}
+
+// completionKey returns the key used to serialize completions of a task for a tipset.
+func completionKey(report *visor.ProcessingReport) string {
+	return fmt.Sprintf("%d/%s/%s/%s", report.Height, report.StateRoot, report.TipSet, report.Task)
+}
+
+// canonicalTables maps the tables that carry an is_canonical flag to the column holding the state root of the
+// tipset that each row was extracted from. Sibling tipsets at the same height usually share a parent state root so,
+// with the exception of the tables in canonicalBlockTables and canonicalReferencedTables, rows are shared by all
+// tipsets with that state root. Tables without a state root column map to an empty string.
+var canonicalTables = map[string]string{
+	"actor_deletions":              "state_root",
+	"actor_states":                 "",
+	"actors":                       "state_root",
+	"block_headers":                "parent_state_root",
+	"block_messages":               "",
+	"block_parents":                "",
+	"chain_economics":              "parent_state_root",
+	"chain_powers":                 "state_root",
+	"chain_rewards":                "state_root",
+	"chain_system_balances":        "state_root",
+	"chain_throughput":             "state_root",
+	"derived_gas_outputs":          "state_root",
+	"drand_block_entries":          "",
+	"id_addresses":                 "state_root",
+	"internal_messages":            "state_root",
+	"market_deal_pieces":           "state_root",
+	"market_deal_proposals":        "state_root",
+	"market_deal_snapshots":        "state_root",
+	"market_deal_states":           "state_root",
+	"message_gas_by_method":        "state_root",
+	"message_gas_economy":          "state_root",
+	"message_gas_traces":           "state_root",
+	"message_heights":              "state_root",
+	"messages":                     "",
+	"miner_current_deadline_infos": "state_root",
+	"miner_deadline_schedules":     "state_root",
+	"miner_fee_debts":              "state_root",
+	"miner_infos":                  "state_root",
+	"miner_locked_funds":           "state_root",
+	"miner_pre_commit_infos":       "state_root",
+	"miner_pre_commit_snapshots":   "state_root",
+	"miner_sector_events":          "state_root",
+	"miner_sector_infos":           "state_root",
+	"miner_sector_snapshots":       "state_root",
+	"multisig_approvals":           "state_root",
+	"multisig_transactions":        "state_root",
+	"parsed_messages":              "",
+	"power_actor_claims":           "state_root",
+	"receipts":                     "state_root",
+	"verifreg_governance":          "state_root",
+}
+
+// canonicalTablesSince holds the first schema version containing tables that were added after canonical flags were
+// introduced in version 1.1.
+var canonicalTablesSince = map[string]model.Version{
+	"actor_deletions":            {Major: 1, Patch: 3},
+	"message_gas_by_method":      {Major: 1, Patch: 21},
+	"chain_throughput":           {Major: 1, Patch: 27},
+	"chain_system_balances":      {Major: 1, Patch: 32},
+	"miner_deadline_schedules":   {Major: 1, Patch: 33},
+	"message_heights":            {Major: 1, Patch: 35},
+	"miner_pre_commit_snapshots": {Major: 1, Patch: 37},
+	"miner_sector_snapshots":     {Major: 1, Patch: 37},
+	"market_deal_snapshots":      {Major: 1, Patch: 38},
+	"message_gas_traces":         {Major: 1, Patch: 39},
+	"market_deal_pieces":         {Major: 1, Patch: 46},
+	"verifreg_governance":        {Major: 1, Patch: 47},
+}
+
+// canonicalBlockTables maps the canonical tables whose rows belong to a single block, rather than to a state root, to
+// the column holding the block CID.
+var canonicalBlockTables = map[string]string{
+	"block_headers":       "cid",
+	"block_messages":      "block",
+	"block_parents":       "block",
+	"drand_block_entries": "block",
+}
+
+// canonicalTablesWithoutHeight holds the canonical block tables that have no height column. Their rows are found by
+// block CID alone.
+var canonicalTablesWithoutHeight = map[string]bool{
+	"drand_block_entries": true,
+}
+
+// canonicalReferencedTables maps the canonical tables whose rows are shared by every block or state root that refers
+// to them, such as a message included in several blocks, to a condition on the row t that holds while a canonical row
+// of another table still refers to it. They are flagged after the tables they refer to.
+var canonicalReferencedTables = map[string]string{
+	"actor_states":    `EXISTS (SELECT 1 FROM actors a WHERE a.height = t.height AND a.head = t.head AND a.code = t.code AND a.is_canonical)`,
+	"messages":        `EXISTS (SELECT 1 FROM block_messages bm WHERE bm.height = t.height AND bm.message = t.cid AND bm.is_canonical)`,
+	"parsed_messages": `EXISTS (SELECT 1 FROM block_messages bm WHERE bm.height = t.height AND bm.message = t.cid AND bm.is_canonical)`,
+}
+
+// canonicalTableNames returns the sorted names of the tables carrying an is_canonical flag in the database's schema
+// version.
+func (d *Database) canonicalTableNames() []string {
+	names := make([]string, 0, len(canonicalTables))
+	for name := range canonicalTables {
+		if since, ok := canonicalTablesSince[name]; ok && d.version.Before(since) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarkNonCanonical flags all rows extracted from the tipset made up of blocks at height with the given parent state
+// root as no longer being part of the canonical chain. Rows keyed by the state root are left unchanged while another
+// canonical tipset at the same height shares the state root. Only schema versions 1.1 and later carry the flag.
+func (d *Database) MarkNonCanonical(ctx context.Context, height int64, stateRoot string, blocks []string) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if d.version.Before(model.Version{Major: 1, Patch: 1}) {
+		log.Debugw("schema version does not support canonical flags, skipping", "version", d.version)
+		return nil
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	names := d.canonicalTableNames()
+
+	return d.runPersistTx(ctx, func(tx *pg.Tx) error {
+		for _, name := range names {
+			col, ok := canonicalBlockTables[name]
+			if !ok {
+				continue
+			}
+			if canonicalTablesWithoutHeight[name] {
+				if _, err := tx.ExecContext(ctx, `UPDATE ? SET is_canonical = false WHERE ? IN (?) AND is_canonical`,
+					pg.Ident(name), pg.Ident(col), pg.In(blocks)); err != nil {
+					return xerrors.Errorf("mark %s non-canonical: %w", name, err)
+				}
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE ? SET is_canonical = false WHERE height = ? AND ? IN (?) AND is_canonical`,
+				pg.Ident(name), height, pg.Ident(col), pg.In(blocks)); err != nil {
+				return xerrors.Errorf("mark %s non-canonical: %w", name, err)
+			}
+		}
+
+		// A sibling tipset that replaced the reverted one will have produced the same rows from the shared state root
+		var shared bool
+		if _, err := tx.QueryOneContext(ctx, pg.Scan(&shared),
+			`SELECT EXISTS (SELECT 1 FROM block_headers WHERE height = ? AND parent_state_root = ? AND is_canonical)`,
+			height, stateRoot); err != nil {
+			return xerrors.Errorf("query canonical siblings: %w", err)
+		}
+		if !shared {
+			for _, name := range names {
+				if _, ok := canonicalBlockTables[name]; ok || canonicalTables[name] == "" {
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, `UPDATE ? SET is_canonical = false WHERE height = ? AND ? = ? AND is_canonical`,
+					pg.Ident(name), height, pg.Ident(canonicalTables[name]), stateRoot); err != nil {
+					return xerrors.Errorf("mark %s non-canonical: %w", name, err)
+				}
+			}
 		}
 
+		// Rows shared with canonical blocks or state roots are left unchanged
+		for _, name := range names {
+			cond, ok := canonicalReferencedTables[name]
+			if !ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE ? AS t SET is_canonical = false WHERE t.height = ? AND t.is_canonical AND NOT `+cond,
+				pg.Ident(name), height); err != nil {
+				return xerrors.Errorf("mark %s non-canonical: %w", name, err)
+			}
+		}
 		return nil
 	})
 }
 
+// restoreCanonical flags rows written by a completed task for a tipset as canonical. Rows keyed by the state root
+// may have been flagged as non-canonical when a sibling tipset with the same state root was reverted, and inserting
+// them again leaves the existing flag unchanged.
+func (d *Database) restoreCanonical(ctx context.Context, tx *pg.Tx, report *visor.ProcessingReport, written notifications) error {
+	if d.version.Before(model.Version{Major: 1, Patch: 1}) {
+		return nil
+	}
+	names := d.canonicalTableNames()
+	for _, name := range names {
+		if _, ok := canonicalBlockTables[name]; ok || canonicalTables[name] == "" {
+			continue
+		}
+		if _, ok := written[name]; !ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE ? SET is_canonical = true WHERE height = ? AND ? = ? AND NOT is_canonical`,
+			pg.Ident(name), report.Height, pg.Ident(canonicalTables[name]), report.StateRoot); err != nil {
+			return xerrors.Errorf("mark %s canonical: %w", name, err)
+		}
+	}
+
+	// Rows shared with other blocks or state roots are restored once a canonical row refers to them again
+	for _, name := range names {
+		cond, ok := canonicalReferencedTables[name]
+		if !ok {
+			continue
+		}
+		if _, ok := written[name]; !ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE ? AS t SET is_canonical = true WHERE t.height = ? AND NOT t.is_canonical AND `+cond,
+			pg.Ident(name), report.Height); err != nil {
+			return xerrors.Errorf("mark %s canonical: %w", name, err)
+		}
+	}
+	return nil
+}
+
 func (d *Database) ExecContext(c context.Context, query interface{}, params ...interface{}) (pg.Result, error) {
+	if d.readOnly {
+		return nil, ErrReadOnly
+	}
 	return d.db.ExecContext(c, query, params...)
 }
 
+func (d *Database) QueryContext(c context.Context, model interface{}, query interface{}, params ...interface{}) (pg.Result, error) {
+	return d.db.QueryContext(c, model, query, params...)
+}
+
+// TableExists reports whether the named table exists in the schema used by the database.
+func (d *Database) TableExists(ctx context.Context, name string) (bool, error) {
+	return tableExists(ctx, d.db, d.SchemaConfig().SchemaName, name)
+}
+
+func (d *Database) newTxStorage(tx *pg.Tx) *TxStorage {
+	return &TxStorage{
+		tx:           tx,
+		upsert:       d.Upsert,
+		notify:       d.Notify,
+		written:      notifications{},
+		conflictKeys: d.schemaConfig.ConflictKeys,
+	}
+}
+
 type TxStorage struct {
-	tx     *pg.Tx
-	upsert bool
+	tx           *pg.Tx
+	upsert       bool
+	notify       bool                // send notifications for the tables written when the transaction commits
+	written      notifications       // tables written to by the transaction
+	conflictKeys map[string][]string // conflict targets of tables that are not upserted by primary key
+}
+
+// sendNotifications queues notifications for the tables written to by the transaction, if enabled.
+func (s *TxStorage) sendNotifications(ctx context.Context) error {
+	if !s.notify {
+		return nil
+	}
+	return s.written.send(ctx, s.tx)
 }
 
 // PersistModel persists a single model
 func (s *TxStorage) PersistModel(ctx context.Context, m interface{}) error {
+	if rm, ok := m.(model.RawModel); ok {
+		return s.persistRawModel(ctx, rm)
+	}
+
 	value := reflect.ValueOf(m)
 
 	elemKind := value.Kind()
@@ -392,9 +826,12 @@ func (s *TxStorage) PersistModel(ctx context.Context, m interface{}) error {
 			m = p.Interface()
 		}
 
+		// Insert rows in a consistent order so repeated runs produce identical tables
+		model.SortModels(m)
 	}
 	if s.upsert {
-		conflict, upsert := GenerateUpsertStrings(m)
+		table := stripQuotes(pg.Model(m).TableModel().Table().SQLNameForSelects)
+		conflict, upsert := GenerateUpsertStringsWithKeys(m, s.conflictKeys[table])
 		if _, err := s.tx.ModelContext(ctx, m).
 			OnConflict(conflict).
 			Set(upsert).
@@ -408,9 +845,128 @@ func (s *TxStorage) PersistModel(ctx context.Context, m interface{}) error {
 			return xerrors.Errorf("persisting model: %w", err)
 		}
 	}
+	s.written.record(m)
+	return nil
+}
+
+// persistRawModel inserts the rows of a raw model. Rows that conflict with existing rows are ignored unless upserts
+// are enabled, in which case they replace the existing rows with the same primary key.
+func (s *TxStorage) persistRawModel(ctx context.Context, rm model.RawModel) error {
+	rows := rm.RawRows()
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := rm.RawColumns()
+
+	var q strings.Builder
+	params := make([]interface{}, 0, 1+len(columns)+len(rows)*len(columns))
+	params = append(params, pg.Ident(rm.RawTable()))
+	q.WriteString("INSERT INTO ?0 (")
+	for i, c := range columns {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		params = append(params, pg.Ident(c))
+		fmt.Fprintf(&q, "?%d", len(params)-1)
+	}
+	q.WriteString(") VALUES ")
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return xerrors.Errorf("row %d of %s has %d values, expected %d", i, rm.RawTable(), len(row), len(columns))
+		}
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				q.WriteString(", ")
+			}
+			if v == nil {
+				q.WriteString("NULL")
+				continue
+			}
+			params = append(params, *v)
+			fmt.Fprintf(&q, "?%d", len(params)-1)
+		}
+		q.WriteString(")")
+	}
+	if s.upsert {
+		keys := s.conflictKeys[rm.RawTable()]
+		if len(keys) == 0 {
+			var err error
+			keys, err = s.primaryKey(ctx, rm.RawTable())
+			if err != nil {
+				return xerrors.Errorf("primary key of %s: %w", rm.RawTable(), err)
+			}
+		}
+		params = writeRawConflict(&q, params, keys, columns)
+	} else {
+		q.WriteString(" ON CONFLICT DO NOTHING")
+	}
+
+	if _, err := s.tx.ExecContext(ctx, q.String(), params...); err != nil {
+		return xerrors.Errorf("persisting raw model: %w", err)
+	}
+	s.written.recordRaw(rm)
 	return nil
 }
 
+// primaryKey returns the names of the columns of the primary key of the named table, which is found using the search
+// path. No columns are returned if the table has no primary key.
+func (s *TxStorage) primaryKey(ctx context.Context, table string) ([]string, error) {
+	var keys []string
+	if _, err := s.tx.QueryOneContext(ctx, pg.Scan(pg.Array(&keys)), `
+SELECT array_agg(a.attname::text ORDER BY a.attname)
+FROM pg_index i
+JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+WHERE i.indrelid = to_regclass(?) AND i.indisprimary`, table); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// writeRawConflict writes the conflict clause of an insert of raw rows that replaces the non key columns of existing
+// rows with the same keys, returning params with the identifiers it refers to appended. Conflicting rows are ignored
+// if there are no keys or every column is a key.
+func writeRawConflict(q *strings.Builder, params []interface{}, keys []string, columns []string) []interface{} {
+	if len(keys) == 0 {
+		q.WriteString(" ON CONFLICT DO NOTHING")
+		return params
+	}
+
+	isKey := make(map[string]bool, len(keys))
+	q.WriteString(" ON CONFLICT (")
+	for i, k := range keys {
+		isKey[k] = true
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		params = append(params, pg.Ident(k))
+		fmt.Fprintf(q, "?%d", len(params)-1)
+	}
+	q.WriteString(")")
+
+	updated := 0
+	for _, c := range columns {
+		if isKey[c] {
+			continue
+		}
+		if updated == 0 {
+			q.WriteString(" DO UPDATE SET ")
+		} else {
+			q.WriteString(", ")
+		}
+		updated++
+		params = append(params, pg.Ident(c))
+		fmt.Fprintf(q, "?%d = EXCLUDED.?%d", len(params)-1, len(params)-1)
+	}
+	if updated == 0 {
+		q.WriteString(" DO NOTHING")
+	}
+	return params
+}
+
 // GenerateUpsertString accepts a visor model and returns two string containing SQL that may be used
 // to upsert the model. The first string is the conflict statement and the second is the insert.
 //
@@ -430,16 +986,37 @@ func (s *TxStorage) PersistModel(ctx context.Context, m interface{}) error {
 // update string:
 // 	"owner_id" = EXCLUDED.owner_id, "worker_id" = EXCLUDED.worker_id
 func GenerateUpsertStrings(model interface{}) (string, string) {
+	return GenerateUpsertStringsWithKeys(model, nil)
+}
+
+// GenerateUpsertStringsWithKeys is like GenerateUpsertStrings but uses keys as the conflict target in place of the
+// primary key of the model when keys is not empty. Every other column is updated, including columns of the primary key
+// that are not in keys.
+func GenerateUpsertStringsWithKeys(model interface{}, keys []string) (string, string) {
 	var cf []string
 	var ucf []string
 
-	// gather all public keys
-	for _, pk := range pg.Model(model).TableModel().Table().PKs {
-		cf = append(cf, pk.SQLName)
-	}
-	// gather all other fields
-	for _, field := range pg.Model(model).TableModel().Table().DataFields {
-		ucf = append(ucf, field.SQLName)
+	table := pg.Model(model).TableModel().Table()
+	if len(keys) == 0 {
+		// gather all public keys
+		for _, pk := range table.PKs {
+			cf = append(cf, pk.SQLName)
+		}
+		// gather all other fields
+		for _, field := range table.DataFields {
+			ucf = append(ucf, field.SQLName)
+		}
+	} else {
+		isKey := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			isKey[k] = true
+			cf = append(cf, k)
+		}
+		for _, field := range table.Fields {
+			if !isKey[field.SQLName] {
+				ucf = append(ucf, field.SQLName)
+			}
+		}
 	}
 
 	// consistent ordering in sql statements.
@@ -14,6 +14,7 @@ import (
 
 	"github.com/filecoin-project/sentinel-visor/model"
 	"github.com/filecoin-project/sentinel-visor/model/actors/miner"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
 	"github.com/filecoin-project/sentinel-visor/schemas"
 	_ "github.com/filecoin-project/sentinel-visor/schemas/v0"
 	"github.com/filecoin-project/sentinel-visor/testutil"
@@ -124,6 +125,90 @@ func TestModelUpsert(t *testing.T) {
 	assert.Equal(t, "UPSERT", owner)
 }
 
+func TestPersistCompletion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("short testing requested")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	db, cleanup, err := testutil.WaitForExclusiveDatabase(ctx, t)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, cleanup()) }()
+
+	_, err = db.Exec(`TRUNCATE TABLE miner_infos, visor_processing_reports`)
+	require.NoError(t, err, "truncating tables")
+
+	clk := testutil.NewMockClock()
+	d := &Database{
+		db:    db,
+		Clock: clk,
+	}
+
+	minerInfo := &miner.MinerInfo{
+		Height:    1,
+		MinerID:   "minerID",
+		StateRoot: "stateroot",
+		OwnerID:   "owner",
+		WorkerID:  "worker",
+	}
+
+	newReport := func(status string) *visor.ProcessingReport {
+		return &visor.ProcessingReport{
+			Height:      1,
+			StateRoot:   "stateroot",
+			Reporter:    "reporter",
+			Task:        "task",
+			StartedAt:   clk.Now(),
+			CompletedAt: clk.Now(),
+			Status:      status,
+		}
+	}
+
+	countRows := func(table string) int {
+		var count int
+		_, err := db.QueryOne(pg.Scan(&count), `SELECT COUNT(*) FROM ?`, pg.Ident(table))
+		require.NoError(t, err)
+		return count
+	}
+
+	// An error report does not mark the task as complete
+	persisted, err := d.PersistCompletion(ctx, newReport(visor.ProcessingStatusError), nil)
+	require.NoError(t, err)
+	assert.True(t, persisted)
+
+	clk.Add(time.Minute)
+	persisted, err = d.PersistCompletion(ctx, newReport(visor.ProcessingStatusOK), minerInfo)
+	require.NoError(t, err)
+	assert.True(t, persisted)
+
+	// The task is complete so neither the report nor the data should be persisted again
+	clk.Add(time.Minute)
+	persisted, err = d.PersistCompletion(ctx, newReport(visor.ProcessingStatusOK), minerInfo)
+	require.NoError(t, err)
+	assert.False(t, persisted)
+
+	assert.Equal(t, 1, countRows("miner_infos"))
+	assert.Equal(t, 2, countRows("visor_processing_reports"))
+
+	// A database that upserts persists the data of a completed task again, replacing the existing rows
+	d.Upsert = true
+	minerInfo.OwnerID = "UPSERT"
+	clk.Add(time.Minute)
+	persisted, err = d.PersistCompletion(ctx, newReport(visor.ProcessingStatusOK), minerInfo)
+	require.NoError(t, err)
+	assert.True(t, persisted)
+
+	assert.Equal(t, 1, countRows("miner_infos"))
+	assert.Equal(t, 3, countRows("visor_processing_reports"))
+
+	var owner string
+	_, err = db.QueryOne(pg.Scan(&owner), `SELECT owner_id FROM miner_infos`)
+	require.NoError(t, err)
+	assert.Equal(t, "UPSERT", owner)
+}
+
 func TestLongNames(t *testing.T) {
 	justLongEnough := strings.Repeat("x", MaxPostgresNameLength)
 	_, err := NewDatabase(context.Background(), "postgres://example.com/fakedb", 1, justLongEnough, "public", false)
@@ -181,6 +266,20 @@ func TestUpsertSQLGeneration(t *testing.T) {
 	assert.Equal(t, testModel.ExpectedUpsertStatement(), upsert)
 }
 
+func TestUpsertSQLGenerationWithKeys(t *testing.T) {
+	testModel := &TestingUpsertStruct{}
+
+	// Primary key columns that are not part of the conflict target are updated
+	conflict, upsert := GenerateUpsertStringsWithKeys(testModel, []string{"cid"})
+	assert.Equal(t, "(cid) DO UPDATE", conflict)
+	assert.Equal(t, `"camel_case" = EXCLUDED.camel_case, "heads" = EXCLUDED.heads, "height" = EXCLUDED.height, "knees" = EXCLUDED.knees, "shoulders" = EXCLUDED.shoulders, "state_root" = EXCLUDED.state_root, "toes" = EXCLUDED.toes`, upsert)
+
+	// Without keys the primary key is used
+	conflict, upsert = GenerateUpsertStringsWithKeys(testModel, nil)
+	assert.Equal(t, testModel.ExpectedConflictStatement(), conflict)
+	assert.Equal(t, testModel.ExpectedUpsertStatement(), upsert)
+}
+
 func TestDatabasePersistWithVersion(t *testing.T) {
 	if testing.Short() {
 		t.Skip("short testing requested")
@@ -350,3 +449,56 @@ func TestDatabasePersistWithUnsupportedVersion(t *testing.T) {
 	err = d.PersistBatch(ctx, vm)
 	require.NoErrorf(t, err, "persisting versioned model: %v", err)
 }
+
+func TestWriteRawConflict(t *testing.T) {
+	render := func(keys []string, columns []string) (string, []interface{}) {
+		var q strings.Builder
+		params := writeRawConflict(&q, []interface{}{pg.Ident("t")}, keys, columns)
+		return q.String(), params
+	}
+
+	q, params := render(nil, []string{"height", "value"})
+	assert.Equal(t, " ON CONFLICT DO NOTHING", q)
+	assert.Len(t, params, 1)
+
+	q, params = render([]string{"height", "id"}, []string{"height", "id", "value"})
+	assert.Equal(t, " ON CONFLICT (?1, ?2) DO UPDATE SET ?3 = EXCLUDED.?3", q)
+	assert.Equal(t, []interface{}{pg.Ident("t"), pg.Ident("height"), pg.Ident("id"), pg.Ident("value")}, params)
+
+	q, _ = render([]string{"height"}, []string{"height"})
+	assert.Equal(t, " ON CONFLICT (?1) DO NOTHING", q)
+}
+
+func TestOverwriting(t *testing.T) {
+	d, err := NewDatabase(context.Background(), "postgres://example.com/fakedb", 1, "visor", "public", false)
+	require.NoError(t, err)
+
+	od, ok := d.Overwriting().(*Database)
+	require.True(t, ok)
+	assert.True(t, od.Upsert)
+	assert.False(t, d.Upsert)
+}
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	if testing.Short() {
+		t.Skip("short testing requested")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDatabaseWaitTime)
+	defer cancel()
+
+	db, cleanup, err := testutil.WaitForExclusiveDatabase(ctx, t)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, cleanup()) }()
+
+	d, err := NewDatabaseFromDB(ctx, db, "public")
+	require.NoError(t, err, "NewDatabaseFromDB")
+	require.NoError(t, d.CheckSchemaCompatibility(ctx))
+
+	// A schema that has changed version since connecting is refused
+	connected := d.version
+	d.version = model.Version{Major: connected.Major, Patch: connected.Patch - 1}
+	err = d.CheckSchemaCompatibility(ctx)
+	assert.ErrorIs(t, err, ErrSchemaIncompatible)
+	d.version = connected
+}
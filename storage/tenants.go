@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// tenantsVersion is the first schema version containing the visor_tenants table.
+var tenantsVersion = model.Version{Major: 1, Patch: 28}
+
+// tenantNameRe restricts tenant names to lower case identifiers that need no quoting as schema or role names.
+var tenantNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,47}$`)
+
+// TenantConfig describes a tenant to provision.
+type TenantConfig struct {
+	// Name of the tenant, which is also used as the name of its postgresql schema.
+	Name string
+
+	// ReaderRole is the role granted read access to the tenant's schema. Defaults to the tenant name followed by
+	// _reader.
+	ReaderRole string
+
+	// CreateRole allows the reader role to be created if it does not exist.
+	CreateRole bool
+
+	// AdminURL is the connection string of a role that may create roles. The database's own connection is used if
+	// empty.
+	AdminURL string
+}
+
+// ProvisionTenant creates a postgresql schema for a tenant holding a read only view of each data table in the visor
+// schema, and grants the tenant's reader role access to it. visor's own bookkeeping tables, whose names start with
+// visor_, are not included. The tenant is recorded so its views are recreated whenever the schema is migrated. It is
+// safe to provision a tenant more than once, which recreates its views.
+func (d *Database) ProvisionTenant(ctx context.Context, cfg TenantConfig) error {
+	if d.version.Before(tenantsVersion) {
+		return xerrors.Errorf("tenants require schema version %s or later", tenantsVersion)
+	}
+	if !tenantNameRe.MatchString(cfg.Name) {
+		return xerrors.Errorf("invalid tenant name %q: must be a lower case identifier", cfg.Name)
+	}
+	schemaName := d.SchemaConfig().SchemaName
+	if cfg.Name == schemaName || cfg.Name == "public" {
+		return xerrors.Errorf("tenant name %q may not be the name of the visor schema", cfg.Name)
+	}
+
+	t := &visor.Tenant{
+		Name:       cfg.Name,
+		ReaderRole: cfg.ReaderRole,
+		CreatedAt:  time.Now(),
+	}
+	if t.ReaderRole == "" {
+		t.ReaderRole = cfg.Name + "_reader"
+	}
+
+	adminOpt := *d.opt
+	if cfg.AdminURL != "" {
+		opt, err := pg.ParseURL(cfg.AdminURL)
+		if err != nil {
+			return xerrors.Errorf("parse admin url: %w", err)
+		}
+		adminOpt = *opt
+		adminOpt.Database = d.opt.Database
+	}
+	adminOpt.OnConnect = nil
+	adminOpt.PoolSize = 1
+	if err := withAdminConn(ctx, &adminOpt, func(db *pg.DB) error {
+		return ensureRole(ctx, db, t.ReaderRole, "", false, cfg.CreateRole)
+	}); err != nil {
+		return err
+	}
+
+	// The schema and its views are owned by the visor role so the views can read the data tables on behalf of the
+	// reader role, which is granted no access to the visor schema itself.
+	if _, err := d.db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS ?`, pg.Ident(t.Name)); err != nil {
+		return xerrors.Errorf("create tenant schema: %w", err)
+	}
+
+	if _, err := d.db.ExecContext(ctx, `INSERT INTO ?.visor_tenants (name, reader_role, created_at) VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET reader_role = EXCLUDED.reader_role`, pg.Ident(schemaName), t.Name, t.ReaderRole, t.CreatedAt); err != nil {
+		return xerrors.Errorf("record tenant: %w", err)
+	}
+
+	log.Infow("provisioning tenant", "tenant", t.Name, "reader_role", t.ReaderRole)
+	return refreshTenantViews(ctx, d.db, schemaName, t)
+}
+
+// Tenants returns the tenants that have been provisioned.
+func (d *Database) Tenants(ctx context.Context) ([]*visor.Tenant, error) {
+	return listTenants(ctx, d.db, d.SchemaConfig().SchemaName)
+}
+
+// listTenants returns the tenants recorded in the visor schema, or none if the schema does not record tenants.
+func listTenants(ctx context.Context, db *pg.DB, schemaName string) ([]*visor.Tenant, error) {
+	exists, err := tableExists(ctx, db, schemaName, "visor_tenants")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	var tenants []*visor.Tenant
+	if _, err := db.QueryContext(ctx, &tenants, `SELECT name, reader_role, created_at FROM ?.visor_tenants ORDER BY name`, pg.Ident(schemaName)); err != nil {
+		return nil, xerrors.Errorf("query tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// dropTenantsViews drops the views of every tenant so that migrations may alter or drop the tables they read.
+func dropTenantsViews(ctx context.Context, db *pg.DB, schemaName string) error {
+	tenants, err := listTenants(ctx, db, schemaName)
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		if err := dropTenantViews(ctx, db, t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshTenants recreates the views of every tenant to match the tables in the visor schema.
+func refreshTenants(ctx context.Context, db *pg.DB, schemaName string) error {
+	tenants, err := listTenants(ctx, db, schemaName)
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		if err := refreshTenantViews(ctx, db, schemaName, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropTenantViews(ctx context.Context, db pg.DBI, tenant string) error {
+	var views []string
+	if _, err := db.QueryContext(ctx, &views, `SELECT table_name FROM information_schema.views WHERE table_schema = ?`, tenant); err != nil {
+		return xerrors.Errorf("query views of tenant %s: %w", tenant, err)
+	}
+	for _, view := range views {
+		if _, err := db.ExecContext(ctx, `DROP VIEW IF EXISTS ?.?`, pg.Ident(tenant), pg.Ident(view)); err != nil {
+			return xerrors.Errorf("drop view %s of tenant %s: %w", view, tenant, err)
+		}
+	}
+	return nil
+}
+
+// refreshTenantViews replaces the views in the tenant's schema with a view of each data table and view in the visor
+// schema and grants the tenant's reader role access to them.
+func refreshTenantViews(ctx context.Context, db *pg.DB, schemaName string, t *visor.Tenant) error {
+	return db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if err := dropTenantViews(ctx, tx, t.Name); err != nil {
+			return err
+		}
+
+		var tables []string
+		if _, err := tx.QueryContext(ctx, &tables, `SELECT table_name FROM information_schema.tables
+WHERE table_schema = ? AND table_type IN ('BASE TABLE', 'VIEW') AND table_name NOT LIKE 'visor\_%' AND table_name <> 'gopg_migrations'
+ORDER BY table_name`, schemaName); err != nil {
+			return xerrors.Errorf("query data tables: %w", err)
+		}
+
+		for _, table := range tables {
+			if _, err := tx.ExecContext(ctx, `CREATE VIEW ?.? AS SELECT * FROM ?.?`, pg.Ident(t.Name), pg.Ident(table), pg.Ident(schemaName), pg.Ident(table)); err != nil {
+				return xerrors.Errorf("create view %s of tenant %s: %w", table, t.Name, err)
+			}
+		}
+
+		stmts := []string{
+			`GRANT USAGE ON SCHEMA ?0 TO ?1`,
+			`GRANT SELECT ON ALL TABLES IN SCHEMA ?0 TO ?1`,
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt, pg.Ident(t.Name), pg.Ident(t.ReaderRole)); err != nil {
+				return xerrors.Errorf("grant read access to %s: %w", t.ReaderRole, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+)
+
+// Transaction isolation levels that may be used when persisting data. An empty level leaves the server's default in
+// place, which is normally read committed.
+const (
+	IsolationReadCommitted  = "read committed"
+	IsolationRepeatableRead = "repeatable read"
+	IsolationSerializable   = "serializable"
+)
+
+// Postgresql error codes of failures that are resolved by running the transaction again.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// ParseIsolationLevel returns the isolation level named by s, ignoring case and allowing words to be separated by
+// hyphens or underscores.
+func ParseIsolationLevel(s string) (string, error) {
+	level := strings.ToLower(strings.NewReplacer("-", " ", "_", " ").Replace(strings.TrimSpace(s)))
+	switch level {
+	case "", IsolationReadCommitted, IsolationRepeatableRead, IsolationSerializable:
+		return level, nil
+	default:
+		return "", xerrors.Errorf("unsupported transaction isolation level: %q", s)
+	}
+}
+
+// runPersistTx runs fn in a transaction using the isolation level configured for the database. Transactions that fail
+// with a serialization failure or deadlock, which are expected when concurrent jobs write to the same tables, are run
+// again up to TxRetries times, waiting TxRetryDelay before the first retry and twice as long before each one after.
+// fn must not retain any state between attempts.
+func (d *Database) runPersistTx(ctx context.Context, fn func(tx *pg.Tx) error) error {
+	delay := d.TxRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+			if d.Isolation != "" {
+				if _, err := tx.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL "+d.Isolation); err != nil {
+					return xerrors.Errorf("set isolation level: %w", err)
+				}
+			}
+			return fn(tx)
+		})
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+		if attempt >= d.TxRetries {
+			return xerrors.Errorf("transaction failed after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Warnw("retrying transaction", "attempt", attempt+1, "delay", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isRetryableTxError reports whether err was caused by a transaction conflicting with a concurrent transaction.
+func isRetryableTxError(err error) bool {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Field('C') {
+	case pgSerializationFailure, pgDeadlockDetected:
+		return true
+	default:
+		return false
+	}
+}
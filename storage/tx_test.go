@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+type fakePgError struct {
+	code string
+}
+
+func (e fakePgError) Error() string { return "pg error " + e.code }
+
+func (e fakePgError) Field(k byte) string {
+	if k == 'C' {
+		return e.code
+	}
+	return ""
+}
+
+func (e fakePgError) IntegrityViolation() bool { return false }
+
+func TestParseIsolationLevel(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                "",
+		"serializable":    IsolationSerializable,
+		"Repeatable-Read": IsolationRepeatableRead,
+		"read_committed":  IsolationReadCommitted,
+		" read committed": IsolationReadCommitted,
+	} {
+		got, err := ParseIsolationLevel(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseIsolationLevel("read uncommitted")
+	assert.Error(t, err)
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	assert.True(t, isRetryableTxError(fakePgError{code: pgSerializationFailure}))
+	assert.True(t, isRetryableTxError(xerrors.Errorf("persist: %w", fakePgError{code: pgDeadlockDetected})))
+	assert.False(t, isRetryableTxError(fakePgError{code: "23505"}))
+	assert.False(t, isRetryableTxError(xerrors.Errorf("not a pg error")))
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+	"github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// walkJobsVersion is the first schema version containing the visor_walk_jobs table.
+var walkJobsVersion = model.Version{Major: 1, Patch: 36}
+
+// walkJobActorTypesVersion is the first schema version in which walk jobs record the types of actor they extract and
+// whether they defer indexes.
+var walkJobActorTypesVersion = model.Version{Major: 1, Patch: 40}
+
+// walkJobColumnsV36 are the columns of the visor_walk_jobs table before walk jobs recorded actor types and deferred
+// indexes.
+var walkJobColumnsV36 = []string{"id", "name", "tasks", "min_height", "max_height", "direction", "strict", "overwrite", "cursor_height", "cursor_tipset", "created_at", "updated_at", "completed_at"}
+
+// ErrWalkJobNotFound is returned when no walk job has the requested id.
+var ErrWalkJobNotFound = errors.New("walk job not found")
+
+// CreateWalkJob records a new walk, setting its ID.
+func (d *Database) CreateWalkJob(ctx context.Context, job *visor.WalkJob) error {
+	if d.version.Before(walkJobsVersion) {
+		return xerrors.Errorf("walk jobs require schema version %s or later", walkJobsVersion)
+	}
+	if d.readOnly {
+		return ErrReadOnly
+	}
+
+	q := d.db.ModelContext(ctx, job)
+	if d.version.Before(walkJobActorTypesVersion) {
+		if len(job.ActorTypes) > 0 {
+			return xerrors.Errorf("walks limited to actor types require schema version %s or later", walkJobActorTypesVersion)
+		}
+		if job.DeferIndexes {
+			return xerrors.Errorf("walks deferring indexes require schema version %s or later", walkJobActorTypesVersion)
+		}
+		q = q.Column(walkJobColumnsV36[1:]...)
+	}
+
+	now := d.Clock.Now()
+	job.ID = 0
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if _, err := q.Returning("id").Insert(); err != nil {
+		return xerrors.Errorf("insert walk job: %w", err)
+	}
+	return nil
+}
+
+// WalkJob returns the walk job with the given id. ErrWalkJobNotFound is returned if there is none.
+func (d *Database) WalkJob(ctx context.Context, id int64) (*visor.WalkJob, error) {
+	if d.version.Before(walkJobsVersion) {
+		return nil, xerrors.Errorf("walk jobs require schema version %s or later", walkJobsVersion)
+	}
+
+	job := &visor.WalkJob{ID: id}
+	q := d.db.ModelContext(ctx, job)
+	if d.version.Before(walkJobActorTypesVersion) {
+		q = q.Column(walkJobColumnsV36...)
+	}
+	if err := q.WherePK().Select(); err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, xerrors.Errorf("walk job %d: %w", id, ErrWalkJobNotFound)
+		}
+		return nil, xerrors.Errorf("query walk job: %w", err)
+	}
+	return job, nil
+}
+
+// UpdateWalkCursor moves the cursor of a walk job to the tipset with the given height and key.
+func (d *Database) UpdateWalkCursor(ctx context.Context, id int64, height int64, tipset string) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+
+	if _, err := d.db.ModelContext(ctx, (*visor.WalkJob)(nil)).
+		Set("cursor_height = ?", height).
+		Set("cursor_tipset = ?", tipset).
+		Set("updated_at = ?", d.Clock.Now()).
+		Where("id = ?", id).
+		Update(); err != nil {
+		return xerrors.Errorf("update walk cursor: %w", err)
+	}
+	return nil
+}
+
+// CompleteWalkJob records that a walk job has reached the end of its range.
+func (d *Database) CompleteWalkJob(ctx context.Context, id int64) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+
+	now := d.Clock.Now()
+	if _, err := d.db.ModelContext(ctx, (*visor.WalkJob)(nil)).
+		Set("completed_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("id = ?", id).
+		Update(); err != nil {
+		return xerrors.Errorf("complete walk job: %w", err)
+	}
+	return nil
+}
+
+// IncompleteWalkJobs returns the walk jobs with the given name that have not reached the end of their range, in the
+// order they were created.
+func (d *Database) IncompleteWalkJobs(ctx context.Context, name string) ([]*visor.WalkJob, error) {
+	if d.version.Before(walkJobsVersion) {
+		return nil, xerrors.Errorf("walk jobs require schema version %s or later", walkJobsVersion)
+	}
+
+	var jobs []*visor.WalkJob
+	q := d.db.ModelContext(ctx, &jobs)
+	if d.version.Before(walkJobActorTypesVersion) {
+		q = q.Column(walkJobColumnsV36...)
+	}
+	if err := q.Where("name = ?", name).Where("completed_at IS NULL").Order("id").Select(); err != nil {
+		return nil, xerrors.Errorf("query incomplete walk jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/model"
+)
+
+// minerWinStatsVersion is the first schema version containing the miner_win_stats table.
+var minerWinStatsVersion = model.Version{Major: 1, Patch: 22}
+
+// expectedWinsPerEpoch is the expected number of winning tickets in each epoch across the whole network, the
+// BlocksPerEpoch parameter of the Filecoin expected consensus.
+const expectedWinsPerEpoch = 5
+
+// refreshMinerWinStatsSQL computes the miner_win_stats rows for the window of epochs ?0 to ?1, which is ?2 epochs long.
+// A miner's expected wins are summed over the intervals between its power claims, using a running sum of the inverse
+// of the total network power at each epoch of the window so each interval is a pair of lookups.
+const refreshMinerWinStatsSQL = `
+WITH tp AS (
+	SELECT g.h AS height, sum(coalesce(1 / nullif(c.total_qa_bytes_power, 0)::double precision, 0)) OVER (ORDER BY g.h) AS cum
+	FROM generate_series(?0::bigint, ?1::bigint) AS g(h)
+	LEFT JOIN chain_powers c ON c.height = g.h AND c.is_canonical
+), claims AS (
+	SELECT miner_id, ?0::bigint AS start, 0 AS ord, quality_adj_power FROM (
+		SELECT DISTINCT ON (miner_id) miner_id, quality_adj_power FROM power_actor_claims
+		WHERE height < ?0 AND is_canonical
+		ORDER BY miner_id, height DESC
+	) prev
+	UNION ALL
+	SELECT miner_id, height, 1, quality_adj_power FROM power_actor_claims
+	WHERE height BETWEEN ?0 AND ?1 AND is_canonical
+), intervals AS (
+	SELECT miner_id, start, coalesce(lead(start) OVER (PARTITION BY miner_id ORDER BY start, ord), ?1 + 1) AS stop, quality_adj_power
+	FROM claims
+), expected AS (
+	SELECT i.miner_id, ?3 * sum(i.quality_adj_power::double precision * (e.cum - coalesce(s.cum, 0))) AS wins
+	FROM intervals i
+	JOIN tp e ON e.height = i.stop - 1
+	LEFT JOIN tp s ON s.height = i.start - 1
+	WHERE i.stop > i.start
+	GROUP BY i.miner_id
+), actual AS (
+	SELECT miner AS miner_id, sum(coalesce(win_count, 1)) AS wins, count(*) AS blocks
+	FROM block_headers
+	WHERE height BETWEEN ?0 AND ?1 AND is_canonical
+	GROUP BY miner
+)
+INSERT INTO miner_win_stats (height, window_epochs, miner_id, expected_wins, actual_wins, blocks, updated_at)
+SELECT ?1, ?2, miner_id, coalesce(e.wins, 0), coalesce(a.wins, 0), coalesce(a.blocks, 0), now()
+FROM expected e FULL OUTER JOIN actual a USING (miner_id)
+WHERE coalesce(e.wins, 0) > 0 OR coalesce(a.blocks, 0) > 0`
+
+// RefreshMinerWinStats recomputes the miner_win_stats rows for the window of epochs ending at height and returns the
+// number of rows written.
+func (d *Database) RefreshMinerWinStats(ctx context.Context, height int64, window int64) (int, error) {
+	if d.version.Before(minerWinStatsVersion) {
+		return 0, xerrors.Errorf("miner win stats require schema version %s or later", minerWinStatsVersion)
+	}
+	if window <= 0 {
+		return 0, xerrors.Errorf("window must be positive")
+	}
+
+	var written int
+	err := d.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM miner_win_stats WHERE height = ? AND window_epochs = ?`, height, window); err != nil {
+			return xerrors.Errorf("delete window: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, refreshMinerWinStatsSQL, height-window+1, height, window, expectedWinsPerEpoch)
+		if err != nil {
+			return xerrors.Errorf("insert window: %w", err)
+		}
+		written = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// minerWinStatsBounds returns the lowest and highest heights for which both total power and block headers have been
+// indexed, or -1 for both if either is missing.
+func (d *Database) minerWinStatsBounds(ctx context.Context) (int64, int64, error) {
+	var powerLow, powerHigh, blocksLow, blocksHigh *int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&powerLow, &powerHigh, &blocksLow, &blocksHigh), `SELECT
+	(SELECT min(height) FROM chain_powers), (SELECT max(height) FROM chain_powers),
+	(SELECT min(height) FROM block_headers), (SELECT max(height) FROM block_headers)`); err != nil {
+		return 0, 0, xerrors.Errorf("query indexed heights: %w", err)
+	}
+	if powerLow == nil || blocksLow == nil {
+		return -1, -1, nil
+	}
+
+	lowest, highest := *powerLow, *powerHigh
+	if *blocksLow > lowest {
+		lowest = *blocksLow
+	}
+	if *blocksHigh < highest {
+		highest = *blocksHigh
+	}
+	return lowest, highest, nil
+}
+
+// lastMinerWinStatsHeight returns the height of the latest window of the given length, or -1 if there is none.
+func (d *Database) lastMinerWinStatsHeight(ctx context.Context, window int64) (int64, error) {
+	var last int64
+	if _, err := d.db.QueryOneContext(ctx, pg.Scan(&last), `SELECT coalesce(max(height), -1) FROM miner_win_stats WHERE window_epochs = ?`, window); err != nil {
+		return 0, xerrors.Errorf("query last window: %w", err)
+	}
+	return last, nil
+}
+
+// NewMinerWinStatsAggregator returns an Aggregator that keeps the miner_win_stats table up to date as power and blocks
+// are indexed. Windows of each of the given lengths are computed at heights that are multiples of step and at least
+// lag epochs behind the latest indexed height. Windows ending before from are not computed.
+func NewMinerWinStatsAggregator(db *Database, windows []int64, step int64, lag int64, from int64, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		table:    "miner_win_stats",
+		interval: interval,
+		params: map[string]interface{}{
+			"windows": windows,
+			"step":    step,
+			"lag":     lag,
+			"from":    from,
+		},
+		refresh: func(ctx context.Context) error {
+			return db.refreshMinerWinStats(ctx, windows, step, lag, from)
+		},
+	}
+}
+
+// refreshMinerWinStats computes the windows of each length that have not been computed yet.
+func (d *Database) refreshMinerWinStats(ctx context.Context, windows []int64, step, lag, from int64) error {
+	lowest, highest, err := d.minerWinStatsBounds(ctx)
+	if err != nil {
+		return err
+	}
+	if highest < 0 {
+		log.Infow("no power or blocks indexed, skipping miner win stats")
+		return nil
+	}
+
+	for _, window := range windows {
+		last, err := d.lastMinerWinStatsHeight(ctx, window)
+		if err != nil {
+			return err
+		}
+
+		ends := windowEnds(last, lowest, highest-lag, from, window, step)
+		for _, end := range ends {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			rows, err := d.RefreshMinerWinStats(ctx, end, window)
+			if err != nil {
+				return xerrors.Errorf("refresh window of %d epochs ending at %d: %w", window, end, err)
+			}
+			log.Debugw("refreshed miner win stats", "height", end, "window", window, "rows", rows)
+		}
+		log.Infow("refreshed miner win stats", "window", window, "windows", len(ends))
+	}
+	return nil
+}
+
+// windowEnds returns the heights at which windows of the given length should next be computed. Windows end at
+// multiples of step after the last window computed, or if there is none, from the first multiple of step at which a
+// full window of indexed data is available. No window ends before from or after until.
+func windowEnds(last, lowest, until, from, window, step int64) []int64 {
+	next := last + step
+	if last < 0 {
+		next = lowest + window - 1
+		if next < from {
+			next = from
+		}
+		// Align to a multiple of step
+		next = (next + step - 1) / step * step
+	}
+
+	var ends []int64
+	for end := next; end <= until; end += step {
+		ends = append(ends, end)
+	}
+	return ends
+}
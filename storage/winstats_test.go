@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowEnds(t *testing.T) {
+	// First run starts at the first aligned height with a full window of data
+	assert.Equal(t, []int64{120, 130, 140}, windowEnds(-1, 95, 145, 0, 20, 10))
+
+	// From delays the first window
+	assert.Equal(t, []int64{140}, windowEnds(-1, 95, 145, 131, 20, 10))
+
+	// Later runs continue from the last window
+	assert.Equal(t, []int64{150, 160}, windowEnds(140, 95, 165, 0, 20, 10))
+
+	// Nothing to do until the next window is complete
+	assert.Empty(t, windowEnds(160, 95, 169, 0, 20, 10))
+}
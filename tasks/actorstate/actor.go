@@ -24,12 +24,7 @@ func (ActorExtractor) Extract(ctx context.Context, a ActorInfo, node ActorStateA
 	stop := metrics.Timer(ctx, metrics.ProcessingDuration)
 	defer stop()
 
-	ast, err := node.StateReadState(ctx, a.Address, a.TipSet.Key())
-	if err != nil {
-		return nil, err
-	}
-
-	state, err := json.Marshal(ast.State)
+	state, err := extractActorState(ctx, a, node)
 	if err != nil {
 		return nil, err
 	}
@@ -41,14 +36,41 @@ func (ActorExtractor) Extract(ctx context.Context, a ActorInfo, node ActorStateA
 			StateRoot: a.ParentStateRoot.String(),
 			Code:      builtin.ActorNameByCode(a.Actor.Code),
 			Head:      a.Actor.Head.String(),
-			Balance:   a.Actor.Balance.String(),
+			Balance:   model.TokenAmount(a.Actor.Balance),
 			Nonce:     a.Actor.Nonce,
 		},
-		State: &commonmodel.ActorState{
-			Height: int64(a.Epoch),
-			Head:   a.Actor.Head.String(),
-			Code:   a.Actor.Code.String(),
-			State:  string(state),
-		},
+		State: state,
+	}, nil
+}
+
+// RawStateExtractor extracts only the top level state of an actor, decoded to JSON.
+type RawStateExtractor struct{}
+
+func (RawStateExtractor) Extract(ctx context.Context, a ActorInfo, node ActorStateAPI) (model.Persistable, error) {
+	ctx, span := global.Tracer("").Start(ctx, "RawStateExtractor")
+	defer span.End()
+
+	stop := metrics.Timer(ctx, metrics.ProcessingDuration)
+	defer stop()
+
+	return extractActorState(ctx, a, node)
+}
+
+func extractActorState(ctx context.Context, a ActorInfo, node ActorStateAPI) (*commonmodel.ActorState, error) {
+	ast, err := node.StateReadState(ctx, a.Address, a.TipSet.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := json.Marshal(ast.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commonmodel.ActorState{
+		Height: int64(a.Epoch),
+		Head:   a.Actor.Head.String(),
+		Code:   a.Actor.Code.String(),
+		State:  string(state),
 	}, nil
 }
@@ -67,3 +67,51 @@ func TestActorExtractor(t *testing.T) {
 	assert.EqualValues(t, expectedBal.String(), actualState.Actor.Balance)
 	assert.EqualValues(t, tipset.ParentState().String(), actualState.Actor.StateRoot)
 }
+
+func TestUntypedActorExtractorMap(t *testing.T) {
+	m := actorstate.UntypedActorExtractorMap{}
+
+	assert.False(t, m.Allow(builtin.AccountActorCodeID))
+	_, ok := m.GetExtractor(builtin.StorageMinerActorCodeID)
+	assert.False(t, ok)
+
+	assert.True(t, m.Allow(builtin.PaymentChannelActorCodeID))
+	ex, ok := m.GetExtractor(builtin.PaymentChannelActorCodeID)
+	require.True(t, ok)
+	assert.IsType(t, actorstate.RawStateExtractor{}, ex)
+}
+
+func TestRawStateExtractor(t *testing.T) {
+	ctx := context.Background()
+	mapi := NewMockAPI(t)
+
+	addr := tutils.NewIDAddr(t, 123)
+	state := mapi.mustCreateAccountStateV0(addr)
+	head, err := mapi.Store().Put(ctx, state)
+	require.NoError(t, err)
+
+	act := types.Actor{
+		Code:    builtin.AccountActorCodeID,
+		Head:    head,
+		Balance: types.NewInt(1),
+	}
+
+	tipset := mapi.fakeTipset(tutils.NewIDAddr(t, 1234), 1)
+	mapi.setActor(tipset.Key(), addr, &act)
+
+	res, err := actorstate.RawStateExtractor{}.Extract(ctx, actorstate.ActorInfo{
+		Actor:           act,
+		Address:         addr,
+		ParentStateRoot: tipset.ParentState(),
+		Epoch:           1,
+		TipSet:          tipset,
+	}, mapi)
+	require.NoError(t, err)
+
+	actualState, ok := res.(*commonmodel.ActorState)
+	require.True(t, ok)
+	assert.EqualValues(t, 1, actualState.Height)
+	assert.EqualValues(t, head.String(), actualState.Head)
+	assert.EqualValues(t, builtin.AccountActorCodeID.String(), actualState.Code)
+	assert.NotEmpty(t, actualState.State)
+}
@@ -2,10 +2,13 @@ package actorstate
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/sentinel-visor/chain/actors/adt"
+	"github.com/ipfs/go-cid"
 	"go.opentelemetry.io/otel/api/global"
 	"golang.org/x/xerrors"
 
@@ -99,9 +102,62 @@ func (m StorageMarketExtractor) Extract(ctx context.Context, a ActorInfo, node A
 	return &marketmodel.MarketTaskResult{
 		Proposals: dealProposalModel,
 		States:    dealStateModel,
+		Pieces:    ExtractMarketDealPieces(dealProposalModel),
 	}, nil
 }
 
+// ExtractMarketDealPieces returns the piece and payload CIDs of newly added deal proposals.
+func ExtractMarketDealPieces(proposals marketmodel.MarketDealProposals) marketmodel.MarketDealPieces {
+	if len(proposals) == 0 {
+		return nil
+	}
+	out := make(marketmodel.MarketDealPieces, len(proposals))
+	for idx, dp := range proposals {
+		out[idx] = &marketmodel.MarketDealPiece{
+			Height:     dp.Height,
+			DealID:     dp.DealID,
+			StateRoot:  dp.StateRoot,
+			PieceCID:   dp.PieceCID,
+			PayloadCID: DealPayloadCID(dp.Label),
+		}
+	}
+	return out
+}
+
+// payloadLabelKeys are the properties that clients are known to use for the payload CID when they write a JSON object
+// into the deal label.
+var payloadLabelKeys = []string{"pcid", "payloadcid", "payload_cid", "root"}
+
+// DealPayloadCID decodes the CID of the payload stored by a deal from its label. Most clients set the label to the
+// payload CID, some write a JSON object holding it. An empty string is returned if no CID can be found.
+func DealPayloadCID(label string) string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return ""
+	}
+
+	if c, err := cid.Decode(label); err == nil {
+		return c.String()
+	}
+
+	var meta map[string]interface{}
+	if err := json.Unmarshal([]byte(label), &meta); err != nil {
+		return ""
+	}
+	for _, key := range payloadLabelKeys {
+		for k, v := range meta {
+			s, ok := v.(string)
+			if !ok || !strings.EqualFold(k, key) {
+				continue
+			}
+			if c, err := cid.Decode(s); err == nil {
+				return c.String()
+			}
+		}
+	}
+	return ""
+}
+
 func ExtractMarketDealProposals(ctx context.Context, ec *MarketStateExtractionContext) (marketmodel.MarketDealProposals, error) {
 	currDealProposals, err := ec.CurrState.Proposals()
 	if err != nil {
@@ -121,9 +177,9 @@ func ExtractMarketDealProposals(ctx context.Context, ec *MarketStateExtractionCo
 				EndEpoch:             int64(dp.EndEpoch),
 				ClientID:             dp.Client.String(),
 				ProviderID:           dp.Provider.String(),
-				ClientCollateral:     dp.ClientCollateral.String(),
-				ProviderCollateral:   dp.ProviderCollateral.String(),
-				StoragePricePerEpoch: dp.StoragePricePerEpoch.String(),
+				ClientCollateral:     model.TokenAmount(dp.ClientCollateral),
+				ProviderCollateral:   model.TokenAmount(dp.ProviderCollateral),
+				StoragePricePerEpoch: model.TokenAmount(dp.StoragePricePerEpoch),
 				PieceCID:             dp.PieceCID.String(),
 				IsVerified:           dp.VerifiedDeal,
 				Label:                dp.Label,
@@ -162,9 +218,9 @@ func ExtractMarketDealProposals(ctx context.Context, ec *MarketStateExtractionCo
 			EndEpoch:             int64(add.Proposal.EndEpoch),
 			ClientID:             add.Proposal.Client.String(),
 			ProviderID:           add.Proposal.Provider.String(),
-			ClientCollateral:     add.Proposal.ClientCollateral.String(),
-			ProviderCollateral:   add.Proposal.ProviderCollateral.String(),
-			StoragePricePerEpoch: add.Proposal.StoragePricePerEpoch.String(),
+			ClientCollateral:     model.TokenAmount(add.Proposal.ClientCollateral),
+			ProviderCollateral:   model.TokenAmount(add.Proposal.ProviderCollateral),
+			StoragePricePerEpoch: model.TokenAmount(add.Proposal.StoragePricePerEpoch),
 			PieceCID:             add.Proposal.PieceCID.String(),
 			IsVerified:           add.Proposal.VerifiedDeal,
 			Label:                add.Proposal.Label,
@@ -105,6 +105,7 @@ func TestMarketPredicates(t *testing.T) {
 	}
 
 	// added
+	payload3 := testutil.RandomCid()
 	newProp3 := &samarket.DealProposal{
 		PieceCID:             testutil.RandomCid(),
 		PieceSize:            0,
@@ -116,6 +117,7 @@ func TestMarketPredicates(t *testing.T) {
 		StoragePricePerEpoch: big.Zero(),
 		ProviderCollateral:   big.Zero(),
 		ClientCollateral:     big.Zero(),
+		Label:                payload3.String(),
 	}
 	newProps := map[abi.DealID]*samarket.DealProposal{
 		abi.DealID(1): oldProp1, // 1 was persisted
@@ -173,6 +175,15 @@ func TestMarketPredicates(t *testing.T) {
 		assert.EqualValues(t, newProp3.Label, mtr.Proposals[0].Label, "Label")
 	})
 
+	t.Run("pieces", func(t *testing.T) {
+		require.Equal(t, 1, len(mtr.Pieces))
+
+		assert.EqualValues(t, abi.DealID(3), mtr.Pieces[0].DealID, "DealID")
+		assert.EqualValues(t, newStateTs.ParentState().String(), mtr.Pieces[0].StateRoot, "StateRoot")
+		assert.EqualValues(t, newProp3.PieceCID.String(), mtr.Pieces[0].PieceCID, "PieceCID")
+		assert.EqualValues(t, payload3.String(), mtr.Pieces[0].PayloadCID, "PayloadCID")
+	})
+
 	t.Run("states", func(t *testing.T) {
 		require.Equal(t, 2, len(mtr.States))
 
@@ -189,3 +200,26 @@ func TestMarketPredicates(t *testing.T) {
 		assert.EqualValues(t, newStateTs.ParentState().String(), mtr.States[1].StateRoot, "StateRoot")
 	})
 }
+
+func TestDealPayloadCID(t *testing.T) {
+	payload := testutil.RandomCid()
+
+	testCases := []struct {
+		name  string
+		label string
+		want  string
+	}{
+		{name: "empty", label: "", want: ""},
+		{name: "cid", label: payload.String(), want: payload.String()},
+		{name: "padded cid", label: " " + payload.String() + "\n", want: payload.String()},
+		{name: "json", label: `{"pcid":"` + payload.String() + `","size":1024}`, want: payload.String()},
+		{name: "json mixed case key", label: `{"PayloadCid":"` + payload.String() + `"}`, want: payload.String()},
+		{name: "json without cid", label: `{"pcid":"not a cid"}`, want: ""},
+		{name: "text", label: "backup of my photos", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, actorstate.DealPayloadCID(tc.label))
+		})
+	}
+}
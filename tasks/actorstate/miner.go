@@ -12,7 +12,9 @@ import (
 
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
 	miner "github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
 
 	"github.com/filecoin-project/sentinel-visor/metrics"
@@ -66,6 +68,11 @@ func (m StorageMinerExtractor) Extract(ctx context.Context, a ActorInfo, node Ac
 		return nil, xerrors.Errorf("extracting miner current deadline info: %w", err)
 	}
 
+	deadlineSchedulesModel, err := ExtractMinerDeadlineSchedules(ctx, a, ec)
+	if err != nil {
+		return nil, xerrors.Errorf("extracting miner deadline schedules: %w", err)
+	}
+
 	preCommitModel, sectorModel, sectorDealsModel, sectorEventsModel, err := ExtractMinerSectorData(ctx, ec, a, node)
 	if err != nil {
 		return nil, xerrors.Errorf("extracting miner sector changes: %w", err)
@@ -83,6 +90,7 @@ func (m StorageMinerExtractor) Extract(ctx context.Context, a ActorInfo, node Ac
 		LockedFundsModel:         lockedFundsModel,
 		FeeDebtModel:             feeDebtModel,
 		CurrentDeadlineInfoModel: currDeadlineModel,
+		DeadlineSchedulesModel:   deadlineSchedulesModel,
 		SectorDealsModel:         sectorDealsModel,
 		SectorEventsModel:        sectorEventsModel,
 		SectorsModel:             sectorModel,
@@ -197,6 +205,7 @@ func ExtractMinerInfo(ctx context.Context, a ActorInfo, ec *MinerStateExtraction
 		ControlAddresses:        newCtrlAddresses,
 		MultiAddresses:          newMultiAddrs,
 		SectorSize:              uint64(newInfo.SectorSize),
+		WindowPoStProofType:     int64(newInfo.WindowPoStProofType),
 	}
 
 	if newInfo.PeerId != nil {
@@ -274,7 +283,7 @@ func ExtractMinerCurrentDeadlineInfo(ctx context.Context, a ActorInfo, ec *Miner
 		if err != nil {
 			return nil, err
 		}
-		if prevDeadlineInfo == currDeadlineInfo {
+		if *prevDeadlineInfo == *currDeadlineInfo {
 			return nil, nil
 		}
 	}
@@ -292,6 +301,96 @@ func ExtractMinerCurrentDeadlineInfo(ctx context.Context, a ActorInfo, ec *Miner
 	}, nil
 }
 
+// ExtractMinerDeadlineSchedules returns the open and close epochs within the current proving period of every deadline
+// that has partitions to prove. Nothing is returned unless the miner's deadlines or proving period start changed.
+func ExtractMinerDeadlineSchedules(ctx context.Context, a ActorInfo, ec *MinerStateExtractionContext) (minermodel.MinerDeadlineScheduleList, error) {
+	_, span := global.Tracer("").Start(ctx, "ExtractMinerDeadlineSchedules")
+	defer span.End()
+
+	currDeadlineInfo, err := ec.CurrState.DeadlineInfo(ec.CurrTs.Height())
+	if err != nil {
+		return nil, xerrors.Errorf("loading current deadline info: %w", err)
+	}
+
+	if ec.HasPreviousState() {
+		prevDeadlineInfo, err := ec.PrevState.DeadlineInfo(ec.CurrTs.Height())
+		if err != nil {
+			return nil, xerrors.Errorf("loading previous deadline info: %w", err)
+		}
+		changed, err := ec.CurrState.DeadlinesChanged(ec.PrevState)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing deadlines: %w", err)
+		}
+		if !changed && prevDeadlineInfo.PeriodStart == currDeadlineInfo.PeriodStart {
+			return nil, nil
+		}
+	}
+
+	var out minermodel.MinerDeadlineScheduleList
+	if err := ec.CurrState.ForEachDeadline(func(idx uint64, dl miner.Deadline) error {
+		var partitions, live, faulty uint64
+		if err := dl.ForEachPartition(func(_ uint64, part miner.Partition) error {
+			partitions++
+
+			liveSectors, err := part.LiveSectors()
+			if err != nil {
+				return err
+			}
+			n, err := liveSectors.Count()
+			if err != nil {
+				return err
+			}
+			live += n
+
+			faultySectors, err := part.FaultySectors()
+			if err != nil {
+				return err
+			}
+			n, err = faultySectors.Count()
+			if err != nil {
+				return err
+			}
+			faulty += n
+			return nil
+		}); err != nil {
+			return xerrors.Errorf("walking partitions of deadline %d: %w", idx, err)
+		}
+
+		if partitions == 0 {
+			return nil
+		}
+
+		dlOpen, dlClose, challenge, faultCutoff := DeadlineSchedule(currDeadlineInfo.PeriodStart, idx)
+		out = append(out, &minermodel.MinerDeadlineSchedule{
+			Height:        int64(ec.CurrTs.Height()),
+			MinerID:       a.Address.String(),
+			StateRoot:     a.ParentStateRoot.String(),
+			DeadlineIndex: idx,
+			PeriodStart:   int64(currDeadlineInfo.PeriodStart),
+			Open:          int64(dlOpen),
+			Close:         int64(dlClose),
+			Challenge:     int64(challenge),
+			FaultCutoff:   int64(faultCutoff),
+			Partitions:    partitions,
+			LiveSectors:   live,
+			FaultySectors: faulty,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DeadlineSchedule returns the epochs at which deadline idx opens and closes in the proving period starting at
+// periodStart, along with the epoch its challenge is drawn from and the last epoch faults may be declared for it.
+func DeadlineSchedule(periodStart abi.ChainEpoch, idx uint64) (abi.ChainEpoch, abi.ChainEpoch, abi.ChainEpoch, abi.ChainEpoch) {
+	dlOpen := periodStart + abi.ChainEpoch(idx)*miner.WPoStChallengeWindow
+	dlClose := dlOpen + miner.WPoStChallengeWindow
+	return dlOpen, dlClose, dlOpen - miner.WPoStChallengeLookback, dlOpen - miner.FaultDeclarationCutoff
+}
+
 func ExtractMinerSectorData(ctx context.Context, ec *MinerStateExtractionContext, a ActorInfo, node ActorStateAPI) (minermodel.MinerPreCommitInfoList, minermodel.MinerSectorInfoList, minermodel.MinerSectorDealList, minermodel.MinerSectorEventList, error) {
 	ctx, span := global.Tracer("").Start(ctx, "ExtractMinerSectorData")
 	defer span.End()
@@ -392,6 +491,8 @@ func ExtractMinerSectorData(ctx context.Context, ec *MinerStateExtractionContext
 			InitialPledge:         added.InitialPledge.String(),
 			ExpectedDayReward:     added.ExpectedDayReward.String(),
 			ExpectedStoragePledge: added.ExpectedStoragePledge.String(),
+			SealProof:             int64(added.SealProof),
+			QAPower:               SectorQAPower(added).String(),
 		}
 		sectorModel = append(sectorModel, sm)
 	}
@@ -411,6 +512,8 @@ func ExtractMinerSectorData(ctx context.Context, ec *MinerStateExtractionContext
 			InitialPledge:         extended.To.InitialPledge.String(),
 			ExpectedDayReward:     extended.To.ExpectedDayReward.String(),
 			ExpectedStoragePledge: extended.To.ExpectedStoragePledge.String(),
+			SealProof:             int64(extended.To.SealProof),
+			QAPower:               SectorQAPower(extended.To).String(),
 		}
 		sectorModel = append(sectorModel, sm)
 	}
@@ -707,3 +810,13 @@ func extractMinerPartitionsDiff(ctx context.Context, ec *MinerStateExtractionCon
 		Recovered:  recovered,
 	}, nil
 }
+
+// SectorQAPower returns the quality adjusted power of a sector. The calculation has not changed between actors
+// versions so it gives comparable values for sectors committed under any network version.
+func SectorQAPower(info miner.SectorOnChainInfo) abi.StoragePower {
+	size, err := info.SealProof.SectorSize()
+	if err != nil {
+		return big.Zero()
+	}
+	return builtin.QAPowerForWeight(size, info.Expiration-info.Activation, info.DealWeight, info.VerifiedDealWeight)
+}
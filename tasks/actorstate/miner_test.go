@@ -0,0 +1,24 @@
+package actorstate_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	sa0miner "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/sentinel-visor/tasks/actorstate"
+)
+
+func TestDeadlineSchedule(t *testing.T) {
+	periodStart := abi.ChainEpoch(1234)
+	for idx := uint64(0); idx < sa0miner.WPoStPeriodDeadlines; idx++ {
+		want := sa0miner.NewDeadlineInfo(periodStart, idx, periodStart)
+
+		dlOpen, dlClose, challenge, faultCutoff := actorstate.DeadlineSchedule(periodStart, idx)
+		assert.Equal(t, want.Open, dlOpen, "open of deadline %d", idx)
+		assert.Equal(t, want.Close, dlClose, "close of deadline %d", idx)
+		assert.Equal(t, want.Challenge, challenge, "challenge of deadline %d", idx)
+		assert.Equal(t, want.FaultCutoff, faultCutoff, "fault cutoff of deadline %d", idx)
+	}
+}
@@ -56,7 +56,7 @@ func ExtractMultisigTransactions(ctx context.Context, a ActorInfo, ec *MsigExtra
 				Height:        int64(ec.CurrTs.Height()),
 				TransactionID: id,
 				To:            txn.To.String(),
-				Value:         txn.Value.String(),
+				Value:         model.TokenAmount(txn.Value),
 				Method:        uint64(txn.Method),
 				Params:        txn.Params,
 				Approved:      approved,
@@ -84,7 +84,7 @@ func ExtractMultisigTransactions(ctx context.Context, a ActorInfo, ec *MsigExtra
 			Height:        int64(ec.CurrTs.Height()),
 			TransactionID: added.TxID,
 			To:            added.Tx.To.String(),
-			Value:         added.Tx.Value.String(),
+			Value:         model.TokenAmount(added.Tx.Value),
 			Method:        uint64(added.Tx.Method),
 			Params:        added.Tx.Params,
 			Approved:      approved,
@@ -102,7 +102,7 @@ func ExtractMultisigTransactions(ctx context.Context, a ActorInfo, ec *MsigExtra
 			Height:        int64(ec.CurrTs.Height()),
 			TransactionID: modded.TxID,
 			To:            modded.To.To.String(),
-			Value:         modded.To.Value.String(),
+			Value:         model.TokenAmount(modded.To.Value),
 			Method:        uint64(modded.To.Method),
 			Params:        modded.To.Params,
 			Approved:      approved,
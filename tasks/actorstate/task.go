@@ -2,11 +2,14 @@ package actorstate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/state"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/ipfs/go-cid"
 	"go.opencensus.io/tag"
@@ -18,6 +21,7 @@ import (
 	"github.com/filecoin-project/sentinel-visor/lens"
 	"github.com/filecoin-project/sentinel-visor/metrics"
 	"github.com/filecoin-project/sentinel-visor/model"
+	commonmodel "github.com/filecoin-project/sentinel-visor/model/actors/common"
 	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
 )
 
@@ -28,7 +32,8 @@ type Task struct {
 	opener lens.APIOpener
 	closer lens.APICloser
 
-	extracterMap ActorExtractorMap
+	extracterMap  ActorExtractorMap
+	skipDeletions bool // true if deleted actors are recorded by another task
 }
 
 func NewTask(opener lens.APIOpener, extracterMap ActorExtractorMap) *Task {
@@ -39,6 +44,17 @@ func NewTask(opener lens.APIOpener, extracterMap ActorExtractorMap) *Task {
 	return p
 }
 
+// SkipDeletions stops the task recording actors deleted from the state tree. It is used when another task that
+// allows the same actors records them so each deletion is only persisted once.
+func (t *Task) SkipDeletions() {
+	t.skipDeletions = true
+}
+
+// Allow reports whether the task extracts the state of actors with the given code.
+func (t *Task) Allow(code cid.Cid) bool {
+	return t.extracterMap.Allow(code)
+}
+
 func (t *Task) ProcessActors(ctx context.Context, ts *types.TipSet, pts *types.TipSet, candidates map[string]types.Actor) (model.Persistable, *visormodel.ProcessingReport, error) {
 	ctx, span := global.Tracer("").Start(ctx, "ProcessActors")
 	if span.IsRecording() {
@@ -85,6 +101,20 @@ func (t *Task) ProcessActors(ctx context.Context, ts *types.TipSet, pts *types.T
 		return data, report, nil
 	}
 
+	// Actors that are missing from the child state have been deleted and have no state to extract
+	deletions, err := t.deletedActors(ts, actors)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("find deleted actors: %w", err)
+	}
+	if len(deletions) > 0 && !t.skipDeletions {
+		ll.Debugw("found deleted actors", "count", len(deletions))
+		data = append(data, deletions)
+	}
+
+	if len(actors) == 0 {
+		return data, report, nil
+	}
+
 	start := time.Now()
 	ll.Debugw("found actor state changes", "count", len(actors))
 
@@ -95,6 +125,7 @@ func (t *Task) ProcessActors(ctx context.Context, ts *types.TipSet, pts *types.T
 	}
 
 	// Gather results
+	extracted := make([]*ActorStateResult, 0, len(actors))
 	inFlight := len(actors)
 	for inFlight > 0 {
 		res := <-results
@@ -118,6 +149,14 @@ func (t *Task) ProcessActors(ctx context.Context, ts *types.TipSet, pts *types.T
 			skippedActors++
 		}
 
+		extracted = append(extracted, res)
+	}
+
+	// Results arrive in the order extraction completed so sort them to keep the persisted data deterministic
+	sort.Slice(extracted, func(i, j int) bool {
+		return extracted[i].Address < extracted[j].Address
+	})
+	for _, res := range extracted {
 		data = append(data, res.Data)
 	}
 
@@ -134,8 +173,51 @@ func (t *Task) ProcessActors(ctx context.Context, ts *types.TipSet, pts *types.T
 	return data, report, nil
 }
 
+// deletedActors removes actors that are not present in the state of ts from the actors map and returns a record of
+// each deletion. The balance recorded is the one from the last state the actor was present in.
+func (t *Task) deletedActors(ts *types.TipSet, actors map[string]types.Actor) (commonmodel.ActorDeletionList, error) {
+	t.nodeMu.Lock()
+	nodeAPI := t.node
+	t.nodeMu.Unlock()
+
+	if nodeAPI == nil {
+		return nil, xerrors.Errorf("no connection to api")
+	}
+
+	tree, err := state.LoadStateTree(nodeAPI.Store(), ts.ParentState())
+	if err != nil {
+		return nil, xerrors.Errorf("load state tree: %w", err)
+	}
+
+	var deletions commonmodel.ActorDeletionList
+	for addrStr, act := range actors {
+		addr, err := address.NewFromString(addrStr)
+		if err != nil {
+			// reported as an error when the actor is extracted
+			continue
+		}
+
+		if _, err := tree.GetActor(addr); err != nil {
+			if !errors.Is(err, types.ErrActorNotFound) {
+				return nil, xerrors.Errorf("get actor %s: %w", addrStr, err)
+			}
+			deletions = append(deletions, &commonmodel.ActorDeletion{
+				Height:    int64(ts.Height()),
+				ID:        addrStr,
+				StateRoot: ts.ParentState().String(),
+				Code:      builtin.ActorNameByCode(act.Code),
+				Balance:   model.TokenAmount(act.Balance),
+			})
+			delete(actors, addrStr)
+		}
+	}
+
+	return deletions, nil
+}
+
 func (t *Task) runActorStateExtraction(ctx context.Context, ts *types.TipSet, pts *types.TipSet, addrStr string, act types.Actor, results chan *ActorStateResult) {
-	ctx, _ = tag.New(ctx, tag.Upsert(metrics.ActorCode, builtin.ActorNameByCode(act.Code)))
+	actorName := builtin.ActorNameByCode(act.Code)
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.ActorCode, actorName), tag.Upsert(metrics.ActorFamily, builtin.ActorFamily(actorName)))
 
 	res := &ActorStateResult{
 		Code:    act.Code,
@@ -181,10 +263,25 @@ func (t *Task) runActorStateExtraction(ctx context.Context, ts *types.TipSet, pt
 			res.Error = xerrors.Errorf("failed to extract parsed actor state: %w", err)
 			return
 		}
-		res.Data = data
+		res.Data = &actorFamilyPersistable{
+			family:      builtin.ActorFamily(actorName),
+			Persistable: data,
+		}
 	}
 }
 
+// actorFamilyPersistable tags the persistence of the data extracted from an actor with the actor's family so the
+// duration of persisting each type of actor can be told apart.
+type actorFamilyPersistable struct {
+	family string
+	model.Persistable
+}
+
+func (p *actorFamilyPersistable) Persist(ctx context.Context, s model.StorageBatch, version model.Version) error {
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.ActorFamily, p.family))
+	return p.Persistable.Persist(ctx, s, version)
+}
+
 func (t *Task) Close() error {
 	t.nodeMu.Lock()
 	defer t.nodeMu.Unlock()
@@ -234,6 +331,22 @@ func (RawActorExtractorMap) GetExtractor(code cid.Cid) (ActorStateExtractor, boo
 	return ActorExtractor{}, true
 }
 
+// An UntypedActorExtractorMap extracts the raw state of the types of actors that have no registered extractor, so
+// changes to their state are recorded even though it is not parsed.
+type UntypedActorExtractorMap struct{}
+
+func (UntypedActorExtractorMap) Allow(code cid.Cid) bool {
+	_, ok := GetActorStateExtractor(code)
+	return !ok
+}
+
+func (u UntypedActorExtractorMap) GetExtractor(code cid.Cid) (ActorStateExtractor, bool) {
+	if !u.Allow(code) {
+		return nil, false
+	}
+	return RawStateExtractor{}, true
+}
+
 // A TypedActorExtractorMap extracts a single type of actor using full parsing of actor state
 type TypedActorExtractorMap struct {
 	codes *cid.Set
@@ -0,0 +1,127 @@
+package actorstate
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"go.opentelemetry.io/otel/api/global"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
+	"github.com/filecoin-project/sentinel-visor/metrics"
+	"github.com/filecoin-project/sentinel-visor/model"
+	verifregmodel "github.com/filecoin-project/sentinel-visor/model/actors/verifreg"
+)
+
+// VerifiedRegistryExtractor extracts the governance actions of the verified registry root key
+type VerifiedRegistryExtractor struct{}
+
+func init() {
+	for _, c := range verifreg.AllCodes() {
+		Register(c, VerifiedRegistryExtractor{})
+	}
+}
+
+func (VerifiedRegistryExtractor) Extract(ctx context.Context, a ActorInfo, node ActorStateAPI) (model.Persistable, error) {
+	ctx, span := global.Tracer("").Start(ctx, "VerifiedRegistryExtractor")
+	defer span.End()
+
+	stop := metrics.Timer(ctx, metrics.ProcessingDuration)
+	defer stop()
+
+	curState, err := verifreg.Load(node.Store(), &a.Actor)
+	if err != nil {
+		return nil, xerrors.Errorf("loading current verified registry state: %w", err)
+	}
+
+	var prevState verifreg.State
+	if a.Epoch != 0 {
+		prevActor, err := node.StateGetActor(ctx, a.Address, a.ParentTipSet.Key())
+		if err != nil {
+			return nil, xerrors.Errorf("loading previous verified registry actor at tipset %s epoch %d: %w", a.ParentTipSet.Key(), a.Epoch, err)
+		}
+
+		prevState, err = verifreg.Load(node.Store(), prevActor)
+		if err != nil {
+			return nil, xerrors.Errorf("loading previous verified registry state: %w", err)
+		}
+	}
+
+	return ExtractVerifiedRegistryGovernance(a, prevState, curState)
+}
+
+// ExtractVerifiedRegistryGovernance compares the root key and verifiers of the verified registry with their previous
+// state. Every verifier and the root key are reported as added when there is no previous state.
+func ExtractVerifiedRegistryGovernance(a ActorInfo, prevState, curState verifreg.State) (verifregmodel.VerifiedRegistryGovernanceList, error) {
+	rootKey, err := curState.RootKey()
+	if err != nil {
+		return nil, xerrors.Errorf("loading root key: %w", err)
+	}
+
+	parentHeight := int64(a.Epoch)
+	if a.ParentTipSet != nil {
+		parentHeight = int64(a.ParentTipSet.Height())
+	}
+
+	newEvent := func(event string, addr address.Address) *verifregmodel.VerifiedRegistryGovernance {
+		return &verifregmodel.VerifiedRegistryGovernance{
+			Height:       int64(a.Epoch),
+			StateRoot:    a.ParentStateRoot.String(),
+			Event:        event,
+			Address:      addr.String(),
+			ParentHeight: parentHeight,
+			RootKey:      rootKey.String(),
+		}
+	}
+
+	var out verifregmodel.VerifiedRegistryGovernanceList
+
+	prevVerifiers := map[address.Address]abi.StoragePower{}
+	if prevState != nil {
+		prevRootKey, err := prevState.RootKey()
+		if err != nil {
+			return nil, xerrors.Errorf("loading previous root key: %w", err)
+		}
+		if prevRootKey != rootKey {
+			out = append(out, newEvent(verifregmodel.RootKeyChanged, rootKey))
+		}
+
+		if err := prevState.ForEachVerifier(func(addr address.Address, dcap abi.StoragePower) error {
+			prevVerifiers[addr] = dcap
+			return nil
+		}); err != nil {
+			return nil, xerrors.Errorf("walking previous verifiers: %w", err)
+		}
+	} else {
+		out = append(out, newEvent(verifregmodel.RootKeyChanged, rootKey))
+	}
+
+	if err := curState.ForEachVerifier(func(addr address.Address, dcap abi.StoragePower) error {
+		prev, found := prevVerifiers[addr]
+		delete(prevVerifiers, addr)
+		switch {
+		case !found:
+			ev := newEvent(verifregmodel.VerifierAdded, addr)
+			ev.DataCap = dcap.String()
+			out = append(out, ev)
+		case dcap.GreaterThan(prev):
+			ev := newEvent(verifregmodel.VerifierDataCapIncrease, addr)
+			ev.DataCap = dcap.String()
+			ev.PreviousDataCap = prev.String()
+			out = append(out, ev)
+		}
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("walking current verifiers: %w", err)
+	}
+
+	for addr, prev := range prevVerifiers {
+		ev := newEvent(verifregmodel.VerifierRemoved, addr)
+		ev.DataCap = "0"
+		ev.PreviousDataCap = prev.String()
+		out = append(out, ev)
+	}
+
+	return out, nil
+}
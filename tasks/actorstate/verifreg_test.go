@@ -0,0 +1,123 @@
+package actorstate_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	tutils "github.com/filecoin-project/specs-actors/support/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
+	verifregmodel "github.com/filecoin-project/sentinel-visor/model/actors/verifreg"
+	"github.com/filecoin-project/sentinel-visor/tasks/actorstate"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+// fakeVerifregState is a verified registry state holding a root key and verifiers.
+type fakeVerifregState struct {
+	rootKey   address.Address
+	verifiers map[address.Address]abi.StoragePower
+}
+
+var _ verifreg.State = (*fakeVerifregState)(nil)
+
+func (s *fakeVerifregState) MarshalCBOR(w io.Writer) error { return nil }
+
+func (s *fakeVerifregState) RootKey() (address.Address, error) { return s.rootKey, nil }
+
+func (s *fakeVerifregState) VerifiedClientDataCap(address.Address) (bool, abi.StoragePower, error) {
+	return false, big.Zero(), nil
+}
+
+func (s *fakeVerifregState) VerifierDataCap(addr address.Address) (bool, abi.StoragePower, error) {
+	dcap, ok := s.verifiers[addr]
+	return ok, dcap, nil
+}
+
+func (s *fakeVerifregState) ForEachVerifier(cb func(addr address.Address, dcap abi.StoragePower) error) error {
+	for addr, dcap := range s.verifiers {
+		if err := cb(addr, dcap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeVerifregState) ForEachClient(func(addr address.Address, dcap abi.StoragePower) error) error {
+	return nil
+}
+
+func TestExtractVerifiedRegistryGovernance(t *testing.T) {
+	rootKey := tutils.NewIDAddr(t, 80)
+	kept := tutils.NewIDAddr(t, 1000)
+	granting := tutils.NewIDAddr(t, 1001)
+	toppedUp := tutils.NewIDAddr(t, 1002)
+	removed := tutils.NewIDAddr(t, 1003)
+	added := tutils.NewIDAddr(t, 1004)
+
+	info := actorstate.ActorInfo{
+		ParentStateRoot: testutil.RandomCid(),
+		Epoch:           10,
+	}
+
+	prevState := &fakeVerifregState{
+		rootKey: rootKey,
+		verifiers: map[address.Address]abi.StoragePower{
+			kept:     big.NewInt(100),
+			granting: big.NewInt(100),
+			toppedUp: big.NewInt(100),
+			removed:  big.NewInt(100),
+		},
+	}
+	curState := &fakeVerifregState{
+		rootKey: rootKey,
+		verifiers: map[address.Address]abi.StoragePower{
+			kept:     big.NewInt(100),
+			granting: big.NewInt(40), // granted datacap to a client, not a governance action
+			toppedUp: big.NewInt(500),
+			added:    big.NewInt(200),
+		},
+	}
+
+	events, err := actorstate.ExtractVerifiedRegistryGovernance(info, prevState, curState)
+	require.NoError(t, err)
+
+	byAddr := map[string]*verifregmodel.VerifiedRegistryGovernance{}
+	for _, ev := range events {
+		assert.EqualValues(t, 10, ev.Height)
+		assert.Equal(t, info.ParentStateRoot.String(), ev.StateRoot)
+		assert.Equal(t, rootKey.String(), ev.RootKey)
+		byAddr[ev.Address] = ev
+	}
+	require.Len(t, byAddr, 3)
+
+	assert.Equal(t, verifregmodel.VerifierAdded, byAddr[added.String()].Event)
+	assert.Equal(t, "200", byAddr[added.String()].DataCap)
+	assert.Empty(t, byAddr[added.String()].PreviousDataCap)
+
+	assert.Equal(t, verifregmodel.VerifierDataCapIncrease, byAddr[toppedUp.String()].Event)
+	assert.Equal(t, "500", byAddr[toppedUp.String()].DataCap)
+	assert.Equal(t, "100", byAddr[toppedUp.String()].PreviousDataCap)
+
+	assert.Equal(t, verifregmodel.VerifierRemoved, byAddr[removed.String()].Event)
+	assert.Equal(t, "0", byAddr[removed.String()].DataCap)
+	assert.Equal(t, "100", byAddr[removed.String()].PreviousDataCap)
+
+	// A new root key is recorded
+	newRootKey := tutils.NewIDAddr(t, 81)
+	events, err = actorstate.ExtractVerifiedRegistryGovernance(info, prevState, &fakeVerifregState{rootKey: newRootKey, verifiers: prevState.verifiers})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, verifregmodel.RootKeyChanged, events[0].Event)
+	assert.Equal(t, newRootKey.String(), events[0].Address)
+	assert.Equal(t, newRootKey.String(), events[0].RootKey)
+
+	// Without a previous state the root key and every verifier are recorded
+	events, err = actorstate.ExtractVerifiedRegistryGovernance(info, nil, prevState)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/filecoin-project/sentinel-visor/model"
 	"github.com/filecoin-project/sentinel-visor/model/blocks"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
 	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
 )
 
@@ -38,6 +39,7 @@ func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persi
 		pl = append(pl, blocks.NewBlockParents(bh))
 		pl = append(pl, blocks.NewDrandBlockEntries(bh))
 	}
+	pl = append(pl, chainmodel.NewEpochTimestamp(ts))
 
 	report := &visormodel.ProcessingReport{
 		Height:    int64(ts.Height()),
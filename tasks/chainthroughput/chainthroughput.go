@@ -0,0 +1,120 @@
+// Package chainthroughput provides a task for recording message throughput and block space utilization
+package chainthroughput
+
+import (
+	"context"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+type Task struct{}
+
+func NewTask() *Task {
+	return &Task{}
+}
+
+func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types.TipSet, _ []*lens.ExecutedMessage, blkMsgs []*lens.BlockMessages) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessChainThroughput")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(pts.Height()),
+		StateRoot: pts.ParentState().String(),
+	}
+
+	tp := &chainmodel.ChainThroughput{
+		Height:           int64(pts.Height()),
+		StateRoot:        pts.ParentState().String(),
+		BlockCount:       int64(len(pts.Blocks())),
+		GasLimitCapacity: int64(len(pts.Blocks())) * build.BlockGasLimit,
+	}
+
+	seen := make(map[cid.Cid]bool)
+	errorsDetected := make([]*MessageError, 0)
+	for _, bm := range blkMsgs {
+		// Stop processing if we have been told to cancel
+		select {
+		case <-ctx.Done():
+			return nil, nil, xerrors.Errorf("context done: %w", ctx.Err())
+		default:
+		}
+
+		for _, msg := range bm.SecpMessages {
+			size, err := serializedSize(msg)
+			if err != nil {
+				errorsDetected = append(errorsDetected, &MessageError{Cid: msg.Cid(), Error: err.Error()})
+			}
+			tp.IncludedMessageCount++
+			tp.IncludedMessageBytes += size
+
+			if seen[msg.Cid()] {
+				continue
+			}
+			seen[msg.Cid()] = true
+			tp.SecpMessageCount++
+			tp.MessageBytes += size
+			tp.GasLimitUniqueTotal += msg.Message.GasLimit
+		}
+		for _, msg := range bm.BlsMessages {
+			size, err := serializedSize(msg)
+			if err != nil {
+				errorsDetected = append(errorsDetected, &MessageError{Cid: msg.Cid(), Error: err.Error()})
+			}
+			tp.IncludedMessageCount++
+			tp.IncludedMessageBytes += size
+
+			if seen[msg.Cid()] {
+				continue
+			}
+			seen[msg.Cid()] = true
+			tp.BlsMessageCount++
+			tp.MessageBytes += size
+			tp.GasLimitUniqueTotal += msg.GasLimit
+		}
+	}
+
+	if tp.GasLimitCapacity > 0 {
+		tp.Utilization = float64(tp.GasLimitUniqueTotal) / float64(tp.GasLimitCapacity)
+	}
+
+	if len(errorsDetected) != 0 {
+		report.ErrorsDetected = errorsDetected
+	}
+
+	return tp, report, nil
+}
+
+type serializer interface {
+	Serialize() ([]byte, error)
+}
+
+// serializedSize returns the size of the message as it is stored on chain.
+func serializedSize(msg serializer) (int64, error) {
+	b, err := msg.Serialize()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to serialize message: %w", err)
+	}
+	return int64(len(b)), nil
+}
+
+func (p *Task) Close() error {
+	return nil
+}
+
+type MessageError struct {
+	Cid   cid.Cid
+	Error string
+}
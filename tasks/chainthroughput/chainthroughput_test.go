@@ -0,0 +1,69 @@
+package chainthroughput
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	tutils "github.com/filecoin-project/specs-actors/support/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func message(t *testing.T, nonce uint64, gasLimit int64) *types.Message {
+	return &types.Message{
+		To:         tutils.NewIDAddr(t, 1000),
+		From:       tutils.NewIDAddr(t, 1001),
+		Nonce:      nonce,
+		Value:      abi.NewTokenAmount(0),
+		GasLimit:   gasLimit,
+		GasFeeCap:  abi.NewTokenAmount(100),
+		GasPremium: abi.NewTokenAmount(10),
+	}
+}
+
+func size(t *testing.T, msg serializer) int64 {
+	n, err := serializedSize(msg)
+	require.NoError(t, err)
+	return n
+}
+
+func TestProcessMessages(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+
+	bls1 := message(t, 1, 1000)
+	bls2 := message(t, 2, 2000)
+	secp := &types.SignedMessage{
+		Message:   *message(t, 3, 4000),
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: make([]byte, 65)},
+	}
+
+	// bls1 and secp are included in both blocks
+	data, report, err := NewTask().ProcessMessages(context.Background(), ts, ts, nil, []*lens.BlockMessages{
+		{BlsMessages: []*types.Message{bls1, bls2}, SecpMessages: []*types.SignedMessage{secp}},
+		{BlsMessages: []*types.Message{bls1}, SecpMessages: []*types.SignedMessage{secp}},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, report.ErrorsDetected)
+
+	tp := data.(*chainmodel.ChainThroughput)
+	assert.Equal(t, int64(1), tp.BlockCount)
+	assert.Equal(t, int64(2), tp.BlsMessageCount)
+	assert.Equal(t, int64(1), tp.SecpMessageCount)
+	assert.Equal(t, int64(5), tp.IncludedMessageCount)
+
+	unique := size(t, bls1) + size(t, bls2) + size(t, secp)
+	assert.Equal(t, unique, tp.MessageBytes)
+	assert.Equal(t, unique+size(t, bls1)+size(t, secp), tp.IncludedMessageBytes)
+
+	assert.Equal(t, int64(7000), tp.GasLimitUniqueTotal)
+	assert.Equal(t, build.BlockGasLimit, tp.GasLimitCapacity)
+	assert.InDelta(t, 7000/float64(build.BlockGasLimit), tp.Utilization, 1e-12)
+}
@@ -0,0 +1,95 @@
+// Package gasbymethod provides a task for aggregating the gas used by messages according to the method they invoked
+package gasbymethod
+
+import (
+	"context"
+	"sort"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	messagemodel "github.com/filecoin-project/sentinel-visor/model/messages"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+type Task struct{}
+
+func NewTask() *Task {
+	return &Task{}
+}
+
+type methodKey struct {
+	family string
+	method uint64
+}
+
+func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types.TipSet, emsgs []*lens.ExecutedMessage, _ []*lens.BlockMessages) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessGasByMethod")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(pts.Height()),
+		StateRoot: pts.ParentState().String(),
+	}
+
+	seen := make(map[cid.Cid]bool, len(emsgs))
+	byMethod := make(map[methodKey]*messagemodel.MessageGasByMethod)
+	for _, m := range emsgs {
+		// Stop processing if we have been told to cancel
+		select {
+		case <-ctx.Done():
+			return nil, nil, xerrors.Errorf("context done: %w", ctx.Err())
+		default:
+		}
+
+		// A message may be included in more than one block of the tipset but is only executed once
+		if seen[m.Cid] {
+			continue
+		}
+		seen[m.Cid] = true
+
+		key := methodKey{
+			family: builtin.ActorFamily(builtin.ActorNameByCode(m.ToActorCode)),
+			method: uint64(m.Message.Method),
+		}
+		agg, ok := byMethod[key]
+		if !ok {
+			agg = &messagemodel.MessageGasByMethod{
+				Height:      int64(pts.Height()),
+				StateRoot:   pts.ParentState().String(),
+				ActorFamily: key.family,
+				Method:      key.method,
+			}
+			byMethod[key] = agg
+		}
+		agg.MessageCount++
+		agg.GasLimit += m.Message.GasLimit
+		agg.GasUsed += m.Receipt.GasUsed
+	}
+
+	results := make(messagemodel.MessageGasByMethodList, 0, len(byMethod))
+	for _, agg := range byMethod {
+		results = append(results, agg)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].ActorFamily != results[j].ActorFamily {
+			return results[i].ActorFamily < results[j].ActorFamily
+		}
+		return results[i].Method < results[j].Method
+	})
+
+	return results, report, nil
+}
+
+func (p *Task) Close() error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package gasbymethod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	sa0builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	messagemodel "github.com/filecoin-project/sentinel-visor/model/messages"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func executed(c cid.Cid, code cid.Cid, method abi.MethodNum, limit, used int64) *lens.ExecutedMessage {
+	return &lens.ExecutedMessage{
+		Cid:         c,
+		Message:     &types.Message{Method: method, GasLimit: limit},
+		Receipt:     &types.MessageReceipt{GasUsed: used},
+		ToActorCode: code,
+	}
+}
+
+func TestProcessMessages(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	dup := testutil.RandomCid()
+
+	data, report, err := NewTask().ProcessMessages(context.Background(), ts, ts, []*lens.ExecutedMessage{
+		executed(testutil.RandomCid(), sa0builtin.StorageMinerActorCodeID, 5, 100, 80),
+		executed(dup, sa0builtin.StorageMinerActorCodeID, 5, 200, 150),
+		executed(dup, sa0builtin.StorageMinerActorCodeID, 5, 200, 150),
+		executed(testutil.RandomCid(), sa0builtin.AccountActorCodeID, 0, 10, 5),
+	}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, report.ErrorsDetected)
+
+	rows := data.(messagemodel.MessageGasByMethodList)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "account", rows[0].ActorFamily)
+	assert.Equal(t, int64(1), rows[0].MessageCount)
+
+	assert.Equal(t, "storageminer", rows[1].ActorFamily)
+	assert.Equal(t, uint64(5), rows[1].Method)
+	assert.Equal(t, int64(2), rows[1].MessageCount, "duplicate messages are counted once")
+	assert.Equal(t, int64(300), rows[1].GasLimit)
+	assert.Equal(t, int64(230), rows[1].GasUsed)
+}
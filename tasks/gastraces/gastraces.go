@@ -0,0 +1,134 @@
+// Package gastraces provides a task for recording the gas charged by each call made while executing messages
+package gastraces
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	messagemodel "github.com/filecoin-project/sentinel-visor/model/messages"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+// Task replays the messages of each tipset to obtain their execution traces and records the gas charges in them.
+// Replaying requires a lens backed by a full node holding the parent state of the tipset.
+type Task struct {
+	nodeMu sync.Mutex // guards mutations to node, opener and closer
+	node   lens.API
+	opener lens.APIOpener
+	closer lens.APICloser
+}
+
+func NewTask(opener lens.APIOpener) *Task {
+	return &Task{
+		opener: opener,
+	}
+}
+
+func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessGasTraces")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	// We use p.node continually through this method so take a broad lock
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.node == nil {
+		node, closer, err := p.opener.Open(ctx)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to open lens: %w", err)
+		}
+		p.node = node
+		p.closer = closer
+	}
+
+	tracer, ok := p.node.(lens.ExecutionTraceAPI)
+	if !ok {
+		return nil, nil, xerrors.Errorf("lens does not support execution traces")
+	}
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+
+	out, err := tracer.StateCompute(ctx, ts.Height(), nil, ts.Key())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("compute state: %w", err)
+	}
+
+	var traces messagemodel.MessageGasTraceList
+	for _, res := range out.Trace {
+		traces = append(traces, GasTraces(ts, res.MsgCid, res.ExecutionTrace)...)
+	}
+	return traces, report, nil
+}
+
+// GasTraces returns the gas charges in the execution trace of a message included in ts. Calls are visited depth first
+// so they are numbered in the order they were made.
+func GasTraces(ts *types.TipSet, msg cid.Cid, et types.ExecutionTrace) messagemodel.MessageGasTraceList {
+	var out messagemodel.MessageGasTraceList
+	calls := 0
+
+	var walk func(et types.ExecutionTrace, depth int)
+	walk = func(et types.ExecutionTrace, depth int) {
+		callIndex := calls
+		calls++
+
+		var to string
+		var method uint64
+		if et.Msg != nil {
+			to = et.Msg.To.String()
+			method = uint64(et.Msg.Method)
+		}
+
+		for i, gc := range et.GasCharges {
+			if gc == nil {
+				continue
+			}
+			out = append(out, &messagemodel.MessageGasTrace{
+				Height:      int64(ts.Height()),
+				StateRoot:   ts.ParentState().String(),
+				Message:     msg.String(),
+				CallIndex:   callIndex,
+				ChargeIndex: i,
+				CallDepth:   depth,
+				To:          to,
+				Method:      method,
+				Name:        gc.Name,
+				TotalGas:    gc.TotalGas,
+				ComputeGas:  gc.ComputeGas,
+				StorageGas:  gc.StorageGas,
+			})
+		}
+
+		for _, sub := range et.Subcalls {
+			walk(sub, depth+1)
+		}
+	}
+	walk(et, 0)
+
+	return out
+}
+
+func (p *Task) Close() error {
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.closer != nil {
+		p.closer()
+		p.closer = nil
+	}
+	p.node = nil
+	return nil
+}
@@ -0,0 +1,64 @@
+package gastraces
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestGasTraces(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	msg := testutil.RandomCid()
+
+	caller, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	callee, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	et := types.ExecutionTrace{
+		Msg: &types.Message{To: caller, Method: abi.MethodNum(2)},
+		GasCharges: []*types.GasTrace{
+			{Name: "OnChainMessage", TotalGas: 30, ComputeGas: 10, StorageGas: 20},
+		},
+		Subcalls: []types.ExecutionTrace{
+			{
+				Msg: &types.Message{To: callee, Method: abi.MethodNum(5)},
+				GasCharges: []*types.GasTrace{
+					{Name: "OnMethodInvocation", TotalGas: 5, ComputeGas: 5},
+					{Name: "OnIpldGet", TotalGas: 7, ComputeGas: 7},
+				},
+			},
+			{
+				// calls without charges are still numbered
+				Msg: &types.Message{To: callee, Method: abi.MethodNum(6)},
+			},
+		},
+	}
+
+	traces := GasTraces(ts, msg, et)
+	require.Len(t, traces, 3)
+
+	assert.EqualValues(t, ts.Height(), traces[0].Height)
+	assert.Equal(t, ts.ParentState().String(), traces[0].StateRoot)
+	assert.Equal(t, msg.String(), traces[0].Message)
+	assert.Equal(t, 0, traces[0].CallIndex)
+	assert.Equal(t, 0, traces[0].CallDepth)
+	assert.Equal(t, caller.String(), traces[0].To)
+	assert.EqualValues(t, 2, traces[0].Method)
+	assert.Equal(t, "OnChainMessage", traces[0].Name)
+	assert.EqualValues(t, 30, traces[0].TotalGas)
+	assert.EqualValues(t, 10, traces[0].ComputeGas)
+	assert.EqualValues(t, 20, traces[0].StorageGas)
+
+	assert.Equal(t, 1, traces[2].CallIndex)
+	assert.Equal(t, 1, traces[2].ChargeIndex)
+	assert.Equal(t, 1, traces[2].CallDepth)
+	assert.Equal(t, callee.String(), traces[2].To)
+	assert.Equal(t, "OnIpldGet", traces[2].Name)
+}
@@ -0,0 +1,158 @@
+// Package marketsnapshots provides a task for periodically recording every deal held by the storage market
+package marketsnapshots
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	logging "github.com/ipfs/go-log/v2"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	marketmodel "github.com/filecoin-project/sentinel-visor/model/actors/market"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+var log = logging.Logger("visor/task/marketsnapshots")
+
+// Interval is the number of epochs between snapshots. Snapshots are taken at heights that are a multiple of the
+// interval so that independent instances snapshot the same heights. Zero disables snapshots.
+var Interval int64 = 2880
+
+// StateLens is the part of the lens used to read the market state.
+type StateLens interface {
+	StateGetActor(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error)
+	Store() adt.Store
+}
+
+type Task struct {
+	nodeMu sync.Mutex // guards mutations to node, opener and closer
+	node   lens.API
+	opener lens.APIOpener
+	closer lens.APICloser
+}
+
+func NewTask(opener lens.APIOpener) *Task {
+	return &Task{
+		opener: opener,
+	}
+}
+
+func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+	if Interval <= 0 || int64(ts.Height())%Interval != 0 {
+		report.StatusInformation = "not a snapshot height"
+		return nil, report, nil
+	}
+
+	ctx, span := global.Tracer("").Start(ctx, "ProcessMarketSnapshots")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	// We use p.node continually through this method so take a broad lock
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.node == nil {
+		node, closer, err := p.opener.Open(ctx)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to open lens: %w", err)
+		}
+		p.node = node
+		p.closer = closer
+	}
+
+	deals, err := ExtractSnapshot(ctx, p.node, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Infow("snapshotted market deals", "height", ts.Height(), "deals", len(deals))
+
+	return deals, report, nil
+}
+
+// ExtractSnapshot returns every deal held by the storage market in the parent state of ts.
+func ExtractSnapshot(ctx context.Context, node StateLens, ts *types.TipSet) (marketmodel.MarketDealSnapshotList, error) {
+	act, err := node.StateGetActor(ctx, market.Address, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("get market actor: %w", err)
+	}
+	st, err := market.Load(node.Store(), act)
+	if err != nil {
+		return nil, xerrors.Errorf("load market state: %w", err)
+	}
+
+	proposals, err := st.Proposals()
+	if err != nil {
+		return nil, xerrors.Errorf("load deal proposals: %w", err)
+	}
+	states, err := st.States()
+	if err != nil {
+		return nil, xerrors.Errorf("load deal states: %w", err)
+	}
+
+	var out marketmodel.MarketDealSnapshotList
+	if err := proposals.ForEach(func(id abi.DealID, dp market.DealProposal) error {
+		ds, found, err := states.Get(id)
+		if err != nil {
+			return xerrors.Errorf("get state of deal %d: %w", id, err)
+		}
+		if !found {
+			// The deal has not been activated yet
+			ds = market.EmptyDealState()
+		}
+		out = append(out, DealSnapshot(ts, id, dp, *ds))
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("walking deal proposals: %w", err)
+	}
+	return out, nil
+}
+
+// DealSnapshot converts a deal in the parent state of ts to a snapshot model.
+func DealSnapshot(ts *types.TipSet, id abi.DealID, dp market.DealProposal, ds market.DealState) *marketmodel.MarketDealSnapshot {
+	return &marketmodel.MarketDealSnapshot{
+		Height:               int64(ts.Height()),
+		DealID:               uint64(id),
+		StateRoot:            ts.ParentState().String(),
+		PieceCID:             dp.PieceCID.String(),
+		PaddedPieceSize:      uint64(dp.PieceSize),
+		UnpaddedPieceSize:    uint64(dp.PieceSize.Unpadded()),
+		IsVerified:           dp.VerifiedDeal,
+		ClientID:             dp.Client.String(),
+		ProviderID:           dp.Provider.String(),
+		StartEpoch:           int64(dp.StartEpoch),
+		EndEpoch:             int64(dp.EndEpoch),
+		StoragePricePerEpoch: model.TokenAmount(dp.StoragePricePerEpoch),
+		ProviderCollateral:   model.TokenAmount(dp.ProviderCollateral),
+		ClientCollateral:     model.TokenAmount(dp.ClientCollateral),
+		SectorStartEpoch:     int64(ds.SectorStartEpoch),
+		LastUpdateEpoch:      int64(ds.LastUpdatedEpoch),
+		SlashEpoch:           int64(ds.SlashEpoch),
+	}
+}
+
+func (p *Task) Close() error {
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.closer != nil {
+		p.closer()
+		p.closer = nil
+	}
+	p.node = nil
+	return nil
+}
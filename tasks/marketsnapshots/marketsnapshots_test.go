@@ -0,0 +1,51 @@
+package marketsnapshots
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestDealSnapshot(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	client, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	provider, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	dp := market.DealProposal{
+		PieceCID:             testutil.RandomCid(),
+		PieceSize:            abi.PaddedPieceSize(2048),
+		VerifiedDeal:         true,
+		Client:               client,
+		Provider:             provider,
+		StartEpoch:           100,
+		EndEpoch:             200,
+		StoragePricePerEpoch: abi.NewTokenAmount(3),
+		ProviderCollateral:   abi.NewTokenAmount(4),
+	}
+
+	// A deal that has not been activated has the empty state
+	ds := DealSnapshot(ts, 42, dp, *market.EmptyDealState())
+	assert.EqualValues(t, ts.Height(), ds.Height)
+	assert.Equal(t, ts.ParentState().String(), ds.StateRoot)
+	assert.EqualValues(t, 42, ds.DealID)
+	assert.EqualValues(t, 2048, ds.PaddedPieceSize)
+	assert.EqualValues(t, 2032, ds.UnpaddedPieceSize)
+	assert.Equal(t, client.String(), ds.ClientID)
+	assert.Equal(t, provider.String(), ds.ProviderID)
+	assert.Equal(t, "3", ds.StoragePricePerEpoch)
+	assert.Equal(t, "0", ds.ClientCollateral, "zero value amounts are persisted as zero")
+	assert.EqualValues(t, -1, ds.SectorStartEpoch)
+	assert.EqualValues(t, -1, ds.SlashEpoch)
+
+	ds = DealSnapshot(ts, 42, dp, market.DealState{SectorStartEpoch: 90, LastUpdatedEpoch: 150, SlashEpoch: -1})
+	assert.EqualValues(t, 90, ds.SectorStartEpoch)
+	assert.EqualValues(t, 150, ds.LastUpdateEpoch)
+}
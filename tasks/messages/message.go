@@ -51,6 +51,7 @@ func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types
 		receiptResults       = make(messagemodel.Receipts, 0, len(emsgs))
 		parsedMessageResults = make(messagemodel.ParsedMessages, 0, len(emsgs))
 		gasOutputsResults    = make(derivedmodel.GasOutputsList, 0, len(emsgs))
+		messageHeightResults = make(messagemodel.MessageHeights, 0, len(emsgs))
 		errorsDetected       = make([]*MessageError, 0, len(emsgs))
 	)
 
@@ -180,16 +181,37 @@ func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types
 			})
 		}
 
+		// The message was included and executed in the parent tipset, the result of execution is in the parent
+		// state of the child tipset
 		rcpt := &messagemodel.Receipt{
-			Height:    int64(ts.Height()), // this is the child height
-			Message:   m.Cid.String(),
-			StateRoot: ts.ParentState().String(),
-			Idx:       int(m.Index),
-			ExitCode:  int64(m.Receipt.ExitCode),
-			GasUsed:   m.Receipt.GasUsed,
+			Height:             int64(ts.Height()), // this is the child height
+			Message:            m.Cid.String(),
+			StateRoot:          ts.ParentState().String(),
+			Idx:                int(m.Index),
+			ExitCode:           int64(m.Receipt.ExitCode),
+			GasUsed:            m.Receipt.GasUsed,
+			RawReturn:          m.Receipt.Return,
+			InclusionHeight:    int64(pts.Height()),
+			InclusionStateRoot: pts.ParentState().String(),
+		}
+		if parsedReturn, err := p.parseReturn(m.Receipt.Return); err == nil {
+			rcpt.ParsedReturn = parsedReturn
+		} else {
+			errorsDetected = append(errorsDetected, &MessageError{
+				Cid:   m.Cid,
+				Error: xerrors.Errorf("failed to parse return value: %w", err).Error(),
+			})
 		}
 		receiptResults = append(receiptResults, rcpt)
 
+		messageHeightResults = append(messageHeightResults, &messagemodel.MessageHeight{
+			Cid:             m.Cid.String(),
+			Height:          int64(pts.Height()),
+			StateRoot:       pts.ParentState().String(),
+			ExecutionHeight: int64(ts.Height()),
+			ExecutionTipSet: visormodel.EncodeTipSetKey(ts.Key()),
+		})
+
 		actorName := builtin.ActorNameByCode(m.ToActorCode)
 		gasOutput := &derivedmodel.GasOutputs{
 			Height:             int64(m.Height),
@@ -222,13 +244,14 @@ func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types
 		method, params, err := p.parseMessageParams(m.Message, m.ToActorCode)
 		if err == nil {
 			pm := &messagemodel.ParsedMessage{
-				Height: int64(m.Height),
-				Cid:    m.Cid.String(),
-				From:   m.Message.From.String(),
-				To:     m.Message.To.String(),
-				Value:  m.Message.Value.String(),
-				Method: method,
-				Params: params,
+				Height:    int64(m.Height),
+				Cid:       m.Cid.String(),
+				From:      m.Message.From.String(),
+				To:        m.Message.To.String(),
+				Value:     m.Message.Value.String(),
+				Method:    method,
+				Params:    params,
+				RawParams: m.Message.Params,
 			}
 			parsedMessageResults = append(parsedMessageResults, pm)
 		} else {
@@ -269,6 +292,7 @@ func (p *Task) ProcessMessages(ctx context.Context, ts *types.TipSet, pts *types
 		parsedMessageResults,
 		gasOutputsResults,
 		messageGasEconomyResult,
+		messageHeightResults,
 	}, report, nil
 }
 
@@ -295,16 +319,37 @@ func (p *Task) parseMessageParams(m *types.Message, destCode cid.Cid) (string, s
 		return method, "", nil
 	}
 
-	buf := bytes.NewBuffer(nil)
-	if err := fcjson.Encoder(params, buf); err != nil {
-		return "", "", xerrors.Errorf("json encode: %w", err)
+	encoded, err := encodeJSON(params)
+	if err != nil {
+		return "", "", err
 	}
 
-	encoded := string(bytes.ReplaceAll(bytes.ToValidUTF8(buf.Bytes(), []byte{}), []byte{0x00}, []byte{}))
-
 	return method, encoded, nil
 }
 
+// parseReturn decodes the value returned by a message and encodes it as JSON.
+func (p *Task) parseReturn(ret []byte) (string, error) {
+	node, err := ParseReturn(ret)
+	if err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", nil
+	}
+
+	return encodeJSON(node)
+}
+
+// encodeJSON encodes a decoded ipld node as JSON suitable for storing in a jsonb column.
+func encodeJSON(n ipld.Node) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := fcjson.Encoder(n, buf); err != nil {
+		return "", xerrors.Errorf("json encode: %w", err)
+	}
+
+	return string(bytes.ReplaceAll(bytes.ToValidUTF8(buf.Bytes(), []byte{}), []byte{0x00}, []byte{})), nil
+}
+
 func (p *Task) Close() error {
 	return nil
 }
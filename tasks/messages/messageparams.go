@@ -250,3 +250,18 @@ func ParseParams(params []byte, method int64, destType cid.Cid) (ipld.Node, stri
 
 	return builder.Build(), name, nil
 }
+
+// ParseReturn decodes the value returned by a method. Return types are not described by the method tables so the
+// value is decoded generically, which preserves its structure but not the names of its fields.
+func ParseReturn(ret []byte) (ipld.Node, error) {
+	if len(ret) == 0 {
+		return nil, nil
+	}
+
+	builder := types.Type.Any__Repr.NewBuilder()
+	if err := dagcbor.Decoder(builder, bytes.NewBuffer(ret)); err != nil {
+		return nil, fmt.Errorf("cbor decode return value failed: %v", err)
+	}
+
+	return builder.Build(), nil
+}
@@ -0,0 +1,202 @@
+// Package minersnapshots provides a task for periodically recording every sector and precommit held by every miner
+package minersnapshots
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	logging "github.com/ipfs/go-log/v2"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	minermodel "github.com/filecoin-project/sentinel-visor/model/actors/miner"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/tasks/actorstate"
+)
+
+var log = logging.Logger("visor/task/minersnapshots")
+
+// Interval is the number of epochs between snapshots. Snapshots are taken at heights that are a multiple of the
+// interval so that independent instances snapshot the same heights. Zero disables snapshots.
+var Interval int64 = 2880
+
+// IsSnapshotHeight reports whether a snapshot is taken at height with the given interval.
+func IsSnapshotHeight(height abi.ChainEpoch, interval int64) bool {
+	return interval > 0 && int64(height)%interval == 0
+}
+
+// StateLens is the part of the lens used to read miner states.
+type StateLens interface {
+	StateGetActor(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error)
+	Store() adt.Store
+}
+
+type Task struct {
+	nodeMu sync.Mutex // guards mutations to node, opener and closer
+	node   lens.API
+	opener lens.APIOpener
+	closer lens.APICloser
+}
+
+func NewTask(opener lens.APIOpener) *Task {
+	return &Task{
+		opener: opener,
+	}
+}
+
+func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+	if !IsSnapshotHeight(ts.Height(), Interval) {
+		report.StatusInformation = "not a snapshot height"
+		return nil, report, nil
+	}
+
+	ctx, span := global.Tracer("").Start(ctx, "ProcessMinerSnapshots")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	// We use p.node continually through this method so take a broad lock
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.node == nil {
+		node, closer, err := p.opener.Open(ctx)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to open lens: %w", err)
+		}
+		p.node = node
+		p.closer = closer
+	}
+
+	snapshot, err := ExtractSnapshot(ctx, p.node, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Infow("snapshotted miners", "height", ts.Height(), "sectors", len(snapshot.Sectors), "precommits", len(snapshot.PreCommits))
+
+	return snapshot, report, nil
+}
+
+// ExtractSnapshot returns the sectors and precommits of every miner with a claim in the power actor in the parent
+// state of ts.
+func ExtractSnapshot(ctx context.Context, node StateLens, ts *types.TipSet) (*minermodel.MinerSnapshot, error) {
+	pact, err := node.StateGetActor(ctx, power.Address, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("get power actor: %w", err)
+	}
+	pst, err := power.Load(node.Store(), pact)
+	if err != nil {
+		return nil, xerrors.Errorf("load power state: %w", err)
+	}
+	miners, err := pst.ListAllMiners()
+	if err != nil {
+		return nil, xerrors.Errorf("list miners: %w", err)
+	}
+
+	snapshot := &minermodel.MinerSnapshot{}
+	for _, addr := range miners {
+		// Stop processing if we have been told to cancel
+		select {
+		case <-ctx.Done():
+			return nil, xerrors.Errorf("context done: %w", ctx.Err())
+		default:
+		}
+
+		act, err := node.StateGetActor(ctx, addr, ts.Key())
+		if err != nil {
+			return nil, xerrors.Errorf("get miner actor %s: %w", addr, err)
+		}
+		mst, err := miner.Load(node.Store(), act)
+		if err != nil {
+			return nil, xerrors.Errorf("load miner state %s: %w", addr, err)
+		}
+
+		sectors, err := mst.LoadSectors(nil)
+		if err != nil {
+			return nil, xerrors.Errorf("load sectors of %s: %w", addr, err)
+		}
+		snapshot.Sectors = append(snapshot.Sectors, SectorSnapshots(ts, addr, sectors)...)
+
+		precommits, err := miner.LoadPreCommits(mst)
+		if err != nil {
+			return nil, xerrors.Errorf("load precommits of %s: %w", addr, err)
+		}
+		snapshot.PreCommits = append(snapshot.PreCommits, PreCommitSnapshots(ts, addr, precommits)...)
+	}
+	return snapshot, nil
+}
+
+// SectorSnapshots converts the sectors of a miner in the parent state of ts to snapshot models.
+func SectorSnapshots(ts *types.TipSet, addr address.Address, sectors []*miner.SectorOnChainInfo) minermodel.MinerSectorSnapshotList {
+	out := make(minermodel.MinerSectorSnapshotList, 0, len(sectors))
+	for _, sector := range sectors {
+		out = append(out, &minermodel.MinerSectorSnapshot{
+			Height:                int64(ts.Height()),
+			MinerID:               addr.String(),
+			SectorID:              uint64(sector.SectorNumber),
+			StateRoot:             ts.ParentState().String(),
+			SealedCID:             sector.SealedCID.String(),
+			ActivationEpoch:       int64(sector.Activation),
+			ExpirationEpoch:       int64(sector.Expiration),
+			DealWeight:            sector.DealWeight.String(),
+			VerifiedDealWeight:    sector.VerifiedDealWeight.String(),
+			InitialPledge:         sector.InitialPledge.String(),
+			ExpectedDayReward:     sector.ExpectedDayReward.String(),
+			ExpectedStoragePledge: sector.ExpectedStoragePledge.String(),
+			SealProof:             int64(sector.SealProof),
+			QAPower:               actorstate.SectorQAPower(*sector).String(),
+		})
+	}
+	return out
+}
+
+// PreCommitSnapshots converts the precommits of a miner in the parent state of ts to snapshot models.
+func PreCommitSnapshots(ts *types.TipSet, addr address.Address, precommits []miner.SectorPreCommitOnChainInfo) minermodel.MinerPreCommitSnapshotList {
+	out := make(minermodel.MinerPreCommitSnapshotList, 0, len(precommits))
+	for _, pc := range precommits {
+		out = append(out, &minermodel.MinerPreCommitSnapshot{
+			Height:                 int64(ts.Height()),
+			MinerID:                addr.String(),
+			SectorID:               uint64(pc.Info.SectorNumber),
+			StateRoot:              ts.ParentState().String(),
+			SealedCID:              pc.Info.SealedCID.String(),
+			SealRandEpoch:          int64(pc.Info.SealRandEpoch),
+			ExpirationEpoch:        int64(pc.Info.Expiration),
+			PreCommitDeposit:       pc.PreCommitDeposit.String(),
+			PreCommitEpoch:         int64(pc.PreCommitEpoch),
+			DealWeight:             pc.DealWeight.String(),
+			VerifiedDealWeight:     pc.VerifiedDealWeight.String(),
+			IsReplaceCapacity:      pc.Info.ReplaceCapacity,
+			ReplaceSectorDeadline:  pc.Info.ReplaceSectorDeadline,
+			ReplaceSectorPartition: pc.Info.ReplaceSectorPartition,
+			ReplaceSectorNumber:    uint64(pc.Info.ReplaceSectorNumber),
+		})
+	}
+	return out
+}
+
+func (p *Task) Close() error {
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.closer != nil {
+		p.closer()
+		p.closer = nil
+	}
+	p.node = nil
+	return nil
+}
@@ -0,0 +1,67 @@
+package minersnapshots
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/miner"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+func TestIsSnapshotHeight(t *testing.T) {
+	assert.True(t, IsSnapshotHeight(0, 2880))
+	assert.True(t, IsSnapshotHeight(5760, 2880))
+	assert.False(t, IsSnapshotHeight(5761, 2880))
+	assert.False(t, IsSnapshotHeight(5760, 0), "zero interval disables snapshots")
+}
+
+func TestSnapshotModels(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	addr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	sectors := SectorSnapshots(ts, addr, []*miner.SectorOnChainInfo{
+		{
+			SectorNumber:          7,
+			SealProof:             abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+			SealedCID:             testutil.RandomCid(),
+			Activation:            100,
+			Expiration:            200,
+			DealWeight:            big.Zero(),
+			VerifiedDealWeight:    big.Zero(),
+			InitialPledge:         abi.NewTokenAmount(10),
+			ExpectedDayReward:     abi.NewTokenAmount(1),
+			ExpectedStoragePledge: abi.NewTokenAmount(2),
+		},
+	})
+	require.Len(t, sectors, 1)
+	assert.EqualValues(t, ts.Height(), sectors[0].Height)
+	assert.Equal(t, ts.ParentState().String(), sectors[0].StateRoot)
+	assert.Equal(t, addr.String(), sectors[0].MinerID)
+	assert.EqualValues(t, 7, sectors[0].SectorID)
+	assert.Equal(t, "10", sectors[0].InitialPledge)
+	assert.Equal(t, "34359738368", sectors[0].QAPower, "a sector without deals has its raw size as power")
+
+	precommits := PreCommitSnapshots(ts, addr, []miner.SectorPreCommitOnChainInfo{
+		{
+			Info: miner.SectorPreCommitInfo{
+				SectorNumber: 8,
+				SealedCID:    testutil.RandomCid(),
+				Expiration:   300,
+			},
+			PreCommitDeposit:   abi.NewTokenAmount(5),
+			PreCommitEpoch:     150,
+			DealWeight:         big.Zero(),
+			VerifiedDealWeight: big.Zero(),
+		},
+	})
+	require.Len(t, precommits, 1)
+	assert.EqualValues(t, 8, precommits[0].SectorID)
+	assert.Equal(t, "5", precommits[0].PreCommitDeposit)
+	assert.EqualValues(t, 150, precommits[0].PreCommitEpoch)
+}
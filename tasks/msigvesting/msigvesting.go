@@ -0,0 +1,212 @@
+// Package msigvesting provides a task for recording the vesting balances of genesis multisigs
+package msigvesting
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	logging "github.com/ipfs/go-log/v2"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/multisig"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	multisigmodel "github.com/filecoin-project/sentinel-visor/model/actors/multisig"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+var log = logging.Logger("visor/task/msigvesting")
+
+type Task struct {
+	nodeMu sync.Mutex // guards mutations to node, opener and closer
+	node   lens.API
+	opener lens.APIOpener
+	closer lens.APICloser
+
+	vesting []address.Address // multisigs with a vesting schedule in the genesis state, nil until discovered
+}
+
+func NewTask(opener lens.APIOpener) *Task {
+	return &Task{
+		opener: opener,
+	}
+}
+
+func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessMultisigVesting")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	// We use p.node continually through this method so take a broad lock
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.node == nil {
+		node, closer, err := p.opener.Open(ctx)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to open lens: %w", err)
+		}
+		p.node = node
+		p.closer = closer
+	}
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+
+	if p.vesting == nil {
+		vesting, err := genesisVestingMultisigs(ctx, p.node)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("find genesis vesting multisigs: %w", err)
+		}
+		log.Infow("found genesis vesting multisigs", "count", len(vesting))
+		p.vesting = vesting
+	}
+
+	results := make(multisigmodel.MultisigVestingList, 0, len(p.vesting))
+	for _, addr := range p.vesting {
+		// Stop processing if we have been told to cancel
+		select {
+		case <-ctx.Done():
+			return nil, nil, xerrors.Errorf("context done: %w", ctx.Err())
+		default:
+		}
+
+		act, err := p.node.StateGetActor(ctx, addr, ts.Key())
+		if err != nil {
+			// The multisig may have been removed from the state tree since genesis
+			if errors.Is(err, types.ErrActorNotFound) {
+				continue
+			}
+			return nil, nil, xerrors.Errorf("get actor %s: %w", addr, err)
+		}
+
+		st, err := multisig.Load(p.node.Store(), act)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("load multisig state %s: %w", addr, err)
+		}
+
+		v, err := Vesting(ts.Height(), act.Balance, st)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("multisig %s vesting: %w", addr, err)
+		}
+		v.StateRoot = ts.ParentState().String()
+		v.MultisigID = addr.String()
+		results = append(results, v)
+	}
+
+	return results, report, nil
+}
+
+// Vesting calculates the vesting balances at epoch of a multisig with the given balance and state.
+func Vesting(epoch abi.ChainEpoch, balance abi.TokenAmount, st multisig.State) (*multisigmodel.MultisigVesting, error) {
+	start, err := st.StartEpoch()
+	if err != nil {
+		return nil, xerrors.Errorf("start epoch: %w", err)
+	}
+	duration, err := st.UnlockDuration()
+	if err != nil {
+		return nil, xerrors.Errorf("unlock duration: %w", err)
+	}
+	initial, err := st.InitialBalance()
+	if err != nil {
+		return nil, xerrors.Errorf("initial balance: %w", err)
+	}
+	locked, err := st.LockedBalance(epoch)
+	if err != nil {
+		return nil, xerrors.Errorf("locked balance: %w", err)
+	}
+
+	vested, available := vestedAndAvailable(balance, initial, locked)
+	return &multisigmodel.MultisigVesting{
+		Height:         int64(epoch),
+		StartEpoch:     int64(start),
+		UnlockDuration: int64(duration),
+		InitialBalance: initial.String(),
+		Balance:        balance.String(),
+		LockedBalance:  locked.String(),
+		VestedBalance:  vested.String(),
+		Available:      available.String(),
+	}, nil
+}
+
+// vestedAndAvailable returns the part of the initial balance that has vested and the part of the balance that is not
+// locked. Neither is negative.
+func vestedAndAvailable(balance, initial, locked abi.TokenAmount) (abi.TokenAmount, abi.TokenAmount) {
+	vested := big.Max(big.Sub(initial, locked), big.Zero())
+	available := big.Max(big.Sub(balance, locked), big.Zero())
+	return vested, available
+}
+
+// genesisVestingMultisigs returns the addresses of the multisigs in the genesis state that have a vesting schedule.
+func genesisVestingMultisigs(ctx context.Context, node lens.API) ([]address.Address, error) {
+	genesis, err := node.ChainGetGenesis(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("get genesis: %w", err)
+	}
+
+	addrs, err := node.StateListActors(ctx, genesis.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("list genesis actors: %w", err)
+	}
+
+	vesting := []address.Address{}
+	for _, addr := range addrs {
+		act, err := node.StateGetActor(ctx, addr, genesis.Key())
+		if err != nil {
+			return nil, xerrors.Errorf("get genesis actor %s: %w", addr, err)
+		}
+		if !isMultisigActor(act) {
+			continue
+		}
+
+		st, err := multisig.Load(node.Store(), act)
+		if err != nil {
+			return nil, xerrors.Errorf("load multisig state %s: %w", addr, err)
+		}
+		duration, err := st.UnlockDuration()
+		if err != nil {
+			return nil, xerrors.Errorf("unlock duration %s: %w", addr, err)
+		}
+		if duration > 0 {
+			vesting = append(vesting, addr)
+		}
+	}
+
+	sort.Slice(vesting, func(i, j int) bool {
+		return vesting[i].String() < vesting[j].String()
+	})
+	return vesting, nil
+}
+
+func isMultisigActor(act *types.Actor) bool {
+	for _, c := range multisig.AllCodes() {
+		if act.Code == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Task) Close() error {
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.closer != nil {
+		p.closer()
+		p.closer = nil
+	}
+	p.node = nil
+	return nil
+}
@@ -0,0 +1,34 @@
+package msigvesting
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVestedAndAvailable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		balance   int64
+		initial   int64
+		locked    int64
+		vested    int64
+		available int64
+	}{
+		{name: "fully locked", balance: 100, initial: 100, locked: 100, vested: 0, available: 0},
+		{name: "partly vested", balance: 100, initial: 100, locked: 40, vested: 60, available: 60},
+		{name: "partly vested and spent", balance: 70, initial: 100, locked: 40, vested: 60, available: 30},
+		{name: "topped up", balance: 150, initial: 100, locked: 40, vested: 60, available: 110},
+		{name: "fully vested", balance: 20, initial: 100, locked: 0, vested: 100, available: 20},
+		{name: "balance below locked", balance: 30, initial: 100, locked: 40, vested: 60, available: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vested, available := vestedAndAvailable(abi.NewTokenAmount(tc.balance), abi.NewTokenAmount(tc.initial), abi.NewTokenAmount(tc.locked))
+			assert.Equal(t, abi.NewTokenAmount(tc.vested).String(), vested.String())
+			assert.Equal(t, abi.NewTokenAmount(tc.available).String(), available.String())
+		})
+	}
+}
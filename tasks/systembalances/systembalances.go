@@ -0,0 +1,163 @@
+// Package systembalances provides a task for recording the balances of the system actors that hold network funds
+package systembalances
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	logging "github.com/ipfs/go-log/v2"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/market"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/power"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/reward"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/verifreg"
+	"github.com/filecoin-project/sentinel-visor/lens"
+	"github.com/filecoin-project/sentinel-visor/model"
+	chainmodel "github.com/filecoin-project/sentinel-visor/model/chain"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+)
+
+var log = logging.Logger("visor/task/systembalances")
+
+// VerifiedRegistryRoot is the name recorded for the root key of the verified registry, whose address is held in the
+// registry's state.
+const VerifiedRegistryRoot = "verifreg_root"
+
+// A SystemActor is an actor whose balance is recorded, identified by its role in the network.
+type SystemActor struct {
+	Name    string
+	Address address.Address
+}
+
+// SystemActors are the actors with fixed addresses whose balances are recorded.
+var SystemActors = []SystemActor{
+	{Name: "reward", Address: reward.Address},
+	{Name: "power", Address: power.Address},
+	{Name: "market", Address: market.Address},
+	{Name: "burnt_funds", Address: builtin.BurntFundsActorAddr},
+	{Name: "reserve", Address: builtin.ReserveAddress},
+}
+
+// BalanceLens is the part of the lens used to read actor balances.
+type BalanceLens interface {
+	StateGetActor(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error)
+	Store() adt.Store
+}
+
+type Task struct {
+	nodeMu sync.Mutex // guards mutations to node, opener and closer
+	node   lens.API
+	opener lens.APIOpener
+	closer lens.APICloser
+}
+
+func NewTask(opener lens.APIOpener) *Task {
+	return &Task{
+		opener: opener,
+	}
+}
+
+func (p *Task) ProcessTipSet(ctx context.Context, ts *types.TipSet) (model.Persistable, *visormodel.ProcessingReport, error) {
+	ctx, span := global.Tracer("").Start(ctx, "ProcessSystemBalances")
+	if span.IsRecording() {
+		span.SetAttributes(label.String("tipset", ts.String()), label.Int64("height", int64(ts.Height())))
+	}
+	defer span.End()
+
+	// We use p.node continually through this method so take a broad lock
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.node == nil {
+		node, closer, err := p.opener.Open(ctx)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to open lens: %w", err)
+		}
+		p.node = node
+		p.closer = closer
+	}
+
+	report := &visormodel.ProcessingReport{
+		Height:    int64(ts.Height()),
+		StateRoot: ts.ParentState().String(),
+	}
+
+	actors := append([]SystemActor{}, SystemActors...)
+	root, err := verifiedRegistryRoot(ctx, p.node, ts)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("verified registry root: %w", err)
+	}
+	if root != address.Undef {
+		actors = append(actors, SystemActor{Name: VerifiedRegistryRoot, Address: root})
+	}
+
+	balances, err := ExtractBalances(ctx, p.node, ts, actors)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return balances, report, nil
+}
+
+// ExtractBalances returns the balances of actors in the parent state of ts. Actors that do not exist in the state are
+// skipped.
+func ExtractBalances(ctx context.Context, node BalanceLens, ts *types.TipSet, actors []SystemActor) (chainmodel.SystemBalanceList, error) {
+	balances := make(chainmodel.SystemBalanceList, 0, len(actors))
+	for _, sa := range actors {
+		act, err := node.StateGetActor(ctx, sa.Address, ts.Key())
+		if err != nil {
+			if errors.Is(err, types.ErrActorNotFound) {
+				log.Debugw("system actor not found", "name", sa.Name, "address", sa.Address, "height", ts.Height())
+				continue
+			}
+			return nil, xerrors.Errorf("get actor %s: %w", sa.Name, err)
+		}
+
+		balances = append(balances, &chainmodel.SystemBalance{
+			Height:    int64(ts.Height()),
+			StateRoot: ts.ParentState().String(),
+			ActorName: sa.Name,
+			ActorID:   sa.Address.String(),
+			Balance:   act.Balance.String(),
+		})
+	}
+	return balances, nil
+}
+
+// verifiedRegistryRoot returns the address of the root key held in the state of the verified registry, or
+// address.Undef if there is no verified registry.
+func verifiedRegistryRoot(ctx context.Context, node BalanceLens, ts *types.TipSet) (address.Address, error) {
+	act, err := node.StateGetActor(ctx, verifreg.Address, ts.Key())
+	if err != nil {
+		if errors.Is(err, types.ErrActorNotFound) {
+			return address.Undef, nil
+		}
+		return address.Undef, xerrors.Errorf("get verified registry actor: %w", err)
+	}
+
+	st, err := verifreg.Load(node.Store(), act)
+	if err != nil {
+		return address.Undef, xerrors.Errorf("load verified registry state: %w", err)
+	}
+	return st.RootKey()
+}
+
+func (p *Task) Close() error {
+	p.nodeMu.Lock()
+	defer p.nodeMu.Unlock()
+
+	if p.closer != nil {
+		p.closer()
+		p.closer = nil
+	}
+	p.node = nil
+	return nil
+}
@@ -0,0 +1,56 @@
+package systembalances
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin"
+	"github.com/filecoin-project/sentinel-visor/chain/actors/builtin/reward"
+	"github.com/filecoin-project/sentinel-visor/testutil"
+)
+
+type fakeBalanceLens struct {
+	actors map[address.Address]*types.Actor
+}
+
+func (f *fakeBalanceLens) StateGetActor(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+	act, ok := f.actors[addr]
+	if !ok {
+		return nil, types.ErrActorNotFound
+	}
+	return act, nil
+}
+
+func (f *fakeBalanceLens) Store() adt.Store {
+	return nil
+}
+
+func TestExtractBalances(t *testing.T) {
+	ts := testutil.FakeTipset(t)
+	node := &fakeBalanceLens{
+		actors: map[address.Address]*types.Actor{
+			reward.Address:              {Balance: abi.NewTokenAmount(100)},
+			builtin.BurntFundsActorAddr: {Balance: abi.NewTokenAmount(7)},
+		},
+	}
+
+	balances, err := ExtractBalances(context.Background(), node, ts, SystemActors)
+	require.NoError(t, err)
+
+	// actors missing from the state are skipped
+	require.Len(t, balances, 2)
+	assert.Equal(t, "reward", balances[0].ActorName)
+	assert.Equal(t, reward.Address.String(), balances[0].ActorID)
+	assert.Equal(t, "100", balances[0].Balance)
+	assert.Equal(t, "burnt_funds", balances[1].ActorName)
+	assert.Equal(t, "7", balances[1].Balance)
+	assert.EqualValues(t, ts.Height(), balances[1].Height)
+	assert.Equal(t, ts.ParentState().String(), balances[1].StateRoot)
+}
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/go-pg/pg/v10"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/sentinel-visor/lens"
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+// Rules used to record discrepancies found by the Reconciler
+const (
+	RowCountGapRule      = "row_count_gap"      // no rows were found for an epoch that should have some
+	RowCountMismatchRule = "row_count_mismatch" // the number of rows found for an epoch differs from the chain
+)
+
+// NewReconciler creates a Reconciler that checks epochs between minHeight and maxHeight inclusive. The name of the
+// reconciler is used as the reporter of any discrepancies found.
+func NewReconciler(db *storage.Database, opener lens.APIOpener, name string, minHeight, maxHeight int64) *Reconciler {
+	return &Reconciler{
+		db:        db,
+		opener:    opener,
+		name:      name,
+		minHeight: minHeight,
+		maxHeight: maxHeight,
+	}
+}
+
+// A Reconciler is a job that walks a range of the chain comparing the number of blocks, messages and receipts persisted
+// in the database for each epoch with the numbers computed from the lens. Epochs with no persisted rows are recorded
+// as gaps and epochs with a different number of rows as mismatches in the visor_validation_failures table.
+type Reconciler struct {
+	db        *storage.Database
+	opener    lens.APIOpener
+	name      string
+	minHeight int64 // limit reconciliation to tipsets equal to or above this height
+	maxHeight int64 // limit reconciliation to tipsets equal to or below this height
+}
+
+func (r *Reconciler) Params() map[string]interface{} {
+	out := make(map[string]interface{})
+	out["minHeight"] = r.minHeight
+	out["maxHeight"] = r.maxHeight
+	return out
+}
+
+// Run walks the chain from the maximum height down to the minimum height and blocks until the walk is complete or the
+// context is done.
+func (r *Reconciler) Run(ctx context.Context) error {
+	node, closer, err := r.opener.Open(ctx)
+	if err != nil {
+		return xerrors.Errorf("open lens: %w", err)
+	}
+	defer closer()
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("get chain head: %w", err)
+	}
+
+	if int64(head.Height()) < r.minHeight {
+		return xerrors.Errorf("cannot reconcile, chain head (%d) is earlier than minimum height (%d)", int64(head.Height()), r.minHeight)
+	}
+
+	// Receipts for a tipset's messages are found in its child so start one tipset above the maximum height if possible
+	var child *types.TipSet
+	ts := head
+	if int64(head.Height()) > r.maxHeight {
+		ts, err = node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(r.maxHeight+1), types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("get tipset by height: %w", err)
+		}
+		// The tipset returned may be earlier than requested if the epoch was a null round
+		if int64(ts.Height()) > r.maxHeight {
+			child = ts
+			ts, err = node.ChainGetTipSet(ctx, child.Parents())
+			if err != nil {
+				return xerrors.Errorf("get tipset: %w", err)
+			}
+		}
+	}
+
+	found := 0
+	for int64(ts.Height()) >= r.minHeight {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		failures, err := r.reconcileTipSet(ctx, node, ts, child)
+		if err != nil {
+			return xerrors.Errorf("reconcile tipset at height %d: %w", ts.Height(), err)
+		}
+
+		if len(failures) > 0 {
+			if err := r.db.PersistBatch(ctx, failures); err != nil {
+				return xerrors.Errorf("persist failures: %w", err)
+			}
+			found += len(failures)
+		}
+
+		if ts.Height() == 0 {
+			break
+		}
+
+		child = ts
+		ts, err = node.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return xerrors.Errorf("get tipset: %w", err)
+		}
+	}
+
+	log.Infow("reconciliation complete", "from", r.minHeight, "to", r.maxHeight, "discrepancies", found)
+	return nil
+}
+
+// reconcileTipSet compares the rows persisted for a tipset with the chain. child is the tipset following ts which
+// holds the receipts for the messages in ts. It may be nil if ts is the chain head.
+func (r *Reconciler) reconcileTipSet(ctx context.Context, node lens.API, ts, child *types.TipSet) (visormodel.ValidationFailureList, error) {
+	height := int64(ts.Height())
+	detectedAt := time.Now()
+	var failures visormodel.ValidationFailureList
+
+	check := func(table string, expected int, query string, params ...interface{}) error {
+		var actual int
+		if _, err := r.db.Reader().QueryContext(ctx, pg.Scan(&actual), query, params...); err != nil {
+			return xerrors.Errorf("count %s: %w", table, err)
+		}
+		if actual == expected {
+			return nil
+		}
+
+		rule := RowCountMismatchRule
+		if actual == 0 {
+			rule = RowCountGapRule
+		}
+		log.Warnw("row count discrepancy", "height", height, "table", table, "expected", expected, "actual", actual)
+		failures = append(failures, &visormodel.ValidationFailure{
+			Height:     height,
+			Rule:       rule,
+			Subject:    table,
+			Details:    fmt.Sprintf("expected %d rows, found %d", expected, actual),
+			Reporter:   r.name,
+			DetectedAt: detectedAt,
+		})
+		return nil
+	}
+
+	if err := check("block_headers", len(ts.Blocks()), `SELECT count(*) FROM block_headers WHERE height = ? AND is_canonical`, height); err != nil {
+		return nil, err
+	}
+
+	messages := map[cid.Cid]struct{}{}
+	for _, blk := range ts.Cids() {
+		bm, err := node.ChainGetBlockMessages(ctx, blk)
+		if err != nil {
+			return nil, xerrors.Errorf("get block messages: %w", err)
+		}
+		for _, c := range bm.Cids {
+			messages[c] = struct{}{}
+		}
+	}
+	if err := check("block_messages", len(messages), `SELECT count(DISTINCT message) FROM block_messages WHERE height = ?`, height); err != nil {
+		return nil, err
+	}
+
+	if child != nil {
+		receipts, err := node.ChainGetParentReceipts(ctx, child.Cids()[0])
+		if err != nil {
+			return nil, xerrors.Errorf("get parent receipts: %w", err)
+		}
+		// Receipts are persisted with the height of the tipset whose parent state contains them
+		if err := check("receipts", len(receipts), `SELECT count(*) FROM receipts WHERE height = ? AND is_canonical`, int64(child.Height())); err != nil {
+			return nil, err
+		}
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"golang.org/x/xerrors"
+)
+
+// A Rule is an invariant that data extracted by visor is expected to satisfy.
+type Rule struct {
+	// Name identifies the rule in the visor_validation_failures table.
+	Name string
+
+	// Description is a human readable explanation of the invariant.
+	Description string
+
+	// Tables lists the tables the rule queries. The rule is skipped if any of them does not exist.
+	Tables []string
+
+	// Query selects a row for each violation of the rule with columns height, subject and details. The lower and
+	// upper bounds of the height range being checked are passed as parameters ?0 and ?1.
+	Query string
+}
+
+// BuiltinRules are the rules that ship with visor.
+var BuiltinRules = []Rule{
+	{
+		Name:        "actor_balance_non_negative",
+		Description: "Actor balances must not be negative.",
+		Tables:      []string{"actors"},
+		Query: `
+SELECT height, id AS subject, format('balance is %s', balance) AS details
+FROM actors
+WHERE height BETWEEN ?0 AND ?1 AND is_canonical AND balance::numeric < 0`,
+	},
+	{
+		Name:        "receipts_match_executed_messages",
+		Description: "Every message executed in a tipset must have exactly one receipt.",
+		Tables:      []string{"block_headers", "block_parents", "block_messages", "receipts"},
+		// Receipts are recorded at the height of the tipset whose parent state contains them, so they are compared
+		// with the messages included in the blocks of its parent tipset. Only the parents of canonical blocks are
+		// counted since the blocks of a reverted tipset name a different parent tipset.
+		Query: `
+SELECT e.height, 'receipts' AS subject, format('%s messages executed but %s receipts found', e.messages, coalesce(r.receipts, 0)) AS details
+FROM (
+	SELECT bp.height, count(DISTINCT bm.message) AS messages
+	FROM block_parents bp
+	JOIN block_headers bh ON bh.cid = bp.block AND bh.height = bp.height AND bh.is_canonical
+	JOIN block_messages bm ON bm.block = bp.parent AND bm.height < bp.height
+	WHERE bp.height BETWEEN ?0 AND ?1
+	GROUP BY bp.height
+) e
+LEFT JOIN (
+	SELECT height, count(*) AS receipts
+	FROM receipts
+	WHERE height BETWEEN ?0 AND ?1 AND is_canonical
+	GROUP BY height
+) r ON r.height = e.height
+WHERE e.messages <> coalesce(r.receipts, 0)`,
+	},
+	{
+		Name:        "datacap_non_negative",
+		Description: "The datacap allowance of verifiers must not be negative.",
+		Tables:      []string{"verifreg_governance"},
+		Query: `
+SELECT height, address AS subject, format('%s left datacap at %s', event, data_cap) AS details
+FROM verifreg_governance
+WHERE height BETWEEN ?0 AND ?1 AND is_canonical AND data_cap < 0`,
+	},
+	{
+		Name:        "client_datacap_non_negative",
+		Description: "Verified clients must not use more datacap than they have been granted.",
+		Tables:      []string{"verified_client_datacap_usage"},
+		// Daily usage is not recorded at a single height so each day is checked at the last epoch it summarises.
+		Query: `
+SELECT to_height AS height, client_id AS subject, format('%s datacap remaining on %s', remaining, day) AS details
+FROM verified_client_datacap_usage
+WHERE to_height BETWEEN ?0 AND ?1 AND remaining < 0`,
+	},
+}
+
+// RulesByName returns the builtin rules with the given names. An empty list of names selects all builtin rules.
+func RulesByName(names []string) ([]Rule, error) {
+	if len(names) == 0 {
+		return BuiltinRules, nil
+	}
+
+	byName := make(map[string]Rule, len(BuiltinRules))
+	for _, r := range BuiltinRules {
+		byName[r.Name] = r
+	}
+
+	rules := make([]Rule, 0, len(names))
+	for _, name := range names {
+		r, ok := byName[name]
+		if !ok {
+			return nil, xerrors.Errorf("unknown rule: %s", name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
@@ -0,0 +1,32 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesByName(t *testing.T) {
+	rules, err := RulesByName(nil)
+	require.NoError(t, err)
+	assert.Equal(t, BuiltinRules, rules)
+
+	rules, err = RulesByName([]string{"datacap_non_negative", "actor_balance_non_negative"})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "datacap_non_negative", rules[0].Name)
+	assert.Equal(t, "actor_balance_non_negative", rules[1].Name)
+
+	_, err = RulesByName([]string{"no_such_rule"})
+	assert.Error(t, err)
+}
+
+func TestBuiltinRulesAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, r := range BuiltinRules {
+		assert.False(t, seen[r.Name], "duplicate rule name: %s", r.Name)
+		seen[r.Name] = true
+		assert.NotEmpty(t, r.Tables, "rule %s does not declare its tables", r.Name)
+	}
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"context"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	visormodel "github.com/filecoin-project/sentinel-visor/model/visor"
+	"github.com/filecoin-project/sentinel-visor/storage"
+)
+
+var log = logging.Logger("visor/validation")
+
+// A Validator checks the data persisted in a database against a set of rules and records any violations in the
+// visor_validation_failures table.
+type Validator struct {
+	db    *storage.Database
+	rules []Rule
+	name  string
+}
+
+// NewValidator creates a Validator that checks rules against db. The name of the validator is used as the reporter
+// of any violations found.
+func NewValidator(db *storage.Database, name string, rules []Rule) *Validator {
+	return &Validator{
+		db:    db,
+		rules: rules,
+		name:  name,
+	}
+}
+
+// violation is a row returned by a rule query.
+type violation struct {
+	Height  int64
+	Subject string
+	Details string
+}
+
+// Run checks every rule against data at heights between from and to inclusive and persists the violations that are
+// found. It returns the total number of violations.
+func (v *Validator) Run(ctx context.Context, from, to int64) (int, error) {
+	total := 0
+	for _, r := range v.rules {
+		ll := log.With("rule", r.Name, "from", from, "to", to)
+
+		ok, err := v.tablesExist(ctx, r.Tables)
+		if err != nil {
+			return total, xerrors.Errorf("check tables for rule %s: %w", r.Name, err)
+		}
+		if !ok {
+			ll.Infow("skipping rule, required tables are not present in the schema", "tables", r.Tables)
+			continue
+		}
+
+		var violations []*violation
+		if _, err := v.db.Reader().QueryContext(ctx, &violations, r.Query, from, to); err != nil {
+			return total, xerrors.Errorf("check rule %s: %w", r.Name, err)
+		}
+
+		if len(violations) == 0 {
+			ll.Infow("rule passed")
+			continue
+		}
+
+		detectedAt := time.Now()
+		failures := make(visormodel.ValidationFailureList, 0, len(violations))
+		for _, vl := range violations {
+			failures = append(failures, &visormodel.ValidationFailure{
+				Height:     vl.Height,
+				Rule:       r.Name,
+				Subject:    vl.Subject,
+				Details:    vl.Details,
+				Reporter:   v.name,
+				DetectedAt: detectedAt,
+			})
+		}
+
+		if err := v.db.PersistBatch(ctx, failures); err != nil {
+			return total, xerrors.Errorf("persist failures for rule %s: %w", r.Name, err)
+		}
+
+		ll.Warnw("rule failed", "violations", len(violations))
+		total += len(violations)
+	}
+
+	return total, nil
+}
+
+func (v *Validator) tablesExist(ctx context.Context, tables []string) (bool, error) {
+	for _, table := range tables {
+		exists, err := v.db.Reader().TableExists(ctx, table)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -9,6 +9,8 @@ var GitVersion string = "unknown"
 
 var reVersion = regexp.MustCompile(`^(v\d+\.\d+.\d+)(?:-)?(.+)?$`)
 
+var reCommit = regexp.MustCompile(`(?:^|-g)([0-9a-f]{7,40})(?:-dirty)?$`)
+
 // String formats the version in semver format, see semver.org
 func String() string {
 	matches := reVersion.FindStringSubmatch(GitVersion)
@@ -21,3 +23,13 @@ func String() string {
 	}
 	return matches[1] + "+" + matches[2]
 }
+
+// Commit returns the abbreviated hash of the commit the binary was built from, or an empty string if it was built from
+// a tagged commit or the commit is not known.
+func Commit() string {
+	matches := reCommit.FindStringSubmatch(GitVersion)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
@@ -23,3 +23,21 @@ func TestString(t *testing.T) {
 	}
 
 }
+
+func TestCommit(t *testing.T) {
+	testCases := map[string]string{
+		"f176923-dirty":           "f176923",
+		"f176923":                 "f176923",
+		"v0.1.3-1-g518f694":       "518f694",
+		"v0.1.3-1-g518f694-dirty": "518f694",
+		"v0.1.3":                  "",
+		"unknown":                 "",
+	}
+
+	for v, want := range testCases {
+		GitVersion = v
+		if Commit() != want {
+			t.Errorf("%s: got %q, want %q", v, Commit(), want)
+		}
+	}
+}